@@ -0,0 +1,172 @@
+// Package accesslog ведёт журнал HTTP-запросов для анализа трафика отдельно от журнала приложения:
+// по строке на запрос в формате Apache combined или JSON, в файл с ротацией (см. File).
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/proxy"
+)
+
+// Форматы строк журнала.
+const (
+	FormatCombined = "combined" // Apache/nginx combined: разбирается GoAccess, AWStats и аналогами
+	FormatJSON     = "json"     // объект JSON на строку, например для Loki или Elasticsearch
+)
+
+// Entry — сведения об одном запросе.
+type Entry struct {
+	Time      time.Time
+	RemoteIP  string
+	Method    string
+	URI       string
+	Proto     string
+	Status    int
+	Bytes     int64
+	Duration  time.Duration
+	Referer   string
+	UserAgent string
+	Host      string
+}
+
+// Middleware пишет в w строку журнала для каждого запроса после его обработки next.
+// Адрес клиента берётся из proxy.ClientIP, поэтому Middleware должен стоять внутри proxy.Middleware.
+func Middleware(w io.Writer, format string, next http.Handler) http.Handler {
+	encode := combined
+	if format == FormatJSON {
+		encode = jsonLine
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: rw}
+		defer func() {
+			status := sw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			line := encode(Entry{
+				Time:      start,
+				RemoteIP:  proxy.ClientIP(r),
+				Method:    r.Method,
+				URI:       r.RequestURI,
+				Proto:     r.Proto,
+				Status:    status,
+				Bytes:     sw.bytes,
+				Duration:  time.Since(start),
+				Referer:   r.Referer(),
+				UserAgent: r.UserAgent(),
+				Host:      r.Host,
+			})
+			if _, err := w.Write(line); err != nil {
+				log.Printf("Error writing access log: %v.", err)
+			}
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// combined форматирует запись в формате Apache combined:
+// адрес - - [время] "запрос" статус байты "Referer" "User-Agent".
+func combined(e Entry) []byte {
+	bytes := "-"
+	if e.Bytes > 0 {
+		bytes = strconv.FormatInt(e.Bytes, 10)
+	}
+	return fmt.Appendf(nil, "%s - - [%s] \"%s %s %s\" %d %s \"%s\" \"%s\"\n",
+		e.RemoteIP,
+		e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		quote(e.Method), quote(e.URI), quote(e.Proto),
+		e.Status, bytes,
+		dash(quote(e.Referer)), dash(quote(e.UserAgent)),
+	)
+}
+
+// jsonEntry — запись журнала в формате JSON.
+type jsonEntry struct {
+	Time       string  `json:"time"`
+	RemoteIP   string  `json:"remote_ip"`
+	Host       string  `json:"host"`
+	Method     string  `json:"method"`
+	URI        string  `json:"uri"`
+	Proto      string  `json:"proto"`
+	Status     int     `json:"status"`
+	Bytes      int64   `json:"bytes"`
+	DurationMS float64 `json:"duration_ms"`
+	Referer    string  `json:"referer,omitempty"`
+	UserAgent  string  `json:"user_agent,omitempty"`
+}
+
+// jsonLine форматирует запись как объект JSON в одну строку.
+func jsonLine(e Entry) []byte {
+	line, err := json.Marshal(jsonEntry{
+		Time:       e.Time.UTC().Format(time.RFC3339Nano),
+		RemoteIP:   e.RemoteIP,
+		Host:       e.Host,
+		Method:     e.Method,
+		URI:        e.URI,
+		Proto:      e.Proto,
+		Status:     e.Status,
+		Bytes:      e.Bytes,
+		DurationMS: float64(e.Duration.Microseconds()) / 1000,
+		Referer:    e.Referer,
+		UserAgent:  e.UserAgent,
+	})
+	if err != nil {
+		return nil
+	}
+	return append(line, '\n')
+}
+
+// quote экранирует кавычки, обратную косую черту и управляющие символы,
+// чтобы значение из запроса не разорвало строку журнала.
+func quote(s string) string {
+	if !strings.ContainsFunc(s, func(r rune) bool { return r == '"' || r == '\\' || r < ' ' || r == 0x7f }) {
+		return s
+	}
+	q := strconv.Quote(s)
+	return q[1 : len(q)-1]
+}
+
+// dash заменяет пустое значение на "-", как принято в combined.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// statusWriter запоминает код ответа и число записанных байт тела.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	// Промежуточные ответы 1xx (например, 103 Early Hints) не являются итоговым статусом.
+	if w.status == 0 && code >= 200 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap возвращает исходный ResponseWriter для http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,93 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMiddleware проверяет строки журнала в форматах combined и json.
+func TestMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/post/1/comments?x=1", nil)
+		r.RemoteAddr = "203.0.113.7:5000"
+		r.Header.Set("Referer", "https://forum.example.com/post/1")
+		r.Header.Set("User-Agent", `bot "quoted"`)
+		return r
+	}
+
+	var buf bytes.Buffer
+	Middleware(&buf, FormatCombined, next).ServeHTTP(httptest.NewRecorder(), newRequest())
+	line := buf.String()
+	for _, want := range []string{
+		`203.0.113.7 - - [`,
+		`] "POST /post/1/comments?x=1 HTTP/1.1" 201 5 "https://forum.example.com/post/1" "bot \"quoted\""`,
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("combined line %q does not contain %q", line, want)
+		}
+	}
+
+	buf.Reset()
+	Middleware(&buf, FormatJSON, next).ServeHTTP(httptest.NewRecorder(), newRequest())
+	var entry jsonEntry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("json line %q: %v", buf.String(), err)
+	}
+	if entry.RemoteIP != "203.0.113.7" || entry.Method != "POST" || entry.URI != "/post/1/comments?x=1" ||
+		entry.Status != http.StatusCreated || entry.Bytes != 5 || entry.UserAgent != `bot "quoted"` {
+		t.Errorf("unexpected json entry %+v", entry)
+	}
+}
+
+// TestFileRotation проверяет ротацию по размеру и удаление старых файлов сверх keep:
+// 9 строк дают 4 ротированных файла по две строки и одну строку в текущем, старейший файл удаляется.
+func TestFileRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", "access.log")
+	f, err := Open(path, 25, 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	line := []byte("0123456789\n") // 11 байт: в файл помещаются две строки
+	for i := 0; i < 9; i++ {
+		if _, err := f.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != len(line) {
+		t.Errorf("current file has %d bytes, want %d", len(data), len(line))
+	}
+	rotated, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 3 {
+		t.Fatalf("got %d rotated files %v, want 3", len(rotated), rotated)
+	}
+	for _, name := range rotated {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != 2*len(line) {
+			t.Errorf("%s has %d bytes, want %d", name, len(data), 2*len(line))
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package accesslog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File — файл журнала с ротацией по размеру и по времени. Текущие записи пишутся в path,
+// при ротации файл переименовывается в path.20060102-150405.000 (время ротации по UTC).
+type File struct {
+	path     string
+	maxSize  int64         // 0 — без ограничения размера
+	interval time.Duration // 0 — без ротации по времени
+	keep     int           // 0 — хранить все ротированные файлы
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	period time.Time // начало интервала, к которому относятся записи текущего файла
+}
+
+// Open открывает (или создаёт) файл журнала path для дозаписи.
+// Файл ротируется, когда запись превысила бы maxSize байт или начался новый интервал interval,
+// отсчитываемый от нулевого момента (при interval 24h — в полночь по UTC). После ротации
+// остаются не более keep старых файлов.
+func Open(path string, maxSize int64, interval time.Duration, keep int) (*File, error) {
+	l := &File{path: path, maxSize: maxSize, interval: interval, keep: keep}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, err
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open открывает path; интервал существующего файла определяется по времени его последнего изменения,
+// чтобы перезапуск сервера не откладывал ротацию.
+func (l *File) open() error {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.f = f
+	l.size = info.Size()
+	l.period = l.truncate(info.ModTime())
+	if l.size == 0 {
+		l.period = l.truncate(time.Now())
+	}
+	return nil
+}
+
+// truncate возвращает начало интервала ротации, содержащего t.
+func (l *File) truncate(t time.Time) time.Time {
+	if l.interval <= 0 {
+		return time.Time{}
+	}
+	return t.UTC().Truncate(l.interval)
+}
+
+// Write дописывает p в журнал, при необходимости предварительно ротируя файл.
+// Запись целиком попадает в один файл.
+func (l *File) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return 0, os.ErrClosed
+	}
+	now := time.Now()
+	if l.size > 0 && (l.maxSize > 0 && l.size+int64(len(p)) > l.maxSize || !l.truncate(now).Equal(l.period)) {
+		if err := l.rotate(now); err != nil {
+			return 0, fmt.Errorf("rotate access log: %w", err)
+		}
+	}
+	n, err := l.f.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+// rotate переименовывает текущий файл, открывает новый и удаляет лишние старые файлы.
+func (l *File) rotate(now time.Time) error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	l.f = nil
+	rotated := l.path + "." + now.UTC().Format("20060102-150405.000")
+	// Несколько ротаций за одну миллисекунду не должны перезаписать друг друга.
+	for i := 1; ; i++ {
+		if _, err := os.Stat(rotated); os.IsNotExist(err) {
+			break
+		}
+		rotated = fmt.Sprintf("%s.%s-%d", l.path, now.UTC().Format("20060102-150405.000"), i)
+	}
+	if err := os.Rename(l.path, rotated); err != nil {
+		// Продолжаем писать в прежний файл, чтобы не терять записи.
+		if openErr := l.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := l.open(); err != nil {
+		return err
+	}
+	return l.prune()
+}
+
+// prune удаляет самые старые ротированные файлы сверх keep.
+// Имена содержат время в сортируемом формате, поэтому порядок определяется по имени.
+func (l *File) prune() error {
+	if l.keep <= 0 {
+		return nil
+	}
+	dir, base := filepath.Split(l.path)
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var rotated []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), base+".") {
+			rotated = append(rotated, e.Name())
+		}
+	}
+	sort.Strings(rotated)
+	for len(rotated) > l.keep {
+		if err := os.Remove(filepath.Join(dir, rotated[0])); err != nil {
+			return err
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+// Close закрывает файл журнала; последующие записи возвращают ошибку.
+func (l *File) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
@@ -0,0 +1,66 @@
+// Package main встраивает шаблоны и статические файлы в бинарный файл, чтобы сервер
+// не зависел от рабочего каталога. Для разработки их можно читать с диска (server.templates_dir, server.static_dir).
+
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+
+	"forum/config"
+	"forum/fingerprint"
+)
+
+//go:embed templates/*.html templates/partials/*.html
+var embeddedTemplates embed.FS
+
+//go:embed static
+var embeddedStatic embed.FS
+
+// assets — файловые системы шаблонов и статических файлов.
+type assets struct {
+	templates fs.FS
+	static    fs.FS
+	// manifest — имена статических файлов с хешем содержимого; nil, если файлы читаются с диска
+	// и могут меняться во время работы.
+	manifest *fingerprint.Manifest
+}
+
+// loadAssets возвращает встроенные шаблоны и статические файлы либо каталоги с диска, если они заданы в настройках.
+// Файлы с диска читаются при каждом обращении, поэтому изменения видны без пересборки.
+func loadAssets(cfg config.Server) (assets, error) {
+	templates, err := assetFS(embeddedTemplates, "templates", cfg.TemplatesDir)
+	if err != nil {
+		return assets{}, err
+	}
+	static, err := assetFS(embeddedStatic, "static", cfg.StaticDir)
+	if err != nil {
+		return assets{}, err
+	}
+	a := assets{templates: templates, static: static}
+	if cfg.StaticDir == "" {
+		if a.manifest, err = fingerprint.New(static, "/static/"); err != nil {
+			return assets{}, err
+		}
+	}
+	return a, nil
+}
+
+// assetFS возвращает каталог dir с диска или, если dir пуст, каталог name из встроенной файловой системы.
+func assetFS(embedded embed.FS, name, dir string) (fs.FS, error) {
+	if dir == "" {
+		return fs.Sub(embedded, name)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s directory: %w", name, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s directory: %s is not a directory", name, dir)
+	}
+	log.Printf("Serving %s from disk: %s.", name, dir)
+	return os.DirFS(dir), nil
+}
@@ -0,0 +1,130 @@
+// Package botcheck отсеивает автоматические регистрации без сторонних капч.
+//
+// Форма регистрации содержит скрытое поле-ловушку (HoneypotField), которое человек не видит и оставляет
+// пустым, а программа, заполняющая все поля подряд, заполняет, и подписанную метку времени показа формы
+// (Form.Token): форму, отправленную быстрее, чем её успел бы заполнить человек, или без действительной метки
+// форум отклоняет. Адреса одноразовых почтовых сервисов отсеивает Domains.
+package botcheck
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HoneypotField — имя скрытого поля-ловушки. Название выбрано так, чтобы программы считали его обычным полем.
+const HoneypotField = "website"
+
+// TokenField — имя поля формы с подписанной меткой времени.
+const TokenField = "form_token"
+
+// Причины отказа Form.Check.
+var (
+	ErrHoneypot = errors.New("honeypot field filled")
+	ErrTooFast  = errors.New("form submitted too fast")
+	ErrExpired  = errors.New("form token missing, invalid or expired")
+)
+
+// Form подписывает и проверяет метки времени формы. nil — проверка выключена: метка пуста, любая форма принимается.
+type Form struct {
+	Secret   []byte        // ключ подписи
+	MinDelay time.Duration // не раньше этого после показа форму можно отправить
+	MaxAge   time.Duration // не позже этого после показа форму можно отправить
+}
+
+// Token возвращает метку времени показа формы now с подписью.
+func (f *Form) Token(now time.Time) string {
+	if f == nil {
+		return ""
+	}
+	ts := strconv.FormatInt(now.Unix(), 10)
+	return ts + "." + f.sign(ts)
+}
+
+// Check проверяет отправленную в момент now форму с меткой token и значением ловушки honeypot.
+// Возвращает ErrHoneypot, ErrTooFast или ErrExpired; nil — форма похожа на заполненную человеком.
+func (f *Form) Check(token, honeypot string, now time.Time) error {
+	if f == nil {
+		return nil
+	}
+	if strings.TrimSpace(honeypot) != "" {
+		return ErrHoneypot
+	}
+	ts, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(f.sign(ts))) {
+		return ErrExpired
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrExpired
+	}
+	elapsed := now.Sub(time.Unix(unix, 0))
+	if elapsed < f.MinDelay {
+		return ErrTooFast
+	}
+	if f.MaxAge > 0 && elapsed > f.MaxAge {
+		return ErrExpired
+	}
+	return nil
+}
+
+// sign возвращает подпись метки ts.
+func (f *Form) sign(ts string) string {
+	mac := hmac.New(sha256.New, f.Secret)
+	mac.Write([]byte("register:" + ts))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// Domains — список почтовых доменов. Адрес подходит, если его домен или любой родительский домен есть в списке.
+type Domains map[string]bool
+
+// NewDomains возвращает список из domains без учёта регистра; пустые строки пропускаются.
+func NewDomains(domains ...string) Domains {
+	d := make(Domains, len(domains))
+	for _, domain := range domains {
+		if domain = strings.ToLower(strings.Trim(strings.TrimSpace(domain), ".")); domain != "" {
+			d[domain] = true
+		}
+	}
+	return d
+}
+
+// Contains сообщает, входит ли домен адреса email (или его родительский домен) в список.
+func (d Domains) Contains(email string) bool {
+	_, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return false
+	}
+	domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+	for domain != "" {
+		if d[domain] {
+			return true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			break
+		}
+		domain = parent
+	}
+	return false
+}
+
+// DisposableDomains — известные сервисы одноразовой почты. Список дополняется настройкой
+// registration.disposable_domains.
+var DisposableDomains = []string{
+	"10minutemail.com", "10minutemail.net", "20minutemail.com", "33mail.com", "burnermail.io",
+	"discard.email", "dispostable.com", "dropmail.me", "emailondeck.com", "fakeinbox.com",
+	"getairmail.com", "getnada.com", "guerrillamail.biz", "guerrillamail.com", "guerrillamail.de",
+	"guerrillamail.info", "guerrillamail.net", "guerrillamail.org", "guerrillamailblock.com", "harakirimail.com",
+	"inboxkitten.com", "incognitomail.org", "mail-temp.com", "mailcatch.com", "maildrop.cc",
+	"mailinator.com", "mailinator.net", "mailnesia.com", "mailpoof.com", "mintemail.com",
+	"mohmal.com", "moakt.com", "mytemp.email", "nada.email", "sharklasers.com",
+	"spam4.me", "spambox.us", "spamgourmet.com", "temp-mail.io", "temp-mail.org",
+	"tempail.com", "tempmail.dev", "tempmail.net", "tempmailo.com", "tempr.email",
+	"throwawaymail.com", "trashmail.com", "trashmail.de", "trashmail.net", "yopmail.com",
+	"yopmail.fr", "yopmail.net",
+}
@@ -0,0 +1,51 @@
+package botcheck
+
+import (
+	"testing"
+	"time"
+)
+
+// TestFormCheck проверяет ловушку, слишком быструю отправку, срок и подпись метки.
+func TestFormCheck(t *testing.T) {
+	f := &Form{Secret: []byte("secret"), MinDelay: 3 * time.Second, MaxAge: time.Hour}
+	shown := time.Unix(1_700_000_000, 0)
+	token := f.Token(shown)
+
+	tests := []struct {
+		name     string
+		token    string
+		honeypot string
+		at       time.Time
+		want     error
+	}{
+		{"human", token, "", shown.Add(10 * time.Second), nil},
+		{"honeypot", token, "http://spam.example", shown.Add(10 * time.Second), ErrHoneypot},
+		{"too fast", token, "", shown.Add(time.Second), ErrTooFast},
+		{"expired", token, "", shown.Add(2 * time.Hour), ErrExpired},
+		{"missing", "", "", shown.Add(10 * time.Second), ErrExpired},
+		{"forged", "1600000000." + token[len("1700000000."):], "", shown.Add(10 * time.Second), ErrExpired},
+		{"other key", (&Form{Secret: []byte("other")}).Token(shown), "", shown.Add(10 * time.Second), ErrExpired},
+	}
+	for _, tt := range tests {
+		if got := f.Check(tt.token, tt.honeypot, tt.at); got != tt.want {
+			t.Errorf("%s: Check = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestDomains проверяет совпадение домена адреса и его родительских доменов.
+func TestDomains(t *testing.T) {
+	d := NewDomains(append(DisposableDomains, " Example.ORG ", "")...)
+	for email, want := range map[string]bool{
+		"bot@mailinator.com":     true,
+		"bot@eu.Mailinator.com":  true,
+		"user@example.org":       true,
+		"user@gmail.com":         false,
+		"user@notmailinator.com": false,
+		"no-at-sign":             false,
+	} {
+		if got := d.Contains(email); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", email, got, want)
+		}
+	}
+}
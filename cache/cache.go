@@ -0,0 +1,105 @@
+// Package cache содержит простой кэш с ограниченным временем жизни записей.
+// Значения хранятся как байты, поэтому реализации можно заменять (память процесса, внешний сервер)
+// без изменения кода, который кэширует данные.
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache описывает хранилище значений с временем жизни.
+// ttl = 0 означает, что запись не истекает.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+	Delete(keys ...string)
+}
+
+// memoryEntry хранит значение и момент его истечения.
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// expired сообщает, истекла ли запись к моменту now.
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// Memory — кэш в памяти процесса. Истёкшие записи удаляются при чтении,
+// а при превышении maxEntries — при следующей записи.
+type Memory struct {
+	mu         sync.Mutex
+	items      map[string]memoryEntry
+	maxEntries int
+}
+
+// defaultMaxEntries ограничивает размер кэша, если не указан другой.
+const defaultMaxEntries = 10000
+
+// NewMemory создаёт кэш в памяти, вмещающий не более maxEntries записей (0 — значение по умолчанию).
+func NewMemory(maxEntries int) *Memory {
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxEntries
+	}
+	return &Memory{items: make(map[string]memoryEntry), maxEntries: maxEntries}
+}
+
+// Get возвращает значение по ключу, если оно есть и не истекло.
+func (m *Memory) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	if e.expired(time.Now()) {
+		delete(m.items, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Set сохраняет значение на время ttl.
+// Если кэш переполнен, сначала удаляются истёкшие записи, а при их отсутствии — весь кэш,
+// кроме бессрочных записей.
+func (m *Memory) Set(key string, value []byte, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.items[key]; !exists && len(m.items) >= m.maxEntries {
+		m.evict()
+	}
+	e := memoryEntry{value: value}
+	if ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+	m.items[key] = e
+}
+
+// Delete удаляет записи по ключам.
+func (m *Memory) Delete(keys ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.items, key)
+	}
+}
+
+// evict освобождает место в переполненном кэше. Вызывается под m.mu.
+func (m *Memory) evict() {
+	now := time.Now()
+	for key, e := range m.items {
+		if e.expired(now) {
+			delete(m.items, key)
+		}
+	}
+	if len(m.items) < m.maxEntries {
+		return
+	}
+	for key, e := range m.items {
+		if !e.expiresAt.IsZero() {
+			delete(m.items, key)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMemoryExpiry проверяет, что запись с истёкшим временем жизни не возвращается, а бессрочная хранится.
+func TestMemoryExpiry(t *testing.T) {
+	m := NewMemory(0)
+	m.Set("short", []byte("a"), 10*time.Millisecond)
+	m.Set("forever", []byte("b"), 0)
+	if v, ok := m.Get("short"); !ok || string(v) != "a" {
+		t.Fatalf("Get(short) = %q, %v before expiry", v, ok)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if v, ok := m.Get("short"); ok {
+		t.Errorf("Get(short) = %q after expiry", v)
+	}
+	if v, ok := m.Get("forever"); !ok || string(v) != "b" {
+		t.Errorf("Get(forever) = %q, %v", v, ok)
+	}
+	if _, ok := m.items["short"]; ok {
+		t.Error("expired entry stays in memory after Get")
+	}
+
+	m.Set("forever", []byte("c"), time.Hour)
+	m.Delete("forever", "missing")
+	if _, ok := m.Get("forever"); ok {
+		t.Error("Get after Delete found the entry")
+	}
+}
+
+// TestMemoryEviction проверяет освобождение места в переполненном кэше: сначала удаляются истёкшие записи,
+// затем все записи со временем жизни; бессрочные записи и перезапись существующего ключа не вытесняют ничего.
+func TestMemoryEviction(t *testing.T) {
+	m := NewMemory(2)
+	m.Set("stale", []byte("1"), time.Millisecond)
+	m.Set("fresh", []byte("2"), time.Hour)
+	time.Sleep(5 * time.Millisecond)
+	m.Set("new", []byte("3"), time.Hour)
+	if _, ok := m.items["stale"]; ok {
+		t.Error("expired entry survived eviction")
+	}
+	if _, ok := m.Get("fresh"); !ok {
+		t.Error("live entry evicted while an expired one could go")
+	}
+
+	m.Set("new", []byte("4"), time.Hour)
+	if v, ok := m.Get("new"); !ok || string(v) != "4" || len(m.items) != 2 {
+		t.Errorf("overwrite in a full cache: %q, %v, %d entries", v, ok, len(m.items))
+	}
+
+	m = NewMemory(2)
+	m.Set("generation", []byte("g"), 0)
+	m.Set("page", []byte("p"), time.Hour)
+	m.Set("other", []byte("o"), time.Hour)
+	if _, ok := m.Get("generation"); !ok {
+		t.Error("entry without ttl evicted")
+	}
+	if _, ok := m.Get("page"); ok {
+		t.Error("entry with ttl survived eviction of a full cache")
+	}
+	if _, ok := m.Get("other"); !ok {
+		t.Error("new entry missing after eviction")
+	}
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTimeout ограничивает время одной операции с Redis: при недоступном сервере
+// страница строится из базы, а не ждёт кэш.
+const redisTimeout = 500 * time.Millisecond
+
+// Redis — кэш на сервере Redis, общий для нескольких экземпляров приложения.
+// Ошибки Redis логируются и считаются промахом кэша.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedis подключается к Redis по URL вида redis://:password@host:6379/0.
+// Все ключи получают префикс prefix, чтобы несколько форумов могли делить один сервер.
+func NewRedis(url, prefix string) (*Redis, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &Redis{client: client, prefix: prefix}, nil
+}
+
+// Get возвращает значение по ключу, если оно есть.
+func (r *Redis) Get(key string) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	value, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Println("Error reading from redis:", err)
+		}
+		return nil, false
+	}
+	return value, true
+}
+
+// Set сохраняет значение на время ttl.
+func (r *Redis) Set(key string, value []byte, ttl time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := r.client.Set(ctx, r.prefix+key, value, ttl).Err(); err != nil {
+		log.Println("Error writing to redis:", err)
+	}
+}
+
+// Delete удаляет записи по ключам.
+func (r *Redis) Delete(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = r.prefix + key
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := r.client.Del(ctx, prefixed...).Err(); err != nil {
+		log.Println("Error deleting from redis:", err)
+	}
+}
+
+// Close закрывает соединения с Redis.
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis — сервер Redis в процессе теста: понимает протокол RESP2 и команды, которые использует Redis
+// (GET, SET с EX/PX, DEL, PING). На остальные команды, в том числе HELLO и CLIENT SETINFO, которые клиент
+// отправляет при подключении, отвечает ошибкой, и клиент продолжает работу по RESP2.
+type fakeRedis struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	items map[string]fakeEntry
+	conns []net.Conn
+}
+
+// fakeEntry — значение ключа и момент его истечения (нулевой — без срока).
+type fakeEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// newFakeRedis запускает сервер на свободном локальном порту; он останавливается по окончании теста.
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeRedis{ln: ln, items: make(map[string]fakeEntry)}
+	go s.serve()
+	t.Cleanup(s.close)
+	return s
+}
+
+// close останавливает сервер и обрывает открытые соединения.
+func (s *fakeRedis) close() {
+	s.ln.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+// url возвращает адрес сервера для NewRedis.
+func (s *fakeRedis) url() string {
+	return "redis://" + s.ln.Addr().String() + "/0"
+}
+
+// entry возвращает запись по полному ключу (с префиксом), если она есть и не истекла.
+func (s *fakeRedis) entry(key string) (fakeEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.items[key]
+	if ok && !e.expiresAt.IsZero() && time.Now().After(e.expiresAt) {
+		delete(s.items, key)
+		return fakeEntry{}, false
+	}
+	return e, ok
+}
+
+func (s *fakeRedis) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+// handle читает команды соединения по одной и отвечает на каждую.
+func (s *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, s.exec(args)); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand читает команду — массив строк RESP.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+// exec выполняет команду и возвращает ответ RESP.
+func (s *fakeRedis) exec(args []string) string {
+	if len(args) == 0 {
+		return "-ERR empty command\r\n"
+	}
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		e, ok := s.entry(args[1])
+		if !ok {
+			return "$-1\r\n"
+		}
+		return "$" + strconv.Itoa(len(e.value)) + "\r\n" + e.value + "\r\n"
+	case "SET":
+		e := fakeEntry{value: args[2]}
+		for i := 3; i+1 < len(args); i += 2 {
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return "-ERR value is not an integer\r\n"
+			}
+			switch strings.ToUpper(args[i]) {
+			case "EX":
+				e.expiresAt = time.Now().Add(time.Duration(n) * time.Second)
+			case "PX":
+				e.expiresAt = time.Now().Add(time.Duration(n) * time.Millisecond)
+			}
+		}
+		s.mu.Lock()
+		s.items[args[1]] = e
+		s.mu.Unlock()
+		return "+OK\r\n"
+	case "DEL":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := s.items[key]; ok {
+				delete(s.items, key)
+				deleted++
+			}
+		}
+		return ":" + strconv.Itoa(deleted) + "\r\n"
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+// TestRedis проверяет чтение, запись с временем жизни, удаление и префикс ключей кэша Redis,
+// а также то, что недоступный сервер означает промах кэша, а не ошибку.
+func TestRedis(t *testing.T) {
+	server := newFakeRedis(t)
+	r, err := NewRedis(server.url(), "forum:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if v, ok := r.Get("missing"); ok {
+		t.Errorf("Get(missing) = %q", v)
+	}
+	r.Set("page", []byte("<html>"), time.Hour)
+	if v, ok := r.Get("page"); !ok || string(v) != "<html>" {
+		t.Errorf("Get(page) = %q, %v", v, ok)
+	}
+	if e, ok := server.entry("forum:page"); !ok || e.expiresAt.IsZero() || time.Until(e.expiresAt) > time.Hour {
+		t.Errorf("stored entry = %+v, %v, want prefixed key with a one-hour ttl", e, ok)
+	}
+	r.Set("generation", []byte("1"), 0)
+	if e, ok := server.entry("forum:generation"); !ok || !e.expiresAt.IsZero() {
+		t.Errorf("entry without ttl = %+v, %v", e, ok)
+	}
+
+	r.Set("short", []byte("x"), 50*time.Millisecond)
+	if _, ok := r.Get("short"); !ok {
+		t.Error("short-lived entry missing before expiry")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if v, ok := r.Get("short"); ok {
+		t.Errorf("Get(short) = %q after expiry", v)
+	}
+
+	r.Delete("page", "generation")
+	r.Delete()
+	for _, key := range []string{"page", "generation"} {
+		if _, ok := r.Get(key); ok {
+			t.Errorf("Get(%s) found the entry after Delete", key)
+		}
+	}
+
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(out)
+	server.close()
+	r.Set("page", []byte("<html>"), time.Hour)
+	if _, ok := r.Get("page"); ok {
+		t.Error("Get succeeded with the server down")
+	}
+}
@@ -0,0 +1,583 @@
+// Package main содержит служебные подкоманды, которые выполняются вместо запуска сервера.
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"forum/config"
+	"forum/database"
+	"forum/i18n"
+	"forum/importer"
+	"forum/mailreply"
+	"forum/models"
+	"forum/seed"
+	"forum/usernames"
+)
+
+// runCommand выполняет подкоманду, переданную в аргументах командной строки, с настройками сервера cfg.
+// Возвращает ошибку для неизвестной подкоманды или при её неудачном выполнении.
+func runCommand(cfg config.Config, db *sql.DB, args []string) error {
+	switch args[0] {
+	case "ban":
+		return runBan(db, args[1:])
+	case "board-moderator":
+		return runBoardModerator(db, args[1:])
+	case "boards":
+		return runBoards(db, args[1:])
+	case "compact":
+		return runCompact(db, args[1:])
+	case "create-board":
+		return runCreateBoard(db, args[1:])
+	case "create-admin":
+		return runCreateAdmin(db, cfg.Usernames, args[1:])
+	case "digest":
+		return runDigest(db, os.Stdout, args[1:])
+	case "import":
+		return runImport(db, args[1:])
+	case "integrity":
+		return runIntegrity(db, args[1:])
+	case "migrate":
+		return runMigrate(db, args[1:])
+	case "reply-address":
+		return runReplyAddress(db, os.Stdout, args[1:])
+	case "reset-password":
+		return runResetPassword(db, args[1:])
+	case "seed":
+		return runSeed(db, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q (available: ban, board-moderator, boards, compact, create-admin, create-board, digest, import, integrity, migrate, reply-address, reset-password, seed)", args[0])
+	}
+}
+
+// runImport загружает данные из JSON-файла или каталога с CSV-файлами.
+// Пример: ./server import -path dump.json
+func runImport(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	path := fs.String("path", "", "JSON dump file or directory with users.csv, posts.csv, comments.csv, votes.csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" && fs.NArg() > 0 {
+		*path = fs.Arg(0)
+	}
+	if *path == "" {
+		return fmt.Errorf("import: -path is required")
+	}
+
+	dump, err := importer.Load(*path)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	report, err := importer.Import(context.Background(), db, dump)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	log.Println("Import finished:", report)
+	for _, reason := range report.Skipped {
+		log.Println("Skipped", reason)
+	}
+	if report.UsersCreated > 0 {
+		log.Println("Imported users received random passwords and must reset them before logging in.")
+	}
+	return nil
+}
+
+// runIntegrity ищет строки, ссылающиеся на несуществующие записи, и с флагом -repair удаляет их.
+// Пример: ./server integrity -repair
+func runIntegrity(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("integrity", flag.ContinueOnError)
+	repair := fs.Bool("repair", false, "delete orphaned rows")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	issues, err := database.CheckIntegrity(context.Background(), db, *repair)
+	if err != nil {
+		return fmt.Errorf("integrity: %w", err)
+	}
+	if len(issues) == 0 {
+		log.Println("Integrity check passed: no orphaned rows.")
+		return nil
+	}
+	for _, issue := range issues {
+		if *repair {
+			fmt.Printf("%-35s %d found, %d deleted\n", issue.Check, issue.Rows, issue.Repaired)
+		} else {
+			fmt.Printf("%-35s %d found\n", issue.Check, issue.Rows)
+		}
+	}
+	if !*repair {
+		log.Println("Run with -repair to delete orphaned rows.")
+	}
+	return nil
+}
+
+// runMigrate показывает состояние миграций схемы или откатывает их до указанной версии.
+// Все миграции применяются автоматически при запуске, поэтому без флагов команда только выводит статус.
+// Пример: ./server migrate -down-to 2
+func runMigrate(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	downTo := fs.Int("down-to", -1, "roll back migrations newer than this version")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *downTo >= 0 {
+		if err := database.MigrateDown(db, *downTo); err != nil {
+			return fmt.Errorf("migrate: %w", err)
+		}
+	}
+
+	statuses, err := database.MigrationsStatus(db)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	for _, st := range statuses {
+		state := "pending"
+		if st.Applied {
+			state = "applied " + st.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%3d  %-30s %s\n", st.Version, st.Name, state)
+	}
+	return nil
+}
+
+// runSeed наполняет базу демонстрационными пользователями, постами, комментариями и голосами.
+// Пример: ./server seed -users 50 -posts 1000 -comments 8 -votes 20
+func runSeed(db *sql.DB, args []string) error {
+	opts := seed.DefaultOptions()
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	fs.IntVar(&opts.Users, "users", opts.Users, "number of demo users")
+	fs.IntVar(&opts.Posts, "posts", opts.Posts, "number of posts")
+	fs.IntVar(&opts.Comments, "comments", opts.Comments, "average comments per post")
+	fs.IntVar(&opts.Votes, "votes", opts.Votes, "average votes per post")
+	fs.DurationVar(&opts.Period, "period", opts.Period, "posts are spread over this period before now")
+	fs.StringVar(&opts.Password, "password", opts.Password, "password of every demo user")
+	fs.Int64Var(&opts.Rand, "rand", opts.Rand, "random seed; the same value generates the same data")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := seed.Run(context.Background(), db, opts)
+	if err != nil {
+		return fmt.Errorf("seed: %w", err)
+	}
+	log.Println("Seed finished:", report)
+	log.Printf("Demo users user001@%s … user%03d@%s can log in with password %q.", seed.EmailDomain, opts.Users, seed.EmailDomain, opts.Password)
+	return nil
+}
+
+// runCreateAdmin создаёт администратора или назначает администратором существующего пользователя с этим email.
+// Без -password генерирует случайный пароль и выводит его. Нужна для первого администратора, когда войти в веб-интерфейс некому.
+// Имя новой учётной записи проверяется правилами имён rules, как при регистрации.
+// Пример: ./server create-admin -email admin@example.com -username keeper
+func runCreateAdmin(db *sql.DB, rules config.Usernames, args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := fs.String("email", "", "email of the administrator")
+	username := fs.String("username", "", "username for a new account")
+	password := fs.String("password", "", "password for a new account (generated if empty)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return fmt.Errorf("create-admin: -email is required")
+	}
+
+	ctx := context.Background()
+	userID, _, _, role, err := database.GetUserByEmail(ctx, db, *email)
+	switch {
+	case err == nil:
+		if role == "admin" {
+			log.Printf("User %s is already an administrator.", *email)
+			return nil
+		}
+		if err := database.SetUserRole(ctx, db, userID, "admin"); err != nil {
+			return fmt.Errorf("create-admin: %w", err)
+		}
+		log.Printf("User %s is now an administrator; the existing password is kept and open sessions get the new role.", *email)
+		return nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("create-admin: %w", err)
+	}
+
+	if *username == "" {
+		return fmt.Errorf("create-admin: no user with email %s; -username is required to create one", *email)
+	}
+	checker, err := usernames.New(rules.MinLength, rules.MaxLength, rules.Charset, rules.Reserved, rules.Profanity)
+	if err != nil {
+		return fmt.Errorf("create-admin: %w", err)
+	}
+	if err := checker.Check(*username); err != nil {
+		return fmt.Errorf("create-admin: username %q is not allowed: %w", *username, err)
+	}
+	taken, err := database.UsernameExists(ctx, db, *username)
+	if err != nil {
+		return fmt.Errorf("create-admin: %w", err)
+	}
+	if taken {
+		return fmt.Errorf("create-admin: username %q is already taken", *username)
+	}
+	secret, generated, err := passwordOrRandom(*password)
+	if err != nil {
+		return fmt.Errorf("create-admin: %w", err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("create-admin: %w", err)
+	}
+	if err := database.RegisterUser(ctx, db, *email, *username, string(hash)); err != nil {
+		return fmt.Errorf("create-admin: %w", err)
+	}
+	userID, _, _, _, err = database.GetUserByEmail(ctx, db, *email)
+	if err != nil {
+		return fmt.Errorf("create-admin: %w", err)
+	}
+	if err := database.SetUserRole(ctx, db, userID, "admin"); err != nil {
+		return fmt.Errorf("create-admin: %w", err)
+	}
+	log.Printf("Administrator %s (%s) created.", *username, *email)
+	if generated {
+		fmt.Println("Password:", secret)
+	}
+	return nil
+}
+
+// runResetPassword задаёт пользователю новый пароль и завершает все его сессии.
+// Без -password генерирует случайный пароль и выводит его.
+// Пример: ./server reset-password -email user@example.com
+func runResetPassword(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	email := fs.String("email", "", "email of the user")
+	password := fs.String("password", "", "new password (generated if empty)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return fmt.Errorf("reset-password: -email is required")
+	}
+
+	ctx := context.Background()
+	userID, err := userIDByEmail(ctx, db, *email)
+	if err != nil {
+		return fmt.Errorf("reset-password: %w", err)
+	}
+	secret, generated, err := passwordOrRandom(*password)
+	if err != nil {
+		return fmt.Errorf("reset-password: %w", err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("reset-password: %w", err)
+	}
+	if err := database.UpdatePassword(ctx, db, userID, string(hash)); err != nil {
+		return fmt.Errorf("reset-password: %w", err)
+	}
+	if err := database.DeleteUserSessions(ctx, db, userID); err != nil {
+		return fmt.Errorf("reset-password: %w", err)
+	}
+	log.Printf("Password of %s reset; existing sessions were ended.", *email)
+	if generated {
+		fmt.Println("Password:", secret)
+	}
+	return nil
+}
+
+// runBan блокирует пользователя (роль "banned") и завершает его сессии; с флагом -unban возвращает роль "user".
+// Заблокированный пользователь не может войти, а его посты и комментарии остаются на месте.
+// Пример: ./server ban -email spammer@example.com
+func runBan(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("ban", flag.ContinueOnError)
+	email := fs.String("email", "", "email of the user")
+	unban := fs.Bool("unban", false, "lift the ban")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return fmt.Errorf("ban: -email is required")
+	}
+
+	ctx := context.Background()
+	userID, _, _, role, err := database.GetUserByEmail(ctx, db, *email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("ban: no user with email %s", *email)
+	}
+	if err != nil {
+		return fmt.Errorf("ban: %w", err)
+	}
+
+	if *unban {
+		if role != "banned" {
+			log.Printf("User %s is not banned.", *email)
+			return nil
+		}
+		if err := database.SetUserRole(ctx, db, userID, "user"); err != nil {
+			return fmt.Errorf("ban: %w", err)
+		}
+		log.Printf("User %s unbanned.", *email)
+		return nil
+	}
+	if role == "admin" {
+		return fmt.Errorf("ban: %s is an administrator and cannot be banned", *email)
+	}
+	if err := database.SetUserRole(ctx, db, userID, "banned"); err != nil {
+		return fmt.Errorf("ban: %w", err)
+	}
+	log.Printf("User %s banned; existing sessions were ended.", *email)
+	return nil
+}
+
+// runCompact перестраивает файл базы (VACUUM), возвращая место после удалений, и обновляет статистику запросов.
+// Блокирует запись на время работы, поэтому запускается при остановленном сервере или в окне обслуживания.
+// Пример: ./server compact
+func runCompact(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("compact", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err := database.Vacuum(ctx, db, database.DialectSQLite); err != nil {
+		return fmt.Errorf("compact: %w", err)
+	}
+	if err := database.Optimize(ctx, db, database.DialectSQLite); err != nil {
+		return fmt.Errorf("compact: %w", err)
+	}
+	log.Println("Database compacted.")
+	return nil
+}
+
+// runBoards выводит разделы форума: адрес, число постов, название и модераторов.
+// Пример: ./server boards
+func runBoards(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("boards", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	boards, err := database.ListBoards(ctx, db)
+	if err != nil {
+		return fmt.Errorf("boards: %w", err)
+	}
+	for _, b := range boards {
+		board, err := database.GetBoardBySlug(ctx, db, b.Slug)
+		if err != nil {
+			return fmt.Errorf("boards: %w", err)
+		}
+		fmt.Printf("%-16s %5d posts  %s", b.Slug, b.PostCount, b.Name)
+		if len(board.Moderators) > 0 {
+			fmt.Printf(" (moderators: %s)", strings.Join(board.Moderators, ", "))
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// runCreateBoard создаёт раздел; его страница открывается по адресу /b/{slug}.
+// Пример: ./server create-board -slug travel -name Travel -description "Trips and plans"
+func runCreateBoard(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("create-board", flag.ContinueOnError)
+	slug := fs.String("slug", "", "address of the board in /b/{slug}: lowercase letters, digits and dashes")
+	name := fs.String("name", "", "display name of the board")
+	description := fs.String("description", "", "short description shown on the board page")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !database.ValidBoardSlug(*slug) {
+		return fmt.Errorf("create-board: -slug %q must be 1-32 lowercase letters, digits or dashes", *slug)
+	}
+	if *name == "" {
+		return fmt.Errorf("create-board: -name is required")
+	}
+
+	ctx := context.Background()
+	if _, err := database.GetBoardBySlug(ctx, db, *slug); err == nil {
+		return fmt.Errorf("create-board: board %q already exists", *slug)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("create-board: %w", err)
+	}
+	if _, err := database.CreateBoard(ctx, db, *slug, *name, *description); err != nil {
+		return fmt.Errorf("create-board: %w", err)
+	}
+	log.Printf("Board %s created at /b/%s.", *name, *slug)
+	return nil
+}
+
+// runBoardModerator назначает пользователя модератором раздела; с флагом -remove снимает назначение.
+// Модератор может удалять посты и комментарии своего раздела.
+// Пример: ./server board-moderator -board travel -email user@example.com
+func runBoardModerator(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("board-moderator", flag.ContinueOnError)
+	slug := fs.String("board", "", "address of the board")
+	email := fs.String("email", "", "email of the user")
+	remove := fs.Bool("remove", false, "remove the user from the moderators")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *slug == "" || *email == "" {
+		return fmt.Errorf("board-moderator: -board and -email are required")
+	}
+
+	ctx := context.Background()
+	board, err := database.GetBoardBySlug(ctx, db, *slug)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("board-moderator: no board %q", *slug)
+	}
+	if err != nil {
+		return fmt.Errorf("board-moderator: %w", err)
+	}
+	userID, err := userIDByEmail(ctx, db, *email)
+	if err != nil {
+		return fmt.Errorf("board-moderator: %w", err)
+	}
+	if err := database.SetBoardModerator(ctx, db, board.ID, userID, !*remove); err != nil {
+		return fmt.Errorf("board-moderator: %w", err)
+	}
+	if *remove {
+		log.Printf("User %s no longer moderates /b/%s.", *email, *slug)
+	} else {
+		log.Printf("User %s now moderates /b/%s.", *email, *slug)
+	}
+	return nil
+}
+
+// runDigest выводит в w письмо с подборкой лучших постов недели: заголовок Subject, пустую строку и текст со ссылками.
+// Без -week берётся прошлая неделя. Подборку, которой ещё нет, собирает и сохраняет, как фоновая задача weekly-digest,
+// поэтому письмо совпадает со страницей /digest. Неделя, которая ещё не закончилась, не принимается.
+// Пример: ./server digest -base-url https://forum.example.com | sendmail list@example.com
+func runDigest(db *sql.DB, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("digest", flag.ContinueOnError)
+	week := fs.String("week", "", "Monday of the week (YYYY-MM-DD); empty means last week")
+	baseURL := fs.String("base-url", "http://localhost:8080", "external address of the forum for links")
+	lang := fs.String("lang", i18n.Default, "language of the message")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	start := database.DigestWeek(time.Now()).AddDate(0, 0, -7)
+	if *week != "" {
+		var ok bool
+		if start, ok = database.ParseDigestWeek(*week); !ok {
+			return fmt.Errorf("digest: -week %q must be a Monday in YYYY-MM-DD format", *week)
+		}
+	}
+	if start.AddDate(0, 0, 7).After(time.Now()) {
+		return fmt.Errorf("digest: the week of %s is not over yet", start.Format(database.DigestWeekFormat))
+	}
+
+	d, created, err := database.EnsureDigest(context.Background(), db, start, database.DigestSize)
+	if err != nil {
+		return fmt.Errorf("digest: %w", err)
+	}
+	if created {
+		log.Printf("Weekly digest for %s saved.", d.Week)
+	}
+	return writeDigestEmail(w, d, strings.TrimRight(*baseURL, "/"), *lang)
+}
+
+// writeDigestEmail пишет письмо с подборкой d на языке lang; ссылки строятся от baseURL.
+func writeDigestEmail(w io.Writer, d models.Digest, baseURL, lang string) error {
+	period := i18n.T(lang, "digest.week", d.Start.Format("02.01.2006"), d.End.AddDate(0, 0, -1).Format("02.01.2006"))
+	var b strings.Builder
+	fmt.Fprintf(&b, "Subject: %s: %s\n\n", i18n.T(lang, "digest.title"), period)
+	if len(d.Categories) == 0 {
+		fmt.Fprintln(&b, i18n.T(lang, "digest.no_posts"))
+	}
+	for _, c := range d.Categories {
+		label, ok := i18n.Lookup(lang, "category."+c.Name)
+		if !ok {
+			label = c.Name
+		}
+		fmt.Fprintf(&b, "%s\n\n", label)
+		for i, p := range c.Posts {
+			fmt.Fprintf(&b, "%d. %s — %s (%s, %s)\n   %s/post/%d\n", i+1, p.Title, p.Username,
+				i18n.T(lang, "digest.score", p.Score), i18n.T(lang, "digest.comments", p.Comments), baseURL, p.ID)
+		}
+		fmt.Fprintln(&b)
+	}
+	fmt.Fprintf(&b, "%s/digest/%s\n", baseURL, d.Week)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// userIDByEmail возвращает ID пользователя с указанным email или понятную ошибку, если его нет.
+func userIDByEmail(ctx context.Context, db *sql.DB, email string) (int, error) {
+	userID, _, _, _, err := database.GetUserByEmail(ctx, db, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("no user with email %s", email)
+	}
+	return userID, err
+}
+
+// passwordOrRandom возвращает password, а если он пуст — случайный пароль из 16 символов; generated сообщает,
+// что пароль сгенерирован и его нужно показать администратору.
+func passwordOrRandom(password string) (secret string, generated bool, err error) {
+	if password != "" {
+		return password, false, nil
+	}
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), true, nil
+}
+
+// runReplyAddress печатает подписанный адрес ответа (см. пакет mailreply) для письма-уведомления о посте:
+// ответ на него станет комментарием пользователя с почтой -email. Внешняя рассылка уведомлений ставит адрес
+// в заголовок Reply-To. Без -secret берётся ключ, сохранённый сервером в базе (mail.reply_secret не задан).
+// Пример: ./server reply-address -post 42 -email alice@example.com -domain reply.forum.example.com
+func runReplyAddress(db *sql.DB, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("reply-address", flag.ContinueOnError)
+	postID := fs.Int("post", 0, "ID of the post")
+	email := fs.String("email", "", "email of the user the notification is sent to")
+	domain := fs.String("domain", "", "mail.reply_domain of the server")
+	secret := fs.String("secret", "", "mail.reply_secret of the server; empty means the key stored in the database")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *postID <= 0 || *email == "" || *domain == "" {
+		return fmt.Errorf("reply-address: -post, -email and -domain are required")
+	}
+
+	ctx := context.Background()
+	if *secret == "" {
+		stored, err := database.GetSetting(ctx, db, database.SettingMailReplySecret)
+		if err != nil {
+			return fmt.Errorf("reply-address: %w", err)
+		}
+		if stored == "" {
+			return fmt.Errorf("reply-address: no signing key in the database; start the server with mail.reply_domain set or pass -secret")
+		}
+		*secret = stored
+	}
+	userID, _, _, _, err := database.GetUserByEmail(ctx, db, *email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("reply-address: no user with email %s", *email)
+	}
+	if err != nil {
+		return fmt.Errorf("reply-address: %w", err)
+	}
+	if _, err := database.GetPostOwnerID(ctx, db, *postID); errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("reply-address: no post %d", *postID)
+	} else if err != nil {
+		return fmt.Errorf("reply-address: %w", err)
+	}
+	a := &mailreply.Addresses{Secret: []byte(*secret), Domain: *domain}
+	_, err = fmt.Fprintln(w, a.For(*postID, userID))
+	return err
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"forum/config"
+	"forum/database"
+)
+
+// TestAdminCommands проверяет создание администратора, сброс пароля, блокировку и разделы через подкоманды.
+func TestAdminCommands(t *testing.T) {
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "forum.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	role := func(email string) string {
+		t.Helper()
+		_, _, _, role, err := database.GetUserByEmail(ctx, db, email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return role
+	}
+
+	if err := runCommand(config.Default(), db, []string{"create-admin", "-email", "admin@example.com"}); err == nil {
+		t.Error("create-admin without -username must fail for a new email")
+	}
+	// Имя нового администратора проверяется теми же правилами, что и при регистрации.
+	for _, name := range []string{"admin", "x", "bad name!"} {
+		if err := runCommand(config.Default(), db, []string{"create-admin", "-email", "admin@example.com", "-username", name, "-password", "first"}); err == nil {
+			t.Errorf("create-admin with username %q must fail", name)
+		}
+	}
+	if err := runCommand(config.Default(), db, []string{"create-admin", "-email", "admin@example.com", "-username", "keeper", "-password", "first"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := role("admin@example.com"); got != "admin" {
+		t.Fatalf("role = %q, want admin", got)
+	}
+	if err := runCommand(config.Default(), db, []string{"ban", "-email", "admin@example.com"}); err == nil {
+		t.Error("banning an administrator must fail")
+	}
+
+	if err := runCommand(config.Default(), db, []string{"reset-password", "-email", "admin@example.com", "-password", "second"}); err != nil {
+		t.Fatal(err)
+	}
+	_, _, hash, _, err := database.GetUserByEmail(ctx, db, "admin@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte("second")) != nil {
+		t.Error("password was not reset")
+	}
+
+	if err := database.RegisterUser(ctx, db, "user@example.com", "user", hash); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCommand(config.Default(), db, []string{"ban", "-email", "user@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := role("user@example.com"); got != "banned" {
+		t.Fatalf("role = %q, want banned", got)
+	}
+	if err := runCommand(config.Default(), db, []string{"ban", "-email", "user@example.com", "-unban"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := role("user@example.com"); got != "user" {
+		t.Fatalf("role = %q, want user", got)
+	}
+
+	if err := runCommand(config.Default(), db, []string{"create-board", "-slug", "Bad Slug", "-name", "Bad"}); err == nil {
+		t.Error("create-board must reject an invalid slug")
+	}
+	if err := runCommand(config.Default(), db, []string{"create-board", "-slug", "travel", "-name", "Travel"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCommand(config.Default(), db, []string{"board-moderator", "-board", "travel", "-email", "user@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	board, err := database.GetBoardBySlug(ctx, db, "travel")
+	if err != nil || len(board.Moderators) != 1 || board.Moderators[0] != "user" {
+		t.Fatalf("board = %+v, %v", board, err)
+	}
+
+	if err := runCommand(config.Default(), db, []string{"compact"}); err != nil {
+		t.Fatal(err)
+	}
+}
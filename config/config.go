@@ -0,0 +1,656 @@
+// Package config собирает настройки сервера из значений по умолчанию, необязательного YAML-файла
+// и переменных окружения FORUM_*. Переменные окружения имеют приоритет над файлом.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"os"
+	"strings"
+	"time"
+
+	"forum/jobs"
+	"forum/proxy"
+	"forum/screening"
+	"forum/summarize"
+	"forum/translate"
+	"forum/usernames"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile — файл настроек, который читается, если FORUM_CONFIG не задан и файл существует.
+const DefaultFile = "forum.yaml"
+
+// Config — настройки сервера.
+type Config struct {
+	Server       Server       `yaml:"server"`
+	Database     Database     `yaml:"database"`
+	Cache        Cache        `yaml:"cache"`
+	Security     Security     `yaml:"security"`
+	Session      Session      `yaml:"session"`
+	Registration Registration `yaml:"registration"`
+	Usernames    Usernames    `yaml:"usernames"`
+	OAuth        OAuth        `yaml:"oauth"`
+	Jobs         Jobs         `yaml:"jobs"`
+	Backup       Backup       `yaml:"backup"`
+	Integrations Integrations `yaml:"integrations"`
+	Mail         Mail         `yaml:"mail"`
+	AccessLog    AccessLog    `yaml:"access_log"`
+	Privileges   Privileges   `yaml:"privileges"`
+	Trust        Trust        `yaml:"trust"`
+	Images       Images       `yaml:"images"`
+	Uploads      Uploads      `yaml:"uploads"`
+	Export       Export       `yaml:"export"`
+	Translation  Translation  `yaml:"translation"`
+	Screening    Screening    `yaml:"screening"`
+	Reports      Reports      `yaml:"reports"`
+	Summary      Summary      `yaml:"summary"`
+}
+
+// Server — параметры HTTP-сервера.
+type Server struct {
+	Addr         string `yaml:"addr"`          // адрес прослушивания, например ":8080"
+	BaseURL      string `yaml:"base_url"`      // внешний адрес форума для ссылок в уведомлениях
+	TemplatesDir string `yaml:"templates_dir"` // каталог HTML-шаблонов на диске; пусто — встроенные в бинарный файл
+	StaticDir    string `yaml:"static_dir"`    // каталог статических файлов на диске; пусто — встроенные
+	DevReload    bool   `yaml:"dev_reload"`    // перечитывать изменённые шаблоны из templates_dir без перезапуска
+
+	// ShutdownTimeout ограничивает время остановки: ожидание текущих запросов и фоновых задач.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	Timeouts Timeouts `yaml:"timeouts"`
+
+	TLS TLS `yaml:"tls"`
+
+	// TrustedProxies — адреса и подсети обратных прокси, чьим заголовкам X-Forwarded-* можно доверять.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// Timeouts — ограничения времени соединений и обработки запросов. Page, Upload и Long задают
+// время обработки для классов маршрутов: обычные страницы и API, отправка форм с содержимым
+// и долгие служебные операции администратора (резервная копия, проверка целостности).
+type Timeouts struct {
+	ReadHeader time.Duration `yaml:"read_header"` // чтение заголовков запроса (защита от slowloris)
+	Idle       time.Duration `yaml:"idle"`        // простой keep-alive соединения между запросами
+	Page       time.Duration `yaml:"page"`
+	Upload     time.Duration `yaml:"upload"`
+	Long       time.Duration `yaml:"long"`
+}
+
+// TLS — HTTPS без отдельного обратного прокси. Сертификат берётся из файлов cert_file и key_file
+// либо выпускается Let's Encrypt для autocert_domains. Без них сервер работает по HTTP.
+type TLS struct {
+	CertFile         string   `yaml:"cert_file"`
+	KeyFile          string   `yaml:"key_file"`
+	AutocertDomains  []string `yaml:"autocert_domains"`
+	AutocertCacheDir string   `yaml:"autocert_cache_dir"` // каталог выпущенных сертификатов и ключа аккаунта
+	AutocertEmail    string   `yaml:"autocert_email"`     // адрес для уведомлений Let's Encrypt; необязателен
+	RedirectAddr     string   `yaml:"redirect_addr"`      // HTTP-адрес, перенаправляющий на HTTPS; пусто или off — не слушать
+}
+
+// Enabled сообщает, включён ли HTTPS.
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || len(t.AutocertDomains) > 0
+}
+
+// Database — подключение к базе данных. Незаданные (nil) параметры пула и SQLite
+// берутся из значений по умолчанию пакета database, которые зависят от драйвера.
+type Database struct {
+	Driver             string         `yaml:"driver"` // sqlite или mysql
+	Path               string         `yaml:"path"`   // файл базы SQLite
+	DSN                string         `yaml:"dsn"`    // строка подключения MySQL/MariaDB
+	MaxOpenConns       *int           `yaml:"max_open_conns"`
+	MaxIdleConns       *int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime    *time.Duration `yaml:"conn_max_lifetime"`
+	SlowQueryThreshold *time.Duration `yaml:"slow_query_threshold"` // 0 отключает журнал медленных запросов
+	SQLite             SQLite         `yaml:"sqlite"`
+}
+
+// SQLite — параметры соединений SQLite.
+type SQLite struct {
+	Synchronous string         `yaml:"synchronous"` // OFF, NORMAL, FULL или EXTRA
+	CacheSize   int            `yaml:"cache_size"`  // страницы, или КиБ при отрицательном значении; 0 — по умолчанию SQLite
+	BusyTimeout *time.Duration `yaml:"busy_timeout"`
+}
+
+// Cache — кэш готовых фрагментов страниц и запросов.
+type Cache struct {
+	Backend  string        `yaml:"backend"`   // memory или redis
+	RedisURL string        `yaml:"redis_url"` // адрес Redis для backend: redis
+	TTL      time.Duration `yaml:"ttl"`       // 0 отключает кэш
+}
+
+// Security — заголовки безопасности ответов.
+type Security struct {
+	// ImageHosts — источники изображений постов в Content-Security-Policy (например, https://i.imgur.com).
+	// Пусто — разрешены любые адреса https, так как посты ссылаются на внешние изображения.
+	ImageHosts []string `yaml:"image_hosts"`
+	// ContentSecurityPolicy полностью заменяет политику по умолчанию; "off" отключает заголовок.
+	ContentSecurityPolicy string        `yaml:"content_security_policy"`
+	HSTSMaxAge            time.Duration `yaml:"hsts_max_age"` // Strict-Transport-Security для HTTPS; 0 отключает
+}
+
+// Session — сессии пользователей.
+type Session struct {
+	Lifetime        time.Duration `yaml:"lifetime"`         // срок действия сессии и cookie
+	CleanupInterval time.Duration `yaml:"cleanup_interval"` // как часто удаляются истёкшие сессии
+}
+
+// Registration — защита формы регистрации от программ (см. пакет botcheck).
+type Registration struct {
+	MinFillTime       time.Duration `yaml:"min_fill_time"`      // раньше этого после показа форма считается отправленной программой
+	FormMaxAge        time.Duration `yaml:"form_max_age"`       // позже этого форму нужно открыть заново
+	FormSecret        string        `yaml:"form_secret"`        // ключ подписи меток формы; пусто — создаётся и хранится в базе
+	BlockDisposable   bool          `yaml:"block_disposable"`   // отклонять адреса одноразовой почты
+	DisposableDomains []string      `yaml:"disposable_domains"` // домены в дополнение к встроенному списку
+	InviteLimit       int           `yaml:"invite_limit"`       // сколько приглашений может создать пользователь; у администраторов без ограничений
+
+	// VerifyEmail включает подтверждение почты: новый пользователь получает письмо со ссылкой
+	// и не может публиковать посты и комментарии, пока не откроет её. Нужна отправка писем (mail.smtp_addr).
+	VerifyEmail   bool          `yaml:"verify_email"`
+	VerifyLinkAge time.Duration `yaml:"verify_link_age"` // срок действия ссылки подтверждения
+	VerifySecret  string        `yaml:"verify_secret"`   // ключ подписи ссылок; пусто — создаётся и хранится в базе
+
+	// ResetLinkAge — срок действия ссылки сброса пароля из письма. Сброс доступен, если настроена отправка писем.
+	ResetLinkAge time.Duration `yaml:"reset_link_age"`
+}
+
+// Usernames — правила имён пользователей при регистрации и смене имени (см. пакет usernames).
+type Usernames struct {
+	MinLength int      `yaml:"min_length"` // длина в символах
+	MaxLength int      `yaml:"max_length"`
+	Charset   string   `yaml:"charset"`   // класс регулярного выражения, которому должен подходить каждый символ
+	Reserved  []string `yaml:"reserved"`  // зарезервированные имена в дополнение к встроенному списку
+	Profanity []string `yaml:"profanity"` // бранные слова в дополнение к встроенному списку
+}
+
+// OAuth — вход через внешних провайдеров (см. пакет oauth). Адрес возврата OAuth-приложения GitHub —
+// {server.base_url}/auth/github/callback.
+type OAuth struct {
+	GitHubClientID     string `yaml:"github_client_id"` // пусто — вход через GitHub выключен
+	GitHubClientSecret string `yaml:"github_client_secret"`
+}
+
+// Jobs — расписание фоновых задач.
+type Jobs struct {
+	PurgeInterval       time.Duration `yaml:"purge_interval"`
+	DeletedRetention    time.Duration `yaml:"deleted_retention"` // срок хранения удалённых постов и комментариев
+	ReconcileInterval   time.Duration `yaml:"reconcile_interval"`
+	MaintenanceInterval time.Duration `yaml:"maintenance_interval"`
+	MaintenanceWindow   string        `yaml:"maintenance_window"` // например "03:00-05:00"; пусто — без окна
+	MaintenanceVacuum   bool          `yaml:"maintenance_vacuum"` // VACUUM внутри окна обслуживания
+	DigestInterval      time.Duration `yaml:"digest_interval"`    // как часто проверяется, подведены ли итоги прошлой недели
+	HistoryRetention    time.Duration `yaml:"history_retention"`  // срок хранения истории просмотров постов
+	// NotificationInterval — как часто лайки прошедших дней собираются в уведомления авторам.
+	NotificationInterval time.Duration `yaml:"notification_interval"`
+	// UploadGCInterval — как часто ищутся загруженные изображения, на которые не ссылается ни один пост или комментарий.
+	UploadGCInterval time.Duration `yaml:"upload_gc_interval"`
+	UploadGrace      time.Duration `yaml:"upload_grace"` // сколько хранится ненужный загруженный файл, прежде чем его удалить
+}
+
+// Backup — резервные копии SQLite по расписанию.
+type Backup struct {
+	Dir      string        `yaml:"dir"` // пусто — копии по расписанию не создаются
+	Interval time.Duration `yaml:"interval"`
+	Keep     int           `yaml:"keep"` // 0 — хранить все копии
+}
+
+// Integrations — уведомления о новых постах. Пустой список категорий означает все категории.
+type Integrations struct {
+	DiscordWebhookURL  string   `yaml:"discord_webhook_url"`
+	DiscordCategories  []string `yaml:"discord_categories"`
+	TelegramBotToken   string   `yaml:"telegram_bot_token"`
+	TelegramChatID     string   `yaml:"telegram_chat_id"`
+	TelegramCategories []string `yaml:"telegram_categories"`
+}
+
+// Mail — отправка писем форума через SMTP и приём ответов на письма-уведомления комментариями (см. пакет mailreply).
+// Почтовый сервис пересылает письма, пришедшие на адреса ответа, вебхуком на /inbound/email/{inbound_token}.
+type Mail struct {
+	ReplyDomain string `yaml:"reply_domain"` // домен адресов ответа; пусто — приём ответов выключен
+	// ReplySecret — ключ подписи адресов ответа. Пусто — ключ создаётся один раз и хранится в базе.
+	ReplySecret  string `yaml:"reply_secret"`
+	InboundToken string `yaml:"inbound_token"` // секрет в адресе вебхука, известный только почтовому сервису
+
+	// Отправка писем форума (см. пакет mailer).
+	SMTPAddr     string        `yaml:"smtp_addr"` // host:port SMTP-сервера; пусто — письма не отправляются
+	SMTPUsername string        `yaml:"smtp_username"`
+	SMTPPassword string        `yaml:"smtp_password"`
+	From         string        `yaml:"from"`         // адрес отправителя, можно с именем
+	SendTimeout  time.Duration `yaml:"send_timeout"` // наибольшее время отправки одного письма
+}
+
+// AccessLog — журнал запросов для анализа трафика, отдельный от журнала приложения.
+type AccessLog struct {
+	Path    string        `yaml:"path"`     // файл журнала; пусто — журнал не ведётся
+	Format  string        `yaml:"format"`   // combined (формат Apache) или json
+	MaxSize int           `yaml:"max_size"` // размер файла в МиБ, после которого он ротируется; 0 — без ограничения
+	Rotate  time.Duration `yaml:"rotate"`   // интервал ротации по времени, например 24h; 0 — только по размеру
+	Keep    int           `yaml:"keep"`     // сколько ротированных файлов хранить; 0 — все
+}
+
+// Privileges — пороги кармы (рейтинга постов и комментариев автора), открывающие действия,
+// и ограничения новых аккаунтов. 0 отключает соответствующую проверку, кроме NewAccountLinks:
+// 0 запрещает новым аккаунтам внешние ссылки. Новые аккаунты не могут добавлять изображения.
+type Privileges struct {
+	ImageKarma                int           `yaml:"image_karma"`                   // изображение в посте
+	DownvoteKarma             int           `yaml:"downvote_karma"`                // дизлайки постов и комментариев
+	NewAccountAge             time.Duration `yaml:"new_account_age"`               // аккаунт моложе этого срока считается новым
+	NewAccountLinks           int           `yaml:"new_account_links"`             // внешних ссылок в посте или комментарии нового аккаунта
+	NewAccountPostsPerHour    int           `yaml:"new_account_posts_per_hour"`    // постов нового аккаунта в час
+	NewAccountCommentsPerHour int           `yaml:"new_account_comments_per_hour"` // комментариев нового аккаунта в час
+	VotesPerHour              int           `yaml:"votes_per_hour"`                // лайков и дизлайков в час для всех, кроме администраторов
+	NewAccountVotesPerHour    int           `yaml:"new_account_votes_per_hour"`    // лайков и дизлайков нового аккаунта в час
+}
+
+// Trust — уровни доверия (см. permissions.TrustLevel): с какой активности пользователь получает уровень и какие
+// права на нём. На уровне new — аккаунты моложе privileges.new_account_age и все, кто ещё не выполнил условий basic;
+// к ним относятся ограничения новых аккаунтов из privileges. Условия уровня new не проверяются, нулевое условие тоже.
+type Trust struct {
+	New     TrustLevel `yaml:"new"`
+	Basic   TrustLevel `yaml:"basic"`
+	Member  TrustLevel `yaml:"member"`
+	Regular TrustLevel `yaml:"regular"`
+}
+
+// TrustLevel — условия и права одного уровня доверия.
+type TrustLevel struct {
+	Age        time.Duration `yaml:"age"`         // возраст аккаунта
+	Read       int           `yaml:"read"`        // прочитанных постов
+	Posts      int           `yaml:"posts"`       // своих постов
+	Comments   int           `yaml:"comments"`    // своих комментариев
+	Karma      int           `yaml:"karma"`       // кармы
+	EditWindow time.Duration `yaml:"edit_window"` // сколько после публикации можно править свой пост; 0 — без ограничения
+	FlagWeight int           `yaml:"flag_weight"` // вес жалобы на пост (см. reports)
+}
+
+// Images — проверка адресов изображений в постах и прокси изображений. Списки разрешённых и запрещённых
+// доменов ведёт администратор в панели (см. database.SettingImageAllowDomains).
+type Images struct {
+	Verify        bool          `yaml:"verify"`         // проверять запросом HEAD, что адрес отвечает изображением
+	VerifyTimeout time.Duration `yaml:"verify_timeout"` // наибольшее время ожидания ответа при проверке
+
+	// Proxy включает прокси /img: браузеры получают изображения постов от форума, а не от внешних хостов.
+	Proxy bool `yaml:"proxy"`
+	// ProxySecret — ключ подписи адресов прокси. Пусто — ключ создаётся один раз и хранится в базе.
+	ProxySecret       string        `yaml:"proxy_secret"`
+	ProxyMaxSize      int           `yaml:"proxy_max_size"`      // наибольший размер изображения в МиБ
+	ProxyTimeout      time.Duration `yaml:"proxy_timeout"`       // наибольшее время загрузки изображения
+	ProxyCacheTTL     time.Duration `yaml:"proxy_cache_ttl"`     // срок хранения изображения в кэше сервера и браузера
+	ProxyCacheEntries int           `yaml:"proxy_cache_entries"` // сколько изображений хранится в памяти; 0 — без кэша
+}
+
+// Uploads — изображения, которые пользователи загружают с постами вместо адреса (см. пакет storage).
+type Uploads struct {
+	Backend string `yaml:"backend"`  // local или s3
+	MaxSize int    `yaml:"max_size"` // наибольший размер файла в МиБ; 0 выключает загрузку
+	Dir     string `yaml:"dir"`      // каталог файлов для backend: local
+	S3      S3     `yaml:"s3"`
+}
+
+// S3 — S3-совместимое хранилище загруженных файлов (Amazon S3, MinIO, Cloudflare R2).
+type S3 struct {
+	Endpoint  string `yaml:"endpoint"` // адрес API, например https://s3.eu-central-1.amazonaws.com
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	PublicURL string `yaml:"public_url"` // адрес публичного бакета или CDN; пусто — файлы отдаёт форум
+}
+
+// Export — выгрузка постов в PDF на /post/{id}/export?format=pdf внешним конвертером (см. пакет htmlpdf).
+// Без конвертера доступна только печатная HTML-версия.
+type Export struct {
+	PDFCommand string        `yaml:"pdf_command"` // программа HTML → PDF через stdin/stdout, например "wkhtmltopdf --quiet - -"
+	PDFURL     string        `yaml:"pdf_url"`     // адрес сервиса Gotenberg, например http://gotenberg:3000
+	PDFTimeout time.Duration `yaml:"pdf_timeout"` // наибольшее время создания одного PDF
+}
+
+// Translation — машинный перевод постов кнопкой «Перевести» (см. пакет translate).
+type Translation struct {
+	Provider string        `yaml:"provider"` // deepl или libretranslate; пусто — перевод выключен
+	URL      string        `yaml:"url"`      // адрес сервиса; для DeepL по умолчанию api-free.deepl.com
+	APIKey   string        `yaml:"api_key"`  // ключ API; для LibreTranslate нужен не всем серверам
+	Timeout  time.Duration `yaml:"timeout"`  // наибольшее время ожидания перевода
+}
+
+// Screening — автоматическая проверка новых постов и комментариев (см. пакет screening).
+// Содержимое с оценкой не ниже threshold скрывается до решения модератора в /moderation.
+type Screening struct {
+	Provider  string        `yaml:"provider"`  // openai или http; пусто — проверка выключена
+	URL       string        `yaml:"url"`       // адрес сервиса; для openai по умолчанию api.openai.com
+	APIKey    string        `yaml:"api_key"`   // ключ API (Bearer); для своего сервиса необязателен
+	Model     string        `yaml:"model"`     // модель модерации OpenAI; пусто — по умолчанию сервиса
+	Threshold float64       `yaml:"threshold"` // оценка от 0 до 1, с которой содержимое скрывается
+	Timeout   time.Duration `yaml:"timeout"`   // наибольшее время проверки; по его истечении содержимое публикуется
+}
+
+// Reports — жалобы читателей на посты. Пост, суммарный вес жалоб на который по одной причине достиг порога,
+// скрывается до решения модератора в /moderation; вес жалобы зависит от уровня доверия (trust.*.flag_weight).
+// Порог 0 — жалобы по этой причине пост не скрывают.
+type Reports struct {
+	Spam     int `yaml:"spam"`      // спам и реклама
+	Abuse    int `yaml:"abuse"`     // оскорбления и травля
+	OffTopic int `yaml:"off_topic"` // не по теме раздела
+	Illegal  int `yaml:"illegal"`   // незаконное содержимое
+}
+
+// Summary — сводки длинных обсуждений языковой моделью (см. пакет summarize).
+// Кнопка «Кратко об обсуждении» появляется у постов, где комментариев не меньше min_comments.
+type Summary struct {
+	Provider    string        `yaml:"provider"`     // openai (и совместимые серверы) или ollama; пусто — сводки выключены
+	URL         string        `yaml:"url"`          // адрес сервиса; по умолчанию api.openai.com или localhost:11434
+	APIKey      string        `yaml:"api_key"`      // ключ API (Bearer); локальным серверам не нужен
+	Model       string        `yaml:"model"`        // модель; для openai по умолчанию gpt-4o-mini
+	MinComments int           `yaml:"min_comments"` // с какого числа комментариев обсуждение считается длинным
+	Timeout     time.Duration `yaml:"timeout"`      // наибольшее время ожидания сводки
+}
+
+// Default возвращает настройки по умолчанию.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:            ":8080",
+			BaseURL:         "http://localhost:8080",
+			ShutdownTimeout: 30 * time.Second,
+			Timeouts: Timeouts{
+				ReadHeader: 10 * time.Second,
+				Idle:       2 * time.Minute,
+				Page:       15 * time.Second,
+				Upload:     2 * time.Minute,
+				Long:       10 * time.Minute,
+			},
+			TLS: TLS{AutocertCacheDir: "certs", RedirectAddr: ":80"},
+		},
+		Database: Database{Driver: "sqlite", Path: "./forum.db"},
+		Cache: Cache{
+			Backend:  "memory",
+			RedisURL: "redis://localhost:6379/0",
+			TTL:      30 * time.Second,
+		},
+		Security: Security{HSTSMaxAge: 180 * 24 * time.Hour},
+		Session:  Session{Lifetime: 24 * time.Hour, CleanupInterval: time.Hour},
+		Registration: Registration{
+			MinFillTime:     3 * time.Second,
+			FormMaxAge:      24 * time.Hour,
+			BlockDisposable: true,
+			InviteLimit:     5,
+			VerifyLinkAge:   48 * time.Hour,
+			ResetLinkAge:    time.Hour,
+		},
+		Usernames: Usernames{
+			MinLength: 3,
+			MaxLength: 30,
+			Charset:   `[\p{L}\p{N}_.-]`,
+		},
+		Jobs: Jobs{
+			PurgeInterval:        24 * time.Hour,
+			DeletedRetention:     30 * 24 * time.Hour,
+			ReconcileInterval:    24 * time.Hour,
+			MaintenanceInterval:  24 * time.Hour,
+			DigestInterval:       time.Hour,
+			HistoryRetention:     90 * 24 * time.Hour,
+			NotificationInterval: time.Hour,
+			UploadGCInterval:     24 * time.Hour,
+			UploadGrace:          24 * time.Hour,
+		},
+		Backup:    Backup{Interval: 24 * time.Hour, Keep: 7},
+		Mail:      Mail{SendTimeout: 10 * time.Second},
+		AccessLog: AccessLog{Format: "combined", MaxSize: 100, Rotate: 24 * time.Hour, Keep: 14},
+		Privileges: Privileges{
+			ImageKarma:                50,
+			DownvoteKarma:             20,
+			NewAccountAge:             72 * time.Hour,
+			NewAccountPostsPerHour:    3,
+			NewAccountCommentsPerHour: 10,
+			VotesPerHour:              120,
+			NewAccountVotesPerHour:    30,
+		},
+		Trust: Trust{
+			New:     TrustLevel{EditWindow: time.Hour, FlagWeight: 1},
+			Basic:   TrustLevel{Read: 5, EditWindow: 24 * time.Hour, FlagWeight: 1},
+			Member:  TrustLevel{Age: 30 * 24 * time.Hour, Read: 30, Comments: 10, Karma: 10, FlagWeight: 2},
+			Regular: TrustLevel{Age: 90 * 24 * time.Hour, Read: 100, Posts: 5, Comments: 50, Karma: 50, FlagWeight: 3},
+		},
+		Images: Images{
+			VerifyTimeout:     5 * time.Second,
+			Proxy:             true,
+			ProxyMaxSize:      5,
+			ProxyTimeout:      10 * time.Second,
+			ProxyCacheTTL:     24 * time.Hour,
+			ProxyCacheEntries: 100,
+		},
+		Uploads:     Uploads{Backend: "local", MaxSize: 5, Dir: "uploads"},
+		Export:      Export{PDFTimeout: 10 * time.Second},
+		Translation: Translation{Timeout: 10 * time.Second},
+		Screening:   Screening{Threshold: 0.8, Timeout: 5 * time.Second},
+		Reports:     Reports{Spam: 3, Abuse: 3, OffTopic: 5, Illegal: 2},
+		Summary:     Summary{MinComments: 20, Timeout: time.Minute},
+	}
+}
+
+// Path возвращает путь к файлу настроек: FORUM_CONFIG, иначе DefaultFile, если он существует, иначе пустую строку.
+func Path() string {
+	if path := os.Getenv("FORUM_CONFIG"); path != "" {
+		return path
+	}
+	if _, err := os.Stat(DefaultFile); err == nil {
+		return DefaultFile
+	}
+	return ""
+}
+
+// Load возвращает настройки по умолчанию, дополненные файлом path (если он задан) и переменными окружения,
+// и проверяет результат. Неизвестные ключи файла и некорректные значения считаются ошибкой.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("config: %w", err)
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return Config{}, fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+	if err := applyEnv(&cfg); err != nil {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Validate проверяет настройки и приводит синонимы (драйверы sqlite3 и mariadb, redirect_addr: off) к основному виду.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr must not be empty")
+	check(!c.Server.DevReload || c.Server.TemplatesDir != "", "server.dev_reload requires server.templates_dir")
+	if c.Server.TLS.RedirectAddr == "off" {
+		c.Server.TLS.RedirectAddr = ""
+	}
+	tls := c.Server.TLS
+	check((tls.CertFile == "") == (tls.KeyFile == ""), "server.tls.cert_file and server.tls.key_file must be set together")
+	check(tls.CertFile == "" || len(tls.AutocertDomains) == 0, "server.tls: use either certificate files or autocert_domains, not both")
+	check(len(tls.AutocertDomains) == 0 || tls.AutocertCacheDir != "", "server.tls.autocert_cache_dir is required for autocert")
+	check(!tls.Enabled() || tls.RedirectAddr != c.Server.Addr, "server.tls.redirect_addr must differ from server.addr")
+	if err := proxy.Validate(c.Server.TrustedProxies); err != nil {
+		check(false, "server.trusted_proxies: %v", err)
+	}
+
+	switch c.Database.Driver {
+	case "", "sqlite", "sqlite3":
+		c.Database.Driver = "sqlite"
+		check(c.Database.Path != "", "database.path is required for the sqlite driver")
+	case "mysql", "mariadb":
+		c.Database.Driver = "mysql"
+		check(c.Database.DSN != "", "database.dsn is required for the mysql driver")
+	default:
+		check(false, "unknown database.driver %q (available: sqlite, mysql)", c.Database.Driver)
+	}
+	check(c.Database.MaxOpenConns == nil || *c.Database.MaxOpenConns >= 0, "database.max_open_conns must not be negative")
+	check(c.Database.MaxIdleConns == nil || *c.Database.MaxIdleConns >= 0, "database.max_idle_conns must not be negative")
+	for _, d := range []struct {
+		name  string
+		value *time.Duration
+	}{
+		{"database.conn_max_lifetime", c.Database.ConnMaxLifetime},
+		{"database.slow_query_threshold", c.Database.SlowQueryThreshold},
+		{"database.sqlite.busy_timeout", c.Database.SQLite.BusyTimeout},
+	} {
+		check(d.value == nil || *d.value >= 0, "%s must not be negative", d.name)
+	}
+
+	check(c.Cache.Backend == "memory" || c.Cache.Backend == "redis", "unknown cache.backend %q (available: memory, redis)", c.Cache.Backend)
+	check(c.Cache.TTL >= 0, "cache.ttl must not be negative")
+
+	for _, host := range c.Security.ImageHosts {
+		check(host != "" && !strings.ContainsAny(host, " ;,'\""), "invalid security.image_hosts entry %q", host)
+	}
+	check(c.Security.HSTSMaxAge >= 0, "security.hsts_max_age must not be negative")
+
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+		{"server.timeouts.read_header", c.Server.Timeouts.ReadHeader},
+		{"server.timeouts.idle", c.Server.Timeouts.Idle},
+		{"server.timeouts.page", c.Server.Timeouts.Page},
+		{"server.timeouts.upload", c.Server.Timeouts.Upload},
+		{"server.timeouts.long", c.Server.Timeouts.Long},
+		{"session.lifetime", c.Session.Lifetime},
+		{"session.cleanup_interval", c.Session.CleanupInterval},
+		{"registration.form_max_age", c.Registration.FormMaxAge},
+		{"registration.verify_link_age", c.Registration.VerifyLinkAge},
+		{"registration.reset_link_age", c.Registration.ResetLinkAge},
+		{"jobs.purge_interval", c.Jobs.PurgeInterval},
+		{"jobs.deleted_retention", c.Jobs.DeletedRetention},
+		{"jobs.reconcile_interval", c.Jobs.ReconcileInterval},
+		{"jobs.maintenance_interval", c.Jobs.MaintenanceInterval},
+		{"jobs.digest_interval", c.Jobs.DigestInterval},
+		{"jobs.history_retention", c.Jobs.HistoryRetention},
+		{"jobs.notification_interval", c.Jobs.NotificationInterval},
+		{"jobs.upload_gc_interval", c.Jobs.UploadGCInterval},
+		{"jobs.upload_grace", c.Jobs.UploadGrace},
+		{"backup.interval", c.Backup.Interval},
+	} {
+		check(d.value > 0, "%s must be positive", d.name)
+	}
+	if c.Jobs.MaintenanceWindow != "" {
+		_, err := jobs.ParseWindow(c.Jobs.MaintenanceWindow)
+		check(err == nil, "jobs.maintenance_window: %v", err)
+	}
+	check(c.Backup.Keep >= 0, "backup.keep must not be negative")
+	check(c.Registration.MinFillTime >= 0, "registration.min_fill_time must not be negative")
+	check(c.Registration.MinFillTime < c.Registration.FormMaxAge, "registration.min_fill_time must be shorter than registration.form_max_age")
+	check(c.Registration.InviteLimit >= 0, "registration.invite_limit must not be negative")
+	check(!c.Registration.VerifyEmail || c.Mail.SMTPAddr != "", "mail.smtp_addr is required with registration.verify_email")
+	check(c.Usernames.MinLength > 0, "usernames.min_length must be positive")
+	check(c.Usernames.MaxLength >= c.Usernames.MinLength, "usernames.max_length must not be less than usernames.min_length")
+	_, err := usernames.New(c.Usernames.MinLength, c.Usernames.MaxLength, c.Usernames.Charset, nil, nil)
+	check(err == nil, "invalid usernames.charset %q", c.Usernames.Charset)
+	check(c.OAuth.GitHubClientID == "" || c.OAuth.GitHubClientSecret != "", "oauth.github_client_secret is required with oauth.github_client_id")
+
+	check(c.AccessLog.Format == "combined" || c.AccessLog.Format == "json", "unknown access_log.format %q (available: combined, json)", c.AccessLog.Format)
+	check(c.AccessLog.MaxSize >= 0, "access_log.max_size must not be negative")
+	check(c.AccessLog.Rotate >= 0, "access_log.rotate must not be negative")
+	check(c.AccessLog.Keep >= 0, "access_log.keep must not be negative")
+
+	check(c.Uploads.Backend == "local" || c.Uploads.Backend == "s3", "unknown uploads.backend %q (available: local, s3)", c.Uploads.Backend)
+	check(c.Uploads.MaxSize >= 0, "uploads.max_size must not be negative")
+	check(c.Uploads.Backend != "local" || c.Uploads.Dir != "", "uploads.dir is required for uploads.backend: local")
+	check(c.Uploads.Backend != "s3" || c.Uploads.S3.Endpoint != "" && c.Uploads.S3.Bucket != "", "uploads.s3.endpoint and uploads.s3.bucket are required for uploads.backend: s3")
+
+	check(c.Privileges.ImageKarma >= 0, "privileges.image_karma must not be negative")
+	check(c.Privileges.DownvoteKarma >= 0, "privileges.downvote_karma must not be negative")
+	check(c.Privileges.NewAccountAge >= 0, "privileges.new_account_age must not be negative")
+	check(c.Privileges.NewAccountLinks >= 0, "privileges.new_account_links must not be negative")
+	check(c.Privileges.NewAccountPostsPerHour >= 0, "privileges.new_account_posts_per_hour must not be negative")
+	check(c.Privileges.NewAccountCommentsPerHour >= 0, "privileges.new_account_comments_per_hour must not be negative")
+	check(c.Privileges.VotesPerHour >= 0, "privileges.votes_per_hour must not be negative")
+	check(c.Privileges.NewAccountVotesPerHour >= 0, "privileges.new_account_votes_per_hour must not be negative")
+	for _, l := range []struct {
+		name  string
+		level TrustLevel
+	}{{"new", c.Trust.New}, {"basic", c.Trust.Basic}, {"member", c.Trust.Member}, {"regular", c.Trust.Regular}} {
+		check(l.level.Age >= 0 && l.level.Read >= 0 && l.level.Posts >= 0 && l.level.Comments >= 0 && l.level.EditWindow >= 0,
+			"trust.%s requirements and edit_window must not be negative", l.name)
+		check(l.level.FlagWeight >= 1, "trust.%s.flag_weight must be at least 1", l.name)
+	}
+	if c.Mail.SMTPAddr != "" {
+		_, _, err := net.SplitHostPort(c.Mail.SMTPAddr)
+		check(err == nil, "invalid mail.smtp_addr %q: want host:port", c.Mail.SMTPAddr)
+		_, err = mail.ParseAddress(c.Mail.From)
+		check(err == nil, "invalid mail.from %q", c.Mail.From)
+		check(c.Mail.SendTimeout > 0, "mail.send_timeout must be positive")
+	}
+	if c.Mail.ReplyDomain != "" {
+		check(!strings.ContainsAny(c.Mail.ReplyDomain, "@ /"), "invalid mail.reply_domain %q", c.Mail.ReplyDomain)
+		check(len(c.Mail.InboundToken) >= 16, "mail.inbound_token of at least 16 characters is required when mail.reply_domain is set")
+	}
+	check(!c.Images.Verify || c.Images.VerifyTimeout > 0, "images.verify_timeout must be positive when images.verify is on")
+	if c.Images.Proxy {
+		check(c.Images.ProxyMaxSize > 0, "images.proxy_max_size must be positive")
+		check(c.Images.ProxyTimeout > 0, "images.proxy_timeout must be positive")
+		check(c.Images.ProxyCacheTTL >= 0, "images.proxy_cache_ttl must not be negative")
+		check(c.Images.ProxyCacheEntries >= 0, "images.proxy_cache_entries must not be negative")
+	}
+	check(c.Export.PDFCommand == "" || c.Export.PDFURL == "", "export: use either pdf_command or pdf_url, not both")
+	check(c.Export.PDFURL == "" || strings.HasPrefix(c.Export.PDFURL, "http://") || strings.HasPrefix(c.Export.PDFURL, "https://"),
+		"export.pdf_url must be an http(s) address")
+	check(c.Export.PDFTimeout > 0, "export.pdf_timeout must be positive")
+	switch c.Translation.Provider {
+	case "":
+	case "deepl":
+		check(c.Translation.APIKey != "", "translation.api_key is required for the deepl provider")
+	case "libretranslate":
+		check(c.Translation.URL != "", "translation.url is required for the libretranslate provider")
+	default:
+		check(false, "unknown translation.provider %q (available: %s)", c.Translation.Provider, strings.Join(translate.Providers, ", "))
+	}
+	check(c.Translation.Timeout > 0, "translation.timeout must be positive")
+	switch c.Screening.Provider {
+	case "":
+	case "openai":
+		check(c.Screening.APIKey != "", "screening.api_key is required for the openai provider")
+	case "http":
+		check(strings.HasPrefix(c.Screening.URL, "http://") || strings.HasPrefix(c.Screening.URL, "https://"),
+			"screening.url must be an http(s) address for the http provider")
+	default:
+		check(false, "unknown screening.provider %q (available: %s)", c.Screening.Provider, strings.Join(screening.Providers, ", "))
+	}
+	check(c.Screening.Threshold > 0 && c.Screening.Threshold <= 1, "screening.threshold must be in (0, 1]")
+	check(c.Screening.Timeout > 0, "screening.timeout must be positive")
+	check(c.Reports.Spam >= 0, "reports.spam must not be negative")
+	check(c.Reports.Abuse >= 0, "reports.abuse must not be negative")
+	check(c.Reports.OffTopic >= 0, "reports.off_topic must not be negative")
+	check(c.Reports.Illegal >= 0, "reports.illegal must not be negative")
+	switch c.Summary.Provider {
+	case "":
+	case "openai":
+		check(c.Summary.APIKey != "" || c.Summary.URL != "", "summary.api_key is required for the OpenAI API (or set summary.url of a compatible server)")
+	case "ollama":
+		check(c.Summary.Model != "", "summary.model is required for the ollama provider")
+	default:
+		check(false, "unknown summary.provider %q (available: %s)", c.Summary.Provider, strings.Join(summarize.Providers, ", "))
+	}
+	check(c.Summary.MinComments > 0, "summary.min_comments must be positive")
+	check(c.Summary.Timeout > 0, "summary.timeout must be positive")
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile создаёт файл настроек с содержимым data во временном каталоге.
+func writeFile(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "forum.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestDefault проверяет, что настройки по умолчанию проходят проверку.
+func TestDefault(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestLoad проверяет, что файл дополняет значения по умолчанию, а переменные окружения имеют приоритет над файлом.
+func TestLoad(t *testing.T) {
+	path := writeFile(t, `
+server:
+  addr: ":9000"
+database:
+  driver: sqlite3
+  path: /tmp/forum-test.db
+  max_open_conns: 4
+cache:
+  ttl: 1m
+integrations:
+  discord_categories: [go, news]
+`)
+	t.Setenv("FORUM_ADDR", ":9100")
+	t.Setenv("FORUM_SESSION_LIFETIME", "2h")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Addr != ":9100" {
+		t.Errorf("addr = %q, want the environment value", cfg.Server.Addr)
+	}
+	if cfg.Database.Driver != "sqlite" || cfg.Database.Path != "/tmp/forum-test.db" {
+		t.Errorf("database = %+v", cfg.Database)
+	}
+	if cfg.Database.MaxOpenConns == nil || *cfg.Database.MaxOpenConns != 4 {
+		t.Errorf("max_open_conns = %v, want 4", cfg.Database.MaxOpenConns)
+	}
+	if cfg.Database.MaxIdleConns != nil {
+		t.Errorf("max_idle_conns = %v, want unset", *cfg.Database.MaxIdleConns)
+	}
+	if cfg.Cache.TTL != time.Minute || cfg.Session.Lifetime != 2*time.Hour {
+		t.Errorf("cache.ttl = %s, session.lifetime = %s", cfg.Cache.TTL, cfg.Session.Lifetime)
+	}
+	if got := strings.Join(cfg.Integrations.DiscordCategories, ","); got != "go,news" {
+		t.Errorf("discord_categories = %q", got)
+	}
+	if cfg.Server.ShutdownTimeout != 30*time.Second || cfg.Jobs.PurgeInterval != 24*time.Hour {
+		t.Errorf("defaults were not kept: %+v %+v", cfg.Server, cfg.Jobs)
+	}
+}
+
+// TestLoadErrors проверяет, что неизвестные ключи и некорректные значения не принимаются.
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		want string
+	}{
+		{"unknown key", "server:\n  port: 8080\n", nil, "port"},
+		{"bad env duration", "", map[string]string{"FORUM_CACHE_TTL": "soon"}, "FORUM_CACHE_TTL"},
+		{"bad env integer", "", map[string]string{"FORUM_BACKUP_KEEP": "many"}, "FORUM_BACKUP_KEEP"},
+		{"unknown driver", "database:\n  driver: postgres\n", nil, "database.driver"},
+		{"mysql without dsn", "database:\n  driver: mysql\n", nil, "database.dsn"},
+		{"zero interval", "jobs:\n  purge_interval: 0s\n", nil, "jobs.purge_interval"},
+		{"bad window", "jobs:\n  maintenance_window: night\n", nil, "jobs.maintenance_window"},
+		{"cert without key", "server:\n  tls:\n    cert_file: cert.pem\n", nil, "server.tls.cert_file"},
+		{"negative pool", "database:\n  max_idle_conns: -1\n", nil, "database.max_idle_conns"},
+		{"bad access log format", "access_log:\n  format: apache\n", nil, "access_log.format"},
+		{"negative karma", "privileges:\n  image_karma: -1\n", nil, "privileges.image_karma"},
+		{"negative report threshold", "reports:\n  off_topic: -1\n", nil, "reports.off_topic"},
+		{"zero flag weight", "trust:\n  member:\n    flag_weight: 0\n", nil, "trust.member.flag_weight"},
+		{"bad username charset", "usernames:\n  charset: '[a-z'\n", nil, "usernames.charset"},
+		{"short max username", "usernames:\n  min_length: 5\n  max_length: 4\n", nil, "usernames.max_length"},
+		{"verify email without smtp", "registration:\n  verify_email: true\n", nil, "mail.smtp_addr"},
+		{"bad mail sender", "mail:\n  smtp_addr: smtp.example.com:587\n  from: nobody\n", nil, "mail.from"},
+		{"github id without secret", "oauth:\n  github_client_id: abc\n", nil, "oauth.github_client_secret"},
+		{"zero reset link age", "registration:\n  reset_link_age: 0s\n", nil, "registration.reset_link_age"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			path := ""
+			if tt.file != "" {
+				path = writeFile(t, tt.file)
+			}
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Load() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// env читает переменные окружения и накапливает ошибки разбора, чтобы сообщить обо всех сразу.
+type env struct {
+	errs []error
+}
+
+func (e *env) lookup(name string) (string, bool) {
+	value := os.Getenv(name)
+	return value, value != ""
+}
+
+func (e *env) fail(name, value, kind string) {
+	e.errs = append(e.errs, fmt.Errorf("%s: invalid %s %q", name, kind, value))
+}
+
+func (e *env) string(name string, dst *string) {
+	if value, ok := e.lookup(name); ok {
+		*dst = value
+	}
+}
+
+// list читает список значений через запятую.
+func (e *env) list(name string, dst *[]string) {
+	if value, ok := e.lookup(name); ok {
+		*dst = strings.Split(value, ",")
+	}
+}
+
+func (e *env) int(name string, dst *int) {
+	if value, ok := e.lookup(name); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			e.fail(name, value, "integer")
+			return
+		}
+		*dst = n
+	}
+}
+
+func (e *env) intPtr(name string, dst **int) {
+	if _, ok := e.lookup(name); ok {
+		var n int
+		e.int(name, &n)
+		*dst = &n
+	}
+}
+
+// float читает дробное число, например 0.8.
+func (e *env) float(name string, dst *float64) {
+	if value, ok := e.lookup(name); ok {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			e.fail(name, value, "number")
+			return
+		}
+		*dst = f
+	}
+}
+
+func (e *env) bool(name string, dst *bool) {
+	if value, ok := e.lookup(name); ok {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			e.fail(name, value, "boolean")
+			return
+		}
+		*dst = b
+	}
+}
+
+// duration читает длительность в формате time.ParseDuration, например 30s или 2h.
+func (e *env) duration(name string, dst *time.Duration) {
+	if value, ok := e.lookup(name); ok {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			e.fail(name, value, "duration")
+			return
+		}
+		*dst = d
+	}
+}
+
+func (e *env) durationPtr(name string, dst **time.Duration) {
+	if _, ok := e.lookup(name); ok {
+		var d time.Duration
+		e.duration(name, &d)
+		*dst = &d
+	}
+}
+
+// applyEnv переносит в cfg заданные переменные окружения FORUM_*.
+func applyEnv(cfg *Config) error {
+	var e env
+
+	e.string("FORUM_ADDR", &cfg.Server.Addr)
+	e.string("FORUM_BASE_URL", &cfg.Server.BaseURL)
+	e.string("FORUM_TEMPLATES_DIR", &cfg.Server.TemplatesDir)
+	e.string("FORUM_STATIC_DIR", &cfg.Server.StaticDir)
+	e.bool("FORUM_DEV_RELOAD", &cfg.Server.DevReload)
+	e.duration("FORUM_SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout)
+	e.duration("FORUM_READ_HEADER_TIMEOUT", &cfg.Server.Timeouts.ReadHeader)
+	e.duration("FORUM_IDLE_TIMEOUT", &cfg.Server.Timeouts.Idle)
+	e.duration("FORUM_PAGE_TIMEOUT", &cfg.Server.Timeouts.Page)
+	e.duration("FORUM_UPLOAD_TIMEOUT", &cfg.Server.Timeouts.Upload)
+	e.duration("FORUM_LONG_TIMEOUT", &cfg.Server.Timeouts.Long)
+	e.string("FORUM_TLS_CERT_FILE", &cfg.Server.TLS.CertFile)
+	e.string("FORUM_TLS_KEY_FILE", &cfg.Server.TLS.KeyFile)
+	e.list("FORUM_TLS_AUTOCERT_DOMAINS", &cfg.Server.TLS.AutocertDomains)
+	e.string("FORUM_TLS_AUTOCERT_CACHE_DIR", &cfg.Server.TLS.AutocertCacheDir)
+	e.string("FORUM_TLS_AUTOCERT_EMAIL", &cfg.Server.TLS.AutocertEmail)
+	e.string("FORUM_TLS_REDIRECT_ADDR", &cfg.Server.TLS.RedirectAddr)
+	e.list("FORUM_TRUSTED_PROXIES", &cfg.Server.TrustedProxies)
+
+	e.string("FORUM_DB_DRIVER", &cfg.Database.Driver)
+	e.string("FORUM_DB_PATH", &cfg.Database.Path)
+	e.string("FORUM_DB_DSN", &cfg.Database.DSN)
+	e.intPtr("FORUM_DB_MAX_OPEN_CONNS", &cfg.Database.MaxOpenConns)
+	e.intPtr("FORUM_DB_MAX_IDLE_CONNS", &cfg.Database.MaxIdleConns)
+	e.durationPtr("FORUM_DB_CONN_MAX_LIFETIME", &cfg.Database.ConnMaxLifetime)
+	e.durationPtr("FORUM_SLOW_QUERY_THRESHOLD", &cfg.Database.SlowQueryThreshold)
+	e.string("FORUM_SQLITE_SYNCHRONOUS", &cfg.Database.SQLite.Synchronous)
+	e.int("FORUM_SQLITE_CACHE_SIZE", &cfg.Database.SQLite.CacheSize)
+	e.durationPtr("FORUM_SQLITE_BUSY_TIMEOUT", &cfg.Database.SQLite.BusyTimeout)
+
+	e.string("FORUM_CACHE", &cfg.Cache.Backend)
+	e.string("FORUM_REDIS_URL", &cfg.Cache.RedisURL)
+	e.duration("FORUM_CACHE_TTL", &cfg.Cache.TTL)
+
+	e.list("FORUM_CSP_IMAGE_HOSTS", &cfg.Security.ImageHosts)
+	e.string("FORUM_CSP", &cfg.Security.ContentSecurityPolicy)
+	e.duration("FORUM_HSTS_MAX_AGE", &cfg.Security.HSTSMaxAge)
+
+	e.duration("FORUM_SESSION_LIFETIME", &cfg.Session.Lifetime)
+	e.duration("FORUM_SESSION_CLEANUP_INTERVAL", &cfg.Session.CleanupInterval)
+
+	e.duration("FORUM_REGISTRATION_MIN_FILL_TIME", &cfg.Registration.MinFillTime)
+	e.duration("FORUM_REGISTRATION_FORM_MAX_AGE", &cfg.Registration.FormMaxAge)
+	e.string("FORUM_REGISTRATION_FORM_SECRET", &cfg.Registration.FormSecret)
+	e.bool("FORUM_REGISTRATION_BLOCK_DISPOSABLE", &cfg.Registration.BlockDisposable)
+	e.list("FORUM_REGISTRATION_DISPOSABLE_DOMAINS", &cfg.Registration.DisposableDomains)
+	e.int("FORUM_REGISTRATION_INVITE_LIMIT", &cfg.Registration.InviteLimit)
+	e.bool("FORUM_REGISTRATION_VERIFY_EMAIL", &cfg.Registration.VerifyEmail)
+	e.duration("FORUM_REGISTRATION_VERIFY_LINK_AGE", &cfg.Registration.VerifyLinkAge)
+	e.string("FORUM_REGISTRATION_VERIFY_SECRET", &cfg.Registration.VerifySecret)
+	e.duration("FORUM_REGISTRATION_RESET_LINK_AGE", &cfg.Registration.ResetLinkAge)
+
+	e.int("FORUM_USERNAME_MIN_LENGTH", &cfg.Usernames.MinLength)
+	e.int("FORUM_USERNAME_MAX_LENGTH", &cfg.Usernames.MaxLength)
+	e.string("FORUM_USERNAME_CHARSET", &cfg.Usernames.Charset)
+	e.list("FORUM_USERNAME_RESERVED", &cfg.Usernames.Reserved)
+	e.list("FORUM_USERNAME_PROFANITY", &cfg.Usernames.Profanity)
+
+	e.string("FORUM_GITHUB_CLIENT_ID", &cfg.OAuth.GitHubClientID)
+	e.string("FORUM_GITHUB_CLIENT_SECRET", &cfg.OAuth.GitHubClientSecret)
+
+	e.duration("FORUM_PURGE_INTERVAL", &cfg.Jobs.PurgeInterval)
+	e.duration("FORUM_DELETED_RETENTION", &cfg.Jobs.DeletedRetention)
+	e.duration("FORUM_RECONCILE_INTERVAL", &cfg.Jobs.ReconcileInterval)
+	e.duration("FORUM_MAINTENANCE_INTERVAL", &cfg.Jobs.MaintenanceInterval)
+	e.string("FORUM_MAINTENANCE_WINDOW", &cfg.Jobs.MaintenanceWindow)
+	e.bool("FORUM_MAINTENANCE_VACUUM", &cfg.Jobs.MaintenanceVacuum)
+	e.duration("FORUM_DIGEST_INTERVAL", &cfg.Jobs.DigestInterval)
+	e.duration("FORUM_HISTORY_RETENTION", &cfg.Jobs.HistoryRetention)
+	e.duration("FORUM_NOTIFICATION_INTERVAL", &cfg.Jobs.NotificationInterval)
+	e.duration("FORUM_UPLOAD_GC_INTERVAL", &cfg.Jobs.UploadGCInterval)
+	e.duration("FORUM_UPLOAD_GRACE", &cfg.Jobs.UploadGrace)
+
+	e.string("FORUM_BACKUP_DIR", &cfg.Backup.Dir)
+	e.duration("FORUM_BACKUP_INTERVAL", &cfg.Backup.Interval)
+	e.int("FORUM_BACKUP_KEEP", &cfg.Backup.Keep)
+
+	e.string("FORUM_DISCORD_WEBHOOK_URL", &cfg.Integrations.DiscordWebhookURL)
+	e.list("FORUM_DISCORD_CATEGORIES", &cfg.Integrations.DiscordCategories)
+	e.string("FORUM_TELEGRAM_BOT_TOKEN", &cfg.Integrations.TelegramBotToken)
+	e.string("FORUM_TELEGRAM_CHAT_ID", &cfg.Integrations.TelegramChatID)
+	e.list("FORUM_TELEGRAM_CATEGORIES", &cfg.Integrations.TelegramCategories)
+
+	e.string("FORUM_REPLY_DOMAIN", &cfg.Mail.ReplyDomain)
+	e.string("FORUM_REPLY_SECRET", &cfg.Mail.ReplySecret)
+	e.string("FORUM_INBOUND_TOKEN", &cfg.Mail.InboundToken)
+	e.string("FORUM_SMTP_ADDR", &cfg.Mail.SMTPAddr)
+	e.string("FORUM_SMTP_USERNAME", &cfg.Mail.SMTPUsername)
+	e.string("FORUM_SMTP_PASSWORD", &cfg.Mail.SMTPPassword)
+	e.string("FORUM_MAIL_FROM", &cfg.Mail.From)
+	e.duration("FORUM_MAIL_SEND_TIMEOUT", &cfg.Mail.SendTimeout)
+
+	e.string("FORUM_PDF_COMMAND", &cfg.Export.PDFCommand)
+	e.string("FORUM_PDF_URL", &cfg.Export.PDFURL)
+	e.duration("FORUM_PDF_TIMEOUT", &cfg.Export.PDFTimeout)
+
+	e.string("FORUM_TRANSLATION_PROVIDER", &cfg.Translation.Provider)
+	e.string("FORUM_TRANSLATION_URL", &cfg.Translation.URL)
+	e.string("FORUM_TRANSLATION_API_KEY", &cfg.Translation.APIKey)
+	e.duration("FORUM_TRANSLATION_TIMEOUT", &cfg.Translation.Timeout)
+
+	e.string("FORUM_SCREENING_PROVIDER", &cfg.Screening.Provider)
+	e.string("FORUM_SCREENING_URL", &cfg.Screening.URL)
+	e.string("FORUM_SCREENING_API_KEY", &cfg.Screening.APIKey)
+	e.string("FORUM_SCREENING_MODEL", &cfg.Screening.Model)
+	e.float("FORUM_SCREENING_THRESHOLD", &cfg.Screening.Threshold)
+	e.duration("FORUM_SCREENING_TIMEOUT", &cfg.Screening.Timeout)
+
+	e.int("FORUM_REPORTS_SPAM", &cfg.Reports.Spam)
+	e.int("FORUM_REPORTS_ABUSE", &cfg.Reports.Abuse)
+	e.int("FORUM_REPORTS_OFF_TOPIC", &cfg.Reports.OffTopic)
+	e.int("FORUM_REPORTS_ILLEGAL", &cfg.Reports.Illegal)
+
+	e.string("FORUM_SUMMARY_PROVIDER", &cfg.Summary.Provider)
+	e.string("FORUM_SUMMARY_URL", &cfg.Summary.URL)
+	e.string("FORUM_SUMMARY_API_KEY", &cfg.Summary.APIKey)
+	e.string("FORUM_SUMMARY_MODEL", &cfg.Summary.Model)
+	e.int("FORUM_SUMMARY_MIN_COMMENTS", &cfg.Summary.MinComments)
+	e.duration("FORUM_SUMMARY_TIMEOUT", &cfg.Summary.Timeout)
+
+	e.string("FORUM_ACCESS_LOG", &cfg.AccessLog.Path)
+	e.string("FORUM_ACCESS_LOG_FORMAT", &cfg.AccessLog.Format)
+	e.int("FORUM_ACCESS_LOG_MAX_SIZE", &cfg.AccessLog.MaxSize)
+	e.duration("FORUM_ACCESS_LOG_ROTATE", &cfg.AccessLog.Rotate)
+	e.int("FORUM_ACCESS_LOG_KEEP", &cfg.AccessLog.Keep)
+
+	e.int("FORUM_IMAGE_KARMA", &cfg.Privileges.ImageKarma)
+	e.int("FORUM_DOWNVOTE_KARMA", &cfg.Privileges.DownvoteKarma)
+	e.duration("FORUM_NEW_ACCOUNT_AGE", &cfg.Privileges.NewAccountAge)
+	e.int("FORUM_NEW_ACCOUNT_LINKS", &cfg.Privileges.NewAccountLinks)
+	e.int("FORUM_NEW_ACCOUNT_POSTS_PER_HOUR", &cfg.Privileges.NewAccountPostsPerHour)
+	e.int("FORUM_NEW_ACCOUNT_COMMENTS_PER_HOUR", &cfg.Privileges.NewAccountCommentsPerHour)
+	e.int("FORUM_VOTES_PER_HOUR", &cfg.Privileges.VotesPerHour)
+	e.int("FORUM_NEW_ACCOUNT_VOTES_PER_HOUR", &cfg.Privileges.NewAccountVotesPerHour)
+
+	for _, l := range []struct {
+		name  string
+		level *TrustLevel
+	}{{"NEW", &cfg.Trust.New}, {"BASIC", &cfg.Trust.Basic}, {"MEMBER", &cfg.Trust.Member}, {"REGULAR", &cfg.Trust.Regular}} {
+		prefix := "FORUM_TRUST_" + l.name + "_"
+		e.duration(prefix+"AGE", &l.level.Age)
+		e.int(prefix+"READ", &l.level.Read)
+		e.int(prefix+"POSTS", &l.level.Posts)
+		e.int(prefix+"COMMENTS", &l.level.Comments)
+		e.int(prefix+"KARMA", &l.level.Karma)
+		e.duration(prefix+"EDIT_WINDOW", &l.level.EditWindow)
+		e.int(prefix+"FLAG_WEIGHT", &l.level.FlagWeight)
+	}
+
+	e.bool("FORUM_IMAGE_VERIFY", &cfg.Images.Verify)
+	e.duration("FORUM_IMAGE_VERIFY_TIMEOUT", &cfg.Images.VerifyTimeout)
+	e.bool("FORUM_IMAGE_PROXY", &cfg.Images.Proxy)
+	e.string("FORUM_IMAGE_PROXY_SECRET", &cfg.Images.ProxySecret)
+	e.int("FORUM_IMAGE_PROXY_MAX_SIZE", &cfg.Images.ProxyMaxSize)
+	e.duration("FORUM_IMAGE_PROXY_TIMEOUT", &cfg.Images.ProxyTimeout)
+	e.duration("FORUM_IMAGE_PROXY_CACHE_TTL", &cfg.Images.ProxyCacheTTL)
+	e.int("FORUM_IMAGE_PROXY_CACHE_ENTRIES", &cfg.Images.ProxyCacheEntries)
+	e.string("FORUM_UPLOADS_BACKEND", &cfg.Uploads.Backend)
+	e.int("FORUM_UPLOADS_MAX_SIZE", &cfg.Uploads.MaxSize)
+	e.string("FORUM_UPLOADS_DIR", &cfg.Uploads.Dir)
+	e.string("FORUM_UPLOADS_S3_ENDPOINT", &cfg.Uploads.S3.Endpoint)
+	e.string("FORUM_UPLOADS_S3_REGION", &cfg.Uploads.S3.Region)
+	e.string("FORUM_UPLOADS_S3_BUCKET", &cfg.Uploads.S3.Bucket)
+	e.string("FORUM_UPLOADS_S3_ACCESS_KEY", &cfg.Uploads.S3.AccessKey)
+	e.string("FORUM_UPLOADS_S3_SECRET_KEY", &cfg.Uploads.S3.SecretKey)
+	e.string("FORUM_UPLOADS_S3_PUBLIC_URL", &cfg.Uploads.S3.PublicURL)
+
+	return errors.Join(e.errs...)
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupPrefix и backupExt задают имена файлов резервных копий: forum-20060102-150405.db.
+const (
+	backupPrefix = "forum-"
+	backupExt    = ".db"
+)
+
+// BackupSQLite записывает согласованную копию базы SQLite в файл path с помощью VACUUM INTO.
+// Копия снимается в одной транзакции чтения и не блокирует запись в режиме WAL. Файл path не должен существовать.
+func BackupSQLite(ctx context.Context, db *sql.DB, path string) error {
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("backup to %s: %w", path, err)
+	}
+	return nil
+}
+
+// BackupFileName возвращает имя файла резервной копии для момента t.
+func BackupFileName(t time.Time) string {
+	return backupPrefix + t.UTC().Format("20060102-150405") + backupExt
+}
+
+// WriteRotatingBackup создаёт новую резервную копию в каталоге dir и удаляет самые старые,
+// оставляя не более keep копий. Возвращает путь к созданной копии.
+func WriteRotatingBackup(ctx context.Context, db *sql.DB, dir string, keep int) (string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, BackupFileName(time.Now()))
+	if err := BackupSQLite(ctx, db, path); err != nil {
+		return "", err
+	}
+	if err := pruneBackups(dir, keep); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+// pruneBackups удаляет из dir самые старые резервные копии сверх keep.
+// Имена содержат время в сортируемом формате, поэтому порядок определяется по имени.
+func pruneBackups(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var backups []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() && strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupExt) {
+			backups = append(backups, name)
+		}
+	}
+	sort.Strings(backups)
+	for len(backups) > keep {
+		if err := os.Remove(filepath.Join(dir, backups[0])); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
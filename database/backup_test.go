@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWriteRotatingBackup проверяет, что копия открывается как база SQLite, а старые копии сверх лимита удаляются.
+func TestWriteRotatingBackup(t *testing.T) {
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "forum.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		CloseStatements(db)
+		db.Close()
+	})
+
+	dir := t.TempDir()
+	for _, name := range []string{"forum-20200101-000000.db", "forum-20200102-000000.db", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path, err := WriteRotatingBackup(context.Background(), db, dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{"forum-20200102-000000.db", filepath.Base(path), "notes.txt"}
+	if len(names) != len(want) {
+		t.Fatalf("backup dir = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("backup dir = %v, want %v", names, want)
+		}
+	}
+
+	backup, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	var categories int
+	if err := backup.QueryRow("SELECT COUNT(*) FROM categories").Scan(&categories); err != nil {
+		t.Fatal(err)
+	}
+	if categories == 0 {
+		t.Fatal("backup has no categories")
+	}
+}
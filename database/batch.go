@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"forum/models"
+)
+
+// inClause возвращает список плейсхолдеров "?, ?, ?" и аргументы для условия IN.
+func inClause(ids []int) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", "), args
+}
+
+// GetCommentsByPostIDs возвращает комментарии к набору постов одним запросом, сгруппированные по ID поста.
+// Внутри поста комментарии отсортированы так же, как в GetCommentsByPostIDWithUserVote (от новых к старым).
+func GetCommentsByPostIDs(ctx context.Context, db *sql.DB, currentUserID int, postIDs []int) (map[int][]models.CommentData, error) {
+	comments := make(map[int][]models.CommentData, len(postIDs))
+	if len(postIDs) == 0 {
+		return comments, nil
+	}
+	in, args := inClause(postIDs)
+	rows, err := db.QueryContext(ctx, `
+        SELECT c.id, c.post_id, c.content, c.created_at, u.id, u.username, COALESCE(u.display_name, ''), c.likes, c.dislikes,
+               COALESCE((SELECT cv.vote FROM comment_votes cv WHERE cv.comment_id = c.id AND cv.user_id = ?), 0) AS user_vote
+        FROM comments c
+        JOIN users u ON c.user_id = u.id
+        WHERE c.post_id IN (`+in+`) AND c.deleted_at IS NULL
+        ORDER BY c.created_at DESC
+    `, append([]interface{}{currentUserID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.CommentData
+		if err := rows.Scan(&c.ID, &c.PostID, &c.Content, &c.CreatedAt, &c.UserID, &c.Username, &c.DisplayName, &c.Likes, &c.Dislikes, &c.UserVote); err != nil {
+			return nil, err
+		}
+		comments[c.PostID] = append(comments[c.PostID], c)
+	}
+	return comments, rows.Err()
+}
+
+// GetLatestCommentsByPostIDs возвращает последний комментарий каждого поста из набора одним запросом: автора, дату
+// и первые snippetLen символов текста (обрезаются на стороне базы, чтобы не передавать длинные комментарии).
+// Ненулевой authorID оставляет только комментарии этого пользователя. Посты без подходящих комментариев
+// в результат не попадают. Голоса и голос пользователя не загружаются.
+func GetLatestCommentsByPostIDs(ctx context.Context, db *sql.DB, postIDs []int, authorID, snippetLen int) (map[int]models.CommentData, error) {
+	comments := make(map[int]models.CommentData, len(postIDs))
+	if len(postIDs) == 0 {
+		return comments, nil
+	}
+	in, args := inClause(postIDs)
+	rows, err := db.QueryContext(ctx, `
+        SELECT c.id, c.post_id, SUBSTR(c.content, 1, ?), c.created_at, u.id, u.username, COALESCE(u.display_name, '')
+        FROM comments c
+        JOIN users u ON c.user_id = u.id
+        WHERE c.post_id IN (`+in+`)
+          AND c.id = (SELECT c2.id FROM comments c2 WHERE c2.post_id = c.post_id AND c2.deleted_at IS NULL
+                      AND (? = 0 OR c2.user_id = ?)
+                      ORDER BY c2.created_at DESC, c2.id DESC LIMIT 1)
+    `, append(append([]interface{}{snippetLen}, args...), authorID, authorID)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.CommentData
+		if err := rows.Scan(&c.ID, &c.PostID, &c.Content, &c.CreatedAt, &c.UserID, &c.Username, &c.DisplayName); err != nil {
+			return nil, err
+		}
+		comments[c.PostID] = c
+	}
+	return comments, rows.Err()
+}
+
+// GetPostCategoriesByPostIDs возвращает категории набора постов со значками и цветами одним запросом,
+// сгруппированные по ID поста.
+func GetPostCategoriesByPostIDs(ctx context.Context, db *sql.DB, postIDs []int) (map[int][]models.Category, error) {
+	categories := make(map[int][]models.Category, len(postIDs))
+	if len(postIDs) == 0 {
+		return categories, nil
+	}
+	in, args := inClause(postIDs)
+	rows, err := db.QueryContext(ctx, `
+        SELECT pc.post_id, c.id, c.name, c.icon, c.color FROM categories c
+        JOIN post_categories pc ON c.id = pc.category_id
+        WHERE pc.post_id IN (`+in+`)
+        ORDER BY c.id
+    `, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		var c models.Category
+		if err := rows.Scan(&postID, &c.ID, &c.Name, &c.Icon, &c.Color); err != nil {
+			return nil, err
+		}
+		categories[postID] = append(categories[postID], c)
+	}
+	return categories, rows.Err()
+}
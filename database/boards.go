@@ -0,0 +1,162 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+
+	"forum/models"
+)
+
+// DefaultBoard — раздел, в который попадают посты без выбранного раздела: созданные до появления разделов,
+// импортированные и отправленные формой без поля board.
+const DefaultBoard = "general"
+
+// Название и описание раздела по умолчанию, с которыми его создаёт миграция.
+const (
+	defaultBoardName        = "General"
+	defaultBoardDescription = "Everything that does not fit another board."
+)
+
+// slugPattern описывает допустимый адрес раздела в /b/{board} и служебной страницы в /pages/{slug}.
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// ValidBoardSlug сообщает, может ли slug быть адресом раздела: строчные латинские буквы, цифры и дефис, до 32 символов.
+func ValidBoardSlug(slug string) bool {
+	return slugPattern.MatchString(slug)
+}
+
+const queryListBoards = `
+        SELECT b.id, b.slug, b.name, b.description,
+               (SELECT COUNT(*) FROM posts p WHERE p.board_id = b.id AND p.deleted_at IS NULL)
+        FROM boards b
+        ORDER BY b.id
+    `
+
+// ListBoards возвращает все разделы в порядке создания с числом постов в каждом.
+// Модераторы не загружаются: они нужны только на странице раздела (см. GetBoardBySlug).
+func ListBoards(ctx context.Context, db *sql.DB) ([]models.Board, error) {
+	rows, err := cachedQuery(ctx, db, queryListBoards)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	boards := []models.Board{}
+	for rows.Next() {
+		var b models.Board
+		if err := rows.Scan(&b.ID, &b.Slug, &b.Name, &b.Description, &b.PostCount); err != nil {
+			return nil, err
+		}
+		boards = append(boards, b)
+	}
+	return boards, rows.Err()
+}
+
+// GetBoardBySlug возвращает раздел по адресу slug вместе с именами модераторов.
+// Если раздела нет, возвращает sql.ErrNoRows.
+func GetBoardBySlug(ctx context.Context, db *sql.DB, slug string) (models.Board, error) {
+	var b models.Board
+	err := db.QueryRowContext(ctx, `
+        SELECT b.id, b.slug, b.name, b.description,
+               (SELECT COUNT(*) FROM posts p WHERE p.board_id = b.id AND p.deleted_at IS NULL)
+        FROM boards b WHERE b.slug = ?
+    `, slug).Scan(&b.ID, &b.Slug, &b.Name, &b.Description, &b.PostCount)
+	if err != nil {
+		return models.Board{}, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+        SELECT u.username FROM board_moderators m JOIN users u ON u.id = m.user_id
+        WHERE m.board_id = ? ORDER BY u.username
+    `, b.ID)
+	if err != nil {
+		return models.Board{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return models.Board{}, err
+		}
+		b.Moderators = append(b.Moderators, username)
+	}
+	return b, rows.Err()
+}
+
+// CreateBoard создаёт раздел и возвращает его ID. Адрес slug проверяет вызывающий код (см. ValidBoardSlug).
+func CreateBoard(ctx context.Context, db *sql.DB, slug, name, description string) (int64, error) {
+	res, err := db.ExecContext(ctx, "INSERT INTO boards (slug, name, description, updated_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)", slug, name, description)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// SetBoardModerator назначает пользователя модератором раздела (moderator = true) или снимает назначение.
+// Время изменения раздела обновляется, чтобы страницы с его модераторами не отдавались из кэша браузера.
+func SetBoardModerator(ctx context.Context, db *sql.DB, boardID, userID int, moderator bool) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM board_moderators WHERE board_id = ? AND user_id = ?", boardID, userID); err != nil {
+		return err
+	}
+	if moderator {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO board_moderators (board_id, user_id) VALUES (?, ?)", boardID, userID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE boards SET updated_at = CURRENT_TIMESTAMP WHERE id = ?", boardID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// IsBoardModerator сообщает, модерирует ли пользователь раздел boardID.
+func IsBoardModerator(ctx context.Context, db *sql.DB, userID, boardID int) (bool, error) {
+	var ok bool
+	err := db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM board_moderators WHERE board_id = ? AND user_id = ?)",
+		boardID, userID,
+	).Scan(&ok)
+	return ok, err
+}
+
+// ModeratesPost сообщает, модерирует ли пользователь раздел, в котором опубликован пост.
+func ModeratesPost(ctx context.Context, db *sql.DB, userID, postID int) (bool, error) {
+	var ok bool
+	err := db.QueryRowContext(ctx, `
+        SELECT EXISTS(SELECT 1 FROM posts p JOIN board_moderators m ON m.board_id = p.board_id
+                      WHERE p.id = ? AND m.user_id = ?)
+    `, postID, userID).Scan(&ok)
+	return ok, err
+}
+
+// ModeratesComment сообщает, модерирует ли пользователь раздел поста, к которому оставлен комментарий.
+func ModeratesComment(ctx context.Context, db *sql.DB, userID, commentID int) (bool, error) {
+	var ok bool
+	err := db.QueryRowContext(ctx, `
+        SELECT EXISTS(SELECT 1 FROM comments c JOIN posts p ON p.id = c.post_id
+                      JOIN board_moderators m ON m.board_id = p.board_id
+                      WHERE c.id = ? AND m.user_id = ?)
+    `, commentID, userID).Scan(&ok)
+	return ok, err
+}
+
+// nullBoard — раздел поста из LEFT JOIN: у постов, добавленных в обход CreatePost, раздела может не быть.
+type nullBoard struct {
+	id   sql.NullInt64
+	slug sql.NullString
+	name sql.NullString
+}
+
+// apply копирует раздел в данные поста.
+func (b nullBoard) apply(p *models.PostData) {
+	p.BoardID = int(b.id.Int64)
+	p.BoardSlug = b.slug.String
+	p.BoardName = b.name.String
+}
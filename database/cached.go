@@ -0,0 +1,480 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/cache"
+	"forum/models"
+)
+
+// generationKey хранит текущее поколение данных. Ключи кэшированных выборок включают поколение,
+// поэтому любая запись через Store делает их недействительными одной операцией, в том числе
+// для других экземпляров приложения, если кэш общий.
+const generationKey = "store:generation"
+
+// storeCache кэширует результаты запросов, не зависящие от пользователя.
+type storeCache struct {
+	cache cache.Cache
+	ttl   time.Duration
+}
+
+// WithCache возвращает копию Store, в которой ленты постов, посты с комментариями, разделы и версии данных
+// для анонимных посетителей, а также сессии и счётчики голосов читаются из кэша c на время ttl.
+// Любая запись через репозитории Store сбрасывает кэшированные выборки.
+// Кэш также доступен обработчикам через поле Cache для готовых фрагментов страниц.
+func (s *Store) WithCache(c cache.Cache, ttl time.Duration) *Store {
+	sc := &storeCache{cache: c, ttl: ttl}
+	cached := *s
+	cached.Cache = c
+	cached.Users = cachedUserRepo{UserRepo: s.Users, c: sc}
+	cached.Posts = cachedPostRepo{PostRepo: s.Posts, c: sc}
+	cached.Comments = cachedCommentRepo{CommentRepo: s.Comments, c: sc}
+	cached.Votes = cachedVoteRepo{VoteRepo: s.Votes, c: sc}
+	cached.Boards = cachedBoardRepo{BoardRepo: s.Boards, c: sc}
+	cached.Settings = cachedSettingsRepo{SettingsRepo: s.Settings, c: sc}
+	cached.Categories = cachedCategoryRepo{CategoryRepo: s.Categories, c: sc}
+	cached.Series = cachedSeriesRepo{SeriesRepo: s.Series, c: sc}
+	return &cached
+}
+
+// InvalidateCache сбрасывает кэшированные выборки после записи в обход репозиториев
+// (функциями пакета над Store.DB), если она меняет видимые в лентах данные. Без кэша ничего не делает.
+func (s *Store) InvalidateCache() {
+	if s.Cache != nil {
+		(&storeCache{cache: s.Cache}).invalidate()
+	}
+}
+
+// generation возвращает текущее поколение данных, создавая его при первом обращении.
+func (sc *storeCache) generation() string {
+	if gen, ok := sc.cache.Get(generationKey); ok {
+		return string(gen)
+	}
+	gen := strconv.FormatInt(time.Now().UnixNano(), 36)
+	sc.cache.Set(generationKey, []byte(gen), 0)
+	return gen
+}
+
+// invalidate начинает новое поколение данных, делая недействительными все кэшированные выборки.
+func (sc *storeCache) invalidate() {
+	sc.cache.Set(generationKey, []byte(strconv.FormatInt(time.Now().UnixNano(), 36)), 0)
+}
+
+// key собирает ключ кэша из поколения данных и частей запроса.
+func (sc *storeCache) key(parts ...string) string {
+	return "store:" + sc.generation() + ":" + strings.Join(parts, ":")
+}
+
+// invalidateAfter сбрасывает кэш после успешной записи и возвращает её ошибку.
+func (sc *storeCache) invalidateAfter(err error) error {
+	if err == nil {
+		sc.invalidate()
+	}
+	return err
+}
+
+// cached возвращает значение из кэша по ключу key или вызывает fetch и сохраняет результат.
+// Ошибки fetch не кэшируются.
+func cached[T any](sc *storeCache, key string, fetch func() (T, error)) (T, error) {
+	if data, ok := sc.cache.Get(key); ok {
+		var v T
+		if err := json.Unmarshal(data, &v); err == nil {
+			return v, nil
+		}
+	}
+	v, err := fetch()
+	if err != nil {
+		return v, err
+	}
+	if data, err := json.Marshal(v); err == nil {
+		sc.cache.Set(key, data, sc.ttl)
+	}
+	return v, nil
+}
+
+// joinIDs форматирует список ID для ключа кэша.
+func joinIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+// cachedUserRepo кэширует сессии и сбрасывает кэш выборок при изменении профиля:
+// имя автора отображается в лентах.
+type cachedUserRepo struct {
+	UserRepo
+	c *storeCache
+}
+
+// cachedSession — сессия в кэше вместе с моментом кэширования,
+// который сравнивается с моментом отзыва всех сессий пользователя.
+type cachedSession struct {
+	UserID   int
+	Role     string
+	Expiry   time.Time
+	CachedAt int64
+}
+
+// sessionsRevokedKey хранит момент последнего отзыва всех сессий пользователя.
+func sessionsRevokedKey(userID int) string {
+	return "sessions-revoked:" + strconv.Itoa(userID)
+}
+
+func (r cachedUserRepo) GetSessionData(ctx context.Context, sessionID string) (int, string, time.Time, error) {
+	key := "session:" + sessionID
+	if data, ok := r.c.cache.Get(key); ok {
+		var s cachedSession
+		if err := json.Unmarshal(data, &s); err == nil {
+			revoked, _ := r.c.cache.Get(sessionsRevokedKey(s.UserID))
+			if revokedAt, _ := strconv.ParseInt(string(revoked), 10, 64); revokedAt < s.CachedAt {
+				return s.UserID, s.Role, s.Expiry, nil
+			}
+		}
+	}
+	cachedAt := time.Now().UnixNano()
+	userID, role, expiry, err := r.UserRepo.GetSessionData(ctx, sessionID)
+	if err != nil {
+		return userID, role, expiry, err
+	}
+	if data, err := json.Marshal(cachedSession{UserID: userID, Role: role, Expiry: expiry, CachedAt: cachedAt}); err == nil {
+		r.c.cache.Set(key, data, r.c.ttl)
+	}
+	return userID, role, expiry, nil
+}
+
+func (r cachedUserRepo) DeleteSession(ctx context.Context, sessionID string) error {
+	r.c.cache.Delete("session:" + sessionID)
+	return r.UserRepo.DeleteSession(ctx, sessionID)
+}
+
+func (r cachedUserRepo) DeleteExpiredSession(ctx context.Context, sessionID string) error {
+	r.c.cache.Delete("session:" + sessionID)
+	return r.UserRepo.DeleteExpiredSession(ctx, sessionID)
+}
+
+// DeleteUserSessions не знает ID удаляемых сессий, поэтому отмечает момент отзыва:
+// сессии пользователя, закэшированные раньше, считаются недействительными.
+func (r cachedUserRepo) DeleteUserSessions(ctx context.Context, userID int) error {
+	err := r.UserRepo.DeleteUserSessions(ctx, userID)
+	if err == nil {
+		r.revokeSessions(userID)
+	}
+	return err
+}
+
+// SetUserRole так же отзывает закэшированные сессии пользователя: следующий запрос прочитает из базы
+// сессию с новой ролью или узнает, что её больше нет.
+func (r cachedUserRepo) SetUserRole(ctx context.Context, userID int, role string) error {
+	err := r.UserRepo.SetUserRole(ctx, userID, role)
+	if err == nil {
+		r.revokeSessions(userID)
+	}
+	return err
+}
+
+// revokeSessions отмечает момент отзыва всех закэшированных сессий пользователя.
+func (r cachedUserRepo) revokeSessions(userID int) {
+	r.c.cache.Set(sessionsRevokedKey(userID), []byte(strconv.FormatInt(time.Now().UnixNano(), 10)), r.c.ttl)
+}
+
+func (r cachedUserRepo) UpdateUserProfile(ctx context.Context, userID int, username string, displayName string) error {
+	return r.c.invalidateAfter(r.UserRepo.UpdateUserProfile(ctx, userID, username, displayName))
+}
+
+// cachedPostRepo кэширует ленты и посты для анонимных посетителей и версии данных для всех.
+type cachedPostRepo struct {
+	PostRepo
+	c *storeCache
+}
+
+func (r cachedPostRepo) GetPosts(ctx context.Context, userID int, q FeedQuery) ([]models.PostData, error) {
+	if userID != 0 {
+		return r.PostRepo.GetPosts(ctx, userID, q)
+	}
+	return cached(r.c, r.c.key("posts", q.cacheKey(userID)), func() ([]models.PostData, error) {
+		return r.PostRepo.GetPosts(ctx, userID, q)
+	})
+}
+
+// feedPage — страница ленты в кэше вместе с курсором следующей страницы.
+type feedPage struct {
+	Posts      []models.PostData
+	NextCursor string
+}
+
+func (r cachedPostRepo) GetFeedPage(ctx context.Context, userID int, q FeedQuery, limit int, cursor string) ([]models.PostData, string, error) {
+	if userID != 0 {
+		return r.PostRepo.GetFeedPage(ctx, userID, q, limit, cursor)
+	}
+	key := r.c.key("feed", q.cacheKey(userID), strconv.Itoa(limit), cursor)
+	page, err := cached(r.c, key, func() (feedPage, error) {
+		posts, next, err := r.PostRepo.GetFeedPage(ctx, userID, q, limit, cursor)
+		return feedPage{Posts: posts, NextCursor: next}, err
+	})
+	return page.Posts, page.NextCursor, err
+}
+
+// cacheKey возвращает часть ключа кэша, однозначно задающую ленту q для посетителя userID (0 — анонимный).
+// Посетитель входит в ключ, потому что от него зависит выборка (см. feedWhere): например, посты только
+// для авторизованных видны лишь вошедшим, и лента участника не должна попасть к анонимному посетителю.
+func (q FeedQuery) cacheKey(userID int) string {
+	unix := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	return strings.Join([]string{strconv.Itoa(userID), q.Filter, q.Category, q.Board, strconv.FormatBool(q.ShowHidden),
+		strings.ToLower(q.Author), unix(q.From), unix(q.To)}, ":")
+}
+
+func (r cachedPostRepo) GetPostByID(ctx context.Context, postID, currentUserID int) (models.PostData, error) {
+	if currentUserID != 0 {
+		return r.PostRepo.GetPostByID(ctx, postID, currentUserID)
+	}
+	return cached(r.c, r.c.key("post", strconv.Itoa(postID)), func() (models.PostData, error) {
+		return r.PostRepo.GetPostByID(ctx, postID, currentUserID)
+	})
+}
+
+func (r cachedPostRepo) GetFeedVersion(ctx context.Context) (ContentVersion, error) {
+	return cached(r.c, r.c.key("feed-version"), func() (ContentVersion, error) {
+		return r.PostRepo.GetFeedVersion(ctx)
+	})
+}
+
+func (r cachedPostRepo) GetPostVersion(ctx context.Context, postID int) (ContentVersion, error) {
+	return cached(r.c, r.c.key("post-version", strconv.Itoa(postID)), func() (ContentVersion, error) {
+		return r.PostRepo.GetPostVersion(ctx, postID)
+	})
+}
+
+func (r cachedPostRepo) CreatePost(ctx context.Context, userID, boardID int, title, content, imageURL string, createdAt time.Time) (int64, error) {
+	id, err := r.PostRepo.CreatePost(ctx, userID, boardID, title, content, imageURL, createdAt)
+	return id, r.c.invalidateAfter(err)
+}
+
+func (r cachedPostRepo) PublishPost(ctx context.Context, p NewPost) (int64, error) {
+	id, err := r.PostRepo.PublishPost(ctx, p)
+	return id, r.c.invalidateAfter(err)
+}
+
+func (r cachedPostRepo) UpdatePost(ctx context.Context, postID int, title, content, imageURL string) error {
+	return r.c.invalidateAfter(r.PostRepo.UpdatePost(ctx, postID, title, content, imageURL))
+}
+
+func (r cachedPostRepo) SetPostMembersOnly(ctx context.Context, postID int, membersOnly bool) error {
+	return r.c.invalidateAfter(r.PostRepo.SetPostMembersOnly(ctx, postID, membersOnly))
+}
+
+func (r cachedPostRepo) SetPostCommentPolicy(ctx context.Context, postID int, policy string) error {
+	return r.c.invalidateAfter(r.PostRepo.SetPostCommentPolicy(ctx, postID, policy))
+}
+
+func (r cachedPostRepo) DeletePost(ctx context.Context, postID int) error {
+	return r.c.invalidateAfter(r.PostRepo.DeletePost(ctx, postID))
+}
+
+func (r cachedPostRepo) DeleteUserPosts(ctx context.Context, userID int, postIDs []int) error {
+	return r.c.invalidateAfter(r.PostRepo.DeleteUserPosts(ctx, userID, postIDs))
+}
+
+func (r cachedPostRepo) SetUserPostsUnpublished(ctx context.Context, userID int, postIDs []int, unpublished bool) error {
+	return r.c.invalidateAfter(r.PostRepo.SetUserPostsUnpublished(ctx, userID, postIDs, unpublished))
+}
+
+func (r cachedPostRepo) SetUserPostsCategories(ctx context.Context, userID int, postIDs, categoryIDs []int) error {
+	return r.c.invalidateAfter(r.PostRepo.SetUserPostsCategories(ctx, userID, postIDs, categoryIDs))
+}
+
+func (r cachedPostRepo) AddPostCategory(ctx context.Context, postID int64, catID int) error {
+	return r.c.invalidateAfter(r.PostRepo.AddPostCategory(ctx, postID, catID))
+}
+
+func (r cachedPostRepo) DeletePostCategories(ctx context.Context, postID int) error {
+	return r.c.invalidateAfter(r.PostRepo.DeletePostCategories(ctx, postID))
+}
+
+// cachedBoardRepo кэширует список разделов и страницы разделов для всех пользователей:
+// они не зависят от пользователя, а число постов в них меняется только вместе с поколением данных.
+// Проверки прав модератора не кэшируются.
+type cachedBoardRepo struct {
+	BoardRepo
+	c *storeCache
+}
+
+func (r cachedBoardRepo) ListBoards(ctx context.Context) ([]models.Board, error) {
+	return cached(r.c, r.c.key("boards"), func() ([]models.Board, error) {
+		return r.BoardRepo.ListBoards(ctx)
+	})
+}
+
+func (r cachedBoardRepo) GetBoardBySlug(ctx context.Context, slug string) (models.Board, error) {
+	return cached(r.c, r.c.key("board", slug), func() (models.Board, error) {
+		return r.BoardRepo.GetBoardBySlug(ctx, slug)
+	})
+}
+
+// cachedSettingsRepo сбрасывает кэш выборок при изменении настроек сайта: от них зависит состав лент.
+// Оформление сайта выводится на каждой странице, поэтому тоже читается из кэша.
+type cachedSettingsRepo struct {
+	SettingsRepo
+	c *storeCache
+}
+
+func (r cachedSettingsRepo) SetSetting(ctx context.Context, name, value string) error {
+	return r.c.invalidateAfter(r.SettingsRepo.SetSetting(ctx, name, value))
+}
+
+func (r cachedSettingsRepo) GetSite(ctx context.Context) (models.Site, error) {
+	return cached(r.c, r.c.key("site"), func() (models.Site, error) {
+		return r.SettingsRepo.GetSite(ctx)
+	})
+}
+
+// cachedCategoryRepo сбрасывает кэш выборок при изменении оформления категорий: оно входит в данные постов.
+type cachedCategoryRepo struct {
+	CategoryRepo
+	c *storeCache
+}
+
+func (r cachedCategoryRepo) UpdateCategoryStyle(ctx context.Context, name, icon, color string) error {
+	return r.c.invalidateAfter(r.CategoryRepo.UpdateCategoryStyle(ctx, name, icon, color))
+}
+
+// cachedSeriesRepo сбрасывает кэш выборок при изменении серий: навигация по серии входит в страницы постов.
+type cachedSeriesRepo struct {
+	SeriesRepo
+	c *storeCache
+}
+
+func (r cachedSeriesRepo) CreateSeries(ctx context.Context, userID int, title string) (int64, error) {
+	id, err := r.SeriesRepo.CreateSeries(ctx, userID, title)
+	return id, r.c.invalidateAfter(err)
+}
+
+func (r cachedSeriesRepo) SetPostSeries(ctx context.Context, postID, seriesID int) error {
+	return r.c.invalidateAfter(r.SeriesRepo.SetPostSeries(ctx, postID, seriesID))
+}
+
+func (r cachedSeriesRepo) MoveSeriesPart(ctx context.Context, seriesID, postID int, up bool) error {
+	return r.c.invalidateAfter(r.SeriesRepo.MoveSeriesPart(ctx, seriesID, postID, up))
+}
+
+// cachedCommentRepo кэширует комментарии для анонимных посетителей.
+type cachedCommentRepo struct {
+	CommentRepo
+	c *storeCache
+}
+
+func (r cachedCommentRepo) GetCommentsByPostIDWithUserVote(ctx context.Context, currentUserID, postID int) ([]models.CommentData, error) {
+	if currentUserID != 0 {
+		return r.CommentRepo.GetCommentsByPostIDWithUserVote(ctx, currentUserID, postID)
+	}
+	return cached(r.c, r.c.key("comments", strconv.Itoa(postID)), func() ([]models.CommentData, error) {
+		return r.CommentRepo.GetCommentsByPostIDWithUserVote(ctx, currentUserID, postID)
+	})
+}
+
+func (r cachedCommentRepo) GetCommentsByPostIDs(ctx context.Context, currentUserID int, postIDs []int) (map[int][]models.CommentData, error) {
+	if currentUserID != 0 {
+		return r.CommentRepo.GetCommentsByPostIDs(ctx, currentUserID, postIDs)
+	}
+	return cached(r.c, r.c.key("comments-batch", joinIDs(postIDs)), func() (map[int][]models.CommentData, error) {
+		return r.CommentRepo.GetCommentsByPostIDs(ctx, currentUserID, postIDs)
+	})
+}
+
+func (r cachedCommentRepo) GetLatestCommentsByPostIDs(ctx context.Context, postIDs []int, authorID, snippetLen int) (map[int]models.CommentData, error) {
+	if authorID != 0 {
+		return r.CommentRepo.GetLatestCommentsByPostIDs(ctx, postIDs, authorID, snippetLen)
+	}
+	return cached(r.c, r.c.key("latest-comments", strconv.Itoa(snippetLen), joinIDs(postIDs)), func() (map[int]models.CommentData, error) {
+		return r.CommentRepo.GetLatestCommentsByPostIDs(ctx, postIDs, authorID, snippetLen)
+	})
+}
+
+func (r cachedCommentRepo) CreateComment(ctx context.Context, postID int, userID int, content, createdAt string) (int64, error) {
+	id, err := r.CommentRepo.CreateComment(ctx, postID, userID, content, createdAt)
+	return id, r.c.invalidateAfter(err)
+}
+
+func (r cachedCommentRepo) DeleteComment(ctx context.Context, commentID int) error {
+	return r.c.invalidateAfter(r.CommentRepo.DeleteComment(ctx, commentID))
+}
+
+func (r cachedCommentRepo) DeleteUserComments(ctx context.Context, userID int, commentIDs []int) error {
+	return r.c.invalidateAfter(r.CommentRepo.DeleteUserComments(ctx, userID, commentIDs))
+}
+
+func (r cachedCommentRepo) DeletePostComments(ctx context.Context, postID int) error {
+	return r.c.invalidateAfter(r.CommentRepo.DeletePostComments(ctx, postID))
+}
+
+// cachedVoteRepo кэширует счётчики голосов и сбрасывает кэш при любом изменении голосов:
+// они входят в счётчики лент.
+type cachedVoteRepo struct {
+	VoteRepo
+	c *storeCache
+}
+
+// voteStats — результат GetPostVoteStats/GetCommentVoteStats в кэше.
+type voteStats struct {
+	Likes, Dislikes int
+	UserVote        int64
+	Voted           bool
+}
+
+func (r cachedVoteRepo) GetPostVoteStats(ctx context.Context, userID, postID int) (int, int, int64, bool, error) {
+	s, err := cached(r.c, r.c.key("post-votes", strconv.Itoa(postID), strconv.Itoa(userID)), func() (voteStats, error) {
+		likes, dislikes, vote, voted, err := r.VoteRepo.GetPostVoteStats(ctx, userID, postID)
+		return voteStats{likes, dislikes, vote, voted}, err
+	})
+	return s.Likes, s.Dislikes, s.UserVote, s.Voted, err
+}
+
+func (r cachedVoteRepo) GetCommentVoteStats(ctx context.Context, userID, commentID int) (int, int, int64, bool, error) {
+	s, err := cached(r.c, r.c.key("comment-votes", strconv.Itoa(commentID), strconv.Itoa(userID)), func() (voteStats, error) {
+		likes, dislikes, vote, voted, err := r.VoteRepo.GetCommentVoteStats(ctx, userID, commentID)
+		return voteStats{likes, dislikes, vote, voted}, err
+	})
+	return s.Likes, s.Dislikes, s.UserVote, s.Voted, err
+}
+
+func (r cachedVoteRepo) SetPostLike(ctx context.Context, userID, postID int) error {
+	return r.c.invalidateAfter(r.VoteRepo.SetPostLike(ctx, userID, postID))
+}
+
+func (r cachedVoteRepo) SetPostDislike(ctx context.Context, userID, postID int) error {
+	return r.c.invalidateAfter(r.VoteRepo.SetPostDislike(ctx, userID, postID))
+}
+
+func (r cachedVoteRepo) RemovePostVote(ctx context.Context, userID, postID int) error {
+	return r.c.invalidateAfter(r.VoteRepo.RemovePostVote(ctx, userID, postID))
+}
+
+func (r cachedVoteRepo) DeletePostVotes(ctx context.Context, postID int) error {
+	return r.c.invalidateAfter(r.VoteRepo.DeletePostVotes(ctx, postID))
+}
+
+func (r cachedVoteRepo) SetCommentLike(ctx context.Context, userID, commentID int) error {
+	return r.c.invalidateAfter(r.VoteRepo.SetCommentLike(ctx, userID, commentID))
+}
+
+func (r cachedVoteRepo) SetCommentDislike(ctx context.Context, userID, commentID int) error {
+	return r.c.invalidateAfter(r.VoteRepo.SetCommentDislike(ctx, userID, commentID))
+}
+
+func (r cachedVoteRepo) RemoveCommentVote(ctx context.Context, userID, commentID int) error {
+	return r.c.invalidateAfter(r.VoteRepo.RemoveCommentVote(ctx, userID, commentID))
+}
+
+func (r cachedVoteRepo) DeleteCommentVotes(ctx context.Context, commentID int) error {
+	return r.c.invalidateAfter(r.VoteRepo.DeleteCommentVotes(ctx, commentID))
+}
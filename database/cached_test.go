@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"forum/cache"
+)
+
+// TestCachedStore проверяет, что Store с кэшем читает сессии, счётчики голосов и посты из кэша,
+// а запись через репозитории или InvalidateCache сбрасывает устаревшие значения.
+func TestCachedStore(t *testing.T) {
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "forum.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		CloseStatements(db)
+		db.Close()
+	})
+	store := NewSQLiteStore(db).WithCache(cache.NewMemory(0), time.Minute)
+	ctx := context.Background()
+
+	if err := store.Users.RegisterUser(ctx, "cached@example.com", "Cached", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	userID, _, _, _, err := store.Users.GetUserByEmail(ctx, "cached@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Сессии: удаление в обход кэша не видно до отзыва, смена роли и выход — видны сразу.
+	if err := store.Users.CreateSession(ctx, "s1", userID, "user", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if id, role, _, err := store.Users.GetSessionData(ctx, "s1"); err != nil || id != userID || role != "user" {
+		t.Fatalf("GetSessionData = %d, %q, %v", id, role, err)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE sessions SET role = 'admin' WHERE session_id = ?", "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, role, _, err := store.Users.GetSessionData(ctx, "s1"); err != nil || role != "user" {
+		t.Fatalf("GetSessionData read past the cache: %q, %v", role, err)
+	}
+	if err := store.Users.SetUserRole(ctx, userID, "moderator"); err != nil {
+		t.Fatal(err)
+	}
+	if _, role, _, err := store.Users.GetSessionData(ctx, "s1"); err != nil || role != "moderator" {
+		t.Errorf("GetSessionData after SetUserRole = %q, %v, want moderator", role, err)
+	}
+	if err := store.Users.DeleteSession(ctx, "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := store.Users.GetSessionData(ctx, "s1"); err == nil {
+		t.Error("GetSessionData returned a deleted session")
+	}
+	if err := store.Users.CreateSession(ctx, "s2", userID, "user", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := store.Users.GetSessionData(ctx, "s2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Users.DeleteUserSessions(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := store.Users.GetSessionData(ctx, "s2"); err == nil {
+		t.Error("GetSessionData returned a session after DeleteUserSessions")
+	}
+
+	// Счётчики голосов и пост для анонимных посетителей.
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	postID, err := store.Posts.CreatePost(ctx, userID, board.ID, "Cached", "Body", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if likes, _, _, _, err := store.Votes.GetPostVoteStats(ctx, 0, int(postID)); err != nil || likes != 0 {
+		t.Fatalf("GetPostVoteStats = %d, %v", likes, err)
+	}
+	if err := store.Votes.SetPostLike(ctx, userID, int(postID)); err != nil {
+		t.Fatal(err)
+	}
+	if likes, _, _, _, err := store.Votes.GetPostVoteStats(ctx, 0, int(postID)); err != nil || likes != 1 {
+		t.Errorf("GetPostVoteStats after SetPostLike = %d, %v, want 1", likes, err)
+	}
+	if post, err := store.Posts.GetPostByID(ctx, int(postID), 0); err != nil || post.Title != "Cached" {
+		t.Fatalf("GetPostByID = %+v, %v", post, err)
+	}
+	if err := store.Posts.UpdatePost(ctx, int(postID), "Edited", "Body", ""); err != nil {
+		t.Fatal(err)
+	}
+	if post, err := store.Posts.GetPostByID(ctx, int(postID), 0); err != nil || post.Title != "Edited" {
+		t.Errorf("GetPostByID after UpdatePost = %q, %v, want Edited", post.Title, err)
+	}
+
+	// Запись в обход репозиториев видна только после InvalidateCache.
+	if _, err := db.ExecContext(ctx, "UPDATE posts SET title = 'Direct' WHERE id = ?", postID); err != nil {
+		t.Fatal(err)
+	}
+	if post, _ := store.Posts.GetPostByID(ctx, int(postID), 0); post.Title != "Edited" {
+		t.Fatalf("GetPostByID read past the cache: %q", post.Title)
+	}
+	store.InvalidateCache()
+	if post, err := store.Posts.GetPostByID(ctx, int(postID), 0); err != nil || post.Title != "Direct" {
+		t.Errorf("GetPostByID after InvalidateCache = %q, %v, want Direct", post.Title, err)
+	}
+
+	// Лента с постом только для авторизованных не попадает из кэша к анонимному посетителю, и наоборот.
+	if err := store.Posts.SetPostMembersOnly(ctx, int(postID), true); err != nil {
+		t.Fatal(err)
+	}
+	hasPost := func(viewerID int) bool {
+		posts, _, err := store.Posts.GetFeedPage(ctx, viewerID, FeedQuery{Filter: "new", ShowHidden: true}, 10, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range posts {
+			if p.ID == int(postID) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, viewerID := range []int{userID, 0, userID, 0} {
+		if got := hasPost(viewerID); got != (viewerID != 0) {
+			t.Errorf("feed of viewer %d includes the members-only post: %v", viewerID, got)
+		}
+	}
+	if key := (FeedQuery{}).cacheKey(0); key == (FeedQuery{}).cacheKey(userID) {
+		t.Errorf("feed cache key %q does not depend on the viewer", key)
+	}
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"forum/models"
+)
+
+// defaultCategoryStyles — значки и цвета, с которыми миграция оформляет стандартные категории.
+var defaultCategoryStyles = map[string][2]string{
+	"news":     {"📰", "#4e8cff"},
+	"life":     {"🌿", "#3bb273"},
+	"auto":     {"🚗", "#e4572e"},
+	"creative": {"🎨", "#c34fd6"},
+	"gadgets":  {"📱", "#17a2b8"},
+	"science":  {"🔬", "#6c63ff"},
+	"games":    {"🎮", "#f5a623"},
+	"other":    {"✨", "#8a94a6"},
+}
+
+// categoryColorPattern описывает цвет категории: шестнадцатеричный #rrggbb.
+var categoryColorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// maxCategoryIconLen — наибольшая длина значка категории в символах (эмодзи с модификаторами занимают несколько).
+const maxCategoryIconLen = 8
+
+// ValidCategoryColor сообщает, подходит ли color для категории: пустая строка (без цвета) или #rrggbb строчными буквами.
+func ValidCategoryColor(color string) bool {
+	return color == "" || categoryColorPattern.MatchString(color)
+}
+
+// ValidCategoryIcon сообщает, подходит ли icon для категории: пустая строка или короткая строка без пробелов и запятых
+// (запятая разделяет значения в списках категорий поста).
+func ValidCategoryIcon(icon string) bool {
+	return utf8.RuneCountInString(icon) <= maxCategoryIconLen && !strings.ContainsAny(icon, ", \t\n")
+}
+
+// categoryColumns — колонки категорий поста p для запросов постов; разбираются в nullCategories.
+// Имена, значки и цвета собираются отдельными списками в одном порядке (по ID категории).
+const categoryColumns = `
+               (SELECT GROUP_CONCAT(c.name ORDER BY c.id) FROM post_categories pc JOIN categories c ON pc.category_id = c.id
+                WHERE pc.post_id = p.id) AS categories,
+               (SELECT GROUP_CONCAT(c.icon ORDER BY c.id) FROM post_categories pc JOIN categories c ON pc.category_id = c.id
+                WHERE pc.post_id = p.id) AS category_icons,
+               (SELECT GROUP_CONCAT(c.color ORDER BY c.id) FROM post_categories pc JOIN categories c ON pc.category_id = c.id
+                WHERE pc.post_id = p.id) AS category_colors`
+
+// nullCategories — колонки categoryColumns; у поста без категорий они равны NULL.
+type nullCategories struct {
+	names  sql.NullString
+	icons  sql.NullString
+	colors sql.NullString
+}
+
+// apply копирует категории в данные поста; первая из них становится основной (Category).
+func (c nullCategories) apply(p *models.PostData) {
+	if !c.names.Valid {
+		return
+	}
+	names := strings.Split(c.names.String, ",")
+	icons := strings.Split(c.icons.String, ",")
+	colors := strings.Split(c.colors.String, ",")
+	p.Categories = make([]models.Category, len(names))
+	for i, name := range names {
+		p.Categories[i] = models.Category{Name: name}
+		if i < len(icons) {
+			p.Categories[i].Icon = icons[i]
+		}
+		if i < len(colors) {
+			p.Categories[i].Color = colors[i]
+		}
+	}
+	p.Category = names[0]
+}
+
+// queryListCategories считает посты и последнюю активность всех категорий одним запросом: время последнего
+// комментария каждого поста берётся из сгруппированной выборки, а не отдельным запросом на категорию.
+const queryListCategories = `
+        SELECT c.id, c.name, c.icon, c.color, COUNT(p.id), MAX(p.created_at), MAX(lc.created_at)
+        FROM categories c
+        LEFT JOIN post_categories pc ON pc.category_id = c.id
+        LEFT JOIN posts p ON p.id = pc.post_id AND p.deleted_at IS NULL
+        LEFT JOIN (
+            SELECT post_id, MAX(created_at) AS created_at FROM comments WHERE deleted_at IS NULL GROUP BY post_id
+        ) lc ON lc.post_id = p.id
+        GROUP BY c.id, c.name, c.icon, c.color
+        ORDER BY c.id
+    `
+
+// ListCategories возвращает все категории по порядку ID со значками, цветами, числом опубликованных постов
+// и временем последнего поста или комментария в них.
+func ListCategories(ctx context.Context, db *sql.DB) ([]models.Category, error) {
+	rows, err := cachedQuery(ctx, db, queryListCategories)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		var c models.Category
+		var lastPost, lastComment sql.NullString
+		if err := rows.Scan(&c.ID, &c.Name, &c.Icon, &c.Color, &c.PostCount, &lastPost, &lastComment); err != nil {
+			return nil, err
+		}
+		c.LastActivity = parseSQLiteTime(lastPost.String)
+		if t := parseSQLiteTime(lastComment.String); t.After(c.LastActivity) {
+			c.LastActivity = t
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// UpdateCategoryStyle задаёт значок и цвет категории name. Значения проверяются заранее
+// (ValidCategoryIcon, ValidCategoryColor). Если категории нет, возвращает sql.ErrNoRows.
+func UpdateCategoryStyle(ctx context.Context, db *sql.DB, name, icon, color string) error {
+	id, err := GetCategoryIDByName(ctx, db, name)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "UPDATE categories SET icon = ?, color = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+		icon, color, id)
+	return err
+}
+
+// setDefaultCategoryStyles оформляет стандартные категории значками и цветами из defaultCategoryStyles.
+func setDefaultCategoryStyles(tx *sql.Tx) error {
+	for name, style := range defaultCategoryStyles {
+		if _, err := tx.Exec("UPDATE categories SET icon = ?, color = ? WHERE name = ?", style[0], style[1], name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
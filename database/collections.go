@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"strings"
+	"unicode/utf8"
+
+	"forum/models"
+)
+
+// MaxCollectionNameLen — наибольшая длина названия подборки в символах.
+const MaxCollectionNameLen = 100
+
+// ValidCollectionName сообщает, подходит ли name (без пробелов по краям) для названия подборки.
+func ValidCollectionName(name string) bool {
+	return name != "" && name == strings.TrimSpace(name) && utf8.RuneCountInString(name) <= MaxCollectionNameLen
+}
+
+// CreateCollection создаёт пустую закрытую подборку закладок пользователя userID и возвращает её ID.
+func CreateCollection(ctx context.Context, db *sql.DB, userID int, name string) (int64, error) {
+	res, err := db.ExecContext(ctx, "INSERT INTO collections (user_id, name) VALUES (?, ?)", userID, name)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// ListUserCollections возвращает подборки пользователя userID по названию с числом неудалённых постов в каждой.
+// Saved отмечает подборки, в которые уже сохранён пост postID; при postID 0 не отмечается ни одна.
+func ListUserCollections(ctx context.Context, db *sql.DB, userID, postID int) ([]models.Collection, error) {
+	rows, err := db.QueryContext(ctx, `
+        SELECT c.id, c.user_id, c.name, COALESCE(c.share_token, ''), c.created_at,
+            (SELECT COUNT(*) FROM collection_items ci JOIN posts p ON p.id = ci.post_id
+             WHERE ci.collection_id = c.id AND p.deleted_at IS NULL),
+            EXISTS(SELECT 1 FROM collection_items ci WHERE ci.collection_id = c.id AND ci.post_id = ?)
+        FROM collections c
+        WHERE c.user_id = ?
+        ORDER BY c.name, c.id
+    `, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []models.Collection{}
+	for rows.Next() {
+		var c models.Collection
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.ShareToken, &c.CreatedAt, &c.ItemCount, &c.Saved); err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+	return collections, rows.Err()
+}
+
+// GetCollection возвращает подборку с именем владельца и её постами, последние сохранённые первыми.
+// Если подборки нет, возвращает sql.ErrNoRows; проверять владельца должен вызывающий.
+func GetCollection(ctx context.Context, db *sql.DB, collectionID int) (models.Collection, error) {
+	return getCollection(ctx, db, "c.id = ?", collectionID, false)
+}
+
+// GetSharedCollection возвращает открытую подборку по ссылке с токеном token. Ссылка доступна без входа,
+// поэтому посты только для авторизованных в неё не попадают и не учитываются в числе постов.
+// Если подборки нет или доступ по ссылке закрыт, возвращает sql.ErrNoRows.
+func GetSharedCollection(ctx context.Context, db *sql.DB, token string) (models.Collection, error) {
+	if token == "" {
+		return models.Collection{}, sql.ErrNoRows
+	}
+	return getCollection(ctx, db, "c.share_token = ?", token, true)
+}
+
+// getCollection читает подборку по условию where с одним аргументом. Для открытой по ссылке подборки (shared)
+// посты только для авторизованных пропускаются.
+func getCollection(ctx context.Context, db *sql.DB, where string, arg any, shared bool) (models.Collection, error) {
+	var c models.Collection
+	err := db.QueryRowContext(ctx, `
+        SELECT c.id, c.user_id, u.username, c.name, COALESCE(c.share_token, ''), c.created_at
+        FROM collections c JOIN users u ON u.id = c.user_id
+        WHERE `+where, arg).Scan(&c.ID, &c.UserID, &c.Username, &c.Name, &c.ShareToken, &c.CreatedAt)
+	if err != nil {
+		return models.Collection{}, err
+	}
+
+	items := "ci.collection_id = ? AND p.deleted_at IS NULL"
+	if shared {
+		items += " AND NOT p.members_only"
+	}
+	rows, err := db.QueryContext(ctx, `
+        SELECT p.id, p.title, u.username, ci.created_at
+        FROM collection_items ci
+        JOIN posts p ON p.id = ci.post_id
+        JOIN users u ON u.id = p.user_id
+        WHERE `+items+`
+        ORDER BY ci.created_at DESC, p.id DESC
+    `, c.ID)
+	if err != nil {
+		return models.Collection{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.CollectionItem
+		if err := rows.Scan(&item.PostID, &item.Title, &item.Username, &item.SavedAt); err != nil {
+			return models.Collection{}, err
+		}
+		c.Items = append(c.Items, item)
+	}
+	c.ItemCount = len(c.Items)
+	return c, rows.Err()
+}
+
+// AddCollectionItem сохраняет пост postID в подборку collectionID. Повторное сохранение ничего не меняет.
+func AddCollectionItem(ctx context.Context, db *sql.DB, collectionID, postID int) error {
+	_, err := db.ExecContext(ctx, `
+        INSERT INTO collection_items (collection_id, post_id)
+        SELECT ?, ? FROM collections
+        WHERE id = ? AND NOT EXISTS (SELECT 1 FROM collection_items WHERE collection_id = ? AND post_id = ?)
+    `, collectionID, postID, collectionID, collectionID, postID)
+	return err
+}
+
+// RemoveCollectionItem убирает пост postID из подборки collectionID.
+func RemoveCollectionItem(ctx context.Context, db *sql.DB, collectionID, postID int) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM collection_items WHERE collection_id = ? AND post_id = ?", collectionID, postID)
+	return err
+}
+
+// SetCollectionShared открывает подборку по ссылке с новым случайным токеном или закрывает её (shared = false).
+// Закрытие удаляет токен, так что после повторного открытия старая ссылка не работает.
+func SetCollectionShared(ctx context.Context, db *sql.DB, collectionID int, shared bool) error {
+	var token any
+	if shared {
+		b := make([]byte, 18)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		token = base64.RawURLEncoding.EncodeToString(b)
+	}
+	_, err := db.ExecContext(ctx, "UPDATE collections SET share_token = ? WHERE id = ?", token, collectionID)
+	return err
+}
+
+// DeleteCollection удаляет подборку вместе со списком её постов; сами посты не затрагиваются.
+func DeleteCollection(ctx context.Context, db *sql.DB, collectionID int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM collection_items WHERE collection_id = ?", collectionID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM collections WHERE id = ?", collectionID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
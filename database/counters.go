@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Счётчики лайков, дизлайков и комментариев хранятся в самих строках posts и comments, чтобы ленты
+// не агрегировали таблицы голосов при каждом просмотре. Счётчики обновляются триггерами, поэтому их
+// поддерживают все пути записи: обработчики, импорт и генератор демонстрационных данных.
+// Каскадные удаления в MySQL триггеры не вызывают, поэтому расхождения исправляет ReconcileCounters.
+
+// counterTrigger — триггер, обновляющий счётчики после изменения строки таблицы table.
+type counterTrigger struct {
+	name   string
+	event  string // INSERT, UPDATE или DELETE
+	table  string
+	update string // одно выражение UPDATE; NEW и OLD — изменённая строка
+}
+
+var counterTriggers = []counterTrigger{
+	{"post_votes_counters_insert", "INSERT", "post_votes",
+		`UPDATE posts SET likes = likes + (NEW.vote = 1), dislikes = dislikes + (NEW.vote = -1) WHERE id = NEW.post_id`},
+	{"post_votes_counters_update", "UPDATE", "post_votes",
+		`UPDATE posts SET likes = likes - (OLD.vote = 1) + (NEW.vote = 1), dislikes = dislikes - (OLD.vote = -1) + (NEW.vote = -1) WHERE id = NEW.post_id`},
+	{"post_votes_counters_delete", "DELETE", "post_votes",
+		`UPDATE posts SET likes = likes - (OLD.vote = 1), dislikes = dislikes - (OLD.vote = -1) WHERE id = OLD.post_id`},
+	{"comment_votes_counters_insert", "INSERT", "comment_votes",
+		`UPDATE comments SET likes = likes + (NEW.vote = 1), dislikes = dislikes + (NEW.vote = -1) WHERE id = NEW.comment_id`},
+	{"comment_votes_counters_update", "UPDATE", "comment_votes",
+		`UPDATE comments SET likes = likes - (OLD.vote = 1) + (NEW.vote = 1), dislikes = dislikes - (OLD.vote = -1) + (NEW.vote = -1) WHERE id = NEW.comment_id`},
+	{"comment_votes_counters_delete", "DELETE", "comment_votes",
+		`UPDATE comments SET likes = likes - (OLD.vote = 1), dislikes = dislikes - (OLD.vote = -1) WHERE id = OLD.comment_id`},
+	// Помеченные удалёнными комментарии не учитываются в comment_count.
+	{"comments_counters_insert", "INSERT", "comments",
+		`UPDATE posts SET comment_count = comment_count + (NEW.deleted_at IS NULL) WHERE id = NEW.post_id`},
+	{"comments_counters_update", "UPDATE", "comments",
+		`UPDATE posts SET comment_count = comment_count - (OLD.deleted_at IS NULL) + (NEW.deleted_at IS NULL) WHERE id = NEW.post_id`},
+	{"comments_counters_delete", "DELETE", "comments",
+		`UPDATE posts SET comment_count = comment_count - (OLD.deleted_at IS NULL) WHERE id = OLD.post_id`},
+}
+
+// counterColumns — колонки счётчиков по таблицам.
+var counterColumns = [][2]string{
+	{"posts", "likes"},
+	{"posts", "dislikes"},
+	{"posts", "comment_count"},
+	{"comments", "likes"},
+	{"comments", "dislikes"},
+}
+
+// Выражения, пересчитывающие счётчики по таблицам голосов и комментариев.
+const (
+	recountPostLikes    = "(SELECT COUNT(*) FROM post_votes pv WHERE pv.post_id = posts.id AND pv.vote = 1)"
+	recountPostDislikes = "(SELECT COUNT(*) FROM post_votes pv WHERE pv.post_id = posts.id AND pv.vote = -1)"
+	recountPostComments = "(SELECT COUNT(*) FROM comments c WHERE c.post_id = posts.id AND c.deleted_at IS NULL)"
+
+	recountCommentLikes    = "(SELECT COUNT(*) FROM comment_votes cv WHERE cv.comment_id = comments.id AND cv.vote = 1)"
+	recountCommentDislikes = "(SELECT COUNT(*) FROM comment_votes cv WHERE cv.comment_id = comments.id AND cv.vote = -1)"
+)
+
+// recountStatements возвращает выражения, исправляющие расходящиеся счётчики постов и комментариев.
+func recountStatements() (posts, comments string) {
+	posts = "UPDATE posts SET likes = " + recountPostLikes + ", dislikes = " + recountPostDislikes +
+		", comment_count = " + recountPostComments +
+		" WHERE likes <> " + recountPostLikes + " OR dislikes <> " + recountPostDislikes +
+		" OR comment_count <> " + recountPostComments
+	comments = "UPDATE comments SET likes = " + recountCommentLikes + ", dislikes = " + recountCommentDislikes +
+		" WHERE likes <> " + recountCommentLikes + " OR dislikes <> " + recountCommentDislikes
+	return posts, comments
+}
+
+// createCounterTriggers создаёт триггеры счётчиков. SQLite требует тело триггера в BEGIN ... END,
+// MySQL принимает одно выражение без него.
+func createCounterTriggers(tx *sql.Tx, dialect string) error {
+	for _, t := range counterTriggers {
+		body := t.update
+		if dialect == DialectSQLite {
+			body = "BEGIN " + body + "; END"
+		}
+		stmt := fmt.Sprintf("CREATE TRIGGER %s AFTER %s ON %s FOR EACH ROW %s", t.name, t.event, t.table, body)
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("trigger %s: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+// dropCounterTriggers удаляет триггеры счётчиков.
+func dropCounterTriggers(tx *sql.Tx) error {
+	for _, t := range counterTriggers {
+		if _, err := tx.Exec("DROP TRIGGER IF EXISTS " + t.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReconcileCounters пересчитывает счётчики постов и комментариев, разошедшиеся с таблицами голосов
+// и комментариев, и возвращает число исправленных постов и комментариев.
+func ReconcileCounters(ctx context.Context, db *sql.DB) (int64, int64, error) {
+	postsStmt, commentsStmt := recountStatements()
+	res, err := db.ExecContext(ctx, postsStmt)
+	if err != nil {
+		return 0, 0, err
+	}
+	posts, _ := res.RowsAffected()
+	res, err = db.ExecContext(ctx, commentsStmt)
+	if err != nil {
+		return posts, 0, err
+	}
+	comments, _ := res.RowsAffected()
+	return posts, comments, nil
+}
@@ -1,16 +1,19 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"forum/models"
-
-	_ "github.com/mattn/go-sqlite3"
+	"forum/ranking"
 )
 
 // SessionsMu protects concurrent access to the in-memory session store.
@@ -19,115 +22,50 @@ var SessionsMu sync.RWMutex
 // Sessions хранит сессии пользователей.
 var Sessions = make(map[string]models.SessionData)
 
-// InitDB открывает или создаёт базу данных и выполняет миграции схемы.
-func InitDB() (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", "./forum.db?_foreign_keys=on")
-	if err != nil {
+// InitDB открывает или создаёт базу данных форума по пути path с параметрами opts и выполняет миграции схемы.
+func InitDB(path string, opts SQLiteOptions) (*sql.DB, error) {
+	return OpenSQLiteWithOptions(path, opts)
+}
+
+// OpenSQLite открывает или создаёт базу данных SQLite по пути path с параметрами по умолчанию
+// и выполняет миграции схемы.
+func OpenSQLite(path string) (*sql.DB, error) {
+	return OpenSQLiteWithOptions(path, DefaultSQLiteOptions())
+}
+
+// OpenSQLiteWithOptions открывает или создаёт базу данных SQLite по пути path с параметрами opts
+// и выполняет миграции схемы.
+func OpenSQLiteWithOptions(path string, opts SQLiteOptions) (*sql.DB, error) {
+	if err := opts.Validate(); err != nil {
 		return nil, err
 	}
+	db := sql.OpenDB(withSlowQueryLog(newSQLiteConnector(path, opts), opts.SlowQueryThreshold))
+	opts.Pool.apply(db)
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, err
 	}
-	if err := ensureSchema(db); err != nil {
+	if err := Migrate(db); err != nil {
 		db.Close()
 		return nil, err
 	}
+	if err := ensureSearchIndex(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("search index: %w", err)
+	}
+	PrepareStatements(db)
 	return db, nil
 }
 
-// ensureSchema создаёт необходимые таблицы и базовые данные, если они отсутствуют.
-func ensureSchema(db *sql.DB) error {
-	statements := []string{
-		`PRAGMA foreign_keys = ON;`,
-		`CREATE TABLE IF NOT EXISTS users (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			email TEXT NOT NULL UNIQUE,
-			username TEXT NOT NULL UNIQUE,
-			password TEXT NOT NULL,
-			role TEXT NOT NULL DEFAULT 'user',
-			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-		);`,
-		`CREATE TABLE IF NOT EXISTS sessions (
-			session_id TEXT PRIMARY KEY,
-			user_id INTEGER NOT NULL,
-			role TEXT NOT NULL,
-			expiry DATETIME NOT NULL,
-			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
-		);`,
-		`CREATE TABLE IF NOT EXISTS posts (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			user_id INTEGER NOT NULL,
-			title TEXT NOT NULL,
-			content TEXT NOT NULL,
-			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			image_url TEXT,
-			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
-		);`,
-		`CREATE TABLE IF NOT EXISTS categories (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT NOT NULL UNIQUE
-		);`,
-		`CREATE TABLE IF NOT EXISTS post_categories (
-			post_id INTEGER NOT NULL,
-			category_id INTEGER NOT NULL,
-			PRIMARY KEY(post_id, category_id),
-			FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
-			FOREIGN KEY(category_id) REFERENCES categories(id) ON DELETE CASCADE
-		);`,
-		`CREATE TABLE IF NOT EXISTS post_votes (
-			user_id INTEGER NOT NULL,
-			post_id INTEGER NOT NULL,
-			vote INTEGER NOT NULL CHECK(vote IN (-1, 1)),
-			PRIMARY KEY(user_id, post_id),
-			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
-			FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
-		);`,
-		`CREATE TABLE IF NOT EXISTS comments (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			post_id INTEGER NOT NULL,
-			user_id INTEGER NOT NULL,
-			content TEXT NOT NULL,
-			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
-			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
-		);`,
-		`CREATE TABLE IF NOT EXISTS comment_votes (
-			user_id INTEGER NOT NULL,
-			comment_id INTEGER NOT NULL,
-			vote INTEGER NOT NULL CHECK(vote IN (-1, 1)),
-			PRIMARY KEY(user_id, comment_id),
-			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
-			FOREIGN KEY(comment_id) REFERENCES comments(id) ON DELETE CASCADE
-		);`,
-	}
-
-	for _, stmt := range statements {
-		if _, err := db.Exec(stmt); err != nil {
-			return fmt.Errorf("schema migration failed: %w", err)
-		}
-	}
-
-	categories := []string{"news", "life", "auto", "creative", "gadgets", "science", "games", "other"}
-	for _, name := range categories {
-		if _, err := db.Exec("INSERT OR IGNORE INTO categories (name) VALUES (?)", name); err != nil {
-			return fmt.Errorf("seed categories failed: %w", err)
-		}
-	}
-
-	// Ensure the display_name column exists in users table (for full name display).
-	// Ignore error if the column already exists.
-	_, _ = db.Exec("ALTER TABLE users ADD COLUMN display_name TEXT")
-
-	return nil
-}
+const querySessionData = "SELECT user_id, role, expiry FROM sessions WHERE session_id = ?"
 
 // GetSessionData возвращает userID, роль и срок действия сессии по sessionID.
 // В случае отсутствия сессии или ошибки возвращает нулевые значения и ошибку.
-func GetSessionData(db *sql.DB, sessionID string) (int, string, time.Time, error) {
+func GetSessionData(ctx context.Context, db *sql.DB, sessionID string) (int, string, time.Time, error) {
 	var userID int
 	var role string
 	var expiry time.Time
-	err := db.QueryRow("SELECT user_id, role, expiry FROM sessions WHERE session_id = ?", sessionID).Scan(&userID, &role, &expiry)
+	err := cachedQueryRow(ctx, db, querySessionData, sessionID).Scan(&userID, &role, &expiry)
 	if err != nil {
 		return 0, "", time.Time{}, err
 	}
@@ -136,8 +74,8 @@ func GetSessionData(db *sql.DB, sessionID string) (int, string, time.Time, error
 
 // DeleteExpiredSession удаляет истёкшую сессию из базы данных.
 // Логирует ошибку, если удаление не удалось.
-func DeleteExpiredSession(db *sql.DB, sessionID string) error {
-	_, err := db.Exec("DELETE FROM sessions WHERE session_id = ?", sessionID)
+func DeleteExpiredSession(ctx context.Context, db *sql.DB, sessionID string) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE session_id = ?", sessionID)
 	if err != nil {
 		log.Println("Error deleting expired session:", err)
 	}
@@ -146,8 +84,8 @@ func DeleteExpiredSession(db *sql.DB, sessionID string) error {
 
 // DeleteSession удаляет сессию из базы данных и из памяти.
 // Возвращает ошибку, если удаление из базы не удалось.
-func DeleteSession(db *sql.DB, sessionID string) error {
-	_, err := db.Exec("DELETE FROM sessions WHERE session_id = ?", sessionID)
+func DeleteSession(ctx context.Context, db *sql.DB, sessionID string) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE session_id = ?", sessionID)
 	if err != nil {
 		log.Println("Error deleting session from database:", err)
 		return err
@@ -156,21 +94,49 @@ func DeleteSession(db *sql.DB, sessionID string) error {
 	return nil
 }
 
+// PurgeExpiredSessions удаляет из базы все истёкшие сессии и возвращает число удалённых строк.
+// Срок хранится строкой с часовым поясом, поэтому сравнение идёт через julianday, а не как строк.
+func PurgeExpiredSessions(ctx context.Context, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE julianday(expiry) < julianday('now')")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PruneSessions удаляет из памяти сессии, истёкшие к моменту now, и возвращает их число.
+func PruneSessions(now time.Time) int {
+	SessionsMu.Lock()
+	defer SessionsMu.Unlock()
+	pruned := 0
+	for id, s := range Sessions {
+		if s.Expiry.Before(now) {
+			delete(Sessions, id)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+const queryUsernameByID = "SELECT username FROM users WHERE id = ?"
+
 // GetUsernameByID возвращает имя пользователя по его ID.
 // В случае отсутствия пользователя возвращает пустую строку и ошибку.
-func GetUsernameByID(db *sql.DB, userID int) (string, error) {
+func GetUsernameByID(ctx context.Context, db *sql.DB, userID int) (string, error) {
 	var username string
-	err := db.QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&username)
+	err := cachedQueryRow(ctx, db, queryUsernameByID, userID).Scan(&username)
 	if err != nil {
 		return "", err
 	}
 	return username, nil
 }
 
+const queryDisplayName = "SELECT display_name FROM users WHERE id = ?"
+
 // GetDisplayName returns the display_name for a user by ID.
-func GetDisplayName(db *sql.DB, userID int) (string, error) {
+func GetDisplayName(ctx context.Context, db *sql.DB, userID int) (string, error) {
 	var displayName sql.NullString
-	err := db.QueryRow("SELECT display_name FROM users WHERE id = ?", userID).Scan(&displayName)
+	err := cachedQueryRow(ctx, db, queryDisplayName, userID).Scan(&displayName)
 	if err != nil {
 		return "", err
 	}
@@ -182,10 +148,10 @@ func GetDisplayName(db *sql.DB, userID int) (string, error) {
 
 // GetUserByEmail возвращает ID, имя, хэш пароля и роль пользователя по email.
 // В случае отсутствия пользователя возвращает нулевые значения и ошибку.
-func GetUserByEmail(db *sql.DB, email string) (int, string, string, string, error) {
+func GetUserByEmail(ctx context.Context, db *sql.DB, email string) (int, string, string, string, error) {
 	var userID int
 	var username, hashedPassword, role string
-	err := db.QueryRow("SELECT id, username, password, role FROM users WHERE email = ?", email).Scan(&userID, &username, &hashedPassword, &role)
+	err := db.QueryRowContext(ctx, "SELECT id, username, password, role FROM users WHERE email = ?", email).Scan(&userID, &username, &hashedPassword, &role)
 	if err != nil {
 		return 0, "", "", "", err
 	}
@@ -194,10 +160,10 @@ func GetUserByEmail(db *sql.DB, email string) (int, string, string, string, erro
 
 // GetUserProfileData возвращает имя пользователя и дату создания профиля по ID.
 // В случае отсутствия пользователя возвращает пустые строки и ошибку.
-func GetUserProfileData(db *sql.DB, userID int) (string, time.Time, error) {
+func GetUserProfileData(ctx context.Context, db *sql.DB, userID int) (string, time.Time, error) {
 	var username string
 	var createdAt time.Time
-	err := db.QueryRow("SELECT username, created_at FROM users WHERE id = ?", userID).Scan(&username, &createdAt)
+	err := db.QueryRowContext(ctx, "SELECT username, created_at FROM users WHERE id = ?", userID).Scan(&username, &createdAt)
 	if err != nil {
 		return "", time.Now(), err
 	}
@@ -206,9 +172,9 @@ func GetUserProfileData(db *sql.DB, userID int) (string, time.Time, error) {
 
 // EmailExists проверяет, существует ли email в базе пользователей.
 // Возвращает true, если email существует, иначе false.
-func EmailExists(db *sql.DB, email string) (bool, error) {
+func EmailExists(ctx context.Context, db *sql.DB, email string) (bool, error) {
 	var exists string
-	err := db.QueryRow("SELECT email FROM users WHERE email = ?", email).Scan(&exists)
+	err := db.QueryRowContext(ctx, "SELECT email FROM users WHERE email = ?", email).Scan(&exists)
 	if err == sql.ErrNoRows {
 		return false, nil
 	}
@@ -220,11 +186,11 @@ func EmailExists(db *sql.DB, email string) (bool, error) {
 
 // UsernameExists проверяет, существует ли имя пользователя в базе.
 // Возвращает true, если имя существует, иначе false.
-func UsernameExists(db *sql.DB, username string) (bool, error) {
+func UsernameExists(ctx context.Context, db *sql.DB, username string) (bool, error) {
 	username = strings.ToLower(username)
 
 	var exists bool
-	err := db.QueryRow(
+	err := db.QueryRowContext(ctx,
 		"SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(username) = ?)",
 		username,
 	).Scan(&exists)
@@ -237,39 +203,233 @@ func UsernameExists(db *sql.DB, username string) (bool, error) {
 
 // RegisterUser создаёт нового пользователя с указанным email, именем и хэшем пароля.
 // Присваивает роль "user". Возвращает ошибку, если регистрация не удалась.
-func RegisterUser(db *sql.DB, email, username, hashedPassword string) error {
-	_, err := db.Exec("INSERT INTO users (email, username, password, role) VALUES (?, ?, ?, 'user')", email, username, hashedPassword)
+func RegisterUser(ctx context.Context, db *sql.DB, email, username, hashedPassword string) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO users (email, username, password, role) VALUES (?, ?, ?, 'user')", email, username, hashedPassword)
 	return err
 }
 
 // DeleteUserSessions удаляет все сессии пользователя из базы данных.
 // Возвращает ошибку, если удаление не удалось.
-func DeleteUserSessions(db *sql.DB, userID int) error {
-	_, err := db.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
+func DeleteUserSessions(ctx context.Context, db *sql.DB, userID int) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userID)
 	return err
 }
 
 // UpdateUserProfile updates username and display_name for a user.
-func UpdateUserProfile(db *sql.DB, userID int, username string, displayName string) error {
-	_, err := db.Exec("UPDATE users SET username = ?, display_name = ? WHERE id = ?", username, displayName, userID)
+func UpdateUserProfile(ctx context.Context, db *sql.DB, userID int, username string, displayName string) error {
+	_, err := db.ExecContext(ctx, "UPDATE users SET username = ?, display_name = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", username, displayName, userID)
+	return err
+}
+
+// GetUserStanding возвращает показатели пользователя, от которых зависят его права и уровень доверия:
+// карму — суммарный рейтинг (лайки минус дизлайки) его неудалённых постов и комментариев, — дату регистрации,
+// число прочитанных постов (счётчик users.posts_read, см. MarkPostViewed), своих неудалённых постов и комментариев
+// и роль. Если пользователя нет, возвращает sql.ErrNoRows.
+func GetUserStanding(ctx context.Context, db *sql.DB, userID int) (models.UserStanding, error) {
+	var s models.UserStanding
+	err := db.QueryRowContext(ctx, `
+        SELECT COALESCE((SELECT SUM(likes - dislikes) FROM posts WHERE user_id = u.id AND deleted_at IS NULL), 0)
+             + COALESCE((SELECT SUM(likes - dislikes) FROM comments WHERE user_id = u.id AND deleted_at IS NULL), 0),
+               u.created_at,
+               u.posts_read,
+               (SELECT COUNT(*) FROM posts WHERE user_id = u.id AND deleted_at IS NULL),
+               (SELECT COUNT(*) FROM comments WHERE user_id = u.id AND deleted_at IS NULL),
+               u.role
+        FROM users u WHERE u.id = ?
+    `, userID).Scan(&s.Karma, &s.CreatedAt, &s.Read, &s.Posts, &s.Comments, &s.Role)
+	return s, err
+}
+
+// CountRecentPosts возвращает, сколько из последних limit постов пользователя (включая удалённые) создано после since.
+func CountRecentPosts(ctx context.Context, db *sql.DB, userID int, since time.Time, limit int) (int, error) {
+	return countRecent(ctx, db, "SELECT created_at FROM posts WHERE user_id = ? ORDER BY id DESC LIMIT ?", userID, since, limit)
+}
+
+// CountRecentComments возвращает, сколько из последних limit комментариев пользователя (включая удалённые) создано после since.
+// created_at комментариев хранит местное время без часового пояса (см. handlers.CommentHandler), а драйверы читают его
+// как UTC, поэтому since переводится в то же представление.
+func CountRecentComments(ctx context.Context, db *sql.DB, userID int, since time.Time, limit int) (int, error) {
+	local := since.Local()
+	since = time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), time.UTC)
+	return countRecent(ctx, db, "SELECT created_at FROM comments WHERE user_id = ? ORDER BY id DESC LIMIT ?", userID, since, limit)
+}
+
+// CountRecentVotes возвращает, сколько лайков и дизлайков постам и комментариям пользователь поставил после since.
+// Снятый голос не учитывается, а изменённый считается по времени последнего изменения.
+// voted_at хранит CURRENT_TIMESTAMP в UTC, поэтому граница сравнивается как строка в UTC (см. GetLeaderboards).
+func CountRecentVotes(ctx context.Context, db *sql.DB, userID int, since time.Time) (int, error) {
+	bound := since.UTC().Format(leaderboardTime)
+	var n int
+	err := db.QueryRowContext(ctx, `
+        SELECT (SELECT COUNT(*) FROM post_votes WHERE user_id = ? AND voted_at >= ?)
+             + (SELECT COUNT(*) FROM comment_votes WHERE user_id = ? AND voted_at >= ?)
+    `, userID, bound, userID, bound).Scan(&n)
+	return n, err
+}
+
+// countRecent выполняет query, выбирающий created_at последних limit записей пользователя, и считает записи после since.
+// Время сравнивается в Go, а не в SQL: посты и комментарии хранят created_at в разных форматах.
+func countRecent(ctx context.Context, db *sql.DB, query string, userID int, since time.Time, limit int) (int, error) {
+	rows, err := db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var createdAt time.Time
+		if err := rows.Scan(&createdAt); err != nil {
+			return 0, err
+		}
+		if createdAt.After(since) {
+			n++
+		}
+	}
+	return n, rows.Err()
+}
+
+// activityDay — формат дня в GetUserActivity.
+const activityDay = "2006-01-02"
+
+// GetUserActivity возвращает по дням число постов и комментариев пользователя (без удалённых), созданных
+// с начала дня from по местному времени сервера, в порядке дат; дни без активности пропускаются.
+// Как и в CountRecentComments, время комментариев уже местное, а время постов переводится в местное.
+func GetUserActivity(ctx context.Context, db *sql.DB, userID int, from time.Time) ([]models.ActivityDay, error) {
+	from = from.Local()
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	// Форматы created_at разные, но все начинаются с ГГГГ-ММ-ДД, поэтому грубая граница берётся сравнением строк
+	// с запасом в день на часовой пояс, а точная проверяется при подсчёте.
+	bound := start.AddDate(0, 0, -1).Format(activityDay)
+
+	days := make(map[string]*models.ActivityDay)
+	count := func(query string, comments bool) error {
+		rows, err := db.QueryContext(ctx, query, userID, bound)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var createdAt time.Time
+			if err := rows.Scan(&createdAt); err != nil {
+				return err
+			}
+			if comments {
+				createdAt = time.Date(createdAt.Year(), createdAt.Month(), createdAt.Day(),
+					createdAt.Hour(), createdAt.Minute(), createdAt.Second(), createdAt.Nanosecond(), time.Local)
+			} else {
+				createdAt = createdAt.Local()
+			}
+			if createdAt.Before(start) {
+				continue
+			}
+			key := createdAt.Format(activityDay)
+			day := days[key]
+			if day == nil {
+				day = &models.ActivityDay{Date: key}
+				days[key] = day
+			}
+			if comments {
+				day.Comments++
+			} else {
+				day.Posts++
+			}
+			day.Count++
+		}
+		return rows.Err()
+	}
+	if err := count("SELECT created_at FROM posts WHERE user_id = ? AND deleted_at IS NULL AND created_at >= ?", false); err != nil {
+		return nil, err
+	}
+	if err := count("SELECT created_at FROM comments WHERE user_id = ? AND deleted_at IS NULL AND created_at >= ?", true); err != nil {
+		return nil, err
+	}
+
+	activity := make([]models.ActivityDay, 0, len(days))
+	for _, key := range slices.Sorted(maps.Keys(days)) {
+		activity = append(activity, *days[key])
+	}
+	return activity, nil
+}
+
+// GetPinnedPostID возвращает ID поста, закреплённого пользователем в профиле, или 0, если закреплённого поста нет.
+func GetPinnedPostID(ctx context.Context, db *sql.DB, userID int) (int, error) {
+	var postID sql.NullInt64
+	err := db.QueryRowContext(ctx, "SELECT pinned_post_id FROM users WHERE id = ?", userID).Scan(&postID)
+	return int(postID.Int64), err
+}
+
+// SetPinnedPost закрепляет пост postID в профиле пользователя; postID 0 снимает закрепление.
+// Принадлежность поста пользователю проверяет вызывающий.
+func SetPinnedPost(ctx context.Context, db *sql.DB, userID, postID int) error {
+	var pinned any
+	if postID != 0 {
+		pinned = postID
+	}
+	_, err := db.ExecContext(ctx, "UPDATE users SET pinned_post_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", pinned, userID)
+	return err
+}
+
+// SetUserRole меняет роль пользователя ("user", "admin" или "banned") и в той же транзакции обновляет его сессии:
+// роль копируется в сессию при входе, и без этого изменение вступило бы в силу только после следующего входа.
+// Заблокированному пользователю сессии удаляются, остальные сохраняют вход, но получают новую роль.
+func SetUserRole(ctx context.Context, db *sql.DB, userID int, role string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET role = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", role, userID); err != nil {
+		return err
+	}
+	if role == "banned" {
+		_, err = tx.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userID)
+	} else {
+		_, err = tx.ExecContext(ctx, "UPDATE sessions SET role = ? WHERE user_id = ?", role, userID)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdatePassword заменяет хэш пароля пользователя.
+func UpdatePassword(ctx context.Context, db *sql.DB, userID int, hashedPassword string) error {
+	_, err := db.ExecContext(ctx, "UPDATE users SET password = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", hashedPassword, userID)
+	return err
+}
+
+// GetLanguage возвращает язык интерфейса, выбранный пользователем, или пустую строку, если он не выбран.
+func GetLanguage(ctx context.Context, db *sql.DB, userID int) (string, error) {
+	var lang sql.NullString
+	if err := db.QueryRowContext(ctx, "SELECT language FROM users WHERE id = ?", userID).Scan(&lang); err != nil {
+		return "", err
+	}
+	return lang.String, nil
+}
+
+// SetLanguage сохраняет язык интерфейса пользователя.
+func SetLanguage(ctx context.Context, db *sql.DB, userID int, lang string) error {
+	_, err := db.ExecContext(ctx, "UPDATE users SET language = ? WHERE id = ?", lang, userID)
 	return err
 }
 
 // CreateSession создаёт новую сессию с указанным ID, userID, ролью и сроком действия.
 // Возвращает ошибку, если создание не удалось.
-func CreateSession(db *sql.DB, sessionID string, userID int, role string, expiry time.Time) error {
-	_, err := db.Exec("INSERT INTO sessions (session_id, user_id, role, expiry) VALUES (?, ?, ?, ?)", sessionID, userID, role, expiry)
+func CreateSession(ctx context.Context, db *sql.DB, sessionID string, userID int, role string, expiry time.Time) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO sessions (session_id, user_id, role, expiry) VALUES (?, ?, ?, ?)", sessionID, userID, role, expiry)
 	return err
 }
 
 // GetPostByIDAndUserID возвращает данные поста по его ID и ID пользователя.
 // В случае отсутствия поста возвращает пустую структуру и ошибку.
-func GetPostByIDAndUserID(db *sql.DB, postID int, userID int) (models.PostData, error) {
+func GetPostByIDAndUserID(ctx context.Context, db *sql.DB, postID int, userID int) (models.PostData, error) {
 	var post models.PostData
-	err := db.QueryRow(`
-        SELECT id, title, content, user_id, image_url
-        FROM posts WHERE id = ? AND user_id = ?
-    `, postID, userID).Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.ImageURL)
+	err := db.QueryRowContext(ctx, `
+        SELECT id, title, content, user_id, image_url, members_only, comment_policy, created_at
+        FROM posts WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+    `, postID, userID).Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.ImageURL, &post.MembersOnly,
+		&post.CommentPolicy, &post.CreatedAt)
 	if err != nil {
 		return models.PostData{}, err
 	}
@@ -278,29 +438,28 @@ func GetPostByIDAndUserID(db *sql.DB, postID int, userID int) (models.PostData,
 
 // GetPostOwnerID возвращает ID владельца поста по ID поста.
 // В случае отсутствия поста возвращает 0 и ошибку.
-func GetPostOwnerID(db *sql.DB, postID int) (int, error) {
+func GetPostOwnerID(ctx context.Context, db *sql.DB, postID int) (int, error) {
 	var ownerID int
-	err := db.QueryRow("SELECT user_id FROM posts WHERE id = ?", postID).Scan(&ownerID)
+	err := db.QueryRowContext(ctx, "SELECT user_id FROM posts WHERE id = ? AND deleted_at IS NULL", postID).Scan(&ownerID)
 	if err != nil {
 		return 0, err
 	}
 	return ownerID, nil
 }
 
-// GetUserPosts возвращает список постов пользователя с количеством лайков и дизлайков.
-// Сортирует посты по дате создания (от новых к старым).
-func GetUserPosts(db *sql.DB, userID int) ([]models.PostData, error) {
+// GetUserPosts возвращает посты пользователя userID с лайками, дизлайками и голосом просматривающего currentUserID.
+// Сортирует посты по дате создания (от новых к старым). Анонимному посетителю (currentUserID 0)
+// посты только для авторизованных не показываются.
+func GetUserPosts(ctx context.Context, db *sql.DB, userID, currentUserID int) ([]models.PostData, error) {
 	query := `
         SELECT p.id, p.title, p.content, p.created_at, p.image_url,
-               COALESCE(SUM(CASE WHEN pv.vote = 1 THEN 1 ELSE 0 END), 0) as likes,
-               COALESCE(SUM(CASE WHEN pv.vote = -1 THEN 1 ELSE 0 END), 0) as dislikes
+               p.likes, p.dislikes, p.comment_count,
+               COALESCE((SELECT pv.vote FROM post_votes pv WHERE pv.post_id = p.id AND pv.user_id = ?), 0) AS user_vote
         FROM posts p
-        LEFT JOIN post_votes pv ON p.id = pv.post_id
-        WHERE p.user_id = ?
-        GROUP BY p.id, p.title, p.content, p.created_at, p.image_url
+        WHERE p.user_id = ? AND p.deleted_at IS NULL AND (? > 0 OR NOT p.members_only)
         ORDER BY p.created_at DESC
     `
-	rows, err := db.Query(query, userID)
+	rows, err := db.QueryContext(ctx, query, currentUserID, userID, currentUserID)
 	if err != nil {
 		return nil, err
 	}
@@ -310,7 +469,7 @@ func GetUserPosts(db *sql.DB, userID int) ([]models.PostData, error) {
 	for rows.Next() {
 		var p models.PostData
 		var imageURL sql.NullString
-		if err := rows.Scan(&p.ID, &p.Title, &p.Content, &p.CreatedAt, &imageURL, &p.Likes, &p.Dislikes); err != nil {
+		if err := rows.Scan(&p.ID, &p.Title, &p.Content, &p.CreatedAt, &imageURL, &p.Likes, &p.Dislikes, &p.CommentCount, &p.UserVote); err != nil {
 			return nil, err
 		}
 		p.ImageURL = imageURL.String
@@ -320,12 +479,12 @@ func GetUserPosts(db *sql.DB, userID int) ([]models.PostData, error) {
 	return posts, nil
 }
 
-// CreatePost создаёт новый пост и возвращает его ID.
+// CreatePost создаёт новый пост в разделе boardID и возвращает его ID.
 // В случае ошибки возвращает 0 и ошибку.
-func CreatePost(db *sql.DB, userID int, title, content, imageURL string, createdAt time.Time) (int64, error) {
-	result, err := db.Exec(
-		"INSERT INTO posts (user_id, title, content, image_url, created_at) VALUES (?, ?, ?, ?, ?)",
-		userID, title, content, imageURL, createdAt,
+func CreatePost(ctx context.Context, db *sql.DB, userID, boardID int, title, content, imageURL string, createdAt time.Time) (int64, error) {
+	result, err := db.ExecContext(ctx,
+		"INSERT INTO posts (user_id, board_id, title, content, image_url, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		userID, boardID, title, content, imageURL, createdAt,
 	)
 	if err != nil {
 		return 0, err
@@ -337,49 +496,160 @@ func CreatePost(db *sql.DB, userID int, title, content, imageURL string, created
 	return postID, nil
 }
 
+// NewPost — пост, публикуемый из формы, вместе с настройками, которые должны действовать с первой минуты:
+// доступом только для авторизованных, правилом комментирования, категориями и серией.
+type NewPost struct {
+	UserID        int
+	BoardID       int
+	Title         string
+	Content       string
+	ImageURL      string
+	CreatedAt     time.Time
+	MembersOnly   bool
+	CommentPolicy string   // одно из CommentPolicies; пустое — CommentsEveryone
+	Categories    []string // названия категорий
+	SeriesID      int      // существующая серия автора или 0
+	NewSeries     string   // название новой серии; важнее SeriesID
+}
+
+// PublishPost создаёт пост p одной транзакцией: строка поста сразу получает доступ и правило комментирования,
+// а категории и серия записываются вместе с ней. Пост никогда не бывает виден без своих настроек — например,
+// открытым для анонимных посетителей, если автор закрыл его. Возвращает ID нового поста.
+func PublishPost(ctx context.Context, db *sql.DB, p NewPost) (int64, error) {
+	policy := p.CommentPolicy
+	if policy == "" {
+		policy = CommentsEveryone
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+        INSERT INTO posts (user_id, board_id, title, content, image_url, created_at, members_only, comment_policy)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+    `, p.UserID, p.BoardID, p.Title, p.Content, p.ImageURL, p.CreatedAt, p.MembersOnly, policy)
+	if err != nil {
+		return 0, err
+	}
+	postID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	for _, name := range p.Categories {
+		_, err := tx.ExecContext(ctx, "INSERT INTO post_categories (post_id, category_id) SELECT ?, id FROM categories WHERE name = ?", postID, name)
+		if err != nil {
+			return 0, err
+		}
+	}
+	seriesID := p.SeriesID
+	if p.NewSeries != "" {
+		res, err := tx.ExecContext(ctx, "INSERT INTO series (user_id, title, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)", p.UserID, p.NewSeries)
+		if err != nil {
+			return 0, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		seriesID = int(id)
+	}
+	if seriesID != 0 {
+		if err := appendSeriesPart(ctx, tx, seriesID, int(postID)); err != nil {
+			return 0, err
+		}
+	}
+	return postID, tx.Commit()
+}
+
 // UpdatePost обновляет заголовок, содержимое и URL изображения поста.
 // Возвращает ошибку, если обновление не удалось.
-func UpdatePost(db *sql.DB, postID int, title, content, imageURL string) error {
-	_, err := db.Exec("UPDATE posts SET title = ?, content = ?, image_url = ? WHERE id = ?", title, content, imageURL, postID)
+func UpdatePost(ctx context.Context, db *sql.DB, postID int, title, content, imageURL string) error {
+	_, err := db.ExecContext(ctx, "UPDATE posts SET title = ?, content = ?, image_url = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", title, content, imageURL, postID)
+	return err
+}
+
+// SetPostMembersOnly открывает пост только авторизованным пользователям (membersOnly) или всем посетителям.
+// Анонимные посетители не видят такой пост ни на его странице, ни в лентах, поиске, профиле автора и JSON API.
+func SetPostMembersOnly(ctx context.Context, db *sql.DB, postID int, membersOnly bool) error {
+	_, err := db.ExecContext(ctx, "UPDATE posts SET members_only = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", membersOnly, postID)
 	return err
 }
 
-// DeletePost удаляет пост по его ID.
+// DeletePost помечает пост удалённым (мягкое удаление): он и его комментарии скрываются из выдачи,
+// а окончательно удаляются вместе с голосами и категориями в PurgeDeletedContent.
 // Возвращает ошибку, если удаление не удалось.
-func DeletePost(db *sql.DB, postID int) error {
-	_, err := db.Exec("DELETE FROM posts WHERE id = ?", postID)
+func DeletePost(ctx context.Context, db *sql.DB, postID int) error {
+	_, err := db.ExecContext(ctx, "UPDATE posts SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL", postID)
 	return err
 }
 
-// GetPostCategories возвращает список категорий, связанных с постом.
+// PurgeDeletedContent окончательно удаляет посты и комментарии, помеченные удалёнными дольше retention назад.
+// Содержимое, скрытое автоматической проверкой до решения модератора (см. RecordScreening), и посты,
+// снятые автором с публикации (см. SetUserPostsUnpublished), не удаляются.
+// Голоса, категории и комментарии удаляемых постов удаляются каскадно по внешним ключам.
+// Возвращает число удалённых постов и комментариев.
+func PurgeDeletedContent(ctx context.Context, db *sql.DB, retention time.Duration) (int64, int64, error) {
+	return purgeDeleted(ctx, db, "deleted_at < datetime('now', ?)", fmt.Sprintf("-%d seconds", int64(retention/time.Second)))
+}
+
+// purgeDeleted выполняет очистку в одной транзакции; cond — условие истечения срока с одним параметром arg.
+func purgeDeleted(ctx context.Context, db *sql.DB, cond string, arg interface{}) (int64, int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM comments WHERE "+cond+" AND "+notPendingReview("comment"), arg)
+	if err != nil {
+		return 0, 0, err
+	}
+	comments, _ := res.RowsAffected()
+	res, err = tx.ExecContext(ctx, "DELETE FROM posts WHERE "+cond+" AND NOT unpublished AND "+notPendingReview("post"), arg)
+	if err != nil {
+		return 0, 0, err
+	}
+	posts, _ := res.RowsAffected()
+	return posts, comments, tx.Commit()
+}
+
+// notPendingReview — условие для строк вида kind, не ждущих решения модератора.
+func notPendingReview(kind string) string {
+	return "id NOT IN (SELECT item_id FROM moderation_decisions WHERE kind = '" + kind + "' AND status = 'pending')"
+}
+
+// GetPostCategories возвращает список категорий, связанных с постом, со значками и цветами (по порядку ID категории).
 // В случае ошибки возвращает nil и ошибку.
-func GetPostCategories(db *sql.DB, postID int) ([]string, error) {
-	rows, err := db.Query(`
-        SELECT c.name FROM categories c
+func GetPostCategories(ctx context.Context, db *sql.DB, postID int) ([]models.Category, error) {
+	rows, err := db.QueryContext(ctx, `
+        SELECT c.id, c.name, c.icon, c.color FROM categories c
         JOIN post_categories pc ON c.id = pc.category_id
         WHERE pc.post_id = ?
+        ORDER BY c.id
     `, postID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var categories []string
+	var categories []models.Category
 	for rows.Next() {
-		var catName string
-		if err := rows.Scan(&catName); err != nil {
+		var c models.Category
+		if err := rows.Scan(&c.ID, &c.Name, &c.Icon, &c.Color); err != nil {
 			return nil, err
 		}
-		categories = append(categories, catName)
+		categories = append(categories, c)
 	}
 	return categories, nil
 }
 
 // GetCategoryIDByName возвращает ID категории по её имени.
 // В случае отсутствия категории возвращает 0 и ошибку.
-func GetCategoryIDByName(db *sql.DB, catName string) (int, error) {
+func GetCategoryIDByName(ctx context.Context, db *sql.DB, catName string) (int, error) {
 	var catID int
-	err := db.QueryRow("SELECT id FROM categories WHERE name = ?", catName).Scan(&catID)
+	err := db.QueryRowContext(ctx, "SELECT id FROM categories WHERE name = ?", catName).Scan(&catID)
 	if err != nil {
 		return 0, err
 	}
@@ -388,37 +658,39 @@ func GetCategoryIDByName(db *sql.DB, catName string) (int, error) {
 
 // AddPostCategory связывает пост с категорией по их ID.
 // Возвращает ошибку, если операция не удалась.
-func AddPostCategory(db *sql.DB, postID int64, catID int) error {
-	_, err := db.Exec("INSERT INTO post_categories (post_id, category_id) VALUES (?, ?)", postID, catID)
+func AddPostCategory(ctx context.Context, db *sql.DB, postID int64, catID int) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO post_categories (post_id, category_id) VALUES (?, ?)", postID, catID)
 	return err
 }
 
 // DeletePostCategories удаляет все категории, связанные с постом.
 // Возвращает ошибку, если удаление не удалось.
-func DeletePostCategories(db *sql.DB, postID int) error {
-	_, err := db.Exec("DELETE FROM post_categories WHERE post_id = ?", postID)
+func DeletePostCategories(ctx context.Context, db *sql.DB, postID int) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM post_categories WHERE post_id = ?", postID)
 	return err
 }
 
 // DeletePostComments удаляет все комментарии к посту.
 // Возвращает ошибку, если удаление не удалось.
-func DeletePostComments(db *sql.DB, postID int) error {
-	_, err := db.Exec("DELETE FROM comments WHERE post_id = ?", postID)
+func DeletePostComments(ctx context.Context, db *sql.DB, postID int) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM comments WHERE post_id = ?", postID)
 	return err
 }
 
 // DeletePostVotes удаляет все лайки и дизлайки поста.
 // Возвращает ошибку, если удаление не удалось.
-func DeletePostVotes(db *sql.DB, postID int) error {
-	_, err := db.Exec("DELETE FROM post_votes WHERE post_id = ?", postID)
+func DeletePostVotes(ctx context.Context, db *sql.DB, postID int) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM post_votes WHERE post_id = ?", postID)
 	return err
 }
 
+const queryUserPostVote = "SELECT vote FROM post_votes WHERE user_id = ? AND post_id = ?"
+
 // GetUserPostVote возвращает голос пользователя за пост (1, -1 или 0).
 // Если голоса нет, возвращает 0 и false. При ошибке возвращает 0, false и ошибку.
-func GetUserPostVote(db *sql.DB, userID, postID int) (int64, bool, error) {
+func GetUserPostVote(ctx context.Context, db *sql.DB, userID, postID int) (int64, bool, error) {
 	var currentVote sql.NullInt64
-	err := db.QueryRow("SELECT vote FROM post_votes WHERE user_id = ? AND post_id = ?", userID, postID).Scan(&currentVote)
+	err := cachedQueryRow(ctx, db, queryUserPostVote, userID, postID).Scan(&currentVote)
 	if err == sql.ErrNoRows {
 		return 0, false, nil
 	}
@@ -430,42 +702,43 @@ func GetUserPostVote(db *sql.DB, userID, postID int) (int64, bool, error) {
 
 // RemovePostVote удаляет голос пользователя за пост.
 // Возвращает ошибку, если удаление не удалось.
-func RemovePostVote(db *sql.DB, userID, postID int) error {
-	_, err := db.Exec("DELETE FROM post_votes WHERE user_id = ? AND post_id = ?", userID, postID)
+func RemovePostVote(ctx context.Context, db *sql.DB, userID, postID int) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM post_votes WHERE user_id = ? AND post_id = ?", userID, postID)
 	return err
 }
 
 // SetPostLike устанавливает или обновляет лайк пользователя для поста.
 // Возвращает ошибку, если операция не удалась.
-func SetPostLike(db *sql.DB, userID, postID int) error {
-	_, err := db.Exec(`
-        INSERT INTO post_votes (user_id, post_id, vote) VALUES (?, ?, 1)
-        ON CONFLICT(user_id, post_id) DO UPDATE SET vote = 1
+func SetPostLike(ctx context.Context, db *sql.DB, userID, postID int) error {
+	_, err := db.ExecContext(ctx, `
+        INSERT INTO post_votes (user_id, post_id, vote, voted_at) VALUES (?, ?, 1, CURRENT_TIMESTAMP)
+        ON CONFLICT(user_id, post_id) DO UPDATE SET vote = 1, voted_at = CURRENT_TIMESTAMP
     `, userID, postID)
 	return err
 }
 
 // SetPostDislike устанавливает или обновляет дизлайк пользователя для поста.
 // Возвращает ошибку, если операция не удалась.
-func SetPostDislike(db *sql.DB, userID, postID int) error {
-	_, err := db.Exec(`
-        INSERT INTO post_votes (user_id, post_id, vote) VALUES (?, ?, -1)
-        ON CONFLICT(user_id, post_id) DO UPDATE SET vote = -1
+func SetPostDislike(ctx context.Context, db *sql.DB, userID, postID int) error {
+	_, err := db.ExecContext(ctx, `
+        INSERT INTO post_votes (user_id, post_id, vote, voted_at) VALUES (?, ?, -1, CURRENT_TIMESTAMP)
+        ON CONFLICT(user_id, post_id) DO UPDATE SET vote = -1, voted_at = CURRENT_TIMESTAMP
     `, userID, postID)
 	return err
 }
 
+const queryPostVoteStats = `
+        SELECT likes, dislikes,
+               (SELECT vote FROM post_votes WHERE user_id = ? AND post_id = ?)
+        FROM posts WHERE id = ?
+    `
+
 // GetPostVoteStats возвращает количество лайков, дизлайков и голос пользователя для поста.
 // Если голоса пользователя нет, возвращает 0 и false для userVote.
-func GetPostVoteStats(db *sql.DB, userID, postID int) (int, int, int64, bool, error) {
+func GetPostVoteStats(ctx context.Context, db *sql.DB, userID, postID int) (int, int, int64, bool, error) {
 	var likes, dislikes int
 	var userVote sql.NullInt64
-	err := db.QueryRow(`
-        SELECT COALESCE(SUM(CASE WHEN vote = 1 THEN 1 ELSE 0 END), 0),
-               COALESCE(SUM(CASE WHEN vote = -1 THEN 1 ELSE 0 END), 0),
-               (SELECT vote FROM post_votes WHERE user_id = ? AND post_id = ?)
-        FROM post_votes WHERE post_id = ?
-    `, userID, postID, postID).Scan(&likes, &dislikes, &userVote)
+	err := cachedQueryRow(ctx, db, queryPostVoteStats, userID, postID, postID).Scan(&likes, &dislikes, &userVote)
 	if err != nil {
 		return 0, 0, 0, false, err
 	}
@@ -477,8 +750,8 @@ func GetPostVoteStats(db *sql.DB, userID, postID int) (int, int, int64, bool, er
 
 // CreateComment создаёт новый комментарий к посту и возвращает его ID.
 // В случае ошибки возвращает 0 и ошибку.
-func CreateComment(db *sql.DB, postID int, userID int, content, createdAt string) (int64, error) {
-	result, err := db.Exec(`
+func CreateComment(ctx context.Context, db *sql.DB, postID int, userID int, content, createdAt string) (int64, error) {
+	result, err := db.ExecContext(ctx, `
 		INSERT INTO comments (post_id, user_id, content, created_at)
 		VALUES (?, ?, ?, ?)`,
 		postID, userID, content, createdAt,
@@ -495,20 +768,16 @@ func CreateComment(db *sql.DB, postID int, userID int, content, createdAt string
 
 // GetCommentsByPostIDWithUserVote возвращает комментарии к посту с лайками, дизлайками и голосом текущего пользователя.
 // Сортирует комментарии по дате создания (от новых к старым).
-func GetCommentsByPostIDWithUserVote(db *sql.DB, currentUserID, postID int) ([]models.CommentData, error) {
+func GetCommentsByPostIDWithUserVote(ctx context.Context, db *sql.DB, currentUserID, postID int) ([]models.CommentData, error) {
 	query := `
-        SELECT c.id, c.content, c.created_at, u.id, u.username,
-               COALESCE(SUM(CASE WHEN cv.vote = 1 THEN 1 ELSE 0 END), 0) as likes,
-               COALESCE(SUM(CASE WHEN cv.vote = -1 THEN 1 ELSE 0 END), 0) as dislikes,
-               (SELECT cv2.vote FROM comment_votes cv2 WHERE cv2.comment_id = c.id AND cv2.user_id = ?) as user_vote
+        SELECT c.id, c.content, c.created_at, u.id, u.username, COALESCE(u.display_name, ''), c.likes, c.dislikes,
+               (SELECT cv.vote FROM comment_votes cv WHERE cv.comment_id = c.id AND cv.user_id = ?) as user_vote
         FROM comments c
         JOIN users u ON c.user_id = u.id
-        LEFT JOIN comment_votes cv ON c.id = cv.comment_id
-        WHERE c.post_id = ?
-        GROUP BY c.id, c.content, c.created_at, u.id, u.username
+        WHERE c.post_id = ? AND c.deleted_at IS NULL
         ORDER BY c.created_at DESC
     `
-	rows, err := db.Query(query, currentUserID, postID)
+	rows, err := db.QueryContext(ctx, query, currentUserID, postID)
 	if err != nil {
 		return nil, err
 	}
@@ -518,7 +787,7 @@ func GetCommentsByPostIDWithUserVote(db *sql.DB, currentUserID, postID int) ([]m
 	for rows.Next() {
 		var c models.CommentData
 		var userVote sql.NullInt64
-		if err := rows.Scan(&c.ID, &c.Content, &c.CreatedAt, &c.UserID, &c.Username, &c.Likes, &c.Dislikes, &userVote); err != nil {
+		if err := rows.Scan(&c.ID, &c.Content, &c.CreatedAt, &c.UserID, &c.Username, &c.DisplayName, &c.Likes, &c.Dislikes, &userVote); err != nil {
 			return nil, err
 		}
 		if userVote.Valid {
@@ -529,25 +798,27 @@ func GetCommentsByPostIDWithUserVote(db *sql.DB, currentUserID, postID int) ([]m
 	return comments, nil
 }
 
-// DeleteComment удаляет комментарий по его ID.
+// DeleteComment помечает комментарий удалённым (мягкое удаление); строка удаляется в PurgeDeletedContent.
 // Возвращает ошибку, если удаление не удалось.
-func DeleteComment(db *sql.DB, commentID int) error {
-	_, err := db.Exec("DELETE FROM comments WHERE id = ?", commentID)
+func DeleteComment(ctx context.Context, db *sql.DB, commentID int) error {
+	_, err := db.ExecContext(ctx, "UPDATE comments SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL", commentID)
 	return err
 }
 
 // DeleteCommentVotes удаляет все лайки и дизлайки комментария.
 // Возвращает ошибку, если удаление не удалось.
-func DeleteCommentVotes(db *sql.DB, commentID int) error {
-	_, err := db.Exec("DELETE FROM comment_votes WHERE comment_id = ?", commentID)
+func DeleteCommentVotes(ctx context.Context, db *sql.DB, commentID int) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM comment_votes WHERE comment_id = ?", commentID)
 	return err
 }
 
+const queryUserCommentVote = "SELECT vote FROM comment_votes WHERE user_id = ? AND comment_id = ?"
+
 // GetUserCommentVote возвращает голос пользователя за комментарий (1, -1 или 0).
 // Если голоса нет, возвращает 0 и false. При ошибке возвращает 0, false и ошибку.
-func GetUserCommentVote(db *sql.DB, userID, commentID int) (int64, bool, error) {
+func GetUserCommentVote(ctx context.Context, db *sql.DB, userID, commentID int) (int64, bool, error) {
 	var currentVote sql.NullInt64
-	err := db.QueryRow("SELECT vote FROM comment_votes WHERE user_id = ? AND comment_id = ?", userID, commentID).Scan(&currentVote)
+	err := cachedQueryRow(ctx, db, queryUserCommentVote, userID, commentID).Scan(&currentVote)
 	if err == sql.ErrNoRows {
 		return 0, false, nil
 	}
@@ -559,42 +830,43 @@ func GetUserCommentVote(db *sql.DB, userID, commentID int) (int64, bool, error)
 
 // RemoveCommentVote удаляет голос пользователя за комментарий.
 // Возвращает ошибку, если удаление не удалось.
-func RemoveCommentVote(db *sql.DB, userID, commentID int) error {
-	_, err := db.Exec("DELETE FROM comment_votes WHERE user_id = ? AND comment_id = ?", userID, commentID)
+func RemoveCommentVote(ctx context.Context, db *sql.DB, userID, commentID int) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM comment_votes WHERE user_id = ? AND comment_id = ?", userID, commentID)
 	return err
 }
 
 // SetCommentLike устанавливает или обновляет лайк пользователя для комментария.
 // Возвращает ошибку, если операция не удалась.
-func SetCommentLike(db *sql.DB, userID, commentID int) error {
-	_, err := db.Exec(`
-        INSERT INTO comment_votes (user_id, comment_id, vote) VALUES (?, ?, 1)
-        ON CONFLICT(user_id, comment_id) DO UPDATE SET vote = 1
+func SetCommentLike(ctx context.Context, db *sql.DB, userID, commentID int) error {
+	_, err := db.ExecContext(ctx, `
+        INSERT INTO comment_votes (user_id, comment_id, vote, voted_at) VALUES (?, ?, 1, CURRENT_TIMESTAMP)
+        ON CONFLICT(user_id, comment_id) DO UPDATE SET vote = 1, voted_at = CURRENT_TIMESTAMP
     `, userID, commentID)
 	return err
 }
 
 // SetCommentDislike устанавливает или обновляет дизлайк пользователя для комментария.
 // Возвращает ошибку, если операция не удалась.
-func SetCommentDislike(db *sql.DB, userID, commentID int) error {
-	_, err := db.Exec(`
-        INSERT INTO comment_votes (user_id, comment_id, vote) VALUES (?, ?, -1)
-        ON CONFLICT(user_id, comment_id) DO UPDATE SET vote = -1
+func SetCommentDislike(ctx context.Context, db *sql.DB, userID, commentID int) error {
+	_, err := db.ExecContext(ctx, `
+        INSERT INTO comment_votes (user_id, comment_id, vote, voted_at) VALUES (?, ?, -1, CURRENT_TIMESTAMP)
+        ON CONFLICT(user_id, comment_id) DO UPDATE SET vote = -1, voted_at = CURRENT_TIMESTAMP
     `, userID, commentID)
 	return err
 }
 
+const queryCommentVoteStats = `
+        SELECT likes, dislikes,
+               (SELECT vote FROM comment_votes WHERE user_id = ? AND comment_id = ?)
+        FROM comments WHERE id = ?
+    `
+
 // GetCommentVoteStats возвращает количество лайков, дизлайков и голос пользователя для комментария.
 // Если голоса пользователя нет, возвращает 0 и false для userVote.
-func GetCommentVoteStats(db *sql.DB, userID, commentID int) (int, int, int64, bool, error) {
+func GetCommentVoteStats(ctx context.Context, db *sql.DB, userID, commentID int) (int, int, int64, bool, error) {
 	var likes, dislikes int
 	var userVote sql.NullInt64
-	err := db.QueryRow(`
-        SELECT COALESCE(SUM(CASE WHEN vote = 1 THEN 1 ELSE 0 END), 0),
-               COALESCE(SUM(CASE WHEN vote = -1 THEN 1 ELSE 0 END), 0),
-               (SELECT vote FROM comment_votes WHERE user_id = ? AND comment_id = ?)
-        FROM comment_votes WHERE comment_id = ?
-    `, userID, commentID, commentID).Scan(&likes, &dislikes, &userVote)
+	err := cachedQueryRow(ctx, db, queryCommentVoteStats, userID, commentID, commentID).Scan(&likes, &dislikes, &userVote)
 	if err != nil {
 		return 0, 0, 0, false, err
 	}
@@ -604,59 +876,31 @@ func GetCommentVoteStats(db *sql.DB, userID, commentID int) (int, int, int64, bo
 	return likes, dislikes, 0, false, nil
 }
 
-// GetPosts возвращает список постов с учётом фильтра (my, liked, commented, best, new) и категории.
-// Включает лайки, дизлайки, голос пользователя и категории поста.
-func GetPosts(db *sql.DB, userID int, filter, category string) ([]models.PostData, error) {
-	query := `
-        SELECT p.id, p.title, p.content, p.created_at, p.image_url, p.user_id, u.username,
-               COALESCE(SUM(CASE WHEN pv.vote = 1 THEN 1 ELSE 0 END), 0) AS likes,
-               COALESCE(SUM(CASE WHEN pv.vote = -1 THEN 1 ELSE 0 END), 0) AS dislikes,
-               COALESCE(pv_user.vote, 0) AS user_vote,
-               GROUP_CONCAT(c.name) AS categories
-        FROM posts p
-        JOIN users u ON p.user_id = u.id
-        LEFT JOIN post_votes pv ON p.id = pv.post_id
-        LEFT JOIN post_votes pv_user ON p.id = pv_user.post_id AND pv_user.user_id = ?
-        LEFT JOIN post_categories pc ON p.id = pc.post_id
-        LEFT JOIN categories c ON pc.category_id = c.id
-    `
-	args := []interface{}{userID}
-
-	var orderBy string
-	switch filter {
-	case "my":
-		query += " WHERE p.user_id = ?"
-		args = append(args, userID)
-		orderBy = " ORDER BY p.created_at DESC"
-	case "liked":
-		query += " WHERE EXISTS (SELECT 1 FROM post_votes pv2 WHERE pv2.post_id = p.id AND pv2.user_id = ? AND pv2.vote = 1)"
-		args = append(args, userID)
-		orderBy = " ORDER BY p.created_at DESC"
-	case "commented":
-		query += " WHERE EXISTS (SELECT 1 FROM comments c WHERE c.post_id = p.id AND c.user_id = ?)"
-		args = append(args, userID)
-		orderBy = " ORDER BY p.created_at DESC"
-	case "best":
-		orderBy = " ORDER BY (COALESCE(SUM(CASE WHEN pv.vote = 1 THEN 1 ELSE 0 END), 0) - COALESCE(SUM(CASE WHEN pv.vote = -1 THEN 1 ELSE 0 END), 0)) DESC"
-	case "new":
-		orderBy = " ORDER BY p.created_at DESC"
-	default:
-		filter = "new"
-		orderBy = " ORDER BY p.created_at DESC"
-	}
-
-	if category != "" {
-		if filter == "new" || filter == "best" {
-			query += " WHERE c.name = ?"
-		} else {
-			query += " AND c.name = ?"
-		}
-		args = append(args, category)
-	}
+// FeedQuery — параметры ленты для GetPosts и GetFeedPage.
+type FeedQuery struct {
+	Filter     string    // new, best, my, liked, disliked, commented, my-comments, unread или unanswered
+	Category   string    // название категории; пустая строка — все категории
+	Board      string    // адрес раздела; пустая строка — все разделы
+	ShowHidden bool      // не пропускать посты с рейтингом ниже порога SettingHideScoreBelow
+	Author     string    // имя автора без учёта регистра; пустая строка — все авторы
+	From       time.Time // только посты, созданные не раньше From; нулевое время — без ограничения
+	To         time.Time // только посты, созданные раньше To; нулевое время — без ограничения
+}
 
-	query += " GROUP BY p.id, p.title, p.content, p.created_at, p.image_url, p.user_id, pv_user.vote" + orderBy
+// GetPosts возвращает список постов ленты q: с учётом фильтра (my, liked, disliked, commented, my-comments, unread,
+// unanswered, best, new), категории, раздела, автора и периода (см. feedWhere). Для неизвестного фильтра
+// возвращает ErrUnknownFilter.
+// В общих лентах (new, best, unread, unanswered) посты с рейтингом ниже порога SettingHideScoreBelow пропускаются, если showHidden не задан;
+// в личных фильтрах они остаются. Включает лайки, дизлайки, голос пользователя, категории, раздел поста и отметки Hidden и Unread.
+// Лайки и дизлайки берутся из счётчиков поста (см. counters.go), категории собираются подзапросом.
+func GetPosts(ctx context.Context, db *sql.DB, userID int, q FeedQuery) ([]models.PostData, error) {
+	query, args, err := feedSelect(userID, q)
+	if err != nil {
+		return nil, err
+	}
+	query += feedOrder(q.Filter)
 
-	rows, err := db.Query(query, args...)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %v", err)
 	}
@@ -664,19 +908,10 @@ func GetPosts(db *sql.DB, userID int, filter, category string) ([]models.PostDat
 
 	var posts []models.PostData
 	for rows.Next() {
-		var p models.PostData
-		var imageURL sql.NullString
-		var categories sql.NullString
-		if err := rows.Scan(&p.ID, &p.Title, &p.Content, &p.CreatedAt, &imageURL, &p.UserID, &p.Username, &p.Likes, &p.Dislikes, &p.UserVote, &categories); err != nil {
+		p, _, err := scanFeedPost(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan failed: %v", err)
 		}
-		p.ImageURL = imageURL.String
-		if categories.Valid {
-			p.Categories = strings.Split(categories.String, ",")
-		}
-		if len(p.Categories) > 0 {
-			p.Category = p.Categories[0]
-		}
 		posts = append(posts, p)
 	}
 
@@ -684,24 +919,305 @@ func GetPosts(db *sql.DB, userID int, filter, category string) ([]models.PostDat
 		return nil, fmt.Errorf("rows error: %v", err)
 	}
 
+	if q.Filter == "best" {
+		decay, err := bestDecay(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		if decay.Enabled() {
+			posts = rankPosts(posts, decay, time.Now())
+		}
+	}
 	return posts, nil
 }
 
+// rankedFeedCandidates — сколько самых новых постов упорядочивает лента «Лучшие» с затуханием рейтинга.
+// Более старые посты к этому времени затухают и в ленту не попадают.
+const rankedFeedCandidates = 1000
+
+// rankPosts возвращает posts, упорядоченные по весу рейтинга с затуханием decay в момент now.
+// При равном весе сохраняется исходный порядок.
+func rankPosts(posts []models.PostData, decay ranking.Decay, now time.Time) []models.PostData {
+	items := make([]ranking.Item, len(posts))
+	for i, p := range posts {
+		items[i] = ranking.Item{Score: p.Likes - p.Dislikes, Created: p.CreatedAt}
+	}
+	ranked := make([]models.PostData, 0, len(posts))
+	for _, i := range decay.Order(items, now) {
+		ranked = append(ranked, posts[i])
+	}
+	return ranked
+}
+
+// feedOrder возвращает порядок ленты: best — по рейтингу, остальные — по времени создания; при равенстве — по ID.
+// GetPosts и GetFeedPage используют один порядок, чтобы постраничная лента совпадала с полной.
+// Если для best включено затухание рейтинга (SettingBestHalfLife), обе функции переупорядочивают посты в Go.
+func feedOrder(filter string) string {
+	if filter == "best" {
+		return " ORDER BY p.likes - p.dislikes DESC, CAST(p.created_at AS CHAR) DESC, p.id DESC"
+	}
+	return " ORDER BY CAST(p.created_at AS CHAR) DESC, p.id DESC"
+}
+
+// GetFeedPage — вариант GetPosts с постраничным выводом по ключу для бесконечной прокрутки ленты:
+// возвращает не больше limit постов после позиции cursor и курсор следующей страницы
+// (пустой, если страница последняя). Пустой cursor означает первую страницу.
+// Порядок задаёт feedOrder.
+func GetFeedPage(ctx context.Context, db *sql.DB, userID int, q FeedQuery, limit int, cursor string) ([]models.PostData, string, error) {
+	query, args, err := feedSelect(userID, q)
+	if err != nil {
+		return nil, "", err
+	}
+	return queryFeedPage(ctx, db, query, args, q.Filter, limit, cursor)
+}
+
+// queryFeedPage дописывает к запросу feedSelect условие курсора, порядок ленты filter (см. feedOrder) и LIMIT
+// и возвращает страницу постов с курсором следующей страницы.
+func queryFeedPage(ctx context.Context, db *sql.DB, query string, args []interface{}, filter string, limit int, cursor string) ([]models.PostData, string, error) {
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	best := filter == "best"
+	if best {
+		decay, err := bestDecay(ctx, db)
+		if err != nil {
+			return nil, "", err
+		}
+		if decay.Enabled() {
+			return queryRankedFeedPage(ctx, db, query, args, decay, limit, after)
+		}
+	}
+	if after != nil {
+		if best {
+			query += " AND (p.likes - p.dislikes, CAST(p.created_at AS CHAR), p.id) < (?, ?, ?)"
+			args = append(args, after.Score, after.CreatedAt, after.ID)
+		} else {
+			query += " AND (CAST(p.created_at AS CHAR), p.id) < (?, ?)"
+			args = append(args, after.CreatedAt, after.ID)
+		}
+	}
+	query += feedOrder(filter) + " LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("query failed: %v", err)
+	}
+	defer rows.Close()
+
+	posts := []models.PostData{}
+	var last pageCursor
+	for rows.Next() {
+		p, position, err := scanFeedPost(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("scan failed: %v", err)
+		}
+		last = position
+		if !best {
+			last.Score = 0
+		}
+		posts = append(posts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("rows error: %v", err)
+	}
+	if len(posts) < limit {
+		return posts, "", nil
+	}
+	return posts, last.encode(), nil
+}
+
+// queryRankedFeedPage возвращает страницу ленты «Лучшие» с затуханием рейтинга decay: упорядочивает
+// rankedFeedCandidates самых новых постов запроса feedSelect в Go (см. pageCursor) и возвращает limit из них
+// после позиции after.
+func queryRankedFeedPage(ctx context.Context, db *sql.DB, query string, args []interface{}, decay ranking.Decay, limit int, after *pageCursor) ([]models.PostData, string, error) {
+	now, offset := time.Now(), 0
+	if after != nil && after.RankedAt > 0 {
+		now, offset = time.Unix(after.RankedAt, 0), after.Offset
+	}
+	query += feedOrder("new") + " LIMIT ?"
+	args = append(args, rankedFeedCandidates)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("query failed: %v", err)
+	}
+	defer rows.Close()
+
+	var candidates []models.PostData
+	positions := make(map[int]pageCursor)
+	for rows.Next() {
+		p, position, err := scanFeedPost(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("scan failed: %v", err)
+		}
+		positions[p.ID] = position
+		candidates = append(candidates, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("rows error: %v", err)
+	}
+
+	ranked := rankPosts(candidates, decay, now)
+	if offset >= len(ranked) {
+		return []models.PostData{}, "", nil
+	}
+	posts := ranked[offset:min(offset+limit, len(ranked))]
+	if offset+limit >= len(ranked) {
+		return posts, "", nil
+	}
+	next := positions[posts[len(posts)-1].ID]
+	next.Score, next.Offset, next.RankedAt = 0, offset+limit, now.Unix()
+	return posts, next.encode(), nil
+}
+
+// feedSelect собирает общий для GetPosts и GetFeedPage запрос ленты без сортировки и возвращает его параметры.
+// Запрос заканчивается условиями WHERE (см. feedWhere), поэтому к нему можно дописывать условия через AND.
+// Колонки запроса разбирает scanFeedPost.
+func feedSelect(userID int, q FeedQuery) (string, []interface{}, error) {
+	where, err := feedWhere(userID, q)
+	if err != nil {
+		return "", nil, err
+	}
+	query := `
+        SELECT p.id, p.title, p.content, p.created_at, p.image_url, p.user_id, u.username, COALESCE(u.display_name, ''),
+               p.likes, p.dislikes, p.comment_count,
+               COALESCE((SELECT pv.vote FROM post_votes pv WHERE pv.post_id = p.id AND pv.user_id = ?), 0) AS user_vote,
+               ` + categoryColumns + `,
+               b.id, b.slug, b.name,
+               ` + hiddenPostCondition + `,
+               ` + unreadPostCondition + `,
+               CAST(p.created_at AS CHAR)
+        FROM posts p
+        JOIN users u ON p.user_id = u.id
+        LEFT JOIN boards b ON b.id = p.board_id
+        WHERE ` + where.String()
+	args := append([]interface{}{userID}, unreadPostArgs(userID)...)
+	return query, append(args, where.args...), nil
+}
+
+// ErrUnknownFilter возвращается для неизвестного фильтра ленты.
+var ErrUnknownFilter = errors.New("unknown feed filter")
+
+// feedFilters — условия фильтров ленты для пользователя userID. Общие ленты (sharedFeedFilters) скрывают
+// посты с низким рейтингом, если не задан FeedQuery.ShowHidden; личные показывают все посты.
+var feedFilters = map[string]func(userID int) (string, []interface{}){
+	"new":  func(int) (string, []interface{}) { return "", nil },
+	"best": func(int) (string, []interface{}) { return "", nil },
+	"my":   func(userID int) (string, []interface{}) { return "p.user_id = ?", []interface{}{userID} },
+	"liked": func(userID int) (string, []interface{}) {
+		return "EXISTS (SELECT 1 FROM post_votes fv WHERE fv.post_id = p.id AND fv.user_id = ? AND fv.vote = 1)", []interface{}{userID}
+	},
+	"disliked": func(userID int) (string, []interface{}) {
+		return "EXISTS (SELECT 1 FROM post_votes fv WHERE fv.post_id = p.id AND fv.user_id = ? AND fv.vote = -1)", []interface{}{userID}
+	},
+	// my-comments отличается от commented только карточками: они ведут к комментарию пользователя (см. handlers).
+	"commented":   commentedCondition,
+	"my-comments": commentedCondition,
+	"unread": func(userID int) (string, []interface{}) {
+		return unreadPostCondition, unreadPostArgs(userID)
+	},
+	"unanswered": func(int) (string, []interface{}) { return "p.comment_count = 0", nil },
+}
+
+// sharedFeedFilters — общие ленты, в которых посты с рейтингом ниже порога SettingHideScoreBelow скрыты.
+var sharedFeedFilters = map[string]bool{"new": true, "best": true, "unread": true, "unanswered": true}
+
+// commentedCondition — условие фильтров commented и my-comments: пользователь оставил под постом комментарий.
+func commentedCondition(userID int) (string, []interface{}) {
+	return "EXISTS (SELECT 1 FROM comments fc WHERE fc.post_id = p.id AND fc.user_id = ? AND fc.deleted_at IS NULL)", []interface{}{userID}
+}
+
+// feedWhere собирает условия ленты q: фильтр, скрытие постов с низким рейтингом, категорию, раздел, автора и период.
+// Каждое условие добавляется отдельно со своими параметрами, поэтому любой фильтр сочетается с остальными.
+// Анонимному посетителю (userID 0) посты только для авторизованных не показываются.
+// Для неизвестного фильтра возвращает ErrUnknownFilter.
+func feedWhere(userID int, q FeedQuery) (whereClause, error) {
+	var where whereClause
+	where.and("p.deleted_at IS NULL")
+	if userID == 0 {
+		where.and("NOT p.members_only")
+	}
+	filter, ok := feedFilters[q.Filter]
+	if !ok {
+		return where, fmt.Errorf("%w: %q", ErrUnknownFilter, q.Filter)
+	}
+	if cond, args := filter(userID); cond != "" {
+		where.and(cond, args...)
+	}
+	if sharedFeedFilters[q.Filter] && !q.ShowHidden {
+		where.and("NOT " + hiddenPostCondition)
+	}
+	if q.Category != "" {
+		where.and(`EXISTS (SELECT 1 FROM post_categories fpc JOIN categories fcat ON fpc.category_id = fcat.id
+                   WHERE fpc.post_id = p.id AND fcat.name = ?)`, q.Category)
+	}
+	if q.Board != "" {
+		where.and("b.slug = ?", q.Board)
+	}
+	if q.Author != "" {
+		where.and("LOWER(u.username) = ?", strings.ToLower(q.Author))
+	}
+	// Посты хранят местное время сервера и сравниваются как строка, как и в поиске по дате.
+	if !q.From.IsZero() {
+		where.and("CAST(p.created_at AS CHAR) >= ?", q.From.Local().Format(leaderboardTime))
+	}
+	if !q.To.IsZero() {
+		where.and("CAST(p.created_at AS CHAR) < ?", q.To.Local().Format(leaderboardTime))
+	}
+	return where, nil
+}
+
+// whereClause — условия WHERE, соединяемые через AND, вместе с их параметрами в порядке следования.
+type whereClause struct {
+	conds []string
+	args  []interface{}
+}
+
+// and добавляет условие cond с параметрами args. Условие берётся в скобки, чтобы OR внутри него
+// не менял смысл соседних условий.
+func (w *whereClause) and(cond string, args ...interface{}) {
+	w.conds = append(w.conds, "("+cond+")")
+	w.args = append(w.args, args...)
+}
+
+// String возвращает условия через AND.
+func (w whereClause) String() string {
+	return strings.Join(w.conds, " AND ")
+}
+
+// scanFeedPost разбирает строку запроса feedSelect и возвращает пост вместе с его позицией для курсора.
+func scanFeedPost(rows *sql.Rows) (models.PostData, pageCursor, error) {
+	var p models.PostData
+	var position pageCursor
+	var imageURL sql.NullString
+	var categories nullCategories
+	var board nullBoard
+	if err := rows.Scan(&p.ID, &p.Title, &p.Content, &p.CreatedAt, &imageURL, &p.UserID, &p.Username, &p.DisplayName, &p.Likes, &p.Dislikes, &p.CommentCount, &p.UserVote,
+		&categories.names, &categories.icons, &categories.colors,
+		&board.id, &board.slug, &board.name, &p.Hidden, &p.Unread, &position.CreatedAt); err != nil {
+		return p, position, err
+	}
+	p.ImageURL = imageURL.String
+	board.apply(&p)
+	categories.apply(&p)
+	position.Score = p.Likes - p.Dislikes
+	position.ID = p.ID
+	return p, position, nil
+}
+
 // GetCommentsByPostID возвращает список комментариев к посту с лайками и дизлайками.
 // Сортирует комментарии по дате создания (от старых к новым).
-func GetCommentsByPostID(db *sql.DB, userID, postID int) ([]models.CommentData, error) {
+func GetCommentsByPostID(ctx context.Context, db *sql.DB, userID, postID int) ([]models.CommentData, error) {
 	query := `
-        SELECT c.id, c.post_id, c.user_id, u.username, c.content, c.created_at,
-               COALESCE(SUM(CASE WHEN cv.vote = 1 THEN 1 ELSE 0 END), 0) AS likes,
-               COALESCE(SUM(CASE WHEN cv.vote = -1 THEN 1 ELSE 0 END), 0) AS dislikes
+        SELECT c.id, c.post_id, c.user_id, u.username, COALESCE(u.display_name, ''), c.content, c.created_at, c.likes, c.dislikes
         FROM comments c
         JOIN users u ON c.user_id = u.id
-        LEFT JOIN comment_votes cv ON c.id = cv.comment_id
-        WHERE c.post_id = ?
-        GROUP BY c.id, c.post_id, c.user_id, u.username, c.content, c.created_at
+        WHERE c.post_id = ? AND c.deleted_at IS NULL
         ORDER BY c.created_at ASC
     `
-	rows, err := db.Query(query, postID)
+	rows, err := db.QueryContext(ctx, query, postID)
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %v", err)
 	}
@@ -710,7 +1226,7 @@ func GetCommentsByPostID(db *sql.DB, userID, postID int) ([]models.CommentData,
 	var comments []models.CommentData
 	for rows.Next() {
 		var c models.CommentData
-		if err := rows.Scan(&c.ID, &c.PostID, &c.UserID, &c.Username, &c.Content, &c.CreatedAt, &c.Likes, &c.Dislikes); err != nil {
+		if err := rows.Scan(&c.ID, &c.PostID, &c.UserID, &c.Username, &c.DisplayName, &c.Content, &c.CreatedAt, &c.Likes, &c.Dislikes); err != nil {
 			return nil, fmt.Errorf("scan failed: %v", err)
 		}
 		comments = append(comments, c)
@@ -723,55 +1239,91 @@ func GetCommentsByPostID(db *sql.DB, userID, postID int) ([]models.CommentData,
 	return comments, nil
 }
 
-// GetPostByID возвращает данные поста по его ID, включая лайки, дизлайки, голос пользователя и категории.
+// GetPostByID возвращает данные поста по его ID, включая лайки, дизлайки, голос пользователя, категории и раздел.
 // В случае отсутствия поста возвращает пустую структуру и ошибку.
-func GetPostByID(db *sql.DB, postID, currentUserID int) (models.PostData, error) {
+func GetPostByID(ctx context.Context, db *sql.DB, postID, currentUserID int) (models.PostData, error) {
 	var post models.PostData
 	var imageURL sql.NullString
-	var categories sql.NullString
+	var categories nullCategories
+	var board nullBoard
 
 	query := `
-        SELECT p.id, p.title, p.content, p.created_at, p.image_url, p.user_id, u.username,
-               COALESCE(SUM(CASE WHEN pv.vote = 1 THEN 1 ELSE 0 END), 0) AS likes,
-               COALESCE(SUM(CASE WHEN pv.vote = -1 THEN 1 ELSE 0 END), 0) AS dislikes,
-               COALESCE(pv_user.vote, 0) AS user_vote,
-               GROUP_CONCAT(c.name) AS categories
+        SELECT p.id, p.title, p.content, p.created_at, p.image_url, p.user_id, u.username, COALESCE(u.display_name, ''),
+               p.likes, p.dislikes,
+               COALESCE((SELECT pv.vote FROM post_votes pv WHERE pv.post_id = p.id AND pv.user_id = ?), 0) AS user_vote,
+               ` + categoryColumns + `,
+               b.id, b.slug, b.name, p.members_only, p.comment_policy
         FROM posts p
         JOIN users u ON p.user_id = u.id
-        LEFT JOIN post_votes pv ON p.id = pv.post_id
-        LEFT JOIN post_votes pv_user ON p.id = pv_user.post_id AND pv_user.user_id = ?
-        LEFT JOIN post_categories pc ON p.id = pc.post_id
-        LEFT JOIN categories c ON pc.category_id = c.id
-        WHERE p.id = ?
-        GROUP BY p.id, p.title, p.content, p.created_at, p.image_url, p.user_id, u.username, pv_user.vote
+        LEFT JOIN boards b ON b.id = p.board_id
+        WHERE p.id = ? AND p.deleted_at IS NULL
     `
 
-	err := db.QueryRow(query, currentUserID, postID).Scan(
+	err := db.QueryRowContext(ctx, query, currentUserID, postID).Scan(
 		&post.ID, &post.Title, &post.Content, &post.CreatedAt, &imageURL,
-		&post.UserID, &post.Username, &post.Likes, &post.Dislikes, &post.UserVote, &categories,
+		&post.UserID, &post.Username, &post.DisplayName, &post.Likes, &post.Dislikes, &post.UserVote,
+		&categories.names, &categories.icons, &categories.colors,
+		&board.id, &board.slug, &board.name, &post.MembersOnly, &post.CommentPolicy,
 	)
 	if err != nil {
 		return models.PostData{}, err
 	}
 
 	post.ImageURL = imageURL.String
-	if categories.Valid {
-		post.Categories = strings.Split(categories.String, ",")
-	}
-	if len(post.Categories) > 0 {
-		post.Category = post.Categories[0]
-	}
+	board.apply(&post)
+	categories.apply(&post)
 
 	return post, nil
 }
 
 // GetCommentOwnerID возвращает ID владельца комментария по его ID.
 // В случае отсутствия комментария возвращает 0 и ошибку.
-func GetCommentOwnerID(db *sql.DB, commentID int) (int, error) {
+func GetCommentOwnerID(ctx context.Context, db *sql.DB, commentID int) (int, error) {
 	var ownerID int
-	err := db.QueryRow("SELECT user_id FROM comments WHERE id = ?", commentID).Scan(&ownerID)
+	err := db.QueryRowContext(ctx, "SELECT user_id FROM comments WHERE id = ? AND deleted_at IS NULL", commentID).Scan(&ownerID)
 	if err != nil {
 		return 0, err
 	}
 	return ownerID, nil
 }
+
+// GetPostSummaries возвращает облегчённый список постов для JSON API.
+// Поддерживает сортировку new/best, фильтр по категории и автору, а также постраничный вывод через limit/offset.
+// Текст поста обрезается до excerptLen символов на стороне базы, чтобы не передавать полное содержимое.
+func GetPostSummaries(ctx context.Context, db *sql.DB, filter, category string, authorID, excerptLen, limit, offset int) ([]models.PostSummary, error) {
+	query := `
+        SELECT p.id, p.title, SUBSTR(p.content, 1, ?) AS excerpt,
+               p.likes - p.dislikes AS score, p.comment_count
+        FROM posts p
+        WHERE p.deleted_at IS NULL
+    `
+	filters, filterArgs := summaryFilters(category, authorID)
+	query += filters
+	args := append([]interface{}{excerptLen}, filterArgs...)
+	if filter == "best" {
+		query += " ORDER BY score DESC, p.created_at DESC, p.id DESC"
+	} else {
+		query += " ORDER BY p.created_at DESC, p.id DESC"
+	}
+	query += " LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %v", err)
+	}
+	defer rows.Close()
+
+	posts := []models.PostSummary{}
+	for rows.Next() {
+		var p models.PostSummary
+		if err := rows.Scan(&p.ID, &p.Title, &p.Excerpt, &p.Score, &p.CommentCount); err != nil {
+			return nil, fmt.Errorf("scan failed: %v", err)
+		}
+		posts = append(posts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %v", err)
+	}
+	return posts, nil
+}
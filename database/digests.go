@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"forum/models"
+)
+
+const (
+	// DigestWeekFormat — формат недели в адресах /digest/{week}: дата понедельника.
+	DigestWeekFormat = "2006-01-02"
+	// DigestSize — сколько лучших постов каждой категории попадает в недельную подборку.
+	DigestSize = 5
+)
+
+// DigestWeek возвращает начало недели (понедельник, 00:00 по местному времени сервера), в которую попадает t.
+func DigestWeek(t time.Time) time.Time {
+	t = t.Local()
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.Local)
+}
+
+// ParseDigestWeek разбирает неделю из адреса. Неделя задаётся понедельником; другие дни недели не принимаются,
+// чтобы у каждой подборки был один адрес.
+func ParseDigestWeek(value string) (time.Time, bool) {
+	t, err := time.ParseInLocation(DigestWeekFormat, value, time.Local)
+	if err != nil || t.Weekday() != time.Monday {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// BuildDigest подбирает лучшие посты недели, начинающейся в week: до limit постов каждой категории,
+// созданных в течение недели, по рейтингу (лайки минус дизлайки), затем по числу комментариев.
+// Пост с несколькими категориями попадает в каждую из них; категории без постов пропускаются. Подборка публичная
+// и уходит в рассылку, поэтому посты только для авторизованных в неё не попадают.
+// Время постов сравнивается как строка местного времени сервера, как и в таблицах лидеров (см. GetLeaderboards).
+func BuildDigest(ctx context.Context, db *sql.DB, week time.Time, limit int) (models.Digest, error) {
+	d := models.Digest{Week: week.Format(DigestWeekFormat), Start: week, End: week.AddDate(0, 0, 7)}
+	rows, err := db.QueryContext(ctx, `
+        SELECT c.name, p.id, p.title, u.id, u.username, p.likes - p.dislikes, p.comment_count
+        FROM posts p
+        JOIN users u ON u.id = p.user_id
+        JOIN post_categories pc ON pc.post_id = p.id
+        JOIN categories c ON c.id = pc.category_id
+        WHERE p.deleted_at IS NULL AND NOT p.members_only AND p.created_at >= ? AND p.created_at < ?
+        ORDER BY c.id, p.likes - p.dislikes DESC, p.comment_count DESC, p.id`,
+		d.Start.Format(leaderboardTime), d.End.Format(leaderboardTime))
+	if err != nil {
+		return d, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var category string
+		var p models.DigestPost
+		if err := rows.Scan(&category, &p.ID, &p.Title, &p.UserID, &p.Username, &p.Score, &p.Comments); err != nil {
+			return d, err
+		}
+		n := len(d.Categories)
+		if n == 0 || d.Categories[n-1].Name != category {
+			d.Categories = append(d.Categories, models.DigestCategory{Name: category})
+			n++
+		}
+		if len(d.Categories[n-1].Posts) < limit {
+			d.Categories[n-1].Posts = append(d.Categories[n-1].Posts, p)
+		}
+	}
+	return d, rows.Err()
+}
+
+// GetDigest возвращает сохранённую подборку недели week (см. DigestWeekFormat) или sql.ErrNoRows.
+func GetDigest(ctx context.Context, db *sql.DB, week string) (models.Digest, error) {
+	var d models.Digest
+	var content string
+	err := db.QueryRowContext(ctx, "SELECT week, content, created_at FROM digests WHERE week = ?", week).
+		Scan(&d.Week, &content, &d.CreatedAt)
+	if err != nil {
+		return d, err
+	}
+	if err := json.Unmarshal([]byte(content), &d.Categories); err != nil {
+		return d, err
+	}
+	if start, ok := ParseDigestWeek(d.Week); ok {
+		d.Start, d.End = start, start.AddDate(0, 0, 7)
+	}
+	return d, nil
+}
+
+// ListDigestWeeks возвращает недели сохранённых подборок, начиная с последней, не больше limit.
+func ListDigestWeeks(ctx context.Context, db *sql.DB, limit int) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT week FROM digests ORDER BY week DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var weeks []string
+	for rows.Next() {
+		var week string
+		if err := rows.Scan(&week); err != nil {
+			return nil, err
+		}
+		weeks = append(weeks, week)
+	}
+	return weeks, rows.Err()
+}
+
+// EnsureDigest возвращает подборку недели week, а если её ещё нет — собирает (см. BuildDigest) и сохраняет.
+// Сохранённая подборка не пересчитывается: голоса, поставленные после подведения итогов, её не меняют.
+// created сообщает, что подборка создана этим вызовом. Наличие проверяется в той же транзакции,
+// что и запись, поэтому несколько экземпляров сервера не создадут подборку дважды.
+func EnsureDigest(ctx context.Context, db *sql.DB, week time.Time, limit int) (d models.Digest, created bool, err error) {
+	key := week.Format(DigestWeekFormat)
+	if d, err = GetDigest(ctx, db, key); err != sql.ErrNoRows {
+		return d, false, err
+	}
+	if d, err = BuildDigest(ctx, db, week, limit); err != nil {
+		return d, false, err
+	}
+	content, err := json.Marshal(d.Categories)
+	if err != nil {
+		return d, false, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return d, false, err
+	}
+	defer tx.Rollback()
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM digests WHERE week = ?)", key).Scan(&exists); err != nil {
+		return d, false, err
+	}
+	if exists {
+		tx.Rollback()
+		d, err = GetDigest(ctx, db, key)
+		return d, false, err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO digests (week, content) VALUES (?, ?)", key, string(content)); err != nil {
+		return d, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return d, false, err
+	}
+	d.CreatedAt = time.Now()
+	return d, true, nil
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Кто может комментировать пост (колонка posts.comment_policy).
+const (
+	CommentsEveryone  = "everyone"  // любой авторизованный пользователь
+	CommentsFollowers = "followers" // только подписчики автора
+	CommentsNobody    = "nobody"    // комментарии закрыты
+)
+
+// CommentPolicies — допустимые значения comment_policy в порядке вывода в форме поста.
+var CommentPolicies = []string{CommentsEveryone, CommentsFollowers, CommentsNobody}
+
+// SetPostCommentPolicy задаёт, кто может комментировать пост (одно из CommentPolicies).
+func SetPostCommentPolicy(ctx context.Context, db *sql.DB, postID int, policy string) error {
+	_, err := db.ExecContext(ctx, "UPDATE posts SET comment_policy = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", policy, postID)
+	return err
+}
+
+// CanComment сообщает, может ли пользователь userID комментировать пост postID, и если нет — какое правило
+// поста мешает (CommentsFollowers или CommentsNobody). Автор всегда может отвечать в своём посте.
+// Если поста нет, возвращает sql.ErrNoRows.
+func CanComment(ctx context.Context, db *sql.DB, postID, userID int) (bool, string, error) {
+	var authorID int
+	var policy string
+	err := db.QueryRowContext(ctx, "SELECT user_id, comment_policy FROM posts WHERE id = ? AND deleted_at IS NULL", postID).Scan(&authorID, &policy)
+	if err != nil {
+		return false, "", err
+	}
+	switch {
+	case authorID == userID || policy == CommentsEveryone:
+		return true, "", nil
+	case policy == CommentsFollowers:
+		following, err := IsFollowing(ctx, db, userID, authorID)
+		return following, policy, err
+	}
+	return false, policy, nil
+}
+
+// Follow подписывает пользователя followerID на followeeID (follow) или отменяет подписку.
+// Повторная подписка, подписка на несуществующего пользователя и отмена несуществующей подписки ничего не меняют.
+func Follow(ctx context.Context, db *sql.DB, followerID, followeeID int, follow bool) error {
+	if !follow {
+		_, err := db.ExecContext(ctx, "DELETE FROM follows WHERE follower_id = ? AND followee_id = ?", followerID, followeeID)
+		return err
+	}
+	_, err := db.ExecContext(ctx, `
+        INSERT INTO follows (follower_id, followee_id)
+        SELECT ?, id FROM users
+        WHERE id = ? AND NOT EXISTS (SELECT 1 FROM follows WHERE follower_id = ? AND followee_id = ?)`,
+		followerID, followeeID, followerID, followeeID)
+	return err
+}
+
+// IsFollowing сообщает, подписан ли followerID на followeeID.
+func IsFollowing(ctx context.Context, db *sql.DB, followerID, followeeID int) (bool, error) {
+	var following bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM follows WHERE follower_id = ? AND followee_id = ?)",
+		followerID, followeeID).Scan(&following)
+	return following, err
+}
+
+// CountFollowers возвращает число подписчиков пользователя userID.
+func CountFollowers(ctx context.Context, db *sql.DB, userID int) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM follows WHERE followee_id = ?", userID).Scan(&n)
+	return n, err
+}
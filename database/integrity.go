@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// integrityCheck описывает одну проверку: строки таблицы table, для которых выполняется условие orphan,
+// ссылаются на несуществующие записи. Условия написаны на общем для SQLite и MySQL диалекте.
+type integrityCheck struct {
+	name   string
+	table  string
+	orphan string
+}
+
+// integrityChecks перечислены так, что родительские таблицы исправляются раньше дочерних:
+// удаление поста без автора каскадно удаляет его комментарии и голоса.
+// Помеченные удалёнными посты и комментарии не считаются ошибкой — их удаляет задача очистки.
+var integrityChecks = []integrityCheck{
+	{"sessions without user", "sessions", "NOT EXISTS (SELECT 1 FROM users u WHERE u.id = sessions.user_id)"},
+	{"posts without author", "posts", "NOT EXISTS (SELECT 1 FROM users u WHERE u.id = posts.user_id)"},
+	{"comments without post", "comments", "NOT EXISTS (SELECT 1 FROM posts p WHERE p.id = comments.post_id)"},
+	{"comments without author", "comments", "NOT EXISTS (SELECT 1 FROM users u WHERE u.id = comments.user_id)"},
+	{"post_categories without post", "post_categories", "NOT EXISTS (SELECT 1 FROM posts p WHERE p.id = post_categories.post_id)"},
+	{"post_categories without category", "post_categories", "NOT EXISTS (SELECT 1 FROM categories c WHERE c.id = post_categories.category_id)"},
+	{"post_votes without post", "post_votes", "NOT EXISTS (SELECT 1 FROM posts p WHERE p.id = post_votes.post_id)"},
+	{"post_votes without user", "post_votes", "NOT EXISTS (SELECT 1 FROM users u WHERE u.id = post_votes.user_id)"},
+	{"comment_votes without comment", "comment_votes", "NOT EXISTS (SELECT 1 FROM comments c WHERE c.id = comment_votes.comment_id)"},
+	{"comment_votes without user", "comment_votes", "NOT EXISTS (SELECT 1 FROM users u WHERE u.id = comment_votes.user_id)"},
+}
+
+// IntegrityIssue — результат одной проверки целостности.
+type IntegrityIssue struct {
+	Check    string `json:"check"`
+	Rows     int64  `json:"rows"`
+	Repaired int64  `json:"repaired"`
+}
+
+// CheckIntegrity ищет строки, ссылающиеся на несуществующие записи. Такие строки появляются в базах,
+// созданных до включения внешних ключей, или после ручного редактирования. Возвращает только проверки
+// с найденными строками. При repair найденные строки удаляются в одной транзакции.
+func CheckIntegrity(ctx context.Context, db *sql.DB, repair bool) ([]IntegrityIssue, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	issues := []IntegrityIssue{}
+	for _, c := range integrityChecks {
+		var n int64
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+c.table+" WHERE "+c.orphan).Scan(&n); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.name, err)
+		}
+		if n == 0 {
+			continue
+		}
+		issue := IntegrityIssue{Check: c.name, Rows: n}
+		if repair {
+			res, err := tx.ExecContext(ctx, "DELETE FROM "+c.table+" WHERE "+c.orphan)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", c.name, err)
+			}
+			issue.Repaired, _ = res.RowsAffected()
+		}
+		issues = append(issues, issue)
+	}
+	if !repair {
+		return issues, nil
+	}
+	return issues, tx.Commit()
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestCheckIntegrity проверяет, что строки без родительских записей находятся и удаляются.
+func TestCheckIntegrity(t *testing.T) {
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "forum.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		CloseStatements(db)
+		db.Close()
+	})
+	ctx := context.Background()
+
+	// Внешние ключи включены для каждого соединения, поэтому «сирот» создаём на отдельном соединении без них.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		"PRAGMA foreign_keys = OFF",
+		"INSERT INTO users (id, email, username, password) VALUES (1, 'a@example.com', 'alice', 'x')",
+		"INSERT INTO posts (id, user_id, title, content) VALUES (1, 1, 'kept', 'text')",
+		"INSERT INTO posts (id, user_id, title, content) VALUES (2, 99, 'orphan', 'text')",
+		"INSERT INTO post_votes (user_id, post_id, vote) VALUES (1, 42, 1)",
+		"INSERT INTO post_categories (post_id, category_id) VALUES (42, 1)",
+		"INSERT INTO sessions (session_id, user_id, role, expiry) VALUES ('s1', 99, 'user', CURRENT_TIMESTAMP)",
+		"PRAGMA foreign_keys = ON",
+	} {
+		if _, err := conn.ExecContext(ctx, q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	conn.Close()
+
+	issues, err := CheckIntegrity(ctx, db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]int64{}
+	for _, issue := range issues {
+		found[issue.Check] = issue.Rows
+	}
+	for _, name := range []string{"sessions without user", "posts without author", "post_votes without post", "post_categories without post"} {
+		if found[name] != 1 {
+			t.Errorf("%s: found %d rows, want 1 (report %+v)", name, found[name], issues)
+		}
+	}
+
+	if _, err := CheckIntegrity(ctx, db, true); err != nil {
+		t.Fatal(err)
+	}
+	if issues, err := CheckIntegrity(ctx, db, false); err != nil || len(issues) != 0 {
+		t.Fatalf("after repair: %+v, %v", issues, err)
+	}
+	var posts int
+	if err := db.QueryRow("SELECT COUNT(*) FROM posts").Scan(&posts); err != nil || posts != 1 {
+		t.Fatalf("posts after repair = %d, %v, want 1", posts, err)
+	}
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+
+	"forum/models"
+)
+
+// ErrInviteLimit возвращается, когда пользователь уже создал все доступные ему приглашения.
+var ErrInviteLimit = errors.New("invite limit reached")
+
+// ErrInvalidInvite возвращается при регистрации по несуществующему или уже использованному приглашению.
+var ErrInvalidInvite = errors.New("invalid or used invite")
+
+// CreateInvite создаёт приглашение пользователя inviterID и возвращает его код. Пользователь может создать
+// не больше limit приглашений за всё время, считая использованные; limit меньше нуля снимает ограничение.
+func CreateInvite(ctx context.Context, db *sql.DB, inviterID, limit int) (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := base64.RawURLEncoding.EncodeToString(b)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	if limit >= 0 {
+		var count int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM invites WHERE inviter_id = ?", inviterID).Scan(&count); err != nil {
+			return "", err
+		}
+		if count >= limit {
+			return "", ErrInviteLimit
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO invites (code, inviter_id) VALUES (?, ?)", code, inviterID); err != nil {
+		return "", err
+	}
+	return code, tx.Commit()
+}
+
+// ListInvites возвращает приглашения пользователя inviterID, новые первыми, с именами зарегистрировавшихся по ним.
+func ListInvites(ctx context.Context, db *sql.DB, inviterID int) ([]models.Invite, error) {
+	rows, err := db.QueryContext(ctx, `
+        SELECT i.code, i.created_at, COALESCE(u.id, 0), COALESCE(u.username, ''), i.used_at IS NOT NULL
+        FROM invites i LEFT JOIN users u ON u.id = i.invitee_id
+        WHERE i.inviter_id = ?
+        ORDER BY i.created_at DESC, i.id DESC
+    `, inviterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var invites []models.Invite
+	for rows.Next() {
+		var i models.Invite
+		if err := rows.Scan(&i.Code, &i.CreatedAt, &i.InviteeID, &i.Invitee, &i.Used); err != nil {
+			return nil, err
+		}
+		invites = append(invites, i)
+	}
+	return invites, rows.Err()
+}
+
+// RegisterInvitedUser регистрирует пользователя, как RegisterUser, по приглашению code и в той же транзакции
+// отмечает приглашение использованным. Если приглашения нет или по нему уже зарегистрировались,
+// возвращает ErrInvalidInvite и пользователя не создаёт.
+func RegisterInvitedUser(ctx context.Context, db *sql.DB, code, email, username, hashedPassword string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, "INSERT INTO users (email, username, password, role) VALUES (?, ?, ?, 'user')", email, username, hashedPassword)
+	if err != nil {
+		return err
+	}
+	userID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	res, err = tx.ExecContext(ctx, "UPDATE invites SET invitee_id = ?, used_at = CURRENT_TIMESTAMP WHERE code = ? AND used_at IS NULL", userID, code)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrInvalidInvite
+	}
+	return tx.Commit()
+}
+
+// InviteTree возвращает дерево приглашений: в корне — пользователи, которые приглашали других, но сами
+// зарегистрировались без приглашения, под каждым — приглашённые им в порядке регистрации.
+// Пользователи, не связанные с приглашениями, в дерево не входят.
+func InviteTree(ctx context.Context, db *sql.DB) ([]models.InviteNode, error) {
+	rows, err := db.QueryContext(ctx, `
+        SELECT i.inviter_id, inviter.username, i.invitee_id, invitee.username
+        FROM invites i
+        JOIN users inviter ON inviter.id = i.inviter_id
+        JOIN users invitee ON invitee.id = i.invitee_id
+        ORDER BY i.used_at, i.id
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	names := map[int]string{}
+	children := map[int][]int{}
+	invited := map[int]bool{}
+	var inviters []int
+	for rows.Next() {
+		var inviterID, inviteeID int
+		var inviterName, inviteeName string
+		if err := rows.Scan(&inviterID, &inviterName, &inviteeID, &inviteeName); err != nil {
+			return nil, err
+		}
+		if _, ok := children[inviterID]; !ok {
+			inviters = append(inviters, inviterID)
+		}
+		names[inviterID], names[inviteeID] = inviterName, inviteeName
+		children[inviterID] = append(children[inviterID], inviteeID)
+		invited[inviteeID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Приглашённый регистрируется позже пригласившего, поэтому циклов в дереве нет.
+	var build func(id int) models.InviteNode
+	build = func(id int) models.InviteNode {
+		node := models.InviteNode{UserID: id, Username: names[id]}
+		for _, child := range children[id] {
+			node.Children = append(node.Children, build(child))
+		}
+		return node
+	}
+	var tree []models.InviteNode
+	for _, id := range inviters {
+		if !invited[id] {
+			tree = append(tree, build(id))
+		}
+	}
+	return tree, nil
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"forum/models"
+)
+
+// leaderboardTime — формат границы периода в запросах таблиц лидеров.
+const leaderboardTime = "2006-01-02 15:04:05"
+
+// GetLeaderboards возвращает таблицы лидеров по активности с момента since (нулевое since — за всё время),
+// по limit строк в каждой. Удалённые посты и комментарии и заблокированные пользователи не учитываются.
+//   - Posters — больше всего постов за период;
+//   - Liked — больше всего лайков, поставленных за период постам и комментариям автора другими пользователями;
+//   - Commenters — наибольший суммарный рейтинг комментариев, написанных за период (только положительный).
+//
+// При равенстве выше тот, кто зарегистрировался раньше.
+func GetLeaderboards(ctx context.Context, db *sql.DB, since time.Time, limit int) (models.Leaderboards, error) {
+	// Граница сравнивается со временем записей как строка: посты и комментарии хранят местное время сервера,
+	// голоса — CURRENT_TIMESTAMP в UTC. Для периода в неделю или месяц этой точности достаточно.
+	postsSince, votesSince, commentsSince := "", "", ""
+	var localArgs, utcArgs []interface{}
+	if !since.IsZero() {
+		postsSince = " AND p.created_at >= ?"
+		votesSince = " AND v.voted_at >= ?"
+		commentsSince = " AND c.created_at >= ?"
+		localArgs = []interface{}{since.Local().Format(leaderboardTime)}
+		utcArgs = []interface{}{since.UTC().Format(leaderboardTime)}
+	}
+
+	var boards models.Leaderboards
+	var err error
+	boards.Posters, err = queryLeaderboard(ctx, db, `
+        SELECT u.id, u.username, COUNT(*) AS value
+        FROM posts p JOIN users u ON u.id = p.user_id
+        WHERE p.deleted_at IS NULL AND u.role <> 'banned'`+postsSince+`
+        GROUP BY u.id, u.username
+        ORDER BY value DESC, u.id
+        LIMIT ?`, append(localArgs, limit)...)
+	if err != nil {
+		return boards, err
+	}
+
+	boards.Liked, err = queryLeaderboard(ctx, db, `
+        SELECT u.id, u.username, COUNT(*) AS value
+        FROM (
+            SELECT p.user_id FROM post_votes v JOIN posts p ON p.id = v.post_id
+            WHERE v.vote = 1 AND v.user_id <> p.user_id AND p.deleted_at IS NULL`+votesSince+`
+            UNION ALL
+            SELECT c.user_id FROM comment_votes v JOIN comments c ON c.id = v.comment_id
+            WHERE v.vote = 1 AND v.user_id <> c.user_id AND c.deleted_at IS NULL`+votesSince+`
+        ) liked JOIN users u ON u.id = liked.user_id
+        WHERE u.role <> 'banned'
+        GROUP BY u.id, u.username
+        ORDER BY value DESC, u.id
+        LIMIT ?`, append(append(utcArgs, utcArgs...), limit)...)
+	if err != nil {
+		return boards, err
+	}
+
+	boards.Commenters, err = queryLeaderboard(ctx, db, `
+        SELECT u.id, u.username, SUM(c.likes - c.dislikes) AS value
+        FROM comments c JOIN users u ON u.id = c.user_id
+        WHERE c.deleted_at IS NULL AND u.role <> 'banned'`+commentsSince+`
+        GROUP BY u.id, u.username
+        HAVING SUM(c.likes - c.dislikes) > 0
+        ORDER BY value DESC, u.id
+        LIMIT ?`, append(localArgs, limit)...)
+	return boards, err
+}
+
+// queryLeaderboard выполняет запрос, выбирающий id, имя пользователя и значение, и возвращает строки таблицы лидеров.
+func queryLeaderboard(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]models.LeaderboardEntry, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.LeaderboardEntry
+	for rows.Next() {
+		var e models.LeaderboardEntry
+		if err := rows.Scan(&e.UserID, &e.Username, &e.Value); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"forum/models"
+)
+
+// likeTarget — таблица голосов за элементы одного вида и запрос, который находит пост элемента.
+type likeTarget struct {
+	votes  string // таблица голосов
+	column string // колонка с ID элемента в таблице голосов
+	post   string // запрос ID поста неудалённого элемента
+}
+
+// likeTargets — виды элементов, для которых показывается список лайкнувших: post и comment.
+var likeTargets = map[string]likeTarget{
+	"post":    {"post_votes", "post_id", "SELECT id FROM posts WHERE id = ? AND deleted_at IS NULL"},
+	"comment": {"comment_votes", "comment_id", "SELECT post_id FROM comments WHERE id = ? AND deleted_at IS NULL"},
+}
+
+// likedAt — время лайка как текст для постраничного вывода по ключу (см. pageCursor).
+// Голоса, поставленные до появления voted_at, считаются самыми ранними.
+const likedAt = "CAST(COALESCE(v.voted_at, '1970-01-01 00:00:00') AS CHAR)"
+
+// GetLikers возвращает страницу пользователей, лайкнувших элемент kind ("post" или "comment") с ID itemID,
+// от последних лайков к первым. Постраничный вывод идёт по ключу (время лайка, ID пользователя), как
+// в GetCommentsPage. Пользователи, скрывающие себя в таких списках (см. SetLikesHidden), только учитываются
+// в Likers.Hidden. Возвращает курсор следующей страницы или пустую строку, если страница последняя.
+// Если элемента нет или он удалён, возвращает sql.ErrNoRows.
+func GetLikers(ctx context.Context, db *sql.DB, kind string, itemID, limit int, cursor string) (models.Likers, string, error) {
+	target, ok := likeTargets[kind]
+	if !ok {
+		return models.Likers{}, "", fmt.Errorf("unknown like target %q", kind)
+	}
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return models.Likers{}, "", err
+	}
+	likers := models.Likers{Users: []models.Liker{}}
+	if err := db.QueryRowContext(ctx, target.post, itemID).Scan(&likers.PostID); err != nil {
+		return models.Likers{}, "", err
+	}
+	err = db.QueryRowContext(ctx, fmt.Sprintf(`
+        SELECT COUNT(*) FROM %s v JOIN users u ON v.user_id = u.id
+        WHERE v.%s = ? AND v.vote = 1 AND u.likes_hidden
+    `, target.votes, target.column), itemID).Scan(&likers.Hidden)
+	if err != nil {
+		return models.Likers{}, "", err
+	}
+
+	query := fmt.Sprintf(`
+        SELECT u.id, u.username, COALESCE(u.display_name, ''), %[1]s
+        FROM %[2]s v
+        JOIN users u ON v.user_id = u.id
+        WHERE v.%[3]s = ? AND v.vote = 1 AND NOT u.likes_hidden
+    `, likedAt, target.votes, target.column)
+	args := []interface{}{itemID}
+	if after != nil {
+		query += " AND (" + likedAt + ", u.id) < (?, ?)"
+		args = append(args, after.CreatedAt, after.ID)
+	}
+	query += " ORDER BY " + likedAt + " DESC, u.id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return models.Likers{}, "", fmt.Errorf("query failed: %v", err)
+	}
+	defer rows.Close()
+
+	var last pageCursor
+	for rows.Next() {
+		var l models.Liker
+		if err := rows.Scan(&l.UserID, &l.Username, &l.DisplayName, &last.CreatedAt); err != nil {
+			return models.Likers{}, "", fmt.Errorf("scan failed: %v", err)
+		}
+		last.ID = l.UserID
+		likers.Users = append(likers.Users, l)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Likers{}, "", fmt.Errorf("rows error: %v", err)
+	}
+	if len(likers.Users) < limit {
+		return likers, "", nil
+	}
+	return likers, last.encode(), nil
+}
+
+// LikesHidden сообщает, скрывает ли пользователь себя в списках лайкнувших.
+func LikesHidden(ctx context.Context, db *sql.DB, userID int) (bool, error) {
+	var hidden bool
+	err := db.QueryRowContext(ctx, "SELECT likes_hidden FROM users WHERE id = ?", userID).Scan(&hidden)
+	return hidden, err
+}
+
+// SetLikesHidden скрывает пользователя в списках лайкнувших или снова показывает его там.
+// Лайки скрытого пользователя по-прежнему учитываются в рейтинге и в числе скрытых (см. GetLikers).
+func SetLikesHidden(ctx context.Context, db *sql.DB, userID int, hidden bool) error {
+	_, err := db.ExecContext(ctx, "UPDATE users SET likes_hidden = ? WHERE id = ?", hidden, userID)
+	return err
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// maintainedTables — таблицы, статистику и файлы которых обслуживают Optimize и Vacuum в MySQL.
+var maintainedTables = []string{"users", "sessions", "posts", "categories", "post_categories", "post_votes", "comments", "comment_votes"}
+
+// Optimize обновляет статистику планировщика запросов. В SQLite выполняет PRAGMA optimize,
+// который запускает ANALYZE только для таблиц, где статистика устарела; в MySQL — ANALYZE TABLE.
+func Optimize(ctx context.Context, db *sql.DB, dialect string) error {
+	switch dialect {
+	case DialectSQLite:
+		_, err := db.ExecContext(ctx, "PRAGMA optimize")
+		return err
+	case DialectMySQL:
+		return execTableMaintenance(ctx, db, "ANALYZE TABLE")
+	default:
+		return fmt.Errorf("optimize is not supported for dialect %q", dialect)
+	}
+}
+
+// Vacuum перестраивает файлы базы, возвращая место, освободившееся после удалений.
+// На время работы блокирует запись, поэтому запускается только в окне обслуживания.
+// В SQLite выполняет VACUUM; в MySQL — OPTIMIZE TABLE.
+func Vacuum(ctx context.Context, db *sql.DB, dialect string) error {
+	switch dialect {
+	case DialectSQLite:
+		_, err := db.ExecContext(ctx, "VACUUM")
+		return err
+	case DialectMySQL:
+		return execTableMaintenance(ctx, db, "OPTIMIZE TABLE")
+	default:
+		return fmt.Errorf("vacuum is not supported for dialect %q", dialect)
+	}
+}
+
+// execTableMaintenance выполняет служебную команду MySQL для всех таблиц форума.
+// Такие команды возвращают результат строками (Msg_type = error), а не ошибкой запроса, поэтому строки проверяются.
+func execTableMaintenance(ctx context.Context, db *sql.DB, command string) error {
+	rows, err := db.QueryContext(ctx, command+" "+strings.Join(maintainedTables, ", "))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, op, msgType, msgText string
+		if err := rows.Scan(&table, &op, &msgType, &msgText); err != nil {
+			return err
+		}
+		if msgType == "error" {
+			return fmt.Errorf("%s %s: %s", command, table, msgText)
+		}
+	}
+	return rows.Err()
+}
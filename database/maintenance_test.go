@@ -0,0 +1,30 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestMaintenance проверяет, что обслуживание SQLite выполняется на рабочей базе.
+func TestMaintenance(t *testing.T) {
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "forum.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		CloseStatements(db)
+		db.Close()
+	})
+
+	ctx := context.Background()
+	if err := Optimize(ctx, db, DialectSQLite); err != nil {
+		t.Fatal("Optimize:", err)
+	}
+	if err := Vacuum(ctx, db, DialectSQLite); err != nil {
+		t.Fatal("Vacuum:", err)
+	}
+	if err := Optimize(ctx, db, "postgres"); err == nil {
+		t.Fatal("Optimize accepted an unknown dialect")
+	}
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"forum/models"
+)
+
+// ErrNotOwner возвращается пакетными операциями над постами и комментариями пользователя, если среди
+// выбранных есть чужие, удалённые или ждущие решения модератора: тогда не меняется ни один.
+var ErrNotOwner = errors.New("content does not belong to the user")
+
+// Снятый с публикации пост скрывается так же, как удалённый (deleted_at), но отмечен unpublished:
+// автор видит его в /my/posts и может вернуть, а PurgeDeletedContent его не удаляет.
+const managedPostCondition = "(deleted_at IS NULL OR unpublished)"
+
+// ListManagedPosts возвращает посты пользователя userID для страницы управления: опубликованные
+// и снятые с публикации, от новых к старым, с разделом и категориями.
+func ListManagedPosts(ctx context.Context, db *sql.DB, userID int) ([]models.PostData, error) {
+	rows, err := db.QueryContext(ctx, `
+        SELECT p.id, p.title, p.created_at, p.comment_count, p.unpublished, b.slug, b.name
+        FROM posts p
+        JOIN boards b ON b.id = p.board_id
+        WHERE p.user_id = ? AND (p.deleted_at IS NULL OR p.unpublished)
+        ORDER BY p.created_at DESC, p.id DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []models.PostData
+	var ids []int
+	for rows.Next() {
+		var p models.PostData
+		if err := rows.Scan(&p.ID, &p.Title, &p.CreatedAt, &p.CommentCount, &p.Unpublished, &p.BoardSlug, &p.BoardName); err != nil {
+			return nil, err
+		}
+		p.UserID = userID
+		posts = append(posts, p)
+		ids = append(ids, p.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	categories, err := GetPostCategoriesByPostIDs(ctx, db, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range posts {
+		posts[i].Categories = categories[posts[i].ID]
+	}
+	return posts, nil
+}
+
+// ListUserComments возвращает не больше limit последних комментариев пользователя userID к опубликованным
+// постам с заголовком поста в PostTitle.
+func ListUserComments(ctx context.Context, db *sql.DB, userID, limit int) ([]models.CommentData, error) {
+	rows, err := db.QueryContext(ctx, `
+        SELECT c.id, c.post_id, p.title, c.content, c.created_at
+        FROM comments c
+        JOIN posts p ON p.id = c.post_id
+        WHERE c.user_id = ? AND c.deleted_at IS NULL AND p.deleted_at IS NULL
+        ORDER BY c.created_at DESC, c.id DESC
+        LIMIT ?
+    `, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []models.CommentData
+	for rows.Next() {
+		var c models.CommentData
+		if err := rows.Scan(&c.ID, &c.PostID, &c.PostTitle, &c.Content, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		c.UserID = userID
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// DeleteUserPosts помечает удалёнными посты postIDs пользователя userID, в том числе снятые с публикации.
+// Если хотя бы один пост ему не принадлежит, возвращает ErrNotOwner и ничего не меняет.
+func DeleteUserPosts(ctx context.Context, db *sql.DB, userID int, postIDs []int) error {
+	return updateUserPosts(ctx, db, userID, postIDs, func(tx *sql.Tx, in string, args []interface{}) error {
+		_, err := tx.ExecContext(ctx, `UPDATE posts SET deleted_at = CURRENT_TIMESTAMP, unpublished = 0, updated_at = CURRENT_TIMESTAMP
+            WHERE id IN (`+in+`)`, args...)
+		return err
+	})
+}
+
+// SetUserPostsUnpublished снимает посты postIDs пользователя userID с публикации (unpublished) или возвращает их.
+// Посты, которые уже в нужном состоянии, не меняются. Если хотя бы один пост ему не принадлежит,
+// возвращает ErrNotOwner и ничего не меняет.
+func SetUserPostsUnpublished(ctx context.Context, db *sql.DB, userID int, postIDs []int, unpublished bool) error {
+	return updateUserPosts(ctx, db, userID, postIDs, func(tx *sql.Tx, in string, args []interface{}) error {
+		update := `UPDATE posts SET deleted_at = CURRENT_TIMESTAMP, unpublished = 1, updated_at = CURRENT_TIMESTAMP
+            WHERE deleted_at IS NULL AND id IN (` + in + `)`
+		if !unpublished {
+			update = `UPDATE posts SET deleted_at = NULL, unpublished = 0, updated_at = CURRENT_TIMESTAMP
+            WHERE unpublished AND id IN (` + in + `)`
+		}
+		_, err := tx.ExecContext(ctx, update, args...)
+		return err
+	})
+}
+
+// SetUserPostsCategories заменяет категории постов postIDs пользователя userID на categoryIDs.
+// Если хотя бы один пост ему не принадлежит, возвращает ErrNotOwner и ничего не меняет.
+func SetUserPostsCategories(ctx context.Context, db *sql.DB, userID int, postIDs, categoryIDs []int) error {
+	return updateUserPosts(ctx, db, userID, postIDs, func(tx *sql.Tx, in string, args []interface{}) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM post_categories WHERE post_id IN ("+in+")", args...); err != nil {
+			return err
+		}
+		for _, postID := range postIDs {
+			for _, catID := range categoryIDs {
+				if _, err := tx.ExecContext(ctx, "INSERT INTO post_categories (post_id, category_id) VALUES (?, ?)", postID, catID); err != nil {
+					return err
+				}
+			}
+		}
+		_, err := tx.ExecContext(ctx, "UPDATE posts SET updated_at = CURRENT_TIMESTAMP WHERE id IN ("+in+")", args...)
+		return err
+	})
+}
+
+// updateUserPosts проверяет в транзакции, что все посты postIDs (без повторов) принадлежат пользователю userID
+// и не удалены, и выполняет update с условием IN по ним.
+func updateUserPosts(ctx context.Context, db *sql.DB, userID int, postIDs []int,
+	update func(tx *sql.Tx, in string, args []interface{}) error) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	in, args := inClause(postIDs)
+	var owned int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM posts WHERE user_id = ? AND "+managedPostCondition+" AND id IN ("+in+")",
+		append([]interface{}{userID}, args...)...).Scan(&owned)
+	if err != nil {
+		return err
+	}
+	if owned != len(postIDs) {
+		return ErrNotOwner
+	}
+	if err := update(tx, in, args); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteUserComments помечает удалёнными комментарии commentIDs (без повторов) пользователя userID.
+// Если хотя бы один комментарий ему не принадлежит или уже удалён, возвращает ErrNotOwner и ничего не меняет.
+func DeleteUserComments(ctx context.Context, db *sql.DB, userID int, commentIDs []int) error {
+	if len(commentIDs) == 0 {
+		return nil
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	in, args := inClause(commentIDs)
+	res, err := tx.ExecContext(ctx, "UPDATE comments SET deleted_at = CURRENT_TIMESTAMP WHERE user_id = ? AND deleted_at IS NULL AND id IN ("+in+")",
+		append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n != int64(len(commentIDs)) {
+		return ErrNotOwner
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,961 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Migration описывает одно версионированное изменение схемы.
+// Up применяет изменение, Down откатывает его; обе функции выполняются внутри транзакции.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+	Down    func(tx *sql.Tx) error
+}
+
+// MigrationStatus описывает состояние миграции в базе данных.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// migrations перечисляет все миграции схемы SQLite в порядке возрастания версии.
+// Новые изменения схемы добавляются только в конец списка; применённые миграции не редактируются.
+// Аналогичные миграции для MySQL перечислены в mysqlMigrations.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: func(tx *sql.Tx) error {
+			err := execAll(tx,
+				`CREATE TABLE IF NOT EXISTS users (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					email TEXT NOT NULL UNIQUE,
+					username TEXT NOT NULL UNIQUE,
+					password TEXT NOT NULL,
+					role TEXT NOT NULL DEFAULT 'user',
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+				);`,
+				`CREATE TABLE IF NOT EXISTS sessions (
+					session_id TEXT PRIMARY KEY,
+					user_id INTEGER NOT NULL,
+					role TEXT NOT NULL,
+					expiry DATETIME NOT NULL,
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				);`,
+				`CREATE TABLE IF NOT EXISTS posts (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					user_id INTEGER NOT NULL,
+					title TEXT NOT NULL,
+					content TEXT NOT NULL,
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					image_url TEXT,
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				);`,
+				`CREATE TABLE IF NOT EXISTS categories (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					name TEXT NOT NULL UNIQUE
+				);`,
+				`CREATE TABLE IF NOT EXISTS post_categories (
+					post_id INTEGER NOT NULL,
+					category_id INTEGER NOT NULL,
+					PRIMARY KEY(post_id, category_id),
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+					FOREIGN KEY(category_id) REFERENCES categories(id) ON DELETE CASCADE
+				);`,
+				`CREATE TABLE IF NOT EXISTS post_votes (
+					user_id INTEGER NOT NULL,
+					post_id INTEGER NOT NULL,
+					vote INTEGER NOT NULL CHECK(vote IN (-1, 1)),
+					PRIMARY KEY(user_id, post_id),
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				);`,
+				`CREATE TABLE IF NOT EXISTS comments (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					post_id INTEGER NOT NULL,
+					user_id INTEGER NOT NULL,
+					content TEXT NOT NULL,
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				);`,
+				`CREATE TABLE IF NOT EXISTS comment_votes (
+					user_id INTEGER NOT NULL,
+					comment_id INTEGER NOT NULL,
+					vote INTEGER NOT NULL CHECK(vote IN (-1, 1)),
+					PRIMARY KEY(user_id, comment_id),
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+					FOREIGN KEY(comment_id) REFERENCES comments(id) ON DELETE CASCADE
+				);`,
+			)
+			if err != nil {
+				return err
+			}
+			for _, name := range []string{"news", "life", "auto", "creative", "gadgets", "science", "games", "other"} {
+				if _, err := tx.Exec("INSERT OR IGNORE INTO categories (name) VALUES (?)", name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"DROP TABLE IF EXISTS comment_votes",
+				"DROP TABLE IF EXISTS comments",
+				"DROP TABLE IF EXISTS post_votes",
+				"DROP TABLE IF EXISTS post_categories",
+				"DROP TABLE IF EXISTS categories",
+				"DROP TABLE IF EXISTS posts",
+				"DROP TABLE IF EXISTS sessions",
+				"DROP TABLE IF EXISTS users",
+			)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "users_display_name",
+		Up: func(tx *sql.Tx) error {
+			return addColumn(tx, "users", "display_name", "TEXT")
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumn(tx, "users", "display_name")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "modification_timestamps",
+		Up: func(tx *sql.Tx) error {
+			for _, c := range [][2]string{
+				{"posts", "updated_at"},
+				{"users", "updated_at"},
+				{"post_votes", "voted_at"},
+				{"comment_votes", "voted_at"},
+			} {
+				if err := addColumn(tx, c[0], c[1], "DATETIME"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *sql.Tx) error {
+			for _, c := range [][2]string{
+				{"posts", "updated_at"},
+				{"users", "updated_at"},
+				{"post_votes", "voted_at"},
+				{"comment_votes", "voted_at"},
+			} {
+				if err := dropColumn(tx, c[0], c[1]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 4,
+		Name:    "soft_deletes",
+		Up: func(tx *sql.Tx) error {
+			for _, table := range []string{"posts", "comments"} {
+				if err := addColumn(tx, table, "deleted_at", "DATETIME"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *sql.Tx) error {
+			// Помеченные удалёнными строки без колонки стали бы снова видимы, поэтому удаляются окончательно.
+			if err := execAll(tx,
+				"DELETE FROM comments WHERE deleted_at IS NOT NULL",
+				"DELETE FROM posts WHERE deleted_at IS NOT NULL",
+			); err != nil {
+				return err
+			}
+			for _, table := range []string{"posts", "comments"} {
+				if err := dropColumn(tx, table, "deleted_at"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 5,
+		Name:    "counters",
+		Up: func(tx *sql.Tx) error {
+			for _, c := range counterColumns {
+				if err := addColumn(tx, c[0], c[1], "INTEGER NOT NULL DEFAULT 0"); err != nil {
+					return err
+				}
+			}
+			posts, comments := recountStatements()
+			if err := execAll(tx, posts, comments); err != nil {
+				return err
+			}
+			return createCounterTriggers(tx, DialectSQLite)
+		},
+		Down: func(tx *sql.Tx) error {
+			// Триггеры ссылаются на колонки счётчиков, поэтому удаляются первыми.
+			if err := dropCounterTriggers(tx); err != nil {
+				return err
+			}
+			for _, c := range counterColumns {
+				if err := dropColumn(tx, c[0], c[1]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 6,
+		Name:    "users_language",
+		Up: func(tx *sql.Tx) error {
+			return addColumn(tx, "users", "language", "TEXT")
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumn(tx, "users", "language")
+		},
+	},
+	{
+		Version: 7,
+		Name:    "boards",
+		Up: func(tx *sql.Tx) error {
+			err := execAll(tx,
+				`CREATE TABLE IF NOT EXISTS boards (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					slug TEXT NOT NULL UNIQUE,
+					name TEXT NOT NULL,
+					description TEXT NOT NULL DEFAULT '',
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					updated_at DATETIME
+				);`,
+				`CREATE TABLE IF NOT EXISTS board_moderators (
+					board_id INTEGER NOT NULL,
+					user_id INTEGER NOT NULL,
+					PRIMARY KEY(board_id, user_id),
+					FOREIGN KEY(board_id) REFERENCES boards(id) ON DELETE CASCADE,
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				);`,
+			)
+			if err != nil {
+				return err
+			}
+			// Без внешнего ключа: SQLite не удаляет колонку, участвующую в ограничении, и откат стал бы невозможен.
+			if err := addColumn(tx, "posts", "board_id", "INTEGER"); err != nil {
+				return err
+			}
+			// Существующие посты переносятся в раздел по умолчанию.
+			return execAll(tx,
+				fmt.Sprintf("INSERT OR IGNORE INTO boards (slug, name, description) VALUES ('%s', '%s', '%s')",
+					DefaultBoard, defaultBoardName, defaultBoardDescription),
+				fmt.Sprintf("UPDATE posts SET board_id = (SELECT id FROM boards WHERE slug = '%s') WHERE board_id IS NULL", DefaultBoard),
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			if err := dropColumn(tx, "posts", "board_id"); err != nil {
+				return err
+			}
+			return execAll(tx,
+				"DROP TABLE IF EXISTS board_moderators",
+				"DROP TABLE IF EXISTS boards",
+			)
+		},
+	},
+	{
+		Version: 8,
+		Name:    "pages",
+		Up: func(tx *sql.Tx) error {
+			err := execAll(tx,
+				`CREATE TABLE IF NOT EXISTS pages (
+					slug TEXT PRIMARY KEY,
+					title TEXT NOT NULL,
+					content TEXT NOT NULL,
+					updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+				);`,
+			)
+			if err != nil {
+				return err
+			}
+			return insertDefaultPages(tx)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS pages")
+		},
+	},
+	{
+		Version: 9,
+		Name:    "settings",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS settings (
+					name TEXT PRIMARY KEY,
+					value TEXT NOT NULL,
+					updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+				);`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS settings")
+		},
+	},
+	{
+		Version: 10,
+		Name:    "category_styles",
+		Up: func(tx *sql.Tx) error {
+			for _, c := range [][2]string{
+				{"icon", "TEXT NOT NULL DEFAULT ''"},
+				{"color", "TEXT NOT NULL DEFAULT ''"},
+				{"updated_at", "DATETIME"},
+			} {
+				if err := addColumn(tx, "categories", c[0], c[1]); err != nil {
+					return err
+				}
+			}
+			return setDefaultCategoryStyles(tx)
+		},
+		Down: func(tx *sql.Tx) error {
+			for _, column := range []string{"icon", "color", "updated_at"} {
+				if err := dropColumn(tx, "categories", column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 11,
+		Name:    "series",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS series (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					user_id INTEGER NOT NULL,
+					title TEXT NOT NULL,
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					updated_at DATETIME,
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				);`,
+				`CREATE TABLE IF NOT EXISTS series_posts (
+					post_id INTEGER PRIMARY KEY,
+					series_id INTEGER NOT NULL,
+					position INTEGER NOT NULL,
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+					FOREIGN KEY(series_id) REFERENCES series(id) ON DELETE CASCADE
+				);`,
+				"CREATE INDEX IF NOT EXISTS idx_series_posts_series ON series_posts(series_id, position)",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"DROP TABLE IF EXISTS series_posts",
+				"DROP TABLE IF EXISTS series",
+			)
+		},
+	},
+	{
+		Version: 12,
+		Name:    "collections",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS collections (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					user_id INTEGER NOT NULL,
+					name TEXT NOT NULL,
+					share_token TEXT UNIQUE,
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				);`,
+				`CREATE TABLE IF NOT EXISTS collection_items (
+					collection_id INTEGER NOT NULL,
+					post_id INTEGER NOT NULL,
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY (collection_id, post_id),
+					FOREIGN KEY(collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				);`,
+				"CREATE INDEX IF NOT EXISTS idx_collections_user ON collections(user_id)",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"DROP TABLE IF EXISTS collection_items",
+				"DROP TABLE IF EXISTS collections",
+			)
+		},
+	},
+	{
+		Version: 13,
+		Name:    "pinned_post",
+		Up: func(tx *sql.Tx) error {
+			return addColumn(tx, "users", "pinned_post_id", "INTEGER REFERENCES posts(id) ON DELETE SET NULL")
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumn(tx, "users", "pinned_post_id")
+		},
+	},
+	{
+		Version: 14,
+		Name:    "digests",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS digests (
+					week TEXT PRIMARY KEY,
+					content TEXT NOT NULL,
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+				);`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS digests")
+		},
+	},
+	{
+		Version: 15,
+		Name:    "post_views",
+		Up: func(tx *sql.Tx) error {
+			err := execAll(tx,
+				`CREATE TABLE IF NOT EXISTS post_views (
+					user_id INTEGER NOT NULL,
+					post_id INTEGER NOT NULL,
+					viewed_at DATETIME NOT NULL,
+					PRIMARY KEY(user_id, post_id),
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				);`,
+				"CREATE INDEX IF NOT EXISTS idx_post_views_user ON post_views(user_id, viewed_at)",
+			)
+			if err != nil {
+				return err
+			}
+			return addColumn(tx, "users", "read_through", "INTEGER NOT NULL DEFAULT 0")
+		},
+		Down: func(tx *sql.Tx) error {
+			if err := dropColumn(tx, "users", "read_through"); err != nil {
+				return err
+			}
+			return execAll(tx, "DROP TABLE IF EXISTS post_views")
+		},
+	},
+	{
+		Version: 16,
+		Name:    "view_history",
+		Up: func(tx *sql.Tx) error {
+			return addColumn(tx, "users", "history_disabled", "BOOLEAN NOT NULL DEFAULT 0")
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumn(tx, "users", "history_disabled")
+		},
+	},
+	{
+		Version: 17,
+		Name:    "short_links",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS short_links (
+					code TEXT PRIMARY KEY,
+					post_id INTEGER NOT NULL UNIQUE,
+					clicks INTEGER NOT NULL DEFAULT 0,
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				);`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS short_links")
+		},
+	},
+	{
+		Version: 18,
+		Name:    "post_translations",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS post_translations (
+					post_id INTEGER NOT NULL,
+					lang TEXT NOT NULL,
+					source_hash TEXT NOT NULL,
+					title TEXT NOT NULL,
+					content TEXT NOT NULL,
+					provider TEXT NOT NULL,
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY(post_id, lang),
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				);`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS post_translations")
+		},
+	},
+	{
+		Version: 19,
+		Name:    "moderation_decisions",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS moderation_decisions (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					kind TEXT NOT NULL,
+					item_id INTEGER NOT NULL,
+					user_id INTEGER NOT NULL,
+					provider TEXT NOT NULL,
+					score REAL NOT NULL,
+					flagged BOOLEAN NOT NULL DEFAULT 0,
+					categories TEXT NOT NULL DEFAULT '',
+					status TEXT NOT NULL,
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					reviewed_by INTEGER,
+					reviewed_at DATETIME,
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+					FOREIGN KEY(reviewed_by) REFERENCES users(id) ON DELETE SET NULL
+				);`,
+				"CREATE INDEX IF NOT EXISTS idx_moderation_status ON moderation_decisions(status, created_at)",
+				"CREATE INDEX IF NOT EXISTS idx_moderation_item ON moderation_decisions(kind, item_id)",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS moderation_decisions")
+		},
+	},
+	{
+		Version: 20,
+		Name:    "post_summaries",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS post_summaries (
+					post_id INTEGER PRIMARY KEY,
+					comment_count INTEGER NOT NULL,
+					last_comment_id INTEGER NOT NULL,
+					summary TEXT NOT NULL,
+					provider TEXT NOT NULL,
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				);`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS post_summaries")
+		},
+	},
+	{
+		Version: 21,
+		Name:    "notifications",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS notifications (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					user_id INTEGER NOT NULL,
+					kind TEXT NOT NULL,
+					item_id INTEGER NOT NULL,
+					post_id INTEGER NOT NULL,
+					day TEXT NOT NULL,
+					likes INTEGER NOT NULL,
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					read_at DATETIME,
+					UNIQUE(user_id, kind, item_id, day),
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				);`,
+				"CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at)",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS notifications")
+		},
+	},
+	{
+		Version: 22,
+		Name:    "invites",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS invites (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					code TEXT NOT NULL UNIQUE,
+					inviter_id INTEGER NOT NULL,
+					invitee_id INTEGER UNIQUE,
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					used_at DATETIME,
+					FOREIGN KEY(inviter_id) REFERENCES users(id) ON DELETE CASCADE,
+					FOREIGN KEY(invitee_id) REFERENCES users(id) ON DELETE SET NULL
+				);`,
+				"CREATE INDEX IF NOT EXISTS idx_invites_inviter ON invites(inviter_id, created_at)",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS invites")
+		},
+	},
+	{
+		Version: 23,
+		Name:    "posts_members_only",
+		Up: func(tx *sql.Tx) error {
+			return addColumn(tx, "posts", "members_only", "BOOLEAN NOT NULL DEFAULT 0")
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumn(tx, "posts", "members_only")
+		},
+	},
+	{
+		Version: 24,
+		Name:    "follows",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS follows (
+					follower_id INTEGER NOT NULL,
+					followee_id INTEGER NOT NULL,
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY(follower_id, followee_id),
+					FOREIGN KEY(follower_id) REFERENCES users(id) ON DELETE CASCADE,
+					FOREIGN KEY(followee_id) REFERENCES users(id) ON DELETE CASCADE
+				);`,
+				"CREATE INDEX IF NOT EXISTS idx_follows_followee ON follows(followee_id)",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS follows")
+		},
+	},
+	{
+		Version: 25,
+		Name:    "posts_comment_policy",
+		Up: func(tx *sql.Tx) error {
+			return addColumn(tx, "posts", "comment_policy", "TEXT NOT NULL DEFAULT 'everyone'")
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumn(tx, "posts", "comment_policy")
+		},
+	},
+	{
+		Version: 26,
+		Name:    "oauth_accounts",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS oauth_accounts (
+					provider TEXT NOT NULL,
+					provider_user_id TEXT NOT NULL,
+					user_id INTEGER NOT NULL,
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY(provider, provider_user_id),
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				);`,
+				"CREATE INDEX IF NOT EXISTS idx_oauth_accounts_user ON oauth_accounts(user_id)",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS oauth_accounts")
+		},
+	},
+	{
+		// Уже зарегистрированные пользователи считаются подтвердившими почту.
+		Version: 27,
+		Name:    "users_email_verified",
+		Up: func(tx *sql.Tx) error {
+			return addColumn(tx, "users", "email_verified", "BOOLEAN NOT NULL DEFAULT 1")
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumn(tx, "users", "email_verified")
+		},
+	},
+	{
+		Version: 28,
+		Name:    "posts_unpublished",
+		Up: func(tx *sql.Tx) error {
+			return addColumn(tx, "posts", "unpublished", "BOOLEAN NOT NULL DEFAULT 0")
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumn(tx, "posts", "unpublished")
+		},
+	},
+	{
+		Version: 29,
+		Name:    "password_resets",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS password_resets (
+					token_hash TEXT PRIMARY KEY,
+					user_id INTEGER NOT NULL,
+					expires_at DATETIME NOT NULL,
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				);`,
+				"CREATE INDEX IF NOT EXISTS idx_password_resets_user ON password_resets(user_id)",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS password_resets")
+		},
+	},
+	{
+		Version: 30,
+		Name:    "post_shares",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS post_shares (
+					post_id INTEGER NOT NULL,
+					day TEXT NOT NULL,
+					channel TEXT NOT NULL,
+					count INTEGER NOT NULL DEFAULT 0,
+					PRIMARY KEY(post_id, day, channel),
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				);`,
+				"CREATE INDEX IF NOT EXISTS idx_post_shares_day ON post_shares(day)",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS post_shares")
+		},
+	},
+	{
+		Version: 31,
+		Name:    "post_reports",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS post_reports (
+					post_id INTEGER NOT NULL,
+					user_id INTEGER NOT NULL,
+					reason TEXT NOT NULL,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY(post_id, user_id),
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				);`,
+				"CREATE INDEX IF NOT EXISTS idx_post_reports_reason ON post_reports(post_id, reason)",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS post_reports")
+		},
+	},
+	{
+		Version: 32,
+		Name:    "post_report_weight",
+		Up: func(tx *sql.Tx) error {
+			return addColumn(tx, "post_reports", "weight", "INTEGER NOT NULL DEFAULT 1")
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumn(tx, "post_reports", "weight")
+		},
+	},
+	{
+		Version: 33,
+		Name:    "likes_privacy",
+		Up: func(tx *sql.Tx) error {
+			return addColumn(tx, "users", "likes_hidden", "BOOLEAN NOT NULL DEFAULT 0")
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumn(tx, "users", "likes_hidden")
+		},
+	},
+	{
+		Version: 34,
+		Name:    "users_posts_read",
+		Up: func(tx *sql.Tx) error {
+			if err := addColumn(tx, "users", "posts_read", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+				return err
+			}
+			return execAll(tx, "UPDATE users SET posts_read = (SELECT COUNT(*) FROM post_views WHERE post_views.user_id = users.id)")
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumn(tx, "users", "posts_read")
+		},
+	},
+	{
+		Version: 35,
+		Name:    "post_reads",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS post_reads (
+					user_id INTEGER NOT NULL,
+					post_id INTEGER NOT NULL,
+					PRIMARY KEY(user_id, post_id),
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				);`,
+				"INSERT OR IGNORE INTO post_reads (user_id, post_id) SELECT user_id, post_id FROM post_views",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS post_reads")
+		},
+	},
+}
+
+// Migrate применяет все ещё не применённые миграции SQLite по порядку.
+// Каждая миграция выполняется в отдельной транзакции вместе с записью в schema_migrations.
+func Migrate(db *sql.DB) error {
+	return migrateUp(db, migrations)
+}
+
+// MigrateDown откатывает применённые миграции SQLite с версией выше target в обратном порядке.
+func MigrateDown(db *sql.DB, target int) error {
+	return migrateDown(db, migrations, target)
+}
+
+// MigrationsStatus возвращает список всех известных миграций SQLite с отметкой о применении.
+func MigrationsStatus(db *sql.DB) ([]MigrationStatus, error) {
+	return migrationsStatus(db, migrations)
+}
+
+// migrateUp применяет не применённые миграции из списка list.
+func migrateUp(db *sql.DB, list []Migration) error {
+	if err := ensureMigrationsTable(db); err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	for _, m := range list {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := runMigration(db, m, true); err != nil {
+			return err
+		}
+		log.Printf("Applied migration %d_%s.", m.Version, m.Name)
+	}
+	return nil
+}
+
+// migrateDown откатывает миграции из списка list с версией выше target.
+func migrateDown(db *sql.DB, list []Migration, target int) error {
+	if err := ensureMigrationsTable(db); err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	for i := len(list) - 1; i >= 0; i-- {
+		m := list[i]
+		if m.Version <= target {
+			break
+		}
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if err := runMigration(db, m, false); err != nil {
+			return err
+		}
+		log.Printf("Rolled back migration %d_%s.", m.Version, m.Name)
+	}
+	return nil
+}
+
+// migrationsStatus возвращает состояние миграций из списка list.
+func migrationsStatus(db *sql.DB, list []Migration) ([]MigrationStatus, error) {
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(list))
+	for _, m := range list {
+		at, ok := applied[m.Version]
+		statuses = append(statuses, MigrationStatus{Version: m.Version, Name: m.Name, Applied: ok, AppliedAt: at})
+	}
+	return statuses, nil
+}
+
+// ensureMigrationsTable создаёт таблицу учёта миграций, если её нет.
+func ensureMigrationsTable(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+// appliedMigrations возвращает версии применённых миграций и время их применения.
+func appliedMigrations(db *sql.DB) (map[int]time.Time, error) {
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// runMigration применяет (up = true) или откатывает миграцию в одной транзакции.
+func runMigration(db *sql.DB, m Migration, up bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if up {
+		if err := m.Up(tx); err != nil {
+			return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.Version, m.Name); err != nil {
+			return err
+		}
+	} else {
+		if m.Down == nil {
+			return fmt.Errorf("migration %d_%s cannot be rolled back", m.Version, m.Name)
+		}
+		if err := m.Down(tx); err != nil {
+			return fmt.Errorf("rollback of migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", m.Version); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// execAll выполняет SQL-выражения по порядку и останавливается на первой ошибке.
+func execAll(tx *sql.Tx, statements ...string) error {
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnExists проверяет наличие колонки в таблице.
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	var exists bool
+	err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM pragma_table_info(?) WHERE name = ?)", table, column).Scan(&exists)
+	return exists, err
+}
+
+// addColumn добавляет колонку, если её ещё нет.
+// Проверка нужна для баз, созданных до появления schema_migrations, где часть колонок уже добавлена.
+func addColumn(tx *sql.Tx, table, column, definition string) error {
+	exists, err := columnExists(tx, table, column)
+	if err != nil || exists {
+		return err
+	}
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// dropColumn удаляет колонку, если она существует.
+func dropColumn(tx *sql.Tx, table, column string) error {
+	exists, err := columnExists(tx, table, column)
+	if err != nil || !exists {
+		return err
+	}
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column))
+	return err
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"forum/models"
+)
+
+// Состояния решений автоматической проверки.
+const (
+	ModerationAllowed  = "allowed"  // опубликовано сразу
+	ModerationPending  = "pending"  // скрыто и ждёт решения модератора
+	ModerationApproved = "approved" // модератор опубликовал
+	ModerationRejected = "rejected" // модератор отклонил; содержимое остаётся удалённым
+)
+
+// moderationTables — таблицы проверяемого содержимого по виду решения.
+var moderationTables = map[string]string{"post": "posts", "comment": "comments"}
+
+// RecordScreening сохраняет решение автоматической проверки только что созданного поста или комментария.
+// Содержимое со статусом ModerationPending скрывается так же, как удалённое (deleted_at), до решения модератора
+// (см. ResolveModeration): оно пропадает из всех лент, счётчиков и поиска, а PurgeDeletedContent его не удаляет.
+func RecordScreening(ctx context.Context, db *sql.DB, d models.ModerationDecision) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := recordDecision(ctx, tx, d); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// recordDecision записывает решение d в транзакции tx и при статусе ModerationPending скрывает содержимое.
+func recordDecision(ctx context.Context, tx *sql.Tx, d models.ModerationDecision) error {
+	table, ok := moderationTables[d.Kind]
+	if !ok {
+		return fmt.Errorf("unknown moderation kind %q", d.Kind)
+	}
+	if d.Status == ModerationPending {
+		update := "UPDATE comments SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?"
+		if table == "posts" {
+			update = "UPDATE posts SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+		}
+		if _, err := tx.ExecContext(ctx, update, d.ItemID); err != nil {
+			return err
+		}
+	}
+	_, err := tx.ExecContext(ctx, `
+        INSERT INTO moderation_decisions (kind, item_id, user_id, provider, score, flagged, categories, status)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+    `, d.Kind, d.ItemID, d.UserID, d.Provider, d.Score, d.Flagged, d.Categories, d.Status)
+	return err
+}
+
+// ListModerationDecisions возвращает решения проверки с содержимым: при pending — очередь скрытого содержимого
+// от старых к новым, иначе — последние остальные решения от новых к старым. Модератору раздела (all = false)
+// видны только посты и комментарии его разделов; администратору (all = true) — все.
+func ListModerationDecisions(ctx context.Context, db *sql.DB, moderatorID int, all, pending bool, limit int) ([]models.ModerationDecision, error) {
+	cond, order := "d.status <> 'pending'", "DESC"
+	if pending {
+		cond, order = "d.status = 'pending'", "ASC"
+	}
+	rows, err := db.QueryContext(ctx, `
+        SELECT d.id, d.kind, d.item_id, d.user_id, u.username, d.provider, d.score, d.flagged, d.categories, d.status,
+               d.created_at, COALESCE(r.username, ''), d.reviewed_at,
+               COALESCE(p.id, cp.id, 0), COALESCE(p.title, cp.title, ''), COALESCE(p.content, c.content, ''), COALESCE(b.name, '')
+        FROM moderation_decisions d
+        JOIN users u ON u.id = d.user_id
+        LEFT JOIN users r ON r.id = d.reviewed_by
+        LEFT JOIN posts p ON d.kind = 'post' AND p.id = d.item_id
+        LEFT JOIN comments c ON d.kind = 'comment' AND c.id = d.item_id
+        LEFT JOIN posts cp ON cp.id = c.post_id
+        LEFT JOIN boards b ON b.id = COALESCE(p.board_id, cp.board_id)
+        WHERE `+cond+` AND (? OR EXISTS (SELECT 1 FROM board_moderators m
+                                          WHERE m.board_id = COALESCE(p.board_id, cp.board_id) AND m.user_id = ?))
+        ORDER BY d.id `+order+`
+        LIMIT ?
+    `, all, moderatorID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	decisions := []models.ModerationDecision{}
+	for rows.Next() {
+		var d models.ModerationDecision
+		var reviewedAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.Kind, &d.ItemID, &d.UserID, &d.Username, &d.Provider, &d.Score, &d.Flagged,
+			&d.Categories, &d.Status, &d.CreatedAt, &d.ReviewedBy, &reviewedAt,
+			&d.PostID, &d.Title, &d.Content, &d.BoardName); err != nil {
+			return nil, err
+		}
+		d.ReviewedAt = reviewedAt.Time
+		decisions = append(decisions, d)
+	}
+	return decisions, rows.Err()
+}
+
+// GetModerationDecision возвращает вид, содержимое и состояние решения id. Если решения нет, возвращает sql.ErrNoRows.
+func GetModerationDecision(ctx context.Context, db *sql.DB, id int) (models.ModerationDecision, error) {
+	d := models.ModerationDecision{ID: id}
+	err := db.QueryRowContext(ctx, "SELECT kind, item_id, user_id, status FROM moderation_decisions WHERE id = ?", id).
+		Scan(&d.Kind, &d.ItemID, &d.UserID, &d.Status)
+	return d, err
+}
+
+// ResolveModeration записывает решение модератора reviewerID по скрытому содержимому: approve публикует его
+// (снимает отметку удаления), иначе оно остаётся удалённым и будет стёрто PurgeDeletedContent.
+// Если решения нет или оно уже принято, возвращает sql.ErrNoRows.
+func ResolveModeration(ctx context.Context, db *sql.DB, id, reviewerID int, approve bool) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var kind string
+	var itemID int
+	err = tx.QueryRowContext(ctx, "SELECT kind, item_id FROM moderation_decisions WHERE id = ? AND status = 'pending'", id).
+		Scan(&kind, &itemID)
+	if err != nil {
+		return err
+	}
+	status := ModerationRejected
+	if approve {
+		status = ModerationApproved
+		update := "UPDATE comments SET deleted_at = NULL WHERE id = ?"
+		if kind == "post" {
+			update = "UPDATE posts SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+		}
+		if _, err := tx.ExecContext(ctx, update, itemID); err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `
+        UPDATE moderation_decisions SET status = ?, reviewed_by = ?, reviewed_at = CURRENT_TIMESTAMP WHERE id = ?
+    `, status, reviewerID, id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
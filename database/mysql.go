@@ -0,0 +1,922 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"forum/models"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// OpenMySQL подключается к MySQL/MariaDB по DSN вида user:pass@tcp(host:3306)/forum
+// и выполняет миграции схемы. Разбор дат (parseTime) включается принудительно,
+// так как репозитории сканируют DATETIME в time.Time. Пул соединений и журнал медленных запросов настраиваются по opts.
+func OpenMySQL(dsn string, opts MySQLOptions) (*sql.DB, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mysql dsn: %w", err)
+	}
+	cfg.ParseTime = true
+
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(withSlowQueryLog(connector, opts.SlowQueryThreshold))
+	opts.Pool.apply(db)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := MigrateMySQL(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// MigrateMySQL применяет не применённые миграции схемы MySQL.
+// MySQL фиксирует DDL неявно, поэтому упавшая миграция может оставить схему частично изменённой.
+func MigrateMySQL(db *sql.DB) error {
+	return migrateUp(db, mysqlMigrations)
+}
+
+// NewMySQLStore создаёт Store с реализациями репозиториев для MySQL.
+// Запросы пользователей, комментариев и разделов совместимы с обоими диалектами и берутся из SQLite-реализации;
+// переопределяются только upsert голосов, подсчёт версий и очистка сессий, где синтаксис различается.
+func NewMySQLStore(db *sql.DB) *Store {
+	return &Store{
+		DB:          db,
+		Dialect:     DialectMySQL,
+		Users:       mysqlUserRepo{sqliteUserRepo{db: db}},
+		Posts:       mysqlPostRepo{sqlitePostRepo{db: db}},
+		Comments:    sqliteCommentRepo{db: db},
+		Votes:       mysqlVoteRepo{sqliteVoteRepo{db: db}},
+		Boards:      sqliteBoardRepo{db: db},
+		Settings:    sqliteSettingsRepo{db: db},
+		Categories:  sqliteCategoryRepo{db: db},
+		Series:      sqliteSeriesRepo{db: db},
+		Collections: sqliteCollectionRepo{db: db},
+	}
+}
+
+// mysqlMigrations повторяет миграции из migrations с синтаксисом MySQL.
+// Версии совпадают, чтобы состояние схемы было сравнимо между диалектами.
+var mysqlMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: func(tx *sql.Tx) error {
+			err := execAll(tx,
+				`CREATE TABLE IF NOT EXISTS users (
+					id INT AUTO_INCREMENT PRIMARY KEY,
+					email VARCHAR(255) NOT NULL UNIQUE,
+					username VARCHAR(255) NOT NULL UNIQUE,
+					password VARCHAR(255) NOT NULL,
+					role VARCHAR(32) NOT NULL DEFAULT 'user',
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+				`CREATE TABLE IF NOT EXISTS sessions (
+					session_id VARCHAR(64) PRIMARY KEY,
+					user_id INT NOT NULL,
+					role VARCHAR(32) NOT NULL,
+					expiry DATETIME(6) NOT NULL,
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+				`CREATE TABLE IF NOT EXISTS posts (
+					id INT AUTO_INCREMENT PRIMARY KEY,
+					user_id INT NOT NULL,
+					title VARCHAR(255) NOT NULL,
+					content MEDIUMTEXT NOT NULL,
+					created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+					image_url TEXT,
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+				`CREATE TABLE IF NOT EXISTS categories (
+					id INT AUTO_INCREMENT PRIMARY KEY,
+					name VARCHAR(64) NOT NULL UNIQUE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+				`CREATE TABLE IF NOT EXISTS post_categories (
+					post_id INT NOT NULL,
+					category_id INT NOT NULL,
+					PRIMARY KEY(post_id, category_id),
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+					FOREIGN KEY(category_id) REFERENCES categories(id) ON DELETE CASCADE
+				) ENGINE=InnoDB`,
+				`CREATE TABLE IF NOT EXISTS post_votes (
+					user_id INT NOT NULL,
+					post_id INT NOT NULL,
+					vote TINYINT NOT NULL CHECK(vote IN (-1, 1)),
+					PRIMARY KEY(user_id, post_id),
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				) ENGINE=InnoDB`,
+				`CREATE TABLE IF NOT EXISTS comments (
+					id INT AUTO_INCREMENT PRIMARY KEY,
+					post_id INT NOT NULL,
+					user_id INT NOT NULL,
+					content TEXT NOT NULL,
+					created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+				`CREATE TABLE IF NOT EXISTS comment_votes (
+					user_id INT NOT NULL,
+					comment_id INT NOT NULL,
+					vote TINYINT NOT NULL CHECK(vote IN (-1, 1)),
+					PRIMARY KEY(user_id, comment_id),
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+					FOREIGN KEY(comment_id) REFERENCES comments(id) ON DELETE CASCADE
+				) ENGINE=InnoDB`,
+			)
+			if err != nil {
+				return err
+			}
+			for _, name := range []string{"news", "life", "auto", "creative", "gadgets", "science", "games", "other"} {
+				if _, err := tx.Exec("INSERT IGNORE INTO categories (name) VALUES (?)", name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"DROP TABLE IF EXISTS comment_votes",
+				"DROP TABLE IF EXISTS comments",
+				"DROP TABLE IF EXISTS post_votes",
+				"DROP TABLE IF EXISTS post_categories",
+				"DROP TABLE IF EXISTS categories",
+				"DROP TABLE IF EXISTS posts",
+				"DROP TABLE IF EXISTS sessions",
+				"DROP TABLE IF EXISTS users",
+			)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "users_display_name",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE users ADD COLUMN display_name VARCHAR(255)")
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE users DROP COLUMN display_name")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "modification_timestamps",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"ALTER TABLE posts ADD COLUMN updated_at DATETIME",
+				"ALTER TABLE users ADD COLUMN updated_at DATETIME",
+				"ALTER TABLE post_votes ADD COLUMN voted_at DATETIME",
+				"ALTER TABLE comment_votes ADD COLUMN voted_at DATETIME",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"ALTER TABLE posts DROP COLUMN updated_at",
+				"ALTER TABLE users DROP COLUMN updated_at",
+				"ALTER TABLE post_votes DROP COLUMN voted_at",
+				"ALTER TABLE comment_votes DROP COLUMN voted_at",
+			)
+		},
+	},
+	{
+		Version: 4,
+		Name:    "soft_deletes",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"ALTER TABLE posts ADD COLUMN deleted_at DATETIME",
+				"ALTER TABLE comments ADD COLUMN deleted_at DATETIME",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"DELETE FROM comments WHERE deleted_at IS NOT NULL",
+				"DELETE FROM posts WHERE deleted_at IS NOT NULL",
+				"ALTER TABLE posts DROP COLUMN deleted_at",
+				"ALTER TABLE comments DROP COLUMN deleted_at",
+			)
+		},
+	},
+	{
+		Version: 5,
+		Name:    "counters",
+		Up: func(tx *sql.Tx) error {
+			for _, c := range counterColumns {
+				if err := execAll(tx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s INT NOT NULL DEFAULT 0", c[0], c[1])); err != nil {
+					return err
+				}
+			}
+			posts, comments := recountStatements()
+			if err := execAll(tx, posts, comments); err != nil {
+				return err
+			}
+			return createCounterTriggers(tx, DialectMySQL)
+		},
+		Down: func(tx *sql.Tx) error {
+			if err := dropCounterTriggers(tx); err != nil {
+				return err
+			}
+			for _, c := range counterColumns {
+				if err := execAll(tx, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", c[0], c[1])); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 6,
+		Name:    "users_language",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE users ADD COLUMN language VARCHAR(8)")
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE users DROP COLUMN language")
+		},
+	},
+	{
+		Version: 7,
+		Name:    "boards",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS boards (
+					id INT AUTO_INCREMENT PRIMARY KEY,
+					slug VARCHAR(32) NOT NULL UNIQUE,
+					name VARCHAR(255) NOT NULL,
+					description TEXT NOT NULL,
+					created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+					updated_at DATETIME(6)
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+				`CREATE TABLE IF NOT EXISTS board_moderators (
+					board_id INT NOT NULL,
+					user_id INT NOT NULL,
+					PRIMARY KEY(board_id, user_id),
+					FOREIGN KEY(board_id) REFERENCES boards(id) ON DELETE CASCADE,
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+				"ALTER TABLE posts ADD COLUMN board_id INT",
+				fmt.Sprintf("INSERT IGNORE INTO boards (slug, name, description) VALUES ('%s', '%s', '%s')",
+					DefaultBoard, defaultBoardName, defaultBoardDescription),
+				fmt.Sprintf("UPDATE posts SET board_id = (SELECT id FROM boards WHERE slug = '%s') WHERE board_id IS NULL", DefaultBoard),
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"ALTER TABLE posts DROP COLUMN board_id",
+				"DROP TABLE IF EXISTS board_moderators",
+				"DROP TABLE IF EXISTS boards",
+			)
+		},
+	},
+	{
+		Version: 8,
+		Name:    "pages",
+		Up: func(tx *sql.Tx) error {
+			err := execAll(tx,
+				`CREATE TABLE IF NOT EXISTS pages (
+					slug VARCHAR(32) PRIMARY KEY,
+					title VARCHAR(255) NOT NULL,
+					content MEDIUMTEXT NOT NULL,
+					updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			)
+			if err != nil {
+				return err
+			}
+			return insertDefaultPages(tx)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS pages")
+		},
+	},
+	{
+		Version: 9,
+		Name:    "settings",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS settings (
+					name VARCHAR(64) PRIMARY KEY,
+					value TEXT NOT NULL,
+					updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS settings")
+		},
+	},
+	{
+		Version: 10,
+		Name:    "category_styles",
+		Up: func(tx *sql.Tx) error {
+			err := execAll(tx,
+				"ALTER TABLE categories ADD COLUMN icon VARCHAR(32) NOT NULL DEFAULT ''",
+				"ALTER TABLE categories ADD COLUMN color VARCHAR(7) NOT NULL DEFAULT ''",
+				"ALTER TABLE categories ADD COLUMN updated_at DATETIME(6)",
+			)
+			if err != nil {
+				return err
+			}
+			return setDefaultCategoryStyles(tx)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"ALTER TABLE categories DROP COLUMN icon",
+				"ALTER TABLE categories DROP COLUMN color",
+				"ALTER TABLE categories DROP COLUMN updated_at",
+			)
+		},
+	},
+	{
+		Version: 11,
+		Name:    "series",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS series (
+					id INT AUTO_INCREMENT PRIMARY KEY,
+					user_id INT NOT NULL,
+					title VARCHAR(255) NOT NULL,
+					created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+					updated_at DATETIME(6),
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+				`CREATE TABLE IF NOT EXISTS series_posts (
+					post_id INT PRIMARY KEY,
+					series_id INT NOT NULL,
+					position INT NOT NULL,
+					INDEX idx_series_posts_series (series_id, position),
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+					FOREIGN KEY(series_id) REFERENCES series(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"DROP TABLE IF EXISTS series_posts",
+				"DROP TABLE IF EXISTS series",
+			)
+		},
+	},
+	{
+		Version: 12,
+		Name:    "collections",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS collections (
+					id INT AUTO_INCREMENT PRIMARY KEY,
+					user_id INT NOT NULL,
+					name VARCHAR(255) NOT NULL,
+					share_token VARCHAR(64) UNIQUE,
+					created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+					INDEX idx_collections_user (user_id),
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+				`CREATE TABLE IF NOT EXISTS collection_items (
+					collection_id INT NOT NULL,
+					post_id INT NOT NULL,
+					created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+					PRIMARY KEY (collection_id, post_id),
+					FOREIGN KEY(collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"DROP TABLE IF EXISTS collection_items",
+				"DROP TABLE IF EXISTS collections",
+			)
+		},
+	},
+	{
+		Version: 13,
+		Name:    "pinned_post",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"ALTER TABLE users ADD COLUMN pinned_post_id INT NULL",
+				"ALTER TABLE users ADD CONSTRAINT fk_users_pinned_post FOREIGN KEY (pinned_post_id) REFERENCES posts(id) ON DELETE SET NULL",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"ALTER TABLE users DROP FOREIGN KEY fk_users_pinned_post",
+				"ALTER TABLE users DROP COLUMN pinned_post_id",
+			)
+		},
+	},
+	{
+		Version: 14,
+		Name:    "digests",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS digests (
+					week CHAR(10) PRIMARY KEY,
+					content MEDIUMTEXT NOT NULL,
+					created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS digests")
+		},
+	},
+	{
+		Version: 15,
+		Name:    "post_views",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS post_views (
+					user_id INT NOT NULL,
+					post_id INT NOT NULL,
+					viewed_at DATETIME(6) NOT NULL,
+					PRIMARY KEY(user_id, post_id),
+					INDEX idx_post_views_user (user_id, viewed_at),
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+				"ALTER TABLE users ADD COLUMN read_through INT NOT NULL DEFAULT 0",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"ALTER TABLE users DROP COLUMN read_through",
+				"DROP TABLE IF EXISTS post_views",
+			)
+		},
+	},
+	{
+		Version: 16,
+		Name:    "view_history",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE users ADD COLUMN history_disabled BOOLEAN NOT NULL DEFAULT FALSE")
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE users DROP COLUMN history_disabled")
+		},
+	},
+	{
+		Version: 17,
+		Name:    "short_links",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS short_links (
+					code VARCHAR(16) CHARACTER SET ascii COLLATE ascii_bin PRIMARY KEY,
+					post_id INT NOT NULL UNIQUE,
+					clicks INT NOT NULL DEFAULT 0,
+					created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS short_links")
+		},
+	},
+	{
+		Version: 18,
+		Name:    "post_translations",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS post_translations (
+					post_id INT NOT NULL,
+					lang VARCHAR(8) NOT NULL,
+					source_hash CHAR(64) NOT NULL,
+					title TEXT NOT NULL,
+					content MEDIUMTEXT NOT NULL,
+					provider VARCHAR(32) NOT NULL,
+					created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+					PRIMARY KEY(post_id, lang),
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS post_translations")
+		},
+	},
+	{
+		Version: 19,
+		Name:    "moderation_decisions",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS moderation_decisions (
+					id INT AUTO_INCREMENT PRIMARY KEY,
+					kind VARCHAR(16) NOT NULL,
+					item_id INT NOT NULL,
+					user_id INT NOT NULL,
+					provider VARCHAR(32) NOT NULL,
+					score DOUBLE NOT NULL,
+					flagged BOOLEAN NOT NULL DEFAULT FALSE,
+					categories VARCHAR(255) NOT NULL DEFAULT '',
+					status VARCHAR(16) NOT NULL,
+					created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+					reviewed_by INT NULL,
+					reviewed_at DATETIME(6) NULL,
+					INDEX idx_moderation_status (status, created_at),
+					INDEX idx_moderation_item (kind, item_id),
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+					FOREIGN KEY(reviewed_by) REFERENCES users(id) ON DELETE SET NULL
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS moderation_decisions")
+		},
+	},
+	{
+		Version: 20,
+		Name:    "post_summaries",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS post_summaries (
+					post_id INT PRIMARY KEY,
+					comment_count INT NOT NULL,
+					last_comment_id INT NOT NULL,
+					summary TEXT NOT NULL,
+					provider VARCHAR(32) NOT NULL,
+					created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS post_summaries")
+		},
+	},
+	{
+		Version: 21,
+		Name:    "notifications",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS notifications (
+					id INT AUTO_INCREMENT PRIMARY KEY,
+					user_id INT NOT NULL,
+					kind VARCHAR(32) NOT NULL,
+					item_id INT NOT NULL,
+					post_id INT NOT NULL,
+					day VARCHAR(10) NOT NULL,
+					likes INT NOT NULL,
+					created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+					read_at DATETIME(6) NULL,
+					UNIQUE (user_id, kind, item_id, day),
+					INDEX idx_notifications_user (user_id, created_at),
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS notifications")
+		},
+	},
+	{
+		Version: 22,
+		Name:    "invites",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS invites (
+					id INT AUTO_INCREMENT PRIMARY KEY,
+					code VARCHAR(32) NOT NULL UNIQUE,
+					inviter_id INT NOT NULL,
+					invitee_id INT NULL UNIQUE,
+					created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+					used_at DATETIME(6) NULL,
+					INDEX idx_invites_inviter (inviter_id, created_at),
+					FOREIGN KEY(inviter_id) REFERENCES users(id) ON DELETE CASCADE,
+					FOREIGN KEY(invitee_id) REFERENCES users(id) ON DELETE SET NULL
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS invites")
+		},
+	},
+	{
+		Version: 23,
+		Name:    "posts_members_only",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE posts ADD COLUMN members_only BOOLEAN NOT NULL DEFAULT FALSE")
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE posts DROP COLUMN members_only")
+		},
+	},
+	{
+		Version: 24,
+		Name:    "follows",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS follows (
+					follower_id INT NOT NULL,
+					followee_id INT NOT NULL,
+					created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+					PRIMARY KEY(follower_id, followee_id),
+					INDEX idx_follows_followee (followee_id),
+					FOREIGN KEY(follower_id) REFERENCES users(id) ON DELETE CASCADE,
+					FOREIGN KEY(followee_id) REFERENCES users(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS follows")
+		},
+	},
+	{
+		Version: 25,
+		Name:    "posts_comment_policy",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE posts ADD COLUMN comment_policy VARCHAR(16) NOT NULL DEFAULT 'everyone'")
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE posts DROP COLUMN comment_policy")
+		},
+	},
+	{
+		Version: 26,
+		Name:    "oauth_accounts",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS oauth_accounts (
+					provider VARCHAR(32) NOT NULL,
+					provider_user_id VARCHAR(255) NOT NULL,
+					user_id INT NOT NULL,
+					created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+					PRIMARY KEY(provider, provider_user_id),
+					INDEX idx_oauth_accounts_user (user_id),
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS oauth_accounts")
+		},
+	},
+	{
+		Version: 27,
+		Name:    "users_email_verified",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT TRUE")
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE users DROP COLUMN email_verified")
+		},
+	},
+	{
+		Version: 28,
+		Name:    "posts_unpublished",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE posts ADD COLUMN unpublished BOOLEAN NOT NULL DEFAULT FALSE")
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE posts DROP COLUMN unpublished")
+		},
+	},
+	{
+		Version: 29,
+		Name:    "password_resets",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS password_resets (
+					token_hash CHAR(64) NOT NULL PRIMARY KEY,
+					user_id INT NOT NULL,
+					expires_at DATETIME(6) NOT NULL,
+					created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+					INDEX idx_password_resets_user (user_id),
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS password_resets")
+		},
+	},
+	{
+		Version: 30,
+		Name:    "post_shares",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS post_shares (
+					post_id INT NOT NULL,
+					day CHAR(10) NOT NULL,
+					channel VARCHAR(20) NOT NULL,
+					count INT NOT NULL DEFAULT 0,
+					PRIMARY KEY(post_id, day, channel),
+					INDEX idx_post_shares_day (day),
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS post_shares")
+		},
+	},
+	{
+		Version: 31,
+		Name:    "post_reports",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS post_reports (
+					post_id INT NOT NULL,
+					user_id INT NOT NULL,
+					reason VARCHAR(20) NOT NULL,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY(post_id, user_id),
+					INDEX idx_post_reports_reason (post_id, reason),
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS post_reports")
+		},
+	},
+	{
+		Version: 32,
+		Name:    "post_report_weight",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE post_reports ADD COLUMN weight INT NOT NULL DEFAULT 1")
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE post_reports DROP COLUMN weight")
+		},
+	},
+	{
+		Version: 33,
+		Name:    "likes_privacy",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE users ADD COLUMN likes_hidden BOOLEAN NOT NULL DEFAULT FALSE")
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE users DROP COLUMN likes_hidden")
+		},
+	},
+	{
+		Version: 34,
+		Name:    "users_posts_read",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				"ALTER TABLE users ADD COLUMN posts_read INT NOT NULL DEFAULT 0",
+				"UPDATE users SET posts_read = (SELECT COUNT(*) FROM post_views WHERE post_views.user_id = users.id)",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "ALTER TABLE users DROP COLUMN posts_read")
+		},
+	},
+	{
+		Version: 35,
+		Name:    "post_reads",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS post_reads (
+					user_id INT NOT NULL,
+					post_id INT NOT NULL,
+					PRIMARY KEY(user_id, post_id),
+					FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+					FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE
+				) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+				"INSERT IGNORE INTO post_reads (user_id, post_id) SELECT user_id, post_id FROM post_views",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return execAll(tx, "DROP TABLE IF EXISTS post_reads")
+		},
+	},
+}
+
+// mysqlUserRepo реализует UserRepo для MySQL.
+type mysqlUserRepo struct {
+	sqliteUserRepo
+}
+
+// PurgeExpiredSessions сравнивает срок как DATETIME: julianday в MySQL нет, а драйвер переводит время в часовой пояс соединения.
+func (r mysqlUserRepo) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM sessions WHERE expiry < ?", time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// MarkPostViewed записывает прочтение через INSERT IGNORE и обновляет время просмотра через ON DUPLICATE KEY UPDATE:
+// INSERT OR IGNORE и ON CONFLICT в MySQL нет.
+func (r mysqlUserRepo) MarkPostViewed(ctx context.Context, userID, postID int, at time.Time) error {
+	return markPostViewed(ctx, r.db, "INSERT IGNORE INTO post_reads (user_id, post_id) SELECT id, ? FROM users WHERE id = ?", `
+        INSERT INTO post_views (user_id, post_id, viewed_at)
+        SELECT id, ?, ? FROM users WHERE id = ? AND NOT history_disabled
+        ON DUPLICATE KEY UPDATE viewed_at = VALUES(viewed_at)
+    `, userID, postID, at)
+}
+
+// mysqlPostRepo реализует PostRepo для MySQL.
+type mysqlPostRepo struct {
+	sqlitePostRepo
+}
+
+// PurgeDeletedContent повторяет PurgeDeletedContent, вычисляя границу срока через INTERVAL вместо datetime().
+func (r mysqlPostRepo) PurgeDeletedContent(ctx context.Context, retention time.Duration) (int64, int64, error) {
+	return purgeDeleted(ctx, r.db, "deleted_at < NOW() - INTERVAL ? SECOND", int64(retention/time.Second))
+}
+
+// SearchPosts повторяет SearchPosts без полнотекстового индекса: текст всегда ищется через LIKE.
+func (r mysqlPostRepo) SearchPosts(ctx context.Context, userID int, f models.SearchFilters, limit int, cursor string) ([]models.PostData, string, error) {
+	return searchPosts(ctx, r.db, userID, f, limit, cursor, false)
+}
+
+const mysqlQueryFeedVersion = `
+        SELECT (SELECT COUNT(*) FROM posts WHERE deleted_at IS NULL),
+               (SELECT COUNT(*) FROM comments WHERE deleted_at IS NULL),
+               (SELECT CONCAT(COUNT(*), ':', COALESCE(SUM(vote), 0)) FROM post_votes),
+               (SELECT CONCAT(COUNT(*), ':', COALESCE(SUM(vote), 0)) FROM comment_votes),
+               (SELECT GROUP_CONCAT(CONCAT(name, '=', value) ORDER BY name) FROM settings),
+               (SELECT MAX(COALESCE(updated_at, created_at)) FROM posts),
+               (SELECT MAX(created_at) FROM comments),
+               (SELECT MAX(voted_at) FROM post_votes),
+               (SELECT MAX(voted_at) FROM comment_votes),
+               (SELECT MAX(updated_at) FROM users),
+               (SELECT MAX(COALESCE(updated_at, created_at)) FROM boards),
+               (SELECT MAX(updated_at) FROM settings),
+               (SELECT MAX(updated_at) FROM categories)
+    `
+
+// GetFeedVersion повторяет GetFeedVersion, заменяя конкатенацию || на CONCAT.
+func (r mysqlPostRepo) GetFeedVersion(ctx context.Context) (ContentVersion, error) {
+	counters := make([]sql.NullString, 5)
+	times := make([]sql.NullString, 8)
+	err := cachedQueryRow(ctx, r.db, mysqlQueryFeedVersion).Scan(&counters[0], &counters[1], &counters[2], &counters[3], &counters[4],
+		&times[0], &times[1], &times[2], &times[3], &times[4], &times[5], &times[6], &times[7])
+	if err != nil {
+		return ContentVersion{}, err
+	}
+	return buildVersion(counters, times), nil
+}
+
+const mysqlQueryPostVersion = `
+        SELECT (SELECT COUNT(*) FROM comments WHERE post_id = p.id AND deleted_at IS NULL),
+               (SELECT CONCAT(COUNT(*), ':', COALESCE(SUM(vote), 0)) FROM post_votes WHERE post_id = p.id),
+               (SELECT CONCAT(COUNT(*), ':', COALESCE(SUM(cv.vote), 0))
+                FROM comment_votes cv JOIN comments c ON c.id = cv.comment_id WHERE c.post_id = p.id),
+               COALESCE(p.updated_at, p.created_at),
+               (SELECT MAX(created_at) FROM comments WHERE post_id = p.id),
+               (SELECT MAX(voted_at) FROM post_votes WHERE post_id = p.id),
+               (SELECT MAX(cv.voted_at)
+                FROM comment_votes cv JOIN comments c ON c.id = cv.comment_id WHERE c.post_id = p.id),
+               (SELECT MAX(updated_at) FROM users),
+               (SELECT MAX(COALESCE(updated_at, created_at)) FROM boards),
+               (SELECT MAX(updated_at) FROM categories),
+               (SELECT s.updated_at FROM series_posts sp JOIN series s ON s.id = sp.series_id WHERE sp.post_id = p.id),
+               (SELECT created_at FROM post_summaries WHERE post_id = p.id)
+        FROM posts p WHERE p.id = ? AND p.deleted_at IS NULL
+    `
+
+// GetPostVersion повторяет GetPostVersion, заменяя конкатенацию || на CONCAT.
+func (r mysqlPostRepo) GetPostVersion(ctx context.Context, postID int) (ContentVersion, error) {
+	counters := make([]sql.NullString, 3)
+	times := make([]sql.NullString, 9)
+	err := cachedQueryRow(ctx, r.db, mysqlQueryPostVersion, postID).Scan(&counters[0], &counters[1], &counters[2],
+		&times[0], &times[1], &times[2], &times[3], &times[4], &times[5], &times[6], &times[7], &times[8])
+	if err != nil {
+		return ContentVersion{}, err
+	}
+	return buildVersion(counters, times), nil
+}
+
+// mysqlVoteRepo реализует VoteRepo для MySQL: upsert голосов через ON DUPLICATE KEY UPDATE.
+type mysqlVoteRepo struct {
+	sqliteVoteRepo
+}
+
+func (r mysqlVoteRepo) SetPostLike(ctx context.Context, userID, postID int) error {
+	return r.setVote(ctx, "post_votes", "post_id", userID, postID, 1)
+}
+
+func (r mysqlVoteRepo) SetPostDislike(ctx context.Context, userID, postID int) error {
+	return r.setVote(ctx, "post_votes", "post_id", userID, postID, -1)
+}
+
+func (r mysqlVoteRepo) SetCommentLike(ctx context.Context, userID, commentID int) error {
+	return r.setVote(ctx, "comment_votes", "comment_id", userID, commentID, 1)
+}
+
+func (r mysqlVoteRepo) SetCommentDislike(ctx context.Context, userID, commentID int) error {
+	return r.setVote(ctx, "comment_votes", "comment_id", userID, commentID, -1)
+}
+
+// setVote устанавливает или обновляет голос пользователя в таблице table.
+func (r mysqlVoteRepo) setVote(ctx context.Context, table, column string, userID, targetID, vote int) error {
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(`
+        INSERT INTO %s (user_id, %s, vote, voted_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+        ON DUPLICATE KEY UPDATE vote = VALUES(vote), voted_at = CURRENT_TIMESTAMP
+    `, table, column), userID, targetID, vote)
+	return err
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"forum/models"
+)
+
+// Виды уведомлений о лайках.
+const (
+	NotificationPostLikes    = "post_likes"
+	NotificationCommentLikes = "comment_likes"
+)
+
+// NotificationDayFormat — формат дня, за который собрано уведомление.
+const NotificationDayFormat = time.DateOnly
+
+// BuildVoteNotifications собирает лайки, поставленные за день day (по местному времени сервера), в уведомления
+// авторам: одно уведомление на пост или комментарий с числом его лайков за этот день, а не по уведомлению
+// на каждый голос. Учитываются только лайки, которые к моменту сборки не сняты и не заменены дизлайком;
+// лайки собственных постов и комментариев, а также удалённые посты и комментарии пропускаются.
+//
+// Уже собранные уведомления за день не меняются, поэтому день можно собирать повторно — появятся только
+// уведомления о постах и комментариях, которых в прошлый раз не было. Возвращает число новых уведомлений.
+// Время голосов хранится в UTC (CURRENT_TIMESTAMP), поэтому границы дня сравниваются как строки в UTC.
+func BuildVoteNotifications(ctx context.Context, db *sql.DB, day time.Time) (int, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+	from := start.UTC().Format(leaderboardTime)
+	to := start.AddDate(0, 0, 1).UTC().Format(leaderboardTime)
+	rows, err := db.QueryContext(ctx, `
+        SELECT p.user_id, ?, p.id, p.id, COUNT(*)
+        FROM post_votes v JOIN posts p ON p.id = v.post_id
+        WHERE v.vote = 1 AND v.voted_at >= ? AND v.voted_at < ? AND v.user_id <> p.user_id AND p.deleted_at IS NULL
+        GROUP BY p.user_id, p.id
+        UNION ALL
+        SELECT c.user_id, ?, c.id, c.post_id, COUNT(*)
+        FROM comment_votes v JOIN comments c ON c.id = v.comment_id
+        WHERE v.vote = 1 AND v.voted_at >= ? AND v.voted_at < ? AND v.user_id <> c.user_id AND c.deleted_at IS NULL
+        GROUP BY c.user_id, c.id, c.post_id
+    `, NotificationPostLikes, from, to, NotificationCommentLikes, from, to)
+	if err != nil {
+		return 0, err
+	}
+	var batch []models.Notification
+	var owners []int
+	for rows.Next() {
+		var n models.Notification
+		var userID int
+		if err := rows.Scan(&userID, &n.Kind, &n.ItemID, &n.PostID, &n.Likes); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, n)
+		owners = append(owners, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	key := start.Format(NotificationDayFormat)
+	created := 0
+	for i, n := range batch {
+		res, err := tx.ExecContext(ctx, `
+            INSERT INTO notifications (user_id, kind, item_id, post_id, day, likes)
+            SELECT id, ?, ?, ?, ?, ? FROM users
+            WHERE id = ? AND NOT EXISTS (SELECT 1 FROM notifications WHERE user_id = ? AND kind = ? AND item_id = ? AND day = ?)
+        `, n.Kind, n.ItemID, n.PostID, key, n.Likes, owners[i], owners[i], n.Kind, n.ItemID, key)
+		if err != nil {
+			return 0, err
+		}
+		if affected, err := res.RowsAffected(); err == nil {
+			created += int(affected)
+		}
+	}
+	return created, tx.Commit()
+}
+
+// GetNotifications возвращает не больше limit последних уведомлений пользователя userID, новые первыми.
+// Уведомления об удалённых постах не показываются.
+func GetNotifications(ctx context.Context, db *sql.DB, userID, limit int) ([]models.Notification, error) {
+	rows, err := db.QueryContext(ctx, `
+        SELECT n.id, n.kind, n.item_id, n.post_id, p.title, n.day, n.likes, n.created_at, n.read_at IS NOT NULL
+        FROM notifications n JOIN posts p ON p.id = n.post_id
+        WHERE n.user_id = ? AND p.deleted_at IS NULL
+        ORDER BY n.day DESC, n.id DESC
+        LIMIT ?
+    `, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []models.Notification
+	for rows.Next() {
+		var n models.Notification
+		if err := rows.Scan(&n.ID, &n.Kind, &n.ItemID, &n.PostID, &n.Title, &n.Day, &n.Likes, &n.CreatedAt, &n.Read); err != nil {
+			return nil, err
+		}
+		list = append(list, n)
+	}
+	return list, rows.Err()
+}
+
+// MarkNotificationsRead отмечает все уведомления пользователя userID прочитанными.
+func MarkNotificationsRead(ctx context.Context, db *sql.DB, userID int) error {
+	_, err := db.ExecContext(ctx, "UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id = ? AND read_at IS NULL", userID)
+	return err
+}
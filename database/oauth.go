@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// GetOAuthUser возвращает ID и роль пользователя, к которому привязана учётная запись providerUserID
+// у провайдера provider. Если учётная запись не привязана, возвращает sql.ErrNoRows.
+func GetOAuthUser(ctx context.Context, db *sql.DB, provider, providerUserID string) (int, string, error) {
+	var userID int
+	var role string
+	err := db.QueryRowContext(ctx, `
+        SELECT u.id, u.role FROM oauth_accounts a JOIN users u ON u.id = a.user_id
+        WHERE a.provider = ? AND a.provider_user_id = ?`, provider, providerUserID).Scan(&userID, &role)
+	return userID, role, err
+}
+
+// LinkOAuthAccount привязывает учётную запись providerUserID у провайдера provider к пользователю userID.
+// Учётная запись провайдера привязывается только к одному пользователю; повторная привязка возвращает
+// ошибку уникальности.
+func LinkOAuthAccount(ctx context.Context, db *sql.DB, provider, providerUserID string, userID int) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO oauth_accounts (provider, provider_user_id, user_id) VALUES (?, ?, ?)",
+		provider, providerUserID, userID)
+	return err
+}
+
+// RegisterOAuthUser создаёт пользователя, входящего через провайдера provider, и привязывает к нему учётную
+// запись providerUserID. Пароль hashedPassword случайный: войти по паролю такой пользователь не может.
+// Возвращает ID нового пользователя.
+func RegisterOAuthUser(ctx context.Context, db *sql.DB, provider, providerUserID, email, username, displayName, hashedPassword string) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, "INSERT INTO users (email, username, password, role, display_name) VALUES (?, ?, ?, 'user', ?)",
+		email, username, hashedPassword, sql.NullString{String: displayName, Valid: displayName != ""})
+	if err != nil {
+		return 0, err
+	}
+	userID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO oauth_accounts (provider, provider_user_id, user_id) VALUES (?, ?, ?)",
+		provider, providerUserID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return int(userID), tx.Commit()
+}
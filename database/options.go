@@ -0,0 +1,152 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// PoolOptions задаёт размер и время жизни соединений пула database/sql.
+// Нулевое значение поля означает ограничение database/sql по умолчанию (без ограничения).
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// apply настраивает пул соединений db.
+func (o PoolOptions) apply(db *sql.DB) {
+	db.SetMaxOpenConns(o.MaxOpenConns)
+	db.SetMaxIdleConns(o.MaxIdleConns)
+	db.SetConnMaxLifetime(o.ConnMaxLifetime)
+}
+
+// Validate проверяет, что значения пула неотрицательны.
+func (o PoolOptions) Validate() error {
+	if o.MaxOpenConns < 0 || o.MaxIdleConns < 0 || o.ConnMaxLifetime < 0 {
+		return fmt.Errorf("pool limits must not be negative")
+	}
+	return nil
+}
+
+// MySQLOptions задаёт параметры подключения к MySQL/MariaDB.
+type MySQLOptions struct {
+	Pool PoolOptions
+	// SlowQueryThreshold — запросы дольше этого времени пишутся в журнал; 0 отключает журнал.
+	SlowQueryThreshold time.Duration
+}
+
+// DefaultMySQLOptions возвращает параметры MySQL по умолчанию. Время жизни соединения меньше
+// типичного wait_timeout сервера, чтобы пул не выдавал соединения, уже закрытые MySQL.
+func DefaultMySQLOptions() MySQLOptions {
+	return MySQLOptions{
+		Pool:               PoolOptions{MaxOpenConns: 25, MaxIdleConns: 25, ConnMaxLifetime: 5 * time.Minute},
+		SlowQueryThreshold: defaultSlowQueryThreshold,
+	}
+}
+
+// Validate проверяет параметры MySQL.
+func (o MySQLOptions) Validate() error {
+	if err := o.Pool.Validate(); err != nil {
+		return err
+	}
+	if o.SlowQueryThreshold < 0 {
+		return fmt.Errorf("slow query threshold must not be negative")
+	}
+	return nil
+}
+
+// defaultSlowQueryThreshold — порог журнала медленных запросов по умолчанию.
+const defaultSlowQueryThreshold = 200 * time.Millisecond
+
+// SQLiteOptions задаёт параметры подключения к SQLite.
+type SQLiteOptions struct {
+	Pool PoolOptions
+	// Synchronous — значение PRAGMA synchronous: OFF, NORMAL, FULL или EXTRA.
+	// В режиме WAL значение NORMAL безопасно при падении приложения и заметно быстрее FULL.
+	Synchronous string
+	// CacheSize — значение PRAGMA cache_size для каждого соединения: положительное — в страницах,
+	// отрицательное — в КиБ. 0 оставляет значение SQLite по умолчанию (около 2 МиБ).
+	CacheSize int
+	// BusyTimeout — сколько писатель ждёт освобождения блокировки вместо ошибки "database is locked".
+	BusyTimeout time.Duration
+	// SlowQueryThreshold — запросы дольше этого времени пишутся в журнал; 0 отключает журнал.
+	SlowQueryThreshold time.Duration
+}
+
+// DefaultSQLiteOptions возвращает параметры SQLite по умолчанию.
+// Запись в SQLite всё равно выполняется по одной; ограничение числа соединений
+// не даёт пику запросов создать десятки соединений, ожидающих одну блокировку.
+func DefaultSQLiteOptions() SQLiteOptions {
+	return SQLiteOptions{
+		Pool:               PoolOptions{MaxOpenConns: 8, MaxIdleConns: 8},
+		Synchronous:        "NORMAL",
+		BusyTimeout:        5 * time.Second,
+		SlowQueryThreshold: defaultSlowQueryThreshold,
+	}
+}
+
+// Validate проверяет параметры SQLite.
+func (o SQLiteOptions) Validate() error {
+	if err := o.Pool.Validate(); err != nil {
+		return err
+	}
+	switch strings.ToUpper(o.Synchronous) {
+	case "OFF", "NORMAL", "FULL", "EXTRA":
+	default:
+		return fmt.Errorf("invalid synchronous mode %q (available: OFF, NORMAL, FULL, EXTRA)", o.Synchronous)
+	}
+	if o.BusyTimeout < 0 {
+		return fmt.Errorf("busy timeout must not be negative")
+	}
+	if o.SlowQueryThreshold < 0 {
+		return fmt.Errorf("slow query threshold must not be negative")
+	}
+	return nil
+}
+
+// dsn возвращает строку подключения к файлу path.
+// WAL позволяет читать базу параллельно с записью, а _txlock=immediate берёт блокировку записи
+// в начале транзакции, исключая взаимные блокировки при её повышении.
+func (o SQLiteOptions) dsn(path string) string {
+	params := url.Values{}
+	params.Set("_foreign_keys", "on")
+	params.Set("_journal_mode", "WAL")
+	params.Set("_synchronous", strings.ToUpper(o.Synchronous))
+	params.Set("_busy_timeout", fmt.Sprint(o.BusyTimeout.Milliseconds()))
+	params.Set("_txlock", "immediate")
+	return path + "?" + params.Encode()
+}
+
+// sqliteConnector открывает соединения SQLite с заданными параметрами.
+// cache_size не задаётся через DSN, поэтому выполняется в ConnectHook для каждого нового соединения.
+type sqliteConnector struct {
+	dsn    string
+	driver *sqlite3.SQLiteDriver
+}
+
+func newSQLiteConnector(path string, opts SQLiteOptions) sqliteConnector {
+	drv := &sqlite3.SQLiteDriver{}
+	if opts.CacheSize != 0 {
+		pragma := fmt.Sprintf("PRAGMA cache_size = %d", opts.CacheSize)
+		drv.ConnectHook = func(conn *sqlite3.SQLiteConn) error {
+			_, err := conn.Exec(pragma, nil)
+			return err
+		}
+	}
+	return sqliteConnector{dsn: opts.dsn(path), driver: drv}
+}
+
+func (c sqliteConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c sqliteConnector) Driver() driver.Driver {
+	return c.driver
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestOpenSQLiteWithOptions проверяет, что пул и PRAGMA применяются к соединениям.
+func TestOpenSQLiteWithOptions(t *testing.T) {
+	opts := SQLiteOptions{
+		Pool:        PoolOptions{MaxOpenConns: 2, MaxIdleConns: 1},
+		Synchronous: "full",
+		CacheSize:   -4096,
+		BusyTimeout: time.Second,
+	}
+	db, err := OpenSQLiteWithOptions(filepath.Join(t.TempDir(), "forum.db"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		CloseStatements(db)
+		db.Close()
+	})
+
+	if got := db.Stats().MaxOpenConnections; got != 2 {
+		t.Fatalf("MaxOpenConnections = %d, want 2", got)
+	}
+	var cacheSize, synchronous, busyTimeout int
+	if err := db.QueryRow("PRAGMA cache_size").Scan(&cacheSize); err != nil || cacheSize != -4096 {
+		t.Fatalf("cache_size = %d, %v, want -4096", cacheSize, err)
+	}
+	if err := db.QueryRow("PRAGMA synchronous").Scan(&synchronous); err != nil || synchronous != 2 {
+		t.Fatalf("synchronous = %d, %v, want 2 (FULL)", synchronous, err)
+	}
+	if err := db.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout); err != nil || busyTimeout != 1000 {
+		t.Fatalf("busy_timeout = %d, %v, want 1000", busyTimeout, err)
+	}
+
+	opts.Synchronous = "fast"
+	if err := opts.Validate(); err == nil {
+		t.Fatal("Validate accepted synchronous = fast")
+	}
+}
+
+// TestSlowQueryLog проверяет, что медленный запрос попадает в журнал с хэшем параметров вместо их значений.
+func TestSlowQueryLog(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	opts := DefaultSQLiteOptions()
+	opts.SlowQueryThreshold = time.Nanosecond
+	db, err := OpenSQLiteWithOptions(filepath.Join(t.TempDir(), "forum.db"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		CloseStatements(db)
+		db.Close()
+	})
+
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE email = ?", "secret@example.com").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "Slow query") || !strings.Contains(out, "SELECT COUNT(*) FROM users WHERE email = ?") {
+		t.Fatalf("slow query not logged: %q", out)
+	}
+	if strings.Contains(out, "secret@example.com") {
+		t.Fatalf("query parameters leaked into the log: %q", out)
+	}
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"forum/models"
+)
+
+// defaultPages создаются миграцией, чтобы ссылки в подвале сайта сразу вели на существующие страницы;
+// текст затем меняет администратор в /admin/pages.
+var defaultPages = []models.Page{
+	{Slug: "rules", Title: "Rules", Content: "# Rules\n\n- Be kind to other members.\n- Stay on topic for the board you post in.\n- No spam or advertising.\n\nModerators may remove posts and comments that break these rules."},
+	{Slug: "about", Title: "About", Content: "# About\n\nPolar Lights is a community forum for sharing plans, stories and traditions."},
+	{Slug: "faq", Title: "FAQ", Content: "# Frequently asked questions\n\n**How do I create a post?**\nRegister, log in and press *New post*.\n\n**Who can delete my post?**\nYou, the moderators of its board and the administrators."},
+}
+
+// ValidPageSlug сообщает, может ли slug быть адресом служебной страницы; правила те же, что у разделов (см. ValidBoardSlug).
+func ValidPageSlug(slug string) bool {
+	return slugPattern.MatchString(slug)
+}
+
+// insertDefaultPages добавляет страницы по умолчанию в только что созданную таблицу pages.
+func insertDefaultPages(tx *sql.Tx) error {
+	for _, p := range defaultPages {
+		if _, err := tx.Exec("INSERT INTO pages (slug, title, content) VALUES (?, ?, ?)", p.Slug, p.Title, p.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListPages возвращает все служебные страницы без текста, упорядоченные по адресу.
+func ListPages(ctx context.Context, db *sql.DB) ([]models.Page, error) {
+	rows, err := db.QueryContext(ctx, "SELECT slug, title, updated_at FROM pages ORDER BY slug")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pages := []models.Page{}
+	for rows.Next() {
+		var p models.Page
+		if err := rows.Scan(&p.Slug, &p.Title, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		pages = append(pages, p)
+	}
+	return pages, rows.Err()
+}
+
+// GetPage возвращает служебную страницу по адресу slug. Если страницы нет, возвращает sql.ErrNoRows.
+func GetPage(ctx context.Context, db *sql.DB, slug string) (models.Page, error) {
+	var p models.Page
+	err := db.QueryRowContext(ctx, "SELECT slug, title, content, updated_at FROM pages WHERE slug = ?", slug).
+		Scan(&p.Slug, &p.Title, &p.Content, &p.UpdatedAt)
+	return p, err
+}
+
+// SavePage обновляет страницу slug или создаёт её, если такой ещё нет. Адрес проверяет вызывающий код (см. ValidPageSlug).
+// Наличие страницы проверяется в той же транзакции: ON CONFLICT (SQLite) и ON DUPLICATE KEY (MySQL) пишутся по-разному.
+func SavePage(ctx context.Context, db *sql.DB, slug, title, content string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM pages WHERE slug = ?)", slug).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		_, err = tx.ExecContext(ctx, "UPDATE pages SET title = ?, content = ?, updated_at = CURRENT_TIMESTAMP WHERE slug = ?", title, content, slug)
+	} else {
+		_, err = tx.ExecContext(ctx, "INSERT INTO pages (slug, title, content) VALUES (?, ?, ?)", slug, title, content)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeletePage удаляет служебную страницу slug. Отсутствие страницы ошибкой не считается.
+func DeletePage(ctx context.Context, db *sql.DB, slug string) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM pages WHERE slug = ?", slug)
+	return err
+}
@@ -0,0 +1,199 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"forum/models"
+)
+
+// ErrInvalidCursor возвращается, если курсор страницы не удалось разобрать.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor — позиция последней строки страницы при постраничном выводе по ключу.
+// CreatedAt хранит значение колонки created_at как текст без преобразований, чтобы сравнение
+// в WHERE совпадало с порядком ORDER BY независимо от формата и часового пояса в базе.
+//
+// Лента «Лучшие» с затуханием рейтинга (см. ranking.Decay) упорядочивается в Go, а не в базе: её страницы
+// задаются номером первой строки Offset в порядке на момент RankedAt (Unix-время первой страницы),
+// чтобы страницы одной прокрутки не пересекались, хотя веса постов меняются со временем.
+type pageCursor struct {
+	Score     int    `json:"s,omitempty"`
+	CreatedAt string `json:"t"`
+	ID        int    `json:"i"`
+	Offset    int    `json:"o,omitempty"`
+	RankedAt  int64  `json:"r,omitempty"`
+}
+
+// encode возвращает курсор в виде непрозрачной строки для URL.
+func (c pageCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor разбирает строку курсора. Пустая строка означает первую страницу.
+func decodeCursor(s string) (*pageCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.CreatedAt == "" || c.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// summaryFilters возвращает условия выборки постов для JSON API (категория и автор) и их параметры.
+// API анонимный, поэтому посты только для авторизованных (см. SetPostMembersOnly) в него не попадают.
+func summaryFilters(category string, authorID int) (string, []interface{}) {
+	where := " AND NOT p.members_only"
+	var args []interface{}
+	if category != "" {
+		where += ` AND EXISTS (SELECT 1 FROM post_categories pc JOIN categories c ON pc.category_id = c.id
+                   WHERE pc.post_id = p.id AND c.name = ?)`
+		args = append(args, category)
+	}
+	if authorID > 0 {
+		where += " AND p.user_id = ?"
+		args = append(args, authorID)
+	}
+	return where, args
+}
+
+// GetPostSummariesAfter — вариант GetPostSummaries с постраничным выводом по ключу вместо OFFSET:
+// следующая страница начинается сразу после строки, на которую указывает cursor, поэтому
+// стоимость запроса не растёт с номером страницы. Пустой cursor означает первую страницу.
+// Возвращает курсор следующей страницы или пустую строку, если страница последняя.
+func GetPostSummariesAfter(ctx context.Context, db *sql.DB, filter, category string, authorID, excerptLen, limit int, cursor string) ([]models.PostSummary, string, error) {
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	filters, filterArgs := summaryFilters(category, authorID)
+	query := `
+        SELECT id, title, excerpt, score, comment_count, created_at FROM (
+            SELECT p.id, p.title, SUBSTR(p.content, 1, ?) AS excerpt,
+                   p.likes - p.dislikes AS score, p.comment_count,
+                   CAST(p.created_at AS CHAR) AS created_at
+            FROM posts p
+            WHERE p.deleted_at IS NULL` + filters + `
+        ) t
+    `
+	args := append([]interface{}{excerptLen}, filterArgs...)
+	best := filter == "best"
+	if after != nil {
+		if best {
+			query += " WHERE (t.score, t.created_at, t.id) < (?, ?, ?)"
+			args = append(args, after.Score, after.CreatedAt, after.ID)
+		} else {
+			query += " WHERE (t.created_at, t.id) < (?, ?)"
+			args = append(args, after.CreatedAt, after.ID)
+		}
+	}
+	if best {
+		query += " ORDER BY t.score DESC, t.created_at DESC, t.id DESC"
+	} else {
+		query += " ORDER BY t.created_at DESC, t.id DESC"
+	}
+	query += " LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("query failed: %v", err)
+	}
+	defer rows.Close()
+
+	posts := []models.PostSummary{}
+	var last pageCursor
+	for rows.Next() {
+		var p models.PostSummary
+		if err := rows.Scan(&p.ID, &p.Title, &p.Excerpt, &p.Score, &p.CommentCount, &last.CreatedAt); err != nil {
+			return nil, "", fmt.Errorf("scan failed: %v", err)
+		}
+		last.ID = p.ID
+		if best {
+			last.Score = p.Score
+		}
+		posts = append(posts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("rows error: %v", err)
+	}
+	if len(posts) < limit {
+		return posts, "", nil
+	}
+	return posts, last.encode(), nil
+}
+
+// GetCommentsPage возвращает страницу комментариев к посту (от новых к старым) с лайками, дизлайками
+// и голосом текущего пользователя. Постраничный вывод идёт по ключу (created_at, id), как в GetPostSummariesAfter.
+// Возвращает курсор следующей страницы или пустую строку, если страница последняя.
+func GetCommentsPage(ctx context.Context, db *sql.DB, currentUserID, postID, limit int, cursor string) ([]models.CommentData, string, error) {
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	query := `
+        SELECT c.id, c.post_id, c.user_id, u.username, COALESCE(u.display_name, ''), c.content, c.created_at, CAST(c.created_at AS CHAR),
+               c.likes, c.dislikes,
+               COALESCE((SELECT cv.vote FROM comment_votes cv WHERE cv.comment_id = c.id AND cv.user_id = ?), 0) AS user_vote
+        FROM comments c
+        JOIN users u ON c.user_id = u.id
+        WHERE c.post_id = ? AND c.deleted_at IS NULL
+    `
+	args := []interface{}{currentUserID, postID}
+	if after != nil {
+		query += " AND (CAST(c.created_at AS CHAR), c.id) < (?, ?)"
+		args = append(args, after.CreatedAt, after.ID)
+	}
+	query += " ORDER BY CAST(c.created_at AS CHAR) DESC, c.id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("query failed: %v", err)
+	}
+	defer rows.Close()
+
+	comments := []models.CommentData{}
+	var last pageCursor
+	for rows.Next() {
+		var c models.CommentData
+		if err := rows.Scan(&c.ID, &c.PostID, &c.UserID, &c.Username, &c.DisplayName, &c.Content, &c.CreatedAt, &last.CreatedAt,
+			&c.Likes, &c.Dislikes, &c.UserVote); err != nil {
+			return nil, "", fmt.Errorf("scan failed: %v", err)
+		}
+		last.ID = c.ID
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("rows error: %v", err)
+	}
+	if len(comments) < limit {
+		return comments, "", nil
+	}
+	return comments, last.encode(), nil
+}
+
+// GetCommentCursor возвращает курсор GetCommentsPage, с которого страница комментариев поста postID начинается
+// комментарием commentID, чтобы на него можно было сослаться, даже если он не на первой странице.
+// Для удалённого комментария или комментария другого поста возвращает sql.ErrNoRows.
+func GetCommentCursor(ctx context.Context, db *sql.DB, postID, commentID int) (string, error) {
+	var createdAt string
+	err := db.QueryRowContext(ctx, "SELECT CAST(created_at AS CHAR) FROM comments WHERE id = ? AND post_id = ? AND deleted_at IS NULL",
+		commentID, postID).Scan(&createdAt)
+	if err != nil {
+		return "", err
+	}
+	// Страница содержит комментарии строго раньше позиции курсора; ID на единицу больше включает сам комментарий.
+	return pageCursor{CreatedAt: createdAt, ID: commentID + 1}.encode(), nil
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrResetInvalid возвращается, если ссылки сброса пароля нет, она уже использована или её срок истёк.
+var ErrResetInvalid = errors.New("password reset link is invalid or expired")
+
+// CreatePasswordReset сохраняет ссылку сброса пароля пользователя userID, действующую до expiresAt.
+// В базе хранится только хеш tokenHash: утечка таблицы не даёт войти по ссылкам. Прежние ссылки
+// пользователя перестают работать.
+func CreatePasswordReset(ctx context.Context, db *sql.DB, userID int, tokenHash string, expiresAt time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM password_resets WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO password_resets (token_hash, user_id, expires_at) VALUES (?, ?, ?)",
+		tokenHash, userID, expiresAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetPasswordReset возвращает ID пользователя, которому выдана ссылка с хешем tokenHash.
+// Если ссылки нет или к моменту now её срок истёк, возвращает ErrResetInvalid.
+func GetPasswordReset(ctx context.Context, db *sql.DB, tokenHash string, now time.Time) (int, error) {
+	return passwordResetUser(db.QueryRowContext(ctx, "SELECT user_id, expires_at FROM password_resets WHERE token_hash = ?", tokenHash), now)
+}
+
+// ResetPassword заменяет пароль пользователя по ссылке с хешем tokenHash на hashedPassword и удаляет все его
+// ссылки сброса, так что ссылка работает один раз. Возвращает ID пользователя; если ссылка недействительна
+// к моменту now — ErrResetInvalid. Сессии пользователя не трогает: их закрывает вызывающий (DeleteUserSessions).
+func ResetPassword(ctx context.Context, db *sql.DB, tokenHash, hashedPassword string, now time.Time) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	userID, err := passwordResetUser(tx.QueryRowContext(ctx, "SELECT user_id, expires_at FROM password_resets WHERE token_hash = ?", tokenHash), now)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET password = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", hashedPassword, userID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM password_resets WHERE user_id = ?", userID); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
+
+// passwordResetUser читает ID пользователя и срок ссылки из row и проверяет срок на момент now.
+func passwordResetUser(row *sql.Row, now time.Time) (int, error) {
+	var userID int
+	var expiresAt time.Time
+	err := row.Scan(&userID, &expiresAt)
+	if err == sql.ErrNoRows {
+		return 0, ErrResetInvalid
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !now.Before(expiresAt) {
+		return 0, ErrResetInvalid
+	}
+	return userID, nil
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"forum/models"
+)
+
+// Причины жалоб на посты.
+const (
+	ReportSpam     = "spam"
+	ReportAbuse    = "abuse"
+	ReportOffTopic = "off_topic"
+	ReportIllegal  = "illegal"
+)
+
+// ReportReasons — причины жалоб в порядке вариантов формы; подписи берутся из каталога (report.reason.<причина>).
+var ReportReasons = []string{ReportSpam, ReportAbuse, ReportOffTopic, ReportIllegal}
+
+// ModerationProviderReports — источник решений в /moderation для постов, скрытых по жалобам читателей.
+const ModerationProviderReports = "reports"
+
+// ErrAlreadyReported возвращается, если пользователь уже жаловался на этот пост.
+var ErrAlreadyReported = errors.New("post already reported by the user")
+
+// ReportPost сохраняет жалобу пользователя userID на пост postID по причине reason с весом weight (зависит от уровня
+// доверия, см. permissions.Rules.FlagWeight). Если суммарный вес жалоб на пост по этой причине достиг threshold
+// (0 — жалобы по ней пост не скрывают), пост скрывается до решения модератора: в очереди /moderation появляется
+// решение с источником ModerationProviderReports, причиной в Categories и весом жалоб в Score. Пост, который
+// модератор уже опубликовал после жалоб, повторно не скрывается. hidden сообщает, скрыла ли пост эта жалоба.
+// Если поста нет или он удалён, возвращает sql.ErrNoRows.
+func ReportPost(ctx context.Context, db *sql.DB, postID, userID int, reason string, weight, threshold int) (hidden bool, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRowContext(ctx, "SELECT 1 FROM post_reports WHERE post_id = ? AND user_id = ?", postID, userID).Scan(&exists)
+	if err == nil {
+		return false, ErrAlreadyReported
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+	var authorID int
+	err = tx.QueryRowContext(ctx, "SELECT user_id FROM posts WHERE id = ? AND deleted_at IS NULL", postID).Scan(&authorID)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO post_reports (post_id, user_id, reason, weight) VALUES (?, ?, ?, ?)",
+		postID, userID, reason, weight)
+	if err != nil {
+		return false, err
+	}
+
+	if threshold > 0 {
+		var total, approved int
+		err = tx.QueryRowContext(ctx, "SELECT SUM(weight) FROM post_reports WHERE post_id = ? AND reason = ?", postID, reason).
+			Scan(&total)
+		if err != nil {
+			return false, err
+		}
+		err = tx.QueryRowContext(ctx, `
+            SELECT COUNT(*) FROM moderation_decisions
+            WHERE kind = 'post' AND item_id = ? AND provider = ? AND status = 'approved'
+        `, postID, ModerationProviderReports).Scan(&approved)
+		if err != nil {
+			return false, err
+		}
+		if total >= threshold && approved == 0 {
+			err = recordDecision(ctx, tx, models.ModerationDecision{
+				Kind: "post", ItemID: postID, UserID: authorID, Provider: ModerationProviderReports,
+				Score: float64(total), Flagged: true, Categories: reason, Status: ModerationPending,
+			})
+			if err != nil {
+				return false, err
+			}
+			hidden = true
+		}
+	}
+	return hidden, tx.Commit()
+}
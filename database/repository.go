@@ -0,0 +1,671 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"forum/cache"
+	"forum/models"
+)
+
+// Диалекты SQL, для которых есть реализации репозиториев.
+const (
+	DialectSQLite = "sqlite"
+	DialectMySQL  = "mysql"
+)
+
+// Store объединяет репозитории, через которые обработчики работают с данными.
+// DB остаётся доступным для функций, ещё не вынесенных в репозитории; такие функции
+// написаны для SQLite, поэтому перед их вызовом стоит проверять Dialect.
+// Реализации репозиториев возвращают sql.ErrNoRows, если запись не найдена.
+// Cache задан, если Store создан через WithCache; обработчики кэшируют в нём готовые фрагменты страниц.
+type Store struct {
+	DB          *sql.DB
+	Dialect     string
+	Cache       cache.Cache
+	Users       UserRepo
+	Posts       PostRepo
+	Comments    CommentRepo
+	Votes       VoteRepo
+	Boards      BoardRepo
+	Settings    SettingsRepo
+	Categories  CategoryRepo
+	Series      SeriesRepo
+	Collections CollectionRepo
+}
+
+// NewSQLiteStore создаёт Store с реализациями репозиториев для SQLite.
+func NewSQLiteStore(db *sql.DB) *Store {
+	return &Store{
+		DB:          db,
+		Dialect:     DialectSQLite,
+		Users:       sqliteUserRepo{db: db},
+		Posts:       sqlitePostRepo{db: db},
+		Comments:    sqliteCommentRepo{db: db},
+		Votes:       sqliteVoteRepo{db: db},
+		Boards:      sqliteBoardRepo{db: db},
+		Settings:    sqliteSettingsRepo{db: db},
+		Categories:  sqliteCategoryRepo{db: db},
+		Series:      sqliteSeriesRepo{db: db},
+		Collections: sqliteCollectionRepo{db: db},
+	}
+}
+
+// UserRepo описывает операции над пользователями и их сессиями.
+type UserRepo interface {
+	GetUsernameByID(ctx context.Context, userID int) (string, error)
+	GetDisplayName(ctx context.Context, userID int) (string, error)
+	GetUserByEmail(ctx context.Context, email string) (int, string, string, string, error)
+	GetUserProfileData(ctx context.Context, userID int) (string, time.Time, error)
+	EmailExists(ctx context.Context, email string) (bool, error)
+	UsernameExists(ctx context.Context, username string) (bool, error)
+	RegisterUser(ctx context.Context, email, username, hashedPassword string) error
+	UpdateUserProfile(ctx context.Context, userID int, username string, displayName string) error
+	GetUserStanding(ctx context.Context, userID int) (models.UserStanding, error)
+	CountRecentPosts(ctx context.Context, userID int, since time.Time, limit int) (int, error)
+	CountRecentComments(ctx context.Context, userID int, since time.Time, limit int) (int, error)
+	GetUserActivity(ctx context.Context, userID int, from time.Time) ([]models.ActivityDay, error)
+	GetLeaderboards(ctx context.Context, since time.Time, limit int) (models.Leaderboards, error)
+	GetPinnedPostID(ctx context.Context, userID int) (int, error)
+	SetPinnedPost(ctx context.Context, userID, postID int) error
+	GetLanguage(ctx context.Context, userID int) (string, error)
+	SetLanguage(ctx context.Context, userID int, lang string) error
+	MarkPostViewed(ctx context.Context, userID, postID int, at time.Time) error
+	MarkAllRead(ctx context.Context, userID int) error
+	GetReadState(ctx context.Context, userID int) (string, error)
+	GetViewHistory(ctx context.Context, userID, limit int) ([]models.HistoryEntry, error)
+	HistoryEnabled(ctx context.Context, userID int) (bool, error)
+	SetHistoryEnabled(ctx context.Context, userID int, enabled bool) error
+	PurgeViewHistory(ctx context.Context, retention time.Duration) (int64, error)
+	GetSessionData(ctx context.Context, sessionID string) (int, string, time.Time, error)
+	CreateSession(ctx context.Context, sessionID string, userID int, role string, expiry time.Time) error
+	DeleteSession(ctx context.Context, sessionID string) error
+	DeleteExpiredSession(ctx context.Context, sessionID string) error
+	DeleteUserSessions(ctx context.Context, userID int) error
+	SetUserRole(ctx context.Context, userID int, role string) error
+	PurgeExpiredSessions(ctx context.Context) (int64, error)
+}
+
+// PostRepo описывает операции над постами, их категориями и версиями лент.
+type PostRepo interface {
+	GetPosts(ctx context.Context, userID int, q FeedQuery) ([]models.PostData, error)
+	GetFeedPage(ctx context.Context, userID int, q FeedQuery, limit int, cursor string) ([]models.PostData, string, error)
+	SearchPosts(ctx context.Context, userID int, f models.SearchFilters, limit int, cursor string) ([]models.PostData, string, error)
+	GetPostByID(ctx context.Context, postID, currentUserID int) (models.PostData, error)
+	GetPostByIDAndUserID(ctx context.Context, postID int, userID int) (models.PostData, error)
+	GetPostOwnerID(ctx context.Context, postID int) (int, error)
+	GetUserPosts(ctx context.Context, userID, currentUserID int) ([]models.PostData, error)
+	GetPostSummaries(ctx context.Context, filter, category string, authorID, excerptLen, limit, offset int) ([]models.PostSummary, error)
+	GetPostSummariesAfter(ctx context.Context, filter, category string, authorID, excerptLen, limit int, cursor string) ([]models.PostSummary, string, error)
+	CreatePost(ctx context.Context, userID, boardID int, title, content, imageURL string, createdAt time.Time) (int64, error)
+	PublishPost(ctx context.Context, p NewPost) (int64, error)
+	UpdatePost(ctx context.Context, postID int, title, content, imageURL string) error
+	SetPostMembersOnly(ctx context.Context, postID int, membersOnly bool) error
+	SetPostCommentPolicy(ctx context.Context, postID int, policy string) error
+	DeletePost(ctx context.Context, postID int) error
+	DeleteUserPosts(ctx context.Context, userID int, postIDs []int) error
+	SetUserPostsUnpublished(ctx context.Context, userID int, postIDs []int, unpublished bool) error
+	SetUserPostsCategories(ctx context.Context, userID int, postIDs, categoryIDs []int) error
+	ListManagedPosts(ctx context.Context, userID int) ([]models.PostData, error)
+	PurgeDeletedContent(ctx context.Context, retention time.Duration) (int64, int64, error)
+	GetPostCategories(ctx context.Context, postID int) ([]models.Category, error)
+	GetPostCategoriesByPostIDs(ctx context.Context, postIDs []int) (map[int][]models.Category, error)
+	GetCategoryIDByName(ctx context.Context, catName string) (int, error)
+	AddPostCategory(ctx context.Context, postID int64, catID int) error
+	DeletePostCategories(ctx context.Context, postID int) error
+	GetFeedVersion(ctx context.Context) (ContentVersion, error)
+	GetPostVersion(ctx context.Context, postID int) (ContentVersion, error)
+}
+
+// CommentRepo описывает операции над комментариями.
+type CommentRepo interface {
+	CreateComment(ctx context.Context, postID int, userID int, content, createdAt string) (int64, error)
+	GetCommentsByPostID(ctx context.Context, userID, postID int) ([]models.CommentData, error)
+	GetCommentsByPostIDWithUserVote(ctx context.Context, currentUserID, postID int) ([]models.CommentData, error)
+	GetCommentsByPostIDs(ctx context.Context, currentUserID int, postIDs []int) (map[int][]models.CommentData, error)
+	GetLatestCommentsByPostIDs(ctx context.Context, postIDs []int, authorID, snippetLen int) (map[int]models.CommentData, error)
+	GetCommentCursor(ctx context.Context, postID, commentID int) (string, error)
+	GetCommentsPage(ctx context.Context, currentUserID, postID, limit int, cursor string) ([]models.CommentData, string, error)
+	GetCommentOwnerID(ctx context.Context, commentID int) (int, error)
+	DeleteComment(ctx context.Context, commentID int) error
+	DeleteUserComments(ctx context.Context, userID int, commentIDs []int) error
+	ListUserComments(ctx context.Context, userID, limit int) ([]models.CommentData, error)
+	DeletePostComments(ctx context.Context, postID int) error
+}
+
+// VoteRepo описывает операции над голосами за посты и комментарии.
+type VoteRepo interface {
+	GetUserPostVote(ctx context.Context, userID, postID int) (int64, bool, error)
+	SetPostLike(ctx context.Context, userID, postID int) error
+	SetPostDislike(ctx context.Context, userID, postID int) error
+	RemovePostVote(ctx context.Context, userID, postID int) error
+	GetPostVoteStats(ctx context.Context, userID, postID int) (int, int, int64, bool, error)
+	DeletePostVotes(ctx context.Context, postID int) error
+	GetUserCommentVote(ctx context.Context, userID, commentID int) (int64, bool, error)
+	SetCommentLike(ctx context.Context, userID, commentID int) error
+	SetCommentDislike(ctx context.Context, userID, commentID int) error
+	RemoveCommentVote(ctx context.Context, userID, commentID int) error
+	GetCommentVoteStats(ctx context.Context, userID, commentID int) (int, int, int64, bool, error)
+	DeleteCommentVotes(ctx context.Context, commentID int) error
+	CountRecentVotes(ctx context.Context, userID int, since time.Time) (int, error)
+}
+
+// BoardRepo описывает чтение разделов форума и проверку прав их модераторов.
+// Разделы и модераторы создаются подкомандами сервера (см. CreateBoard, SetBoardModerator).
+type BoardRepo interface {
+	ListBoards(ctx context.Context) ([]models.Board, error)
+	GetBoardBySlug(ctx context.Context, slug string) (models.Board, error)
+	IsBoardModerator(ctx context.Context, userID, boardID int) (bool, error)
+	ModeratesPost(ctx context.Context, userID, postID int) (bool, error)
+	ModeratesComment(ctx context.Context, userID, commentID int) (bool, error)
+}
+
+// SettingsRepo описывает настройки сайта, которые администратор меняет без перезапуска (см. SettingHideScoreBelow).
+type SettingsRepo interface {
+	GetSetting(ctx context.Context, name string) (string, error)
+	SetSetting(ctx context.Context, name, value string) error
+	GetSite(ctx context.Context) (models.Site, error)
+}
+
+// CategoryRepo описывает категории постов и их оформление (значок и цвет метки).
+type CategoryRepo interface {
+	ListCategories(ctx context.Context) ([]models.Category, error)
+	UpdateCategoryStyle(ctx context.Context, name, icon, color string) error
+}
+
+// SeriesRepo описывает серии постов и порядок постов в них.
+type SeriesRepo interface {
+	CreateSeries(ctx context.Context, userID int, title string) (int64, error)
+	ListUserSeries(ctx context.Context, userID int) ([]models.Series, error)
+	GetSeries(ctx context.Context, seriesID, viewerID int) (models.Series, error)
+	GetPostSeries(ctx context.Context, postID, viewerID int) (models.SeriesNav, error)
+	SetPostSeries(ctx context.Context, postID, seriesID int) error
+	MoveSeriesPart(ctx context.Context, seriesID, postID int, up bool) error
+}
+
+// CollectionRepo описывает подборки закладок пользователей и доступ к ним по ссылке.
+type CollectionRepo interface {
+	CreateCollection(ctx context.Context, userID int, name string) (int64, error)
+	ListUserCollections(ctx context.Context, userID, postID int) ([]models.Collection, error)
+	GetCollection(ctx context.Context, collectionID int) (models.Collection, error)
+	GetSharedCollection(ctx context.Context, token string) (models.Collection, error)
+	AddCollectionItem(ctx context.Context, collectionID, postID int) error
+	RemoveCollectionItem(ctx context.Context, collectionID, postID int) error
+	SetCollectionShared(ctx context.Context, collectionID int, shared bool) error
+	DeleteCollection(ctx context.Context, collectionID int) error
+}
+
+// sqliteUserRepo реализует UserRepo поверх функций пакета для SQLite.
+type sqliteUserRepo struct {
+	db *sql.DB
+}
+
+func (r sqliteUserRepo) GetUsernameByID(ctx context.Context, userID int) (string, error) {
+	return GetUsernameByID(ctx, r.db, userID)
+}
+
+func (r sqliteUserRepo) GetDisplayName(ctx context.Context, userID int) (string, error) {
+	return GetDisplayName(ctx, r.db, userID)
+}
+
+func (r sqliteUserRepo) GetUserByEmail(ctx context.Context, email string) (int, string, string, string, error) {
+	return GetUserByEmail(ctx, r.db, email)
+}
+
+func (r sqliteUserRepo) GetUserProfileData(ctx context.Context, userID int) (string, time.Time, error) {
+	return GetUserProfileData(ctx, r.db, userID)
+}
+
+func (r sqliteUserRepo) EmailExists(ctx context.Context, email string) (bool, error) {
+	return EmailExists(ctx, r.db, email)
+}
+
+func (r sqliteUserRepo) UsernameExists(ctx context.Context, username string) (bool, error) {
+	return UsernameExists(ctx, r.db, username)
+}
+
+func (r sqliteUserRepo) RegisterUser(ctx context.Context, email, username, hashedPassword string) error {
+	return RegisterUser(ctx, r.db, email, username, hashedPassword)
+}
+
+func (r sqliteUserRepo) UpdateUserProfile(ctx context.Context, userID int, username string, displayName string) error {
+	return UpdateUserProfile(ctx, r.db, userID, username, displayName)
+}
+
+func (r sqliteUserRepo) GetUserStanding(ctx context.Context, userID int) (models.UserStanding, error) {
+	return GetUserStanding(ctx, r.db, userID)
+}
+
+func (r sqliteUserRepo) CountRecentPosts(ctx context.Context, userID int, since time.Time, limit int) (int, error) {
+	return CountRecentPosts(ctx, r.db, userID, since, limit)
+}
+
+func (r sqliteUserRepo) CountRecentComments(ctx context.Context, userID int, since time.Time, limit int) (int, error) {
+	return CountRecentComments(ctx, r.db, userID, since, limit)
+}
+
+func (r sqliteUserRepo) GetUserActivity(ctx context.Context, userID int, from time.Time) ([]models.ActivityDay, error) {
+	return GetUserActivity(ctx, r.db, userID, from)
+}
+
+func (r sqliteUserRepo) GetLeaderboards(ctx context.Context, since time.Time, limit int) (models.Leaderboards, error) {
+	return GetLeaderboards(ctx, r.db, since, limit)
+}
+
+func (r sqliteUserRepo) GetPinnedPostID(ctx context.Context, userID int) (int, error) {
+	return GetPinnedPostID(ctx, r.db, userID)
+}
+
+func (r sqliteUserRepo) SetPinnedPost(ctx context.Context, userID, postID int) error {
+	return SetPinnedPost(ctx, r.db, userID, postID)
+}
+
+func (r sqliteUserRepo) GetLanguage(ctx context.Context, userID int) (string, error) {
+	return GetLanguage(ctx, r.db, userID)
+}
+
+func (r sqliteUserRepo) SetLanguage(ctx context.Context, userID int, lang string) error {
+	return SetLanguage(ctx, r.db, userID, lang)
+}
+
+func (r sqliteUserRepo) MarkPostViewed(ctx context.Context, userID, postID int, at time.Time) error {
+	return MarkPostViewed(ctx, r.db, userID, postID, at)
+}
+
+func (r sqliteUserRepo) MarkAllRead(ctx context.Context, userID int) error {
+	return MarkAllRead(ctx, r.db, userID)
+}
+
+func (r sqliteUserRepo) GetReadState(ctx context.Context, userID int) (string, error) {
+	return GetReadState(ctx, r.db, userID)
+}
+
+func (r sqliteUserRepo) GetViewHistory(ctx context.Context, userID, limit int) ([]models.HistoryEntry, error) {
+	return GetViewHistory(ctx, r.db, userID, limit)
+}
+
+func (r sqliteUserRepo) HistoryEnabled(ctx context.Context, userID int) (bool, error) {
+	return HistoryEnabled(ctx, r.db, userID)
+}
+
+func (r sqliteUserRepo) SetHistoryEnabled(ctx context.Context, userID int, enabled bool) error {
+	return SetHistoryEnabled(ctx, r.db, userID, enabled)
+}
+
+func (r sqliteUserRepo) PurgeViewHistory(ctx context.Context, retention time.Duration) (int64, error) {
+	return PurgeViewHistory(ctx, r.db, retention)
+}
+
+func (r sqliteUserRepo) GetSessionData(ctx context.Context, sessionID string) (int, string, time.Time, error) {
+	return GetSessionData(ctx, r.db, sessionID)
+}
+
+func (r sqliteUserRepo) CreateSession(ctx context.Context, sessionID string, userID int, role string, expiry time.Time) error {
+	return CreateSession(ctx, r.db, sessionID, userID, role, expiry)
+}
+
+func (r sqliteUserRepo) DeleteSession(ctx context.Context, sessionID string) error {
+	return DeleteSession(ctx, r.db, sessionID)
+}
+
+func (r sqliteUserRepo) DeleteExpiredSession(ctx context.Context, sessionID string) error {
+	return DeleteExpiredSession(ctx, r.db, sessionID)
+}
+
+func (r sqliteUserRepo) DeleteUserSessions(ctx context.Context, userID int) error {
+	return DeleteUserSessions(ctx, r.db, userID)
+}
+
+func (r sqliteUserRepo) SetUserRole(ctx context.Context, userID int, role string) error {
+	return SetUserRole(ctx, r.db, userID, role)
+}
+
+func (r sqliteUserRepo) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	return PurgeExpiredSessions(ctx, r.db)
+}
+
+// sqlitePostRepo реализует PostRepo поверх функций пакета для SQLite.
+type sqlitePostRepo struct {
+	db *sql.DB
+}
+
+func (r sqlitePostRepo) GetPosts(ctx context.Context, userID int, q FeedQuery) ([]models.PostData, error) {
+	return GetPosts(ctx, r.db, userID, q)
+}
+
+func (r sqlitePostRepo) GetFeedPage(ctx context.Context, userID int, q FeedQuery, limit int, cursor string) ([]models.PostData, string, error) {
+	return GetFeedPage(ctx, r.db, userID, q, limit, cursor)
+}
+
+func (r sqlitePostRepo) SearchPosts(ctx context.Context, userID int, f models.SearchFilters, limit int, cursor string) ([]models.PostData, string, error) {
+	return SearchPosts(ctx, r.db, userID, f, limit, cursor)
+}
+
+func (r sqlitePostRepo) GetPostByID(ctx context.Context, postID, currentUserID int) (models.PostData, error) {
+	return GetPostByID(ctx, r.db, postID, currentUserID)
+}
+
+func (r sqlitePostRepo) GetPostByIDAndUserID(ctx context.Context, postID int, userID int) (models.PostData, error) {
+	return GetPostByIDAndUserID(ctx, r.db, postID, userID)
+}
+
+func (r sqlitePostRepo) GetPostOwnerID(ctx context.Context, postID int) (int, error) {
+	return GetPostOwnerID(ctx, r.db, postID)
+}
+
+func (r sqlitePostRepo) GetUserPosts(ctx context.Context, userID, currentUserID int) ([]models.PostData, error) {
+	return GetUserPosts(ctx, r.db, userID, currentUserID)
+}
+
+func (r sqlitePostRepo) GetPostSummaries(ctx context.Context, filter, category string, authorID, excerptLen, limit, offset int) ([]models.PostSummary, error) {
+	return GetPostSummaries(ctx, r.db, filter, category, authorID, excerptLen, limit, offset)
+}
+
+func (r sqlitePostRepo) GetPostSummariesAfter(ctx context.Context, filter, category string, authorID, excerptLen, limit int, cursor string) ([]models.PostSummary, string, error) {
+	return GetPostSummariesAfter(ctx, r.db, filter, category, authorID, excerptLen, limit, cursor)
+}
+
+func (r sqlitePostRepo) CreatePost(ctx context.Context, userID, boardID int, title, content, imageURL string, createdAt time.Time) (int64, error) {
+	return CreatePost(ctx, r.db, userID, boardID, title, content, imageURL, createdAt)
+}
+
+func (r sqlitePostRepo) PublishPost(ctx context.Context, p NewPost) (int64, error) {
+	return PublishPost(ctx, r.db, p)
+}
+
+func (r sqlitePostRepo) UpdatePost(ctx context.Context, postID int, title, content, imageURL string) error {
+	return UpdatePost(ctx, r.db, postID, title, content, imageURL)
+}
+
+func (r sqlitePostRepo) SetPostMembersOnly(ctx context.Context, postID int, membersOnly bool) error {
+	return SetPostMembersOnly(ctx, r.db, postID, membersOnly)
+}
+
+func (r sqlitePostRepo) SetPostCommentPolicy(ctx context.Context, postID int, policy string) error {
+	return SetPostCommentPolicy(ctx, r.db, postID, policy)
+}
+
+func (r sqlitePostRepo) DeleteUserPosts(ctx context.Context, userID int, postIDs []int) error {
+	return DeleteUserPosts(ctx, r.db, userID, postIDs)
+}
+
+func (r sqlitePostRepo) SetUserPostsUnpublished(ctx context.Context, userID int, postIDs []int, unpublished bool) error {
+	return SetUserPostsUnpublished(ctx, r.db, userID, postIDs, unpublished)
+}
+
+func (r sqlitePostRepo) SetUserPostsCategories(ctx context.Context, userID int, postIDs, categoryIDs []int) error {
+	return SetUserPostsCategories(ctx, r.db, userID, postIDs, categoryIDs)
+}
+
+func (r sqlitePostRepo) ListManagedPosts(ctx context.Context, userID int) ([]models.PostData, error) {
+	return ListManagedPosts(ctx, r.db, userID)
+}
+
+func (r sqlitePostRepo) DeletePost(ctx context.Context, postID int) error {
+	return DeletePost(ctx, r.db, postID)
+}
+
+func (r sqlitePostRepo) PurgeDeletedContent(ctx context.Context, retention time.Duration) (int64, int64, error) {
+	return PurgeDeletedContent(ctx, r.db, retention)
+}
+
+func (r sqlitePostRepo) GetPostCategories(ctx context.Context, postID int) ([]models.Category, error) {
+	return GetPostCategories(ctx, r.db, postID)
+}
+
+func (r sqlitePostRepo) GetPostCategoriesByPostIDs(ctx context.Context, postIDs []int) (map[int][]models.Category, error) {
+	return GetPostCategoriesByPostIDs(ctx, r.db, postIDs)
+}
+
+func (r sqlitePostRepo) GetCategoryIDByName(ctx context.Context, catName string) (int, error) {
+	return GetCategoryIDByName(ctx, r.db, catName)
+}
+
+func (r sqlitePostRepo) AddPostCategory(ctx context.Context, postID int64, catID int) error {
+	return AddPostCategory(ctx, r.db, postID, catID)
+}
+
+func (r sqlitePostRepo) DeletePostCategories(ctx context.Context, postID int) error {
+	return DeletePostCategories(ctx, r.db, postID)
+}
+
+func (r sqlitePostRepo) GetFeedVersion(ctx context.Context) (ContentVersion, error) {
+	return GetFeedVersion(ctx, r.db)
+}
+
+func (r sqlitePostRepo) GetPostVersion(ctx context.Context, postID int) (ContentVersion, error) {
+	return GetPostVersion(ctx, r.db, postID)
+}
+
+// sqliteCommentRepo реализует CommentRepo поверх функций пакета для SQLite.
+type sqliteCommentRepo struct {
+	db *sql.DB
+}
+
+func (r sqliteCommentRepo) CreateComment(ctx context.Context, postID int, userID int, content, createdAt string) (int64, error) {
+	return CreateComment(ctx, r.db, postID, userID, content, createdAt)
+}
+
+func (r sqliteCommentRepo) GetCommentsByPostID(ctx context.Context, userID, postID int) ([]models.CommentData, error) {
+	return GetCommentsByPostID(ctx, r.db, userID, postID)
+}
+
+func (r sqliteCommentRepo) GetCommentsByPostIDWithUserVote(ctx context.Context, currentUserID, postID int) ([]models.CommentData, error) {
+	return GetCommentsByPostIDWithUserVote(ctx, r.db, currentUserID, postID)
+}
+
+func (r sqliteCommentRepo) GetCommentsByPostIDs(ctx context.Context, currentUserID int, postIDs []int) (map[int][]models.CommentData, error) {
+	return GetCommentsByPostIDs(ctx, r.db, currentUserID, postIDs)
+}
+
+func (r sqliteCommentRepo) GetLatestCommentsByPostIDs(ctx context.Context, postIDs []int, authorID, snippetLen int) (map[int]models.CommentData, error) {
+	return GetLatestCommentsByPostIDs(ctx, r.db, postIDs, authorID, snippetLen)
+}
+
+func (r sqliteCommentRepo) GetCommentCursor(ctx context.Context, postID, commentID int) (string, error) {
+	return GetCommentCursor(ctx, r.db, postID, commentID)
+}
+
+func (r sqliteCommentRepo) GetCommentsPage(ctx context.Context, currentUserID, postID, limit int, cursor string) ([]models.CommentData, string, error) {
+	return GetCommentsPage(ctx, r.db, currentUserID, postID, limit, cursor)
+}
+
+func (r sqliteCommentRepo) GetCommentOwnerID(ctx context.Context, commentID int) (int, error) {
+	return GetCommentOwnerID(ctx, r.db, commentID)
+}
+
+func (r sqliteCommentRepo) DeleteComment(ctx context.Context, commentID int) error {
+	return DeleteComment(ctx, r.db, commentID)
+}
+
+func (r sqliteCommentRepo) DeleteUserComments(ctx context.Context, userID int, commentIDs []int) error {
+	return DeleteUserComments(ctx, r.db, userID, commentIDs)
+}
+
+func (r sqliteCommentRepo) ListUserComments(ctx context.Context, userID, limit int) ([]models.CommentData, error) {
+	return ListUserComments(ctx, r.db, userID, limit)
+}
+
+func (r sqliteCommentRepo) DeletePostComments(ctx context.Context, postID int) error {
+	return DeletePostComments(ctx, r.db, postID)
+}
+
+// sqliteVoteRepo реализует VoteRepo поверх функций пакета для SQLite.
+type sqliteVoteRepo struct {
+	db *sql.DB
+}
+
+func (r sqliteVoteRepo) GetUserPostVote(ctx context.Context, userID, postID int) (int64, bool, error) {
+	return GetUserPostVote(ctx, r.db, userID, postID)
+}
+
+func (r sqliteVoteRepo) SetPostLike(ctx context.Context, userID, postID int) error {
+	return SetPostLike(ctx, r.db, userID, postID)
+}
+
+func (r sqliteVoteRepo) SetPostDislike(ctx context.Context, userID, postID int) error {
+	return SetPostDislike(ctx, r.db, userID, postID)
+}
+
+func (r sqliteVoteRepo) RemovePostVote(ctx context.Context, userID, postID int) error {
+	return RemovePostVote(ctx, r.db, userID, postID)
+}
+
+func (r sqliteVoteRepo) GetPostVoteStats(ctx context.Context, userID, postID int) (int, int, int64, bool, error) {
+	return GetPostVoteStats(ctx, r.db, userID, postID)
+}
+
+func (r sqliteVoteRepo) DeletePostVotes(ctx context.Context, postID int) error {
+	return DeletePostVotes(ctx, r.db, postID)
+}
+
+func (r sqliteVoteRepo) GetUserCommentVote(ctx context.Context, userID, commentID int) (int64, bool, error) {
+	return GetUserCommentVote(ctx, r.db, userID, commentID)
+}
+
+func (r sqliteVoteRepo) SetCommentLike(ctx context.Context, userID, commentID int) error {
+	return SetCommentLike(ctx, r.db, userID, commentID)
+}
+
+func (r sqliteVoteRepo) SetCommentDislike(ctx context.Context, userID, commentID int) error {
+	return SetCommentDislike(ctx, r.db, userID, commentID)
+}
+
+func (r sqliteVoteRepo) RemoveCommentVote(ctx context.Context, userID, commentID int) error {
+	return RemoveCommentVote(ctx, r.db, userID, commentID)
+}
+
+func (r sqliteVoteRepo) GetCommentVoteStats(ctx context.Context, userID, commentID int) (int, int, int64, bool, error) {
+	return GetCommentVoteStats(ctx, r.db, userID, commentID)
+}
+
+func (r sqliteVoteRepo) DeleteCommentVotes(ctx context.Context, commentID int) error {
+	return DeleteCommentVotes(ctx, r.db, commentID)
+}
+
+func (r sqliteVoteRepo) CountRecentVotes(ctx context.Context, userID int, since time.Time) (int, error) {
+	return CountRecentVotes(ctx, r.db, userID, since)
+}
+
+// sqliteBoardRepo реализует BoardRepo поверх функций пакета; запросы совместимы и с MySQL.
+type sqliteBoardRepo struct {
+	db *sql.DB
+}
+
+func (r sqliteBoardRepo) ListBoards(ctx context.Context) ([]models.Board, error) {
+	return ListBoards(ctx, r.db)
+}
+
+func (r sqliteBoardRepo) GetBoardBySlug(ctx context.Context, slug string) (models.Board, error) {
+	return GetBoardBySlug(ctx, r.db, slug)
+}
+
+func (r sqliteBoardRepo) IsBoardModerator(ctx context.Context, userID, boardID int) (bool, error) {
+	return IsBoardModerator(ctx, r.db, userID, boardID)
+}
+
+func (r sqliteBoardRepo) ModeratesPost(ctx context.Context, userID, postID int) (bool, error) {
+	return ModeratesPost(ctx, r.db, userID, postID)
+}
+
+func (r sqliteBoardRepo) ModeratesComment(ctx context.Context, userID, commentID int) (bool, error) {
+	return ModeratesComment(ctx, r.db, userID, commentID)
+}
+
+// sqliteSettingsRepo реализует SettingsRepo поверх функций пакета; запросы совместимы и с MySQL.
+type sqliteSettingsRepo struct {
+	db *sql.DB
+}
+
+func (r sqliteSettingsRepo) GetSetting(ctx context.Context, name string) (string, error) {
+	return GetSetting(ctx, r.db, name)
+}
+
+func (r sqliteSettingsRepo) SetSetting(ctx context.Context, name, value string) error {
+	return SetSetting(ctx, r.db, name, value)
+}
+
+func (r sqliteSettingsRepo) GetSite(ctx context.Context) (models.Site, error) {
+	return GetSite(ctx, r.db)
+}
+
+// sqliteCategoryRepo реализует CategoryRepo поверх функций пакета; запросы совместимы и с MySQL.
+type sqliteCategoryRepo struct {
+	db *sql.DB
+}
+
+func (r sqliteCategoryRepo) ListCategories(ctx context.Context) ([]models.Category, error) {
+	return ListCategories(ctx, r.db)
+}
+
+func (r sqliteCategoryRepo) UpdateCategoryStyle(ctx context.Context, name, icon, color string) error {
+	return UpdateCategoryStyle(ctx, r.db, name, icon, color)
+}
+
+// sqliteSeriesRepo реализует SeriesRepo поверх функций пакета; запросы совместимы и с MySQL.
+type sqliteSeriesRepo struct {
+	db *sql.DB
+}
+
+func (r sqliteSeriesRepo) CreateSeries(ctx context.Context, userID int, title string) (int64, error) {
+	return CreateSeries(ctx, r.db, userID, title)
+}
+
+func (r sqliteSeriesRepo) ListUserSeries(ctx context.Context, userID int) ([]models.Series, error) {
+	return ListUserSeries(ctx, r.db, userID)
+}
+
+func (r sqliteSeriesRepo) GetSeries(ctx context.Context, seriesID, viewerID int) (models.Series, error) {
+	return GetSeries(ctx, r.db, seriesID, viewerID)
+}
+
+func (r sqliteSeriesRepo) GetPostSeries(ctx context.Context, postID, viewerID int) (models.SeriesNav, error) {
+	return GetPostSeries(ctx, r.db, postID, viewerID)
+}
+
+func (r sqliteSeriesRepo) SetPostSeries(ctx context.Context, postID, seriesID int) error {
+	return SetPostSeries(ctx, r.db, postID, seriesID)
+}
+
+func (r sqliteSeriesRepo) MoveSeriesPart(ctx context.Context, seriesID, postID int, up bool) error {
+	return MoveSeriesPart(ctx, r.db, seriesID, postID, up)
+}
+
+// sqliteCollectionRepo реализует CollectionRepo поверх функций пакета; запросы совместимы и с MySQL.
+// Подборки видны только владельцу и по ссылке, поэтому их запись не сбрасывает кэш выборок Store.
+type sqliteCollectionRepo struct {
+	db *sql.DB
+}
+
+func (r sqliteCollectionRepo) CreateCollection(ctx context.Context, userID int, name string) (int64, error) {
+	return CreateCollection(ctx, r.db, userID, name)
+}
+
+func (r sqliteCollectionRepo) ListUserCollections(ctx context.Context, userID, postID int) ([]models.Collection, error) {
+	return ListUserCollections(ctx, r.db, userID, postID)
+}
+
+func (r sqliteCollectionRepo) GetCollection(ctx context.Context, collectionID int) (models.Collection, error) {
+	return GetCollection(ctx, r.db, collectionID)
+}
+
+func (r sqliteCollectionRepo) GetSharedCollection(ctx context.Context, token string) (models.Collection, error) {
+	return GetSharedCollection(ctx, r.db, token)
+}
+
+func (r sqliteCollectionRepo) AddCollectionItem(ctx context.Context, collectionID, postID int) error {
+	return AddCollectionItem(ctx, r.db, collectionID, postID)
+}
+
+func (r sqliteCollectionRepo) RemoveCollectionItem(ctx context.Context, collectionID, postID int) error {
+	return RemoveCollectionItem(ctx, r.db, collectionID, postID)
+}
+
+func (r sqliteCollectionRepo) SetCollectionShared(ctx context.Context, collectionID int, shared bool) error {
+	return SetCollectionShared(ctx, r.db, collectionID, shared)
+}
+
+func (r sqliteCollectionRepo) DeleteCollection(ctx context.Context, collectionID int) error {
+	return DeleteCollection(ctx, r.db, collectionID)
+}
@@ -0,0 +1,441 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"forum/models"
+)
+
+// searchDate — формат дат в фильтрах before: и after:.
+const searchDate = "2006-01-02"
+
+// maxSearchTerms ограничивает число слов поиска: без полнотекстового индекса каждое добавляет к запросу два сравнения LIKE.
+const maxSearchTerms = 10
+
+// maxSearchTokens ограничивает число слов, операторов и скобок в тексте запроса, а с ним и глубину разбора.
+const maxSearchTokens = 64
+
+// ParseSearchQuery разбирает строку поиска. Фильтры записываются как ключ:значение, значение с пробелами
+// берётся в кавычки: author:имя, category:имя (tag: — то же самое, категории служат метками постов),
+// after:ГГГГ-ММ-ДД, before:ГГГГ-ММ-ДД, min_score:число. Остальные слова и фразы в кавычках ищутся
+// в заголовке и тексте поста; слова с двоеточием и неизвестным ключом (например, адреса) тоже считаются текстом.
+// Текст может содержать операторы AND, OR, NOT, скобки и слова с * на конце (см. parseSearchExpr).
+// Повторный author:, after:, before: или min_score: заменяет прежнее значение, category: добавляет категорию.
+func ParseSearchQuery(q string) models.SearchFilters {
+	var f models.SearchFilters
+	var text []string
+	for _, token := range splitSearchQuery(q) {
+		key, value, ok := strings.Cut(token, ":")
+		if ok {
+			value = strings.Trim(value, `"`)
+		}
+		switch key = strings.ToLower(key); {
+		case !ok || value == "":
+			ok = false
+		case key == "author":
+			f.Author = value
+		case key == "category" || key == "tag":
+			value = strings.ToLower(value)
+			if !f.HasCategory(value) {
+				f.Categories = append(f.Categories, value)
+			}
+		case key == "after" || key == "before":
+			if _, err := time.Parse(searchDate, value); err != nil {
+				f.Invalid = append(f.Invalid, token)
+			} else if key == "after" {
+				f.After = value
+			} else {
+				f.Before = value
+			}
+		case key == "min_score":
+			if _, err := strconv.Atoi(value); err != nil {
+				f.Invalid = append(f.Invalid, token)
+			} else {
+				f.MinScore = value
+			}
+		default:
+			ok = false
+		}
+		if !ok {
+			text = append(text, token)
+		}
+	}
+	f.Text = strings.Join(text, " ")
+	f.Terms = parseSearchExpr(f.Text).terms(nil)
+	return f
+}
+
+// splitSearchQuery делит строку поиска на слова по пробелам; пробелы внутри кавычек слово не разрывают.
+func splitSearchQuery(q string) []string {
+	var tokens []string
+	var b strings.Builder
+	quoted := false
+	for _, r := range q {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			b.WriteRune(r)
+		case !quoted && (r == ' ' || r == '\t' || r == '\n' || r == '\r'):
+			if b.Len() > 0 {
+				tokens = append(tokens, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() > 0 {
+		tokens = append(tokens, b.String())
+	}
+	return tokens
+}
+
+// SearchPosts возвращает страницу постов, подходящих под фильтры f, новые первыми, и курсор следующей страницы
+// (см. GetFeedPage). Посты с низким рейтингом не скрываются: их ищут явно, а в карточке они отмечены Hidden.
+// Если есть полнотекстовый индекс posts_fts (см. ensureSearchIndex), текст ищется по нему, иначе — через LIKE.
+func SearchPosts(ctx context.Context, db *sql.DB, userID int, f models.SearchFilters, limit int, cursor string) ([]models.PostData, string, error) {
+	fts, err := searchIndexReady(ctx, db)
+	if err != nil {
+		return nil, "", err
+	}
+	return searchPosts(ctx, db, userID, f, limit, cursor, fts)
+}
+
+// searchPosts — SearchPosts с явным выбором способа поиска текста: fts — по индексу posts_fts, иначе через LIKE.
+// Без индекса слова ищутся как подстроки без учёта регистра латинских букв.
+func searchPosts(ctx context.Context, db *sql.DB, userID int, f models.SearchFilters, limit int, cursor string, fts bool) ([]models.PostData, string, error) {
+	query, args, err := feedSelect(userID, FeedQuery{Filter: "new", ShowHidden: true, Author: f.Author})
+	if err != nil {
+		return nil, "", err
+	}
+	if expr := parseSearchExpr(f.Text); expr != nil {
+		cond, condArgs := expr.condition(fts)
+		query += " AND " + cond
+		args = append(args, condArgs...)
+	}
+	for _, category := range f.Categories {
+		query += ` AND EXISTS (SELECT 1 FROM post_categories pc JOIN categories c ON pc.category_id = c.id
+                   WHERE pc.post_id = p.id AND c.name = ?)`
+		args = append(args, category)
+	}
+	// created_at сравнивается как строка, как и в курсоре ленты: дата ГГГГ-ММ-ДД — начало этой строки.
+	if f.After != "" {
+		query += " AND CAST(p.created_at AS CHAR) >= ?"
+		args = append(args, f.After)
+	}
+	if f.Before != "" {
+		query += " AND CAST(p.created_at AS CHAR) < ?"
+		args = append(args, f.Before)
+	}
+	if f.MinScore != "" {
+		minScore, _ := strconv.Atoi(f.MinScore)
+		query += " AND p.likes - p.dislikes >= ?"
+		args = append(args, minScore)
+	}
+	return queryFeedPage(ctx, db, query, args, "new", limit, cursor)
+}
+
+// searchNode — узел разобранного текста запроса: слово или фраза (op пуст) либо оператор AND, OR или NOT над children.
+type searchNode struct {
+	op       string
+	term     string
+	prefix   bool // слово с * на конце: ищутся слова, начинающиеся с term
+	children []*searchNode
+}
+
+// parseSearchExpr разбирает текст запроса в выражение. Слова подряд соединяются через AND; операторы AND, OR
+// и NOT пишутся заглавными буквами (строчные and и or — обычные слова), NOT связывает сильнее AND, AND — сильнее OR.
+// Фраза в кавычках ищется целиком, * на конце слова или фразы ищет по началу слова, скобки группируют условия.
+// Разбор не бывает ошибочным: лишние операторы и скобки отбрасываются, слова без букв и цифр пропускаются,
+// слова сверх maxSearchTerms не учитываются. Возвращает nil, если искать нечего.
+func parseSearchExpr(text string) *searchNode {
+	p := &searchParser{tokens: lexSearchExpr(text)}
+	var n *searchNode
+	for p.pos < len(p.tokens) {
+		if p.peek() == ")" {
+			p.pos++
+			continue
+		}
+		n = joinSearch("AND", n, p.parseOr())
+	}
+	return n
+}
+
+// lexSearchExpr делит текст запроса на слова, фразы в кавычках (вместе с кавычками и * после них) и скобки.
+func lexSearchExpr(text string) []string {
+	var tokens []string
+	var b strings.Builder
+	flush := func() {
+		if b.Len() > 0 {
+			tokens = append(tokens, b.String())
+			b.Reset()
+		}
+	}
+	quoted := false
+	for _, r := range text {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			b.WriteRune(r)
+		case quoted:
+			b.WriteRune(r)
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	flush()
+	if len(tokens) > maxSearchTokens {
+		tokens = tokens[:maxSearchTokens]
+	}
+	return tokens
+}
+
+// searchParser — разбор текста запроса рекурсивным спуском.
+type searchParser struct {
+	tokens []string
+	pos    int
+	terms  int
+}
+
+func (p *searchParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// parseOr разбирает условия, разделённые OR.
+func (p *searchParser) parseOr() *searchNode {
+	n := p.parseAnd()
+	for p.peek() == "OR" {
+		p.pos++
+		n = joinSearch("OR", n, p.parseAnd())
+	}
+	return n
+}
+
+// parseAnd разбирает условия, записанные подряд или через AND, до OR, закрывающей скобки или конца запроса.
+func (p *searchParser) parseAnd() *searchNode {
+	var n *searchNode
+	for {
+		switch p.peek() {
+		case "", ")", "OR":
+			return n
+		case "AND":
+			p.pos++
+		default:
+			n = joinSearch("AND", n, p.parseUnary())
+		}
+	}
+}
+
+// parseUnary разбирает NOT, группу в скобках или отдельное слово.
+func (p *searchParser) parseUnary() *searchNode {
+	token := p.peek()
+	p.pos++
+	switch token {
+	case "NOT":
+		switch p.peek() {
+		case "", ")", "AND", "OR":
+			return nil
+		}
+		if n := p.parseUnary(); n != nil {
+			return &searchNode{op: "NOT", children: []*searchNode{n}}
+		}
+		return nil
+	case "(":
+		n := p.parseOr()
+		if p.peek() == ")" {
+			p.pos++
+		}
+		return n
+	}
+	prefix := strings.HasSuffix(token, "*")
+	term := strings.TrimSpace(strings.Trim(strings.TrimRight(token, "*"), `"`))
+	if !strings.ContainsFunc(term, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) || p.terms >= maxSearchTerms {
+		return nil
+	}
+	p.terms++
+	return &searchNode{term: term, prefix: prefix}
+}
+
+// joinSearch соединяет a и b оператором op, пропуская пустые части и не вкладывая одинаковые операторы друг в друга.
+func joinSearch(op string, a, b *searchNode) *searchNode {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.op == op:
+		a.children = append(a.children, b)
+		return a
+	}
+	return &searchNode{op: op, children: []*searchNode{a, b}}
+}
+
+// terms дописывает к dst слова и фразы выражения в порядке записи.
+func (n *searchNode) terms(dst []string) []string {
+	if n == nil {
+		return dst
+	}
+	if n.op == "" {
+		return append(dst, n.term)
+	}
+	for _, c := range n.children {
+		dst = c.terms(dst)
+	}
+	return dst
+}
+
+// condition переводит выражение в условие WHERE и его аргументы. Пользовательский текст попадает в запрос
+// только аргументами. С fts части выражения, которые можно записать одним запросом FTS5 (см. match),
+// проверяются по индексу posts_fts; остальное — например, запрос из одного NOT — соединяется операторами SQL.
+func (n *searchNode) condition(fts bool) (string, []any) {
+	if fts {
+		if m, ok := n.match(); ok {
+			return "p.id IN (SELECT rowid FROM posts_fts WHERE posts_fts MATCH ?)", []any{m}
+		}
+	}
+	if n.op == "" {
+		pattern := "%" + escapeLike(strings.ToLower(n.term)) + "%"
+		return "(LOWER(p.title) LIKE ? ESCAPE '!' OR LOWER(p.content) LIKE ? ESCAPE '!')", []any{pattern, pattern}
+	}
+	parts := make([]string, 0, len(n.children))
+	var args []any
+	for _, c := range n.children {
+		cond, condArgs := c.condition(fts)
+		parts = append(parts, cond)
+		args = append(args, condArgs...)
+	}
+	if n.op == "NOT" {
+		return "NOT (" + parts[0] + ")", args
+	}
+	return "(" + strings.Join(parts, " "+n.op+" ") + ")", args
+}
+
+// match записывает выражение на языке запросов FTS5. Каждое слово и фраза берутся в кавычки с удвоением
+// кавычек внутри, поэтому операторы, имена колонок и прочий синтаксис FTS5 в словах пользователя не действуют.
+// В FTS5 NOT — двуместный оператор, поэтому ok ложно для выражения, где NOT не с чем вычесть.
+func (n *searchNode) match() (string, bool) {
+	switch n.op {
+	case "":
+		m := `"` + strings.ReplaceAll(n.term, `"`, `""`) + `"`
+		if n.prefix {
+			m += "*"
+		}
+		return m, true
+	case "OR":
+		parts := make([]string, 0, len(n.children))
+		for _, c := range n.children {
+			m, ok := c.match()
+			if !ok {
+				return "", false
+			}
+			parts = append(parts, "("+m+")")
+		}
+		return strings.Join(parts, " OR "), true
+	case "AND":
+		var include, exclude []string
+		for _, c := range n.children {
+			target := &include
+			if c.op == "NOT" {
+				c, target = c.children[0], &exclude
+			}
+			m, ok := c.match()
+			if !ok {
+				return "", false
+			}
+			*target = append(*target, "("+m+")")
+		}
+		if len(include) == 0 {
+			return "", false
+		}
+		m := "(" + strings.Join(include, " AND ") + ")"
+		for _, e := range exclude {
+			m += " NOT " + e
+		}
+		return m, true
+	}
+	return "", false
+}
+
+// searchIndexTriggers — триггеры, которые поддерживают индекс posts_fts в соответствии с таблицей posts.
+var searchIndexTriggers = []string{"posts_fts_ai", "posts_fts_ad", "posts_fts_au"}
+
+// ensureSearchIndex создаёт полнотекстовый индекс постов posts_fts (FTS5), если SQLite собран с FTS5
+// (драйвер собирается с ним по тегу sqlite_fts5). Индекс не входит в миграции: схема должна применяться
+// и без FTS5, а тогда поиск работает через LIKE. Без FTS5 удаляются триггеры индекса, оставшиеся от сборки с ним,
+// иначе запись постов завершалась бы ошибкой; сама таблица остаётся, а когда триггеры создаются заново,
+// индекс перестраивается по таблице posts.
+func ensureSearchIndex(db *sql.DB) error {
+	var enabled bool
+	if err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled); err != nil {
+		return err
+	}
+	if !enabled {
+		for _, name := range searchIndexTriggers {
+			if _, err := db.Exec("DROP TRIGGER IF EXISTS " + name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	ready, err := searchIndexReady(context.Background(), db)
+	if err != nil || ready {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = execAll(tx,
+		`CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts5(
+			title, content, content='posts', content_rowid='id', tokenize='unicode61 remove_diacritics 2'
+		);`,
+		`CREATE TRIGGER IF NOT EXISTS posts_fts_ai AFTER INSERT ON posts BEGIN
+			INSERT INTO posts_fts(rowid, title, content) VALUES (new.id, new.title, new.content);
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS posts_fts_ad AFTER DELETE ON posts BEGIN
+			INSERT INTO posts_fts(posts_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS posts_fts_au AFTER UPDATE OF title, content ON posts BEGIN
+			INSERT INTO posts_fts(posts_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+			INSERT INTO posts_fts(rowid, title, content) VALUES (new.id, new.title, new.content);
+		END;`,
+		`INSERT INTO posts_fts(posts_fts) VALUES ('rebuild');`,
+	)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("Built full-text search index.")
+	return nil
+}
+
+// searchIndexReady сообщает, поддерживается ли индекс posts_fts триггерами, то есть можно ли по нему искать.
+func searchIndexReady(ctx context.Context, db *sql.DB) (bool, error) {
+	var ready bool
+	err := cachedQueryRow(ctx, db, "SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'trigger' AND name = ?)",
+		searchIndexTriggers[len(searchIndexTriggers)-1]).Scan(&ready)
+	return ready, err
+}
+
+// escapeLike экранирует символы шаблона LIKE знаком '!', одинаково понятным SQLite и MySQL.
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestParseSearchQuery проверяет разбор фильтров и текста строки поиска.
+func TestParseSearchQuery(t *testing.T) {
+	f := ParseSearchQuery(`aurora author:"Alice" tag:News category:news after:2026-01-01 before:2026-13-01 min_score:x min_score:5 "northern lights" https://example.com`)
+	if f.Author != "Alice" || fmt.Sprint(f.Categories) != "[news]" || f.After != "2026-01-01" || f.Before != "" || f.MinScore != "5" {
+		t.Errorf("filters = %+v", f)
+	}
+	if fmt.Sprint(f.Invalid) != "[before:2026-13-01 min_score:x]" {
+		t.Errorf("Invalid = %q", f.Invalid)
+	}
+	if fmt.Sprintf("%q", f.Terms) != `["aurora" "northern lights" "https://example.com"]` {
+		t.Errorf("Terms = %q", f.Terms)
+	}
+	if f.Text != `aurora "northern lights" https://example.com` {
+		t.Errorf("Text = %q", f.Text)
+	}
+	if !ParseSearchQuery("  ").Empty() || ParseSearchQuery("min_score:0").Empty() {
+		t.Error("Empty is wrong")
+	}
+}
+
+// TestSearchExpr проверяет разбор операторов и перевод выражения в запрос FTS5 с экранированием.
+func TestSearchExpr(t *testing.T) {
+	tests := []struct {
+		text, match string
+		ok          bool
+	}{
+		{`aurora lights`, `(("aurora") AND ("lights"))`, true},
+		{`aurora OR "northern lights"*`, `("aurora") OR ("northern lights"*)`, true},
+		{`a NOT (b OR c) d`, `(("a") AND ("d")) NOT (("b") OR ("c"))`, true},
+		{`title:x NEAR(y) "q""uote`, `(("title:x") AND ("NEAR") AND ("y") AND ("q""""uote"))`, true},
+		{`aurora AND OR ) NOT`, `"aurora"`, true},
+		{`NOT aurora`, ``, false},
+		{`and or !!! *`, `(("and") AND ("or"))`, true},
+	}
+	for _, tt := range tests {
+		m, ok := parseSearchExpr(tt.text).match()
+		if m != tt.match || ok != tt.ok {
+			t.Errorf("match(%q) = %q, %v; want %q, %v", tt.text, m, ok, tt.match, tt.ok)
+		}
+	}
+	if n := parseSearchExpr(`( ) AND NOT`); n != nil {
+		t.Errorf("parseSearchExpr without terms = %+v, want nil", n)
+	}
+
+	cond, args := parseSearchExpr(`NOT 100%`).condition(true)
+	if cond != "NOT (p.id IN (SELECT rowid FROM posts_fts WHERE posts_fts MATCH ?))" || fmt.Sprint(args) != `["100%"]` {
+		t.Errorf("condition(fts) = %q %q", cond, args)
+	}
+	cond, args = parseSearchExpr(`a_b OR c`).condition(false)
+	if !strings.HasPrefix(cond, "((LOWER(p.title) LIKE ?") || !strings.Contains(cond, " OR (LOWER") || fmt.Sprint(args) != "[%a!_b% %a!_b% %c% %c%]" {
+		t.Errorf("condition(like) = %q %q", cond, args)
+	}
+}
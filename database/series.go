@@ -0,0 +1,221 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"unicode/utf8"
+
+	"forum/models"
+)
+
+// MaxSeriesTitleLen — наибольшая длина названия серии в символах.
+const MaxSeriesTitleLen = 100
+
+// ValidSeriesTitle сообщает, подходит ли title (без пробелов по краям) для названия серии.
+func ValidSeriesTitle(title string) bool {
+	return title != "" && title == strings.TrimSpace(title) && utf8.RuneCountInString(title) <= MaxSeriesTitleLen
+}
+
+// CreateSeries создаёт пустую серию автора userID и возвращает её ID. Посты добавляются в неё через SetPostSeries.
+func CreateSeries(ctx context.Context, db *sql.DB, userID int, title string) (int64, error) {
+	res, err := db.ExecContext(ctx, "INSERT INTO series (user_id, title, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)", userID, title)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// ListUserSeries возвращает серии автора userID, новые первыми, без списков постов — для выбора серии в форме поста.
+func ListUserSeries(ctx context.Context, db *sql.DB, userID int) ([]models.Series, error) {
+	rows, err := db.QueryContext(ctx, `
+        SELECT id, user_id, title, created_at FROM series
+        WHERE user_id = ?
+        ORDER BY created_at DESC, id DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := []models.Series{}
+	for rows.Next() {
+		var s models.Series
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Title, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		series = append(series, s)
+	}
+	return series, rows.Err()
+}
+
+// GetSeries возвращает серию с именем автора и её постами по порядку; удалённые посты пропускаются,
+// а посты только для авторизованных — для анонимного посетителя (viewerID 0).
+// Если серии нет, возвращает sql.ErrNoRows.
+func GetSeries(ctx context.Context, db *sql.DB, seriesID, viewerID int) (models.Series, error) {
+	var s models.Series
+	err := db.QueryRowContext(ctx, `
+        SELECT s.id, s.user_id, u.username, s.title, s.created_at
+        FROM series s JOIN users u ON u.id = s.user_id
+        WHERE s.id = ?
+    `, seriesID).Scan(&s.ID, &s.UserID, &s.Username, &s.Title, &s.CreatedAt)
+	if err != nil {
+		return models.Series{}, err
+	}
+	if s.Parts, err = seriesParts(ctx, db, seriesID, viewerID); err != nil {
+		return models.Series{}, err
+	}
+	return s, nil
+}
+
+// seriesParts возвращает неудалённые посты серии по порядку; анонимному посетителю (viewerID 0) —
+// без постов только для авторизованных.
+func seriesParts(ctx context.Context, db *sql.DB, seriesID, viewerID int) ([]models.SeriesPart, error) {
+	rows, err := db.QueryContext(ctx, `
+        SELECT p.id, p.title, p.created_at FROM series_posts sp
+        JOIN posts p ON p.id = sp.post_id
+        WHERE sp.series_id = ? AND p.deleted_at IS NULL AND (? > 0 OR NOT p.members_only)
+        ORDER BY sp.position
+    `, seriesID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parts []models.SeriesPart
+	for rows.Next() {
+		var part models.SeriesPart
+		if err := rows.Scan(&part.PostID, &part.Title, &part.CreatedAt); err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+	return parts, rows.Err()
+}
+
+// GetPostSeries возвращает место поста postID в его серии: номер части, их число и соседние посты.
+// Части считаются так же, как в GetSeries для посетителя viewerID. Если пост не входит в серию, возвращает sql.ErrNoRows.
+func GetPostSeries(ctx context.Context, db *sql.DB, postID, viewerID int) (models.SeriesNav, error) {
+	var nav models.SeriesNav
+	err := db.QueryRowContext(ctx, `
+        SELECT s.id, s.title FROM series_posts sp JOIN series s ON s.id = sp.series_id
+        WHERE sp.post_id = ?
+    `, postID).Scan(&nav.SeriesID, &nav.Title)
+	if err != nil {
+		return models.SeriesNav{}, err
+	}
+	parts, err := seriesParts(ctx, db, nav.SeriesID, viewerID)
+	if err != nil {
+		return models.SeriesNav{}, err
+	}
+	nav.Total = len(parts)
+	for i := range parts {
+		if parts[i].PostID != postID {
+			continue
+		}
+		nav.Part = i + 1
+		if i > 0 {
+			nav.Prev = &parts[i-1]
+		}
+		if i+1 < len(parts) {
+			nav.Next = &parts[i+1]
+		}
+	}
+	return nav, nil
+}
+
+// SetPostSeries переносит пост postID в конец серии seriesID; seriesID 0 убирает пост из серии.
+// Серия, из которой ушёл последний пост, удаляется. Принадлежность серии автору поста проверяет вызывающий.
+func SetPostSeries(ctx context.Context, db *sql.DB, postID, seriesID int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current int
+	err = tx.QueryRowContext(ctx, "SELECT series_id FROM series_posts WHERE post_id = ?", postID).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if current == seriesID {
+		return nil
+	}
+	if current != 0 {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM series_posts WHERE post_id = ?", postID); err != nil {
+			return err
+		}
+		if err := touchSeries(ctx, tx, current); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM series WHERE id = ? AND NOT EXISTS (SELECT 1 FROM series_posts WHERE series_id = ?)",
+			current, current)
+		if err != nil {
+			return err
+		}
+	}
+	if seriesID != 0 {
+		if err := appendSeriesPart(ctx, tx, seriesID, postID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// appendSeriesPart добавляет пост postID последней частью серии seriesID.
+func appendSeriesPart(ctx context.Context, tx *sql.Tx, seriesID, postID int) error {
+	var position int
+	err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(position), 0) + 1 FROM series_posts WHERE series_id = ?", seriesID).Scan(&position)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO series_posts (post_id, series_id, position) VALUES (?, ?, ?)", postID, seriesID, position)
+	if err != nil {
+		return err
+	}
+	return touchSeries(ctx, tx, seriesID)
+}
+
+// MoveSeriesPart меняет пост postID местами с соседом по серии seriesID: предыдущим при up, иначе следующим.
+// Крайний пост остаётся на месте. Если поста нет в серии, возвращает sql.ErrNoRows.
+func MoveSeriesPart(ctx context.Context, db *sql.DB, seriesID, postID int, up bool) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var position int
+	err = tx.QueryRowContext(ctx, "SELECT position FROM series_posts WHERE series_id = ? AND post_id = ?", seriesID, postID).Scan(&position)
+	if err != nil {
+		return err
+	}
+	neighbor := "SELECT post_id, position FROM series_posts WHERE series_id = ? AND position > ? ORDER BY position LIMIT 1"
+	if up {
+		neighbor = "SELECT post_id, position FROM series_posts WHERE series_id = ? AND position < ? ORDER BY position DESC LIMIT 1"
+	}
+	var otherID, otherPosition int
+	err = tx.QueryRowContext(ctx, neighbor, seriesID, position).Scan(&otherID, &otherPosition)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE series_posts SET position = ? WHERE post_id = ?", otherPosition, postID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE series_posts SET position = ? WHERE post_id = ?", position, otherID); err != nil {
+		return err
+	}
+	if err := touchSeries(ctx, tx, seriesID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// touchSeries отмечает изменение серии: от updated_at зависит версия страниц её постов.
+func touchSeries(ctx context.Context, tx *sql.Tx, seriesID int) error {
+	_, err := tx.ExecContext(ctx, "UPDATE series SET updated_at = CURRENT_TIMESTAMP WHERE id = ?", seriesID)
+	return err
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/ranking"
+)
+
+// SettingHideScoreBelow — порог рейтинга (лайки минус дизлайки): посты с рейтингом ниже него не показываются
+// в общих лентах, но открываются по ссылке и в ленте с параметром hidden=1. Пустое значение выключает скрытие.
+const SettingHideScoreBelow = "hide_score_below"
+
+// SettingBestHalfLife — период полураспада рейтинга в ленте «Лучшие», в часах (см. ranking.Decay).
+// Пустое значение — лента упорядочена по рейтингу без затухания.
+const SettingBestHalfLife = "best_half_life"
+
+// SettingImageAllowDomains и SettingImageBlockDomains — домены, с которых можно и нельзя прикладывать
+// изображения к постам, по одному в строке (см. imagecheck.Policy). Пустой список разрешённых доменов
+// разрешает все, кроме запрещённых.
+const (
+	SettingImageAllowDomains = "image_allow_domains"
+	SettingImageBlockDomains = "image_block_domains"
+)
+
+// SettingEmailAllowDomains и SettingEmailBlockDomains — почтовые домены, с адресами на которых можно и нельзя
+// зарегистрироваться, по одному в строке; домен включает свои поддомены. Пустой список разрешённых доменов
+// разрешает все, кроме запрещённых.
+const (
+	SettingEmailAllowDomains = "email_allow_domains"
+	SettingEmailBlockDomains = "email_block_domains"
+)
+
+// SettingInviteOnly — регистрация только по приглашениям (см. RegisterInvitedUser): "1" — включена,
+// пустое значение — регистрация открыта для всех.
+const SettingInviteOnly = "invite_only"
+
+// SettingImageProxySecret — ключ подписи адресов прокси изображений, если он не задан в настройках сервера.
+// Создаётся при первом запуске с включённым прокси; общий для всех экземпляров, работающих с одной базой.
+const SettingImageProxySecret = "image_proxy_secret"
+
+// SettingMailReplySecret — ключ подписи адресов ответа на письма (см. пакет mailreply), если он не задан
+// в настройках сервера. Создаётся при первом запуске с включённым приёмом ответов.
+const SettingMailReplySecret = "mail_reply_secret"
+
+// SettingRegistrationFormSecret — ключ подписи меток времени формы регистрации (см. пакет botcheck), если он не задан
+// в настройках сервера. Создаётся при первом запуске.
+const SettingRegistrationFormSecret = "registration_form_secret"
+
+// SettingEmailVerifySecret — ключ подписи ссылок подтверждения почты (см. пакет emailverify), если он не задан
+// в настройках сервера. Создаётся при первом запуске с включённым подтверждением почты.
+const SettingEmailVerifySecret = "email_verify_secret"
+
+// hiddenPostCondition истинно для поста p, рейтинг которого ниже порога SettingHideScoreBelow.
+// Порог читается в том же запросе, поэтому лента не требует отдельного обращения к настройкам;
+// value + 0 переводит строку в число и в SQLite, и в MySQL.
+const hiddenPostCondition = `EXISTS (SELECT 1 FROM settings s WHERE s.name = '` + SettingHideScoreBelow + `'
+                   AND s.value <> '' AND p.likes - p.dislikes < s.value + 0)`
+
+// ParseScoreThreshold проверяет значение порога SettingHideScoreBelow: целое число или пустая строка (скрытие выключено).
+// Возвращает значение в том виде, в каком его нужно сохранить.
+func ParseScoreThreshold(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return "", false
+	}
+	return strconv.Itoa(n), true
+}
+
+// ParseBestHalfLife проверяет значение SettingBestHalfLife: целое число часов от 1 до 8760 или пустая строка
+// (затухание выключено). Возвращает значение в том виде, в каком его нужно сохранить.
+func ParseBestHalfLife(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > 8760 {
+		return "", false
+	}
+	return strconv.Itoa(n), true
+}
+
+// bestDecay возвращает затухание рейтинга ленты «Лучшие» из настройки SettingBestHalfLife.
+func bestDecay(ctx context.Context, db *sql.DB) (ranking.Decay, error) {
+	value, err := GetSetting(ctx, db, SettingBestHalfLife)
+	if err != nil {
+		return ranking.Decay{}, err
+	}
+	hours, _ := strconv.Atoi(value)
+	return ranking.Decay{HalfLife: time.Duration(hours) * time.Hour}, nil
+}
+
+// GetSetting возвращает значение настройки сайта name или пустую строку, если она не задана.
+func GetSetting(ctx context.Context, db *sql.DB, name string) (string, error) {
+	var value string
+	err := db.QueryRowContext(ctx, "SELECT value FROM settings WHERE name = ?", name).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+// SetSetting сохраняет значение настройки сайта name. Строка не удаляется и при пустом значении:
+// время её изменения входит в версию ленты (см. GetFeedVersion).
+func SetSetting(ctx context.Context, db *sql.DB, name, value string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM settings WHERE name = ?)", name).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		_, err = tx.ExecContext(ctx, "UPDATE settings SET value = ?, updated_at = CURRENT_TIMESTAMP WHERE name = ?", value, name)
+	} else {
+		_, err = tx.ExecContext(ctx, "INSERT INTO settings (name, value) VALUES (?, ?)", name, value)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
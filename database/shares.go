@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"forum/models"
+)
+
+// ShareChannelShortLink — способ «поделиться» для переходов по короткой ссылке /s/{code}.
+const ShareChannelShortLink = "short_link"
+
+// RecordPostShare засчитывает, что постом postID поделились способом channel в момент at. Переходы хранятся
+// суммами за день (UTC) в post_shares. Если поста нет или он удалён, возвращает sql.ErrNoRows.
+// Строка дня ищется в той же транзакции: ON CONFLICT (SQLite) и ON DUPLICATE KEY (MySQL) пишутся по-разному.
+func RecordPostShare(ctx context.Context, db *sql.DB, postID int, channel string, at time.Time) error {
+	day := at.UTC().Format(time.DateOnly)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE post_shares SET count = count + 1 WHERE post_id = ? AND day = ? AND channel = ?",
+		postID, day, channel)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		res, err = tx.ExecContext(ctx, `
+            INSERT INTO post_shares (post_id, day, channel, count)
+            SELECT id, ?, ?, 1 FROM posts WHERE id = ? AND deleted_at IS NULL
+        `, day, channel, postID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return sql.ErrNoRows
+		}
+	}
+	return tx.Commit()
+}
+
+// CountPostShares возвращает, сколько раз постом postID поделились всеми способами за всё время.
+func CountPostShares(ctx context.Context, db *sql.DB, postID int) (int, error) {
+	var count int
+	err := cachedQueryRow(ctx, db, "SELECT COALESCE(SUM(count), 0) FROM post_shares WHERE post_id = ?", postID).Scan(&count)
+	return count, err
+}
+
+// GetShareReport возвращает, сколько раз делились постами начиная с дня since (UTC): всего, по способам, по дням
+// и limit самых распространяемых неудалённых постов.
+func GetShareReport(ctx context.Context, db *sql.DB, since time.Time, limit int) (models.ShareReport, error) {
+	var report models.ShareReport
+	day := since.UTC().Format(time.DateOnly)
+
+	rows, err := db.QueryContext(ctx, `
+        SELECT channel, SUM(count) AS total FROM post_shares
+        WHERE day >= ?
+        GROUP BY channel
+        ORDER BY total DESC, channel
+    `, day)
+	if err != nil {
+		return report, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c models.ShareCount
+		if err := rows.Scan(&c.Channel, &c.Count); err != nil {
+			return report, err
+		}
+		report.Channels = append(report.Channels, c)
+		report.Total += c.Count
+	}
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	rows, err = db.QueryContext(ctx, `
+        SELECT day, SUM(count) FROM post_shares
+        WHERE day >= ?
+        GROUP BY day
+        ORDER BY day
+    `, day)
+	if err != nil {
+		return report, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d models.ShareDay
+		if err := rows.Scan(&d.Day, &d.Count); err != nil {
+			return report, err
+		}
+		report.Days = append(report.Days, d)
+	}
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	rows, err = db.QueryContext(ctx, `
+        SELECT p.id, p.title, SUM(s.count) AS total
+        FROM post_shares s JOIN posts p ON p.id = s.post_id
+        WHERE s.day >= ? AND p.deleted_at IS NULL
+        GROUP BY p.id, p.title
+        ORDER BY total DESC, p.id DESC
+        LIMIT ?
+    `, day, limit)
+	if err != nil {
+		return report, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.SharedPost
+		if err := rows.Scan(&p.PostID, &p.Title, &p.Count); err != nil {
+			return report, err
+		}
+		report.Posts = append(report.Posts, p)
+	}
+	return report, rows.Err()
+}
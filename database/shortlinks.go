@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+
+	"forum/models"
+)
+
+// shortCodeAlphabet — символы коротких кодов без похожих друг на друга 0/O, 1/l/I: ссылку печатают и вводят вручную.
+const shortCodeAlphabet = "23456789abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// shortCodeLength — длина короткого кода; 55^6 ≈ 2,8·10^10 вариантов, совпадения почти не случаются.
+const shortCodeLength = 6
+
+// newShortCode возвращает случайный короткий код.
+func newShortCode() (string, error) {
+	b := make([]byte, shortCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = shortCodeAlphabet[int(b[i])%len(shortCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+// EnsureShortLink возвращает короткую ссылку /s/{code} поста postID, создавая её при первом обращении.
+// У поста одна ссылка: код не меняется, а счётчик переходов накапливается. Если поста нет, возвращает sql.ErrNoRows.
+// Уникальность кода и поста проверяет база: при совпадении кода или одновременном создании вставка повторяется
+// с новым кодом или возвращается ссылка, созданная другим запросом.
+func EnsureShortLink(ctx context.Context, db *sql.DB, postID int) (models.ShortLink, error) {
+	var insertErr error
+	for attempt := 0; ; attempt++ {
+		link := models.ShortLink{PostID: postID}
+		err := db.QueryRowContext(ctx, "SELECT code, clicks FROM short_links WHERE post_id = ?", postID).Scan(&link.Code, &link.Clicks)
+		if err == nil {
+			return link, nil
+		}
+		if err != sql.ErrNoRows {
+			return models.ShortLink{}, err
+		}
+		if attempt > 0 && insertErr == nil {
+			// Вставка прошла без ошибки, но ничего не добавила: поста нет или он удалён.
+			return models.ShortLink{}, sql.ErrNoRows
+		}
+		if attempt == 3 {
+			return models.ShortLink{}, insertErr
+		}
+
+		code, err := newShortCode()
+		if err != nil {
+			return models.ShortLink{}, err
+		}
+		_, insertErr = db.ExecContext(ctx, `
+            INSERT INTO short_links (code, post_id)
+            SELECT ?, id FROM posts WHERE id = ? AND deleted_at IS NULL
+        `, code, postID)
+	}
+}
+
+// ResolveShortLink возвращает пост короткой ссылки code и засчитывает переход по ней.
+// Если ссылки нет или пост удалён, возвращает sql.ErrNoRows.
+func ResolveShortLink(ctx context.Context, db *sql.DB, code string) (int, error) {
+	var postID int
+	err := db.QueryRowContext(ctx, `
+        SELECT s.post_id FROM short_links s
+        JOIN posts p ON p.id = s.post_id
+        WHERE s.code = ? AND p.deleted_at IS NULL
+    `, code).Scan(&postID)
+	if err != nil {
+		return 0, err
+	}
+	_, err = db.ExecContext(ctx, "UPDATE short_links SET clicks = clicks + 1 WHERE code = ?", code)
+	return postID, err
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"forum/models"
+)
+
+// Настройки оформления сайта (см. models.Site): название, описание под ним, адрес загруженного логотипа
+// и ссылки подвала — по одной в строке в виде «Подпись | адрес». Пустое значение — оформление по умолчанию.
+const (
+	SettingSiteTitle       = "site_title"
+	SettingSiteDescription = "site_description"
+	SettingSiteLogo        = "site_logo"
+	SettingSiteFooterLinks = "site_footer_links"
+)
+
+// Ограничения ссылок подвала: число ссылок и длина подписи в символах.
+const (
+	maxFooterLinks      = 10
+	maxFooterLinkLabel  = 40
+	footerLinkSeparator = " | "
+)
+
+// querySite выбирает настройки оформления сайта; читается на каждой странице, поэтому выполняется
+// подготовленным выражением.
+const querySite = "SELECT name, value FROM settings WHERE name IN (?, ?, ?, ?)"
+
+// GetSite возвращает оформление сайта из настроек одним запросом. Строку подвала не заполняет:
+// она зависит от языка страницы.
+func GetSite(ctx context.Context, db *sql.DB) (models.Site, error) {
+	var site models.Site
+	rows, err := cachedQuery(ctx, db, querySite, SettingSiteTitle, SettingSiteDescription, SettingSiteLogo, SettingSiteFooterLinks)
+	if err != nil {
+		return site, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return site, err
+		}
+		switch name {
+		case SettingSiteTitle:
+			site.Title = value
+		case SettingSiteDescription:
+			site.Description = value
+		case SettingSiteLogo:
+			site.Logo = value
+		case SettingSiteFooterLinks:
+			site.FooterLinks, _ = ParseFooterLinks(value)
+		}
+	}
+	return site, rows.Err()
+}
+
+// ParseFooterLinks разбирает ссылки подвала, по одной в строке: «Подпись | адрес». Адрес — путь на форуме (/pages/rules)
+// или http(s)-адрес; пустые строки пропускаются. Возвращает false, если строка не разбирается, ссылок больше
+// maxFooterLinks или подпись длиннее maxFooterLinkLabel символов.
+func ParseFooterLinks(value string) ([]models.FooterLink, bool) {
+	var links []models.FooterLink
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		label, target, ok := strings.Cut(line, "|")
+		label, target = strings.TrimSpace(label), strings.TrimSpace(target)
+		if !ok || label == "" || utf8.RuneCountInString(label) > maxFooterLinkLabel || !footerLinkURL(target) {
+			return nil, false
+		}
+		links = append(links, models.FooterLink{Label: label, URL: target})
+	}
+	if len(links) > maxFooterLinks {
+		return nil, false
+	}
+	return links, true
+}
+
+// FormatFooterLinks записывает ссылки подвала в том виде, в каком их разбирает ParseFooterLinks.
+func FormatFooterLinks(links []models.FooterLink) string {
+	lines := make([]string, len(links))
+	for i, link := range links {
+		lines[i] = link.Label + footerLinkSeparator + link.URL
+	}
+	return strings.Join(lines, "\n")
+}
+
+// footerLinkURL сообщает, годится ли адрес для ссылки подвала: путь на форуме или http(s)-адрес.
+func footerLinkURL(target string) bool {
+	if strings.HasPrefix(target, "/") {
+		return !strings.HasPrefix(target, "//") && !strings.HasPrefix(target, "/\\")
+	}
+	u, err := url.Parse(target)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
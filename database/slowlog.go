@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"strings"
+	"time"
+)
+
+// slowQueryNameLen ограничивает длину имени запроса в журнале медленных запросов.
+const slowQueryNameLen = 100
+
+// timedConnector оборачивает соединения драйвера и логирует запросы дольше threshold.
+// Время запроса на чтение измеряется до получения первых строк, без их чтения.
+type timedConnector struct {
+	driver.Connector
+	threshold time.Duration
+}
+
+// withSlowQueryLog возвращает connector с журналом медленных запросов или сам connector, если threshold = 0.
+func withSlowQueryLog(connector driver.Connector, threshold time.Duration) driver.Connector {
+	if threshold <= 0 {
+		return connector
+	}
+	return timedConnector{Connector: connector, threshold: threshold}
+}
+
+func (c timedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &timedConn{Conn: conn, threshold: c.threshold}, nil
+}
+
+// logIfSlow пишет в журнал запрос query, если он выполнялся дольше threshold.
+// Вместо значений параметров логируется их хэш: среди них бывают ID сессий и хэши паролей,
+// а одинаковый хэш позволяет увидеть, что медленным оказался один и тот же вызов.
+func logIfSlow(threshold time.Duration, start time.Time, query string, args []driver.NamedValue) {
+	elapsed := time.Since(start)
+	if elapsed < threshold {
+		return
+	}
+	log.Printf("Slow query (%s): %s [args %s].", elapsed.Round(time.Millisecond), queryName(query), argsHash(args))
+}
+
+// queryName сжимает текст запроса в одну строку ограниченной длины.
+func queryName(query string) string {
+	name := strings.Join(strings.Fields(query), " ")
+	if len(name) > slowQueryNameLen {
+		name = name[:slowQueryNameLen] + "…"
+	}
+	return name
+}
+
+// argsHash возвращает короткий хэш значений параметров запроса.
+func argsHash(args []driver.NamedValue) string {
+	if len(args) == 0 {
+		return "-"
+	}
+	h := fnv.New32a()
+	for _, a := range args {
+		fmt.Fprintf(h, "%d:%v;", a.Ordinal, a.Value)
+	}
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// timedConn замеряет запросы соединения. Необязательные интерфейсы драйвера передаются
+// внутреннему соединению; если оно их не реализует, database/sql использует обычный путь через driver.ErrSkip.
+type timedConn struct {
+	driver.Conn
+	threshold time.Duration
+}
+
+func (c *timedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	defer logIfSlow(c.threshold, time.Now(), query, args)
+	return q.QueryContext(ctx, query, args)
+}
+
+func (c *timedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	defer logIfSlow(c.threshold, time.Now(), query, args)
+	return e.ExecContext(ctx, query, args)
+}
+
+func (c *timedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &timedStmt{Stmt: stmt, query: query, threshold: c.threshold}, nil
+}
+
+func (c *timedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *timedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *timedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *timedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *timedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// timedStmt замеряет выполнение подготовленного выражения.
+type timedStmt struct {
+	driver.Stmt
+	query     string
+	threshold time.Duration
+}
+
+func (s *timedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	defer logIfSlow(s.threshold, time.Now(), s.query, args)
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return q.QueryContext(ctx, args)
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Query(values)
+}
+
+func (s *timedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	defer logIfSlow(s.threshold, time.Now(), s.query, args)
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return e.ExecContext(ctx, args)
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Exec(values)
+}
+
+func (s *timedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// namedValues переводит параметры в позиционные для драйверов без поддержки контекста.
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, fmt.Errorf("named parameters are not supported")
+		}
+		values[i] = a.Value
+	}
+	return values, nil
+}
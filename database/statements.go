@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+)
+
+// stmtKey идентифицирует подготовленное выражение: одно и то же SQL готовится отдельно для каждой базы.
+type stmtKey struct {
+	db    *sql.DB
+	query string
+}
+
+// stmtCache хранит подготовленные выражения для запросов, выполняемых почти на каждой странице.
+// sql.Stmt безопасен для параллельного использования и сам переподготавливается на новых соединениях пула.
+type stmtCache struct {
+	mu    sync.RWMutex
+	stmts map[stmtKey]*sql.Stmt
+}
+
+var statements = &stmtCache{stmts: make(map[stmtKey]*sql.Stmt)}
+
+// hotQueries перечисляет запросы, которые готовятся при открытии базы.
+var hotQueries = []string{
+	querySessionData,
+	queryUsernameByID,
+	queryDisplayName,
+	queryUserPostVote,
+	queryPostVoteStats,
+	queryUserCommentVote,
+	queryCommentVoteStats,
+	queryFeedVersion,
+	queryPostVersion,
+	queryListBoards,
+}
+
+// get возвращает подготовленное выражение для query, готовя его при первом обращении.
+func (c *stmtCache) get(ctx context.Context, db *sql.DB, query string) (*sql.Stmt, error) {
+	key := stmtKey{db: db, query: query}
+	c.mu.RLock()
+	stmt, ok := c.stmts[key]
+	c.mu.RUnlock()
+	if ok {
+		return stmt, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stmt, ok := c.stmts[key]; ok {
+		return stmt, nil
+	}
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	c.stmts[key] = stmt
+	return stmt, nil
+}
+
+// PrepareStatements заранее готовит часто выполняемые запросы SQLite, чтобы ошибки в них
+// обнаруживались при запуске, а первые запросы не тратили время на разбор SQL.
+// Для других диалектов выражения готовятся лениво при первом выполнении.
+func PrepareStatements(db *sql.DB) {
+	for _, query := range hotQueries {
+		if _, err := statements.get(context.Background(), db, query); err != nil {
+			log.Println("Error preparing statement:", err)
+		}
+	}
+}
+
+// CloseStatements закрывает подготовленные выражения базы db. Вызывается перед db.Close.
+func CloseStatements(db *sql.DB) {
+	statements.mu.Lock()
+	defer statements.mu.Unlock()
+	for key, stmt := range statements.stmts {
+		if key.db == db {
+			stmt.Close()
+			delete(statements.stmts, key)
+		}
+	}
+}
+
+// cachedQueryRow выполняет запрос через подготовленное выражение из кэша.
+// Если подготовить выражение не удалось, запрос выполняется напрямую и возвращает ту же ошибку в Scan.
+func cachedQueryRow(ctx context.Context, db *sql.DB, query string, args ...interface{}) *sql.Row {
+	stmt, err := statements.get(ctx, db, query)
+	if err != nil {
+		return db.QueryRowContext(ctx, query, args...)
+	}
+	return stmt.QueryRowContext(ctx, args...)
+}
+
+// cachedQuery выполняет запрос, возвращающий несколько строк, через подготовленное выражение из кэша.
+func cachedQuery(ctx context.Context, db *sql.DB, query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, err := statements.get(ctx, db, query)
+	if err != nil {
+		return db.QueryContext(ctx, query, args...)
+	}
+	return stmt.QueryContext(ctx, args...)
+}
@@ -0,0 +1,2202 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"forum/models"
+)
+
+// TestSQLiteStore прогоняет общий сценарий на временной базе SQLite.
+func TestSQLiteStore(t *testing.T) {
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "forum.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer CloseStatements(db)
+	testStore(t, NewSQLiteStore(db))
+}
+
+// TestMySQLStore прогоняет общий сценарий на MySQL/MariaDB.
+// Требует отдельную пустую базу: FORUM_TEST_MYSQL_DSN=user:pass@tcp(127.0.0.1:3306)/forum_test.
+// Все таблицы форума в этой базе удаляются перед запуском.
+func TestMySQLStore(t *testing.T) {
+	dsn := os.Getenv("FORUM_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("FORUM_TEST_MYSQL_DSN is not set")
+	}
+	db, err := OpenMySQL(dsn, DefaultMySQLOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer CloseStatements(db)
+	if err := migrateDown(db, mysqlMigrations, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := MigrateMySQL(db); err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, NewMySQLStore(db))
+}
+
+// testStore проверяет, что реализация Store ведёт себя одинаково в любом диалекте.
+func testStore(t *testing.T, store *Store) {
+	ctx := context.Background()
+
+	if err := store.Users.RegisterUser(ctx, "alice@example.com", "Alice", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	userID, username, _, role, err := store.Users.GetUserByEmail(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if username != "Alice" || role != "user" {
+		t.Fatalf("GetUserByEmail = %q, %q", username, role)
+	}
+	if exists, err := store.Users.UsernameExists(ctx, "alice"); err != nil || !exists {
+		t.Fatalf("UsernameExists(alice) = %v, %v", exists, err)
+	}
+
+	expiry := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if err := store.Users.CreateSession(ctx, "session-1", userID, "user", expiry); err != nil {
+		t.Fatal(err)
+	}
+	sessionUser, _, sessionExpiry, err := store.Users.GetSessionData(ctx, "session-1")
+	if err != nil || sessionUser != userID || !sessionExpiry.Equal(expiry) {
+		t.Fatalf("GetSessionData = %d, %v, %v", sessionUser, sessionExpiry, err)
+	}
+
+	// Сроки в разных часовых поясах: строково "2…-10:00" меньше текущего времени, хотя сессия ещё действует.
+	expired := time.Now().Add(-time.Hour).In(time.FixedZone("", 5*3600))
+	active := time.Now().Add(time.Hour).In(time.FixedZone("", -10*3600))
+	if err := store.Users.CreateSession(ctx, "session-expired", userID, "user", expired); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Users.CreateSession(ctx, "session-active", userID, "user", active); err != nil {
+		t.Fatal(err)
+	}
+	if purged, err := store.Users.PurgeExpiredSessions(ctx); err != nil || purged != 1 {
+		t.Fatalf("PurgeExpiredSessions = %d, %v, want 1", purged, err)
+	}
+	if _, _, _, err := store.Users.GetSessionData(ctx, "session-active"); err != nil {
+		t.Fatalf("active session purged: %v", err)
+	}
+
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	postID, err := store.Posts.CreatePost(ctx, userID, board.ID, "Hello", "First post body", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	catID, err := store.Posts.GetCategoryIDByName(ctx, "news")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Posts.AddPostCategory(ctx, postID, catID); err != nil {
+		t.Fatal(err)
+	}
+	post, err := store.Posts.GetPostByID(ctx, int(postID), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Title != "Hello" || post.Username != "Alice" || post.Category != "news" {
+		t.Fatalf("GetPostByID = %+v", post)
+	}
+	if pinned, err := store.Users.GetPinnedPostID(ctx, userID); err != nil || pinned != 0 {
+		t.Fatalf("GetPinnedPostID before pinning = %d, %v", pinned, err)
+	}
+	if err := store.Users.SetPinnedPost(ctx, userID, int(postID)); err != nil {
+		t.Fatal(err)
+	}
+	if pinned, err := store.Users.GetPinnedPostID(ctx, userID); err != nil || pinned != int(postID) {
+		t.Fatalf("GetPinnedPostID = %d, %v, want %d", pinned, err, postID)
+	}
+	if err := store.Users.SetPinnedPost(ctx, userID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := store.Users.CountRecentPosts(ctx, userID, time.Now().Add(-time.Hour), 3); err != nil || n != 1 {
+		t.Fatalf("CountRecentPosts = %d, %v, want 1", n, err)
+	}
+	if n, err := store.Users.CountRecentPosts(ctx, userID, time.Now().Add(time.Hour), 3); err != nil || n != 0 {
+		t.Fatalf("CountRecentPosts in the future = %d, %v, want 0", n, err)
+	}
+
+	before, err := store.Posts.GetFeedVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Votes.SetPostLike(ctx, userID, int(postID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Votes.SetPostDislike(ctx, userID, int(postID)); err != nil {
+		t.Fatal(err)
+	}
+	likes, dislikes, vote, voted, err := store.Votes.GetPostVoteStats(ctx, userID, int(postID))
+	if err != nil || likes != 0 || dislikes != 1 || vote != -1 || !voted {
+		t.Fatalf("GetPostVoteStats = %d, %d, %d, %v, %v", likes, dislikes, vote, voted, err)
+	}
+
+	after, err := store.Posts.GetFeedVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Fingerprint == before.Fingerprint {
+		t.Fatal("feed version did not change after a vote")
+	}
+
+	// Вторая категория не должна удваивать счётчики голосов.
+	otherID, err := store.Posts.GetCategoryIDByName(ctx, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Posts.AddPostCategory(ctx, postID, otherID); err != nil {
+		t.Fatal(err)
+	}
+	posts, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: "best", Category: "news", Board: DefaultBoard})
+	if err != nil || len(posts) != 1 || posts[0].Dislikes != 1 || posts[0].UserVote != -1 || len(posts[0].Categories) != 2 || posts[0].BoardSlug != DefaultBoard {
+		t.Fatalf("GetPosts = %+v, %v", posts, err)
+	}
+	if posts, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: "new", Board: "missing"}); err != nil || len(posts) != 0 {
+		t.Fatalf("GetPosts of another board = %+v, %v", posts, err)
+	}
+	testCategoryStyles(t, store, int(postID))
+	testHiddenPosts(t, store, userID)
+	if ok, err := store.Boards.ModeratesPost(ctx, userID, int(postID)); err != nil || ok {
+		t.Fatalf("ModeratesPost before assignment = %v, %v", ok, err)
+	}
+	if err := SetBoardModerator(ctx, store.DB, board.ID, userID, true); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := store.Boards.ModeratesPost(ctx, userID, int(postID)); err != nil || !ok {
+		t.Fatalf("ModeratesPost after assignment = %v, %v", ok, err)
+	}
+	if board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard); err != nil || board.PostCount != 1 || len(board.Moderators) != 1 {
+		t.Fatalf("GetBoardBySlug = %+v, %v", board, err)
+	}
+
+	commentID, err := store.Comments.CreateComment(ctx, int(postID), userID, "Nice", time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := store.Votes.SetCommentLike(ctx, userID, int(commentID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	comments, err := store.Comments.GetCommentsByPostIDWithUserVote(ctx, userID, int(postID))
+	if err != nil || len(comments) != 1 || comments[0].Likes != 1 || comments[0].UserVote != 1 {
+		t.Fatalf("GetCommentsByPostIDWithUserVote = %+v, %v", comments, err)
+	}
+
+	byPost, err := store.Comments.GetCommentsByPostIDs(ctx, userID, []int{int(postID)})
+	if err != nil || len(byPost[int(postID)]) != 1 || byPost[int(postID)][0].UserVote != 1 {
+		t.Fatalf("GetCommentsByPostIDs = %+v, %v", byPost, err)
+	}
+	categories, err := store.Posts.GetPostCategoriesByPostIDs(ctx, []int{int(postID)})
+	if err != nil || len(categories[int(postID)]) != 2 {
+		t.Fatalf("GetPostCategoriesByPostIDs = %+v, %v", categories, err)
+	}
+
+	hiddenID, err := store.Comments.CreateComment(ctx, int(postID), userID, "Hidden", time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Comments.DeleteComment(ctx, int(hiddenID)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Comments.GetCommentOwnerID(ctx, int(hiddenID)); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetCommentOwnerID after delete = %v, want sql.ErrNoRows", err)
+	}
+	if n, err := store.Users.CountRecentComments(ctx, userID, time.Now().Add(-time.Hour), 10); err != nil || n != 2 {
+		t.Fatalf("CountRecentComments = %d, %v, want 2 (deleted comments count too)", n, err)
+	}
+
+	// Удалённый комментарий не считается; пост и комментарий созданы сегодня.
+	if days, err := store.Users.GetUserActivity(ctx, userID, time.Now().AddDate(0, 0, -7)); err != nil || len(days) != 1 ||
+		days[0].Date != time.Now().Format("2006-01-02") || days[0].Posts != 1 || days[0].Comments != 1 || days[0].Count != 2 {
+		t.Fatalf("GetUserActivity = %+v, %v", days, err)
+	}
+	if days, err := store.Users.GetUserActivity(ctx, userID, time.Now().AddDate(0, 0, 1)); err != nil || len(days) != 0 {
+		t.Fatalf("GetUserActivity from tomorrow = %+v, %v", days, err)
+	}
+
+	summaries, err := store.Posts.GetPostSummaries(ctx, "new", "news", userID, 5, 10, 0)
+	if err != nil || len(summaries) != 1 {
+		t.Fatalf("GetPostSummaries = %+v, %v", summaries, err)
+	}
+	if s := summaries[0]; s.Excerpt != "First" || s.Score != -1 || s.CommentCount != 1 {
+		t.Fatalf("GetPostSummaries = %+v", s)
+	}
+	// В ленте «Без ответов» только посты без комментариев; From отсекает посты, созданные раньше.
+	if posts, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: "unanswered", ShowHidden: true}); err != nil || len(posts) != 0 {
+		t.Fatalf("GetPosts(unanswered) = %+v, %v, want none: the post has a comment", posts, err)
+	}
+	if posts, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: "new", ShowHidden: true, From: time.Now().Add(time.Hour)}); err != nil || len(posts) != 0 {
+		t.Fatalf("GetPosts(from the future) = %+v, %v, want none", posts, err)
+	}
+	if posts, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: "new", ShowHidden: true, From: time.Now().AddDate(0, 0, -1)}); err != nil || len(posts) != 1 {
+		t.Fatalf("GetPosts(from yesterday) = %+v, %v, want one post", posts, err)
+	}
+	// Карточки ленты и профиля показывают число комментариев без загрузки самих комментариев.
+	if feed, _, err := store.Posts.GetFeedPage(ctx, userID, FeedQuery{Filter: "new", ShowHidden: true}, 10, ""); err != nil || len(feed) != 1 || feed[0].CommentCount != 1 {
+		t.Fatalf("GetFeedPage = %+v, %v, want one post with one comment", feed, err)
+	}
+	if posts, err := store.Posts.GetUserPosts(ctx, userID, userID); err != nil || len(posts) != 1 || posts[0].CommentCount != 1 {
+		t.Fatalf("GetUserPosts = %+v, %v, want one post with one comment", posts, err)
+	}
+	// Последним считается неудалённый комментарий; текст обрезается до заданной длины.
+	if latest, err := store.Comments.GetLatestCommentsByPostIDs(ctx, []int{int(postID), 0}, 0, 3); err != nil || len(latest) != 1 ||
+		latest[int(postID)].ID != int(commentID) || latest[int(postID)].Content != "Nic" || latest[int(postID)].Username == "" {
+		t.Fatalf("GetLatestCommentsByPostIDs = %+v, %v", latest, err)
+	}
+	if latest, err := store.Comments.GetLatestCommentsByPostIDs(ctx, []int{int(postID)}, userID+1000, 3); err != nil || len(latest) != 0 {
+		t.Fatalf("GetLatestCommentsByPostIDs(other author) = %+v, %v, want none", latest, err)
+	}
+	// Личные ленты: пост с дизлайком пользователя и пост с его комментарием; ссылка на комментарий открывает
+	// страницу комментариев, начинающуюся с него.
+	for _, filter := range []string{"disliked", "my-comments"} {
+		if posts, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: filter}); err != nil || len(posts) != 1 || posts[0].ID != int(postID) {
+			t.Fatalf("GetPosts(%s) = %+v, %v, want the post", filter, posts, err)
+		}
+	}
+	if posts, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: "liked"}); err != nil || len(posts) != 0 {
+		t.Fatalf("GetPosts(liked) = %+v, %v, want none", posts, err)
+	}
+	commentCursor, err := store.Comments.GetCommentCursor(ctx, int(postID), int(commentID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page, _, err := store.Comments.GetCommentsPage(ctx, userID, int(postID), 1, commentCursor); err != nil || len(page) != 1 || page[0].ID != int(commentID) {
+		t.Fatalf("GetCommentsPage(comment cursor) = %+v, %v", page, err)
+	}
+	if _, err := store.Comments.GetCommentCursor(ctx, int(postID)+1000, int(commentID)); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetCommentCursor(other post) = %v, want sql.ErrNoRows", err)
+	}
+
+	// Счётчики, поддерживаемые триггерами, совпадают с таблицами; испорченный счётчик исправляется.
+	if posts, comments, err := ReconcileCounters(ctx, store.DB); err != nil || posts != 0 || comments != 0 {
+		t.Fatalf("ReconcileCounters = %d, %d, %v, want 0, 0", posts, comments, err)
+	}
+	if _, err := store.DB.ExecContext(ctx, "UPDATE posts SET likes = 5, comment_count = 0 WHERE id = ?", postID); err != nil {
+		t.Fatal(err)
+	}
+	if posts, _, err := ReconcileCounters(ctx, store.DB); err != nil || posts != 1 {
+		t.Fatalf("ReconcileCounters after drift = %d, %v, want 1", posts, err)
+	}
+	if post, err := store.Posts.GetPostByID(ctx, int(postID), userID); err != nil || post.Likes != 0 || post.Dislikes != 1 {
+		t.Fatalf("GetPostByID after reconcile = %+v, %v", post, err)
+	}
+
+	if _, err := store.Posts.GetPostVersion(ctx, int(postID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Posts.DeletePost(ctx, int(postID)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Posts.GetPostVersion(ctx, int(postID)); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetPostVersion after delete = %v, want sql.ErrNoRows", err)
+	}
+	if posts, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: "new"}); err != nil || len(posts) != 0 {
+		t.Fatalf("GetPosts after delete = %+v, %v", posts, err)
+	}
+
+	// Мягко удалённый пост остаётся в базе до истечения срока хранения.
+	if purged, _, err := store.Posts.PurgeDeletedContent(ctx, time.Hour); err != nil || purged != 0 {
+		t.Fatalf("PurgeDeletedContent(1h) = %d, %v, want 0", purged, err)
+	}
+	if _, err := store.DB.ExecContext(ctx, "UPDATE posts SET deleted_at = '2000-01-01 00:00:00' WHERE id = ?", postID); err != nil {
+		t.Fatal(err)
+	}
+	if purged, _, err := store.Posts.PurgeDeletedContent(ctx, time.Hour); err != nil || purged != 1 {
+		t.Fatalf("PurgeDeletedContent(1h) = %d, %v, want 1", purged, err)
+	}
+	var remaining int
+	if err := store.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM comments WHERE post_id = ?", postID).Scan(&remaining); err != nil || remaining != 0 {
+		t.Fatalf("comments of purged post = %d, %v, want 0", remaining, err)
+	}
+
+	testKeysetPagination(t, store, userID)
+	testSeries(t, store, userID)
+	testPublishPost(t, store, userID)
+	testCollections(t, store, userID)
+	testPages(t, store)
+	testSearch(t, store, userID)
+	testLeaderboards(t, store, userID)
+	testDigests(t, store)
+	testReadTracking(t, store, userID)
+	testViewHistory(t, store)
+	testShortLinks(t, store)
+	testPostTranslations(t, store)
+	testModeration(t, store, userID)
+	testThreadSummaries(t, store, userID)
+	testVoteReport(t, store, userID)
+	testBestDecay(t, store, userID)
+	testFeedConditions(t, store, userID)
+	testVoteNotifications(t, store, userID)
+	testInvites(t, store, userID)
+	testUploadReferences(t, store, userID)
+	testCommentPolicies(t, store, userID)
+	testOAuthAccounts(t, store, userID)
+	testEmailVerification(t, store, userID)
+	testManageUserContent(t, store, userID)
+	testCategoryStats(t, store, userID)
+	testPasswordResets(t, store, userID)
+	testSiteSettings(t, store)
+	testPostShares(t, store, userID)
+	testPostReports(t, store, userID)
+	testUserStanding(t, store, userID)
+	testLikers(t, store, userID)
+}
+
+// testCategoryStyles проверяет оформление категорий по умолчанию и его изменение в данных поста postID
+// с категориями news и other.
+func testCategoryStyles(t *testing.T, store *Store, postID int) {
+	ctx := context.Background()
+	categories, err := store.Categories.ListCategories(ctx)
+	if err != nil || len(categories) != len(defaultCategoryStyles) || categories[0].Name != "news" || categories[0].Color != "#4e8cff" {
+		t.Fatalf("ListCategories = %+v, %v", categories, err)
+	}
+	before, err := store.Posts.GetFeedVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Categories.UpdateCategoryStyle(ctx, "news", "N", "#123456"); err != nil {
+		t.Fatal(err)
+	}
+	if after, err := store.Posts.GetFeedVersion(ctx); err != nil || after.Fingerprint == before.Fingerprint {
+		t.Fatalf("GetFeedVersion after UpdateCategoryStyle = %+v, %v; want a new fingerprint", after, err)
+	}
+	if err := store.Categories.UpdateCategoryStyle(ctx, "missing", "", ""); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("UpdateCategoryStyle(missing) = %v, want sql.ErrNoRows", err)
+	}
+
+	want := "[{news N #123456} {other ✨ #8a94a6}]"
+	post, err := store.Posts.GetPostByID(ctx, postID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := categoryStyles(post.Categories); got != want || post.Category != "news" {
+		t.Fatalf("GetPostByID categories = %s (%s), want %s", got, post.Category, want)
+	}
+	byID, err := store.Posts.GetPostCategories(ctx, postID)
+	if got := categoryStyles(byID); err != nil || got != want {
+		t.Fatalf("GetPostCategories = %s, %v; want %s", got, err, want)
+	}
+}
+
+// categoryStyles выводит имена, значки и цвета категорий для сравнения в тестах.
+func categoryStyles(categories []models.Category) string {
+	parts := make([]string, len(categories))
+	for i, c := range categories {
+		parts[i] = fmt.Sprintf("{%s %s %s}", c.Name, c.Icon, c.Color)
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// testHiddenPosts проверяет скрытие единственного поста с рейтингом -1 порогом SettingHideScoreBelow.
+func testHiddenPosts(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	before, err := store.Posts.GetFeedVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Settings.SetSetting(ctx, SettingHideScoreBelow, "0"); err != nil {
+		t.Fatal(err)
+	}
+	if after, err := store.Posts.GetFeedVersion(ctx); err != nil || after.Fingerprint == before.Fingerprint {
+		t.Fatalf("GetFeedVersion after SetSetting = %+v, %v; want a new fingerprint", after, err)
+	}
+	if value, err := store.Settings.GetSetting(ctx, SettingHideScoreBelow); err != nil || value != "0" {
+		t.Fatalf("GetSetting = %q, %v", value, err)
+	}
+	if posts, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: "new"}); err != nil || len(posts) != 0 {
+		t.Fatalf("GetPosts with a low-score post = %+v, %v; want it hidden", posts, err)
+	}
+	if posts, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: "new", ShowHidden: true}); err != nil || len(posts) != 1 || !posts[0].Hidden {
+		t.Fatalf("GetPosts(showHidden) = %+v, %v", posts, err)
+	}
+	if posts, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: "my"}); err != nil || len(posts) != 1 {
+		t.Fatalf("GetPosts(my) = %+v, %v; personal feeds must not hide posts", posts, err)
+	}
+	if err := store.Settings.SetSetting(ctx, SettingHideScoreBelow, ""); err != nil {
+		t.Fatal(err)
+	}
+	if posts, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: "new"}); err != nil || len(posts) != 1 || posts[0].Hidden {
+		t.Fatalf("GetPosts with hiding disabled = %+v, %v", posts, err)
+	}
+}
+
+// testPublishPost проверяет, что пост из формы создаётся сразу с доступом, правилом комментирования, категориями
+// и серией, а при ошибке не создаётся вовсе.
+func testPublishPost(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	draft := NewPost{
+		UserID: userID, BoardID: board.ID, Title: "Members", Content: "Body", CreatedAt: time.Now(),
+		MembersOnly: true, CommentPolicy: CommentsFollowers, Categories: []string{"news", "games"}, NewSeries: "Chronicle",
+	}
+	first, err := store.Posts.PublishPost(ctx, draft)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err := store.Posts.GetPostByID(ctx, int(first), userID)
+	if err != nil || !post.MembersOnly || post.CommentPolicy != CommentsFollowers {
+		t.Errorf("published post = %+v, %v", post, err)
+	}
+	if cats, err := store.Posts.GetPostCategories(ctx, int(first)); err != nil || len(cats) != 2 {
+		t.Errorf("categories of the published post = %+v, %v", cats, err)
+	}
+	nav, err := store.Series.GetPostSeries(ctx, int(first), userID)
+	if err != nil || nav.Title != "Chronicle" || nav.Part != 1 || nav.Total != 1 {
+		t.Fatalf("series of the published post = %+v, %v", nav, err)
+	}
+
+	second, err := store.Posts.PublishPost(ctx, NewPost{
+		UserID: userID, BoardID: board.ID, Title: "Open", Content: "Body", CreatedAt: time.Now(),
+		Categories: []string{"news"}, SeriesID: nav.SeriesID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post, err := store.Posts.GetPostByID(ctx, int(second), 0); err != nil || post.MembersOnly || post.CommentPolicy != CommentsEveryone {
+		t.Errorf("post with default settings = %+v, %v", post, err)
+	}
+	if nav, err := store.Series.GetPostSeries(ctx, int(second), userID); err != nil || nav.Part != 2 || nav.Total != 2 {
+		t.Errorf("second part of the series = %+v, %v", nav, err)
+	}
+
+	var before int
+	if err := store.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM posts").Scan(&before); err != nil {
+		t.Fatal(err)
+	}
+	draft.Title, draft.NewSeries, draft.SeriesID = "Broken", "", 999999
+	if _, err := store.Posts.PublishPost(ctx, draft); err == nil {
+		t.Fatal("PublishPost into a missing series succeeded")
+	}
+	var after int
+	if err := store.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM posts").Scan(&after); err != nil || after != before {
+		t.Errorf("posts after a failed publish = %d, %v, want %d", after, err, before)
+	}
+}
+
+// testSeries проверяет порядок частей серии, навигацию по ней, перестановку и удаление опустевшей серии.
+func testSeries(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seriesID, err := store.Series.CreateSeries(ctx, userID, "Tutorial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var postIDs []int
+	for i := 0; i < 3; i++ {
+		id, err := store.Posts.CreatePost(ctx, userID, board.ID, fmt.Sprintf("Part %d", i+1), "Body", "", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Series.SetPostSeries(ctx, int(id), int(seriesID)); err != nil {
+			t.Fatal(err)
+		}
+		postIDs = append(postIDs, int(id))
+	}
+
+	nav, err := store.Series.GetPostSeries(ctx, postIDs[1], userID)
+	if err != nil || nav.Part != 2 || nav.Total != 3 || nav.Prev == nil || nav.Prev.PostID != postIDs[0] || nav.Next == nil || nav.Next.PostID != postIDs[2] {
+		t.Fatalf("GetPostSeries = %+v, %v", nav, err)
+	}
+	if err := store.Series.MoveSeriesPart(ctx, int(seriesID), postIDs[2], true); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Series.MoveSeriesPart(ctx, int(seriesID), postIDs[0], true); err != nil {
+		t.Fatal(err)
+	}
+	series, err := store.Series.GetSeries(ctx, int(seriesID), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []int
+	for _, part := range series.Parts {
+		order = append(order, part.PostID)
+	}
+	if want := []int{postIDs[0], postIDs[2], postIDs[1]}; fmt.Sprint(order) != fmt.Sprint(want) || series.Title != "Tutorial" {
+		t.Fatalf("GetSeries = %q %v, want Tutorial %v", series.Title, order, want)
+	}
+	// Пост только для авторизованных анонимный посетитель в серии не видит.
+	if err := store.Posts.SetPostMembersOnly(ctx, postIDs[2], true); err != nil {
+		t.Fatal(err)
+	}
+	if series, err := store.Series.GetSeries(ctx, int(seriesID), 0); err != nil || len(series.Parts) != 2 {
+		t.Fatalf("anonymous GetSeries = %+v, %v, want 2 parts", series.Parts, err)
+	}
+	if nav, err := store.Series.GetPostSeries(ctx, postIDs[0], 0); err != nil || nav.Total != 2 || nav.Next == nil || nav.Next.PostID != postIDs[1] {
+		t.Fatalf("anonymous GetPostSeries = %+v, %v", nav, err)
+	}
+	if _, err := store.Series.GetPostSeries(ctx, postIDs[0]+100, userID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetPostSeries(not in series) = %v, want sql.ErrNoRows", err)
+	}
+
+	for _, id := range postIDs {
+		if err := store.Series.SetPostSeries(ctx, id, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Series.GetSeries(ctx, int(seriesID), userID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetSeries after removing every part = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// testCollections проверяет сохранение постов в подборку, отметку сохранённого поста и доступ по ссылке.
+func testCollections(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	postID, err := store.Posts.CreatePost(ctx, userID, board.ID, "Worth keeping", "Body", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	collectionID, err := store.Collections.CreateCollection(ctx, userID, "Reading list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := store.Collections.AddCollectionItem(ctx, int(collectionID), int(postID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	collections, err := store.Collections.ListUserCollections(ctx, userID, int(postID))
+	if err != nil || len(collections) != 1 || !collections[0].Saved || collections[0].ItemCount != 1 {
+		t.Fatalf("ListUserCollections = %+v, %v", collections, err)
+	}
+	if _, err := store.Collections.GetSharedCollection(ctx, ""); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetSharedCollection(private) = %v, want sql.ErrNoRows", err)
+	}
+	if err := store.Collections.SetCollectionShared(ctx, int(collectionID), true); err != nil {
+		t.Fatal(err)
+	}
+	collection, err := store.Collections.GetCollection(ctx, int(collectionID))
+	if err != nil || collection.ShareToken == "" {
+		t.Fatalf("GetCollection after sharing = %+v, %v", collection, err)
+	}
+	shared, err := store.Collections.GetSharedCollection(ctx, collection.ShareToken)
+	if err != nil || len(shared.Items) != 1 || shared.Items[0].PostID != int(postID) {
+		t.Fatalf("GetSharedCollection = %+v, %v", shared, err)
+	}
+	if err := store.Collections.SetCollectionShared(ctx, int(collectionID), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Collections.GetSharedCollection(ctx, collection.ShareToken); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetSharedCollection after unsharing = %v, want sql.ErrNoRows", err)
+	}
+
+	if err := store.Collections.RemoveCollectionItem(ctx, int(collectionID), int(postID)); err != nil {
+		t.Fatal(err)
+	}
+	if collection, err := store.Collections.GetCollection(ctx, int(collectionID)); err != nil || len(collection.Items) != 0 {
+		t.Fatalf("GetCollection after removing = %+v, %v", collection, err)
+	}
+	if err := store.Collections.DeleteCollection(ctx, int(collectionID)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Collections.GetCollection(ctx, int(collectionID)); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetCollection after deleting = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// testPages проверяет страницы по умолчанию, сохранение новой и обновление существующей страницы.
+func testPages(t *testing.T, store *Store) {
+	ctx := context.Background()
+	if pages, err := ListPages(ctx, store.DB); err != nil || len(pages) != len(defaultPages) {
+		t.Fatalf("ListPages = %+v, %v", pages, err)
+	}
+	if err := SavePage(ctx, store.DB, "contacts", "Contacts", "Write to **us**."); err != nil {
+		t.Fatal(err)
+	}
+	if err := SavePage(ctx, store.DB, "contacts", "Contact us", "Updated."); err != nil {
+		t.Fatal(err)
+	}
+	if page, err := GetPage(ctx, store.DB, "contacts"); err != nil || page.Title != "Contact us" || page.Content != "Updated." {
+		t.Fatalf("GetPage = %+v, %v", page, err)
+	}
+	if err := DeletePage(ctx, store.DB, "contacts"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetPage(ctx, store.DB, "contacts"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetPage after delete = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// testKeysetPagination проверяет, что страницы по курсору покрывают все строки без пропусков и повторов,
+// в том числе при одинаковом времени создания.
+func testKeysetPagination(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createdAt := time.Now()
+	var postIDs []int
+	for i := 0; i < 5; i++ {
+		id, err := store.Posts.CreatePost(ctx, userID, board.ID, "Page", "Body", "", createdAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		postIDs = append(postIDs, int(id))
+	}
+	if err := store.Votes.SetPostLike(ctx, userID, postIDs[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, filter := range []string{"new", "best"} {
+		var got []int
+		cursor := ""
+		for page := 0; ; page++ {
+			posts, next, err := store.Posts.GetPostSummariesAfter(ctx, filter, "", userID, 10, 2, cursor)
+			if err != nil {
+				t.Fatalf("GetPostSummariesAfter(%s) = %v", filter, err)
+			}
+			for _, p := range posts {
+				got = append(got, p.ID)
+			}
+			if next == "" {
+				break
+			}
+			if page > 5 {
+				t.Fatalf("GetPostSummariesAfter(%s) does not terminate: %v", filter, got)
+			}
+			cursor = next
+		}
+		want := []int{postIDs[4], postIDs[3], postIDs[2], postIDs[1], postIDs[0]}
+		if filter == "best" {
+			want = []int{postIDs[1], postIDs[4], postIDs[3], postIDs[2], postIDs[0]}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("GetPostSummariesAfter(%s) pages = %v, want %v", filter, got, want)
+		}
+
+		all, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: filter, Board: DefaultBoard})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var wantFeed, gotFeed []int
+		for _, p := range all {
+			wantFeed = append(wantFeed, p.ID)
+		}
+		for cursor, page := "", 0; ; page++ {
+			posts, next, err := store.Posts.GetFeedPage(ctx, userID, FeedQuery{Filter: filter, Board: DefaultBoard}, 2, cursor)
+			if err != nil {
+				t.Fatalf("GetFeedPage(%s) = %v", filter, err)
+			}
+			for _, p := range posts {
+				gotFeed = append(gotFeed, p.ID)
+			}
+			if next == "" {
+				break
+			}
+			if page > len(all) {
+				t.Fatalf("GetFeedPage(%s) does not terminate: %v", filter, gotFeed)
+			}
+			cursor = next
+		}
+		if fmt.Sprint(gotFeed) != fmt.Sprint(wantFeed) {
+			t.Fatalf("GetFeedPage(%s) pages = %v, want %v", filter, gotFeed, wantFeed)
+		}
+	}
+	if _, _, err := store.Posts.GetFeedPage(ctx, userID, FeedQuery{Filter: "new"}, 2, "not-a-cursor"); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("GetFeedPage(bad cursor) = %v, want ErrInvalidCursor", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := store.Comments.CreateComment(ctx, postIDs[0], userID, "Reply", createdAt.Format("2006-01-02 15:04:05")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	unanswered, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: "unanswered", ShowHidden: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.ContainsFunc(unanswered, func(p models.PostData) bool { return p.ID == postIDs[0] }) ||
+		!slices.ContainsFunc(unanswered, func(p models.PostData) bool { return p.ID == postIDs[1] }) {
+		t.Fatalf("GetPosts(unanswered) = %+v, want posts without comments only", unanswered)
+	}
+	first, next, err := store.Comments.GetCommentsPage(ctx, userID, postIDs[0], 2, "")
+	if err != nil || len(first) != 2 || next == "" {
+		t.Fatalf("GetCommentsPage = %d comments, %q, %v", len(first), next, err)
+	}
+	second, next, err := store.Comments.GetCommentsPage(ctx, userID, postIDs[0], 2, next)
+	if err != nil || len(second) != 1 || next != "" || second[0].ID >= first[1].ID {
+		t.Fatalf("GetCommentsPage (second) = %+v, %q, %v", second, next, err)
+	}
+	if _, _, err := store.Comments.GetCommentsPage(ctx, userID, postIDs[0], 2, "not-a-cursor"); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("GetCommentsPage(bad cursor) = %v, want ErrInvalidCursor", err)
+	}
+}
+
+// testSearch проверяет поиск: слова без учёта регистра, экранирование LIKE, операторы, автора, категорию, даты и рейтинг.
+// Запросы подобраны так, что ответ одинаков с индексом FTS5 и без него.
+func testSearch(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	postID, err := store.Posts.CreatePost(ctx, userID, board.ID, "Aurora hunting 100%", "Northern lights guide", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	catID, err := store.Posts.GetCategoryIDByName(ctx, "science")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Posts.AddPostCategory(ctx, postID, catID); err != nil {
+		t.Fatal(err)
+	}
+
+	today := time.Now().Format("2006-01-02")
+	tests := []struct {
+		query string
+		found bool
+	}{
+		{"AURORA", true},
+		{`"northern lights" 100%`, true},
+		{"hunt_ng", false},
+		{"auro* guide", true},
+		{"zzz OR (aurora NOT zzz)", true},
+		{"aurora NOT northern", false},
+		{"NOT aurora", false},
+		{`"lights northern"`, false},
+		{"aurora author:alice category:science", true},
+		{"aurora tag:news", false},
+		{"aurora after:" + today, true},
+		{"aurora before:" + today, false},
+		{"aurora min_score:1", false},
+	}
+	for _, tt := range tests {
+		posts, _, err := store.Posts.SearchPosts(ctx, userID, ParseSearchQuery(tt.query), 20, "")
+		if err != nil {
+			t.Fatalf("SearchPosts(%q) = %v", tt.query, err)
+		}
+		found := false
+		for _, p := range posts {
+			found = found || p.ID == int(postID)
+		}
+		if found != tt.found {
+			t.Errorf("SearchPosts(%q) found post = %v, want %v", tt.query, found, tt.found)
+		}
+	}
+}
+
+// testLeaderboards проверяет таблицы лидеров: свои лайки и удалённые комментарии не учитываются, период ограничивает выборку.
+func testLeaderboards(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	all, err := store.Users.GetLeaderboards(ctx, time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all.Posters) == 0 || all.Posters[0].UserID != userID || all.Posters[0].Username != "Alice" {
+		t.Errorf("Posters = %+v", all.Posters)
+	}
+	if len(all.Liked) != 0 {
+		t.Errorf("Liked = %+v, want none (only own likes)", all.Liked)
+	}
+	if len(all.Commenters) != 0 {
+		t.Errorf("Commenters = %+v, want none (no comments with positive score)", all.Commenters)
+	}
+	future, err := store.Users.GetLeaderboards(ctx, time.Now().Add(time.Hour), 10)
+	if err != nil || len(future.Posters) != 0 {
+		t.Errorf("GetLeaderboards(future) = %+v, %v", future, err)
+	}
+}
+
+// testDigests проверяет недельную подборку: посты текущей недели попадают в свою категорию,
+// а сохранённый снимок не пересобирается повторным вызовом.
+func testDigests(t *testing.T, store *Store) {
+	ctx := context.Background()
+	week := DigestWeek(time.Now())
+	if week.Weekday() != time.Monday || week.After(time.Now()) {
+		t.Fatalf("DigestWeek = %v", week)
+	}
+	d, err := BuildDigest(ctx, store.DB, week, DigestSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, c := range d.Categories {
+		for _, p := range c.Posts {
+			found = found || c.Name == "science" && p.Title == "Aurora hunting 100%"
+		}
+	}
+	if !found {
+		t.Errorf("BuildDigest = %+v, want the science post", d.Categories)
+	}
+
+	last := week.AddDate(0, 0, -7)
+	saved, created, err := EnsureDigest(ctx, store.DB, last, DigestSize)
+	if err != nil || !created || len(saved.Categories) != 0 {
+		t.Fatalf("EnsureDigest = %+v, %v, %v", saved, created, err)
+	}
+	if _, created, err := EnsureDigest(ctx, store.DB, last, DigestSize); err != nil || created {
+		t.Fatalf("EnsureDigest (again) created = %v, %v", created, err)
+	}
+	weeks, err := ListDigestWeeks(ctx, store.DB, 10)
+	if err != nil || len(weeks) != 1 || weeks[0] != last.Format(DigestWeekFormat) {
+		t.Fatalf("ListDigestWeeks = %v, %v", weeks, err)
+	}
+	if _, ok := ParseDigestWeek(week.AddDate(0, 0, 1).Format(DigestWeekFormat)); ok {
+		t.Error("ParseDigestWeek accepted a Tuesday")
+	}
+}
+
+// testReadTracking проверяет отметки непрочитанного: чужой пост непрочитан, пока его не открыли,
+// свои посты не отмечаются, а «отметить всё прочитанным» снимает отметки со всех опубликованных постов.
+func testReadTracking(t *testing.T, store *Store, authorID int) {
+	ctx := context.Background()
+	if err := store.Users.RegisterUser(ctx, "reader@example.com", "Reader", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	readerID, _, _, _, err := store.Users.GetUserByEmail(ctx, "reader@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unread := func(userID int) []int {
+		t.Helper()
+		posts, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: "unread", ShowHidden: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, p := range posts {
+			if !p.Unread {
+				t.Errorf("post %d in the unread feed is not marked Unread", p.ID)
+			}
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	if ids := unread(authorID); len(ids) != 0 {
+		t.Errorf("unread for the author = %v, want none", ids)
+	}
+	ids := unread(readerID)
+	if len(ids) < 2 {
+		t.Fatalf("unread for the reader = %v, want at least two posts", ids)
+	}
+	if anon, err := store.Posts.GetPosts(ctx, 0, FeedQuery{Filter: "new", ShowHidden: true}); err != nil || len(anon) == 0 || anon[0].Unread {
+		t.Errorf("GetPosts(anonymous) = %+v, %v", anon, err)
+	}
+
+	before, err := store.Users.GetReadState(ctx, readerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Users.MarkPostViewed(ctx, readerID, ids[0], time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Users.MarkPostViewed(ctx, readerID, ids[0], time.Now()); err != nil {
+		t.Fatalf("MarkPostViewed (again) = %v", err)
+	}
+	if after := unread(readerID); len(after) != len(ids)-1 || slices.Contains(after, ids[0]) {
+		t.Errorf("unread after viewing %d = %v", ids[0], after)
+	}
+	if state, err := store.Users.GetReadState(ctx, readerID); err != nil || state == before {
+		t.Errorf("GetReadState after viewing = %q, %v (was %q)", state, err, before)
+	}
+
+	if err := store.Users.MarkAllRead(ctx, readerID); err != nil {
+		t.Fatal(err)
+	}
+	if after := unread(readerID); len(after) != 0 {
+		t.Errorf("unread after MarkAllRead = %v, want none", after)
+	}
+}
+
+// testViewHistory проверяет историю просмотров читателя из testReadTracking: порядок, срок хранения
+// и отключение истории, после которого просмотры не записываются, а посты остаются прочитанными.
+func testViewHistory(t *testing.T, store *Store) {
+	ctx := context.Background()
+	readerID, _, _, _, err := store.Users.GetUserByEmail(ctx, "reader@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	posts, err := store.Posts.GetPosts(ctx, 0, FeedQuery{Filter: "new", ShowHidden: true})
+	if err != nil || len(posts) < 2 {
+		t.Fatalf("GetPosts = %d posts, %v", len(posts), err)
+	}
+	older, newer := posts[1].ID, posts[0].ID
+	if err := store.Users.MarkPostViewed(ctx, readerID, older, time.Now().Add(-48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Users.MarkPostViewed(ctx, readerID, newer, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	history, err := store.Users.GetViewHistory(ctx, readerID, 10)
+	if err != nil || len(history) < 2 || history[0].PostID != newer {
+		t.Fatalf("GetViewHistory = %+v, %v", history, err)
+	}
+
+	if purged, err := store.Users.PurgeViewHistory(ctx, 24*time.Hour); err != nil || purged != 1 {
+		t.Fatalf("PurgeViewHistory(24h) = %d, %v, want 1", purged, err)
+	}
+	history, err = store.Users.GetViewHistory(ctx, readerID, 10)
+	if err != nil || slices.ContainsFunc(history, func(e models.HistoryEntry) bool { return e.PostID == older }) {
+		t.Fatalf("GetViewHistory after purge = %+v, %v", history, err)
+	}
+
+	if err := store.Users.SetHistoryEnabled(ctx, readerID, false); err != nil {
+		t.Fatal(err)
+	}
+	if enabled, err := store.Users.HistoryEnabled(ctx, readerID); err != nil || enabled {
+		t.Fatalf("HistoryEnabled after opt-out = %v, %v", enabled, err)
+	}
+	if err := store.Users.MarkPostViewed(ctx, readerID, newer, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if history, err := store.Users.GetViewHistory(ctx, readerID, 10); err != nil || len(history) != 0 {
+		t.Errorf("GetViewHistory after opt-out = %+v, %v", history, err)
+	}
+	if unread, err := store.Posts.GetPosts(ctx, readerID, FeedQuery{Filter: "unread", ShowHidden: true}); err != nil || len(unread) != 0 {
+		t.Errorf("unread after opt-out = %d, %v, want none", len(unread), err)
+	}
+	if err := store.Users.SetHistoryEnabled(ctx, readerID, true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testShortLinks проверяет, что у поста одна постоянная короткая ссылка и переходы по ней считаются.
+func testShortLinks(t *testing.T, store *Store) {
+	ctx := context.Background()
+	posts, err := store.Posts.GetPosts(ctx, 0, FeedQuery{Filter: "new", ShowHidden: true})
+	if err != nil || len(posts) == 0 {
+		t.Fatalf("GetPosts = %d posts, %v", len(posts), err)
+	}
+	postID := posts[0].ID
+
+	link, err := EnsureShortLink(ctx, store.DB, postID)
+	if err != nil || len(link.Code) != shortCodeLength || link.Clicks != 0 {
+		t.Fatalf("EnsureShortLink = %+v, %v", link, err)
+	}
+	if again, err := EnsureShortLink(ctx, store.DB, postID); err != nil || again.Code != link.Code {
+		t.Fatalf("EnsureShortLink again = %+v, %v, want code %q", again, err, link.Code)
+	}
+	if _, err := EnsureShortLink(ctx, store.DB, 1<<30); err != sql.ErrNoRows {
+		t.Errorf("EnsureShortLink(missing post) error = %v, want sql.ErrNoRows", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if got, err := ResolveShortLink(ctx, store.DB, link.Code); err != nil || got != postID {
+			t.Fatalf("ResolveShortLink = %d, %v, want %d", got, err, postID)
+		}
+	}
+	if link, err = EnsureShortLink(ctx, store.DB, postID); err != nil || link.Clicks != 2 {
+		t.Errorf("clicks = %d, %v, want 2", link.Clicks, err)
+	}
+	if _, err := ResolveShortLink(ctx, store.DB, strings.ToUpper(link.Code)+"x"); err != sql.ErrNoRows {
+		t.Errorf("ResolveShortLink(unknown) error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// testPostTranslations проверяет, что перевод хранится по языку и не отдаётся для изменённого поста.
+func testPostTranslations(t *testing.T, store *Store) {
+	ctx := context.Background()
+	posts, err := store.Posts.GetPosts(ctx, 0, FeedQuery{Filter: "new", ShowHidden: true})
+	if err != nil || len(posts) == 0 {
+		t.Fatalf("GetPosts = %d posts, %v", len(posts), err)
+	}
+	postID := posts[0].ID
+
+	if _, err := GetPostTranslation(ctx, store.DB, postID, "ru", "v1"); err != sql.ErrNoRows {
+		t.Fatalf("GetPostTranslation before saving error = %v, want sql.ErrNoRows", err)
+	}
+	for _, tr := range []models.PostTranslation{
+		{PostID: postID, Lang: "ru", SourceHash: "v1", Title: "Заголовок", Content: "Текст", Provider: "test"},
+		{PostID: postID, Lang: "ru", SourceHash: "v2", Title: "Новый заголовок", Content: "Новый текст", Provider: "test"},
+		{PostID: postID, Lang: "en", SourceHash: "v2", Title: "Title", Content: "Text", Provider: "test"},
+	} {
+		if err := SavePostTranslation(ctx, store.DB, tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, err := GetPostTranslation(ctx, store.DB, postID, "ru", "v2"); err != nil || got.Title != "Новый заголовок" || got.Provider != "test" {
+		t.Errorf("GetPostTranslation(ru) = %+v, %v", got, err)
+	}
+	if _, err := GetPostTranslation(ctx, store.DB, postID, "ru", "v1"); err != sql.ErrNoRows {
+		t.Errorf("GetPostTranslation(stale) error = %v, want sql.ErrNoRows", err)
+	}
+	if got, err := GetPostTranslation(ctx, store.DB, postID, "en", "v2"); err != nil || got.Content != "Text" {
+		t.Errorf("GetPostTranslation(en) = %+v, %v", got, err)
+	}
+}
+
+// testModeration проверяет, что скрытый проверкой пост не виден и не стирается очисткой, пока его не одобрит модератор.
+func testModeration(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := store.Posts.CreatePost(ctx, userID, board.ID, "Held post", "Suspicious text", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	postID := int(id)
+	err = RecordScreening(ctx, store.DB, models.ModerationDecision{
+		Kind: "post", ItemID: postID, UserID: userID, Provider: "test", Score: 0.95, Categories: "spam", Status: ModerationPending,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Posts.GetPostOwnerID(ctx, postID); err != sql.ErrNoRows {
+		t.Fatalf("GetPostOwnerID(held) error = %v, want sql.ErrNoRows", err)
+	}
+
+	if _, err := store.DB.ExecContext(ctx, "UPDATE posts SET deleted_at = ? WHERE id = ?", time.Now().Add(-48*time.Hour), postID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Posts.PurgeDeletedContent(ctx, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	var exists bool
+	if err := store.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM posts WHERE id = ?)", postID).Scan(&exists); err != nil || !exists {
+		t.Fatalf("held post purged: exists = %v, %v", exists, err)
+	}
+
+	pending, err := ListModerationDecisions(ctx, store.DB, userID, true, true, 10)
+	if err != nil || len(pending) != 1 || pending[0].ItemID != postID || pending[0].Title != "Held post" || pending[0].Categories != "spam" {
+		t.Fatalf("ListModerationDecisions(pending) = %+v, %v", pending, err)
+	}
+	if own, err := ListModerationDecisions(ctx, store.DB, userID, false, true, 10); err != nil || len(own) != 1 {
+		t.Errorf("ListModerationDecisions(board moderator) = %+v, %v, want the held post", own, err)
+	}
+	if other, err := ListModerationDecisions(ctx, store.DB, userID+1000, false, true, 10); err != nil || len(other) != 0 {
+		t.Errorf("ListModerationDecisions(not a moderator) = %+v, %v, want none", other, err)
+	}
+
+	if err := ResolveModeration(ctx, store.DB, pending[0].ID, userID, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Posts.GetPostOwnerID(ctx, postID); err != nil {
+		t.Errorf("GetPostOwnerID(approved) error = %v", err)
+	}
+	if err := ResolveModeration(ctx, store.DB, pending[0].ID, userID, false); err != sql.ErrNoRows {
+		t.Errorf("ResolveModeration(resolved) error = %v, want sql.ErrNoRows", err)
+	}
+	recent, err := ListModerationDecisions(ctx, store.DB, userID, true, false, 10)
+	if err != nil || len(recent) == 0 || recent[0].Status != ModerationApproved || recent[0].ReviewedBy == "" {
+		t.Errorf("ListModerationDecisions(recent) = %+v, %v", recent, err)
+	}
+}
+
+// testThreadSummaries проверяет, что сводка обсуждения перестаёт действовать с новым комментарием,
+// а её создание меняет версию страницы поста.
+func testThreadSummaries(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := store.Posts.CreatePost(ctx, userID, board.ID, "Long thread", "Discuss", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	postID := int(id)
+	commentID, err := store.Comments.CreateComment(ctx, postID, userID, "First", time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := GetThreadSummary(ctx, store.DB, postID)
+	if err != nil || s.Summary != "" || s.CommentCount != 1 || s.LastCommentID != int(commentID) {
+		t.Fatalf("GetThreadSummary before saving = %+v, %v", s, err)
+	}
+	before, err := store.Posts.GetPostVersion(ctx, postID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Summary, s.Provider = "One comment so far.", "test"
+	if err := SaveThreadSummary(ctx, store.DB, s); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := GetThreadSummary(ctx, store.DB, postID); err != nil || got.Summary != "One comment so far." || got.CreatedAt.IsZero() {
+		t.Errorf("GetThreadSummary = %+v, %v", got, err)
+	}
+	if after, err := store.Posts.GetPostVersion(ctx, postID); err != nil || after.Fingerprint == before.Fingerprint {
+		t.Errorf("GetPostVersion after summary = %+v, %v, want a new fingerprint", after, err)
+	}
+
+	if _, err := store.Comments.CreateComment(ctx, postID, userID, "Second", time.Now().Format("2006-01-02 15:04:05")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := GetThreadSummary(ctx, store.DB, postID); err != nil || got.Summary != "" || got.CommentCount != 2 {
+		t.Errorf("GetThreadSummary after a new comment = %+v, %v, want no summary", got, err)
+	}
+}
+
+// testVoteReport проверяет счёт недавних голосов и отчёт о голосах новых аккаунтов и взаимных лайках.
+func testVoteReport(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := store.Posts.CreatePost(ctx, userID, board.ID, "Brigaded post", "Vote for me", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	postID := int(id)
+
+	var voters []int
+	for _, name := range []string{"Sock1", "Sock2"} {
+		email := strings.ToLower(name) + "@example.com"
+		if err := store.Users.RegisterUser(ctx, email, name, "hash"); err != nil {
+			t.Fatal(err)
+		}
+		voterID, _, _, _, err := store.Users.GetUserByEmail(ctx, email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Votes.SetPostLike(ctx, voterID, postID); err != nil {
+			t.Fatal(err)
+		}
+		voters = append(voters, voterID)
+	}
+	sockPost, err := store.Posts.CreatePost(ctx, voters[0], board.ID, "Sock post", "Thanks", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Votes.SetPostLike(ctx, userID, int(sockPost)); err != nil {
+		t.Fatal(err)
+	}
+
+	since := time.Now().Add(-time.Hour)
+	if n, err := store.Votes.CountRecentVotes(ctx, voters[0], since); err != nil || n != 1 {
+		t.Errorf("CountRecentVotes = %d, %v, want 1", n, err)
+	}
+	if n, err := store.Votes.CountRecentVotes(ctx, voters[0], time.Now().Add(time.Hour)); err != nil || n != 0 {
+		t.Errorf("CountRecentVotes(future) = %d, %v, want 0", n, err)
+	}
+
+	report, err := GetVoteReport(ctx, store.DB, since, 72*time.Hour, 1, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := slices.IndexFunc(report.NewAccountVotes, func(s models.VoteSuspect) bool { return s.UserID == userID })
+	if i < 0 || report.NewAccountVotes[i].Likes < 2 || report.NewAccountVotes[i].Voters < 2 || report.NewAccountVotes[i].Percent <= 0 {
+		t.Errorf("GetVoteReport NewAccountVotes = %+v, want the brigaded author", report.NewAccountVotes)
+	}
+	if !slices.ContainsFunc(report.Rings, func(r models.VoteRing) bool {
+		return (r.UserID == userID && r.OtherID == voters[0]) || (r.UserID == voters[0] && r.OtherID == userID)
+	}) {
+		t.Errorf("GetVoteReport Rings = %+v, want the mutual pair", report.Rings)
+	}
+	if old, err := GetVoteReport(ctx, store.DB, since, 0, 1, 50); err != nil || len(old.NewAccountVotes) != 0 {
+		t.Errorf("GetVoteReport without new accounts = %+v, %v", old.NewAccountVotes, err)
+	}
+}
+
+// testBestDecay проверяет, что с затуханием рейтинга свежий пост обгоняет старый популярный,
+// а страницы ленты «Лучшие» совпадают с полной лентой.
+func testBestDecay(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldID, err := store.Posts.CreatePost(ctx, userID, board.ID, "Old favourite", "Popular long ago", "", time.Now().AddDate(0, -2, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	freshID, err := store.Posts.CreatePost(ctx, userID, board.ID, "Fresh post", "Just posted", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, likes := range map[int64]int{oldID: 1000, freshID: 2} {
+		if _, err := store.DB.ExecContext(ctx, "UPDATE posts SET likes = ?, dislikes = 0 WHERE id = ?", likes, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	order := func(posts []models.PostData) (old, fresh int) {
+		old = slices.IndexFunc(posts, func(p models.PostData) bool { return p.ID == int(oldID) })
+		fresh = slices.IndexFunc(posts, func(p models.PostData) bool { return p.ID == int(freshID) })
+		return old, fresh
+	}
+
+	if err := store.Settings.SetSetting(ctx, SettingBestHalfLife, "24"); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Settings.SetSetting(ctx, SettingBestHalfLife, "")
+	all, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: "best", ShowHidden: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old, fresh := order(all); old < 0 || fresh < 0 || fresh > old {
+		t.Fatalf("GetPosts(best, decay): old at %d, fresh at %d, want fresh first", old, fresh)
+	}
+	var paged []models.PostData
+	cursor := ""
+	for {
+		page, next, err := store.Posts.GetFeedPage(ctx, userID, FeedQuery{Filter: "best", ShowHidden: true}, 2, cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paged = append(paged, page...)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if len(paged) != len(all) || !slices.EqualFunc(paged, all, func(a, b models.PostData) bool { return a.ID == b.ID }) {
+		t.Errorf("GetFeedPage(best, decay) pages differ from GetPosts: %d vs %d posts", len(paged), len(all))
+	}
+
+	if err := store.Settings.SetSetting(ctx, SettingBestHalfLife, ""); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: "best", ShowHidden: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old, fresh := order(raw); old > fresh {
+		t.Errorf("GetPosts(best) without decay: old at %d, fresh at %d, want old first", old, fresh)
+	}
+}
+
+// testFeedConditions проверяет, что любой фильтр ленты сочетается с категорией, автором и периодом.
+func testFeedConditions(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Users.RegisterUser(ctx, "combo@example.com", "Combo", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	authorID, _, _, _, err := store.Users.GetUserByEmail(ctx, "combo@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gamesID, err := store.Posts.GetCategoryIDByName(ctx, "games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, createdAt := range []time.Time{time.Now().AddDate(0, 0, -10), time.Now()} {
+		id, err := store.Posts.CreatePost(ctx, authorID, board.ID, "Combo", "Body", "", createdAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Posts.AddPostCategory(ctx, id, gamesID); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, int(id))
+	}
+	old, fresh := ids[0], ids[1]
+	if err := store.Votes.SetPostLike(ctx, userID, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Votes.SetPostDislike(ctx, userID, fresh); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Comments.CreateComment(ctx, fresh, userID, "Hi", time.Now().Format("2006-01-02 15:04:05")); err != nil {
+		t.Fatal(err)
+	}
+
+	week := time.Now().AddDate(0, 0, -5)
+	tests := []struct {
+		q    FeedQuery
+		want []int
+	}{
+		{FeedQuery{Filter: "liked", Category: "games", Author: "COMBO"}, []int{old}},
+		{FeedQuery{Filter: "disliked", Category: "games", Author: "combo"}, []int{fresh}},
+		{FeedQuery{Filter: "commented", Category: "games", Author: "combo", From: week}, []int{fresh}},
+		{FeedQuery{Filter: "my-comments", Author: "combo", To: week}, nil},
+		{FeedQuery{Filter: "liked", Category: "news", Author: "combo"}, nil},
+		{FeedQuery{Filter: "new", Author: "combo", From: week}, []int{fresh}},
+		{FeedQuery{Filter: "best", Board: DefaultBoard, Author: "combo", To: week}, []int{old}},
+		{FeedQuery{Filter: "unanswered", Category: "games", Author: "combo", ShowHidden: true}, []int{old}},
+	}
+	for _, tt := range tests {
+		posts, err := store.Posts.GetPosts(ctx, userID, tt.q)
+		if err != nil {
+			t.Fatalf("GetPosts(%+v) = %v", tt.q, err)
+		}
+		page, _, err := store.Posts.GetFeedPage(ctx, userID, tt.q, 10, "")
+		if err != nil {
+			t.Fatalf("GetFeedPage(%+v) = %v", tt.q, err)
+		}
+		if !slices.Equal(postIDs(posts), tt.want) || !slices.Equal(postIDs(page), tt.want) {
+			t.Errorf("feed %+v = %v and %v, want %v", tt.q, postIDs(posts), postIDs(page), tt.want)
+		}
+	}
+	if _, err := store.Posts.GetPosts(ctx, userID, FeedQuery{Filter: "bogus"}); !errors.Is(err, ErrUnknownFilter) {
+		t.Errorf("GetPosts(bogus) = %v, want ErrUnknownFilter", err)
+	}
+}
+
+// postIDs возвращает ID постов по порядку; для пустого списка — nil.
+func postIDs(posts []models.PostData) []int {
+	var ids []int
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+// testVoteNotifications проверяет сборку лайков за день в уведомления авторам и их прочтение.
+func testVoteNotifications(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := store.Posts.CreatePost(ctx, userID, board.ID, "Liked post", "Like me", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	postID := int(id)
+	id, err = store.Comments.CreateComment(ctx, postID, userID, "Liked comment", time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	commentID := int(id)
+
+	var fans []int
+	for _, name := range []string{"Fan1", "Fan2"} {
+		email := strings.ToLower(name) + "@example.com"
+		if err := store.Users.RegisterUser(ctx, email, name, "hash"); err != nil {
+			t.Fatal(err)
+		}
+		fanID, _, _, _, err := store.Users.GetUserByEmail(ctx, email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Votes.SetPostLike(ctx, fanID, postID); err != nil {
+			t.Fatal(err)
+		}
+		fans = append(fans, fanID)
+	}
+	if err := store.Votes.SetCommentLike(ctx, fans[0], commentID); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Votes.SetCommentDislike(ctx, fans[1], commentID); err != nil {
+		t.Fatal(err)
+	}
+	// Лайк собственного поста в уведомление не попадает.
+	if err := store.Votes.SetPostLike(ctx, userID, postID); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := BuildVoteNotifications(ctx, store.DB, time.Now()); err != nil || n == 0 {
+		t.Fatalf("BuildVoteNotifications = %d, %v", n, err)
+	}
+	if n, err := BuildVoteNotifications(ctx, store.DB, time.Now()); err != nil || n != 0 {
+		t.Errorf("second BuildVoteNotifications = %d, %v, want 0", n, err)
+	}
+	if n, err := BuildVoteNotifications(ctx, store.DB, time.Now().AddDate(0, 0, -1)); err != nil || n != 0 {
+		t.Errorf("BuildVoteNotifications(yesterday) = %d, %v, want 0", n, err)
+	}
+
+	list, err := GetNotifications(ctx, store.DB, userID, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	find := func(kind string, itemID int) *models.Notification {
+		for i := range list {
+			if list[i].Kind == kind && list[i].ItemID == itemID {
+				return &list[i]
+			}
+		}
+		return nil
+	}
+	today := time.Now().Format(NotificationDayFormat)
+	if n := find(NotificationPostLikes, postID); n == nil || n.Likes != 2 || n.Title != "Liked post" || n.Day != today || n.Read {
+		t.Errorf("post notification = %+v, want 2 unread likes today", n)
+	}
+	if n := find(NotificationCommentLikes, commentID); n == nil || n.Likes != 1 || n.PostID != postID {
+		t.Errorf("comment notification = %+v, want 1 like", n)
+	}
+	if others, err := GetNotifications(ctx, store.DB, fans[0], 50); err != nil || len(others) != 0 {
+		t.Errorf("fan notifications = %+v, %v, want none", others, err)
+	}
+
+	if err := MarkNotificationsRead(ctx, store.DB, userID); err != nil {
+		t.Fatal(err)
+	}
+	if list, err = GetNotifications(ctx, store.DB, userID, 50); err != nil {
+		t.Fatal(err)
+	}
+	if n := find(NotificationPostLikes, postID); n == nil || !n.Read {
+		t.Errorf("after MarkNotificationsRead = %+v, want read", n)
+	}
+}
+
+// testInvites проверяет ограничение числа приглашений, однократную регистрацию по приглашению и дерево приглашений.
+func testInvites(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	code, err := CreateInvite(ctx, store.DB, userID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateInvite(ctx, store.DB, userID, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateInvite(ctx, store.DB, userID, 2); err != ErrInviteLimit {
+		t.Errorf("third invite: err = %v, want ErrInviteLimit", err)
+	}
+
+	if err := RegisterInvitedUser(ctx, store.DB, code, "guest@example.com", "Guest", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterInvitedUser(ctx, store.DB, code, "guest2@example.com", "Guest2", "hash"); err != ErrInvalidInvite {
+		t.Errorf("reused invite: err = %v, want ErrInvalidInvite", err)
+	}
+	if exists, err := store.Users.EmailExists(ctx, "guest2@example.com"); err != nil || exists {
+		t.Errorf("user registered with a used invite: %v, %v", exists, err)
+	}
+
+	invites, err := ListInvites(ctx, store.DB, userID)
+	if err != nil || len(invites) != 2 {
+		t.Fatalf("ListInvites = %+v, %v", invites, err)
+	}
+	used := 0
+	for _, i := range invites {
+		if i.Used {
+			used++
+			if i.Invitee != "Guest" {
+				t.Errorf("used invite = %+v, want invitee Guest", i)
+			}
+		}
+	}
+	if used != 1 {
+		t.Errorf("%d used invites, want 1", used)
+	}
+
+	tree, err := InviteTree(ctx, store.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree) != 1 || tree[0].UserID != userID || len(tree[0].Children) != 1 || tree[0].Children[0].Username != "Guest" {
+		t.Errorf("InviteTree = %+v", tree)
+	}
+}
+
+// testUploadReferences проверяет поиск ссылок на загруженный файл в изображениях и текстах постов и в комментариях.
+func testUploadReferences(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	postID, err := store.Posts.CreatePost(ctx, userID, board.ID, "Upload", "See /uploads/posts/inline.png", "/uploads/posts/image.png", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Comments.CreateComment(ctx, int(postID), userID, "Also /uploads/posts/comment.png", time.Now().Format("2006-01-02 15:04:05")); err != nil {
+		t.Fatal(err)
+	}
+	for url, want := range map[string]bool{
+		"/uploads/posts/image.png":   true,
+		"/uploads/posts/inline.png":  true,
+		"/uploads/posts/comment.png": true,
+		"/uploads/posts/orphan.png":  false,
+		"/uploads/posts/imag_.png":   false,
+	} {
+		if got, err := UploadReferenced(ctx, store.DB, url); err != nil || got != want {
+			t.Errorf("UploadReferenced(%q) = %v, %v, want %v", url, got, err, want)
+		}
+	}
+}
+
+// testCommentPolicies проверяет подписки и правила комментирования поста автора authorID.
+func testCommentPolicies(t *testing.T, store *Store, authorID int) {
+	ctx := context.Background()
+	if err := store.Users.RegisterUser(ctx, "follower@example.com", "Follower", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	followerID, _, _, _, err := store.Users.GetUserByEmail(ctx, "follower@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	postID, err := store.Posts.CreatePost(ctx, authorID, board.ID, "Policy", "Who may comment", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post, err := store.Posts.GetPostByID(ctx, int(postID), authorID); err != nil || post.CommentPolicy != CommentsEveryone {
+		t.Fatalf("default comment policy = %q, %v", post.CommentPolicy, err)
+	}
+	if ok, _, err := CanComment(ctx, store.DB, int(postID), followerID); err != nil || !ok {
+		t.Errorf("CanComment on an open post = %v, %v", ok, err)
+	}
+
+	if err := store.Posts.SetPostCommentPolicy(ctx, int(postID), CommentsFollowers); err != nil {
+		t.Fatal(err)
+	}
+	if ok, policy, err := CanComment(ctx, store.DB, int(postID), followerID); err != nil || ok || policy != CommentsFollowers {
+		t.Errorf("CanComment before following = %v, %q, %v", ok, policy, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := Follow(ctx, store.DB, followerID, authorID, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := CountFollowers(ctx, store.DB, authorID); err != nil || n != 1 {
+		t.Errorf("CountFollowers after following twice = %d, %v", n, err)
+	}
+	if ok, _, err := CanComment(ctx, store.DB, int(postID), followerID); err != nil || !ok {
+		t.Errorf("CanComment as a follower = %v, %v", ok, err)
+	}
+
+	if err := store.Posts.SetPostCommentPolicy(ctx, int(postID), CommentsNobody); err != nil {
+		t.Fatal(err)
+	}
+	if ok, policy, err := CanComment(ctx, store.DB, int(postID), followerID); err != nil || ok || policy != CommentsNobody {
+		t.Errorf("CanComment on a closed post = %v, %q, %v", ok, policy, err)
+	}
+	if ok, _, err := CanComment(ctx, store.DB, int(postID), authorID); err != nil || !ok {
+		t.Errorf("CanComment as the author of a closed post = %v, %v", ok, err)
+	}
+	if _, _, err := CanComment(ctx, store.DB, 1<<30, followerID); err != sql.ErrNoRows {
+		t.Errorf("CanComment on a missing post: err = %v, want sql.ErrNoRows", err)
+	}
+
+	if err := Follow(ctx, store.DB, followerID, authorID, false); err != nil {
+		t.Fatal(err)
+	}
+	if following, err := IsFollowing(ctx, store.DB, followerID, authorID); err != nil || following {
+		t.Errorf("IsFollowing after unfollowing = %v, %v", following, err)
+	}
+	if err := Follow(ctx, store.DB, followerID, 1<<30, true); err != nil {
+		t.Fatal(err)
+	}
+	if following, err := IsFollowing(ctx, store.DB, followerID, 1<<30); err != nil || following {
+		t.Errorf("following a missing user = %v, %v", following, err)
+	}
+}
+
+// testOAuthAccounts проверяет привязку учётных записей GitHub к существующему пользователю userID и к новому.
+func testOAuthAccounts(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	if _, _, err := GetOAuthUser(ctx, store.DB, "github", "1"); err != sql.ErrNoRows {
+		t.Fatalf("GetOAuthUser of an unlinked account: err = %v", err)
+	}
+	if err := LinkOAuthAccount(ctx, store.DB, "github", "1", userID); err != nil {
+		t.Fatal(err)
+	}
+	if id, role, err := GetOAuthUser(ctx, store.DB, "github", "1"); err != nil || id != userID || role != "user" {
+		t.Errorf("GetOAuthUser = %d, %q, %v", id, role, err)
+	}
+	if err := LinkOAuthAccount(ctx, store.DB, "github", "1", userID); err == nil {
+		t.Error("the same GitHub account was linked twice")
+	}
+
+	newID, err := RegisterOAuthUser(ctx, store.DB, "github", "2", "octo@example.com", "octocat", "The Octocat", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _, err := GetOAuthUser(ctx, store.DB, "github", "2"); err != nil || id != newID {
+		t.Errorf("GetOAuthUser of a registered account = %d, %v; want %d", id, err, newID)
+	}
+	if name, err := GetDisplayName(ctx, store.DB, newID); err != nil || name != "The Octocat" {
+		t.Errorf("display name = %q, %v", name, err)
+	}
+	// Занятое имя откатывает создание пользователя вместе с привязкой.
+	if _, err := RegisterOAuthUser(ctx, store.DB, "github", "3", "other@example.com", "octocat", "", "hash"); err == nil {
+		t.Error("RegisterOAuthUser accepted a taken username")
+	}
+	if _, _, err := GetOAuthUser(ctx, store.DB, "github", "3"); err != sql.ErrNoRows {
+		t.Errorf("failed registration left a linked account: err = %v", err)
+	}
+}
+
+// testEmailVerification проверяет отметку о подтверждении почты пользователя userID.
+func testEmailVerification(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	if _, verified, err := GetEmailVerification(ctx, store.DB, userID); err != nil || !verified {
+		t.Fatalf("existing user: verified = %v, %v", verified, err)
+	}
+	if err := SetEmailVerified(ctx, store.DB, userID, false); err != nil {
+		t.Fatal(err)
+	}
+	if email, verified, err := GetEmailVerification(ctx, store.DB, userID); err != nil || verified || email == "" {
+		t.Errorf("after SetEmailVerified(false) = %q, %v, %v", email, verified, err)
+	}
+	if err := SetEmailVerified(ctx, store.DB, userID, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := GetEmailVerification(ctx, store.DB, 1<<30); err != sql.ErrNoRows {
+		t.Errorf("missing user: err = %v", err)
+	}
+}
+
+// testManageUserContent проверяет пакетные операции пользователя userID над своими постами и комментариями
+// и отказ, если среди выбранных есть чужие.
+func testManageUserContent(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Users.RegisterUser(ctx, "stranger@example.com", "Stranger", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	strangerID, _, _, _, err := store.Users.GetUserByEmail(ctx, "stranger@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mine []int
+	for _, title := range []string{"Bulk one", "Bulk two"} {
+		id, err := store.Posts.CreatePost(ctx, userID, board.ID, title, "Body", "", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		mine = append(mine, int(id))
+	}
+	foreign, err := store.Posts.CreatePost(ctx, strangerID, board.ID, "Not yours", "Body", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Чужой пост в выборке отменяет действие целиком.
+	if err := store.Posts.SetUserPostsUnpublished(ctx, userID, append(mine, int(foreign)), true); err != ErrNotOwner {
+		t.Fatalf("unpublish with a foreign post: err = %v, want ErrNotOwner", err)
+	}
+	if post, err := store.Posts.GetPostByID(ctx, mine[0], userID); err != nil || post.ID != mine[0] {
+		t.Fatalf("post after rejected unpublish = %+v, %v", post, err)
+	}
+
+	if err := store.Posts.SetUserPostsUnpublished(ctx, userID, mine, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Posts.GetPostByID(ctx, mine[0], userID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("unpublished post is visible: err = %v", err)
+	}
+	managed, err := store.Posts.ListManagedPosts(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unpublished := 0
+	for _, p := range managed {
+		if p.Unpublished {
+			unpublished++
+		}
+	}
+	if unpublished != 2 {
+		t.Errorf("ListManagedPosts shows %d unpublished posts, want 2", unpublished)
+	}
+	// Снятые с публикации посты не удаляются при очистке, даже если давно скрыты.
+	if _, err := store.DB.ExecContext(ctx, "UPDATE posts SET deleted_at = '2000-01-01 00:00:00' WHERE id = ?", mine[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Posts.PurgeDeletedContent(ctx, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Posts.SetUserPostsUnpublished(ctx, userID, mine, false); err != nil {
+		t.Fatalf("publish again: %v", err)
+	}
+	if _, err := store.Posts.GetPostByID(ctx, mine[0], userID); err != nil {
+		t.Errorf("published post: err = %v", err)
+	}
+
+	catID, err := store.Posts.GetCategoryIDByName(ctx, "science")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Posts.SetUserPostsCategories(ctx, userID, mine, []int{catID}); err != nil {
+		t.Fatal(err)
+	}
+	categories, err := GetPostCategoriesByPostIDs(ctx, store.DB, mine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range mine {
+		if len(categories[id]) != 1 || categories[id][0].Name != "science" {
+			t.Errorf("categories of post %d = %+v", id, categories[id])
+		}
+	}
+
+	if err := store.Posts.DeleteUserPosts(ctx, userID, []int{int(foreign)}); err != ErrNotOwner {
+		t.Errorf("delete a foreign post: err = %v, want ErrNotOwner", err)
+	}
+	if err := store.Posts.DeleteUserPosts(ctx, userID, mine); err != nil {
+		t.Fatal(err)
+	}
+	if managed, err := store.Posts.ListManagedPosts(ctx, userID); err != nil || slices.ContainsFunc(managed, func(p models.PostData) bool { return p.ID == mine[0] }) {
+		t.Errorf("deleted post is still managed: %v", err)
+	}
+
+	comment, err := store.Comments.CreateComment(ctx, int(foreign), userID, "Mine", time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := store.Comments.CreateComment(ctx, int(foreign), strangerID, "Theirs", time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comments, err := store.Comments.ListUserComments(ctx, userID, 10); err != nil || len(comments) == 0 || comments[0].PostTitle != "Not yours" {
+		t.Errorf("ListUserComments = %+v, %v", comments, err)
+	}
+	if err := store.Comments.DeleteUserComments(ctx, userID, []int{int(comment), int(other)}); err != ErrNotOwner {
+		t.Errorf("delete a foreign comment: err = %v, want ErrNotOwner", err)
+	}
+	if err := store.Comments.DeleteUserComments(ctx, userID, []int{int(comment)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Comments.DeleteUserComments(ctx, userID, []int{int(comment)}); err != ErrNotOwner {
+		t.Errorf("delete an already deleted comment: err = %v, want ErrNotOwner", err)
+	}
+}
+
+// testCategoryStats проверяет число опубликованных постов и последнюю активность категорий: удалённые посты
+// не считаются, а комментарий продвигает активность категорий своего поста.
+func testCategoryStats(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	stats := func(name string) models.Category {
+		t.Helper()
+		categories, err := store.Categories.ListCategories(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range categories {
+			if c.Name == name {
+				return c
+			}
+		}
+		t.Fatalf("category %q not listed", name)
+		return models.Category{}
+	}
+	before := stats("games")
+
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	catID, err := store.Posts.GetCategoryIDByName(ctx, "games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	posted := time.Now().Add(-3 * time.Hour).Truncate(time.Second)
+	var ids []int
+	for _, title := range []string{"Charades", "Snowball bingo"} {
+		id, err := store.Posts.CreatePost(ctx, userID, board.ID, title, "Rules", "", posted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Posts.AddPostCategory(ctx, id, catID); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, int(id))
+	}
+	if got := stats("games"); got.PostCount != before.PostCount+2 || got.LastActivity.Before(posted) {
+		t.Errorf("after two posts: %d posts, active %v; want %d, not before %v", got.PostCount, got.LastActivity, before.PostCount+2, posted)
+	}
+
+	commented := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if _, err := store.Comments.CreateComment(ctx, ids[0], userID, "Count me in", commented.Format("2006-01-02 15:04:05")); err != nil {
+		t.Fatal(err)
+	}
+	if got := stats("games"); !got.LastActivity.Equal(commented) {
+		t.Errorf("after a comment: active %v, want %v", got.LastActivity, commented)
+	}
+
+	if err := store.Posts.DeletePost(ctx, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if got := stats("games"); got.PostCount != before.PostCount+1 {
+		t.Errorf("after deleting a post: %d posts, want %d", got.PostCount, before.PostCount+1)
+	}
+}
+
+// testPasswordResets проверяет ссылки сброса пароля пользователя userID: срок действия, замену прежней ссылки
+// новой и одноразовость.
+func testPasswordResets(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	now := time.Now()
+	if err := CreatePasswordReset(ctx, store.DB, userID, "old", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreatePasswordReset(ctx, store.DB, userID, "new", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetPasswordReset(ctx, store.DB, "old", now); err != ErrResetInvalid {
+		t.Errorf("replaced link: err = %v, want ErrResetInvalid", err)
+	}
+	if id, err := GetPasswordReset(ctx, store.DB, "new", now); err != nil || id != userID {
+		t.Errorf("GetPasswordReset = %d, %v; want %d", id, err, userID)
+	}
+	if _, err := ResetPassword(ctx, store.DB, "new", "new-hash", now.Add(2*time.Hour)); err != ErrResetInvalid {
+		t.Errorf("expired link: err = %v, want ErrResetInvalid", err)
+	}
+
+	if id, err := ResetPassword(ctx, store.DB, "new", "new-hash", now); err != nil || id != userID {
+		t.Fatalf("ResetPassword = %d, %v; want %d", id, err, userID)
+	}
+	var hash string
+	if err := store.DB.QueryRowContext(ctx, "SELECT password FROM users WHERE id = ?", userID).Scan(&hash); err != nil || hash != "new-hash" {
+		t.Errorf("password after reset = %q, %v", hash, err)
+	}
+	if _, err := ResetPassword(ctx, store.DB, "new", "other-hash", now); err != ErrResetInvalid {
+		t.Errorf("used link: err = %v, want ErrResetInvalid", err)
+	}
+}
+
+// testSiteSettings проверяет оформление сайта: чтение из настроек одним запросом и разбор ссылок подвала.
+func testSiteSettings(t *testing.T, store *Store) {
+	ctx := context.Background()
+	if site, err := GetSite(ctx, store.DB); err != nil || site.Title != "" || site.FooterLinks != nil {
+		t.Errorf("default site = %+v, %v; want empty", site, err)
+	}
+
+	links, ok := ParseFooterLinks("Rules | /pages/rules\n\n  Blog|https://blog.example.com/  \n")
+	want := []models.FooterLink{{Label: "Rules", URL: "/pages/rules"}, {Label: "Blog", URL: "https://blog.example.com/"}}
+	if !ok || !slices.Equal(links, want) {
+		t.Fatalf("ParseFooterLinks = %+v, %v; want %+v", links, ok, want)
+	}
+	for _, bad := range []string{"No address", "Evil | javascript:alert(1)", "Other host | //evil.example", " | /pages/faq",
+		strings.Repeat("x", 41) + " | /", strings.Repeat("A | /\n", 11)} {
+		if _, ok := ParseFooterLinks(bad); ok {
+			t.Errorf("ParseFooterLinks(%q) accepted", bad)
+		}
+	}
+
+	for name, value := range map[string]string{
+		SettingSiteTitle:       "School Forum",
+		SettingSiteDescription: "Class of 2027",
+		SettingSiteLogo:        "/uploads/site/logo.png",
+		SettingSiteFooterLinks: FormatFooterLinks(links),
+	} {
+		if err := store.Settings.SetSetting(ctx, name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	site, err := store.Settings.GetSite(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if site.Title != "School Forum" || site.Description != "Class of 2027" || site.Logo != "/uploads/site/logo.png" ||
+		!slices.Equal(site.FooterLinks, want) {
+		t.Errorf("GetSite = %+v", site)
+	}
+	for _, name := range []string{SettingSiteTitle, SettingSiteDescription, SettingSiteLogo, SettingSiteFooterLinks} {
+		if err := store.Settings.SetSetting(ctx, name, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// testPostShares проверяет суммы «Поделиться» за день: повторные переходы складываются в одну строку,
+// отчёт разбивает их по способам, дням и постам, а удалённый пост не засчитывается и пропадает из отчёта.
+func testPostShares(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	postID, err := store.Posts.CreatePost(ctx, userID, board.ID, "Shared post", "Body", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := int(postID)
+
+	day := time.Date(2031, 5, 10, 23, 30, 0, 0, time.UTC)
+	for _, share := range []struct {
+		channel string
+		at      time.Time
+	}{
+		{"telegram", day},
+		{"telegram", day.Add(10 * time.Minute)},
+		{ShareChannelShortLink, day},
+		{"telegram", day.Add(time.Hour)}, // следующий день по UTC
+	} {
+		if err := RecordPostShare(ctx, store.DB, id, share.channel, share.at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count, err := CountPostShares(ctx, store.DB, id); err != nil || count != 4 {
+		t.Errorf("CountPostShares = %d, %v; want 4", count, err)
+	}
+
+	report, err := GetShareReport(ctx, store.DB, day, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChannels := []models.ShareCount{{Channel: "telegram", Count: 3}, {Channel: ShareChannelShortLink, Count: 1}}
+	wantDays := []models.ShareDay{{Day: "2031-05-10", Count: 3}, {Day: "2031-05-11", Count: 1}}
+	if report.Total != 4 || !slices.Equal(report.Channels, wantChannels) || !slices.Equal(report.Days, wantDays) {
+		t.Errorf("GetShareReport = %+v", report)
+	}
+	if len(report.Posts) != 1 || report.Posts[0] != (models.SharedPost{PostID: id, Title: "Shared post", Count: 4}) {
+		t.Errorf("shared posts = %+v", report.Posts)
+	}
+	if report, err := GetShareReport(ctx, store.DB, day.AddDate(0, 0, 2), 10); err != nil || report.Total != 0 {
+		t.Errorf("report after the shares = %+v, %v", report, err)
+	}
+
+	if err := store.Posts.DeletePost(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordPostShare(ctx, store.DB, id, "telegram", day.AddDate(0, 0, 3)); err != sql.ErrNoRows {
+		t.Errorf("share of a deleted post: err = %v, want sql.ErrNoRows", err)
+	}
+	if report, err := GetShareReport(ctx, store.DB, day, 10); err != nil || len(report.Posts) != 0 {
+		t.Errorf("deleted post in the report: %+v, %v", report.Posts, err)
+	}
+}
+
+// testPostReports проверяет жалобы на пост: повторная жалоба отклоняется, вес жалоб считается по каждой причине
+// отдельно, пост скрывается в очередь /moderation, а после публикации модератором жалобы его больше не скрывают.
+func testPostReports(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := store.Posts.CreatePost(ctx, userID, board.ID, "Reported post", "Buy now", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	postID := int(id)
+
+	var reporters []int
+	for _, name := range []string{"Reporter1", "Reporter2", "Reporter3", "Reporter4"} {
+		email := strings.ToLower(name) + "@example.com"
+		if err := store.Users.RegisterUser(ctx, email, name, "hash"); err != nil {
+			t.Fatal(err)
+		}
+		reporterID, _, _, _, err := store.Users.GetUserByEmail(ctx, email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reporters = append(reporters, reporterID)
+	}
+
+	for _, report := range []struct {
+		user   int
+		reason string
+		weight int
+		hidden bool
+		err    error
+	}{
+		{reporters[0], ReportSpam, 1, false, nil},
+		{reporters[0], ReportAbuse, 1, false, ErrAlreadyReported},
+		{reporters[1], ReportAbuse, 2, false, nil}, // у другой причины свой счётчик
+		{reporters[2], ReportSpam, 2, true, nil},
+	} {
+		if hidden, err := ReportPost(ctx, store.DB, postID, report.user, report.reason, report.weight, 3); hidden != report.hidden || err != report.err {
+			t.Fatalf("ReportPost(%d, %s) = %v, %v; want %v, %v", report.user, report.reason, hidden, err, report.hidden, report.err)
+		}
+	}
+	if _, err := store.Posts.GetPostOwnerID(ctx, postID); err != sql.ErrNoRows {
+		t.Fatalf("GetPostOwnerID(reported) error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := ReportPost(ctx, store.DB, postID, reporters[3], ReportSpam, 1, 3); err != sql.ErrNoRows {
+		t.Errorf("report of a hidden post: err = %v, want sql.ErrNoRows", err)
+	}
+
+	pending, err := ListModerationDecisions(ctx, store.DB, userID, true, true, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := slices.IndexFunc(pending, func(d models.ModerationDecision) bool { return d.Kind == "post" && d.ItemID == postID })
+	if i < 0 || pending[i].Provider != ModerationProviderReports || pending[i].Categories != ReportSpam ||
+		pending[i].Score != 3 || pending[i].UserID != userID {
+		t.Fatalf("reported post in the queue = %+v", pending)
+	}
+	if err := ResolveModeration(ctx, store.DB, pending[i].ID, userID, true); err != nil {
+		t.Fatal(err)
+	}
+	if hidden, err := ReportPost(ctx, store.DB, postID, reporters[3], ReportSpam, 1, 1); hidden || err != nil {
+		t.Errorf("report after approval = %v, %v; want the post to stay published", hidden, err)
+	}
+	if _, err := ReportPost(ctx, store.DB, 999999, reporters[3], ReportSpam, 1, 1); err != sql.ErrNoRows {
+		t.Errorf("report of a missing post: err = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// testUserStanding проверяет показатели активности для уровня доверия: прочитанные посты (в том числе
+// без истории просмотров), свои посты и комментарии без удалённых.
+func testUserStanding(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	if err := store.Users.RegisterUser(ctx, "climber@example.com", "Climber", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	climberID, _, _, _, err := store.Users.GetUserByEmail(ctx, "climber@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	postID, err := store.Posts.CreatePost(ctx, userID, board.ID, "Read me", "Body", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Users.MarkPostViewed(ctx, climberID, int(postID), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	own, err := store.Posts.CreatePost(ctx, climberID, board.ID, "My post", "Body", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"First", "Second"} {
+		if _, err := store.Comments.CreateComment(ctx, int(postID), climberID, text, time.Now().Format("2006-01-02 15:04:05")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Posts.DeletePost(ctx, int(own)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Users.MarkPostViewed(ctx, climberID, int(postID), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	s, err := store.Users.GetUserStanding(ctx, climberID)
+	if err != nil || s.Read != 1 || s.Posts != 0 || s.Comments != 2 || s.Role != "user" || s.CreatedAt.IsZero() {
+		t.Errorf("GetUserStanding = %+v, %v", s, err)
+	}
+
+	// Прочитанные посты не теряются при удалении старых просмотров и отключении истории, продолжают
+	// считаться без неё, и каждый пост по-прежнему засчитывается один раз.
+	if _, err := PurgeViewHistory(ctx, store.DB, -time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Users.MarkPostViewed(ctx, climberID, int(postID), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := store.Users.GetUserStanding(ctx, climberID); err != nil || s.Read != 1 {
+		t.Errorf("GetUserStanding after reopening a purged view = %+v, %v, want 1 read", s, err)
+	}
+	if err := store.Users.SetHistoryEnabled(ctx, climberID, false); err != nil {
+		t.Fatal(err)
+	}
+	another, err := store.Posts.CreatePost(ctx, userID, board.ID, "Read me too", "Body", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := store.Users.MarkPostViewed(ctx, climberID, int(another), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s, err := store.Users.GetUserStanding(ctx, climberID); err != nil || s.Read != 2 {
+		t.Errorf("GetUserStanding after purge and without history = %+v, %v, want 2 read", s, err)
+	}
+
+	// Пользователь без истории с самого начала тоже не накручивает счётчик повторными просмотрами.
+	if err := store.Users.RegisterUser(ctx, "refresher@example.com", "Refresher", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	refresherID, _, _, _, err := store.Users.GetUserByEmail(ctx, "refresher@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Users.SetHistoryEnabled(ctx, refresherID, false); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := store.Users.MarkPostViewed(ctx, refresherID, int(postID), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s, err := store.Users.GetUserStanding(ctx, refresherID); err != nil || s.Read != 1 {
+		t.Errorf("GetUserStanding after viewing one post twice without history = %+v, %v, want 1 read", s, err)
+	}
+	if _, err := store.Users.GetUserStanding(ctx, 999999); err != sql.ErrNoRows {
+		t.Errorf("GetUserStanding(missing) error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// testLikers проверяет список лайкнувших пост и комментарий: порядок, постраничный вывод, дизлайки
+// и пользователей, скрывающих себя в таких списках.
+func testLikers(t *testing.T, store *Store, userID int) {
+	ctx := context.Background()
+	board, err := store.Boards.GetBoardBySlug(ctx, DefaultBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	postID, err := store.Posts.CreatePost(ctx, userID, board.ID, "Liked", "Body", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	commentID, err := store.Comments.CreateComment(ctx, int(postID), userID, "Liked comment", time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fans []int
+	for _, name := range []string{"LikerOne", "LikerTwo", "LikerThree", "LikerCritic"} {
+		email := strings.ToLower(name) + "@likers.example"
+		if err := store.Users.RegisterUser(ctx, email, name, "hash"); err != nil {
+			t.Fatal(err)
+		}
+		id, _, _, _, err := store.Users.GetUserByEmail(ctx, email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fans = append(fans, id)
+	}
+	for _, id := range fans[:3] {
+		if err := store.Votes.SetPostLike(ctx, id, int(postID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Votes.SetPostDislike(ctx, fans[3], int(postID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Votes.SetCommentLike(ctx, fans[0], int(commentID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetLikesHidden(ctx, store.DB, fans[2], true); err != nil {
+		t.Fatal(err)
+	}
+	if hidden, err := LikesHidden(ctx, store.DB, fans[2]); err != nil || !hidden {
+		t.Errorf("LikesHidden = %v, %v, want true", hidden, err)
+	}
+
+	first, next, err := GetLikers(ctx, store.DB, "post", int(postID), 1, "")
+	if err != nil || next == "" || first.PostID != int(postID) || first.Hidden != 1 || len(first.Users) != 1 || first.Users[0].UserID != fans[1] {
+		t.Fatalf("GetLikers(first page) = %+v, %q, %v", first, next, err)
+	}
+	second, next, err := GetLikers(ctx, store.DB, "post", int(postID), 1, next)
+	if err != nil || len(second.Users) != 1 || second.Users[0].UserID != fans[0] || second.Users[0].Username != "LikerOne" {
+		t.Fatalf("GetLikers(second page) = %+v, %q, %v", second, next, err)
+	}
+	if last, next, err := GetLikers(ctx, store.DB, "post", int(postID), 1, next); err != nil || next != "" || len(last.Users) != 0 {
+		t.Errorf("GetLikers(last page) = %+v, %q, %v, want empty", last, next, err)
+	}
+	comment, next, err := GetLikers(ctx, store.DB, "comment", int(commentID), 10, "")
+	if err != nil || next != "" || comment.PostID != int(postID) || comment.Hidden != 0 || len(comment.Users) != 1 || comment.Users[0].UserID != fans[0] {
+		t.Errorf("GetLikers(comment) = %+v, %q, %v", comment, next, err)
+	}
+	if _, _, err := GetLikers(ctx, store.DB, "post", int(postID), 10, "bogus"); err != ErrInvalidCursor {
+		t.Errorf("GetLikers(bad cursor) error = %v, want ErrInvalidCursor", err)
+	}
+	if err := store.Posts.DeletePost(ctx, int(postID)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := GetLikers(ctx, store.DB, "post", int(postID), 10, ""); err != sql.ErrNoRows {
+		t.Errorf("GetLikers(deleted post) error = %v, want sql.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"forum/models"
+)
+
+// GetThreadSummary возвращает число и последний из видимых комментариев поста postID вместе с его сводкой.
+// Summary пусто, если сводки нет или с её создания комментарии добавились или были удалены.
+func GetThreadSummary(ctx context.Context, db *sql.DB, postID int) (models.ThreadSummary, error) {
+	s := models.ThreadSummary{PostID: postID}
+	var createdAt sql.NullTime
+	err := db.QueryRowContext(ctx, `
+        SELECT t.comment_count, t.last_comment_id, COALESCE(s.summary, ''), COALESCE(s.provider, ''), s.created_at
+        FROM (SELECT COUNT(*) AS comment_count, COALESCE(MAX(id), 0) AS last_comment_id
+              FROM comments WHERE post_id = ? AND deleted_at IS NULL) t
+        LEFT JOIN post_summaries s
+               ON s.post_id = ? AND s.comment_count = t.comment_count AND s.last_comment_id = t.last_comment_id
+    `, postID, postID).Scan(&s.CommentCount, &s.LastCommentID, &s.Summary, &s.Provider, &createdAt)
+	s.CreatedAt = createdAt.Time
+	return s, err
+}
+
+// SaveThreadSummary сохраняет сводку обсуждения, заменяя прежнюю. Число и последний комментарий в s
+// должны описывать комментарии, по которым сводка составлена.
+// Замена идёт удалением и вставкой в одной транзакции, как в SavePostTranslation.
+func SaveThreadSummary(ctx context.Context, db *sql.DB, s models.ThreadSummary) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM post_summaries WHERE post_id = ?", s.PostID); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO post_summaries (post_id, comment_count, last_comment_id, summary, provider)
+        VALUES (?, ?, ?, ?, ?)
+    `, s.PostID, s.CommentCount, s.LastCommentID, s.Summary, s.Provider)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
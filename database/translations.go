@@ -0,0 +1,41 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"forum/models"
+)
+
+// GetPostTranslation возвращает сохранённый перевод поста postID на язык lang, сделанный с текста с отпечатком sourceHash.
+// Если перевода нет или пост с тех пор изменился, возвращает sql.ErrNoRows.
+func GetPostTranslation(ctx context.Context, db *sql.DB, postID int, lang, sourceHash string) (models.PostTranslation, error) {
+	t := models.PostTranslation{PostID: postID, Lang: lang, SourceHash: sourceHash}
+	err := db.QueryRowContext(ctx, `
+        SELECT title, content, provider, created_at FROM post_translations
+        WHERE post_id = ? AND lang = ? AND source_hash = ?
+    `, postID, lang, sourceHash).Scan(&t.Title, &t.Content, &t.Provider, &t.CreatedAt)
+	return t, err
+}
+
+// SavePostTranslation сохраняет перевод поста, заменяя прежний перевод на тот же язык.
+// Замена идёт удалением и вставкой в одной транзакции: ON CONFLICT (SQLite) и ON DUPLICATE KEY (MySQL) пишутся по-разному.
+func SavePostTranslation(ctx context.Context, db *sql.DB, t models.PostTranslation) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM post_translations WHERE post_id = ? AND lang = ?", t.PostID, t.Lang); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO post_translations (post_id, lang, source_hash, title, content, provider)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `, t.PostID, t.Lang, t.SourceHash, t.Title, t.Content, t.Provider)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,19 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// UploadReferenced сообщает, ссылается ли на загруженный файл с адресом url какой-нибудь пост
+// (изображением или в тексте) или комментарий. Удалённые, но ещё не стёртые посты и комментарии тоже считаются:
+// их можно восстановить из базы до окончательного удаления.
+func UploadReferenced(ctx context.Context, db *sql.DB, url string) (bool, error) {
+	pattern := "%" + escapeLike(url) + "%"
+	var referenced bool
+	err := db.QueryRowContext(ctx, `
+        SELECT EXISTS (SELECT 1 FROM posts WHERE image_url = ? OR content LIKE ? ESCAPE '!')
+            OR EXISTS (SELECT 1 FROM comments WHERE content LIKE ? ESCAPE '!')`,
+		url, pattern, pattern).Scan(&referenced)
+	return referenced, err
+}
@@ -0,0 +1,22 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// GetEmailVerification возвращает почту пользователя userID и признак того, что он её подтвердил.
+// Если пользователя нет, возвращает sql.ErrNoRows.
+func GetEmailVerification(ctx context.Context, db *sql.DB, userID int) (string, bool, error) {
+	var email string
+	var verified bool
+	err := db.QueryRowContext(ctx, "SELECT email, email_verified FROM users WHERE id = ?", userID).Scan(&email, &verified)
+	return email, verified, err
+}
+
+// SetEmailVerified отмечает почту пользователя userID подтверждённой или неподтверждённой.
+// Пользователи создаются с подтверждённой почтой; регистрация с проверкой почты снимает отметку.
+func SetEmailVerified(ctx context.Context, db *sql.DB, userID int, verified bool) error {
+	_, err := db.ExecContext(ctx, "UPDATE users SET email_verified = ? WHERE id = ?", verified, userID)
+	return err
+}
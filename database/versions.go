@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// ContentVersion описывает состояние данных, от которого зависит содержимое страницы.
+// Fingerprint меняется при любом изменении постов, комментариев, голосов, профилей, разделов, оформления категорий
+// или настроек сайта,
+// LastModified — время самого позднего из этих изменений.
+type ContentVersion struct {
+	Fingerprint  string
+	LastModified time.Time
+}
+
+// sqliteTimeLayouts перечисляет форматы, в которых в базе хранятся даты.
+var sqliteTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05",
+	time.RFC3339Nano,
+}
+
+// parseSQLiteTime разбирает дату из базы в любом из используемых форматов.
+// Возвращает нулевое время, если строку разобрать не удалось.
+func parseSQLiteTime(value string) time.Time {
+	for _, layout := range sqliteTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// buildVersion собирает ContentVersion из строк-счётчиков и строк-дат.
+func buildVersion(counters []sql.NullString, times []sql.NullString) ContentVersion {
+	parts := make([]string, 0, len(counters)+len(times))
+	var lastModified time.Time
+	for _, c := range counters {
+		parts = append(parts, c.String)
+	}
+	for _, t := range times {
+		parts = append(parts, t.String)
+		if parsed := parseSQLiteTime(t.String); parsed.After(lastModified) {
+			lastModified = parsed
+		}
+	}
+	return ContentVersion{Fingerprint: strings.Join(parts, "|"), LastModified: lastModified}
+}
+
+const queryFeedVersion = `
+        SELECT (SELECT COUNT(*) FROM posts WHERE deleted_at IS NULL),
+               (SELECT COUNT(*) FROM comments WHERE deleted_at IS NULL),
+               (SELECT COUNT(*) || ':' || COALESCE(SUM(vote), 0) FROM post_votes),
+               (SELECT COUNT(*) || ':' || COALESCE(SUM(vote), 0) FROM comment_votes),
+               (SELECT GROUP_CONCAT(name || '=' || value) FROM (SELECT name, value FROM settings ORDER BY name)),
+               (SELECT MAX(COALESCE(updated_at, created_at)) FROM posts),
+               (SELECT MAX(created_at) FROM comments),
+               (SELECT MAX(voted_at) FROM post_votes),
+               (SELECT MAX(voted_at) FROM comment_votes),
+               (SELECT MAX(updated_at) FROM users),
+               (SELECT MAX(COALESCE(updated_at, created_at)) FROM boards),
+               (SELECT MAX(updated_at) FROM settings),
+               (SELECT MAX(updated_at) FROM categories)
+    `
+
+// GetFeedVersion возвращает версию данных, из которых строятся ленты постов.
+// Выполняет один лёгкий агрегирующий запрос без соединений таблиц.
+func GetFeedVersion(ctx context.Context, db *sql.DB) (ContentVersion, error) {
+	counters := make([]sql.NullString, 5)
+	times := make([]sql.NullString, 8)
+	err := cachedQueryRow(ctx, db, queryFeedVersion).Scan(&counters[0], &counters[1], &counters[2], &counters[3], &counters[4],
+		&times[0], &times[1], &times[2], &times[3], &times[4], &times[5], &times[6], &times[7])
+	if err != nil {
+		return ContentVersion{}, err
+	}
+	return buildVersion(counters, times), nil
+}
+
+const queryPostVersion = `
+        SELECT (SELECT COUNT(*) FROM comments WHERE post_id = p.id AND deleted_at IS NULL),
+               (SELECT COUNT(*) || ':' || COALESCE(SUM(vote), 0) FROM post_votes WHERE post_id = p.id),
+               (SELECT COUNT(*) || ':' || COALESCE(SUM(cv.vote), 0)
+                FROM comment_votes cv JOIN comments c ON c.id = cv.comment_id WHERE c.post_id = p.id),
+               COALESCE(p.updated_at, p.created_at),
+               (SELECT MAX(created_at) FROM comments WHERE post_id = p.id),
+               (SELECT MAX(voted_at) FROM post_votes WHERE post_id = p.id),
+               (SELECT MAX(cv.voted_at)
+                FROM comment_votes cv JOIN comments c ON c.id = cv.comment_id WHERE c.post_id = p.id),
+               (SELECT MAX(updated_at) FROM users),
+               (SELECT MAX(COALESCE(updated_at, created_at)) FROM boards),
+               (SELECT MAX(updated_at) FROM categories),
+               (SELECT s.updated_at FROM series_posts sp JOIN series s ON s.id = sp.series_id WHERE sp.post_id = p.id),
+               (SELECT created_at FROM post_summaries WHERE post_id = p.id)
+        FROM posts p WHERE p.id = ? AND p.deleted_at IS NULL
+    `
+
+// GetPostVersion возвращает версию данных страницы поста: сам пост, его комментарии, голоса, серию и сводку обсуждения.
+// Если пост не найден, возвращает sql.ErrNoRows.
+func GetPostVersion(ctx context.Context, db *sql.DB, postID int) (ContentVersion, error) {
+	counters := make([]sql.NullString, 3)
+	times := make([]sql.NullString, 9)
+	err := cachedQueryRow(ctx, db, queryPostVersion, postID).Scan(&counters[0], &counters[1], &counters[2],
+		&times[0], &times[1], &times[2], &times[3], &times[4], &times[5], &times[6], &times[7], &times[8])
+	if err != nil {
+		return ContentVersion{}, err
+	}
+	return buildVersion(counters, times), nil
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"forum/models"
+)
+
+// unreadPostCondition отмечает посты, которые пользователь ещё не открывал: своих постов это не касается,
+// а посты с ID не больше users.read_through считаются прочитанными («отметить всё прочитанным»).
+// Для анонимного посетителя (ID 0) условие ложно. Параметры — ID пользователя четыре раза (см. unreadPostArgs).
+const unreadPostCondition = `(? <> 0 AND p.user_id <> ?
+               AND NOT EXISTS (SELECT 1 FROM post_views rv WHERE rv.post_id = p.id AND rv.user_id = ?)
+               AND p.id > COALESCE((SELECT ru.read_through FROM users ru WHERE ru.id = ?), 0))`
+
+// unreadPostArgs возвращает параметры unreadPostCondition.
+func unreadPostArgs(userID int) []interface{} {
+	return []interface{}{userID, userID, userID, userID}
+}
+
+// postReadInsert записывает прочтение поста, если его ещё нет; параметры — ID поста и ID пользователя.
+const postReadInsert = "INSERT OR IGNORE INTO post_reads (user_id, post_id) SELECT id, ? FROM users WHERE id = ?"
+
+// postViewUpsert записывает просмотр поста или обновляет его время, если пользователь не отключил историю
+// просмотров; параметры — ID поста, время и ID пользователя.
+const postViewUpsert = `
+        INSERT INTO post_views (user_id, post_id, viewed_at)
+        SELECT id, ?, ? FROM users WHERE id = ? AND NOT history_disabled
+        ON CONFLICT(user_id, post_id) DO UPDATE SET viewed_at = excluded.viewed_at
+    `
+
+// MarkPostViewed запоминает, что пользователь открыл пост, и обновляет время последнего просмотра.
+// Если пользователь отключил историю просмотров (см. SetHistoryEnabled), просмотр не записывается.
+func MarkPostViewed(ctx context.Context, db *sql.DB, userID, postID int, at time.Time) error {
+	return markPostViewed(ctx, db, postReadInsert, postViewUpsert, userID, postID, at)
+}
+
+// markPostViewed записывает прочтение запросом insert (см. postReadInsert) и просмотр запросом upsert
+// (см. postViewUpsert). Прочтения хранятся в post_reads отдельно от истории: их не удаляют ни отключение
+// истории, ни удаление старых просмотров. Счётчик users.posts_read, от которого зависит уровень доверия,
+// растёт, только если прочтение действительно добавлено, поэтому каждый пост засчитывается один раз,
+// в том числе при одновременных просмотрах.
+func markPostViewed(ctx context.Context, db *sql.DB, insert, upsert string, userID, postID int, at time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, insert, postID, userID)
+	if err != nil {
+		return err
+	}
+	added, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if added > 0 {
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET posts_read = posts_read + 1 WHERE id = ?", userID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, upsert, postID, at, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MarkAllRead отмечает прочитанными все посты, опубликованные до этого момента: запоминает наибольший ID поста.
+// Просмотры отдельных постов не трогаются — по ним строится история.
+func MarkAllRead(ctx context.Context, db *sql.DB, userID int) error {
+	_, err := db.ExecContext(ctx, "UPDATE users SET read_through = (SELECT COALESCE(MAX(id), 0) FROM posts) WHERE id = ?", userID)
+	return err
+}
+
+// GetReadState возвращает отпечаток состояния прочтения пользователя: число просмотренных постов,
+// время последнего просмотра и границу «прочитано всё». Отпечаток входит в ETag ленты,
+// чтобы отметки непрочитанного обновлялись после просмотров.
+func GetReadState(ctx context.Context, db *sql.DB, userID int) (string, error) {
+	var count int
+	var last sql.NullString
+	var through int
+	err := db.QueryRowContext(ctx, `
+        SELECT (SELECT COUNT(*) FROM post_views WHERE user_id = ?),
+               (SELECT CAST(MAX(viewed_at) AS CHAR) FROM post_views WHERE user_id = ?),
+               COALESCE((SELECT read_through FROM users WHERE id = ?), 0)
+    `, userID, userID, userID).Scan(&count, &last, &through)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(count) + "-" + last.String + "-" + strconv.Itoa(through), nil
+}
+
+// GetViewHistory возвращает посты, которые пользователь открывал, начиная с последнего просмотренного, не больше limit.
+// Удалённые посты пропускаются.
+func GetViewHistory(ctx context.Context, db *sql.DB, userID, limit int) ([]models.HistoryEntry, error) {
+	rows, err := db.QueryContext(ctx, `
+        SELECT p.id, p.title, u.id, u.username, pv.viewed_at
+        FROM post_views pv
+        JOIN posts p ON p.id = pv.post_id
+        JOIN users u ON u.id = p.user_id
+        WHERE pv.user_id = ? AND p.deleted_at IS NULL
+        ORDER BY pv.viewed_at DESC, p.id DESC
+        LIMIT ?
+    `, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.HistoryEntry
+	for rows.Next() {
+		var e models.HistoryEntry
+		if err := rows.Scan(&e.PostID, &e.Title, &e.UserID, &e.Username, &e.ViewedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// HistoryEnabled сообщает, ведётся ли история просмотров пользователя.
+func HistoryEnabled(ctx context.Context, db *sql.DB, userID int) (bool, error) {
+	var disabled bool
+	err := db.QueryRowContext(ctx, "SELECT history_disabled FROM users WHERE id = ?", userID).Scan(&disabled)
+	return !disabled, err
+}
+
+// SetHistoryEnabled включает или отключает историю просмотров пользователя.
+// При отключении записанные просмотры удаляются, а все опубликованные посты отмечаются прочитанными
+// (см. MarkAllRead), чтобы открытые раньше посты не стали снова непрочитанными.
+func SetHistoryEnabled(ctx context.Context, db *sql.DB, userID int, enabled bool) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET history_disabled = ? WHERE id = ?", !enabled, userID); err != nil {
+		return err
+	}
+	if !enabled {
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET read_through = (SELECT COALESCE(MAX(id), 0) FROM posts) WHERE id = ?", userID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM post_views WHERE user_id = ?", userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// PurgeViewHistory удаляет просмотры старше retention и возвращает их число.
+// Граница «прочитано всё» каждого пользователя сдвигается до самого нового из удалённых постов:
+// посты старше просмотренного за пределами срока хранения остаются прочитанными.
+func PurgeViewHistory(ctx context.Context, db *sql.DB, retention time.Duration) (int64, error) {
+	before := time.Now().Add(-retention)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, `
+        UPDATE users SET read_through = (
+            SELECT MAX(pv.post_id) FROM post_views pv WHERE pv.user_id = users.id AND pv.viewed_at < ?)
+        WHERE read_through < COALESCE((
+            SELECT MAX(pv.post_id) FROM post_views pv WHERE pv.user_id = users.id AND pv.viewed_at < ?), 0)
+    `, before, before)
+	if err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM post_views WHERE viewed_at < ?", before)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
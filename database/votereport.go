@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"forum/models"
+)
+
+// GetVoteReport ищет подозрительные голоса, поставленные после since, и возвращает не больше limit строк
+// в каждом разделе отчёта:
+//   - NewAccountVotes — авторы, за посты и комментарии которых не меньше minVotes раз проголосовали аккаунты,
+//     которым в момент голоса не было newAccountAge (накрутка или травля свежими аккаунтами);
+//   - Rings — пары пользователей, поставивших друг другу не меньше minVotes лайков каждый (взаимная накрутка).
+//
+// Голоса за собственные посты и комментарии не учитываются. Время голосов и регистрации хранится в UTC
+// (CURRENT_TIMESTAMP), а возраст аккаунта считается в Go, чтобы запрос одинаково работал в SQLite и MySQL.
+func GetVoteReport(ctx context.Context, db *sql.DB, since time.Time, newAccountAge time.Duration, minVotes, limit int) (models.VoteReport, error) {
+	bound := since.UTC().Format(leaderboardTime)
+	rows, err := db.QueryContext(ctx, `
+        SELECT v.voter_id, voter.username, voter.created_at, v.voted_at, v.author_id, author.username, v.vote
+        FROM (
+            SELECT v.user_id AS voter_id, p.user_id AS author_id, v.vote, v.voted_at
+            FROM post_votes v JOIN posts p ON p.id = v.post_id
+            WHERE v.voted_at >= ? AND v.user_id <> p.user_id
+            UNION ALL
+            SELECT v.user_id, c.user_id, v.vote, v.voted_at
+            FROM comment_votes v JOIN comments c ON c.id = v.comment_id
+            WHERE v.voted_at >= ? AND v.user_id <> c.user_id
+        ) v
+        JOIN users voter ON voter.id = v.voter_id
+        JOIN users author ON author.id = v.author_id
+    `, bound, bound)
+	if err != nil {
+		return models.VoteReport{}, err
+	}
+	defer rows.Close()
+
+	type pair struct{ from, to int }
+	suspects := make(map[int]*models.VoteSuspect)
+	totals := make(map[int]int)
+	newVoters := make(map[pair]bool)
+	likes := make(map[pair]int)
+	names := make(map[int]string)
+	for rows.Next() {
+		var voterID, authorID, vote int
+		var voterName, authorName string
+		var registered, votedAt time.Time
+		if err := rows.Scan(&voterID, &voterName, &registered, &votedAt, &authorID, &authorName, &vote); err != nil {
+			return models.VoteReport{}, err
+		}
+		names[voterID], names[authorID] = voterName, authorName
+		totals[authorID]++
+		if vote > 0 {
+			likes[pair{voterID, authorID}]++
+		}
+		if votedAt.Sub(registered) >= newAccountAge {
+			continue
+		}
+		s := suspects[authorID]
+		if s == nil {
+			s = &models.VoteSuspect{UserID: authorID, Username: authorName}
+			suspects[authorID] = s
+		}
+		if vote > 0 {
+			s.Likes++
+		} else {
+			s.Dislikes++
+		}
+		if !newVoters[pair{voterID, authorID}] {
+			newVoters[pair{voterID, authorID}] = true
+			s.Voters++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return models.VoteReport{}, err
+	}
+
+	var report models.VoteReport
+	for id, s := range suspects {
+		if s.Likes+s.Dislikes >= minVotes {
+			s.Percent = 100 * (s.Likes + s.Dislikes) / totals[id]
+			report.NewAccountVotes = append(report.NewAccountVotes, *s)
+		}
+	}
+	sort.Slice(report.NewAccountVotes, func(i, j int) bool {
+		a, b := report.NewAccountVotes[i], report.NewAccountVotes[j]
+		if a.Likes+a.Dislikes != b.Likes+b.Dislikes {
+			return a.Likes+a.Dislikes > b.Likes+b.Dislikes
+		}
+		return a.UserID < b.UserID
+	})
+	for p, n := range likes {
+		back := likes[pair{p.to, p.from}]
+		if p.from < p.to && n >= minVotes && back >= minVotes {
+			report.Rings = append(report.Rings, models.VoteRing{
+				UserID: p.from, Username: names[p.from], OtherID: p.to, OtherName: names[p.to], Likes: n, OtherLikes: back,
+			})
+		}
+	}
+	sort.Slice(report.Rings, func(i, j int) bool {
+		a, b := report.Rings[i], report.Rings[j]
+		if a.Likes+a.OtherLikes != b.Likes+b.OtherLikes {
+			return a.Likes+a.OtherLikes > b.Likes+b.OtherLikes
+		}
+		return a.UserID < b.UserID || a.UserID == b.UserID && a.OtherID < b.OtherID
+	})
+	if len(report.NewAccountVotes) > limit {
+		report.NewAccountVotes = report.NewAccountVotes[:limit]
+	}
+	if len(report.Rings) > limit {
+		report.Rings = report.Rings[:limit]
+	}
+	return report, nil
+}
@@ -0,0 +1,64 @@
+// Package emailverify выдаёт и проверяет ссылки подтверждения почты. Токен в ссылке имеет вид
+// {пользователь}.{срок}.{подпись}: подпись (HMAC) связывает пользователя, срок действия и адрес почты,
+// поэтому токен нельзя подделать или продлить, а после смены почты старые ссылки перестают действовать.
+// Токены не хранятся в базе.
+package emailverify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tokens выдаёт и проверяет токены подтверждения.
+type Tokens struct {
+	Secret []byte        // ключ подписи
+	MaxAge time.Duration // срок действия ссылки
+}
+
+// New возвращает токен подтверждения адреса email пользователя userID, выданный в момент now.
+func (t *Tokens) New(userID int, email string, now time.Time) string {
+	expires := now.Add(t.MaxAge).Unix()
+	return fmt.Sprintf("%d.%d.%s", userID, expires, t.sign(userID, expires, email))
+}
+
+// UserID возвращает пользователя, которому выдан токен. Подпись не проверяется: ok лишь означает,
+// что токен правильно составлен, а проверяет его Valid с почтой этого пользователя.
+func (t *Tokens) UserID(token string) (userID int, ok bool) {
+	id, _, _, ok := parse(token)
+	return id, ok
+}
+
+// Valid сообщает, подтверждает ли токен адрес email и не истёк ли он к моменту now.
+func (t *Tokens) Valid(token, email string, now time.Time) bool {
+	userID, expires, sig, ok := parse(token)
+	if !ok || now.Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(t.sign(userID, expires, email)))
+}
+
+// parse разбирает токен на пользователя, срок действия (Unix-время) и подпись.
+func parse(token string) (userID int, expires int64, sig string, ok bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, 0, "", false
+	}
+	userID, err1 := strconv.Atoi(parts[0])
+	expires, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil || userID <= 0 {
+		return 0, 0, "", false
+	}
+	return userID, expires, parts[2], true
+}
+
+// sign возвращает подпись пользователя, срока и адреса. Регистр адреса не учитывается.
+func (t *Tokens) sign(userID int, expires int64, email string) string {
+	mac := hmac.New(sha256.New, t.Secret)
+	fmt.Fprintf(mac, "%d\x00%d\x00%s", userID, expires, strings.ToLower(email))
+	return hex.EncodeToString(mac.Sum(nil))
+}
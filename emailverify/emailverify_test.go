@@ -0,0 +1,41 @@
+package emailverify
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTokens проверяет, что токен подтверждает только свой адрес, не действует после срока
+// и не принимается с изменённым пользователем, сроком или чужим ключом.
+func TestTokens(t *testing.T) {
+	tokens := &Tokens{Secret: []byte("secret"), MaxAge: time.Hour}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	token := tokens.New(7, "Anna@example.com", now)
+
+	if id, ok := tokens.UserID(token); !ok || id != 7 {
+		t.Errorf("UserID = %d, %v", id, ok)
+	}
+	if !tokens.Valid(token, "anna@example.com", now.Add(59*time.Minute)) {
+		t.Error("fresh token is not valid")
+	}
+	if tokens.Valid(token, "anna@example.com", now.Add(61*time.Minute)) {
+		t.Error("expired token is valid")
+	}
+	if tokens.Valid(token, "other@example.com", now) {
+		t.Error("token is valid for another address")
+	}
+	other := &Tokens{Secret: []byte("other"), MaxAge: time.Hour}
+	if other.Valid(token, "anna@example.com", now) {
+		t.Error("token is valid with another key")
+	}
+	forged := tokens.New(8, "anna@example.com", now)
+	forged = "7" + forged[1:]
+	if tokens.Valid(forged, "anna@example.com", now) {
+		t.Error("token with a changed user is valid")
+	}
+	for _, bad := range []string{"", "7", "7.x.abc", "0.1.abc", "7.1.abc.def"} {
+		if _, ok := tokens.UserID(bad); ok {
+			t.Errorf("UserID(%q) accepted a malformed token", bad)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Package fingerprint раздаёт статические файлы под именами с хешем содержимого (styles.3f2a9c1b04.css),
+// чтобы браузеры кэшировали их бессрочно, а изменённый файл получал новое имя.
+// Ссылки url(...) и @import в CSS переписываются на такие имена, поэтому изменение подключаемого
+// файла меняет и хеш подключающего.
+package fingerprint
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// hashLen — число шестнадцатеричных символов хеша в имени файла.
+const hashLen = 10
+
+// Cache-Control для файлов с хешем в имени и для остальных файлов.
+const (
+	immutableCache  = "public, max-age=31536000, immutable"
+	revalidateCache = "no-cache"
+)
+
+// cssURL находит ссылки url(...) в CSS, в том числе в @import url(...).
+var cssURL = regexp.MustCompile(`url\(\s*(["']?)([^"')]+)(["']?)\s*\)`)
+
+// asset — файл с хешем в имени.
+type asset struct {
+	name    string // исходный путь в fsys
+	content []byte // содержимое; для CSS — с переписанными ссылками
+	etag    string
+}
+
+// Manifest сопоставляет статические файлы и их имена с хешем.
+type Manifest struct {
+	prefix   string
+	fsys     fs.FS
+	hashed   map[string]*asset // имя с хешем → файл
+	original map[string]string // исходный путь → имя с хешем
+}
+
+// New строит манифест для файлов fsys, раздаваемых по префиксу prefix (например, "/static/").
+func New(fsys fs.FS, prefix string) (*Manifest, error) {
+	m := &Manifest{
+		prefix:   prefix,
+		fsys:     fsys,
+		hashed:   make(map[string]*asset),
+		original: make(map[string]string),
+	}
+	var css []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if path.Ext(name) == ".css" {
+			css = append(css, name)
+			return nil
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		m.add(name, content)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// CSS обрабатывается после остальных файлов и в порядке зависимостей, чтобы ссылки
+	// указывали на уже известные имена с хешем.
+	visiting := make(map[string]bool)
+	for _, name := range css {
+		if err := m.addCSS(name, visiting); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// add регистрирует файл name с содержимым content.
+func (m *Manifest) add(name string, content []byte) {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])[:hashLen]
+	ext := path.Ext(name)
+	hashed := strings.TrimSuffix(name, ext) + "." + hash + ext
+	m.hashed[hashed] = &asset{name: name, content: content, etag: `"` + hash + `"`}
+	m.original[name] = hashed
+}
+
+// addCSS регистрирует CSS-файл name, предварительно зарегистрировав файлы, на которые он ссылается.
+func (m *Manifest) addCSS(name string, visiting map[string]bool) error {
+	if _, ok := m.original[name]; ok {
+		return nil
+	}
+	if visiting[name] {
+		return fmt.Errorf("fingerprint: circular CSS import involving %s", name)
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	content, err := fs.ReadFile(m.fsys, name)
+	if err != nil {
+		return err
+	}
+	var firstErr error
+	content = cssURL.ReplaceAllFunc(content, func(match []byte) []byte {
+		sub := cssURL.FindSubmatch(match)
+		ref := string(sub[2])
+		target, ok := m.resolve(name, ref)
+		if !ok {
+			return match
+		}
+		if path.Ext(target) == ".css" {
+			if err := m.addCSS(target, visiting); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		hashed, ok := m.original[target]
+		if !ok {
+			return match
+		}
+		return []byte("url(" + string(sub[1]) + m.prefix + hashed + string(sub[3]) + ")")
+	})
+	if firstErr != nil {
+		return firstErr
+	}
+	m.add(name, content)
+	return nil
+}
+
+// resolve возвращает путь в fsys, на который ссылается ref из файла from.
+// Внешние адреса, data: и якоря не переписываются.
+func (m *Manifest) resolve(from, ref string) (string, bool) {
+	if ref == "" || strings.HasPrefix(ref, "#") || strings.HasPrefix(ref, "data:") || strings.Contains(ref, "://") || strings.HasPrefix(ref, "//") {
+		return "", false
+	}
+	ref, _, _ = strings.Cut(ref, "?")
+	var target string
+	if strings.HasPrefix(ref, m.prefix) {
+		target = strings.TrimPrefix(ref, m.prefix)
+	} else if strings.HasPrefix(ref, "/") {
+		return "", false
+	} else {
+		target = path.Join(path.Dir(from), ref)
+	}
+	if _, err := fs.Stat(m.fsys, target); err != nil {
+		return "", false
+	}
+	return target, true
+}
+
+// Path возвращает адрес файла name: с хешем в имени, если файл есть в манифесте, иначе обычный.
+// Nil-манифест всегда возвращает обычный адрес.
+func (m *Manifest) Path(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if m != nil {
+		if hashed, ok := m.original[name]; ok {
+			return m.prefix + hashed
+		}
+		return m.prefix + name
+	}
+	return "/static/" + name
+}
+
+// Handler раздаёт файлы fsys. Файлы с хешем в имени кэшируются браузером бессрочно,
+// остальные — с проверкой актуальности при каждом использовании. Путь запроса должен быть
+// уже без префикса (см. http.StripPrefix). Nil-манифест раздаёт только файлы fsys без хеша.
+func Handler(m *Manifest, fsys fs.FS) http.Handler {
+	files := http.FileServerFS(fsys)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m != nil {
+			if a, ok := m.hashed[strings.TrimPrefix(r.URL.Path, "/")]; ok {
+				w.Header().Set("Cache-Control", immutableCache)
+				w.Header().Set("ETag", a.etag)
+				http.ServeContent(w, r, a.name, time.Time{}, bytes.NewReader(a.content))
+				return
+			}
+		}
+		w.Header().Set("Cache-Control", revalidateCache)
+		files.ServeHTTP(w, r)
+	})
+}
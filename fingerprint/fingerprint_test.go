@@ -0,0 +1,100 @@
+package fingerprint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func files(base string) fstest.MapFS {
+	return fstest.MapFS{
+		"styles.css":     {Data: []byte(`@import url("base.css");` + "\n" + `@import url("https://fonts.example.com/x.css");`)},
+		"base.css":       {Data: []byte(base)},
+		"images/bg.png":  {Data: []byte("png")},
+		"script.js":      {Data: []byte("console.log(1)")},
+		"nested/app.css": {Data: []byte(`.x { background: url('../images/bg.png'); }`)},
+	}
+}
+
+// TestManifest проверяет имена с хешем и переписывание ссылок в CSS.
+func TestManifest(t *testing.T) {
+	m, err := New(files("body { background: url(/static/images/bg.png) }"), "/static/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	styles := m.Path("styles.css")
+	if !strings.HasPrefix(styles, "/static/styles.") || !strings.HasSuffix(styles, ".css") || len(styles) != len("/static/styles..css")+hashLen {
+		t.Fatalf("Path(styles.css) = %q", styles)
+	}
+	if got := m.Path("missing.txt"); got != "/static/missing.txt" {
+		t.Errorf("Path(missing.txt) = %q", got)
+	}
+
+	content := string(m.hashed[strings.TrimPrefix(styles, "/static/")].content)
+	if !strings.Contains(content, `url("`+m.Path("base.css")+`")`) || !strings.Contains(content, "https://fonts.example.com/x.css") {
+		t.Errorf("styles.css was not rewritten: %s", content)
+	}
+	base := string(m.hashed[strings.TrimPrefix(m.Path("base.css"), "/static/")].content)
+	if !strings.Contains(base, "url("+m.Path("images/bg.png")+")") {
+		t.Errorf("base.css was not rewritten: %s", base)
+	}
+	app := string(m.hashed[strings.TrimPrefix(m.Path("nested/app.css"), "/static/")].content)
+	if !strings.Contains(app, "url('"+m.Path("images/bg.png")+"')") {
+		t.Errorf("nested/app.css was not rewritten: %s", app)
+	}
+
+	// Изменение подключаемого файла меняет имя подключающего.
+	changed, err := New(files("body { color: red }"), "/static/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.Path("styles.css") == styles {
+		t.Error("styles.css kept its name after base.css changed")
+	}
+	if changed.Path("script.js") != m.Path("script.js") {
+		t.Error("script.js changed its name although its content did not")
+	}
+}
+
+// TestHandler проверяет заголовки кэширования.
+func TestHandler(t *testing.T) {
+	fsys := files("body {}")
+	m, err := New(fsys, "/static/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := http.StripPrefix("/static/", Handler(m, fsys))
+	for _, tt := range []struct {
+		path, cache string
+	}{
+		{m.Path("script.js"), immutableCache},
+		{"/static/script.js", revalidateCache},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != tt.cache || w.Body.String() != "console.log(1)" {
+			t.Errorf("%s: %d %q %q", tt.path, w.Code, w.Header().Get("Cache-Control"), w.Body.String())
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, m.Path("script.js"), nil)
+	r.Header.Set("If-None-Match", m.hashed[strings.TrimPrefix(m.Path("script.js"), "/static/")].etag)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("conditional request: %d, want 304", w.Code)
+	}
+}
+
+// TestCircularImport проверяет, что циклические @import не приводят к зацикливанию.
+func TestCircularImport(t *testing.T) {
+	_, err := New(fstest.MapFS{
+		"a.css": {Data: []byte(`@import url("b.css");`)},
+		"b.css": {Data: []byte(`@import url("a.css");`)},
+	}, "/static/")
+	if err == nil {
+		t.Fatal("want an error for circular imports")
+	}
+}
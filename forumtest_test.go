@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"forum/cache"
+	"forum/config"
+	"forum/database"
+	"forum/handlers"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestPassword — пароль всех пользователей из тестовых данных NewTestForum.
+const TestPassword = "secret-password"
+
+// TestUser — пользователь из тестовых данных с уже открытой сессией.
+type TestUser struct {
+	ID       int
+	Email    string
+	Username string
+	Session  *http.Cookie
+}
+
+// TestForum — приложение форума со всеми маршрутами поверх базы SQLite в памяти с тестовыми данными.
+// Alice — автор поста PostID, Bob — автор комментария CommentID к нему, Admin — администратор.
+type TestForum struct {
+	Handler   http.Handler
+	Store     *database.Store
+	Alice     TestUser
+	Bob       TestUser
+	Admin     TestUser
+	PostID    int
+	CommentID int
+
+	routes func(*database.Store) http.Handler // маршруты приложения поверх хранилища
+}
+
+// NewTestForum создаёт приложение для интеграционных тестов обработчиков через httptest.
+// База живёт, пока открыто её единственное соединение, и закрывается по окончании теста;
+// журнал приложения на это время отключается.
+func NewTestForum(tb testing.TB) *TestForum {
+	tb.Helper()
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	tb.Cleanup(func() { log.SetOutput(out) })
+
+	// У каждого соединения с ":memory:" своя база, поэтому пул ограничен одним соединением,
+	// которое не закрывается по времени.
+	opts := database.DefaultSQLiteOptions()
+	opts.Pool = database.PoolOptions{MaxOpenConns: 1, MaxIdleConns: 1}
+	db, err := database.OpenSQLiteWithOptions(":memory:", opts)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		database.CloseStatements(db)
+		db.Close()
+	})
+
+	cfg := config.Default()
+	files, err := loadAssets(cfg.Server)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := handlers.Configure(cfg, files.templates, files.manifest); err != nil {
+		tb.Fatal(err)
+	}
+
+	f := &TestForum{Store: database.NewSQLiteStore(db)}
+	f.routes = func(store *database.Store) http.Handler {
+		return setupRoutes(files, cfg.Server.Timeouts, store, nil)
+	}
+	f.Handler = f.routes(f.Store)
+	f.seed(tb)
+	return f
+}
+
+// WithCache переводит приложение на хранилище с кэшем c, как при настроенном cache в конфигурации.
+func (f *TestForum) WithCache(c cache.Cache) {
+	f.Store = f.Store.WithCache(c, time.Minute)
+	f.Handler = f.routes(f.Store)
+}
+
+// seed создаёт пользователей, пост Alice и комментарий Bob к нему.
+func (f *TestForum) seed(tb testing.TB) {
+	tb.Helper()
+	ctx := context.Background()
+	// Минимальная стоимость bcrypt, чтобы вход в тестах не занимал заметного времени.
+	hash, err := bcrypt.GenerateFromPassword([]byte(TestPassword), bcrypt.MinCost)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	f.Alice = f.addUser(tb, "alice", string(hash), "user")
+	f.Bob = f.addUser(tb, "bob", string(hash), "user")
+	f.Admin = f.addUser(tb, "admin", string(hash), "admin")
+
+	board, err := f.Store.Boards.GetBoardBySlug(ctx, database.DefaultBoard)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	postID, err := f.Store.Posts.CreatePost(ctx, f.Alice.ID, board.ID, "Hello", "First post", "", time.Now())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	f.PostID = int(postID)
+	commentID, err := f.Store.Comments.CreateComment(ctx, f.PostID, f.Bob.ID, "First comment", time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	f.CommentID = int(commentID)
+}
+
+// addUser регистрирует пользователя name с ролью role и открывает ему сессию.
+func (f *TestForum) addUser(tb testing.TB, name, hash, role string) TestUser {
+	tb.Helper()
+	ctx := context.Background()
+	u := TestUser{Email: name + "@example.com", Username: name}
+	if err := f.Store.Users.RegisterUser(ctx, u.Email, name, hash); err != nil {
+		tb.Fatal(err)
+	}
+	id, _, _, _, err := f.Store.Users.GetUserByEmail(ctx, u.Email)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	u.ID = id
+	if role != "user" {
+		if err := database.SetUserRole(ctx, f.Store.DB, id, role); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	session := "test-session-" + name
+	if err := f.Store.Users.CreateSession(ctx, session, id, role, time.Now().Add(time.Hour)); err != nil {
+		tb.Fatal(err)
+	}
+	u.Session = &http.Cookie{Name: "session_id", Value: session}
+	return u
+}
+
+// Do выполняет запрос method к target от имени as (nil — анонимно). Непустая form отправляется
+// как тело application/x-www-form-urlencoded. Перенаправления не выполняются: их код и Location
+// остаются в ответе.
+func (f *TestForum) Do(method, target string, form url.Values, as *TestUser) *httptest.ResponseRecorder {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	r := httptest.NewRequest(method, target, body)
+	if form != nil {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if as != nil {
+		r.AddCookie(as.Session)
+	}
+	w := httptest.NewRecorder()
+	f.Handler.ServeHTTP(w, r)
+	return w
+}
@@ -0,0 +1,432 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/database"
+	"forum/imagecheck"
+	"forum/jobs"
+	"forum/models"
+)
+
+// backupTimeout ограничивает создание резервной копии. Оно дольше общего таймаута запроса,
+// потому что копирование большой базы может занять больше 15 секунд.
+const backupTimeout = 10 * time.Minute
+
+// BackupHandler отдаёт администратору согласованную копию базы SQLite.
+// Копия создаётся через VACUUM INTO во временном файле,
+// передаётся как вложение и затем удаляется.
+func BackupHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAuth, _, role := IsAuthenticated(store, r)
+		if !isAuth {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if role != "admin" {
+			w.WriteHeader(http.StatusForbidden)
+			writeError(w, r, http.StatusForbidden)
+			return
+		}
+		if store.Dialect != database.DialectSQLite {
+			w.WriteHeader(http.StatusNotImplemented)
+			writeError(w, r, http.StatusNotImplemented)
+			return
+		}
+
+		dir, err := os.MkdirTemp("", "forum-backup-")
+		if err != nil {
+			log.Println("Error creating backup directory:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		defer os.RemoveAll(dir)
+
+		name := database.BackupFileName(time.Now())
+		path := filepath.Join(dir, name)
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), backupTimeout)
+		defer cancel()
+		if err := database.BackupSQLite(ctx, store.DB, path); err != nil {
+			log.Println("Error creating backup:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			log.Println("Error opening backup:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+
+		w.Header().Set("Content-Type", "application/vnd.sqlite3")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		w.Header().Set("Cache-Control", "no-store")
+		if info, err := f.Stat(); err == nil {
+			w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+		}
+		if _, err := io.Copy(w, f); err != nil {
+			log.Println("Error sending backup:", err)
+			return
+		}
+		log.Println("Backup downloaded:", name)
+	}
+}
+
+// IntegrityHandler проверяет базу на строки, ссылающиеся на несуществующие записи, и возвращает отчёт в JSON.
+// GET только проверяет; POST с параметром repair=1 удаляет найденные строки.
+func IntegrityHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAuth, _, role := IsAuthenticated(store, r)
+		if !isAuth {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "message": tr(r, "api.auth_required")})
+			return
+		}
+		if role != "admin" {
+			writeJSON(w, http.StatusForbidden, map[string]interface{}{"success": false, "message": tr(r, "api.forbidden")})
+			return
+		}
+
+		repair := r.Method == "POST" && r.FormValue("repair") == "1"
+		issues, err := database.CheckIntegrity(r.Context(), store.DB, repair)
+		if err != nil {
+			log.Println("Error checking integrity:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "message": tr(r, "api.internal_error")})
+			return
+		}
+		if repair && len(issues) > 0 {
+			log.Printf("Integrity repair removed orphaned rows: %v.", issues)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success":  true,
+			"issues":   issues,
+			"repaired": repair,
+		})
+	}
+}
+
+// adminPageData — данные панели администратора.
+type adminPageData struct {
+	models.PageData
+	Dialect        string
+	Jobs           []jobs.Status
+	HideScoreBelow string
+	BestHalfLife   string
+	InviteOnly     bool
+	ImageAllow     string
+	ImageBlock     string
+	EmailAllow     string
+	EmailBlock     string
+	Branding       models.Site // оформление сайта, как его задал администратор; пустые поля — по умолчанию
+	FooterLinks    string      // ссылки подвала в виде для редактирования (см. database.FormatFooterLinks)
+}
+
+// AdminHandler показывает панель администратора с состоянием фоновых задач
+// (резервные копии, очистка, обслуживание базы), настройками сайта и ссылками на служебные страницы.
+func AdminHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAuth, userID, role := IsAuthenticated(store, r)
+		if !isAuth {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if role != "admin" {
+			w.WriteHeader(http.StatusForbidden)
+			writeError(w, r, http.StatusForbidden)
+			return
+		}
+
+		username, err := store.Users.GetUsernameByID(r.Context(), userID)
+		if err != nil {
+			log.Println("Error getting username:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		hideScoreBelow, err := store.Settings.GetSetting(r.Context(), database.SettingHideScoreBelow)
+		if err != nil {
+			log.Println("Error reading settings:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		bestHalfLife, err := store.Settings.GetSetting(r.Context(), database.SettingBestHalfLife)
+		if err != nil {
+			log.Println("Error reading settings:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		inviteOnly, err := inviteOnlyRegistration(r, store)
+		if err != nil {
+			log.Println("Error reading settings:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		policy, err := imagePolicy(r.Context(), store)
+		if err != nil {
+			log.Println("Error reading settings:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		emailAllow, err := store.Settings.GetSetting(r.Context(), database.SettingEmailAllowDomains)
+		if err != nil {
+			log.Println("Error reading settings:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		emailBlock, err := store.Settings.GetSetting(r.Context(), database.SettingEmailBlockDomains)
+		if err != nil {
+			log.Println("Error reading settings:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		categories, err := store.Categories.ListCategories(r.Context())
+		if err != nil {
+			log.Println("Error listing categories:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		branding, err := store.Settings.GetSite(r.Context())
+		if err != nil {
+			log.Println("Error reading settings:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		statuses := jobs.Statuses()
+		for i := range statuses {
+			statuses[i].LastDuration = statuses[i].LastDuration.Round(time.Millisecond)
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		data := adminPageData{
+			PageData: models.PageData{
+				Username:     username,
+				Categories:   categories,
+				ErrorMessage: flash(r, "error", "admin.error."),
+				Message:      flash(r, "message", "admin.message."),
+			},
+			Dialect:        store.Dialect,
+			Jobs:           statuses,
+			HideScoreBelow: hideScoreBelow,
+			BestHalfLife:   bestHalfLife,
+			InviteOnly:     inviteOnly,
+			ImageAllow:     strings.Join(policy.Allow, "\n"),
+			ImageBlock:     strings.Join(policy.Block, "\n"),
+			EmailAllow:     emailAllow,
+			EmailBlock:     emailBlock,
+			Branding:       branding,
+			FooterLinks:    database.FormatFooterLinks(branding.FooterLinks),
+		}
+		renderPage(w, r, "admin.html", data)
+	}
+}
+
+// AdminSettingsHandler сохраняет настройки сайта из формы панели администратора и возвращает на панель.
+// Сейчас это порог скрытия постов с низким рейтингом (см. database.SettingHideScoreBelow),
+// затухание рейтинга в ленте «Лучшие» (см. database.SettingBestHalfLife) и регистрация только
+// по приглашениям (см. database.SettingInviteOnly).
+func AdminSettingsHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireAdmin(w, r, store); !ok {
+			return
+		}
+		threshold, ok := database.ParseScoreThreshold(r.FormValue("hide_score_below"))
+		if !ok {
+			http.Redirect(w, r, "/admin?error=threshold", http.StatusSeeOther)
+			return
+		}
+		halfLife, ok := database.ParseBestHalfLife(r.FormValue("best_half_life"))
+		if !ok {
+			http.Redirect(w, r, "/admin?error=half_life", http.StatusSeeOther)
+			return
+		}
+		inviteOnly := ""
+		if r.FormValue("invite_only") == "1" {
+			inviteOnly = "1"
+		}
+		for name, value := range map[string]string{
+			database.SettingHideScoreBelow: threshold,
+			database.SettingBestHalfLife:   halfLife,
+			database.SettingInviteOnly:     inviteOnly,
+		} {
+			if err := store.Settings.SetSetting(r.Context(), name, value); err != nil {
+				log.Println("Error saving settings:", err)
+				http.Redirect(w, r, "/admin?error=server", http.StatusSeeOther)
+				return
+			}
+			log.Printf("Setting %s changed to %q.", name, value)
+		}
+		http.Redirect(w, r, "/admin?message=settings_saved", http.StatusSeeOther)
+	}
+}
+
+// AdminImageDomainsHandler сохраняет списки разрешённых и запрещённых доменов изображений
+// из полей allow и block панели администратора. Новые списки действуют для новых и изменённых изображений.
+func AdminImageDomainsHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireAdmin(w, r, store); !ok {
+			return
+		}
+		allow, err := imagecheck.ParseDomains(r.FormValue("allow"))
+		if err != nil {
+			http.Redirect(w, r, "/admin?error=domains", http.StatusSeeOther)
+			return
+		}
+		block, err := imagecheck.ParseDomains(r.FormValue("block"))
+		if err != nil {
+			http.Redirect(w, r, "/admin?error=domains", http.StatusSeeOther)
+			return
+		}
+		for name, domains := range map[string][]string{
+			database.SettingImageAllowDomains: allow,
+			database.SettingImageBlockDomains: block,
+		} {
+			if err := store.Settings.SetSetting(r.Context(), name, strings.Join(domains, "\n")); err != nil {
+				log.Println("Error saving settings:", err)
+				http.Redirect(w, r, "/admin?error=server", http.StatusSeeOther)
+				return
+			}
+		}
+		log.Printf("Image domains changed: allow %v, block %v.", allow, block)
+		http.Redirect(w, r, "/admin?message=image_domains_saved", http.StatusSeeOther)
+	}
+}
+
+// AdminEmailDomainsHandler сохраняет списки почтовых доменов, с которых можно и нельзя регистрироваться,
+// из полей allow и block панели администратора. Уже зарегистрированных пользователей списки не затрагивают.
+func AdminEmailDomainsHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireAdmin(w, r, store); !ok {
+			return
+		}
+		allow, err := imagecheck.ParseDomains(r.FormValue("allow"))
+		if err != nil {
+			http.Redirect(w, r, "/admin?error=domains", http.StatusSeeOther)
+			return
+		}
+		block, err := imagecheck.ParseDomains(r.FormValue("block"))
+		if err != nil {
+			http.Redirect(w, r, "/admin?error=domains", http.StatusSeeOther)
+			return
+		}
+		for name, domains := range map[string][]string{
+			database.SettingEmailAllowDomains: allow,
+			database.SettingEmailBlockDomains: block,
+		} {
+			if err := store.Settings.SetSetting(r.Context(), name, strings.Join(domains, "\n")); err != nil {
+				log.Println("Error saving settings:", err)
+				http.Redirect(w, r, "/admin?error=server", http.StatusSeeOther)
+				return
+			}
+		}
+		log.Printf("Email domains changed: allow %v, block %v.", allow, block)
+		http.Redirect(w, r, "/admin?message=email_domains_saved", http.StatusSeeOther)
+	}
+}
+
+// AdminCategoriesHandler сохраняет значок и цвет категории из формы панели администратора и возвращает на панель.
+// Цвет задаётся как #rrggbb; пустые значок или цвет возвращают метке оформление по умолчанию.
+func AdminCategoriesHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireAdmin(w, r, store); !ok {
+			return
+		}
+		name := r.FormValue("name")
+		icon := strings.TrimSpace(r.FormValue("icon"))
+		color := strings.ToLower(strings.TrimSpace(r.FormValue("color")))
+		if !database.ValidCategoryIcon(icon) || !database.ValidCategoryColor(color) {
+			http.Redirect(w, r, "/admin?error=category_style", http.StatusSeeOther)
+			return
+		}
+		err := store.Categories.UpdateCategoryStyle(r.Context(), name, icon, color)
+		if err == sql.ErrNoRows {
+			http.Redirect(w, r, "/admin?error=category", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			log.Println("Error saving category style:", err)
+			http.Redirect(w, r, "/admin?error=server", http.StatusSeeOther)
+			return
+		}
+		log.Printf("Category %s style changed to %q %q.", name, icon, color)
+		http.Redirect(w, r, "/admin?message=category_saved", http.StatusSeeOther)
+	}
+}
+
+// adminRoles — роли, которые можно назначить из панели администратора.
+var adminRoles = map[string]bool{"user": true, "admin": true, "banned": true}
+
+// AdminRolesHandler меняет роль пользователя с адресом email из формы панели администратора (поле role:
+// user, admin или banned). Сессии пользователя обновляются сразу (см. database.SetUserRole): новая роль
+// действует с его следующего запроса, а заблокированный выходит из всех сессий. Свою роль администратор
+// не меняет, чтобы не лишить сайт последнего администратора, а администратора нельзя заблокировать,
+// не сняв сначала с него роль — так же, как в подкоманде ban.
+func AdminRolesHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := requireAdmin(w, r, store)
+		if !ok {
+			return
+		}
+		role := r.FormValue("role")
+		if !adminRoles[role] {
+			http.Redirect(w, r, "/admin?error=role", http.StatusSeeOther)
+			return
+		}
+		email := strings.TrimSpace(r.FormValue("email"))
+		targetID, target, _, current, err := store.Users.GetUserByEmail(r.Context(), email)
+		if err == sql.ErrNoRows {
+			http.Redirect(w, r, "/admin?error=no_user", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching user:", err)
+			http.Redirect(w, r, "/admin?error=server", http.StatusSeeOther)
+			return
+		}
+		if target == username {
+			http.Redirect(w, r, "/admin?error=own_role", http.StatusSeeOther)
+			return
+		}
+		if current == "admin" && role == "banned" {
+			http.Redirect(w, r, "/admin?error=ban_admin", http.StatusSeeOther)
+			return
+		}
+		if current != role {
+			if err := store.Users.SetUserRole(r.Context(), targetID, role); err != nil {
+				log.Println("Error changing role:", err)
+				http.Redirect(w, r, "/admin?error=server", http.StatusSeeOther)
+				return
+			}
+			log.Printf("Role of %s changed from %s to %s by %s.", target, current, role, username)
+		}
+		http.Redirect(w, r, "/admin?message=role_saved", http.StatusSeeOther)
+	}
+}
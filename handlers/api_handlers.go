@@ -0,0 +1,559 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"forum/database"
+	"forum/i18n"
+	"forum/models"
+)
+
+const (
+	// apiDefaultLimit — количество постов в ответе API по умолчанию.
+	apiDefaultLimit = 20
+	// apiMaxLimit — максимальное количество постов, которое можно запросить за раз.
+	apiMaxLimit = 100
+	// apiExcerptLen — длина отрывка текста поста в символах.
+	apiExcerptLen = 160
+	// activityDays — сколько последних дней, включая сегодняшний, охватывает /api/activity.
+	activityDays = 365
+)
+
+// APIPostsHandler возвращает облегчённый JSON-список постов для мобильного клиента и бесконечной прокрутки.
+// Принимает GET-запрос с параметрами filter (new, best), category, author_id, limit и cursor либо offset.
+// Без offset страницы выбираются по ключу: ответ содержит next_cursor, который передаётся в cursor
+// для следующей страницы (пустой next_cursor означает последнюю страницу).
+// Отдаёт сильный ETag, вычисленный по версии данных и параметрам запроса,
+// и отвечает 304 без обращения к спискам постов, если содержимое не изменилось.
+func APIPostsHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := query.Get("filter")
+		if filter == "" {
+			filter = "new"
+		}
+		if filter != "new" && filter != "best" {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.invalid_filter"),
+			})
+			return
+		}
+
+		category := query.Get("category")
+		if category != "" {
+			if _, err := store.Posts.GetCategoryIDByName(r.Context(), category); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+					"success": false,
+					"message": tr(r, "api.invalid_category"),
+				})
+				return
+			}
+		}
+
+		authorID, ok := queryInt(query.Get("author_id"), 0)
+		if !ok || authorID < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.invalid_author"),
+			})
+			return
+		}
+		limit, ok := queryInt(query.Get("limit"), apiDefaultLimit)
+		if !ok || limit < 1 || limit > apiMaxLimit {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.invalid_limit", apiMaxLimit),
+			})
+			return
+		}
+		offset, ok := queryInt(query.Get("offset"), 0)
+		if !ok || offset < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.invalid_offset"),
+			})
+			return
+		}
+		cursor := query.Get("cursor")
+		if cursor != "" && offset > 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.cursor_and_offset"),
+			})
+			return
+		}
+
+		version, err := store.Posts.GetFeedVersion(r.Context())
+		if err != nil {
+			log.Println("Error querying feed version:", err)
+		} else {
+			etag := versionETag(false, version, "api-posts", filter, category,
+				strconv.Itoa(authorID), strconv.Itoa(limit), strconv.Itoa(offset), cursor)
+			w.Header().Set("Cache-Control", "no-cache")
+			if notModified(w, r, etag, version.LastModified) {
+				return
+			}
+		}
+
+		var posts []models.PostSummary
+		var nextCursor string
+		if offset > 0 {
+			posts, err = store.Posts.GetPostSummaries(r.Context(), filter, category, authorID, apiExcerptLen+1, limit, offset)
+		} else {
+			posts, nextCursor, err = store.Posts.GetPostSummariesAfter(r.Context(), filter, category, authorID, apiExcerptLen+1, limit, cursor)
+		}
+		if errors.Is(err, database.ErrInvalidCursor) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.invalid_cursor"),
+			})
+			return
+		}
+		if err != nil {
+			log.Println("Error querying post summaries:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.server_error"),
+			})
+			return
+		}
+		for i := range posts {
+			posts[i].Excerpt = excerpt(posts[i].Excerpt, apiExcerptLen)
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success":     true,
+			"posts":       posts,
+			"limit":       limit,
+			"offset":      offset,
+			"next_cursor": nextCursor,
+		})
+	}
+}
+
+// APIActivityHandler возвращает активность пользователя user_id по дням за последний год для тепловой карты
+// профиля: days содержит только дни с постами или комментариями (удалённые не считаются), from и to — границы
+// периода по местному времени сервера, total — сумма, max — наибольшее число за день.
+// Отдаёт сильный ETag по версии данных и текущей дате, как APIPostsHandler.
+func APIActivityHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := queryInt(r.URL.Query().Get("user_id"), 0)
+		if !ok || userID <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.invalid_user_id"),
+			})
+			return
+		}
+		if _, err := store.Users.GetUsernameByID(r.Context(), userID); err != nil {
+			code, key := http.StatusNotFound, "api.user_not_found"
+			if err != sql.ErrNoRows {
+				log.Println("Error querying user:", err)
+				code, key = http.StatusInternalServerError, "api.server_error"
+			}
+			writeJSON(w, code, map[string]interface{}{
+				"success": false,
+				"message": tr(r, key),
+			})
+			return
+		}
+
+		to := time.Now()
+		from := to.AddDate(0, 0, 1-activityDays)
+		version, err := store.Posts.GetFeedVersion(r.Context())
+		if err != nil {
+			log.Println("Error querying feed version:", err)
+		} else {
+			etag := versionETag(false, version, "api-activity", strconv.Itoa(userID), to.Format("2006-01-02"))
+			w.Header().Set("Cache-Control", "no-cache")
+			if notModified(w, r, etag, version.LastModified) {
+				return
+			}
+		}
+
+		days, err := store.Users.GetUserActivity(r.Context(), userID, from)
+		if err != nil {
+			log.Println("Error querying user activity:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.server_error"),
+			})
+			return
+		}
+		total, maxCount := 0, 0
+		for _, d := range days {
+			total += d.Count
+			maxCount = max(maxCount, d.Count)
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"user_id": userID,
+			"from":    from.Format("2006-01-02"),
+			"to":      to.Format("2006-01-02"),
+			"days":    days,
+			"total":   total,
+			"max":     maxCount,
+		})
+	}
+}
+
+// APIFeedHandler отдаёт следующую страницу ленты для бесконечной прокрутки главной страницы и /b/{board}:
+// карточки постов готовым HTML (тот же фрагмент post-card, что и в ленте) и курсор следующей страницы.
+// Принимает GET-запрос с параметрами filter, category, board, hidden, author, days, from, to и cursor, как у ленты;
+// пустой next_cursor означает, что постов больше нет. ETag зависит от пользователя и языка, как у главной страницы.
+func APIFeedHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := query.Get("filter")
+		if filter == "" {
+			filter = "new"
+		}
+		if !feedFilters[filter] {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.invalid_filter"),
+			})
+			return
+		}
+		category := query.Get("category")
+		if category != "" && !slices.Contains(categorySlugs, category) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.invalid_category"),
+			})
+			return
+		}
+		board := query.Get("board")
+		if board != "" {
+			if _, err := store.Boards.GetBoardBySlug(r.Context(), board); err == sql.ErrNoRows {
+				writeJSON(w, http.StatusNotFound, map[string]interface{}{
+					"success": false,
+					"message": tr(r, "api.board_not_found"),
+				})
+				return
+			} else if err != nil {
+				log.Println("Error fetching board:", err)
+				writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+					"success": false,
+					"message": tr(r, "api.server_error"),
+				})
+				return
+			}
+		}
+		showHidden := query.Get("hidden") == "1"
+		author := strings.TrimSpace(query.Get("author"))
+		cursor := query.Get("cursor")
+		from, to, ok := feedPeriod(query, time.Now())
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.invalid_period"),
+			})
+			return
+		}
+
+		isAuth, userID, role := IsAuthenticated(store, r)
+		if personalFeedFilters[filter] && !isAuth {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.auth_required"),
+			})
+			return
+		}
+
+		version, err := store.Posts.GetFeedVersion(r.Context())
+		if err != nil {
+			log.Println("Error querying feed version:", err)
+		} else {
+			etag := versionETag(true, version, "api-feed", board, filter, category, strconv.FormatBool(showHidden), author, from.Format(time.DateOnly), to.Format(time.DateOnly), cursor,
+				strconv.Itoa(userID), role, i18n.FromContext(r.Context()), readState(store, r, userID))
+			w.Header().Set("Cache-Control", "no-cache")
+			if userID != 0 {
+				setPrivateCaching(w)
+			}
+			if notModified(w, r, etag, version.LastModified) {
+				return
+			}
+		}
+
+		feed := database.FeedQuery{Filter: filter, Category: category, Board: board, ShowHidden: showHidden, Author: author, From: from, To: to}
+		posts, nextCursor, err := store.Posts.GetFeedPage(r.Context(), userID, feed, feedPageSize, cursor)
+		if errors.Is(err, database.ErrInvalidCursor) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.invalid_cursor"),
+			})
+			return
+		}
+		if err != nil {
+			log.Println("Error querying feed page:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.server_error"),
+			})
+			return
+		}
+
+		if err := attachLatestComments(r.Context(), store, posts, filter, userID); err != nil {
+			log.Println("Error querying latest comments:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.server_error"),
+			})
+			return
+		}
+
+		tmpl, err := pageTemplate(r, "index.html")
+		if err != nil {
+			log.Println("Error parsing template:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.server_error"),
+			})
+			return
+		}
+		var html bytes.Buffer
+		for _, p := range posts {
+			p.CreatedAtStr = p.CreatedAt.Format(time.DateOnly)
+			if err := tmpl.ExecuteTemplate(&html, "post-card", p); err != nil {
+				log.Println("Error executing post card template:", err)
+				writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+					"success": false,
+					"message": tr(r, "api.server_error"),
+				})
+				return
+			}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success":     true,
+			"html":        html.String(),
+			"count":       len(posts),
+			"next_cursor": nextCursor,
+		})
+	}
+}
+
+// APICommentsHandler возвращает JSON-страницу комментариев к посту (от новых к старым).
+// Принимает GET-запрос с параметрами post_id, limit и cursor; страницы выбираются по ключу,
+// а next_cursor из ответа передаётся в cursor для следующей страницы.
+// Для авторизованного пользователя в каждом комментарии возвращается его голос.
+// С format=html вместо списка comments возвращается html с готовой разметкой комментариев (тот же фрагмент,
+// что и на странице поста) — так страница поста подгружает комментарии при прокрутке.
+func APICommentsHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		postID, ok := queryInt(query.Get("post_id"), 0)
+		if !ok || postID <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.invalid_post_id"),
+			})
+			return
+		}
+		limit, ok := queryInt(query.Get("limit"), apiDefaultLimit)
+		if !ok || limit < 1 || limit > apiMaxLimit {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.invalid_limit", apiMaxLimit),
+			})
+			return
+		}
+		cursor := query.Get("cursor")
+		format := query.Get("format")
+		if format != "" && format != "json" && format != "html" {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.invalid_format"),
+			})
+			return
+		}
+
+		isAuth, userID, role := IsAuthenticated(store, r)
+		// Комментарии к посту только для авторизованных анонимному посетителю не выдаются, как и сам пост.
+		if !isAuth {
+			post, err := store.Posts.GetPostByID(r.Context(), postID, 0)
+			if err == sql.ErrNoRows || err == nil && post.MembersOnly {
+				writeJSON(w, http.StatusNotFound, map[string]interface{}{
+					"success": false,
+					"message": tr(r, "api.post_not_found"),
+				})
+				return
+			}
+			if err != nil {
+				log.Println("Error fetching post:", err)
+				writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+					"success": false,
+					"message": tr(r, "api.server_error"),
+				})
+				return
+			}
+		}
+		version, err := store.Posts.GetPostVersion(r.Context(), postID)
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.post_not_found"),
+			})
+			return
+		}
+		if err != nil {
+			log.Println("Error querying post version:", err)
+		} else {
+			etag := versionETag(false, version, "api-comments", strconv.Itoa(postID), strconv.Itoa(userID),
+				strconv.Itoa(limit), cursor, format, role, i18n.FromContext(r.Context()))
+			w.Header().Set("Cache-Control", "no-cache")
+			if userID != 0 {
+				setPrivateCaching(w)
+			}
+			if notModified(w, r, etag, version.LastModified) {
+				return
+			}
+		}
+
+		comments, nextCursor, err := store.Comments.GetCommentsPage(r.Context(), userID, postID, limit, cursor)
+		if errors.Is(err, database.ErrInvalidCursor) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.invalid_cursor"),
+			})
+			return
+		}
+		if err != nil {
+			log.Println("Error querying comments page:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"success": false,
+				"message": tr(r, "api.server_error"),
+			})
+			return
+		}
+
+		if format == "html" {
+			writeCommentsHTML(w, r, store, isAuth, userID, role, postID, comments, nextCursor)
+			return
+		}
+
+		summaries := make([]models.CommentSummary, len(comments))
+		for i, c := range comments {
+			summaries[i] = models.CommentSummary{
+				ID:        c.ID,
+				UserID:    c.UserID,
+				Username:  c.Username,
+				Content:   c.Content,
+				CreatedAt: c.CreatedAt,
+				Likes:     c.Likes,
+				Dislikes:  c.Dislikes,
+				UserVote:  c.UserVote,
+			}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success":     true,
+			"comments":    summaries,
+			"limit":       limit,
+			"next_cursor": nextCursor,
+		})
+	}
+}
+
+// writeCommentsHTML отвечает на /api/comments?format=html: разметка комментариев строится шаблоном "comments"
+// с теми же правами на удаление, что и на странице поста (автор, администратор, модератор раздела).
+func writeCommentsHTML(w http.ResponseWriter, r *http.Request, store *database.Store, isAuth bool, userID int, role string,
+	postID int, comments []models.CommentData, nextCursor string) {
+	var isModerator bool
+	if isAuth {
+		var err error
+		if isModerator, err = store.Boards.ModeratesPost(r.Context(), userID, postID); err != nil {
+			log.Println("Error checking board moderator:", err)
+		}
+	}
+	for i := range comments {
+		comments[i].CreatedAtStr = comments[i].CreatedAt.Format(time.DateOnly)
+	}
+	data := models.PageData{
+		IsAuthenticated: isAuth,
+		UserID:          userID,
+		Role:            role,
+		IsModerator:     isModerator,
+		Post:            models.PostData{ID: postID, Comments: comments},
+	}
+
+	var html bytes.Buffer
+	tmpl, err := pageTemplate(r, "post.html")
+	if err == nil {
+		err = tmpl.ExecuteTemplate(&html, "comments", data)
+	}
+	if err != nil {
+		log.Println("Error executing comments template:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"message": tr(r, "api.server_error"),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"html":        html.String(),
+		"count":       len(comments),
+		"next_cursor": nextCursor,
+	})
+}
+
+// writeJSON отправляет JSON-ответ с указанным кодом статуса.
+func writeJSON(w http.ResponseWriter, code int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		log.Println("Error encoding JSON response:", err)
+	}
+}
+
+// queryInt разбирает целочисленный параметр запроса.
+// Возвращает значение по умолчанию, если параметр пуст, и false, если он не является числом.
+func queryInt(value string, def int) (int, bool) {
+	if value == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// pathID разбирает числовой параметр пути name (например, {id} в /post/{id}).
+// Возвращает false, если параметр не является положительным числом.
+func pathID(r *http.Request, name string) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue(name))
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// excerpt обрезает текст до maxLen символов по границе слова и добавляет многоточие.
+func excerpt(text string, maxLen int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= maxLen {
+		return text
+	}
+	runes := []rune(text)[:maxLen]
+	cut := string(runes)
+	if i := strings.LastIndex(cut, " "); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " .,;:") + "…"
+}
@@ -1,62 +1,124 @@
 package handlers
 
 import (
+	"bytes"
 	"database/sql"
-	"html/template"
+	"errors"
 	"log"
 	"net/http"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"forum/botcheck"
 	"forum/database"
+	"forum/i18n"
 	"forum/models"
+	"forum/proxy"
+	"forum/usernames"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var ErrorTpl = template.Must(template.ParseFiles("templates/error.html"))
+// errorPage — данные шаблона error.html; ErrorID показывается, если задан.
+type errorPage struct {
+	Code    int
+	Message string
+	ErrorID string
+}
 
-func writeError(wr http.ResponseWriter, code int) {
-	ErrorTpl.Execute(wr, struct {
-		Code    int
-		Message string
-	}{
+func writeError(wr http.ResponseWriter, r *http.Request, code int) {
+	tmpl, err := pageTemplate(r, "error.html")
+	if err != nil {
+		http.Error(wr, statusText(r, code), code)
+		return
+	}
+	tmpl.Execute(wr, errorPage{
 		Code:    code,
-		Message: http.StatusText(code),
+		Message: statusText(r, code),
+	})
+}
+
+// InternalError отвечает 500 с идентификатором ошибки errorID, по которому запрос можно найти в журнале:
+// JSON, если клиент его предпочитает (см. MethodNotAllowed), иначе страницей ошибки.
+func InternalError(w http.ResponseWriter, r *http.Request, errorID string) {
+	if acceptsJSON(r) {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"success":  false,
+			"message":  tr(r, "api.internal_error"),
+			"error_id": errorID,
+		})
+		return
+	}
+	var buf bytes.Buffer
+	err := Render(&buf, r, "error.html", errorPage{
+		Code:    http.StatusInternalServerError,
+		Message: statusText(r, http.StatusInternalServerError),
+		ErrorID: errorID,
 	})
+	if err != nil {
+		log.Println("Error executing error template:", err)
+		http.Error(w, statusText(r, http.StatusInternalServerError)+". "+tr(r, "error.id")+" "+errorID, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	buf.WriteTo(w)
+}
+
+// MethodNotAllowed отвечает 405 на запрос с неподдерживаемым методом: JSON, если клиент
+// предпочитает application/json (fetch из script.js, мобильный клиент), иначе страницей ошибки.
+// Заголовок Allow устанавливает вызывающий.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	log.Println("Method not allowed:", r.Method, r.URL.Path)
+	if acceptsJSON(r) {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]interface{}{
+			"success": false,
+			"message": tr(r, "api.method_not_allowed"),
+		})
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	writeError(w, r, http.StatusMethodNotAllowed)
+}
+
+// acceptsJSON сообщает, что в заголовке Accept указан application/json раньше text/html или без него.
+func acceptsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	j := strings.Index(accept, "application/json")
+	if j < 0 {
+		return false
+	}
+	h := strings.Index(accept, "text/html")
+	return h < 0 || j < h
 }
 
 // UpdateProfileHandler updates username and display_name for the authenticated user.
-func UpdateProfileHandler(db *sql.DB) http.HandlerFunc {
+func UpdateProfileHandler(store *database.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		isAuth, userID, _ := IsAuthenticated(db, r)
+		isAuth, userID, _ := IsAuthenticated(store, r)
 		if !isAuth {
-			writeError(w, http.StatusUnauthorized)
-			return
-		}
-
-		if r.Method != "POST" {
-			w.Header().Set("Content-Type", "text/plain")
-			w.WriteHeader(http.StatusMethodNotAllowed)
+			writeError(w, r, http.StatusUnauthorized)
 			return
 		}
 
 		if err := r.ParseForm(); err != nil {
 			log.Println("Error parsing form:", err)
-			writeError(w, http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest)
 			return
 		}
 
 		newUsername := strings.TrimSpace(r.FormValue("username"))
 		newDisplayName := strings.TrimSpace(r.FormValue("display_name"))
 
-		currentUsername, err := database.GetUsernameByID(db, userID)
+		currentUsername, err := store.Users.GetUsernameByID(r.Context(), userID)
 		if err != nil {
 			log.Println("Error fetching current username:", err)
-			writeError(w, http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
 			return
 		}
 
@@ -64,28 +126,35 @@ func UpdateProfileHandler(db *sql.DB) http.HandlerFunc {
 		if newUsername == "" {
 			newUsername = currentUsername
 		} else if newUsername != currentUsername {
+			// Прежние имена остаются в силе, но новое имя должно соответствовать правилам имён
+			if err := usernameRules.Check(newUsername); err != nil {
+				log.Printf("Username change of user %d to %q rejected: %v.", userID, newUsername, err)
+				w.WriteHeader(http.StatusBadRequest)
+				writeError(w, r, http.StatusBadRequest)
+				return
+			}
 			// Check uniqueness
-			exists, err := database.UsernameExists(db, newUsername)
+			exists, err := store.Users.UsernameExists(r.Context(), newUsername)
 			if err != nil {
 				log.Println("Error checking username existence:", err)
-				writeError(w, http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
 			if exists {
-				writeError(w, http.StatusBadRequest)
+				writeError(w, r, http.StatusBadRequest)
 				return
 			}
 		}
 
 		// If no display name provided, try to keep existing
-		currentDisplayName, _ := database.GetDisplayName(db, userID)
+		currentDisplayName, _ := store.Users.GetDisplayName(r.Context(), userID)
 		if newDisplayName == "" {
 			newDisplayName = currentDisplayName
 		}
 
-		if err := database.UpdateUserProfile(db, userID, newUsername, newDisplayName); err != nil {
+		if err := store.Users.UpdateUserProfile(r.Context(), userID, newUsername, newDisplayName); err != nil {
 			log.Println("Error updating user profile:", err)
-			writeError(w, http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
 			return
 		}
 
@@ -93,15 +162,64 @@ func UpdateProfileHandler(db *sql.DB) http.HandlerFunc {
 	}
 }
 
+// PinPostHandler закрепляет пост /post/{id}/pin вверху профиля его автора (pinned=1) или снимает закрепление.
+// Закрепить можно только свой пост; в профиле закреплён не больше одного поста. Возвращает в профиль.
+func PinPostHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		postID, ok := pathID(r, "id")
+		if !ok {
+			return
+		}
+		isAuth, userID, _ := IsAuthenticated(store, r)
+		if !isAuth {
+			writeError(w, r, http.StatusUnauthorized)
+			return
+		}
+		_, err := store.Posts.GetPostByIDAndUserID(r.Context(), postID, userID)
+		if err == sql.ErrNoRows {
+			w.WriteHeader(http.StatusForbidden)
+			writeError(w, r, http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching post:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		pinned, err := store.Users.GetPinnedPostID(r.Context(), userID)
+		if err != nil {
+			log.Println("Error fetching pinned post:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		switch {
+		case r.FormValue("pinned") == "1":
+			pinned = postID
+		case pinned == postID:
+			pinned = 0
+		}
+		if err := store.Users.SetPinnedPost(r.Context(), userID, pinned); err != nil {
+			log.Println("Error pinning post:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/profile/"+strconv.Itoa(userID), http.StatusSeeOther)
+	}
+}
+
 // IsAuthenticated проверяет, аутентифицирован ли пользователь.
 // Возвращает true, userID и роль, если сессия действительна, иначе false, 0 и пустую строку.
-func IsAuthenticated(db *sql.DB, r *http.Request) (bool, int, string) {
+func IsAuthenticated(store *database.Store, r *http.Request) (bool, int, string) {
 	cookie, err := r.Cookie("session_id")
 	if err != nil {
 		return false, 0, ""
 	}
 
-	userID, role, expiry, err := database.GetSessionData(db, cookie.Value)
+	userID, role, expiry, err := store.Users.GetSessionData(r.Context(), cookie.Value)
 	if err == sql.ErrNoRows {
 		return false, 0, ""
 	}
@@ -111,7 +229,7 @@ func IsAuthenticated(db *sql.DB, r *http.Request) (bool, int, string) {
 	}
 
 	if expiry.Before(time.Now()) {
-		err := database.DeleteExpiredSession(db, cookie.Value)
+		err := store.Users.DeleteExpiredSession(r.Context(), cookie.Value)
 		if err != nil {
 			log.Println("Error deleting expired session:", err)
 		}
@@ -132,9 +250,11 @@ func IsAuthenticated(db *sql.DB, r *http.Request) (bool, int, string) {
 // RegisterHandler регистрирует нового пользователя.
 // При GET отображает форму регистрации, при POST выполняет регистрацию.
 // Перенаправляет аутентифицированных пользователей на главную страницу.
-func RegisterHandler(db *sql.DB) http.HandlerFunc {
+// Перед регистрацией форма проверяется на отправку программой (см. пакет botcheck):
+// заполнившей ловушку программе показывается обычное сообщение об успехе, но пользователь не создаётся.
+func RegisterHandler(store *database.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		isAuth, userID, role := IsAuthenticated(db, r)
+		isAuth, userID, role := IsAuthenticated(store, r)
 		if isAuth {
 			http.Redirect(w, r, "/", http.StatusSeeOther)
 			return
@@ -143,147 +263,208 @@ func RegisterHandler(db *sql.DB) http.HandlerFunc {
 		if r.Method == "POST" {
 			if err := r.ParseForm(); err != nil {
 				log.Println("Error parsing form:", err)
-				writeError(w, http.StatusBadRequest)
+				writeError(w, r, http.StatusBadRequest)
 				return
 			}
 			email := strings.TrimSpace(r.FormValue("email"))
 			username := strings.TrimSpace(r.FormValue("username"))
 			password := r.FormValue("password")
 
-			if email == "" || username == "" || password == "" {
-				tmpl, err := template.ParseFiles("templates/register.html")
+			if err := registrationForm.Check(r.FormValue(botcheck.TokenField), r.FormValue(botcheck.HoneypotField), time.Now()); err != nil {
+				log.Printf("Registration of %q rejected: %v.", email, err)
+				switch err {
+				case botcheck.ErrHoneypot:
+					renderRegister(w, r, store, models.PageData{Message: tr(r, "register.success")})
+				case botcheck.ErrTooFast:
+					renderRegister(w, r, store, models.PageData{ErrorMessage: tr(r, "register.error.too_fast")})
+				default:
+					renderRegister(w, r, store, models.PageData{ErrorMessage: tr(r, "register.error.form_expired")})
+				}
+				return
+			}
+
+			invite := strings.TrimSpace(r.FormValue("invite"))
+			if invite == "" {
+				inviteOnly, err := inviteOnlyRegistration(r, store)
 				if err != nil {
-					log.Println("Error parsing register template:", err)
-					writeError(w, http.StatusInternalServerError)
+					log.Println("Error reading settings:", err)
+					writeError(w, r, http.StatusInternalServerError)
 					return
 				}
-				pageData := models.PageData{ErrorMessage: "All fields are required."}
-				w.Header().Set("Content-Type", "text/html; charset=utf-8")
-				if err := tmpl.Execute(w, pageData); err != nil {
-					log.Println("Error executing register template:", err)
+				if inviteOnly {
+					renderRegister(w, r, store, models.PageData{ErrorMessage: tr(r, "register.error.invite_required")})
+					return
 				}
+			}
+
+			if email == "" || username == "" || password == "" {
+				renderRegister(w, r, store, models.PageData{ErrorMessage: tr(r, "register.error.required")})
 				return
 			}
 
 			emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
 			if !emailRegex.MatchString(email) {
-				tmpl, err := template.ParseFiles("templates/register.html")
-				if err != nil {
-					log.Println("Error parsing register template:", err)
-					writeError(w, http.StatusInternalServerError)
-					return
-				}
-				pageData := models.PageData{ErrorMessage: "Invalid email format."}
-				w.Header().Set("Content-Type", "text/html; charset=utf-8")
-				if err := tmpl.Execute(w, pageData); err != nil {
-					log.Println("Error executing register template:", err)
-				}
+				renderRegister(w, r, store, models.PageData{ErrorMessage: tr(r, "register.error.email")})
+				return
+			}
+			if disposableDomains.Contains(email) {
+				log.Printf("Registration of %q rejected: disposable email domain.", email)
+				renderRegister(w, r, store, models.PageData{ErrorMessage: tr(r, "register.error.disposable_email")})
+				return
+			}
+			allowed, err := emailDomainAllowed(r, store, email)
+			if err != nil {
+				log.Println("Error reading settings:", err)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
+				log.Printf("Registration of %q rejected: email domain is not allowed.", email)
+				renderRegister(w, r, store, models.PageData{ErrorMessage: tr(r, "register.error.email_domain")})
 				return
 			}
 
-			emailExists, err := database.EmailExists(db, email)
+			emailExists, err := store.Users.EmailExists(r.Context(), email)
 			if err != nil {
 				log.Println("Error checking email:", err)
-				writeError(w, http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
 			if emailExists {
-				tmpl, err := template.ParseFiles("templates/register.html")
-				if err != nil {
-					log.Println("Error parsing register template:", err)
-					writeError(w, http.StatusInternalServerError)
-					return
-				}
-				pageData := models.PageData{ErrorMessage: "Email already taken."}
-				w.Header().Set("Content-Type", "text/html; charset=utf-8")
-				if err := tmpl.Execute(w, pageData); err != nil {
-					log.Println("Error executing register template:", err)
-				}
+				renderRegister(w, r, store, models.PageData{ErrorMessage: tr(r, "register.error.email_taken")})
 				return
 			}
 
-			usernameExists, err := database.UsernameExists(db, username)
+			if err := usernameRules.Check(username); err != nil {
+				log.Printf("Registration of %q rejected: username %q: %v.", email, username, err)
+				renderRegister(w, r, store, models.PageData{ErrorMessage: usernameError(r, err)})
+				return
+			}
+
+			usernameExists, err := store.Users.UsernameExists(r.Context(), username)
 			if err != nil {
 				log.Println("Error checking username:", err)
-				writeError(w, http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
 			if usernameExists {
-				tmpl, err := template.ParseFiles("templates/register.html")
-				if err != nil {
-					log.Println("Error parsing register template:", err)
-					writeError(w, http.StatusInternalServerError)
-					return
-				}
-				pageData := models.PageData{ErrorMessage: "Username already taken."}
-				w.Header().Set("Content-Type", "text/html; charset=utf-8")
-				if err := tmpl.Execute(w, pageData); err != nil {
-					log.Println("Error executing register template:", err)
-				}
+				renderRegister(w, r, store, models.PageData{ErrorMessage: tr(r, "register.error.username_taken")})
 				return
 			}
 
 			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 			if err != nil {
 				log.Println("Error hashing password:", err)
-				writeError(w, http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
 
-			err = database.RegisterUser(db, email, username, string(hashedPassword))
+			if invite != "" {
+				err = database.RegisterInvitedUser(r.Context(), store.DB, invite, email, username, string(hashedPassword))
+			} else {
+				err = store.Users.RegisterUser(r.Context(), email, username, string(hashedPassword))
+			}
+			if errors.Is(err, database.ErrInvalidInvite) {
+				renderRegister(w, r, store, models.PageData{ErrorMessage: tr(r, "register.error.invite_invalid")})
+				return
+			}
 			if err != nil {
 				log.Println("Error inserting user:", err)
-				writeError(w, http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
 
-			tmpl, err := template.ParseFiles("templates/register.html")
-			if err != nil {
-				log.Println("Error parsing register template:", err)
-				writeError(w, http.StatusInternalServerError)
+			if verifyTokens != nil {
+				newUserID, _, _, _, err := store.Users.GetUserByEmail(r.Context(), email)
+				if err == nil {
+					err = database.SetEmailVerified(r.Context(), store.DB, newUserID, false)
+				}
+				if err != nil {
+					log.Println("Error marking email unverified:", err)
+					writeError(w, r, http.StatusInternalServerError)
+					return
+				}
+				// Письмо, которое не удалось отправить, пользователь запросит заново после входа.
+				if err := sendVerificationEmail(r, newUserID, email); err != nil {
+					log.Println("Error sending verification email:", err)
+				}
+				renderRegister(w, r, store, models.PageData{Message: tr(r, "register.success_verify", email)})
 				return
 			}
-			pageData := models.PageData{Message: "Registration successful, please login."}
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			if err := tmpl.Execute(w, pageData); err != nil {
-				log.Println("Error executing register template:", err)
-			}
-			return
-		}
 
-		tmpl, err := template.ParseFiles("templates/register.html")
-		if err != nil {
-			log.Println("Error parsing register template:", err)
-			writeError(w, http.StatusInternalServerError)
+			renderRegister(w, r, store, models.PageData{Message: tr(r, "register.success")})
 			return
 		}
-		pageData := models.PageData{
+
+		renderRegister(w, r, store, models.PageData{
 			IsAuthenticated: isAuth,
 			UserID:          userID,
 			Username:        "",
 			Role:            role,
-			ErrorMessage:    r.URL.Query().Get("error"),
+			ErrorMessage:    flash(r, "error", "register.error."),
 			Filter:          "",
-		}
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		if err := tmpl.Execute(w, pageData); err != nil {
-			log.Println("Error executing register template:", err)
-		}
+		})
+	}
+}
+
+// emailDomainAllowed проверяет домен адреса email по спискам администратора (database.SettingEmailAllowDomains
+// и database.SettingEmailBlockDomains): непустой список разрешённых доменов пропускает только их,
+// запрещённые домены отклоняются всегда.
+func emailDomainAllowed(r *http.Request, store *database.Store, email string) (bool, error) {
+	allow, err := store.Settings.GetSetting(r.Context(), database.SettingEmailAllowDomains)
+	if err != nil {
+		return false, err
 	}
+	block, err := store.Settings.GetSetting(r.Context(), database.SettingEmailBlockDomains)
+	if err != nil {
+		return false, err
+	}
+	if allowed := botcheck.NewDomains(strings.Fields(allow)...); len(allowed) > 0 && !allowed.Contains(email) {
+		return false, nil
+	}
+	return !botcheck.NewDomains(strings.Fields(block)...).Contains(email), nil
+}
+
+// usernameError возвращает сообщение об имени, отклонённом правилами usernameRules с ошибкой err.
+func usernameError(r *http.Request, err error) string {
+	switch err {
+	case usernames.ErrCharset:
+		return tr(r, "register.error.username_charset")
+	case usernames.ErrReserved:
+		return tr(r, "register.error.username_reserved")
+	case usernames.ErrProfane:
+		return tr(r, "register.error.username_profane")
+	}
+	return tr(r, "register.error.username_length", usernameRules.MinLength, usernameRules.MaxLength)
+}
+
+// renderRegister показывает страницу регистрации с данными data, новой меткой времени формы
+// и кодом приглашения из запроса.
+func renderRegister(w http.ResponseWriter, r *http.Request, store *database.Store, data models.PageData) {
+	inviteOnly, err := inviteOnlyRegistration(r, store)
+	if err != nil {
+		log.Println("Error reading settings:", err)
+		writeError(w, r, http.StatusInternalServerError)
+		return
+	}
+	data.FormToken = registrationForm.Token(time.Now())
+	data.Invite = strings.TrimSpace(r.FormValue("invite"))
+	data.InviteOnly = inviteOnly
+	renderPage(w, r, "register.html", data)
 }
 
 // LoginHandler выполняет вход пользователя.
 // При GET перенаправляет на главную страницу, при POST аутентифицирует пользователя и создаёт сессию.
 // Перенаправляет аутентифицированных пользователей на указанный URL или главную страницу.
-func LoginHandler(db *sql.DB) http.HandlerFunc {
+func LoginHandler(store *database.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if db == nil {
+		if store == nil {
 			log.Println("Database is not initialized.")
-			writeError(w, http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
 			return
 		}
 
-		isAuth, _, _ := IsAuthenticated(db, r)
+		isAuth, _, _ := IsAuthenticated(store, r)
 		if isAuth {
 			redirectURL := r.URL.Query().Get("redirect")
 			if redirectURL == "" {
@@ -295,7 +476,7 @@ func LoginHandler(db *sql.DB) http.HandlerFunc {
 
 		if r.Method == "GET" {
 			log.Println("Redirecting GET /login to /.")
-			http.Redirect(w, r, "/?message=Login+please", http.StatusSeeOther)
+			http.Redirect(w, r, "/?message=login_required", http.StatusSeeOther)
 			return
 		}
 
@@ -303,7 +484,7 @@ func LoginHandler(db *sql.DB) http.HandlerFunc {
 			log.Println("Processing login attempt.")
 			if err := r.ParseForm(); err != nil {
 				log.Println("Error parsing form:", err)
-				http.Redirect(w, r, "/?login_error=Bad request", http.StatusSeeOther)
+				http.Redirect(w, r, "/?login_error=bad_request", http.StatusSeeOther)
 				return
 			}
 			email := strings.TrimSpace(r.FormValue("email"))
@@ -311,50 +492,36 @@ func LoginHandler(db *sql.DB) http.HandlerFunc {
 
 			if email == "" || password == "" {
 				log.Println("Empty email or password.")
-				http.Redirect(w, r, "/?login_error=Email and password are required", http.StatusSeeOther)
+				http.Redirect(w, r, "/?login_error=required", http.StatusSeeOther)
 				return
 			}
 
-			userID, _, hashedPassword, role, err := database.GetUserByEmail(db, email)
+			userID, _, hashedPassword, role, err := store.Users.GetUserByEmail(r.Context(), email)
 			if err != nil {
 				log.Printf("Error fetching user with email %s: %v", email, err)
-				http.Redirect(w, r, "/?login_error=Invalid email or password", http.StatusSeeOther)
+				http.Redirect(w, r, "/?login_error=invalid", http.StatusSeeOther)
 				return
 			}
 
 			err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 			if err != nil {
 				log.Printf("Invalid password for email %s.", email)
-				http.Redirect(w, r, "/?login_error=Invalid email or password", http.StatusSeeOther)
+				http.Redirect(w, r, "/?login_error=invalid", http.StatusSeeOther)
 				return
 			}
 
-			err = database.DeleteUserSessions(db, userID)
-			if err != nil {
-				log.Println("Error deleting old sessions:", err)
-				writeError(w, http.StatusInternalServerError)
+			if role == "banned" {
+				log.Printf("Banned user %d attempted to log in.", userID)
+				http.Redirect(w, r, "/?login_error=banned", http.StatusSeeOther)
 				return
 			}
 
-			sessionID := uuid.New().String()
-			expiry := time.Now().Add(24 * time.Hour)
-			err = database.CreateSession(db, sessionID, userID, role, expiry)
-			if err != nil {
-				log.Println("Error saving session:", err)
-				writeError(w, http.StatusInternalServerError)
+			if err := startSession(w, r, store, userID, role); err != nil {
+				log.Println("Error starting session:", err)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
 
-			cookie := http.Cookie{
-				Name:     "session_id",
-				Value:    sessionID,
-				Path:     "/",
-				HttpOnly: true,
-				MaxAge:   24 * 60 * 60,
-				SameSite: http.SameSiteLaxMode,
-			}
-			http.SetCookie(w, &cookie)
-
 			redirectURL := r.URL.Query().Get("redirect")
 			if redirectURL == "" {
 				redirectURL = "/"
@@ -362,20 +529,48 @@ func LoginHandler(db *sql.DB) http.HandlerFunc {
 			http.Redirect(w, r, redirectURL, http.StatusSeeOther)
 			return
 		}
+	}
+}
 
-		log.Println("Method not allowed:", r.Method)
-		w.Header().Set("Allow", "GET, POST")
-		writeError(w, http.StatusMethodNotAllowed)
+// startSession открывает пользователю userID с ролью role новую сессию вместо прежних и ставит её cookie.
+func startSession(w http.ResponseWriter, r *http.Request, store *database.Store, userID int, role string) error {
+	if err := store.Users.DeleteUserSessions(r.Context(), userID); err != nil {
+		return err
 	}
+
+	sessionID := uuid.New().String()
+	expiry := time.Now().Add(sessionLifetime)
+	if err := store.Users.CreateSession(r.Context(), sessionID, userID, role, expiry); err != nil {
+		return err
+	}
+
+	cookie := http.Cookie{
+		Name:     "session_id",
+		Value:    sessionID,
+		Path:     "/",
+		HttpOnly: true,
+		MaxAge:   int(sessionLifetime / time.Second),
+		SameSite: http.SameSiteLaxMode,
+		Secure:   proxy.IsSecure(r), // по HTTPS cookie не передаётся по незашифрованному соединению
+	}
+	http.SetCookie(w, &cookie)
+
+	// Язык, выбранный пользователем раньше, применяется и на этом устройстве.
+	if lang, err := store.Users.GetLanguage(r.Context(), userID); err != nil {
+		log.Println("Error fetching language preference:", err)
+	} else if i18n.Supported(lang) {
+		setLanguageCookie(w, r, lang)
+	}
+	return nil
 }
 
 // LogoutHandler выполняет выход пользователя.
 // Удаляет сессию из базы данных и очищает cookie, затем перенаправляет на главную страницу.
-func LogoutHandler(db *sql.DB) http.HandlerFunc {
+func LogoutHandler(store *database.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		cookie, err := r.Cookie("session_id")
 		if err == nil {
-			err = database.DeleteSession(db, cookie.Value)
+			err = store.Users.DeleteSession(r.Context(), cookie.Value)
 			if err != nil {
 				log.Println("Error deleting session:", err)
 			}
@@ -386,6 +581,7 @@ func LogoutHandler(db *sql.DB) http.HandlerFunc {
 				Expires:  time.Unix(0, 0),
 				Path:     "/",
 				HttpOnly: true,
+				Secure:   proxy.IsSecure(r),
 			})
 		}
 
@@ -394,81 +590,114 @@ func LogoutHandler(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-// ProfileHandler отображает профиль пользователя по его ID.
-// Включает посты пользователя с категориями и комментариями.
-func ProfileHandler(db *sql.DB) http.HandlerFunc {
+// ProfileHandler отображает профиль пользователя по его ID из пути /profile/{id}.
+// Включает посты пользователя с категориями и комментариями, число подписчиков и кнопку подписки.
+func ProfileHandler(store *database.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		isAuth, currentUserID, role := IsAuthenticated(db, r)
+		isAuth, currentUserID, role := IsAuthenticated(store, r)
 		var currentUsername string
 		if isAuth {
 			var err error
-			currentUsername, err = database.GetUsernameByID(db, currentUserID)
+			currentUsername, err = store.Users.GetUsernameByID(r.Context(), currentUserID)
 			if err != nil {
 				log.Println("Error fetching current username:", err)
-				writeError(w, http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
 		}
 
-		userIDStr := r.URL.Query().Get("user_id")
-		if userIDStr == "" {
-			writeError(w, http.StatusBadRequest)
+		userID, ok := pathID(r, "id")
+		if !ok {
+			writeError(w, r, http.StatusBadRequest)
 			return
 		}
 
-		userID, err := strconv.Atoi(userIDStr)
+		profileUsername, createdAt, err := store.Users.GetUserProfileData(r.Context(), userID)
+		if err == sql.ErrNoRows {
+			writeError(w, r, http.StatusBadRequest)
+			return
+		}
 		if err != nil {
-			log.Println("Invalid user_id format:", err)
-			writeError(w, http.StatusBadRequest)
+			log.Println("Error querying user:", err)
+			writeError(w, r, http.StatusInternalServerError)
 			return
 		}
 
-		profileUsername, createdAt, err := database.GetUserProfileData(db, userID)
-		if err == sql.ErrNoRows {
-			writeError(w, http.StatusBadRequest)
+		displayName, err := store.Users.GetDisplayName(r.Context(), userID)
+		if err != nil {
+			log.Println("Error querying display name:", err)
+			writeError(w, r, http.StatusInternalServerError)
 			return
 		}
+		standing, err := store.Users.GetUserStanding(r.Context(), userID)
 		if err != nil {
-			log.Println("Error querying user:", err)
-			writeError(w, http.StatusInternalServerError)
+			log.Println("Error querying user standing:", err)
+			writeError(w, r, http.StatusInternalServerError)
 			return
 		}
 
-		posts, err := database.GetUserPosts(db, userID)
+		posts, err := store.Posts.GetUserPosts(r.Context(), userID, currentUserID)
 		if err != nil {
 			log.Println("Error querying user posts:", err)
-			writeError(w, http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		// Закреплённый пост выводится первым; удалённый закреплённый пост в список не попадает и не показывается.
+		pinnedPostID, err := store.Users.GetPinnedPostID(r.Context(), userID)
+		if err != nil {
+			log.Println("Error querying pinned post:", err)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		if i := slices.IndexFunc(posts, func(p models.PostData) bool { return p.ID == pinnedPostID }); i > 0 {
+			pinned := posts[i]
+			copy(posts[1:i+1], posts[:i])
+			posts[0] = pinned
+		}
+
+		ids := postIDs(posts)
+		categories, err := store.Posts.GetPostCategoriesByPostIDs(r.Context(), ids)
+		if err != nil {
+			log.Println("Error querying categories for posts:", err)
+			writeError(w, r, http.StatusInternalServerError)
 			return
 		}
 
 		for i := range posts {
 			posts[i].Username = profileUsername
-			categories, err := database.GetPostCategories(db, posts[i].ID)
-			if err != nil {
-				log.Println("Error querying categories for post:", err)
-				writeError(w, http.StatusInternalServerError)
-				return
-			}
-			posts[i].Categories = categories
-			if len(categories) > 0 {
-				posts[i].Category = categories[0]
+			posts[i].DisplayName = displayName
+			posts[i].Categories = categories[posts[i].ID]
+			if len(posts[i].Categories) > 0 {
+				posts[i].Category = posts[i].Categories[0].Name
 			}
+			posts[i].CreatedAtStr = createdAt.Format(time.DateOnly)
+		}
 
-			comments, err := database.GetCommentsByPostIDWithUserVote(db, currentUserID, posts[i].ID)
+		followers, err := database.CountFollowers(r.Context(), store.DB, userID)
+		if err != nil {
+			log.Println("Error counting followers:", err)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		var following bool
+		if isAuth && currentUserID != userID {
+			following, err = database.IsFollowing(r.Context(), store.DB, currentUserID, userID)
 			if err != nil {
-				log.Println("Error querying comments for post:", err)
-				writeError(w, http.StatusInternalServerError)
+				log.Println("Error checking follow:", err)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
-			posts[i].Comments = comments
-			posts[i].CreatedAtStr = createdAt.Format(time.DateOnly)
 		}
 
-		tmpl, err := template.ParseFiles("templates/profile.html")
-		if err != nil {
-			log.Println("Error parsing profile template:", err)
-			writeError(w, http.StatusInternalServerError)
-			return
+		var likesHidden bool
+		if isAuth && currentUserID == userID {
+			likesHidden, err = database.LikesHidden(r.Context(), store.DB, userID)
+			if err != nil {
+				log.Println("Error fetching likes privacy:", err)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
 		}
 
 		pageData := models.PageData{
@@ -479,11 +708,15 @@ func ProfileHandler(db *sql.DB) http.HandlerFunc {
 			Filter:           "",
 			Posts:            posts,
 			ProfileUsername:  profileUsername,
+			ProfileName:      displayName,
 			ProfileCreatedAt: createdAt.Format(time.DateOnly),
+			ProfileTrust:     privileges.Level(standingOf(standing, standing.Role), time.Now()).String(),
+			ProfileUserID:    userID,
+			PinnedPostID:     pinnedPostID,
+			Followers:        followers,
+			Following:        following,
+			LikesHidden:      likesHidden,
 		}
-		if err := tmpl.Execute(w, pageData); err != nil {
-			log.Println("Error executing profile template:", err)
-			writeError(w, http.StatusInternalServerError)
-		}
+		renderPage(w, r, "profile.html", pageData)
 	}
 }
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"forum/database"
+)
+
+// moderatesPost сообщает, модерирует ли пользователь раздел поста postID.
+// Ошибка запроса записывается в журнал и считается отказом в правах.
+func moderatesPost(r *http.Request, store *database.Store, userID, postID int) bool {
+	ok, err := store.Boards.ModeratesPost(r.Context(), userID, postID)
+	if err != nil {
+		log.Println("Error checking board moderator:", err)
+	}
+	return ok
+}
+
+// moderatesComment сообщает, модерирует ли пользователь раздел поста, к которому оставлен комментарий commentID.
+// Ошибка запроса записывается в журнал и считается отказом в правах.
+func moderatesComment(r *http.Request, store *database.Store, userID, commentID int) bool {
+	ok, err := store.Boards.ModeratesComment(r.Context(), userID, commentID)
+	if err != nil {
+		log.Println("Error checking board moderator:", err)
+	}
+	return ok
+}
@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"forum/database"
+	"forum/models"
+)
+
+// collectionPageData — данные страницы подборки закладок. IsOwner включает кнопки управления;
+// по ссылке /shared/{token} подборка всегда показывается только для чтения.
+type collectionPageData struct {
+	models.PageData
+	Collection models.Collection
+	IsOwner    bool
+}
+
+// CollectionsHandler обслуживает /collections: GET показывает подборки закладок пользователя,
+// POST создаёт новую подборку из поля name и открывает её. Гостей отправляет на вход.
+func CollectionsHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAuth, userID, role := IsAuthenticated(store, r)
+		if !isAuth {
+			http.Redirect(w, r, "/login?redirect=/collections", http.StatusSeeOther)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			name := strings.TrimSpace(r.FormValue("name"))
+			if !database.ValidCollectionName(name) {
+				http.Redirect(w, r, "/collections?error=name", http.StatusSeeOther)
+				return
+			}
+			id, err := store.Collections.CreateCollection(r.Context(), userID, name)
+			if err != nil {
+				log.Println("Error creating collection:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+			http.Redirect(w, r, "/collections/"+strconv.FormatInt(id, 10), http.StatusSeeOther)
+			return
+		}
+
+		username, err := store.Users.GetUsernameByID(r.Context(), userID)
+		if err != nil {
+			log.Println("Error fetching username:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		collections, err := store.Collections.ListUserCollections(r.Context(), userID, 0)
+		if err != nil {
+			log.Println("Error fetching collections:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		data := models.PageData{
+			IsAuthenticated: true,
+			UserID:          userID,
+			Username:        username,
+			Role:            role,
+			Collections:     collections,
+			ErrorMessage:    flash(r, "error", "collections.error."),
+		}
+		renderPage(w, r, "collections.html", data)
+	}
+}
+
+// CollectionHandler отображает владельцу его подборку /collections/{id}. Чужие и несуществующие подборки
+// не раскрываются: обработчик ничего не пишет, и CustomHandler отвечает 404.
+func CollectionHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, ok := ownCollection(w, r, store)
+		if !ok {
+			return
+		}
+		renderCollection(w, r, store, collection, true)
+	}
+}
+
+// SharedCollectionHandler отображает открытую подборку по ссылке /shared/{token} только для чтения.
+// Если ссылка неверна или доступ закрыт, ничего не пишет, и CustomHandler отвечает 404.
+func SharedCollectionHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, err := store.Collections.GetSharedCollection(r.Context(), r.PathValue("token"))
+		if err == sql.ErrNoRows {
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching shared collection:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		renderCollection(w, r, store, collection, false)
+	}
+}
+
+// renderCollection выводит страницу подборки; isOwner включает кнопки управления.
+func renderCollection(w http.ResponseWriter, r *http.Request, store *database.Store, collection models.Collection, isOwner bool) {
+	isAuth, userID, role := IsAuthenticated(store, r)
+	var username string
+	if isAuth {
+		var err error
+		if username, err = store.Users.GetUsernameByID(r.Context(), userID); err != nil {
+			log.Println("Error fetching username:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	data := collectionPageData{
+		PageData:   models.PageData{IsAuthenticated: isAuth, UserID: userID, Username: username, Role: role},
+		Collection: collection,
+		IsOwner:    isOwner,
+	}
+	renderPage(w, r, "collection.html", data)
+}
+
+// ShareCollectionHandler открывает подборку /collections/{id}/share по ссылке (shared=1) или закрывает её.
+// Повторное открытие уже открытой подборки сохраняет прежнюю ссылку.
+func ShareCollectionHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, ok := ownCollection(w, r, store)
+		if !ok {
+			return
+		}
+		shared := r.FormValue("shared") == "1"
+		if shared != (collection.ShareToken != "") {
+			if err := store.Collections.SetCollectionShared(r.Context(), collection.ID, shared); err != nil {
+				log.Println("Error sharing collection:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+		}
+		http.Redirect(w, r, "/collections/"+strconv.Itoa(collection.ID), http.StatusSeeOther)
+	}
+}
+
+// RemoveCollectionItemHandler убирает пост post_id из подборки /collections/{id}/remove.
+func RemoveCollectionItemHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, ok := ownCollection(w, r, store)
+		if !ok {
+			return
+		}
+		postID, err := strconv.Atoi(r.FormValue("post_id"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest)
+			return
+		}
+		if err := store.Collections.RemoveCollectionItem(r.Context(), collection.ID, postID); err != nil {
+			log.Println("Error removing collection item:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/collections/"+strconv.Itoa(collection.ID), http.StatusSeeOther)
+	}
+}
+
+// DeleteCollectionHandler удаляет подборку /collections/{id}/delete и возвращает к списку подборок.
+func DeleteCollectionHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, ok := ownCollection(w, r, store)
+		if !ok {
+			return
+		}
+		if err := store.Collections.DeleteCollection(r.Context(), collection.ID); err != nil {
+			log.Println("Error deleting collection:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/collections", http.StatusSeeOther)
+	}
+}
+
+// SavePostHandler сохраняет пост /post/{id}/save в подборку collection или в новую подборку new_collection
+// (название новой важнее выбранной) и возвращает на страницу поста; при неверном выборе — с ?error=collection.
+func SavePostHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		postID, ok := pathID(r, "id")
+		if !ok {
+			return
+		}
+		postURL := "/post/" + strconv.Itoa(postID)
+		isAuth, userID, _ := IsAuthenticated(store, r)
+		if !isAuth {
+			http.Redirect(w, r, "/login?redirect="+postURL, http.StatusSeeOther)
+			return
+		}
+		_, err := store.Posts.GetPostByID(r.Context(), postID, userID)
+		if err == sql.ErrNoRows {
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching post:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		var collectionID int
+		if name := strings.TrimSpace(r.FormValue("new_collection")); name != "" {
+			if !database.ValidCollectionName(name) {
+				http.Redirect(w, r, postURL+"?error=collection", http.StatusSeeOther)
+				return
+			}
+			id, err := store.Collections.CreateCollection(r.Context(), userID, name)
+			if err != nil {
+				log.Println("Error creating collection:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+			collectionID = int(id)
+		} else {
+			id, err := strconv.Atoi(r.FormValue("collection"))
+			if err != nil {
+				http.Redirect(w, r, postURL+"?error=collection", http.StatusSeeOther)
+				return
+			}
+			collection, err := store.Collections.GetCollection(r.Context(), id)
+			if err == sql.ErrNoRows || (err == nil && collection.UserID != userID) {
+				http.Redirect(w, r, postURL+"?error=collection", http.StatusSeeOther)
+				return
+			}
+			if err != nil {
+				log.Println("Error fetching collection:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+			collectionID = id
+		}
+
+		if err := store.Collections.AddCollectionItem(r.Context(), collectionID, postID); err != nil {
+			log.Println("Error saving post to collection:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, postURL, http.StatusSeeOther)
+	}
+}
+
+// ownCollection загружает подборку из пути /collections/{id} и проверяет, что она принадлежит текущему пользователю.
+// Гостя отправляет на вход; для чужой или несуществующей подборки ничего не пишет, и CustomHandler отвечает 404.
+func ownCollection(w http.ResponseWriter, r *http.Request, store *database.Store) (models.Collection, bool) {
+	collectionID, ok := pathID(r, "id")
+	if !ok {
+		return models.Collection{}, false
+	}
+	isAuth, userID, _ := IsAuthenticated(store, r)
+	if !isAuth {
+		http.Redirect(w, r, "/login?redirect=/collections/"+strconv.Itoa(collectionID), http.StatusSeeOther)
+		return models.Collection{}, false
+	}
+	collection, err := store.Collections.GetCollection(r.Context(), collectionID)
+	if err == sql.ErrNoRows || (err == nil && collection.UserID != userID) {
+		return models.Collection{}, false
+	}
+	if err != nil {
+		log.Println("Error fetching collection:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError)
+		return models.Collection{}, false
+	}
+	return collection, true
+}
+
+// collectionsTag описывает подборки пользователя на странице поста для ETag: страница меняется,
+// когда пост сохраняют в подборку или подборку создают либо удаляют.
+func collectionsTag(collections []models.Collection) string {
+	var b strings.Builder
+	for _, c := range collections {
+		b.WriteString(strconv.Itoa(c.ID))
+		if c.Saved {
+			b.WriteByte('+')
+		}
+		b.WriteString(c.Name)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
@@ -1,35 +1,29 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"log"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
 	"forum/database"
+	"forum/permissions"
 )
 
 // CommentHandler создаёт новый комментарий к посту.
-// Принимает POST-запрос с post_id и content, возвращает JSON с данными комментария или ошибкой.
-// Требует аутентификации пользователя.
-func CommentHandler(db *sql.DB) http.HandlerFunc {
+// Принимает POST-запрос на /post/{id}/comments с полем content, возвращает JSON с данными комментария или ошибкой.
+// Требует аутентификации пользователя. Комментарий, скрытый автоматической проверкой, возвращается
+// с полем held = true и сообщением вместо данных комментария. Если автор поста закрыл комментарии или разрешил
+// их только подписчикам, отвечает 403 с объяснением в message и правилом поста в policy.
+func CommentHandler(store *database.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		isAuth, userID, _ := IsAuthenticated(db, r)
+		isAuth, userID, role := IsAuthenticated(store, r)
 		if !isAuth {
 			log.Printf("Unauthenticated user attempted to create a comment.")
-			http.Redirect(w, r, "/?message=Login+please", http.StatusSeeOther)
-			return
-		}
-
-		if r.Method != "POST" {
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Method not allowed.",
-			})
+			http.Redirect(w, r, "/?message=login_required", http.StatusSeeOther)
 			return
 		}
 
@@ -38,84 +32,73 @@ func CommentHandler(db *sql.DB) http.HandlerFunc {
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Bad request.",
+				"message": tr(r, "api.bad_request"),
 			})
 			return
 		}
 
 		log.Printf("Received form data: %v.", r.Form)
 
-		postIDStr := r.FormValue("post_id")
+		postID, ok := pathID(r, "id")
 		content := r.FormValue("content")
-		log.Printf("Comment attempt: post_id=%s, content=%q.", postIDStr, content)
+		log.Printf("Comment attempt: post_id=%d, content=%q.", postID, content)
 
-		if postIDStr == "" || content == "" {
+		if !ok {
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Post ID and content are required.",
+				"message": tr(r, "api.invalid_post_id"),
 			})
 			return
 		}
 
-		postID, err := strconv.Atoi(postIDStr)
+		now := time.Now()
+		commentID, held, rejection, err := validateAndCreateComment(r.Context(), store, postID, userID, role, content, now)
 		if err != nil {
+			log.Println("Error creating comment:", err)
 			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Invalid Post ID.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
-
-		trimmedContent := strings.TrimSpace(content)
-		if trimmedContent == "" {
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]interface{}{
+		if rejection != nil {
+			response := map[string]interface{}{
 				"success": false,
-				"message": "Comment content cannot be empty or contain only whitespace.",
-			})
-			return
-		}
-
-		if len(trimmedContent) < 3 {
+				"message": tr(r, rejection.Key, rejection.Args...),
+			}
+			if rejection.Policy != "" {
+				response["policy"] = rejection.Policy
+			}
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Comment must be at least 3 characters long.",
-			})
+			w.WriteHeader(rejection.Status)
+			json.NewEncoder(w).Encode(response)
 			return
 		}
-		if len(trimmedContent) > 500 {
+		if held {
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Comment cannot be longer than 500 characters.",
+				"success": true,
+				"held":    true,
+				"message": tr(r, "comment.held"),
 			})
 			return
 		}
 
-		createdAt := time.Now().Format("2006-01-02 15:04:05")
-		commentID, err := database.CreateComment(db, postID, userID, content, createdAt)
-		if err != nil {
-			log.Println("Error inserting comment:", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Server error.",
-			})
-			return
+		username, err := store.Users.GetUsernameByID(r.Context(), userID)
+		var displayName string
+		if err == nil {
+			displayName, err = store.Users.GetDisplayName(r.Context(), userID)
 		}
-
-		username, err := database.GetUsernameByID(db, userID)
 		if err != nil {
 			log.Println("Error fetching username:", err)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Server error.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
@@ -127,77 +110,130 @@ func CommentHandler(db *sql.DB) http.HandlerFunc {
 			"content":    content,
 			"user_id":    userID,
 			"username":   username,
-			"created_at": createdAt,
+			"author":     authorName(displayName, username),
+			"created_at": now.Format("2006-01-02 15:04:05"),
 		})
 	}
 }
 
-// DeleteCommentHandler удаляет комментарий по его ID.
-// Принимает DELETE-запрос, требует аутентификации и прав администратора или владельца комментария.
-// Возвращает JSON с результатом операции.
-func DeleteCommentHandler(db *sql.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != "DELETE" {
-			log.Println("Method not allowed:", r.Method)
-			w.Header().Set("Content-Type", "application/json")
-			w.Header().Set("Allow", "DELETE")
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Method not allowed.",
-			})
-			return
+// commentRejection — причина, по которой комментарий не принят: ключ сообщения с параметрами, код ответа сайта
+// и правило поста, если комментировать мешает оно.
+type commentRejection struct {
+	Status int
+	Key    string
+	Args   []interface{}
+	Policy string
+}
+
+// validateAndCreateComment проверяет комментарий content пользователя userID к посту postID по всем правилам
+// комментирования — правилу поста, подтверждению почты, длине, ссылкам и лимиту в час — и сохраняет его
+// со временем now. Комментарии с сайта и из писем (см. InboundEmailHandler) проходят через неё одинаково,
+// каждый обработчик лишь переводит результат в свой ответ. held равно true, если комментарий скрыт
+// автоматической проверкой до решения модератора. Если комментарий не принят, возвращает причину;
+// err — только ошибки сервера.
+func validateAndCreateComment(ctx context.Context, store *database.Store, postID, userID int, role, content string, now time.Time) (commentID int64, held bool, rejection *commentRejection, err error) {
+	allowed, policy, err := database.CanComment(ctx, store.DB, postID, userID)
+	if err == sql.ErrNoRows {
+		return 0, false, &commentRejection{Status: http.StatusNotFound, Key: "api.post_not_found"}, nil
+	}
+	if err != nil {
+		return 0, false, nil, err
+	}
+	if !allowed {
+		return 0, false, &commentRejection{Status: http.StatusForbidden, Key: commentBlockKey(policy), Policy: policy}, nil
+	}
+	unverified, err := emailUnverified(ctx, store, userID)
+	if err != nil {
+		return 0, false, nil, err
+	}
+	if unverified {
+		return 0, false, &commentRejection{Status: http.StatusForbidden, Key: "comment.error.unverified"}, nil
+	}
+
+	trimmed := strings.TrimSpace(content)
+	switch {
+	case trimmed == "":
+		return 0, false, &commentRejection{Status: http.StatusOK, Key: "comment.error.empty"}, nil
+	case len(trimmed) < 3:
+		return 0, false, &commentRejection{Status: http.StatusOK, Key: "comment.error.too_short", Args: []interface{}{3}}, nil
+	case len(trimmed) > 500:
+		return 0, false, &commentRejection{Status: http.StatusOK, Key: "comment.error.too_long", Args: []interface{}{500}}, nil
+	}
+
+	standing, err := userStanding(ctx, store, userID, role)
+	if err != nil {
+		return 0, false, nil, err
+	}
+	if !privileges.AllowsLinks(standing, now, trimmed) {
+		if privileges.NewAccountLinks == 0 {
+			return 0, false, &commentRejection{Status: http.StatusOK, Key: "comment.error.no_links", Args: []interface{}{int(privileges.NewAccountAge.Hours())}}, nil
 		}
+		return 0, false, &commentRejection{Status: http.StatusOK, Key: "comment.error.links", Args: []interface{}{privileges.NewAccountLinks}}, nil
+	}
+	if limit := privileges.HourlyLimit(standing, now, permissions.ActivityComment); limit > 0 {
+		recent, err := store.Users.CountRecentComments(ctx, userID, now.Add(-time.Hour), limit)
+		if err != nil {
+			return 0, false, nil, err
+		}
+		if recent >= limit {
+			return 0, false, &commentRejection{Status: http.StatusTooManyRequests, Key: "comment.error.rate", Args: []interface{}{limit}}, nil
+		}
+	}
+
+	verdict, screened := screen(ctx, "comment", userID, role, "", trimmed)
+	commentID, err = store.Comments.CreateComment(ctx, postID, userID, content, now.Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return 0, false, nil, err
+	}
+	held = screened && recordScreening(ctx, store, "comment", int(commentID), userID, verdict)
+	return commentID, held, nil, nil
+}
 
-		isAuth, userID, role := IsAuthenticated(db, r)
+// DeleteCommentHandler удаляет комментарий по его ID (мягко, см. database.DeleteComment).
+// Принимает DELETE-запрос на /comment/{id}, требует аутентификации и прав администратора, владельца комментария
+// или модератора раздела, в котором опубликован пост.
+// Возвращает JSON с результатом операции.
+func DeleteCommentHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAuth, userID, role := IsAuthenticated(store, r)
 		if !isAuth {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Not authenticated.",
+				"message": tr(r, "api.not_authenticated"),
 			})
 			return
 		}
 
-		commentIDStr := r.URL.Query().Get("comment_id")
-		if commentIDStr == "" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Comment ID is required.",
-			})
-			return
-		}
-		commentID, err := strconv.Atoi(commentIDStr)
-		if err != nil {
+		commentID, ok := pathID(r, "id")
+		if !ok {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Invalid Comment ID.",
+				"message": tr(r, "api.invalid_comment_id"),
 			})
 			return
 		}
 
-		commentOwnerID, err := database.GetCommentOwnerID(db, commentID)
+		commentOwnerID, err := store.Comments.GetCommentOwnerID(r.Context(), commentID)
 		if err == sql.ErrNoRows {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Comment not found.",
+				"message": tr(r, "api.comment_not_found"),
 			})
 			return
 		}
 
-		if role != "admin" && userID != commentOwnerID {
+		if role != "admin" && userID != commentOwnerID && !moderatesComment(r, store, userID, commentID) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusForbidden)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Unauthorized.",
+				"message": tr(r, "api.unauthorized"),
 			})
 			return
 		}
@@ -208,31 +244,19 @@ func DeleteCommentHandler(db *sql.DB) http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Server error.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
 
-		err = database.DeleteCommentVotes(db, commentID)
-		if err != nil {
-			log.Println("Error deleting comment votes:", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Server error.",
-			})
-			return
-		}
-
-		err = database.DeleteComment(db, commentID)
+		err = store.Comments.DeleteComment(r.Context(), commentID)
 		if err != nil {
 			log.Println("Error deleting comment:", err)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Server error.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
@@ -241,70 +265,68 @@ func DeleteCommentHandler(db *sql.DB) http.HandlerFunc {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": true,
-			"message": "Comment deleted.",
+			"message": tr(r, "api.comment_deleted"),
 		})
 	}
 }
 
 // CommentLikeHandler устанавливает или снимает лайк для комментария.
-// Принимает POST-запрос с comment_id, требует аутентификации.
+// Принимает POST-запрос на /comment/{id}/like, требует аутентификации.
 // Возвращает JSON с количеством лайков, дизлайков и текущим голосом пользователя.
-func CommentLikeHandler(db *sql.DB) http.HandlerFunc {
+func CommentLikeHandler(store *database.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		isAuth, userID, _ := IsAuthenticated(db, r)
+		isAuth, userID, role := IsAuthenticated(store, r)
 		if !isAuth {
 			log.Printf("Unauthenticated user attempted to like a comment.")
-			http.Redirect(w, r, "/?message=Login+please", http.StatusSeeOther)
+			http.Redirect(w, r, "/?message=login_required", http.StatusSeeOther)
 			return
 		}
 
-		if r.Method != "POST" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Method not allowed.",
-			})
-			return
-		}
-
-		commentIDStr := r.URL.Query().Get("comment_id")
-		if commentIDStr == "" {
+		commentID, ok := pathID(r, "id")
+		if !ok {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Comment ID is required.",
-			})
-			return
-		}
-		commentID, err := strconv.Atoi(commentIDStr)
-		if err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Invalid Comment ID.",
+				"message": tr(r, "api.invalid_comment_id"),
 			})
 			return
 		}
 
-		currentVote, voteExists, err := database.GetUserCommentVote(db, userID, commentID)
+		currentVote, voteExists, err := store.Votes.GetUserCommentVote(r.Context(), userID, commentID)
 		if err != nil {
 			log.Println("Error checking vote:", err)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Server error.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
 
+		// Поставить лайк можно в пределах лимита голосов в час (см. allowVote), снять — всегда.
+		if !voteExists || currentVote != 1 {
+			standing, err := userStanding(r.Context(), store, userID, role)
+			if err != nil {
+				log.Println("Error fetching user karma:", err)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": false,
+					"message": tr(r, "api.server_error"),
+				})
+				return
+			}
+			if !allowVote(w, r, store, userID, standing) {
+				return
+			}
+		}
+
 		if voteExists && currentVote == 1 {
-			err = database.RemoveCommentVote(db, userID, commentID)
+			err = store.Votes.RemoveCommentVote(r.Context(), userID, commentID)
 		} else {
-			err = database.SetCommentLike(db, userID, commentID)
+			err = store.Votes.SetCommentLike(r.Context(), userID, commentID)
 		}
 		if err != nil {
 			log.Println("Error updating vote:", err)
@@ -312,19 +334,19 @@ func CommentLikeHandler(db *sql.DB) http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Server error.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
 
-		likes, dislikes, userVote, userVoteExists, err := database.GetCommentVoteStats(db, userID, commentID)
+		likes, dislikes, userVote, userVoteExists, err := store.Votes.GetCommentVoteStats(r.Context(), userID, commentID)
 		if err != nil {
 			log.Println("Error fetching comment votes:", err)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Server error.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
@@ -345,64 +367,72 @@ func CommentLikeHandler(db *sql.DB) http.HandlerFunc {
 }
 
 // CommentDislikeHandler устанавливает или снимает дизлайк для комментария.
-// Принимает POST-запрос с comment_id, требует аутентификации.
+// Принимает POST-запрос на /comment/{id}/dislike, требует аутентификации.
 // Возвращает JSON с количеством лайков, дизлайков и текущим голосом пользователя.
-func CommentDislikeHandler(db *sql.DB) http.HandlerFunc {
+func CommentDislikeHandler(store *database.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		isAuth, userID, _ := IsAuthenticated(db, r)
+		isAuth, userID, role := IsAuthenticated(store, r)
 		if !isAuth {
 			log.Printf("Unauthenticated user attempted to dislike a comment.")
-			http.Redirect(w, r, "/?message=Login+please", http.StatusSeeOther)
-			return
-		}
-
-		if r.Method != "POST" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Method not allowed.",
-			})
+			http.Redirect(w, r, "/?message=login_required", http.StatusSeeOther)
 			return
 		}
 
-		commentIDStr := r.URL.Query().Get("comment_id")
-		if commentIDStr == "" {
+		commentID, ok := pathID(r, "id")
+		if !ok {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Comment ID is required.",
-			})
-			return
-		}
-		commentID, err := strconv.Atoi(commentIDStr)
-		if err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Invalid Comment ID.",
+				"message": tr(r, "api.invalid_comment_id"),
 			})
 			return
 		}
 
-		currentVote, voteExists, err := database.GetUserCommentVote(db, userID, commentID)
+		currentVote, voteExists, err := store.Votes.GetUserCommentVote(r.Context(), userID, commentID)
 		if err != nil {
 			log.Println("Error checking vote:", err)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Server error.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
 
+		// Снять свой дизлайк можно при любой карме; поставить — только с кармы privileges.DownvoteKarma
+		// и в пределах лимита голосов в час (см. allowVote).
+		if !voteExists || currentVote != -1 {
+			standing, err := userStanding(r.Context(), store, userID, role)
+			if err != nil {
+				log.Println("Error fetching user karma:", err)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": false,
+					"message": tr(r, "api.server_error"),
+				})
+				return
+			}
+			if !privileges.Allows(standing, permissions.Downvote) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": false,
+					"message": tr(r, "api.downvote_karma", privileges.Required(permissions.Downvote), standing.Karma),
+				})
+				return
+			}
+			if !allowVote(w, r, store, userID, standing) {
+				return
+			}
+		}
+
 		if voteExists && currentVote == -1 {
-			err = database.RemoveCommentVote(db, userID, commentID)
+			err = store.Votes.RemoveCommentVote(r.Context(), userID, commentID)
 		} else {
-			err = database.SetCommentDislike(db, userID, commentID)
+			err = store.Votes.SetCommentDislike(r.Context(), userID, commentID)
 		}
 		if err != nil {
 			log.Println("Error updating vote:", err)
@@ -410,19 +440,19 @@ func CommentDislikeHandler(db *sql.DB) http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Server error.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
 
-		likes, dislikes, userVote, userVoteExists, err := database.GetCommentVoteStats(db, userID, commentID)
+		likes, dislikes, userVote, userVoteExists, err := store.Votes.GetCommentVoteStats(r.Context(), userID, commentID)
 		if err != nil {
 			log.Println("Error fetching comment votes:", err)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Server error.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
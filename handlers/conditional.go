@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"forum/database"
+)
+
+// versionETag строит ETag из версии данных и параметров, от которых зависит ответ
+// (фильтры, текущий пользователь и т. п.). weak задаёт слабый ETag для HTML-страниц.
+func versionETag(weak bool, version database.ContentVersion, parts ...string) string {
+	h := sha256.New()
+	h.Write([]byte(version.Fingerprint))
+	for _, p := range parts {
+		h.Write([]byte{0})
+		h.Write([]byte(p))
+	}
+	tag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// notModified выставляет заголовки ETag и Last-Modified и проверяет условные заголовки запроса.
+// If-None-Match имеет приоритет над If-Modified-Since. Если у клиента актуальная версия,
+// отвечает 304 Not Modified и возвращает true.
+func notModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etagMatches(inm, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		if err == nil && !lastModified.Truncate(time.Second).After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// etagMatches проверяет, содержит ли заголовок If-None-Match указанный ETag.
+// Использует слабое сравнение, поддерживает список значений через запятую и "*".
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// setPrivateCaching помечает персонализированную HTML-страницу как требующую перепроверки при каждом запросе.
+// Vary дополняется, а не заменяется: язык страницы уже добавил в него Accept-Language (см. i18n.Middleware).
+func setPrivateCaching(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Add("Vary", "Cookie")
+}
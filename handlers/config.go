@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"io/fs"
+	"net/url"
+	"strings"
+	"time"
+
+	"forum/botcheck"
+	"forum/config"
+	"forum/database"
+	"forum/emailverify"
+	"forum/fingerprint"
+	"forum/htmlpdf"
+	"forum/imagecheck"
+	"forum/imageproxy"
+	"forum/mailer"
+	"forum/mailreply"
+	"forum/oauth"
+	"forum/permissions"
+	"forum/screening"
+	"forum/storage"
+	"forum/summarize"
+	"forum/translate"
+	"forum/usernames"
+)
+
+// Настройки обработчиков; задаются через Configure при запуске сервера.
+var (
+	sessionLifetime    = 24 * time.Hour
+	staticFiles        *fingerprint.Manifest
+	privileges         permissions.Rules
+	imageVerifier      *imagecheck.Verifier // nil — адреса изображений не проверяются запросом
+	imageProxy         *imageproxy.Proxy    // nil — изображения постов загружаются браузером напрямую
+	replyAddresses     *mailreply.Addresses // nil — ответы на письма не принимаются
+	inboundToken       string               // секрет в адресе вебхука входящей почты
+	siteURL            string               // внешний адрес форума без «/» в конце для полных ссылок на страницах
+	pdfConverter       htmlpdf.Converter    // nil — выгрузка постов в PDF выключена
+	translator         translate.Provider   // nil — машинный перевод постов выключен
+	screener           screening.Screener   // nil — новые посты и комментарии публикуются без проверки
+	screenThreshold    float64              // оценка проверки, с которой содержимое скрывается до решения модератора
+	reportThresholds   map[string]int       // сколько жалоб по причине скрывают пост до решения модератора; 0 — не скрывают
+	summarizer         summarize.Summarizer // nil — сводки обсуждений выключены
+	summaryMinComments int                  // с какого числа комментариев обсуждение можно пересказать
+	registrationForm   *botcheck.Form       // nil — форма регистрации не проверяется на отправку программой
+	disposableDomains  botcheck.Domains     // почтовые домены, с которыми нельзя зарегистрироваться
+	inviteLimit        int                  // сколько приглашений может создать пользователь; у администраторов без ограничений
+	usernameRules      *usernames.Rules     // правила новых имён пользователей
+	githubAuth         *oauth.GitHub        // nil — вход через GitHub выключен
+	verifyTokens       *emailverify.Tokens  // nil — почта новых пользователей не подтверждается
+	mailSender         mailer.Sender        // отправка писем; nil — подтверждение почты и сброс пароля недоступны
+	resetLinkAge       time.Duration        // срок действия ссылки сброса пароля
+	uploadStorage      storage.Storage      // nil — изображения к постам только по адресу, без загрузки
+	maxUploadSize      int64                // наибольший размер загружаемого файла в байтах
+)
+
+// Configure применяет настройки сервера к обработчикам и разбирает шаблоны страниц.
+// tpl — файловая система с шаблонами (встроенная или каталог на диске); static — манифест
+// статических файлов для функции шаблонов asset (nil — адреса без хеша).
+// Вызывается один раз при запуске, до регистрации маршрутов.
+func Configure(cfg config.Config, tpl fs.FS, static *fingerprint.Manifest) error {
+	set, err := newTemplateSet(tpl, cfg.Server.DevReload)
+	if err != nil {
+		return err
+	}
+	pages = set
+	sessionLifetime = cfg.Session.Lifetime
+	staticFiles = static
+	privileges = permissions.Rules{
+		ImageKarma:                cfg.Privileges.ImageKarma,
+		DownvoteKarma:             cfg.Privileges.DownvoteKarma,
+		NewAccountAge:             cfg.Privileges.NewAccountAge,
+		NewAccountLinks:           cfg.Privileges.NewAccountLinks,
+		NewAccountPostsPerHour:    cfg.Privileges.NewAccountPostsPerHour,
+		NewAccountCommentsPerHour: cfg.Privileges.NewAccountCommentsPerHour,
+		VotesPerHour:              cfg.Privileges.VotesPerHour,
+		NewAccountVotesPerHour:    cfg.Privileges.NewAccountVotesPerHour,
+		Trust: [permissions.TrustRegular + 1]permissions.Level{
+			permissions.TrustNew:     trustLevel(cfg.Trust.New),
+			permissions.TrustBasic:   trustLevel(cfg.Trust.Basic),
+			permissions.TrustMember:  trustLevel(cfg.Trust.Member),
+			permissions.TrustRegular: trustLevel(cfg.Trust.Regular),
+		},
+	}
+	siteURL = strings.TrimRight(cfg.Server.BaseURL, "/")
+	if u, err := url.Parse(cfg.Server.BaseURL); err == nil {
+		privileges.SiteHost = u.Host
+	}
+	inboundToken = cfg.Mail.InboundToken
+	pdfConverter = htmlpdf.New(cfg.Export.PDFCommand, cfg.Export.PDFURL, cfg.Export.PDFTimeout)
+	translator = translate.New(cfg.Translation.Provider, cfg.Translation.URL, cfg.Translation.APIKey, cfg.Translation.Timeout)
+	screener = screening.New(cfg.Screening.Provider, cfg.Screening.URL, cfg.Screening.APIKey, cfg.Screening.Model, cfg.Screening.Timeout)
+	screenThreshold = cfg.Screening.Threshold
+	reportThresholds = map[string]int{
+		database.ReportSpam:     cfg.Reports.Spam,
+		database.ReportAbuse:    cfg.Reports.Abuse,
+		database.ReportOffTopic: cfg.Reports.OffTopic,
+		database.ReportIllegal:  cfg.Reports.Illegal,
+	}
+	summarizer = summarize.New(cfg.Summary.Provider, cfg.Summary.URL, cfg.Summary.APIKey, cfg.Summary.Model, cfg.Summary.Timeout)
+	summaryMinComments = cfg.Summary.MinComments
+	inviteLimit = cfg.Registration.InviteLimit
+	resetLinkAge = cfg.Registration.ResetLinkAge
+	usernameRules, err = usernames.New(cfg.Usernames.MinLength, cfg.Usernames.MaxLength, cfg.Usernames.Charset,
+		cfg.Usernames.Reserved, cfg.Usernames.Profanity)
+	if err != nil {
+		return err
+	}
+	disposableDomains = nil
+	if cfg.Registration.BlockDisposable {
+		disposableDomains = botcheck.NewDomains(append(botcheck.DisposableDomains, cfg.Registration.DisposableDomains...)...)
+	}
+	imageVerifier = nil
+	if cfg.Images.Verify {
+		imageVerifier = imagecheck.NewVerifier(cfg.Images.VerifyTimeout)
+	}
+	return nil
+}
+
+// trustLevel переводит настройки уровня доверия в условия и права permissions.Level.
+func trustLevel(l config.TrustLevel) permissions.Level {
+	return permissions.Level{
+		Requirement: permissions.Requirement{Age: l.Age, Read: l.Read, Posts: l.Posts, Comments: l.Comments, Karma: l.Karma},
+		EditWindow:  l.EditWindow,
+		FlagWeight:  l.FlagWeight,
+	}
+}
+
+// UseImageProxy включает прокси изображений постов: шаблоны ссылаются на изображения через /img,
+// а ImageProxyHandler отдаёт их. nil выключает прокси. Вызывается при запуске вместе с Configure.
+func UseImageProxy(p *imageproxy.Proxy) {
+	imageProxy = p
+}
+
+// UseReplyAddresses включает приём ответов на письма-уведомления: InboundEmailHandler публикует их комментариями.
+// nil выключает приём. Вызывается при запуске вместе с Configure.
+func UseReplyAddresses(a *mailreply.Addresses) {
+	replyAddresses = a
+}
+
+// UseStorage включает загрузку изображений к постам: файлы размером до maxSize байт сохраняются в s,
+// а UploadHandler отдаёт их с форума. nil выключает загрузку. Вызывается при запуске вместе с Configure.
+func UseStorage(s storage.Storage, maxSize int64) {
+	uploadStorage = s
+	maxUploadSize = maxSize
+}
+
+// UseRegistrationForm включает проверку формы регистрации ловушкой и меткой времени (см. пакет botcheck).
+// nil выключает проверку. Вызывается при запуске вместе с Configure.
+func UseRegistrationForm(f *botcheck.Form) {
+	registrationForm = f
+}
+
+// UseGitHub включает вход через GitHub: GitHubLoginHandler отправляет пользователя подтверждать доступ
+// приложению g. nil выключает вход. Вызывается при запуске вместе с Configure.
+func UseGitHub(g *oauth.GitHub) {
+	githubAuth = g
+}
+
+// UseMail включает отправку писем через sender: ссылки подтверждения почты и сброса пароля.
+// nil выключает отправку, а с ней и сброс пароля. Вызывается при запуске вместе с Configure.
+func UseMail(sender mailer.Sender) {
+	mailSender = sender
+}
+
+// UseEmailVerification включает подтверждение почты при регистрации: новый пользователь получает письмо
+// со ссылкой, подписанной tokens, и не может публиковать посты и комментарии, пока не откроет её.
+// Письма отправляются через UseMail. nil выключает подтверждение. Вызывается при запуске вместе с Configure.
+func UseEmailVerification(tokens *emailverify.Tokens) {
+	verifyTokens = tokens
+}
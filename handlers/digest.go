@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"forum/database"
+	"forum/models"
+)
+
+// digestArchiveSize — сколько прошлых недель перечислено на странице подборки.
+const digestArchiveSize = 12
+
+// digestPageData — данные страницы недельной подборки: сама подборка и недели архива.
+// Нулевая подборка (пустой Week) означает, что итоги ещё ни разу не подводились.
+type digestPageData struct {
+	models.PageData
+	Digest models.Digest
+	Weeks  []string
+}
+
+// DigestHandler показывает последнюю сохранённую подборку лучших постов недели (/digest).
+// Подборки сохраняет фоновая задача после окончания недели (см. database.EnsureDigest).
+func DigestHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		renderDigest(w, r, store, "")
+	}
+}
+
+// DigestWeekHandler показывает подборку недели /digest/{week}, где week — дата понедельника (ГГГГ-ММ-ДД).
+// Для неверной даты или недели без подборки ничего не пишет, и CustomHandler отвечает 404.
+func DigestWeekHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		week := r.PathValue("week")
+		if _, ok := database.ParseDigestWeek(week); !ok {
+			return
+		}
+		renderDigest(w, r, store, week)
+	}
+}
+
+// renderDigest отображает подборку недели week; пустая week означает последнюю сохранённую подборку.
+func renderDigest(w http.ResponseWriter, r *http.Request, store *database.Store, week string) {
+	weeks, err := database.ListDigestWeeks(r.Context(), store.DB, digestArchiveSize)
+	if err != nil {
+		log.Println("Error listing digests:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError)
+		return
+	}
+	if week == "" && len(weeks) > 0 {
+		week = weeks[0]
+	}
+	var digest models.Digest
+	if week != "" {
+		digest, err = database.GetDigest(r.Context(), store.DB, week)
+		if err == sql.ErrNoRows {
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching digest:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	isAuth, userID, role := IsAuthenticated(store, r)
+	var username string
+	if isAuth {
+		if username, err = store.Users.GetUsernameByID(r.Context(), userID); err != nil {
+			log.Println("Error fetching username:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	data := digestPageData{
+		PageData: models.PageData{IsAuthenticated: isAuth, UserID: userID, Username: username, Role: role},
+		Digest:   digest,
+		Weeks:    weeks,
+	}
+	renderPage(w, r, "digest.html", data)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"cmp"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"forum/database"
+	"forum/models"
+	"forum/ranking"
+)
+
+// exportTopComments — сколько лучших комментариев входит в выгрузку поста по умолчанию.
+const exportTopComments = 20
+
+// exportPageData — данные печатной версии поста.
+type exportPageData struct {
+	models.PageData
+	Comments      []models.CommentData
+	TotalComments int
+	AllComments   bool   // выгружены все комментарии, а не только лучшие
+	SourceURL     string // полный адрес страницы поста
+	ExportedAt    time.Time
+	PDF           bool // документ отдаётся конвертеру: без кнопок, изображения по исходным адресам
+	PDFAvailable  bool
+}
+
+// ExportPostHandler отдаёт печатную версию поста для архива обсуждений: GET /post/{id}/export.
+// Документ самодостаточен (стили внутри страницы) и содержит пост и лучшие комментарии — с положительным
+// рейтингом, не больше exportTopComments, в порядке публикации; ?comments=all выгружает все комментарии.
+// С ?format=pdf документ превращается в PDF настроенным конвертером (см. пакет htmlpdf);
+// без конвертера, как и для несуществующего поста или поста только для авторизованных при анонимном запросе,
+// ничего не пишет, и CustomHandler отвечает 404.
+func ExportPostHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		postID, ok := pathID(r, "id")
+		if !ok {
+			writeError(w, r, http.StatusBadRequest)
+			return
+		}
+		pdf := r.URL.Query().Get("format") == "pdf"
+		if pdf && pdfConverter == nil {
+			return
+		}
+
+		isAuth, userID, _ := IsAuthenticated(store, r)
+		post, err := store.Posts.GetPostByID(r.Context(), postID, userID)
+		if err == sql.ErrNoRows || err == nil && post.MembersOnly && !isAuth {
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching post:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		comments, err := store.Comments.GetCommentsByPostID(r.Context(), userID, postID)
+		if err != nil {
+			log.Println("Error querying comments:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		data := exportPageData{
+			PageData:      models.PageData{Post: post},
+			Comments:      comments,
+			TotalComments: len(comments),
+			AllComments:   r.URL.Query().Get("comments") == "all",
+			SourceURL:     siteURL + "/post/" + strconv.Itoa(postID),
+			ExportedAt:    time.Now(),
+			PDF:           pdf,
+			PDFAvailable:  pdfConverter != nil,
+		}
+		if !data.AllComments {
+			data.Comments = topComments(comments, exportTopComments)
+		}
+
+		if !pdf {
+			renderPage(w, r, "export.html", data)
+			return
+		}
+		var html bytes.Buffer
+		if err := Render(&html, r, "export.html", data); err != nil {
+			log.Println("Error executing export template:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		doc, err := pdfConverter.Convert(r.Context(), html.Bytes())
+		if err != nil {
+			log.Println("Error converting post to PDF:", err)
+			w.WriteHeader(http.StatusBadGateway)
+			writeError(w, r, http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="post-%d.pdf"`, postID))
+		w.Write(doc)
+	}
+}
+
+// topComments возвращает не больше limit лучших комментариев с положительным рейтингом (лайки минус дизлайки)
+// в исходном порядке публикации. Лучшие выбираются по ranking.Wilson, а не по рейтингу, чтобы единодушно
+// одобренный комментарий не уступал спорному с большим числом голосов.
+func topComments(comments []models.CommentData, limit int) []models.CommentData {
+	var top []models.CommentData
+	for _, c := range comments {
+		if c.Likes-c.Dislikes > 0 {
+			top = append(top, c)
+		}
+	}
+	slices.SortStableFunc(top, func(a, b models.CommentData) int {
+		return cmp.Compare(ranking.Wilson(b.Likes, b.Dislikes), ranking.Wilson(a.Likes, a.Dislikes))
+	})
+	if len(top) > limit {
+		top = top[:limit]
+	}
+	slices.SortStableFunc(top, func(a, b models.CommentData) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return top
+}
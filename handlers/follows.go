@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"forum/database"
+)
+
+// commentPolicy читает из формы поста поле comment_policy: кто может комментировать пост.
+// Пустое поле означает «все»; второе значение false, если правило не из database.CommentPolicies.
+func commentPolicy(r *http.Request) (string, bool) {
+	policy := r.FormValue("comment_policy")
+	if policy == "" {
+		return database.CommentsEveryone, true
+	}
+	return policy, slices.Contains(database.CommentPolicies, policy)
+}
+
+// commentBlockKey возвращает ключ сообщения, объясняющего, почему правило поста policy не даёт его комментировать.
+func commentBlockKey(policy string) string {
+	if policy == database.CommentsFollowers {
+		return "comment.error.followers_only"
+	}
+	return "comment.error.closed"
+}
+
+// FollowHandler подписывает пользователя на автора /profile/{id}/follow (follow=1) или отменяет подписку.
+// Подписка даёт право комментировать посты автора, открытые только для подписчиков. Возвращает в профиль автора.
+func FollowHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		followeeID, ok := pathID(r, "id")
+		if !ok {
+			return
+		}
+		isAuth, userID, _ := IsAuthenticated(store, r)
+		if !isAuth {
+			http.Redirect(w, r, "/login?redirect=/profile/"+strconv.Itoa(followeeID), http.StatusSeeOther)
+			return
+		}
+		if followeeID == userID {
+			w.WriteHeader(http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest)
+			return
+		}
+		if err := database.Follow(r.Context(), store.DB, userID, followeeID, r.FormValue("follow") == "1"); err != nil {
+			log.Println("Error updating follow:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/profile/"+strconv.Itoa(followeeID), http.StatusSeeOther)
+	}
+}
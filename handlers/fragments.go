@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"time"
+
+	"forum/database"
+)
+
+// fragmentTTL — время жизни готовой страницы в кэше. Ключ включает ETag версии данных,
+// поэтому после изменений старая страница просто перестаёт запрашиваться и истекает сама.
+const fragmentTTL = 5 * time.Minute
+
+// fragmentKey возвращает ключ кэша готовой страницы для анонимного посетителя
+// или пустую строку, если страницу кэшировать нельзя.
+func fragmentKey(store *database.Store, isAuth bool, page, etag string) string {
+	if store.Cache == nil || isAuth || etag == "" {
+		return ""
+	}
+	return "fragment:" + page + ":" + etag
+}
+
+// writeCachedFragment отправляет готовую страницу из кэша. Возвращает false, если её там нет.
+func writeCachedFragment(w http.ResponseWriter, store *database.Store, key string) bool {
+	if key == "" {
+		return false
+	}
+	html, ok := store.Cache.Get(key)
+	if !ok {
+		return false
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(html)
+	return true
+}
+
+// renderCachedPage отвечает страницей name, как renderPage, и, если key не пуст, сохраняет её в кэше.
+func renderCachedPage(w http.ResponseWriter, r *http.Request, store *database.Store, key, name string, data interface{}) {
+	var buf bytes.Buffer
+	if err := Render(&buf, r, name, data); err != nil {
+		log.Printf("Error executing %s template: %v", name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError)
+		return
+	}
+	if key != "" {
+		store.Cache.Set(key, buf.Bytes(), fragmentTTL)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Println("Error writing response:", err)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"forum/database"
+	"forum/models"
+)
+
+// historySize — сколько последних просмотренных постов показывает /history.
+const historySize = 50
+
+// historyPageData — данные страницы истории просмотров: последние просмотренные посты и настройка приватности.
+type historyPageData struct {
+	models.PageData
+	Entries []models.HistoryEntry
+	Enabled bool
+}
+
+// HistoryHandler показывает посты, которые пользователь недавно открывал (/history).
+// POST с полем enabled=1 или enabled=0 включает или отключает историю; при отключении она очищается
+// (см. database.SetHistoryEnabled). Просмотры старше срока хранения удаляет фоновая задача.
+func HistoryHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAuth, userID, role := IsAuthenticated(store, r)
+		if !isAuth {
+			http.Redirect(w, r, "/login?redirect=/history", http.StatusSeeOther)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			if err := store.Users.SetHistoryEnabled(r.Context(), userID, r.FormValue("enabled") == "1"); err != nil {
+				log.Println("Error saving history setting:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+			http.Redirect(w, r, "/history", http.StatusSeeOther)
+			return
+		}
+
+		username, err := store.Users.GetUsernameByID(r.Context(), userID)
+		if err != nil {
+			log.Println("Error fetching username:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		enabled, err := store.Users.HistoryEnabled(r.Context(), userID)
+		if err != nil {
+			log.Println("Error fetching history setting:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		entries, err := store.Users.GetViewHistory(r.Context(), userID, historySize)
+		if err != nil {
+			log.Println("Error fetching view history:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		data := historyPageData{
+			PageData: models.PageData{IsAuthenticated: isAuth, UserID: userID, Username: username, Role: role},
+			Entries:  entries,
+			Enabled:  enabled,
+		}
+		renderPage(w, r, "history.html", data)
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"forum/database"
+	"forum/imagecheck"
+)
+
+// imagePolicy читает списки доменов изображений из настроек сайта.
+func imagePolicy(ctx context.Context, store *database.Store) (imagecheck.Policy, error) {
+	allow, err := store.Settings.GetSetting(ctx, database.SettingImageAllowDomains)
+	if err != nil {
+		return imagecheck.Policy{}, err
+	}
+	block, err := store.Settings.GetSetting(ctx, database.SettingImageBlockDomains)
+	if err != nil {
+		return imagecheck.Policy{}, err
+	}
+	return imagecheck.Policy{Allow: strings.Fields(allow), Block: strings.Fields(block)}, nil
+}
+
+// checkImageURL проверяет адрес изображения поста и возвращает его в каноническом виде.
+// Если адрес не подходит, возвращает код ошибки формы поста: image_url, image_domain или image_fetch
+// (адрес не отвечает изображением; проверяется, только если включена настройка images.verify).
+func checkImageURL(ctx context.Context, store *database.Store, raw string) (string, string, error) {
+	policy, err := imagePolicy(ctx, store)
+	if err != nil {
+		return "", "", err
+	}
+	imageURL, err := policy.Validate(raw)
+	switch {
+	case errors.Is(err, imagecheck.ErrDomain):
+		return "", "image_domain", nil
+	case err != nil:
+		return "", "image_url", nil
+	}
+	if imageVerifier != nil {
+		if err := imageVerifier.Verify(ctx, imageURL); err != nil {
+			log.Printf("Image URL %s rejected: %v.", imageURL, err)
+			return "", "image_fetch", nil
+		}
+	}
+	return imageURL, "", nil
+}
+
+// imageSrc возвращает адрес изображения поста для атрибута src: через прокси, если он включён.
+func imageSrc(raw string) string {
+	if imageProxy == nil {
+		return raw
+	}
+	return imageProxy.URL(raw)
+}
+
+// ImageProxyHandler отдаёт изображения постов через прокси /img/{sig}. Если прокси выключен,
+// ничего не пишет, и CustomHandler отвечает 404.
+func ImageProxyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if imageProxy != nil {
+			imageProxy.ServeHTTP(w, r)
+		}
+	}
+}
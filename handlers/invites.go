@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"forum/database"
+	"forum/models"
+)
+
+// invitesPageData — данные страницы приглашений пользователя.
+type invitesPageData struct {
+	models.PageData
+	Invites    []models.Invite
+	Remaining  int    // сколько приглашений ещё можно создать; -1 — без ограничений
+	InviteOnly bool   // регистрация сейчас только по приглашениям
+	InviteURL  string // адрес регистрации без кода приглашения
+}
+
+// InvitesHandler показывает приглашения пользователя и ссылки для регистрации по ним (/invites);
+// POST создаёт новое приглашение. Пользователь может создать не больше registration.invite_limit
+// приглашений, администратор — сколько угодно.
+func InvitesHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAuth, userID, role := IsAuthenticated(store, r)
+		if !isAuth {
+			http.Redirect(w, r, "/login?redirect=/invites", http.StatusSeeOther)
+			return
+		}
+		limit := inviteLimit
+		if role == "admin" {
+			limit = -1
+		}
+
+		if r.Method == http.MethodPost {
+			_, err := database.CreateInvite(r.Context(), store.DB, userID, limit)
+			if errors.Is(err, database.ErrInviteLimit) {
+				http.Redirect(w, r, "/invites?error=limit", http.StatusSeeOther)
+				return
+			}
+			if err != nil {
+				log.Println("Error creating invite:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+			http.Redirect(w, r, "/invites?message=created", http.StatusSeeOther)
+			return
+		}
+
+		username, err := store.Users.GetUsernameByID(r.Context(), userID)
+		if err != nil {
+			log.Println("Error fetching username:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		invites, err := database.ListInvites(r.Context(), store.DB, userID)
+		if err != nil {
+			log.Println("Error fetching invites:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		inviteOnly, err := inviteOnlyRegistration(r, store)
+		if err != nil {
+			log.Println("Error reading settings:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		data := invitesPageData{
+			PageData: models.PageData{
+				IsAuthenticated: isAuth, UserID: userID, Username: username, Role: role,
+				ErrorMessage: flash(r, "error", "invites.error."),
+				Message:      flash(r, "message", "invites.message."),
+			},
+			Invites:    invites,
+			Remaining:  -1,
+			InviteOnly: inviteOnly,
+			InviteURL:  siteURL + "/register?invite=",
+		}
+		if limit >= 0 {
+			data.Remaining = max(limit-len(invites), 0)
+		}
+		renderPage(w, r, "invites.html", data)
+	}
+}
+
+// invitesTreePageData — данные страницы дерева приглашений для администратора.
+type invitesTreePageData struct {
+	models.PageData
+	Tree []models.InviteNode
+}
+
+// AdminInvitesHandler показывает администратору дерево приглашений: кто кого пригласил на форум (/admin/invites).
+func AdminInvitesHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := requireAdmin(w, r, store)
+		if !ok {
+			return
+		}
+		tree, err := database.InviteTree(r.Context(), store.DB)
+		if err != nil {
+			log.Println("Error building invite tree:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		renderPage(w, r, "admin_invites.html", invitesTreePageData{PageData: models.PageData{Username: username}, Tree: tree})
+	}
+}
+
+// inviteOnlyRegistration сообщает, включена ли регистрация только по приглашениям (database.SettingInviteOnly).
+func inviteOnlyRegistration(r *http.Request, store *database.Store) (bool, error) {
+	value, err := store.Settings.GetSetting(r.Context(), database.SettingInviteOnly)
+	return value == "1", err
+}
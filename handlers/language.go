@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/database"
+	"forum/i18n"
+	"forum/proxy"
+)
+
+// languageCookieLifetime — срок хранения выбранного языка в браузере.
+const languageCookieLifetime = 365 * 24 * time.Hour
+
+// language — язык для переключателя: код и название на самом языке.
+type language struct {
+	Code string
+	Name string
+}
+
+// languages перечисляет поддерживаемые языки для переключателя в подвале страниц.
+func languages() []language {
+	list := make([]language, len(i18n.Languages))
+	for i, code := range i18n.Languages {
+		list[i] = language{Code: code, Name: i18n.T(code, "language.name")}
+	}
+	return list
+}
+
+// tr переводит сообщение каталога на язык запроса r.
+func tr(r *http.Request, key string, args ...interface{}) string {
+	return i18n.T(i18n.FromContext(r.Context()), key, args...)
+}
+
+// flash возвращает сообщение prefix+код из параметра запроса param (например, ?error=empty).
+// В адресе передаётся только код, поэтому неизвестное значение не показывается.
+func flash(r *http.Request, param, prefix string) string {
+	code := r.URL.Query().Get(param)
+	if code == "" {
+		return ""
+	}
+	msg, ok := i18n.Lookup(i18n.FromContext(r.Context()), prefix+code)
+	if !ok {
+		return ""
+	}
+	return msg
+}
+
+// statusText возвращает описание кода ответа на языке запроса.
+func statusText(r *http.Request, code int) string {
+	if msg, ok := i18n.Lookup(i18n.FromContext(r.Context()), "error.status."+strconv.Itoa(code)); ok {
+		return msg
+	}
+	return http.StatusText(code)
+}
+
+// setLanguageCookie запоминает язык в браузере; его читает i18n.Middleware.
+func setLanguageCookie(w http.ResponseWriter, r *http.Request, lang string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     i18n.CookieName,
+		Value:    lang,
+		Path:     "/",
+		MaxAge:   int(languageCookieLifetime / time.Second),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   proxy.IsSecure(r),
+	})
+}
+
+// LanguageHandler переключает язык интерфейса: POST /language с полями lang и redirect.
+// Выбор сохраняется в cookie, а для вошедшего пользователя — ещё и в профиле,
+// чтобы применяться при входе с других устройств. Затем возвращает на страницу redirect или Referer.
+func LanguageHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lang := r.FormValue("lang")
+		if !i18n.Supported(lang) {
+			w.WriteHeader(http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest)
+			return
+		}
+		setLanguageCookie(w, r, lang)
+
+		if isAuth, userID, _ := IsAuthenticated(store, r); isAuth {
+			if err := store.Users.SetLanguage(r.Context(), userID, lang); err != nil {
+				log.Println("Error saving language preference:", err)
+			}
+		}
+
+		target := r.FormValue("redirect")
+		if target == "" {
+			target = refererPath(r)
+		}
+		http.Redirect(w, r, localRedirect(target), http.StatusSeeOther)
+	}
+}
+
+// refererPath возвращает путь и строку запроса из Referer, если он указывает на этот же сайт.
+// Переключатель в подвале не знает адрес страницы, поэтому возвращает на неё по Referer.
+func refererPath(r *http.Request) string {
+	ref, err := url.Parse(r.Referer())
+	if err != nil || ref.Host != r.Host {
+		return ""
+	}
+	return ref.RequestURI()
+}
+
+// localRedirect возвращает target, если это путь на этом же сайте, иначе "/".
+// Адреса вида //host и /\host браузеры считают внешними, поэтому они отклоняются.
+func localRedirect(target string) string {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return "/"
+	}
+	return target
+}
+
+// absoluteURL возвращает полный адрес пути path для ссылок, которые открываются не с этой страницы
+// (в письмах, на сайте провайдера входа): от server.base_url, а если он не задан — от адреса запроса.
+func absoluteURL(r *http.Request, path string) string {
+	if siteURL != "" {
+		return siteURL + path
+	}
+	scheme := "http"
+	if proxy.IsSecure(r) {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + path
+}
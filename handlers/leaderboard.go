@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"forum/database"
+	"forum/models"
+)
+
+const (
+	// leaderboardSize — сколько пользователей показывает каждая таблица лидеров.
+	leaderboardSize = 10
+	// leaderboardTTL — сколько таблицы лидеров хранятся в кэше. Кэш не сбрасывается при записи,
+	// как выборки Store: таблицы считаются агрегатными запросами по всем постам и голосам,
+	// а отставание на несколько минут для них незаметно.
+	leaderboardTTL = 5 * time.Minute
+)
+
+// leaderboardWindows — периоды таблиц лидеров; нулевая длительность означает всё время.
+var leaderboardWindows = map[string]time.Duration{
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"all":   0,
+}
+
+// LeaderboardHandler показывает таблицы лидеров: самых активных авторов, авторов с наибольшим числом лайков
+// и лучших комментаторов за период window (week — по умолчанию, month или all).
+// Неизвестный период — 400.
+func LeaderboardHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		window := r.URL.Query().Get("window")
+		if window == "" {
+			window = "week"
+		}
+		if _, ok := leaderboardWindows[window]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest)
+			return
+		}
+
+		isAuth, userID, role := IsAuthenticated(store, r)
+		var username string
+		if isAuth {
+			var err error
+			if username, err = store.Users.GetUsernameByID(r.Context(), userID); err != nil {
+				log.Println("Error fetching username:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+		}
+
+		boards, err := leaderboards(r, store, window)
+		if err != nil {
+			log.Println("Error querying leaderboards:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		data := models.PageData{
+			IsAuthenticated: isAuth,
+			UserID:          userID,
+			Username:        username,
+			Role:            role,
+			Filter:          window,
+			Leaderboards:    boards,
+		}
+		renderPage(w, r, "leaderboard.html", data)
+	}
+}
+
+// leaderboards возвращает таблицы лидеров за период window из кэша Store или считает их и кэширует на leaderboardTTL.
+func leaderboards(r *http.Request, store *database.Store, window string) (models.Leaderboards, error) {
+	key := "leaderboard:" + window
+	if store.Cache != nil {
+		if data, ok := store.Cache.Get(key); ok {
+			var boards models.Leaderboards
+			if err := json.Unmarshal(data, &boards); err == nil {
+				return boards, nil
+			}
+		}
+	}
+
+	var since time.Time
+	if d := leaderboardWindows[window]; d > 0 {
+		since = time.Now().Add(-d)
+	}
+	boards, err := store.Users.GetLeaderboards(r.Context(), since, leaderboardSize)
+	if err != nil {
+		return boards, err
+	}
+	if store.Cache != nil {
+		if data, err := json.Marshal(boards); err == nil {
+			store.Cache.Set(key, data, leaderboardTTL)
+		}
+	}
+	return boards, nil
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"forum/database"
+	"forum/models"
+)
+
+// likersPageSize — сколько лайкнувших показывает одна страница списка.
+const likersPageSize = 30
+
+// likersPageData — данные страницы со списком лайкнувших пост или комментарий.
+type likersPageData struct {
+	models.PageData
+	Kind   string // post или comment
+	ItemID int
+	Likers models.Likers
+}
+
+// PostLikersHandler показывает, кто лайкнул пост (/post/{id}/likes).
+func PostLikersHandler(store *database.Store) http.HandlerFunc {
+	return likersHandler(store, "post")
+}
+
+// CommentLikersHandler показывает, кто лайкнул комментарий (/comment/{id}/likes).
+func CommentLikersHandler(store *database.Store) http.HandlerFunc {
+	return likersHandler(store, "comment")
+}
+
+// likersHandler выводит страницу лайкнувших элемент kind от последних лайков к первым; следующая страница —
+// по ссылке с параметром cursor. Пользователи, скрывающие себя в таких списках (см. LikesPrivacyHandler),
+// показываются только числом. Список виден тем, кому виден пост элемента; для удалённого элемента или
+// поста только для авторизованных при анонимном запросе ничего не пишет, и CustomHandler отвечает 404.
+func likersHandler(store *database.Store, kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		itemID, ok := pathID(r, "id")
+		if !ok {
+			return
+		}
+		isAuth, userID, role := IsAuthenticated(store, r)
+		likers, nextCursor, err := database.GetLikers(r.Context(), store.DB, kind, itemID, likersPageSize, r.URL.Query().Get("cursor"))
+		if err == sql.ErrNoRows {
+			return
+		}
+		if errors.Is(err, database.ErrInvalidCursor) {
+			w.WriteHeader(http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching likers:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		post, err := store.Posts.GetPostByID(r.Context(), likers.PostID, userID)
+		if err == sql.ErrNoRows || err == nil && post.MembersOnly && !isAuth {
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching post:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		var username string
+		if isAuth {
+			if username, err = store.Users.GetUsernameByID(r.Context(), userID); err != nil {
+				log.Println("Error fetching username:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+		}
+		data := likersPageData{
+			PageData: models.PageData{IsAuthenticated: isAuth, UserID: userID, Username: username, Role: role, Post: post},
+			Kind:     kind,
+			ItemID:   itemID,
+			Likers:   likers,
+		}
+		if nextCursor != "" {
+			data.NextPage = "/" + kind + "/" + strconv.Itoa(itemID) + "/likes?" + url.Values{"cursor": {nextCursor}}.Encode()
+		}
+		renderPage(w, r, "likes.html", data)
+	}
+}
+
+// LikesPrivacyHandler скрывает пользователя в списках лайкнувших (POST /profile/likes с hidden=1) или снова
+// показывает его там (hidden=0). Возвращает в профиль пользователя.
+func LikesPrivacyHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAuth, userID, _ := IsAuthenticated(store, r)
+		if !isAuth {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if err := database.SetLikesHidden(r.Context(), store.DB, userID, r.FormValue("hidden") == "1"); err != nil {
+			log.Println("Error saving likes privacy:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/profile/"+strconv.Itoa(userID), http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"forum/database"
+	"forum/mailreply"
+)
+
+// maxInboundEmailSize ограничивает размер запроса вебхука входящей почты вместе с вложениями.
+const maxInboundEmailSize = 10 << 20
+
+// InboundEmailHandler принимает ответ на письмо-уведомление от почтового сервиса:
+// POST /inbound/email/{token} с полями письма, как их присылают Mailgun (recipient, sender, stripped-text,
+// body-plain) или SendGrid Inbound Parse (to, from, text). Ответ на подписанный адрес reply+…@mail.reply_domain
+// (см. пакет mailreply) публикуется комментарием к посту от имени пользователя, которому выдан адрес.
+// Отправитель должен совпадать с почтой этого пользователя; комментарий проверяется теми же правилами, что и комментарии
+// с сайта (см. validateAndCreateComment).
+// Если приём ответов выключен или токен неверен, ничего не пишет, и CustomHandler отвечает 404.
+// Отклонённое письмо получает ответ 406, чтобы почтовый сервис не повторял доставку; ошибка сервера — 500.
+func InboundEmailHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if replyAddresses == nil || subtle.ConstantTimeCompare([]byte(r.PathValue("token")), []byte(inboundToken)) != 1 {
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxInboundEmailSize)
+		if err := r.ParseMultipartForm(maxInboundEmailSize); err != nil && err != http.ErrNotMultipart {
+			log.Println("Error parsing inbound email:", err)
+			rejectEmail(w, r, "api.bad_request")
+			return
+		}
+
+		postID, userID, ok := replyAddresses.Find(firstValue(r, "recipient", "to"))
+		if !ok {
+			log.Printf("Inbound email rejected: no signed reply address in %q.", firstValue(r, "recipient", "to"))
+			rejectEmail(w, r, "mail.error.address")
+			return
+		}
+		sender := mailreply.SenderAddress(firstValue(r, "sender", "from"))
+		senderID, _, _, role, err := store.Users.GetUserByEmail(r.Context(), sender)
+		if err != nil && err != sql.ErrNoRows {
+			log.Println("Error fetching user:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "message": tr(r, "api.server_error")})
+			return
+		}
+		if err == sql.ErrNoRows || senderID != userID || role == "banned" {
+			log.Printf("Inbound email rejected: sender %q does not own the reply address of user %d.", sender, userID)
+			rejectEmail(w, r, "mail.error.sender")
+			return
+		}
+		content := mailreply.ReplyText(firstValue(r, "stripped-text", "body-plain", "text"))
+		commentID, held, rejection, err := validateAndCreateComment(r.Context(), store, postID, userID, role, content, time.Now())
+		if err != nil {
+			log.Println("Error creating comment:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "message": tr(r, "api.server_error")})
+			return
+		}
+		if rejection != nil {
+			rejectEmail(w, r, rejection.Key, rejection.Args...)
+			return
+		}
+		if held {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "comment_id": commentID, "held": true})
+			return
+		}
+		log.Printf("Comment %d posted by email from user %d to post %d.", commentID, userID, postID)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "comment_id": commentID})
+	}
+}
+
+// rejectEmail отвечает почтовому сервису 406 с причиной отказа: письмо не будет доставлено повторно.
+func rejectEmail(w http.ResponseWriter, r *http.Request, key string, args ...interface{}) {
+	writeJSON(w, http.StatusNotAcceptable, map[string]interface{}{"success": false, "message": tr(r, key, args...)})
+}
+
+// firstValue возвращает первое непустое поле формы из names: почтовые сервисы называют одни и те же поля по-разному.
+func firstValue(r *http.Request, names ...string) string {
+	for _, name := range names {
+		if v := r.FormValue(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"forum/database"
+	"forum/models"
+)
+
+// manageCommentsLimit — сколько последних комментариев показывает страница управления.
+const manageCommentsLimit = 100
+
+// managePostsPageData — данные страницы управления своими постами и комментариями.
+type managePostsPageData struct {
+	models.PageData
+	Posts         []models.PostData
+	Comments      []models.CommentData
+	CommentsLimit int
+}
+
+// ManagePostsHandler показывает пользователю его посты, в том числе снятые с публикации, и последние
+// комментарии с флажками для пакетных действий (/my/posts). POST выполняет действие action над
+// выбранными post_id или comment_id: delete, unpublish, publish, categories (новые категории — в categories)
+// или delete_comments. Если среди выбранных есть чужие, не меняется ничего.
+func ManagePostsHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAuth, userID, role := IsAuthenticated(store, r)
+		if !isAuth {
+			http.Redirect(w, r, "/login?redirect=/my/posts", http.StatusSeeOther)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			managePosts(w, r, store, userID)
+			return
+		}
+
+		username, err := store.Users.GetUsernameByID(r.Context(), userID)
+		if err != nil {
+			log.Println("Error fetching username:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		posts, err := store.Posts.ListManagedPosts(r.Context(), userID)
+		if err != nil {
+			log.Println("Error fetching managed posts:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		comments, err := store.Comments.ListUserComments(r.Context(), userID, manageCommentsLimit)
+		if err != nil {
+			log.Println("Error fetching user comments:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		renderPage(w, r, "manage_posts.html", managePostsPageData{
+			PageData: models.PageData{
+				IsAuthenticated: isAuth, UserID: userID, Username: username, Role: role,
+				ErrorMessage: flash(r, "error", "manage.error."),
+				Message:      flash(r, "message", "manage.message."),
+			},
+			Posts:         posts,
+			Comments:      comments,
+			CommentsLimit: manageCommentsLimit,
+		})
+	}
+}
+
+// managePosts выполняет пакетное действие формы страницы /my/posts и возвращает пользователя на неё.
+func managePosts(w http.ResponseWriter, r *http.Request, store *database.Store, userID int) {
+	if err := r.ParseForm(); err != nil {
+		http.Redirect(w, r, "/my/posts?error=invalid", http.StatusSeeOther)
+		return
+	}
+	action := r.PostForm.Get("action")
+	field := "post_id"
+	if action == "delete_comments" {
+		field = "comment_id"
+	}
+	ids, ok := formIDs(r.PostForm[field])
+	if !ok {
+		http.Redirect(w, r, "/my/posts?error=invalid", http.StatusSeeOther)
+		return
+	}
+	if len(ids) == 0 {
+		http.Redirect(w, r, "/my/posts?error=empty", http.StatusSeeOther)
+		return
+	}
+
+	var err error
+	switch action {
+	case "delete":
+		err = store.Posts.DeleteUserPosts(r.Context(), userID, ids)
+	case "unpublish", "publish":
+		err = store.Posts.SetUserPostsUnpublished(r.Context(), userID, ids, action == "unpublish")
+	case "categories":
+		categoryIDs, code, catErr := formCategoryIDs(r, store)
+		if catErr != nil {
+			log.Println("Error fetching categories:", catErr)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		if code != "" {
+			http.Redirect(w, r, "/my/posts?error="+code, http.StatusSeeOther)
+			return
+		}
+		err = store.Posts.SetUserPostsCategories(r.Context(), userID, ids, categoryIDs)
+	case "delete_comments":
+		err = store.Comments.DeleteUserComments(r.Context(), userID, ids)
+	default:
+		http.Redirect(w, r, "/my/posts?error=invalid", http.StatusSeeOther)
+		return
+	}
+	if errors.Is(err, database.ErrNotOwner) {
+		log.Printf("User %d attempted %s on content they do not own.", userID, action)
+		http.Redirect(w, r, "/my/posts?error=not_owner", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		log.Printf("Error applying %s to user %d content: %v", action, userID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError)
+		return
+	}
+	log.Printf("User %d applied %s to %d items.", userID, action, len(ids))
+	http.Redirect(w, r, "/my/posts?message="+action, http.StatusSeeOther)
+}
+
+// formIDs разбирает значения полей формы в положительные ID без повторов.
+func formIDs(values []string) ([]int, bool) {
+	ids := make([]int, 0, len(values))
+	for _, v := range values {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			return nil, false
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, true
+}
+
+// formCategoryIDs возвращает ID категорий из полей categories формы по тем же правилам, что и при создании
+// поста: от одной до трёх категорий из categorySlugs. Если выбор неверный, возвращает код ошибки
+// (category, too_many_categories).
+func formCategoryIDs(r *http.Request, store *database.Store) ([]int, string, error) {
+	var slugs []string
+	for _, slug := range r.PostForm["categories"] {
+		if slices.Contains(categorySlugs, slug) && !slices.Contains(slugs, slug) {
+			slugs = append(slugs, slug)
+		}
+	}
+	if len(slugs) == 0 {
+		return nil, "category", nil
+	}
+	if len(slugs) > 3 {
+		return nil, "too_many_categories", nil
+	}
+	ids := make([]int, len(slugs))
+	for i, slug := range slugs {
+		id, err := store.Posts.GetCategoryIDByName(r.Context(), slug)
+		if err != nil {
+			return nil, "", err
+		}
+		ids[i] = id
+	}
+	return ids, "", nil
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+
+	"forum/database"
+	"forum/integrations"
+	"forum/models"
+	"forum/screening"
+)
+
+// moderationQueueSize — сколько скрытых записей и последних решений показывает /moderation.
+const moderationQueueSize = 50
+
+// moderationPageData — данные страницы проверки: очередь скрытого содержимого и последние решения.
+type moderationPageData struct {
+	models.PageData
+	Pending   []models.ModerationDecision
+	Recent    []models.ModerationDecision
+	Provider  string // имя включённой проверки; пусто — проверка выключена
+	Threshold float64
+}
+
+// screen проверяет новое содержимое настроенной проверкой до записи в базу. ok = false, если проверка
+// выключена, автор — администратор или сервис не ответил: тогда содержимое публикуется как обычно.
+func screen(ctx context.Context, kind string, userID int, role, title, text string) (screening.Verdict, bool) {
+	if screener == nil || role == "admin" {
+		return screening.Verdict{}, false
+	}
+	v, err := screener.Screen(ctx, screening.Content{Kind: kind, Title: title, Text: text, AuthorID: userID})
+	if err != nil {
+		log.Printf("Error screening %s of user %d: %v", kind, userID, err)
+		return screening.Verdict{}, false
+	}
+	return v, true
+}
+
+// recordScreening сохраняет решение проверки только что созданного содержимого и сообщает, скрыто ли оно
+// до решения модератора. Если решение не удалось сохранить, содержимое остаётся опубликованным.
+func recordScreening(ctx context.Context, store *database.Store, kind string, itemID, userID int, v screening.Verdict) bool {
+	status := database.ModerationAllowed
+	if v.Hold(screenThreshold) {
+		status = database.ModerationPending
+	}
+	err := database.RecordScreening(ctx, store.DB, models.ModerationDecision{
+		Kind: kind, ItemID: itemID, UserID: userID, Provider: screener.Name(),
+		Score: v.Score, Flagged: v.Flagged, Categories: strings.Join(v.Categories, ","), Status: status,
+	})
+	if err != nil {
+		log.Printf("Error recording screening of %s %d: %v", kind, itemID, err)
+		return false
+	}
+	if status != database.ModerationPending {
+		return false
+	}
+	store.InvalidateCache()
+	log.Printf("%s %d of user %d held for review (score %.2f).", kind, itemID, userID, v.Score)
+	return true
+}
+
+// ModerationHandler показывает очередь содержимого, скрытого автоматической проверкой, и последние решения (/moderation).
+// Администратор видит всё, модератор раздела — посты и комментарии своих разделов.
+func ModerationHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAuth, userID, role := IsAuthenticated(store, r)
+		if !isAuth {
+			http.Redirect(w, r, "/login?redirect=/moderation", http.StatusSeeOther)
+			return
+		}
+
+		username, err := store.Users.GetUsernameByID(r.Context(), userID)
+		if err != nil {
+			log.Println("Error fetching username:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		all := role == "admin"
+		pending, err := database.ListModerationDecisions(r.Context(), store.DB, userID, all, true, moderationQueueSize)
+		if err != nil {
+			log.Println("Error fetching moderation queue:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		recent, err := database.ListModerationDecisions(r.Context(), store.DB, userID, all, false, moderationQueueSize)
+		if err != nil {
+			log.Println("Error fetching moderation decisions:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		data := moderationPageData{
+			PageData: models.PageData{
+				IsAuthenticated: isAuth, UserID: userID, Username: username, Role: role,
+				Message: flash(r, "message", "moderation.message."),
+			},
+			Pending:   pending,
+			Recent:    recent,
+			Threshold: screenThreshold,
+		}
+		if screener != nil {
+			data.Provider = screener.Name()
+		}
+		renderPage(w, r, "moderation.html", data)
+	}
+}
+
+// ResolveModerationHandler принимает решение по скрытому содержимому: POST /moderation/{id} с полем
+// action=approve публикует его, action=reject оставляет удалённым. Решать могут администратор и модераторы
+// раздела, в котором опубликовано содержимое. Одобренный пост рассылается интеграциям как новый.
+func ResolveModerationHandler(store *database.Store, notifier *integrations.Dispatcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAuth, userID, role := IsAuthenticated(store, r)
+		if !isAuth {
+			http.Redirect(w, r, "/login?redirect=/moderation", http.StatusSeeOther)
+			return
+		}
+		id, ok := pathID(r, "id")
+		if !ok {
+			return
+		}
+		action := r.FormValue("action")
+		if action != "approve" && action != "reject" {
+			w.WriteHeader(http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest)
+			return
+		}
+
+		decision, err := database.GetModerationDecision(r.Context(), store.DB, id)
+		if err == sql.ErrNoRows {
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching moderation decision:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		if role != "admin" {
+			moderates := moderatesComment
+			if decision.Kind == "post" {
+				moderates = moderatesPost
+			}
+			if !moderates(r, store, userID, decision.ItemID) {
+				w.WriteHeader(http.StatusForbidden)
+				writeError(w, r, http.StatusForbidden)
+				return
+			}
+		}
+
+		err = database.ResolveModeration(r.Context(), store.DB, id, userID, action == "approve")
+		if err == sql.ErrNoRows {
+			http.Redirect(w, r, "/moderation?message=resolved", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			log.Println("Error resolving moderation decision:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		store.InvalidateCache()
+
+		if action == "approve" && decision.Kind == "post" {
+			post, err := store.Posts.GetPostByID(r.Context(), decision.ItemID, 0)
+			if err != nil {
+				log.Println("Error fetching approved post:", err)
+			} else if !post.MembersOnly {
+				categories := make([]string, 0, len(post.Categories))
+				for _, c := range post.Categories {
+					categories = append(categories, c.Name)
+				}
+				notifier.PostPublished(integrations.PostEvent{
+					ID: post.ID, Title: post.Title, Author: post.Username, Categories: categories,
+				})
+			}
+		}
+		message := "rejected"
+		if action == "approve" {
+			message = "approved"
+		}
+		http.Redirect(w, r, "/moderation?message="+message, http.StatusSeeOther)
+	}
+}
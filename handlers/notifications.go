@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"forum/database"
+	"forum/models"
+)
+
+// notificationsSize — сколько последних уведомлений показывает /notifications.
+const notificationsSize = 50
+
+// notificationsPageData — данные страницы уведомлений о лайках.
+type notificationsPageData struct {
+	models.PageData
+	Notifications []models.Notification
+}
+
+// NotificationsHandler показывает уведомления пользователя о лайках его постов и комментариев (/notifications).
+// Лайки собираются в уведомления раз в день фоновой задачей (см. database.BuildVoteNotifications);
+// после показа все уведомления отмечаются прочитанными, а непрочитанные до этого выделяются на странице.
+func NotificationsHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAuth, userID, role := IsAuthenticated(store, r)
+		if !isAuth {
+			http.Redirect(w, r, "/login?redirect=/notifications", http.StatusSeeOther)
+			return
+		}
+
+		username, err := store.Users.GetUsernameByID(r.Context(), userID)
+		if err != nil {
+			log.Println("Error fetching username:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		list, err := database.GetNotifications(r.Context(), store.DB, userID, notificationsSize)
+		if err != nil {
+			log.Println("Error fetching notifications:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		for i, n := range list {
+			if n.Kind == database.NotificationCommentLikes {
+				list[i].Link = commentLink(n.PostID, n.ItemID)
+			} else {
+				list[i].Link = "/post/" + strconv.Itoa(n.PostID)
+			}
+		}
+
+		data := notificationsPageData{
+			PageData:      models.PageData{IsAuthenticated: isAuth, UserID: userID, Username: username, Role: role},
+			Notifications: list,
+		}
+		renderPage(w, r, "notifications.html", data)
+
+		if err := database.MarkNotificationsRead(r.Context(), store.DB, userID); err != nil {
+			log.Println("Error marking notifications read:", err)
+		}
+	}
+}
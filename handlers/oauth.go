@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"forum/database"
+	"forum/oauth"
+	"forum/proxy"
+	"forum/usernames"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// oauthStateCookie хранит параметр state входа через GitHub и адрес возврата до ответа GitHub.
+const oauthStateCookie = "oauth_state"
+
+// oauthStateLifetime — сколько пользователь может подтверждать доступ на github.com.
+const oauthStateLifetime = 10 * time.Minute
+
+// githubCallbackPath — адрес, на который GitHub возвращает пользователя; его указывают в настройках OAuth-приложения.
+const githubCallbackPath = "/auth/github/callback"
+
+// GitHubLoginHandler начинает вход через GitHub: запоминает в cookie случайный state и адрес возврата
+// (параметр redirect) и отправляет пользователя подтверждать доступ на github.com.
+// Уже вошедший пользователь так привязывает GitHub к своей учётной записи.
+// Если вход через GitHub выключен, ничего не пишет, и CustomHandler отвечает 404.
+func GitHubLoginHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if githubAuth == nil {
+			return
+		}
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			log.Println("Error generating OAuth state:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		state := hex.EncodeToString(b)
+		http.SetCookie(w, &http.Cookie{
+			Name:     oauthStateCookie,
+			Value:    state + "|" + url.QueryEscape(localRedirect(r.URL.Query().Get("redirect"))),
+			Path:     "/auth/github",
+			MaxAge:   int(oauthStateLifetime / time.Second),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+			Secure:   proxy.IsSecure(r),
+		})
+		http.Redirect(w, r, githubAuth.AuthCodeURL(state, absoluteURL(r, githubCallbackPath)), http.StatusSeeOther)
+	}
+}
+
+// GitHubCallbackHandler завершает вход через GitHub. Ответ без state из cookie отклоняется: так чужой сайт
+// не может подсунуть пользователю вход в свою учётную запись. Привязанная учётная запись GitHub открывает
+// сессию своего пользователя; вошедшему пользователю она привязывается; иначе создаётся новый пользователь
+// с почтой из GitHub и свободным именем на основе логина GitHub.
+func GitHubCallbackHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if githubAuth == nil {
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name: oauthStateCookie, Path: "/auth/github", MaxAge: -1, HttpOnly: true, Secure: proxy.IsSecure(r),
+		})
+		var state, redirectURL string
+		if cookie, err := r.Cookie(oauthStateCookie); err == nil {
+			var escaped string
+			state, escaped, _ = strings.Cut(cookie.Value, "|")
+			redirectURL, _ = url.QueryUnescape(escaped)
+		}
+		redirectURL = localRedirect(redirectURL)
+		got := r.URL.Query().Get("state")
+		if state == "" || subtle.ConstantTimeCompare([]byte(got), []byte(state)) != 1 {
+			log.Println("GitHub login rejected: state does not match.")
+			http.Redirect(w, r, "/?login_error=oauth", http.StatusSeeOther)
+			return
+		}
+		if r.URL.Query().Get("error") != "" {
+			http.Redirect(w, r, "/?login_error=oauth", http.StatusSeeOther)
+			return
+		}
+
+		token, err := githubAuth.Exchange(r.Context(), r.URL.Query().Get("code"), absoluteURL(r, githubCallbackPath))
+		var user oauth.User
+		if err == nil {
+			user, err = githubAuth.User(r.Context(), token)
+		}
+		if err != nil {
+			log.Println("Error completing GitHub login:", err)
+			http.Redirect(w, r, "/?login_error=oauth", http.StatusSeeOther)
+			return
+		}
+
+		userID, role, err := database.GetOAuthUser(r.Context(), store.DB, oauth.ProviderGitHub, user.ID)
+		if err == sql.ErrNoRows {
+			if isAuth, currentID, _ := IsAuthenticated(store, r); isAuth {
+				if err := database.LinkOAuthAccount(r.Context(), store.DB, oauth.ProviderGitHub, user.ID, currentID); err != nil {
+					log.Println("Error linking GitHub account:", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					writeError(w, r, http.StatusInternalServerError)
+					return
+				}
+				http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+				return
+			}
+			var code string
+			userID, code, err = registerGitHubUser(r, store, user)
+			if code != "" {
+				log.Printf("GitHub registration of %q rejected: %s.", user.Login, code)
+				http.Redirect(w, r, "/?login_error="+code, http.StatusSeeOther)
+				return
+			}
+			role = "user"
+		}
+		if err != nil {
+			log.Println("Error fetching GitHub account:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		if role == "banned" {
+			log.Printf("Banned user %d attempted to log in with GitHub.", userID)
+			http.Redirect(w, r, "/?login_error=banned", http.StatusSeeOther)
+			return
+		}
+		if err := startSession(w, r, store, userID, role); err != nil {
+			log.Println("Error starting session:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+	}
+}
+
+// registerGitHubUser создаёт пользователя для учётной записи GitHub user по тем же правилам, что и форма
+// регистрации: приглашения, почтовые домены. Возвращает ID пользователя или код ошибки входа
+// (oauth_email, oauth_email_taken, oauth_closed).
+func registerGitHubUser(r *http.Request, store *database.Store, user oauth.User) (int, string, error) {
+	if user.Email == "" {
+		return 0, "oauth_email", nil
+	}
+	inviteOnly, err := inviteOnlyRegistration(r, store)
+	if err != nil {
+		return 0, "", err
+	}
+	allowed, err := emailDomainAllowed(r, store, user.Email)
+	if err != nil {
+		return 0, "", err
+	}
+	if inviteOnly || !allowed || disposableDomains.Contains(user.Email) {
+		return 0, "oauth_closed", nil
+	}
+	// Учётная запись с той же почтой не привязывается сама: владелец входит паролем и привязывает GitHub сам.
+	exists, err := store.Users.EmailExists(r.Context(), user.Email)
+	if err != nil {
+		return 0, "", err
+	}
+	if exists {
+		return 0, "oauth_email_taken", nil
+	}
+
+	username, err := uniqueUsername(r, store, user.Login)
+	if err != nil {
+		return 0, "", err
+	}
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return 0, "", err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(password)), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, "", err
+	}
+	userID, err := database.RegisterOAuthUser(r.Context(), store.DB, oauth.ProviderGitHub, user.ID, user.Email, username,
+		user.Name, string(hashedPassword))
+	return userID, "", err
+}
+
+// uniqueUsername подбирает свободное имя пользователя на основе логина login: сам логин, а если он занят —
+// логин с номером (octocat2, octocat3, …). Логин, который не подходит под правила имён, заменяется на «user».
+func uniqueUsername(r *http.Request, store *database.Store, login string) (string, error) {
+	base := login
+	if err := usernameRules.Check(base); err != nil && err != usernames.ErrLength {
+		base = "user"
+	}
+	maxLength := len(base)
+	if usernameRules != nil {
+		maxLength = usernameRules.MaxLength
+	}
+	for n := 1; n <= 1000; n++ {
+		suffix := ""
+		if n > 1 {
+			suffix = fmt.Sprint(n)
+		}
+		candidate := base
+		if len(candidate)+len(suffix) > maxLength {
+			candidate = candidate[:maxLength-len(suffix)]
+		}
+		candidate += suffix
+		if usernameRules.Check(candidate) != nil {
+			continue
+		}
+		exists, err := store.Users.UsernameExists(r.Context(), candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+	}
+	return "", errors.New("no free username for " + login)
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+
+	"forum/database"
+	"forum/models"
+)
+
+// staticPageData — данные служебной страницы: пользователь для шапки и сама страница; текст переводится
+// из Markdown в шаблоне (функция markdown).
+type staticPageData struct {
+	models.PageData
+	Page models.Page
+}
+
+// PageHandler отображает служебную страницу /pages/{slug} (правила, о проекте, FAQ) в общем оформлении сайта.
+// Для несуществующей страницы ничего не пишет, и CustomHandler отвечает 404.
+func PageHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, err := database.GetPage(r.Context(), store.DB, r.PathValue("slug"))
+		if err == sql.ErrNoRows {
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching page:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		isAuth, userID, role := IsAuthenticated(store, r)
+		var username string
+		if isAuth {
+			if username, err = store.Users.GetUsernameByID(r.Context(), userID); err != nil {
+				log.Println("Error fetching username:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+		}
+
+		data := staticPageData{
+			PageData: models.PageData{IsAuthenticated: isAuth, UserID: userID, Username: username, Role: role},
+			Page:     page,
+		}
+		renderPage(w, r, "page.html", data)
+	}
+}
+
+// adminPagesData — данные страницы управления служебными страницами: список и форма редактирования.
+type adminPagesData struct {
+	models.PageData
+	Pages  []models.Page
+	Page   models.Page
+	Exists bool
+}
+
+// AdminPagesHandler показывает администратору список служебных страниц и форму новой страницы (GET /admin/pages)
+// и сохраняет страницу из формы (POST /admin/pages): существующая страница с тем же адресом перезаписывается.
+func AdminPagesHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := requireAdmin(w, r, store)
+		if !ok {
+			return
+		}
+		if r.Method == "POST" {
+			savePage(w, r, store)
+			return
+		}
+		renderAdminPages(w, r, store, username, models.Page{}, false)
+	}
+}
+
+// AdminPageHandler показывает форму редактирования страницы /admin/pages/{slug} с предпросмотром.
+// Если страницы ещё нет, форма пуста, и сохранение создаст её под этим адресом.
+func AdminPageHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := requireAdmin(w, r, store)
+		if !ok {
+			return
+		}
+		slug := r.PathValue("slug")
+		page, err := database.GetPage(r.Context(), store.DB, slug)
+		if err != nil && err != sql.ErrNoRows {
+			log.Println("Error fetching page:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		page.Slug = slug
+		renderAdminPages(w, r, store, username, page, err == nil)
+	}
+}
+
+// DeletePageHandler удаляет служебную страницу /admin/pages/{slug} и возвращает администратора к списку.
+func DeletePageHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireAdmin(w, r, store); !ok {
+			return
+		}
+		slug := r.PathValue("slug")
+		if err := database.DeletePage(r.Context(), store.DB, slug); err != nil {
+			log.Println("Error deleting page:", err)
+			http.Redirect(w, r, "/admin/pages?error=server", http.StatusSeeOther)
+			return
+		}
+		log.Printf("Page %s deleted.", slug)
+		http.Redirect(w, r, "/admin/pages?message=deleted", http.StatusSeeOther)
+	}
+}
+
+// savePage проверяет и сохраняет страницу из формы, затем перенаправляет на её форму редактирования.
+func savePage(w http.ResponseWriter, r *http.Request, store *database.Store) {
+	slug := strings.TrimSpace(r.FormValue("slug"))
+	title := strings.TrimSpace(r.FormValue("title"))
+	content := strings.TrimSpace(r.FormValue("content"))
+	if !database.ValidPageSlug(slug) {
+		http.Redirect(w, r, "/admin/pages?error=slug", http.StatusSeeOther)
+		return
+	}
+	if title == "" || content == "" {
+		http.Redirect(w, r, "/admin/pages/"+slug+"?error=empty", http.StatusSeeOther)
+		return
+	}
+	if err := database.SavePage(r.Context(), store.DB, slug, title, content); err != nil {
+		log.Println("Error saving page:", err)
+		http.Redirect(w, r, "/admin/pages/"+slug+"?error=server", http.StatusSeeOther)
+		return
+	}
+	log.Printf("Page %s saved.", slug)
+	http.Redirect(w, r, "/admin/pages/"+slug+"?message=saved", http.StatusSeeOther)
+}
+
+// renderAdminPages выводит список страниц и форму для page; exists сообщает, что страница уже сохранена.
+func renderAdminPages(w http.ResponseWriter, r *http.Request, store *database.Store, username string, page models.Page, exists bool) {
+	list, err := database.ListPages(r.Context(), store.DB)
+	if err != nil {
+		log.Println("Error listing pages:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError)
+		return
+	}
+	data := adminPagesData{
+		PageData: models.PageData{
+			Username:     username,
+			ErrorMessage: flash(r, "error", "pages.error."),
+			Message:      flash(r, "message", "pages.message."),
+		},
+		Pages:  list,
+		Page:   page,
+		Exists: exists,
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	renderPage(w, r, "admin_pages.html", data)
+}
+
+// requireAdmin пропускает только администратора и возвращает его имя. Гостя перенаправляет на вход,
+// остальным отвечает 403; в этих случаях ok равно false и ответ уже записан.
+func requireAdmin(w http.ResponseWriter, r *http.Request, store *database.Store) (username string, ok bool) {
+	isAuth, userID, role := IsAuthenticated(store, r)
+	if !isAuth {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return "", false
+	}
+	if role != "admin" {
+		w.WriteHeader(http.StatusForbidden)
+		writeError(w, r, http.StatusForbidden)
+		return "", false
+	}
+	username, err := store.Users.GetUsernameByID(r.Context(), userID)
+	if err != nil {
+		log.Println("Error getting username:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError)
+		return "", false
+	}
+	return username, true
+}
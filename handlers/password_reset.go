@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"forum/database"
+	"forum/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// resetPasswordPath — адрес ссылки сброса пароля из письма.
+const resetPasswordPath = "/reset-password"
+
+// resetPageData — данные страницы нового пароля.
+type resetPageData struct {
+	models.PageData
+	Token string // ссылка из письма; пусто — ссылка недействительна, и форма не показывается
+}
+
+// hashResetToken возвращает хеш ссылки сброса пароля, под которым она хранится в базе.
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ForgotPasswordHandler показывает форму «Забыли пароль?» (/forgot-password); POST отправляет на указанную почту
+// ссылку сброса, действующую registration.reset_link_age. Ответ одинаков, есть ли такой пользователь или нет,
+// чтобы по форме нельзя было проверить, зарегистрирован ли адрес.
+// Если отправка писем не настроена, ничего не пишет, и CustomHandler отвечает 404.
+func ForgotPasswordHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if mailSender == nil {
+			return
+		}
+		if r.Method != http.MethodPost {
+			renderPage(w, r, "forgot_password.html", models.PageData{
+				ErrorMessage: flash(r, "error", "reset.error."),
+				Message:      flash(r, "message", "reset.message."),
+			})
+			return
+		}
+
+		email := strings.TrimSpace(r.FormValue("email"))
+		if email == "" {
+			http.Redirect(w, r, "/forgot-password?error=required", http.StatusSeeOther)
+			return
+		}
+		userID, _, _, role, err := store.Users.GetUserByEmail(r.Context(), email)
+		if err == sql.ErrNoRows || role == "banned" {
+			log.Printf("Password reset requested for unknown or banned address %s.", email)
+			http.Redirect(w, r, "/forgot-password?message=sent", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching user:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			log.Println("Error generating password reset token:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		token := base64.RawURLEncoding.EncodeToString(b)
+		if err := database.CreatePasswordReset(r.Context(), store.DB, userID, hashResetToken(token), time.Now().Add(resetLinkAge)); err != nil {
+			log.Println("Error saving password reset:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		link := absoluteURL(r, resetPasswordPath+"?token="+url.QueryEscape(token))
+		site := pageSite(r).Title
+		body := tr(r, "reset.email.body", site, link, int(resetLinkAge.Minutes()))
+		if err := mailSender.Send(r.Context(), email, tr(r, "reset.email.subject", site), body); err != nil {
+			log.Println("Error sending password reset email:", err)
+			http.Redirect(w, r, "/forgot-password?error=send_failed", http.StatusSeeOther)
+			return
+		}
+		log.Printf("Password reset link sent to user %d.", userID)
+		http.Redirect(w, r, "/forgot-password?message=sent", http.StatusSeeOther)
+	}
+}
+
+// ResetPasswordHandler задаёт новый пароль по ссылке из письма (/reset-password?token=…). GET показывает форму,
+// POST сохраняет новый пароль, после чего ссылка перестаёт работать, а все сессии пользователя закрываются:
+// вошедший с украденным паролем теряет доступ. Если отправка писем не настроена, CustomHandler отвечает 404.
+func ResetPasswordHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if mailSender == nil {
+			return
+		}
+		if r.Method != http.MethodPost {
+			token := r.URL.Query().Get("token")
+			_, err := database.GetPasswordReset(r.Context(), store.DB, hashResetToken(token), time.Now())
+			if err != nil && !errors.Is(err, database.ErrResetInvalid) {
+				log.Println("Error fetching password reset:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+			data := resetPageData{PageData: models.PageData{ErrorMessage: flash(r, "error", "reset.error.")}}
+			if err != nil {
+				data.ErrorMessage = tr(r, "reset.error.invalid")
+			} else {
+				data.Token = token
+			}
+			w.Header().Set("Cache-Control", "no-store")
+			w.Header().Set("Referrer-Policy", "no-referrer")
+			renderPage(w, r, "reset_password.html", data)
+			return
+		}
+
+		token := r.FormValue("token")
+		password := r.FormValue("password")
+		retry := resetPasswordPath + "?token=" + url.QueryEscape(token) + "&error="
+		if password == "" {
+			http.Redirect(w, r, retry+"required", http.StatusSeeOther)
+			return
+		}
+		if password != r.FormValue("confirm") {
+			http.Redirect(w, r, retry+"mismatch", http.StatusSeeOther)
+			return
+		}
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+			http.Redirect(w, r, retry+"too_long", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			log.Println("Error hashing password:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		userID, err := database.ResetPassword(r.Context(), store.DB, hashResetToken(token), string(hashedPassword), time.Now())
+		if errors.Is(err, database.ErrResetInvalid) {
+			log.Println("Password reset rejected: invalid or expired link.")
+			http.Redirect(w, r, resetPasswordPath, http.StatusSeeOther)
+			return
+		}
+		if err == nil {
+			err = store.Users.DeleteUserSessions(r.Context(), userID)
+		}
+		if err != nil {
+			log.Println("Error resetting password:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		log.Printf("User %d reset their password; all sessions closed.", userID)
+		http.Redirect(w, r, "/?message=password_reset", http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"forum/database"
+	"forum/models"
+	"forum/permissions"
+)
+
+// userStanding загружает карму, дату регистрации и показатели активности пользователя для проверки его прав
+// и уровня доверия (см. privileges).
+// Роль берётся из сессии (role), а не из базы, как и в остальных проверках прав.
+func userStanding(ctx context.Context, store *database.Store, userID int, role string) (permissions.Standing, error) {
+	s, err := store.Users.GetUserStanding(ctx, userID)
+	if err != nil {
+		return permissions.Standing{}, err
+	}
+	return standingOf(s, role), nil
+}
+
+// standingOf переводит показатели пользователя s с ролью role в сведения для проверки прав.
+func standingOf(s models.UserStanding, role string) permissions.Standing {
+	return permissions.Standing{
+		Role: role, Karma: s.Karma, CreatedAt: s.CreatedAt, Read: s.Read, Posts: s.Posts, Comments: s.Comments,
+	}
+}
+
+// postFormError возвращает сообщение об ошибке формы поста из параметра error. Сообщения об ограничениях
+// новых аккаунтов и кармы подставляют действующие пороги, остальные берутся из post.error.* как есть.
+func postFormError(r *http.Request) string {
+	hours := int(privileges.NewAccountAge.Hours())
+	switch r.URL.Query().Get("error") {
+	case "image_karma":
+		return tr(r, "post.error.image_karma", privileges.ImageKarma)
+	case "new_account_image":
+		return tr(r, "post.error.new_account_image", hours)
+	case "links":
+		if privileges.NewAccountLinks == 0 {
+			return tr(r, "post.error.no_links", hours)
+		}
+		return tr(r, "post.error.links", hours, privileges.NewAccountLinks)
+	case "rate":
+		return tr(r, "post.error.rate", privileges.NewAccountPostsPerHour)
+	}
+	return flash(r, "error", "post.error.")
+}
+
+// imageError возвращает код ошибки формы поста, если пользователю нельзя добавить изображение, иначе "".
+func imageError(standing permissions.Standing, now time.Time) string {
+	switch {
+	case privileges.IsNew(standing, now):
+		return "new_account_image"
+	case !privileges.Allows(standing, permissions.PostImage):
+		return "image_karma"
+	}
+	return ""
+}
+
+// allowVote проверяет ограничение голосов в час (см. permissions.ActivityVote) перед тем, как поставить или
+// изменить голос; снять голос можно всегда. Если лимит исчерпан или проверка не удалась, отвечает JSON
+// с сообщением и возвращает false.
+func allowVote(w http.ResponseWriter, r *http.Request, store *database.Store, userID int, standing permissions.Standing) bool {
+	now := time.Now()
+	limit := privileges.HourlyLimit(standing, now, permissions.ActivityVote)
+	if limit == 0 {
+		return true
+	}
+	recent, err := store.Votes.CountRecentVotes(r.Context(), userID, now.Add(-time.Hour))
+	if err != nil {
+		log.Println("Error counting recent votes:", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "message": tr(r, "api.server_error")})
+		return false
+	}
+	if recent >= limit {
+		log.Printf("User %d hit the vote limit (%d per hour).", userID, limit)
+		writeJSON(w, http.StatusTooManyRequests, map[string]interface{}{"success": false, "message": tr(r, "api.vote_rate", limit)})
+		return false
+	}
+	return true
+}
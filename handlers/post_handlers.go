@@ -1,212 +1,481 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"html/template"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"forum/database"
+	"forum/i18n"
+	"forum/integrations"
 	"forum/models"
+	"forum/permissions"
 )
 
-// IndexHandler отображает главную страницу с постами.
+// IndexHandler отображает главную страницу с постами всех разделов.
 // Принимает GET-запрос с параметрами filter и category, возвращает HTML-страницу.
-// Перенаправляет неаутентифицированных пользователей на логин для фильтров my, liked, commented.
-func IndexHandler(db *sql.DB) http.HandlerFunc {
+// Перенаправляет неаутентифицированных пользователей на логин для личных фильтров (см. personalFeedFilters).
+// Поддерживает условные запросы (ETag/Last-Modified) и отвечает 304, если лента не изменилась.
+func IndexHandler(store *database.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/" {
+		serveFeed(w, r, store, models.Board{})
+	}
+}
+
+// BoardHandler отображает страницу раздела /b/{board}: описание, модераторов и ленту постов раздела
+// с теми же параметрами filter и category, что и на главной странице.
+// Для неизвестного раздела ничего не пишет, и CustomHandler отвечает страницей 404.
+func BoardHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		board, err := store.Boards.GetBoardBySlug(r.Context(), r.PathValue("board"))
+		if err == sql.ErrNoRows {
 			return
 		}
-
-		if r.Method != "GET" {
-			log.Println("Method not allowed:", r.Method)
-			w.Header().Set("Content-Type", "text/plain")
-			w.Header().Set("Allow", "GET")
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			fmt.Fprintln(w, "Method not allowed.")
+		if err != nil {
+			log.Println("Error fetching board:", err)
+			writeError(w, r, http.StatusInternalServerError)
 			return
 		}
+		serveFeed(w, r, store, board)
+	}
+}
 
-		isAuth, userID, role := IsAuthenticated(db, r)
-		var username string
-		if isAuth {
-			var err error
-			username, err = database.GetUsernameByID(db, userID)
-			if err != nil {
-				log.Println("Error fetching username:", err)
-				writeError(w, http.StatusInternalServerError)
-				return
-			}
+// serveFeed отображает ленту постов раздела board или всех разделов, если board пуст.
+// Параметр days ограничивает ленту постами за последние days дней (например, filter=unanswered&days=7 — посты
+// без ответов за неделю), параметры from и to — постами за период между датами (см. feedPeriod),
+// параметр author — постами одного автора (имя без учёта регистра).
+// Параметр hidden=1 показывает и посты с рейтингом ниже порога скрытия (см. database.SettingHideScoreBelow).
+// Лента выводится страницами по feedPageSize постов: ссылка «Показать ещё» ведёт на следующую страницу (cursor),
+// а script.js вместо перехода дозагружает её через /api/feed.
+func serveFeed(w http.ResponseWriter, r *http.Request, store *database.Store, board models.Board) {
+	isAuth, userID, role := IsAuthenticated(store, r)
+	var username string
+	if isAuth {
+		var err error
+		username, err = store.Users.GetUsernameByID(r.Context(), userID)
+		if err != nil {
+			log.Println("Error fetching username:", err)
+			writeError(w, r, http.StatusInternalServerError)
+			return
 		}
+	}
 
-		message := r.URL.Query().Get("message")
-		filter := r.URL.Query().Get("filter")
-		if filter == "" {
-			filter = "new"
-		}
-		category := r.URL.Query().Get("category")
-		log.Printf("Filter applied: %s, Category: %s.", filter, category)
+	filter := r.URL.Query().Get("filter")
+	if filter == "" {
+		filter = "new"
+	}
+	category := r.URL.Query().Get("category")
+	author := strings.TrimSpace(r.URL.Query().Get("author"))
+	showHidden := r.URL.Query().Get("hidden") == "1"
+	cursor := r.URL.Query().Get("cursor")
+	log.Printf("Filter applied: %s, Category: %s.", filter, category)
+
+	if !feedFilters[filter] {
+		log.Printf("Invalid filter value: %s.", filter)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, tr(r, "api.invalid_filter"))
+		return
+	}
 
-		validFilters := map[string]bool{
-			"new": true, "best": true, "my": true, "liked": true, "commented": true,
-		}
-		if !validFilters[filter] {
-			log.Printf("Invalid filter value: %s.", filter)
-			w.Header().Set("Content-Type", "text/plain")
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintln(w, "Invalid filter value.")
-			return
-		}
+	from, to, ok := feedPeriod(r.URL.Query(), time.Now())
+	if !ok {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, tr(r, "api.invalid_period"))
+		return
+	}
 
-		validCategories := map[string]bool{
-			"news": true, "life": true, "auto": true, "creative": true,
-			"gadgets": true, "science": true, "games": true, "other": true,
-		}
-		if category != "" && !validCategories[category] {
-			log.Printf("Invalid category value: %s.", category)
-			w.Header().Set("Content-Type", "text/plain")
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintln(w, "Invalid category value.")
+	if category != "" && !slices.Contains(categorySlugs, category) {
+		log.Printf("Invalid category value: %s.", category)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, tr(r, "api.invalid_category"))
+		return
+	}
+
+	if personalFeedFilters[filter] && !isAuth {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	var etag string
+	version, err := store.Posts.GetFeedVersion(r.Context())
+	if err != nil {
+		log.Println("Error querying feed version:", err)
+	} else {
+		etag = versionETag(true, version, "index", board.Slug, r.URL.RawQuery, from.Format(time.DateOnly), strconv.Itoa(userID), role, username,
+			i18n.FromContext(r.Context()), readState(store, r, userID))
+		setPrivateCaching(w)
+		if notModified(w, r, etag, version.LastModified) {
 			return
 		}
+	}
+	cacheKey := fragmentKey(store, isAuth, "index", etag)
+	if writeCachedFragment(w, store, cacheKey) {
+		return
+	}
 
-		posts, err := database.GetPosts(db, userID, filter, category)
-		if err != nil {
-			log.Println("Error querying posts:", err)
-			writeError(w, http.StatusInternalServerError)
-			return
+	boards, err := store.Boards.ListBoards(r.Context())
+	if err != nil {
+		log.Println("Error querying boards:", err)
+		writeError(w, r, http.StatusInternalServerError)
+		return
+	}
+	// Число постов и последняя активность всех категорий считаются одним запросом (см. database.ListCategories).
+	categories, err := store.Categories.ListCategories(r.Context())
+	if err != nil {
+		log.Println("Error querying categories:", err)
+		writeError(w, r, http.StatusInternalServerError)
+		return
+	}
+	feed := database.FeedQuery{Filter: filter, Category: category, Board: board.Slug, ShowHidden: showHidden, Author: author, From: from, To: to}
+	posts, nextCursor, err := store.Posts.GetFeedPage(r.Context(), userID, feed, feedPageSize, cursor)
+	if errors.Is(err, database.ErrInvalidCursor) {
+		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Println("Error querying posts:", err)
+		writeError(w, r, http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Posts retrieved: %d.", len(posts))
+	// Лайки, дизлайки, число комментариев и голос пользователя уже посчитаны в GetFeedPage; из комментариев
+	// карточкам нужен только последний.
+	if err := attachLatestComments(r.Context(), store, posts, filter, userID); err != nil {
+		log.Println("Error querying latest comments:", err)
+		writeError(w, r, http.StatusInternalServerError)
+		return
+	}
+	for i := range posts {
+		posts[i].CreatedAtStr = posts[i].CreatedAt.Format(time.DateOnly)
+	}
+
+	data := models.PageData{
+		IsAuthenticated: isAuth,
+		UserID:          userID,
+		Username:        username,
+		Role:            role,
+		Posts:           posts,
+		ErrorMessage:    flash(r, "login_error", "login.error."),
+		Filter:          filter,
+		Message:         flash(r, "message", "message."),
+		Boards:          boards,
+		Board:           board,
+		Categories:      categories,
+		ShowHidden:      showHidden,
+	}
+	if i := slices.IndexFunc(categories, func(c models.Category) bool { return c.Name == category }); i >= 0 {
+		data.Category = categories[i]
+	}
+	data.Days, _ = feedDays(r.URL.Query().Get("days"))
+	data.From, data.To = r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	data.Author = author
+	if nextCursor != "" {
+		data.NextPage, data.NextFeed = feedLinks(board.Slug, r.URL.Query(), nextCursor)
+	}
+
+	renderCachedPage(w, r, store, cacheKey, "index.html", data)
+}
+
+// feedPageSize — число постов на одной странице ленты, в том числе во фрагменте /api/feed.
+const feedPageSize = 20
+
+// commentsPageSize — число комментариев на странице поста и в каждой подгрузке из /api/comments.
+const commentsPageSize = 20
+
+// latestCommentLen — длина отрывка последнего комментария на карточке поста в ленте, в символах.
+const latestCommentLen = 100
+
+// feedFilters перечисляет допустимые значения параметра filter ленты.
+var feedFilters = map[string]bool{
+	"new": true, "best": true, "my": true, "liked": true, "disliked": true, "commented": true, "my-comments": true,
+	"unread": true, "unanswered": true,
+}
+
+// personalFeedFilters — фильтры ленты, которые зависят от пользователя и требуют входа.
+var personalFeedFilters = map[string]bool{
+	"my": true, "liked": true, "disliked": true, "commented": true, "my-comments": true, "unread": true,
+}
+
+// feedMaxDays — наибольшее значение параметра days ленты.
+const feedMaxDays = 365
+
+// feedDays разбирает параметр days ленты: число дней от 1 до feedMaxDays; пустое значение — 0, без ограничения.
+// ok ложно для неверного значения.
+func feedDays(value string) (days int, ok bool) {
+	if value == "" {
+		return 0, true
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 1 || days > feedMaxDays {
+		return 0, false
+	}
+	return days, true
+}
+
+// feedSince возвращает начало периода ленты за последние days дней в момент now: полночь по времени сервера,
+// чтобы граница, а с ней ETag и ключ кэша, не менялись в течение дня. Для days = 0 возвращает нулевое время.
+func feedSince(days int, now time.Time) time.Time {
+	if days == 0 {
+		return time.Time{}
+	}
+	y, m, d := now.AddDate(0, 0, 1-days).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+}
+
+// feedPeriod разбирает период ленты из параметров запроса: days (последние дни, см. feedDays) или from и to —
+// даты ГГГГ-ММ-ДД по времени сервера, обе включительно; любую из дат можно не указывать.
+// Возвращает начало периода и границу сразу после его конца (нулевое время — без ограничения).
+// ok ложно для неверного значения, для from позже to и для days вместе с from.
+func feedPeriod(query url.Values, now time.Time) (from, to time.Time, ok bool) {
+	days, ok := feedDays(query.Get("days"))
+	if !ok {
+		return from, to, false
+	}
+	from = feedSince(days, now)
+	if value := query.Get("from"); value != "" {
+		date, err := time.ParseInLocation(time.DateOnly, value, now.Location())
+		if err != nil || days != 0 {
+			return from, to, false
 		}
-		log.Printf("Posts retrieved: %d.", len(posts))
-		for i, p := range posts {
-			likes, dislikes, userVote, _, _ := database.GetPostVoteStats(db, userID, p.ID)
-			posts[i].Likes = likes
-			posts[i].Dislikes = dislikes
-			posts[i].UserVote = int(userVote)
-			posts[i].CreatedAtStr = posts[i].CreatedAt.Format(time.DateOnly)
-			log.Printf("Post %d: ID=%d, Likes=%d, Dislikes=%d.", i, p.ID, p.Likes, p.Dislikes)
+		from = date
+	}
+	if value := query.Get("to"); value != "" {
+		date, err := time.ParseInLocation(time.DateOnly, value, now.Location())
+		if err != nil || date.Before(from) {
+			return from, to, false
 		}
+		to = date.AddDate(0, 0, 1)
+	}
+	return from, to, true
+}
 
-		if (filter == "my" || filter == "liked" || filter == "commented") && !isAuth {
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
-			return
-		}
+// feedParams — параметры ленты, которые переносятся в ссылки на следующую страницу.
+var feedParams = []string{"filter", "category", "hidden", "author", "days", "from", "to"}
 
-		for i := range posts {
-			comments, err := database.GetCommentsByPostIDWithUserVote(db, userID, posts[i].ID)
-			if err != nil {
-				log.Println("Error querying comments:", err)
-				writeError(w, http.StatusInternalServerError)
-				return
-			}
-			posts[i].Comments = comments
+// feedLinks возвращает адрес следующей страницы ленты с параметрами params (см. feedParams) и адрес её фрагмента
+// в /api/feed с теми же параметрами.
+func feedLinks(board string, params url.Values, cursor string) (page, feed string) {
+	query := url.Values{"cursor": {cursor}}
+	for _, name := range feedParams {
+		if value := params.Get(name); value != "" {
+			query.Set(name, value)
 		}
+	}
+	page = "/?" + query.Encode()
+	if board != "" {
+		page = "/b/" + url.PathEscape(board) + "?" + query.Encode()
+		query.Set("board", board)
+	}
+	return page, "/api/feed?" + query.Encode()
+}
 
-		tmpl, err := template.ParseFiles("templates/index.html")
-		if err != nil {
-			log.Println("Error parsing template:", err)
-			writeError(w, http.StatusInternalServerError)
-			return
-		}
+// commentLinks возвращает адреса следующей страницы комментариев: page — страница поста с параметром cursor
+// (для браузеров без JavaScript), api — фрагмент /api/comments для подгрузки при прокрутке.
+func commentLinks(postID int, cursor string) (page, api string) {
+	page = "/post/" + strconv.Itoa(postID) + "?" + url.Values{"cursor": {cursor}}.Encode()
+	query := url.Values{"post_id": {strconv.Itoa(postID)}, "limit": {strconv.Itoa(commentsPageSize)}, "cursor": {cursor}, "format": {"html"}}
+	return page, "/api/comments?" + query.Encode()
+}
 
-		data := models.PageData{
-			IsAuthenticated: isAuth,
-			UserID:          userID,
-			Username:        username,
-			Role:            role,
-			Posts:           posts,
-			ErrorMessage:    r.URL.Query().Get("login_error"),
-			Filter:          filter,
-			Message:         message,
+// attachLatestComments подставляет постам ленты filter их последний комментарий с отрывком текста одним запросом.
+// В ленте my-comments это последний комментарий пользователя userID, и карточка ведёт прямо к нему.
+func attachLatestComments(ctx context.Context, store *database.Store, posts []models.PostData, filter string, userID int) error {
+	authorID := 0
+	if filter == "my-comments" {
+		authorID = userID
+	}
+	latest, err := store.Comments.GetLatestCommentsByPostIDs(ctx, postIDs(posts), authorID, latestCommentLen+1)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		if c, ok := latest[posts[i].ID]; ok {
+			c.Content = excerpt(c.Content, latestCommentLen)
+			posts[i].LatestComment = &c
+			if authorID != 0 {
+				posts[i].Link = commentLink(c.PostID, c.ID)
+			}
 		}
+	}
+	return nil
+}
 
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		if err := tmpl.Execute(w, data); err != nil {
-			log.Println("Error executing template:", err)
-		}
+// commentLink возвращает адрес комментария commentID на странице поста postID: страница комментариев
+// начинается с него (параметр comment), а якорь прокручивает к нему.
+func commentLink(postID, commentID int) string {
+	return "/post/" + strconv.Itoa(postID) + "?comment=" + strconv.Itoa(commentID) + "#comment-" + strconv.Itoa(commentID)
+}
+
+// postIDs возвращает ID постов в том же порядке, что и в списке.
+func postIDs(posts []models.PostData) []int {
+	ids := make([]int, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
 	}
+	return ids
 }
 
 // CreatePostHandler создаёт новый пост.
-// При GET отображает форму создания, при POST сохраняет пост с категориями.
+// При GET отображает форму создания (раздел можно выбрать заранее параметром board),
+// при POST сохраняет пост в выбранном разделе с категориями; без поля board пост попадает в раздел по умолчанию.
 // Требует аутентификации, перенаправляет на логин при её отсутствии.
-func CreatePostHandler(db *sql.DB) http.HandlerFunc {
+// После публикации уведомляет внешние интеграции (Discord, Telegram), подписанные на категории поста.
+// Если включена автоматическая проверка и она скрыла пост, интеграции получат его только после одобрения модератором.
+func CreatePostHandler(store *database.Store, notifier *integrations.Dispatcher) http.HandlerFunc {
 	allowedCategories := map[string]bool{
 		"news": true, "gadgets": true, "life": true, "auto": true,
 		"creative": true, "science": true, "games": true, "other": true,
 	}
 	return func(w http.ResponseWriter, r *http.Request) {
-		isAuth, userID, role := IsAuthenticated(db, r)
+		isAuth, userID, role := IsAuthenticated(store, r)
 		if !isAuth {
-			http.Redirect(w, r, "/login?redirect=/create-post", http.StatusSeeOther)
+			http.Redirect(w, r, "/login?redirect=/post/new", http.StatusSeeOther)
 			return
 		}
 
-		username, err := database.GetUsernameByID(db, userID)
+		username, err := store.Users.GetUsernameByID(r.Context(), userID)
 		if err != nil {
 			log.Println("Error fetching username:", err)
-			writeError(w, http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		// Пока почта не подтверждена, форма показывается с кнопкой нового письма, а пост не сохраняется.
+		unverified, err := emailUnverified(r.Context(), store, userID)
+		if err != nil {
+			log.Println("Error checking email verification:", err)
+			writeError(w, r, http.StatusInternalServerError)
 			return
 		}
 
-		fmt.Println(r.Method)
 		if r.Method == "GET" {
-			tmpl, err := template.ParseFiles("templates/create_post.html")
+			boards, err := store.Boards.ListBoards(r.Context())
 			if err != nil {
-				log.Println("Error parsing create post template:", err)
-				writeError(w, http.StatusInternalServerError)
+				log.Println("Error querying boards:", err)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
+			series, err := store.Series.ListUserSeries(r.Context(), userID)
+			if err != nil {
+				log.Println("Error querying series:", err)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+			selected := r.URL.Query().Get("board")
+			if selected == "" {
+				selected = database.DefaultBoard
+			}
 			pageData := models.PageData{
 				IsAuthenticated: isAuth,
 				UserID:          userID,
 				Username:        username,
 				Role:            role,
-				ErrorMessage:    r.URL.Query().Get("error"),
+				ErrorMessage:    postFormError(r),
+				Boards:          boards,
+				Board:           models.Board{Slug: selected},
+				Series:          series,
+				EmailUnverified: unverified,
 			}
-			if err := tmpl.Execute(w, pageData); err != nil {
-				log.Println("Error executing create post template:", err)
-				writeError(w, http.StatusInternalServerError)
+			if unverified && pageData.ErrorMessage == "" {
+				pageData.ErrorMessage = tr(r, "post.error.unverified")
 			}
+			renderPage(w, r, "create_post.html", pageData)
 			return
 		}
-
-		if r.Method != "POST" {
-			err := ErrorTpl.Execute(
-				w,
-				struct {
-					Code    int
-					Message string
-				}{
-					405, "Method not allowed",
-				},
-			)
-			if err != nil {
-				http.Error(w, "Method not allowd", 405)
-			}
+		if unverified {
+			http.Redirect(w, r, "/post/new?error=unverified", http.StatusSeeOther)
 			return
 		}
 
-		if err := r.ParseForm(); err != nil {
-			log.Println("Error parsing form:", err)
-			http.Redirect(w, r, "/create-post?error=Bad+request", http.StatusSeeOther)
+		if code := parsePostForm(w, r); code != "" {
+			http.Redirect(w, r, "/post/new?error="+code, http.StatusSeeOther)
 			return
 		}
 
 		title := strings.TrimSpace(r.FormValue("title"))
 		content := strings.TrimSpace(r.FormValue("content"))
-		imageURL := r.FormValue("image_url")
+		imageURL := strings.TrimSpace(r.FormValue("image_url"))
+		upload := uploadedImage(r)
+		membersOnly := r.FormValue("members_only") == "1"
+		policy, validPolicy := commentPolicy(r)
 		categories := r.Form["categories"]
 
 		if title == "" || content == "" {
-			http.Redirect(w, r, "/create-post?error=Title+and+content+cannot+be+empty", http.StatusSeeOther)
+			http.Redirect(w, r, "/post/new?error=empty", http.StatusSeeOther)
 			return
 		}
+		if !validPolicy {
+			http.Redirect(w, r, "/post/new?error=bad_request", http.StatusSeeOther)
+			return
+		}
+
+		standing, err := userStanding(r.Context(), store, userID, role)
+		if err != nil {
+			log.Println("Error fetching user karma:", err)
+			http.Redirect(w, r, "/post/new?error=server", http.StatusSeeOther)
+			return
+		}
+		now := time.Now()
+		if imageURL != "" || upload != nil {
+			if code := imageError(standing, now); code != "" {
+				http.Redirect(w, r, "/post/new?error="+code, http.StatusSeeOther)
+				return
+			}
+		}
+		if !privileges.AllowsLinks(standing, now, title, content) {
+			http.Redirect(w, r, "/post/new?error=links", http.StatusSeeOther)
+			return
+		}
+		if limit := privileges.HourlyLimit(standing, now, permissions.ActivityPost); limit > 0 {
+			recent, err := store.Users.CountRecentPosts(r.Context(), userID, now.Add(-time.Hour), limit)
+			if err != nil {
+				log.Println("Error counting recent posts:", err)
+				http.Redirect(w, r, "/post/new?error=server", http.StatusSeeOther)
+				return
+			}
+			if recent >= limit {
+				http.Redirect(w, r, "/post/new?error=rate", http.StatusSeeOther)
+				return
+			}
+		}
+		// Загруженный файл заменяет адрес изображения, если указано и то, и другое.
+		if upload != nil {
+			saved, code, err := saveUploadedImage(r, upload, "posts")
+			if err != nil {
+				log.Println("Error saving uploaded image:", err)
+				http.Redirect(w, r, "/post/new?error=server", http.StatusSeeOther)
+				return
+			}
+			if code != "" {
+				http.Redirect(w, r, "/post/new?error="+code, http.StatusSeeOther)
+				return
+			}
+			imageURL = saved
+		} else if imageURL != "" {
+			checked, code, err := checkImageURL(r.Context(), store, imageURL)
+			if err != nil {
+				log.Println("Error reading image settings:", err)
+				http.Redirect(w, r, "/post/new?error=server", http.StatusSeeOther)
+				return
+			}
+			if code != "" {
+				http.Redirect(w, r, "/post/new?error="+code, http.StatusSeeOther)
+				return
+			}
+			imageURL = checked
+		}
 
 		validCategories := make([]string, 0, len(categories))
 		for _, catName := range categories {
@@ -216,155 +485,261 @@ func CreatePostHandler(db *sql.DB) http.HandlerFunc {
 			}
 		}
 		if len(validCategories) == 0 {
-			http.Redirect(w, r, "/create-post?error=Please+choose+valid+category", http.StatusSeeOther)
+			http.Redirect(w, r, "/post/new?error=category", http.StatusSeeOther)
 			return
 		}
 		if len(validCategories) > 3 {
-			http.Redirect(w, r, "/create-post?error=You+can+select+up+to+3+categories", http.StatusSeeOther)
+			http.Redirect(w, r, "/post/new?error=too_many_categories", http.StatusSeeOther)
 			return
 		}
 
-		createdAt := time.Now()
-		postID, err := database.CreatePost(db, userID, title, content, imageURL, createdAt)
+		boardSlug := r.FormValue("board")
+		if boardSlug == "" {
+			boardSlug = database.DefaultBoard
+		}
+		board, err := store.Boards.GetBoardBySlug(r.Context(), boardSlug)
+		if err == sql.ErrNoRows {
+			http.Redirect(w, r, "/post/new?error=board", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching board:", err)
+			http.Redirect(w, r, "/post/new?error=server", http.StatusSeeOther)
+			return
+		}
+
+		series, ok, err := parseSeriesChoice(r, store, userID)
+		if err != nil {
+			log.Println("Error fetching series:", err)
+			http.Redirect(w, r, "/post/new?error=server", http.StatusSeeOther)
+			return
+		}
+		if !ok {
+			http.Redirect(w, r, "/post/new?error=series", http.StatusSeeOther)
+			return
+		}
+
+		verdict, screened := screen(r.Context(), "post", userID, role, title, content)
+
+		// Доступ, правило комментирования, категории и серия записываются вместе с постом одной транзакцией:
+		// пост, закрытый автором для анонимных посетителей, не бывает открытым ни мгновения.
+		postID, err := store.Posts.PublishPost(r.Context(), database.NewPost{
+			UserID:        userID,
+			BoardID:       board.ID,
+			Title:         title,
+			Content:       content,
+			ImageURL:      imageURL,
+			CreatedAt:     time.Now(),
+			MembersOnly:   membersOnly,
+			CommentPolicy: policy,
+			Categories:    validCategories,
+			SeriesID:      series.ID,
+			NewSeries:     series.Title,
+		})
 		if err != nil {
 			log.Println("Error inserting post:", err)
-			http.Redirect(w, r, "/create-post?error=Server+error", http.StatusSeeOther)
+			http.Redirect(w, r, "/post/new?error=server", http.StatusSeeOther)
 			return
 		}
 
-		for _, catName := range validCategories {
-			catID, err := database.GetCategoryIDByName(db, catName)
-			if err != nil {
-				log.Println("Error fetching category:", err)
-				http.Redirect(w, r, "/create-post?error=Server+error", http.StatusSeeOther)
-				return
-			}
-			err = database.AddPostCategory(db, postID, catID)
-			if err != nil {
-				log.Println("Error inserting post_category:", err)
-				http.Redirect(w, r, "/create-post?error=Server+error", http.StatusSeeOther)
-				return
-			}
+		if screened && recordScreening(r.Context(), store, "post", int(postID), userID, verdict) {
+			http.Redirect(w, r, "/?message=post_held", http.StatusSeeOther)
+			return
+		}
+
+		// Пост только для авторизованных не анонсируется во внешних каналах: их читают и без входа на форум.
+		if !membersOnly {
+			notifier.PostPublished(integrations.PostEvent{
+				ID:         int(postID),
+				Title:      title,
+				Author:     username,
+				Categories: validCategories,
+			})
 		}
-		http.Redirect(w, r, "/post?post_id="+strconv.FormatInt(postID, 10), http.StatusSeeOther)
+		http.Redirect(w, r, "/post/"+strconv.FormatInt(postID, 10), http.StatusSeeOther)
 		return
 
 	}
 }
 
 // EditPostHandler редактирует существующий пост.
-// При GET отображает форму редактирования, при POST обновляет пост и категории.
+// При GET /post/{id}/edit отображает форму редактирования, при POST обновляет пост и категории.
 // Требует аутентификации и прав владельца поста.
-func EditPostHandler(db *sql.DB) http.HandlerFunc {
+func EditPostHandler(store *database.Store) http.HandlerFunc {
 	allowedCategories := map[string]bool{
 		"news": true, "gadgets": true, "life": true, "auto": true,
 		"creative": true, "science": true, "games": true, "other": true,
 	}
 	return func(w http.ResponseWriter, r *http.Request) {
-		isAuth, userID, role := IsAuthenticated(db, r)
+		postID, ok := pathID(r, "id")
+		if !ok {
+			writeError(w, r, http.StatusBadRequest)
+			return
+		}
+
+		isAuth, userID, role := IsAuthenticated(store, r)
 		if !isAuth {
-			http.Redirect(w, r, "/login?redirect=/edit-post?post_id="+r.URL.Query().Get("post_id"), http.StatusSeeOther)
+			http.Redirect(w, r, "/login?redirect=/post/"+strconv.Itoa(postID)+"/edit", http.StatusSeeOther)
 			return
 		}
 
-		username, err := database.GetUsernameByID(db, userID)
+		username, err := store.Users.GetUsernameByID(r.Context(), userID)
 		if err != nil {
 			log.Println("Error fetching username:", err)
-			writeError(w, http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
 			return
 		}
 
 		if r.Method == "GET" {
-			postIDStr := r.URL.Query().Get("post_id")
-			if postIDStr == "" {
-				writeError(w, http.StatusBadRequest)
+			post, err := store.Posts.GetPostByIDAndUserID(r.Context(), postID, userID)
+			if err == sql.ErrNoRows {
+				writeError(w, r, http.StatusForbidden)
 				return
 			}
-			postID, err := strconv.Atoi(postIDStr)
 			if err != nil {
-				writeError(w, http.StatusBadRequest)
+				log.Println("Error fetching post:", err)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
-
-			post, err := database.GetPostByIDAndUserID(db, postID, userID)
-			if err == sql.ErrNoRows {
-				writeError(w, http.StatusForbidden)
+			standing, err := userStanding(r.Context(), store, userID, role)
+			if err != nil {
+				log.Println("Error fetching user karma:", err)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
-			if err != nil {
-				log.Println("Error fetching post:", err)
-				writeError(w, http.StatusInternalServerError)
+			if !privileges.AllowsEdit(standing, time.Now(), post.CreatedAt) {
+				http.Redirect(w, r, "/post/"+strconv.Itoa(postID)+"?error=edit_window", http.StatusSeeOther)
 				return
 			}
 
-			post.Categories, err = database.GetPostCategories(db, postID)
+			post.Categories, err = store.Posts.GetPostCategories(r.Context(), postID)
 			if err != nil {
 				log.Println("Error fetching categories:", err)
-				writeError(w, http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
 
-			tmpl, err := template.ParseFiles("templates/edit_post.html")
+			series, err := store.Series.ListUserSeries(r.Context(), userID)
 			if err != nil {
-				log.Println("Error parsing edit post template:", err)
-				writeError(w, http.StatusInternalServerError)
+				log.Println("Error querying series:", err)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
+			nav, err := store.Series.GetPostSeries(r.Context(), postID, userID)
+			if err != nil && err != sql.ErrNoRows {
+				log.Println("Error fetching post series:", err)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+
 			pageData := models.PageData{
 				IsAuthenticated: isAuth,
 				UserID:          userID,
 				Username:        username,
 				Role:            role,
 				Post:            post,
-				ErrorMessage:    r.URL.Query().Get("error"),
-			}
-			if err := tmpl.Execute(w, pageData); err != nil {
-				log.Println("Error executing edit post template:", err)
-				writeError(w, http.StatusInternalServerError)
+				ErrorMessage:    postFormError(r),
+				Series:          series,
+				SeriesNav:       nav,
 			}
+			renderPage(w, r, "edit_post.html", pageData)
 			return
 		}
 
 		if r.Method == "POST" {
-			if err := r.ParseForm(); err != nil {
-				log.Println("Error parsing form:", err)
-				writeError(w, http.StatusBadRequest)
-				return
-			}
-
-			postIDStr := r.FormValue("post_id")
-			if postIDStr == "" {
-				writeError(w, http.StatusBadRequest)
+			editURL := "/post/" + strconv.Itoa(postID) + "/edit"
+			if code := parsePostForm(w, r); code == "upload_size" {
+				http.Redirect(w, r, editURL+"?error="+code, http.StatusSeeOther)
 				return
-			}
-			postID, err := strconv.Atoi(postIDStr)
-			if err != nil {
-				writeError(w, http.StatusBadRequest)
+			} else if code != "" {
+				writeError(w, r, http.StatusBadRequest)
 				return
 			}
 
-			ownerID, err := database.GetPostOwnerID(db, postID)
+			ownerID, err := store.Posts.GetPostOwnerID(r.Context(), postID)
 			if err == sql.ErrNoRows {
-				writeError(w, http.StatusNotFound)
+				writeError(w, r, http.StatusNotFound)
 				return
 			}
 			if err != nil {
 				log.Println("Error fetching post owner:", err)
-				writeError(w, http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
 			if ownerID != userID {
-				writeError(w, http.StatusForbidden)
+				writeError(w, r, http.StatusForbidden)
 				return
 			}
 
 			title := strings.TrimSpace(r.FormValue("title"))
 			content := strings.TrimSpace(r.FormValue("content"))
-			imageURL := r.FormValue("image_url")
+			imageURL := strings.TrimSpace(r.FormValue("image_url"))
+			upload := uploadedImage(r)
+			membersOnly := r.FormValue("members_only") == "1"
+			policy, validPolicy := commentPolicy(r)
 			categories := r.Form["categories"]
 
-			if title == "" || content == "" {
-				writeError(w, http.StatusBadRequest)
+			if title == "" || content == "" || !validPolicy {
+				writeError(w, r, http.StatusBadRequest)
+				return
+			}
+
+			// Изображение, оставшееся от прежней версии поста, заново не проверяется: его сохраняют,
+			// даже если карма с тех пор упала ниже порога или домен убрали из разрешённых.
+			standing, err := userStanding(r.Context(), store, userID, role)
+			if err != nil {
+				log.Println("Error fetching user karma:", err)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+			current, err := store.Posts.GetPostByIDAndUserID(r.Context(), postID, userID)
+			if err != nil {
+				log.Println("Error fetching post:", err)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+			now := time.Now()
+			if !privileges.AllowsEdit(standing, now, current.CreatedAt) {
+				http.Redirect(w, r, "/post/"+strconv.Itoa(postID)+"?error=edit_window", http.StatusSeeOther)
+				return
+			}
+			if upload != nil {
+				if code := imageError(standing, now); code != "" {
+					http.Redirect(w, r, editURL+"?error="+code, http.StatusSeeOther)
+					return
+				}
+				saved, code, err := saveUploadedImage(r, upload, "posts")
+				if err != nil {
+					log.Println("Error saving uploaded image:", err)
+					writeError(w, r, http.StatusInternalServerError)
+					return
+				}
+				if code != "" {
+					http.Redirect(w, r, editURL+"?error="+code, http.StatusSeeOther)
+					return
+				}
+				imageURL = saved
+			} else if imageURL != "" {
+				if current.ImageURL != imageURL {
+					if code := imageError(standing, now); code != "" {
+						http.Redirect(w, r, editURL+"?error="+code, http.StatusSeeOther)
+						return
+					}
+					checked, code, err := checkImageURL(r.Context(), store, imageURL)
+					if err != nil {
+						log.Println("Error reading image settings:", err)
+						writeError(w, r, http.StatusInternalServerError)
+						return
+					}
+					if code != "" {
+						http.Redirect(w, r, editURL+"?error="+code, http.StatusSeeOther)
+						return
+					}
+					imageURL = checked
+				}
+			}
+			if !privileges.AllowsLinks(standing, now, title, content) {
+				http.Redirect(w, r, editURL+"?error=links", http.StatusSeeOther)
 				return
 			}
 
@@ -376,163 +751,137 @@ func EditPostHandler(db *sql.DB) http.HandlerFunc {
 				}
 			}
 			if len(validCategories) == 0 {
-				writeError(w, http.StatusBadRequest)
+				writeError(w, r, http.StatusBadRequest)
 				return
 			}
 			if len(validCategories) > 2 {
-				writeError(w, http.StatusBadRequest)
+				writeError(w, r, http.StatusBadRequest)
+				return
+			}
+
+			series, ok, err := parseSeriesChoice(r, store, userID)
+			if err != nil {
+				log.Println("Error fetching series:", err)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				writeError(w, r, http.StatusBadRequest)
 				return
 			}
 
-			err = database.UpdatePost(db, postID, title, content, imageURL)
+			err = store.Posts.UpdatePost(r.Context(), postID, title, content, imageURL)
 			if err != nil {
 				log.Println("Error updating post:", err)
-				writeError(w, http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+			if err := store.Posts.SetPostMembersOnly(r.Context(), postID, membersOnly); err != nil {
+				log.Println("Error updating post visibility:", err)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+			if err := store.Posts.SetPostCommentPolicy(r.Context(), postID, policy); err != nil {
+				log.Println("Error updating comment policy:", err)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
 
-			err = database.DeletePostCategories(db, postID)
+			err = store.Posts.DeletePostCategories(r.Context(), postID)
 			if err != nil {
 				log.Println("Error deleting categories:", err)
-				writeError(w, http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
 
 			for _, catName := range validCategories {
-				catID, err := database.GetCategoryIDByName(db, catName)
+				catID, err := store.Posts.GetCategoryIDByName(r.Context(), catName)
 				if err == sql.ErrNoRows {
 					log.Printf("Category %s not found in allowed list.", catName)
-					writeError(w, http.StatusBadRequest)
+					writeError(w, r, http.StatusBadRequest)
 					return
 				}
 				if err != nil {
 					log.Println("Error fetching category:", err)
-					writeError(w, http.StatusInternalServerError)
+					writeError(w, r, http.StatusInternalServerError)
 					return
 				}
-				err = database.AddPostCategory(db, int64(postID), catID)
+				err = store.Posts.AddPostCategory(r.Context(), int64(postID), catID)
 				if err != nil {
 					log.Println("Error inserting post_category:", err)
-					writeError(w, http.StatusInternalServerError)
+					writeError(w, r, http.StatusInternalServerError)
 					return
 				}
 			}
 
+			if err := applySeriesChoice(r.Context(), store, userID, postID, series); err != nil {
+				log.Println("Error updating post series:", err)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+
 			http.Redirect(w, r, "/?filter=my", http.StatusSeeOther)
 			return
 		}
-
-		writeError(w, http.StatusMethodNotAllowed)
 	}
 }
 
-// DeletePostHandler удаляет пост по его ID.
-// Принимает DELETE-запрос, требует аутентификации и прав администратора или владельца.
+// DeletePostHandler удаляет пост по его ID. Пост помечается удалённым и исчезает из выдачи,
+// а окончательно удаляется фоновой задачей очистки после срока хранения.
+// Принимает DELETE-запрос на /post/{id}, требует аутентификации и прав администратора, владельца или модератора раздела поста.
 // Возвращает JSON с результатом операции.
-func DeletePostHandler(db *sql.DB) http.HandlerFunc {
+func DeletePostHandler(store *database.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != "DELETE" {
-			log.Println("Method not allowed:", r.Method)
-			w.Header().Set("Content-Type", "application/json")
-			w.Header().Set("Allow", "DELETE")
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Method not allowed.",
-			})
-			return
-		}
-
-		isAuth, userID, role := IsAuthenticated(db, r)
+		isAuth, userID, role := IsAuthenticated(store, r)
 		if !isAuth {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Not authenticated.",
+				"message": tr(r, "api.not_authenticated"),
 			})
 			return
 		}
 
-		postIDStr := r.URL.Query().Get("post_id")
-		if postIDStr == "" {
+		postID, ok := pathID(r, "id")
+		if !ok {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Post ID is required.",
-			})
-			return
-		}
-		postID, err := strconv.Atoi(postIDStr)
-		if err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Invalid Post ID.",
+				"message": tr(r, "api.invalid_post_id"),
 			})
 			return
 		}
 
-		postUserID, err := database.GetPostOwnerID(db, postID)
+		postUserID, err := store.Posts.GetPostOwnerID(r.Context(), postID)
 		if err != nil {
 			log.Println("Error fetching post:", err)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Post not found.",
+				"message": tr(r, "api.post_not_found"),
 			})
 			return
 		}
 
-		if userID != postUserID && role != "admin" {
+		if userID != postUserID && role != "admin" && !moderatesPost(r, store, userID, postID) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusForbidden)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Unauthorized.",
+				"message": tr(r, "api.unauthorized"),
 			})
 			return
 		}
 
-		if err := database.DeletePostCategories(db, postID); err != nil {
-			log.Println("Error deleting post categories:", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Server error.",
-			})
-			return
-		}
-
-		if err := database.DeletePostComments(db, postID); err != nil {
-			log.Println("Error deleting comments:", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Server error.",
-			})
-			return
-		}
-
-		if err := database.DeletePostVotes(db, postID); err != nil {
-			log.Println("Error deleting post votes:", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Server error.",
-			})
-			return
-		}
-
-		if err := database.DeletePost(db, postID); err != nil {
+		if err := store.Posts.DeletePost(r.Context(), postID); err != nil {
 			log.Println("Error deleting post:", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Server error.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
@@ -541,78 +890,86 @@ func DeletePostHandler(db *sql.DB) http.HandlerFunc {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": true,
-			"message": "Post deleted successfully.",
+			"message": tr(r, "api.post_deleted"),
 		})
 	}
 }
 
 // LikeHandler устанавливает или снимает лайк для поста.
-// Принимает POST-запрос с post_id, требует аутентификации.
+// Принимает POST-запрос на /post/{id}/like или /post/{id}/dislike, требует аутентификации.
 // Возвращает JSON с количеством лайков, дизлайков и текущим голосом пользователя.
-func LikeHandler(db *sql.DB) http.HandlerFunc {
+func LikeHandler(store *database.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		isAuth, userID, _ := IsAuthenticated(db, r)
+		isAuth, userID, role := IsAuthenticated(store, r)
 		if !isAuth {
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Not authenticated.",
+				"message": tr(r, "api.not_authenticated"),
 			})
 			return
 		}
 
-		postIDStr := r.URL.Query().Get("post_id")
-		if postIDStr == "" {
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Post ID is required.",
-			})
-			return
-		}
-		postID, err := strconv.Atoi(postIDStr)
-		if err != nil {
+		postID, ok := pathID(r, "id")
+		if !ok {
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Invalid Post ID.",
+				"message": tr(r, "api.invalid_post_id"),
 			})
 			return
 		}
 
-		currentVote, voteExists, err := database.GetUserPostVote(db, userID, postID)
+		currentVote, voteExists, err := store.Votes.GetUserPostVote(r.Context(), userID, postID)
 		if err != nil {
 			log.Println("Error checking vote:", err)
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Server error.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
 
+		// Поставить лайк можно в пределах лимита голосов в час (см. allowVote), снять — всегда.
+		if !voteExists || currentVote != 1 {
+			standing, err := userStanding(r.Context(), store, userID, role)
+			if err != nil {
+				log.Println("Error fetching user karma:", err)
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": false,
+					"message": tr(r, "api.server_error"),
+				})
+				return
+			}
+			if !allowVote(w, r, store, userID, standing) {
+				return
+			}
+		}
+
 		if voteExists && currentVote == 1 {
-			err = database.RemovePostVote(db, userID, postID)
+			err = store.Votes.RemovePostVote(r.Context(), userID, postID)
 		} else {
-			err = database.SetPostLike(db, userID, postID)
+			err = store.Votes.SetPostLike(r.Context(), userID, postID)
 		}
 		if err != nil {
 			log.Println("Error updating vote:", err)
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Server error.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
 
-		likes, dislikes, userVote, userVoteExists, err := database.GetPostVoteStats(db, userID, postID)
+		likes, dislikes, userVote, userVoteExists, err := store.Votes.GetPostVoteStats(r.Context(), userID, postID)
 		if err != nil {
 			log.Println("Error fetching votes:", err)
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Server error.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
@@ -632,72 +989,89 @@ func LikeHandler(db *sql.DB) http.HandlerFunc {
 }
 
 // DislikeHandler устанавливает или снимает дизлайк для поста.
-// Принимает POST-запрос с post_id, требует аутентификации.
+// Принимает POST-запрос на /post/{id}/like или /post/{id}/dislike, требует аутентификации.
 // Возвращает JSON с количеством лайков, дизлайков и текущим голосом пользователя.
-func DislikeHandler(db *sql.DB) http.HandlerFunc {
+func DislikeHandler(store *database.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		isAuth, userID, _ := IsAuthenticated(db, r)
+		isAuth, userID, role := IsAuthenticated(store, r)
 		if !isAuth {
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Not authenticated.",
+				"message": tr(r, "api.not_authenticated"),
 			})
 			return
 		}
 
-		postIDStr := r.URL.Query().Get("post_id")
-		if postIDStr == "" {
+		postID, ok := pathID(r, "id")
+		if !ok {
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Post ID is required.",
-			})
-			return
-		}
-		postID, err := strconv.Atoi(postIDStr)
-		if err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": "Invalid Post ID.",
+				"message": tr(r, "api.invalid_post_id"),
 			})
 			return
 		}
 
-		currentVote, voteExists, err := database.GetUserPostVote(db, userID, postID)
+		currentVote, voteExists, err := store.Votes.GetUserPostVote(r.Context(), userID, postID)
 		if err != nil {
 			log.Println("Error checking vote:", err)
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Server error.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
 
+		// Снять свой дизлайк можно при любой карме; поставить — только с кармы privileges.DownvoteKarma
+		// и в пределах лимита голосов в час (см. allowVote).
+		if !voteExists || currentVote != -1 {
+			standing, err := userStanding(r.Context(), store, userID, role)
+			if err != nil {
+				log.Println("Error fetching user karma:", err)
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": false,
+					"message": tr(r, "api.server_error"),
+				})
+				return
+			}
+			if !privileges.Allows(standing, permissions.Downvote) {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": false,
+					"message": tr(r, "api.downvote_karma", privileges.Required(permissions.Downvote), standing.Karma),
+				})
+				return
+			}
+			if !allowVote(w, r, store, userID, standing) {
+				return
+			}
+		}
+
 		if voteExists && currentVote == -1 {
-			err = database.RemovePostVote(db, userID, postID)
+			err = store.Votes.RemovePostVote(r.Context(), userID, postID)
 		} else {
-			err = database.SetPostDislike(db, userID, postID)
+			err = store.Votes.SetPostDislike(r.Context(), userID, postID)
 		}
 		if err != nil {
 			log.Println("Error updating vote:", err)
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Server error.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
 
-		likes, dislikes, userVote, userVoteExists, err := database.GetPostVoteStats(db, userID, postID)
+		likes, dislikes, userVote, userVoteExists, err := store.Votes.GetPostVoteStats(r.Context(), userID, postID)
 		if err != nil {
 			log.Println("Error fetching votes:", err)
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": false,
-				"message": "Server error.",
+				"message": tr(r, "api.server_error"),
 			})
 			return
 		}
@@ -716,76 +1090,186 @@ func DislikeHandler(db *sql.DB) http.HandlerFunc {
 	}
 }
 
+// openGraphDescriptionLen — наибольшая длина описания поста в превью ссылки, в символах.
+const openGraphDescriptionLen = 200
+
+// postOpenGraph описывает пост для превью ссылки на него: заголовок, начало текста, изображение, автор и время
+// публикации. Адреса полные (от server.base_url), как того требуют соцсети. Изображение берётся по исходному
+// адресу, а не через прокси /img: сервисы превью загружают его сами.
+func postOpenGraph(post models.PostData) models.OpenGraph {
+	og := models.OpenGraph{
+		Title:       post.Title,
+		Description: excerpt(post.Content, openGraphDescriptionLen),
+		URL:         siteURL + "/post/" + strconv.Itoa(post.ID),
+		Author:      authorName(post.DisplayName, post.Username),
+		Published:   post.CreatedAt.Format(time.RFC3339),
+	}
+	switch {
+	case strings.HasPrefix(post.ImageURL, "http://"), strings.HasPrefix(post.ImageURL, "https://"):
+		og.Image = post.ImageURL
+	case strings.HasPrefix(post.ImageURL, "/"):
+		og.Image = siteURL + post.ImageURL
+	}
+	return og
+}
+
 // PostHandler отображает страницу отдельного поста с комментариями.
-// Принимает GET-запрос с post_id, возвращает HTML-страницу.
-// Возвращает ошибку, если пост не найден.
-func PostHandler(db *sql.DB) http.HandlerFunc {
+// Принимает GET-запрос на /post/{id}, возвращает HTML-страницу.
+// Возвращает ошибку, если пост не найден. Отвечает 304, если пост, комментарии и голоса не менялись.
+func PostHandler(store *database.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != "GET" {
-			log.Println("Method not allowed:", r.Method)
-			w.Header().Set("Content-Type", "text/plain")
-			w.Header().Set("Allow", "GET")
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			fmt.Fprintln(w, "Method not allowed.")
+		postID, ok := pathID(r, "id")
+		if !ok {
+			writeError(w, r, http.StatusBadRequest)
 			return
 		}
 
-		postIDStr := r.URL.Query().Get("post_id")
-		if postIDStr == "" {
-			writeError(w, http.StatusBadRequest)
-			return
-		}
-		postID, err := strconv.Atoi(postIDStr)
-		if err != nil {
-			writeError(w, http.StatusBadRequest)
-			return
-		}
-
-		isAuth, userID, role := IsAuthenticated(db, r)
+		isAuth, userID, role := IsAuthenticated(store, r)
 		var username string
+		var err error
 		if isAuth {
-			username, err = database.GetUsernameByID(db, userID)
+			username, err = store.Users.GetUsernameByID(r.Context(), userID)
 			if err != nil {
 				log.Println("Error fetching username:", err)
-				writeError(w, http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+		}
+
+		// Подборки закладок пользователя выводятся в форме сохранения поста и не входят в версию данных,
+		// поэтому учитываются в ETag отдельно.
+		var collections []models.Collection
+		if isAuth {
+			collections, err = store.Collections.ListUserCollections(r.Context(), userID, postID)
+			if err != nil {
+				log.Println("Error fetching collections:", err)
+				writeError(w, r, http.StatusInternalServerError)
 				return
 			}
 		}
 
-		post, err := database.GetPostByID(db, postID, userID)
+		// Пост только для авторизованных проверяется до ответа 304 и до кэша готовых страниц: анонимный
+		// посетитель не должен получить страницу такого поста ни из какого кэша.
+		post, err := store.Posts.GetPostByID(r.Context(), postID, userID)
 		if err == sql.ErrNoRows {
-			writeError(w, http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest)
 			return
 		}
-		post.CreatedAtStr = post.CreatedAt.Format(time.DateOnly)
-		likes, dislikes, userVote, _, _ := database.GetPostVoteStats(db, userID, postID)
-		post.Likes = likes
-		post.Dislikes = dislikes
-		post.UserVote = int(userVote)
-
 		if err != nil {
 			log.Println("Error fetching post:", err)
-			writeError(w, http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		if post.MembersOnly && !isAuth {
+			http.Redirect(w, r, "/login?redirect=/post/"+strconv.Itoa(postID), http.StatusSeeOther)
 			return
 		}
 
-		comments, err := database.GetCommentsByPostIDWithUserVote(db, userID, postID)
+		version, err := store.Posts.GetPostVersion(r.Context(), postID)
+		if err == sql.ErrNoRows {
+			writeError(w, r, http.StatusBadRequest)
+			return
+		}
+		if isAuth && err == nil {
+			// Просмотр отмечается и при ответе 304: пост открыт, даже если страница взята из кэша браузера.
+			if err := store.Users.MarkPostViewed(r.Context(), userID, postID, time.Now()); err != nil {
+				log.Println("Error marking post viewed:", err)
+			}
+		}
+		// Автор поста видит число переходов по короткой ссылке; оно не входит в версию данных,
+		// поэтому для авторизованных ссылка загружается до проверки ETag и учитывается в нём.
+		var shortLink models.ShortLink
+		if isAuth && err == nil {
+			shortLink = postShortLink(store, r, postID)
+		}
+		// Число переходов «Поделиться» видно всем и тоже не входит в версию данных.
+		var shares int
+		if err == nil {
+			var shareErr error
+			if shares, shareErr = database.CountPostShares(r.Context(), store.DB, postID); shareErr != nil {
+				log.Println("Error counting post shares:", shareErr)
+			}
+		}
+		// Подписка на автора решает, можно ли комментировать пост с правилом followers, и тоже не входит в версию данных.
+		var commentBlock string
+		if isAuth && err == nil {
+			allowed, policy, err := database.CanComment(r.Context(), store.DB, postID, userID)
+			if err != nil && err != sql.ErrNoRows {
+				log.Println("Error checking comment policy:", err)
+			}
+			if err == nil && !allowed {
+				commentBlock = commentBlockKey(policy)
+			}
+			if unverified, err := emailUnverified(r.Context(), store, userID); err != nil {
+				log.Println("Error checking email verification:", err)
+			} else if unverified && commentBlock == "" {
+				commentBlock = "comment.error.unverified"
+			}
+		}
+		var etag string
+		if err != nil {
+			log.Println("Error querying post version:", err)
+		} else {
+			etag = versionETag(true, version, "post", r.URL.RawQuery, strconv.Itoa(userID), role, username, i18n.FromContext(r.Context()),
+				collectionsTag(collections), shortLink.Code, strconv.Itoa(shortLink.Clicks), strconv.Itoa(shares), commentBlock)
+			setPrivateCaching(w)
+			if notModified(w, r, etag, version.LastModified) {
+				return
+			}
+		}
+		cacheKey := fragmentKey(store, isAuth, "post", etag)
+		if writeCachedFragment(w, store, cacheKey) {
+			return
+		}
+
+		post.CreatedAtStr = post.CreatedAt.Format(time.DateOnly)
+
+		var isModerator bool
+		if isAuth && post.BoardID != 0 {
+			isModerator, err = store.Boards.IsBoardModerator(r.Context(), userID, post.BoardID)
+			if err != nil {
+				log.Println("Error checking board moderator:", err)
+			}
+		}
+
+		// Страница поста выводит только первую страницу комментариев; остальные подгружаются
+		// из /api/comments по мере прокрутки (без JavaScript — по ссылке с параметром cursor).
+		// Параметр comment начинает страницу с этого комментария (ссылки из ленты my-comments); если комментарий
+		// удалён или относится к другому посту, выводится первая страница.
+		cursor := r.URL.Query().Get("cursor")
+		if commentID, err := strconv.Atoi(r.URL.Query().Get("comment")); err == nil && cursor == "" {
+			cursor, err = store.Comments.GetCommentCursor(r.Context(), postID, commentID)
+			if err != nil && err != sql.ErrNoRows {
+				log.Println("Error querying comment cursor:", err)
+			}
+		}
+		comments, nextCursor, err := store.Comments.GetCommentsPage(r.Context(), userID, postID, commentsPageSize, cursor)
+		if errors.Is(err, database.ErrInvalidCursor) {
+			w.WriteHeader(http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			log.Println("Error querying comments:", err)
-			writeError(w, http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
 			return
 		}
 		post.Comments = comments
-		
+
 		for i := range post.Comments {
 			c := &comments[i]
 			c.CreatedAtStr = c.CreatedAt.Format(time.DateOnly)
 		}
 
-		tmpl, err := template.ParseFiles("templates/post.html")
-		if err != nil {
-			log.Println("Error parsing post template:", err)
-			writeError(w, http.StatusInternalServerError)
+		if shortLink.Code == "" {
+			shortLink = postShortLink(store, r, postID)
+		}
+		summary, canSummarize := threadSummary(store, r, postID, isAuth)
+
+		nav, err := store.Series.GetPostSeries(r.Context(), postID, userID)
+		if err != nil && err != sql.ErrNoRows {
+			log.Println("Error fetching post series:", err)
+			writeError(w, r, http.StatusInternalServerError)
 			return
 		}
 
@@ -795,13 +1279,24 @@ func PostHandler(db *sql.DB) http.HandlerFunc {
 			Username:        username,
 			Role:            role,
 			Post:            post,
-			ErrorMessage:    r.URL.Query().Get("error"),
+			ErrorMessage:    flash(r, "error", "post.error."),
+			Message:         flash(r, "message", "post.message."),
+			IsModerator:     isModerator,
+			SeriesNav:       nav,
+			Collections:     collections,
+			ShortLink:       shortLink,
+			Shares:          shares,
+			ThreadSummary:   summary,
+			CanSummarize:    canSummarize,
+			OpenGraph:       postOpenGraph(post),
 		}
-
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		if err := tmpl.Execute(w, data); err != nil {
-			log.Println("Error executing post template:", err)
-			writeError(w, http.StatusInternalServerError)
+		if commentBlock != "" {
+			data.CommentBlock = tr(r, commentBlock)
 		}
+		if nextCursor != "" {
+			data.NextPage, data.NextComments = commentLinks(postID, nextCursor)
+		}
+
+		renderCachedPage(w, r, store, cacheKey, "post.html", data)
 	}
 }
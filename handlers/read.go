@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"forum/database"
+)
+
+// MarkAllReadHandler отмечает прочитанными все посты ленты: POST /read-all с полем redirect.
+// Отметки непрочитанного пропадают со всех уже опубликованных постов, а новые посты снова считаются непрочитанными.
+// Затем возвращает на страницу redirect или Referer, как переключатель языка.
+func MarkAllReadHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAuth, userID, _ := IsAuthenticated(store, r)
+		if !isAuth {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if err := store.Users.MarkAllRead(r.Context(), userID); err != nil {
+			log.Println("Error marking posts read:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		target := r.FormValue("redirect")
+		if target == "" {
+			target = refererPath(r)
+		}
+		http.Redirect(w, r, localRedirect(target), http.StatusSeeOther)
+	}
+}
+
+// readState возвращает отпечаток прочтения пользователя для ETag ленты (см. database.GetReadState):
+// отметки непрочитанного меняются от просмотров, которые не затрагивают версию данных.
+// Для анонимного посетителя и при ошибке возвращает пустую строку.
+func readState(store *database.Store, r *http.Request, userID int) string {
+	if userID == 0 {
+		return ""
+	}
+	state, err := store.Users.GetReadState(r.Context(), userID)
+	if err != nil {
+		log.Println("Error querying read state:", err)
+		return ""
+	}
+	return state
+}
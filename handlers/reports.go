@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"forum/database"
+)
+
+// ReportPostHandler принимает жалобу на пост: POST /post/{id}/report с причиной reason (см. database.ReportReasons).
+// На пост можно пожаловаться один раз и не на свой. Вес жалобы зависит от уровня доверия пользователя; если вес
+// жалоб по причине достиг порога из настроек reports, пост скрывается до решения модератора в /moderation,
+// а пожаловавшийся попадает на главную.
+// Для удалённого поста ничего не пишет, и CustomHandler отвечает 404.
+func ReportPostHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		postID, ok := pathID(r, "id")
+		if !ok {
+			return
+		}
+		postURL := "/post/" + strconv.Itoa(postID)
+		isAuth, userID, role := IsAuthenticated(store, r)
+		if !isAuth {
+			http.Redirect(w, r, "/login?redirect="+postURL, http.StatusSeeOther)
+			return
+		}
+		reason := r.FormValue("reason")
+		if !slices.Contains(database.ReportReasons, reason) {
+			http.Redirect(w, r, postURL+"?error=report_reason", http.StatusSeeOther)
+			return
+		}
+		post, err := store.Posts.GetPostByID(r.Context(), postID, userID)
+		if err == sql.ErrNoRows {
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching post:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		if post.UserID == userID {
+			http.Redirect(w, r, postURL+"?error=report_own", http.StatusSeeOther)
+			return
+		}
+
+		standing, err := userStanding(r.Context(), store, userID, role)
+		if err != nil {
+			log.Println("Error fetching user karma:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		weight := privileges.FlagWeight(standing, time.Now())
+		hidden, err := database.ReportPost(r.Context(), store.DB, postID, userID, reason, weight, reportThresholds[reason])
+		if err == sql.ErrNoRows {
+			return
+		}
+		if err == database.ErrAlreadyReported {
+			http.Redirect(w, r, postURL+"?message=already_reported", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			log.Println("Error reporting post:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		if !hidden {
+			http.Redirect(w, r, postURL+"?message=reported", http.StatusSeeOther)
+			return
+		}
+		store.InvalidateCache()
+		log.Printf("Post %d held for review after reports (%s).", postID, reason)
+		http.Redirect(w, r, "/?message=post_reported", http.StatusSeeOther)
+	}
+}
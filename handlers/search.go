@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"forum/database"
+	"forum/models"
+)
+
+// searchFields — поля формы поиска, которые задают фильтр с тем же ключом (см. database.ParseSearchQuery).
+var searchFields = []string{"author", "after", "before", "min_score"}
+
+// SearchHandler ищет посты по строке q с фильтрами вида author:имя (см. database.ParseSearchQuery).
+// Те же фильтры можно задать полями формы author, category, after, before и min_score: они дописываются
+// к строке q и заменяют одноимённые фильтры из неё. Пустой запрос показывает только форму.
+func SearchHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAuth, userID, role := IsAuthenticated(store, r)
+		var username string
+		if isAuth {
+			var err error
+			if username, err = store.Users.GetUsernameByID(r.Context(), userID); err != nil {
+				log.Println("Error fetching username:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+		}
+
+		query := r.URL.Query()
+		filters := database.ParseSearchQuery(searchQuery(query))
+		data := models.PageData{
+			IsAuthenticated: isAuth,
+			UserID:          userID,
+			Username:        username,
+			Role:            role,
+			Search:          filters,
+		}
+		if !filters.Empty() {
+			posts, nextCursor, err := store.Posts.SearchPosts(r.Context(), userID, filters, feedPageSize, query.Get("cursor"))
+			if errors.Is(err, database.ErrInvalidCursor) {
+				w.WriteHeader(http.StatusBadRequest)
+				writeError(w, r, http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				log.Println("Error searching posts:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+			for i := range posts {
+				posts[i].CreatedAtStr = posts[i].CreatedAt.Format(time.DateOnly)
+			}
+			data.Posts = posts
+			if nextCursor != "" {
+				data.NextPage = searchLink(filters, nextCursor)
+			}
+		}
+
+		renderPage(w, r, "search.html", data)
+	}
+}
+
+// searchQuery собирает строку поиска из параметра q и полей формы с фильтрами.
+func searchQuery(query url.Values) string {
+	parts := []string{query.Get("q")}
+	for _, name := range searchFields {
+		if v := strings.TrimSpace(query.Get(name)); v != "" {
+			if strings.ContainsAny(v, " \t") {
+				v = `"` + strings.ReplaceAll(v, `"`, "") + `"`
+			}
+			parts = append(parts, name+":"+v)
+		}
+	}
+	for _, category := range query["category"] {
+		parts = append(parts, "category:"+category)
+	}
+	return strings.Join(parts, " ")
+}
+
+// searchLink возвращает адрес следующей страницы результатов поиска с теми же фильтрами.
+func searchLink(f models.SearchFilters, cursor string) string {
+	query := url.Values{"cursor": {cursor}}
+	for name, value := range map[string]string{"q": f.Text, "author": f.Author, "after": f.After, "before": f.Before, "min_score": f.MinScore} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	if len(f.Categories) > 0 {
+		query["category"] = f.Categories
+	}
+	return "/search?" + query.Encode()
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"forum/database"
+	"forum/models"
+)
+
+// seriesPageData — данные страницы серии: посты по порядку, индекс последнего из них
+// (у него нет кнопки «ниже») и признак того, что её смотрит автор.
+type seriesPageData struct {
+	models.PageData
+	Series  models.Series
+	Last    int
+	IsOwner bool
+}
+
+// SeriesHandler отображает страницу серии /series/{id}: название, автора и все части по порядку;
+// посты только для авторизованных анонимному посетителю не показываются. Автор видит кнопки, меняющие порядок
+// частей. Для несуществующей серии ничего не пишет, и CustomHandler отвечает 404.
+func SeriesHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		seriesID, ok := pathID(r, "id")
+		if !ok {
+			return
+		}
+		isAuth, userID, role := IsAuthenticated(store, r)
+		series, err := store.Series.GetSeries(r.Context(), seriesID, userID)
+		if err == sql.ErrNoRows {
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching series:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		var username string
+		if isAuth {
+			if username, err = store.Users.GetUsernameByID(r.Context(), userID); err != nil {
+				log.Println("Error fetching username:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError)
+				return
+			}
+		}
+
+		data := seriesPageData{
+			PageData: models.PageData{IsAuthenticated: isAuth, UserID: userID, Username: username, Role: role},
+			Series:   series,
+			Last:     len(series.Parts) - 1,
+			IsOwner:  isAuth && series.UserID == userID,
+		}
+		renderPage(w, r, "series.html", data)
+	}
+}
+
+// MoveSeriesPartHandler переставляет пост post_id в серии /series/{id}/move на одну позицию:
+// direction=up — ближе к началу, иначе ближе к концу. Доступно только автору серии; возвращает на страницу серии.
+func MoveSeriesPartHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		seriesID, ok := pathID(r, "id")
+		if !ok {
+			return
+		}
+		isAuth, userID, _ := IsAuthenticated(store, r)
+		if !isAuth {
+			http.Redirect(w, r, "/login?redirect=/series/"+strconv.Itoa(seriesID), http.StatusSeeOther)
+			return
+		}
+		series, err := store.Series.GetSeries(r.Context(), seriesID, userID)
+		if err == sql.ErrNoRows {
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching series:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		if series.UserID != userID {
+			w.WriteHeader(http.StatusForbidden)
+			writeError(w, r, http.StatusForbidden)
+			return
+		}
+		postID, err := strconv.Atoi(r.FormValue("post_id"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest)
+			return
+		}
+		err = store.Series.MoveSeriesPart(r.Context(), seriesID, postID, r.FormValue("direction") == "up")
+		if err == sql.ErrNoRows {
+			w.WriteHeader(http.StatusBadRequest)
+			writeError(w, r, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Println("Error moving series part:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/series/"+strconv.Itoa(seriesID), http.StatusSeeOther)
+	}
+}
+
+// seriesChoice — выбор серии в форме поста: существующая серия автора (ID), новая серия (Title) или ни одной.
+type seriesChoice struct {
+	ID    int
+	Title string
+}
+
+// parseSeriesChoice читает поля series и new_series формы поста. Название новой серии важнее выбранной.
+// ok равно false, если выбрана чужая или несуществующая серия либо название не подходит (см. database.ValidSeriesTitle).
+func parseSeriesChoice(r *http.Request, store *database.Store, userID int) (choice seriesChoice, ok bool, err error) {
+	if title := strings.TrimSpace(r.FormValue("new_series")); title != "" {
+		return seriesChoice{Title: title}, database.ValidSeriesTitle(title), nil
+	}
+	value := r.FormValue("series")
+	if value == "" {
+		return seriesChoice{}, true, nil
+	}
+	id, convErr := strconv.Atoi(value)
+	if convErr != nil {
+		return seriesChoice{}, false, nil
+	}
+	series, err := store.Series.GetSeries(r.Context(), id, userID)
+	if err == sql.ErrNoRows {
+		return seriesChoice{}, false, nil
+	}
+	if err != nil {
+		return seriesChoice{}, false, err
+	}
+	return seriesChoice{ID: id}, series.UserID == userID, nil
+}
+
+// applySeriesChoice переносит пост в выбранную серию, при необходимости создавая её; пустой выбор убирает пост из серии.
+func applySeriesChoice(ctx context.Context, store *database.Store, userID, postID int, choice seriesChoice) error {
+	if choice.Title != "" {
+		id, err := store.Series.CreateSeries(ctx, userID, choice.Title)
+		if err != nil {
+			return err
+		}
+		choice.ID = int(id)
+	}
+	return store.Series.SetPostSeries(ctx, postID, choice.ID)
+}
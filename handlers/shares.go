@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"forum/database"
+	"forum/models"
+)
+
+const (
+	shareReportDays    = 30  // период отчёта по умолчанию, в днях
+	shareReportMaxDays = 365 // наибольший период отчёта
+	shareReportSize    = 20  // самых распространяемых постов в отчёте
+)
+
+// shareChannels — способы поделиться постом в порядке кнопок на его странице; подписи берутся из каталога
+// (share.channel.<способ>).
+var shareChannels = []string{"telegram", "whatsapp", "vk", "x", "facebook", "email"}
+
+// shareTargets возвращают адрес, по которому соцсеть или почтовая программа предлагает поделиться ссылкой link
+// с заголовком title.
+var shareTargets = map[string]func(link, title string) string{
+	"telegram": func(link, title string) string {
+		return "https://t.me/share/url?" + url.Values{"url": {link}, "text": {title}}.Encode()
+	},
+	"whatsapp": func(link, title string) string {
+		return "https://wa.me/?" + url.Values{"text": {title + " " + link}}.Encode()
+	},
+	"vk": func(link, title string) string {
+		return "https://vk.com/share.php?" + url.Values{"url": {link}, "title": {title}}.Encode()
+	},
+	"x": func(link, title string) string {
+		return "https://twitter.com/intent/tweet?" + url.Values{"url": {link}, "text": {title}}.Encode()
+	},
+	"facebook": func(link, title string) string {
+		return "https://www.facebook.com/sharer/sharer.php?" + url.Values{"u": {link}}.Encode()
+	},
+	"email": func(link, title string) string {
+		return "mailto:?subject=" + url.PathEscape(title) + "&body=" + url.PathEscape(link)
+	},
+}
+
+// SharePostHandler засчитывает нажатие кнопки «Поделиться» (/post/{id}/share/{channel}) и переводит на страницу
+// соцсети или в почтовую программу с адресом и заголовком поста. Ответ 302 и no-store, чтобы каждое нажатие
+// дошло до счётчика. Для неизвестного способа, удалённого поста или поста только для авторизованных при анонимном
+// запросе ничего не пишет, и CustomHandler отвечает 404.
+func SharePostHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		postID, ok := pathID(r, "id")
+		target, known := shareTargets[r.PathValue("channel")]
+		if !ok || !known {
+			return
+		}
+		isAuth, userID, _ := IsAuthenticated(store, r)
+		post, err := store.Posts.GetPostByID(r.Context(), postID, userID)
+		if err == sql.ErrNoRows || err == nil && post.MembersOnly && !isAuth {
+			return
+		}
+		if err == nil {
+			err = database.RecordPostShare(r.Context(), store.DB, postID, r.PathValue("channel"), time.Now())
+		}
+		if err == sql.ErrNoRows {
+			return
+		}
+		if err != nil {
+			log.Println("Error recording post share:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, target(absoluteURL(r, "/post/"+strconv.Itoa(postID)), post.Title), http.StatusFound)
+	}
+}
+
+// shareReportData — данные страницы отчёта о том, как делятся постами.
+type shareReportData struct {
+	models.PageData
+	Days   int
+	Report models.ShareReport
+}
+
+// ShareReportHandler показывает администратору, сколько раз делились постами (/admin/shares): всего, по способам
+// (кнопки соцсетей и короткие ссылки), по дням и самые распространяемые посты. Параметр days (1–365,
+// по умолчанию 30) задаёт период.
+func ShareReportHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := requireAdmin(w, r, store)
+		if !ok {
+			return
+		}
+
+		data := shareReportData{PageData: models.PageData{Username: username}, Days: shareReportDays}
+		if n, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && n >= 1 && n <= shareReportMaxDays {
+			data.Days = n
+		}
+		report, err := database.GetShareReport(r.Context(), store.DB, time.Now().AddDate(0, 0, 1-data.Days), shareReportSize)
+		if err != nil {
+			log.Println("Error building share report:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		data.Report = report
+
+		w.Header().Set("Cache-Control", "no-store")
+		renderPage(w, r, "admin_shares.html", data)
+	}
+}
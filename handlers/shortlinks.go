@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"forum/database"
+	"forum/models"
+)
+
+// ShortLinkHandler переводит по короткой ссылке /s/{code} на страницу поста и засчитывает переход — и в счётчике ссылки,
+// и в суммах за день, как нажатие кнопки «Поделиться» (см. database.ShareChannelShortLink).
+// Ссылка постоянная, но ответ 302, а не 301: браузеры запоминают 301 и следующие переходы не дошли бы до счётчика.
+// Если ссылки нет или пост удалён, ничего не пишет, и CustomHandler отвечает 404.
+func ShortLinkHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		postID, err := database.ResolveShortLink(r.Context(), store.DB, r.PathValue("code"))
+		if err == sql.ErrNoRows {
+			return
+		}
+		if err != nil {
+			log.Println("Error resolving short link:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		// Переход уже засчитан в счётчике ссылки, поэтому ошибка суммы за день только записывается в журнал.
+		if err := database.RecordPostShare(r.Context(), store.DB, postID, database.ShareChannelShortLink, time.Now()); err != nil {
+			log.Println("Error recording short link share:", err)
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, "/post/"+strconv.Itoa(postID), http.StatusFound)
+	}
+}
+
+// postShortLink возвращает короткую ссылку поста для его страницы, создавая её при первом показе.
+// Страница выводится и без ссылки, поэтому ошибка только записывается в журнал.
+func postShortLink(store *database.Store, r *http.Request, postID int) models.ShortLink {
+	link, err := database.EnsureShortLink(r.Context(), store.DB, postID)
+	if err != nil && err != sql.ErrNoRows {
+		log.Println("Error creating short link:", err)
+	}
+	return link
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"forum/database"
+	"forum/i18n"
+	"forum/models"
+)
+
+// Ограничения полей оформления сайта в символах.
+const (
+	maxSiteTitle       = 80
+	maxSiteDescription = 160
+)
+
+// siteContextKey — ключ контекста запроса, под которым SiteMiddleware передаёт настройки сайта.
+type siteContextKey struct{}
+
+// SiteMiddleware передаёт обработчикам настройки сайта, из которых Render берёт оформление страниц
+// (см. database.GetSite). Оформление читается, только если запрос отвечает страницей.
+func SiteMiddleware(store *database.Store, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), siteContextKey{}, store.Settings)))
+	})
+}
+
+// pageSite возвращает оформление сайта для страницы запроса r: заданное администратором, а пустые поля —
+// по умолчанию на языке страницы. Если настройки прочитать не удалось, страница выводится с оформлением
+// по умолчанию.
+func pageSite(r *http.Request) models.Site {
+	var site models.Site
+	if settings, ok := r.Context().Value(siteContextKey{}).(database.SettingsRepo); ok {
+		var err error
+		if site, err = settings.GetSite(r.Context()); err != nil {
+			log.Println("Error reading site settings:", err)
+			site = models.Site{}
+		}
+	}
+	lang := i18n.FromContext(r.Context())
+	if site.Title == "" {
+		site.Title = i18n.T(lang, "site.title")
+		site.Footer = i18n.T(lang, "site.footer")
+	} else {
+		site.Footer = i18n.T(lang, "site.footer_custom", site.Title)
+	}
+	if site.Description == "" {
+		site.Description = i18n.T(lang, "site.subtitle")
+	}
+	if site.Logo == "" {
+		site.Logo = staticFiles.Path("images/logo.png")
+	}
+	if site.FooterLinks == nil {
+		site.FooterLinks = []models.FooterLink{
+			{Label: i18n.T(lang, "pages.rules"), URL: "/pages/rules"},
+			{Label: i18n.T(lang, "pages.about"), URL: "/pages/about"},
+			{Label: i18n.T(lang, "pages.faq"), URL: "/pages/faq"},
+			{Label: i18n.T(lang, "leaderboard.title"), URL: "/leaderboard"},
+			{Label: i18n.T(lang, "digest.title"), URL: "/digest"},
+		}
+	}
+	return site
+}
+
+// siteSetter — данные страницы со встроенным models.PageData (см. models.PageData.SetSite).
+type siteSetter interface {
+	SetSite(models.Site)
+}
+
+// withSite возвращает данные страницы data с заполненным полем Site. Обработчики передают данные по значению,
+// поэтому заполняется копия; данные без встроенного PageData возвращаются как есть, а вместо nil
+// подставляется PageData только с оформлением.
+func withSite(data interface{}, site models.Site) interface{} {
+	if data == nil {
+		return models.PageData{Site: site}
+	}
+	if setter, ok := data.(siteSetter); ok {
+		setter.SetSite(site)
+		return data
+	}
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Struct {
+		return data
+	}
+	copied := reflect.New(v.Type())
+	copied.Elem().Set(v)
+	setter, ok := copied.Interface().(siteSetter)
+	if !ok {
+		return data
+	}
+	setter.SetSite(site)
+	return copied.Interface()
+}
+
+// AdminSiteHandler сохраняет оформление сайта из панели администратора: название title, описание description,
+// ссылки подвала footer_links (по одной в строке, «Подпись | адрес») и логотип — файл image_file, если включена
+// загрузка файлов (см. UseStorage). reset_logo=1 возвращает логотип по умолчанию. Пустые поля — оформление по умолчанию.
+func AdminSiteHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireAdmin(w, r, store); !ok {
+			return
+		}
+		if code := parsePostForm(w, r); code != "" {
+			http.Redirect(w, r, "/admin?error="+code, http.StatusSeeOther)
+			return
+		}
+		title := strings.TrimSpace(r.FormValue("title"))
+		description := strings.TrimSpace(r.FormValue("description"))
+		if utf8.RuneCountInString(title) > maxSiteTitle || utf8.RuneCountInString(description) > maxSiteDescription {
+			http.Redirect(w, r, "/admin?error=site_text", http.StatusSeeOther)
+			return
+		}
+		links, ok := database.ParseFooterLinks(r.FormValue("footer_links"))
+		if !ok {
+			http.Redirect(w, r, "/admin?error=footer_links", http.StatusSeeOther)
+			return
+		}
+
+		settings := map[string]string{
+			database.SettingSiteTitle:       title,
+			database.SettingSiteDescription: description,
+			database.SettingSiteFooterLinks: database.FormatFooterLinks(links),
+		}
+		if r.FormValue("reset_logo") == "1" {
+			settings[database.SettingSiteLogo] = ""
+		} else if upload := uploadedImage(r); upload != nil {
+			logo, code, err := saveUploadedImage(r, upload, "site")
+			if err != nil {
+				log.Println("Error saving logo:", err)
+				http.Redirect(w, r, "/admin?error=server", http.StatusSeeOther)
+				return
+			}
+			if code != "" {
+				http.Redirect(w, r, "/admin?error="+code, http.StatusSeeOther)
+				return
+			}
+			settings[database.SettingSiteLogo] = logo
+		}
+		for name, value := range settings {
+			if err := store.Settings.SetSetting(r.Context(), name, value); err != nil {
+				log.Println("Error saving settings:", err)
+				http.Redirect(w, r, "/admin?error=server", http.StatusSeeOther)
+				return
+			}
+			log.Printf("Setting %s changed to %q.", name, value)
+		}
+		http.Redirect(w, r, "/admin?message=site_saved", http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"slices"
+
+	"forum/database"
+	"forum/models"
+	"forum/summarize"
+)
+
+// threadSummary загружает сводку обсуждения для страницы поста и сообщает, можно ли её запросить:
+// сводки включены, пользователь авторизован, а обсуждение длинное и ещё не пересказано.
+// Ошибка запроса записывается в журнал, и страница показывается без сводки.
+func threadSummary(store *database.Store, r *http.Request, postID int, isAuth bool) (models.ThreadSummary, bool) {
+	if summarizer == nil {
+		return models.ThreadSummary{}, false
+	}
+	s, err := database.GetThreadSummary(r.Context(), store.DB, postID)
+	if err != nil {
+		log.Println("Error fetching thread summary:", err)
+		return models.ThreadSummary{}, false
+	}
+	return s, isAuth && s.Summary == "" && s.CommentCount >= summaryMinComments
+}
+
+// SummarizeThreadHandler пересказывает обсуждение поста языковой моделью: POST /post/{id}/summarize.
+// Отвечает JSON со сводкой, которая затем показывается в начале обсуждения всем посетителям.
+// Сводка хранится в базе и отдаётся оттуда, пока в обсуждении не появятся новые комментарии.
+// Пересказать можно только обсуждения не короче summary.min_comments и только авторизованным пользователям:
+// запрос к модели платный. Если сводки не настроены, ничего не пишет, и CustomHandler отвечает 404.
+func SummarizeThreadHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if summarizer == nil {
+			return
+		}
+		isAuth, userID, _ := IsAuthenticated(store, r)
+		if !isAuth {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "message": tr(r, "api.auth_required")})
+			return
+		}
+		postID, ok := pathID(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "message": tr(r, "api.invalid_post_id")})
+			return
+		}
+
+		post, err := store.Posts.GetPostByID(r.Context(), postID, userID)
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "message": tr(r, "api.post_not_found")})
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching post:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "message": tr(r, "api.server_error")})
+			return
+		}
+
+		summary, err := database.GetThreadSummary(r.Context(), store.DB, postID)
+		if err != nil {
+			log.Println("Error fetching thread summary:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "message": tr(r, "api.server_error")})
+			return
+		}
+		cached := summary.Summary != ""
+		if !cached {
+			comments, err := store.Comments.GetCommentsByPostID(r.Context(), userID, postID)
+			if err != nil {
+				log.Println("Error querying comments:", err)
+				writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "message": tr(r, "api.server_error")})
+				return
+			}
+			if len(comments) < summaryMinComments {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+					"success": false, "message": tr(r, "post.summary_too_short", summaryMinComments),
+				})
+				return
+			}
+
+			thread := summarize.Thread{Title: post.Title, Text: post.Content, Author: post.Username}
+			summary = models.ThreadSummary{PostID: postID, CommentCount: len(comments), Provider: summarizer.Name()}
+			// Комментарии приходят от новых к старым, а модели обсуждение передаётся в порядке публикации.
+			for _, c := range slices.Backward(comments) {
+				thread.Comments = append(thread.Comments, summarize.Comment{Author: c.Username, Text: c.Content})
+				summary.LastCommentID = max(summary.LastCommentID, c.ID)
+			}
+			summary.Summary, err = summarizer.Summarize(r.Context(), thread)
+			if err != nil {
+				log.Printf("Error summarizing post %d: %v", postID, err)
+				writeJSON(w, http.StatusBadGateway, map[string]interface{}{"success": false, "message": tr(r, "post.summary_failed")})
+				return
+			}
+			if err := database.SaveThreadSummary(r.Context(), store.DB, summary); err != nil {
+				log.Println("Error saving thread summary:", err)
+			} else {
+				// Сводка входит в версию страницы поста, а версии кэшируются вместе с выборками.
+				store.InvalidateCache()
+			}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success":  true,
+			"summary":  summary.Summary,
+			"comments": summary.CommentCount,
+			"provider": summary.Provider,
+			"cached":   cached,
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"forum/database"
+	"forum/i18n"
+	"forum/markdown"
+)
+
+// category — категория постов с подписью для страниц.
+type category struct {
+	Slug  string
+	Label string
+}
+
+// categorySlugs перечислены в порядке показа в шапке; подписи берутся из каталога (category.<slug>).
+var categorySlugs = []string{"news", "life", "auto", "creative", "gadgets", "science", "games", "other"}
+
+// templateFuncs — функции, доступные во всех шаблонах.
+var templateFuncs = template.FuncMap{
+	// asset возвращает адрес статического файла с хешем содержимого, например {{asset "styles.css"}}.
+	"asset":     func(name string) string { return staticFiles.Path(name) },
+	"languages": languages,
+	// image возвращает адрес изображения поста, при включённом прокси — через /img (см. UseImageProxy).
+	"image": imageSrc,
+	// siteURL возвращает внешний адрес форума (server.base_url) для ссылок, которые копируют за пределы сайта.
+	"siteURL": func() string { return siteURL },
+	// uploadsEnabled сообщает, можно ли загрузить изображение к посту файлом (см. UseStorage).
+	"uploadsEnabled": func() bool { return uploadStorage != nil },
+	// translationEnabled сообщает, настроен ли машинный перевод постов (см. TranslatePostHandler).
+	"translationEnabled": func() bool { return translator != nil },
+	// githubEnabled сообщает, показывать ли ссылку «Войти через GitHub» (см. UseGitHub).
+	"githubEnabled": func() bool { return githubAuth != nil },
+	// shareChannels перечисляет способы поделиться постом для кнопок его страницы (см. SharePostHandler).
+	"shareChannels": func() []string { return shareChannels },
+	// reportReasons перечисляет причины жалоб для формы на странице поста (см. ReportPostHandler).
+	"reportReasons": func() []string { return database.ReportReasons },
+	// passwordResetEnabled сообщает, показывать ли ссылку «Забыли пароль?»: для сброса нужна отправка писем.
+	"passwordResetEnabled": func() bool { return mailSender != nil },
+	// date и datetime — общие форматы дат страниц: {{date .CreatedAt}} → 2026-01-31,
+	// {{datetime .CreatedAt}} → 31.01.2026 18:05.
+	"date":     func(t time.Time) string { return t.Format(time.DateOnly) },
+	"datetime": func(t time.Time) string { return t.Format("02.01.2006 15:04") },
+	// markdown переводит текст в безопасный HTML (см. пакет markdown).
+	"markdown": markdown.Render,
+	// author подписывает автора поста или комментария: {{author .DisplayName .Username}} → «Анна (@anna)».
+	"author": authorName,
+}
+
+// authorName возвращает подпись автора «Отображаемое имя (@username)» или просто username,
+// если отображаемое имя не задано или совпадает с именем пользователя.
+func authorName(displayName, username string) string {
+	displayName = strings.TrimSpace(displayName)
+	if displayName == "" || displayName == username {
+		return username
+	}
+	return displayName + " (@" + username + ")"
+}
+
+// languageFuncs возвращает функции шаблонов, зависящие от языка страницы lang.
+func languageFuncs(lang string) template.FuncMap {
+	return template.FuncMap{
+		// t переводит сообщение каталога, например {{t "user.greeting" .Username}}.
+		"t":    func(key string, args ...interface{}) string { return i18n.T(lang, key, args...) },
+		"lang": func() string { return lang },
+		// plural подставляет число в сообщение нужной формы, например {{plural "post.comment_count" 5}} (см. i18n.Plural).
+		"plural": func(key string, n int) string { return i18n.Plural(lang, key, n) },
+		// messages передаёт скриптам страницы сообщения, которые они показывают сами.
+		"messages": func() map[string]string { return i18n.Messages(lang, "js.", "votes.", "comment.error.") },
+		"categories": func() []category {
+			list := make([]category, len(categorySlugs))
+			for i, slug := range categorySlugs {
+				list[i] = category{Slug: slug, Label: i18n.T(lang, "category."+slug)}
+			}
+			return list
+		},
+		// ago описывает давность времени: {{ago .LastActivity}} → «2 hours ago».
+		"ago": func(t time.Time) string { return timeAgo(lang, t, time.Now()) },
+		// categoryLabel возвращает подпись категории; неизвестные категории показываются как other.
+		"categoryLabel": func(slug string) string {
+			if label, ok := i18n.Lookup(lang, "category."+slug); ok {
+				return label
+			}
+			return i18n.T(lang, "category.other")
+		},
+	}
+}
+
+// timeAgo описывает на языке lang, сколько прошло от t до now: в минутах в пределах часа, в часах в пределах суток,
+// дальше в днях. Меньше минуты — «только что».
+func timeAgo(lang string, t, now time.Time) string {
+	switch d := now.Sub(t); {
+	case d < time.Minute:
+		return i18n.T(lang, "ago.now")
+	case d < time.Hour:
+		return i18n.Plural(lang, "ago.minutes", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return i18n.Plural(lang, "ago.hours", int(d/time.Hour))
+	default:
+		return i18n.Plural(lang, "ago.days", int(d/(24*time.Hour)))
+	}
+}
+
+// partialsPattern — общие фрагменты страниц ({{define}}), доступные каждому шаблону.
+const partialsPattern = "partials/*.html"
+
+// templateSet хранит разобранные шаблоны страниц отдельно для каждого языка: функция t
+// подставляет строки своего каталога. Шаблоны разбираются один раз при запуске;
+// в режиме разработки (reload) набор разбирается заново, если файлы изменились.
+type templateSet struct {
+	fsys   fs.FS
+	reload bool
+
+	mu       sync.Mutex
+	pages    map[string]map[string]*template.Template // язык → имя страницы → шаблон
+	modified time.Time                                // время последнего изменения файлов на момент разбора
+}
+
+// pages — набор шаблонов сервера; задаётся в Configure.
+var pages *templateSet
+
+// newTemplateSet разбирает все шаблоны страниц из fsys.
+func newTemplateSet(fsys fs.FS, reload bool) (*templateSet, error) {
+	s := &templateSet{fsys: fsys, reload: reload}
+	modified, err := s.lastModified()
+	if err != nil {
+		return nil, err
+	}
+	if s.pages, err = parsePages(fsys); err != nil {
+		return nil, err
+	}
+	s.modified = modified
+	return s, nil
+}
+
+// parsePages разбирает каждую страницу (*.html в корне fsys) вместе с общими фрагментами на всех языках.
+func parsePages(fsys fs.FS) (map[string]map[string]*template.Template, error) {
+	parsed := make(map[string]map[string]*template.Template, len(i18n.Languages))
+	for _, lang := range i18n.Languages {
+		pages, err := parseLanguage(fsys, lang)
+		if err != nil {
+			return nil, err
+		}
+		parsed[lang] = pages
+	}
+	return parsed, nil
+}
+
+// parseLanguage разбирает страницы с функциями шаблонов языка lang.
+func parseLanguage(fsys fs.FS, lang string) (map[string]*template.Template, error) {
+	base := template.New("").Funcs(templateFuncs).Funcs(languageFuncs(lang))
+	partials, err := fs.Glob(fsys, partialsPattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(partials) > 0 {
+		if base, err = base.ParseFS(fsys, partialsPattern); err != nil {
+			return nil, err
+		}
+	}
+	names, err := fs.Glob(fsys, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	parsed := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		t, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if t, err = t.ParseFS(fsys, name); err != nil {
+			return nil, err
+		}
+		parsed[name] = t.Lookup(path.Base(name))
+	}
+	return parsed, nil
+}
+
+// lastModified возвращает время последнего изменения файлов шаблонов.
+// Для встроенных файлов время нулевое, поэтому они не перечитываются.
+func (s *templateSet) lastModified() (time.Time, error) {
+	var latest time.Time
+	err := fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return latest, err
+}
+
+// lookup возвращает шаблон страницы name на языке lang, при необходимости разобрав набор заново.
+// Ошибка разбора в режиме разработки логируется, и используется предыдущий набор.
+func (s *templateSet) lookup(lang, name string) (*template.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reload {
+		if modified, err := s.lastModified(); err == nil && modified.After(s.modified) {
+			if parsed, err := parsePages(s.fsys); err != nil {
+				log.Println("Error reloading templates:", err)
+			} else {
+				s.pages, s.modified = parsed, modified
+				log.Println("Templates reloaded.")
+			}
+		}
+	}
+	pages, ok := s.pages[lang]
+	if !ok {
+		pages = s.pages[i18n.Default]
+	}
+	t, ok := pages[name]
+	if !ok {
+		return nil, fmt.Errorf("template %s not found", name)
+	}
+	return t, nil
+}
+
+// pageTemplate возвращает разобранный шаблон страницы name на языке запроса r (см. i18n.Middleware).
+func pageTemplate(r *http.Request, name string) (*template.Template, error) {
+	if pages == nil {
+		return nil, errors.New("templates are not configured")
+	}
+	return pages.lookup(i18n.FromContext(r.Context()), name)
+}
+
+// Render выполняет шаблон страницы name на языке запроса r с данными data; в поле Site встроенного
+// в данные models.PageData подставляется оформление сайта (см. SiteMiddleware).
+// Обработчики отвечают страницами через renderPage; Render нужен, когда результат пишется не в ответ.
+func Render(w io.Writer, r *http.Request, name string, data interface{}) error {
+	t, err := pageTemplate(r, name)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, withSite(data, pageSite(r)))
+}
+
+// renderPage отвечает страницей name на языке запроса r с данными data. Страница собирается целиком
+// до отправки, поэтому ошибка в шаблоне даёт страницу ошибки 500, а не оборванный ответ.
+func renderPage(w http.ResponseWriter, r *http.Request, name string, data interface{}) {
+	renderCachedPage(w, r, nil, "", name, data)
+}
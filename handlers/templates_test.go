@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"forum/i18n"
+	"forum/models"
+)
+
+// TestTemplateSet проверяет, что все шаблоны репозитория разбираются вместе с общими фрагментами на каждом языке.
+func TestTemplateSet(t *testing.T) {
+	set, err := newTemplateSet(os.DirFS("../templates"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, lang := range i18n.Languages {
+		for _, name := range []string{"index.html", "post.html", "register.html", "profile.html", "admin.html", "admin_votes.html", "error.html", "404.html"} {
+			if _, err := set.lookup(lang, name); err != nil {
+				t.Errorf("%s %s: %v", lang, name, err)
+			}
+		}
+	}
+	if _, err := set.lookup(i18n.Default, "partials/footer.html"); err == nil {
+		t.Error("partials must not be available as pages")
+	}
+}
+
+// TestLayout проверяет, что страницы собираются в общий каркас с переопределёнными блоками
+// и что общие функции шаблонов подставляют даты и Markdown.
+func TestLayout(t *testing.T) {
+	set, err := newTemplateSet(os.DirFS("../templates"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	render := func(name string, data interface{}) string {
+		tmpl, err := set.lookup(i18n.Default, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return b.String()
+	}
+
+	page := render("page.html", staticPageData{Page: models.Page{
+		Title:     "Rules",
+		Content:   "**Be kind**",
+		UpdatedAt: time.Date(2026, 1, 31, 18, 5, 0, 0, time.UTC),
+	}})
+	for _, want := range []string{"<title>Rules • ", `class="aurora-header compact"`, "script.js", "<strong>Be kind</strong>", "2026-01-31", `class="footer-pages"`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page.html does not contain %q", want)
+		}
+	}
+	if !strings.HasPrefix(page, "<!DOCTYPE html>") {
+		t.Errorf("page.html does not start with the layout: %.40q", page)
+	}
+
+	admin := render("admin_votes.html", voteReportData{})
+	if strings.Contains(admin, "script.js") || strings.Contains(admin, `class="categories"`) {
+		t.Error("admin_votes.html must override the scripts and the header")
+	}
+	if !strings.Contains(admin, `class="footer-pages"`) {
+		t.Error("admin_votes.html lost the footer")
+	}
+}
+
+// TestTemplateReload проверяет, что в режиме разработки изменённый шаблон разбирается заново.
+func TestTemplateReload(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string, mtime time.Time) {
+		path := filepath.Join(dir, "page.html")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	render := func(set *templateSet, lang string) string {
+		tmpl, err := set.lookup(lang, "page.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, nil); err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+
+	now := time.Now()
+	write(`{{categoryLabel "news"}}`, now.Add(-time.Hour))
+	set, err := newTemplateSet(os.DirFS(dir), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := render(set, "en"); got != "Polar News" {
+		t.Fatalf("got %q", got)
+	}
+	if got := render(set, "ru"); got != "Полярные новости" {
+		t.Fatalf("ru: got %q", got)
+	}
+	write(`{{categoryLabel "games"}}`, now)
+	if got := render(set, "en"); got != "Party Games" {
+		t.Fatalf("after change got %q, want the new template", got)
+	}
+	write(`{{broken`, now.Add(time.Hour))
+	if got := render(set, "en"); got != "Party Games" {
+		t.Fatalf("after a broken change got %q, want the previous template", got)
+	}
+}
+
+// TestTimeAgo проверяет описание давности в минутах, часах и днях с формами слов обоих языков.
+func TestTimeAgo(t *testing.T) {
+	now := time.Date(2026, 1, 31, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		lang string
+		ago  time.Duration
+		want string
+	}{
+		{"en", 20 * time.Second, "just now"},
+		{"en", time.Minute, "1 minute ago"},
+		{"en", 2*time.Hour + 59*time.Minute, "2 hours ago"},
+		{"en", 50 * time.Hour, "2 days ago"},
+		{"ru", 5 * time.Minute, "5 минут назад"},
+		{"ru", 22 * time.Hour, "22 часа назад"},
+		{"ru", 21 * 24 * time.Hour, "21 день назад"},
+	}
+	for _, tt := range tests {
+		if got := timeAgo(tt.lang, now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("timeAgo(%s, %v) = %q, want %q", tt.lang, tt.ago, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"forum/database"
+	"forum/i18n"
+	"forum/models"
+	"forum/translate"
+)
+
+// TranslatePostHandler переводит пост машинным переводом: POST /post/{id}/translate с полем lang
+// (по умолчанию язык страницы). Отвечает JSON с переведёнными заголовком и текстом.
+// Перевод сохраняется в базе и отдаётся оттуда, пока автор не изменит пост, так что провайдер
+// получает каждый пост на каждый язык один раз. Переводить могут только авторизованные пользователи:
+// запрос к провайдеру платный. Если перевод не настроен, ничего не пишет, и CustomHandler отвечает 404.
+func TranslatePostHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if translator == nil {
+			return
+		}
+		isAuth, userID, _ := IsAuthenticated(store, r)
+		if !isAuth {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "message": tr(r, "api.auth_required")})
+			return
+		}
+		postID, ok := pathID(r, "id")
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "message": tr(r, "api.invalid_post_id")})
+			return
+		}
+		lang := r.FormValue("lang")
+		if lang == "" {
+			lang = i18n.FromContext(r.Context())
+		}
+		if !i18n.Supported(lang) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "message": tr(r, "api.bad_request")})
+			return
+		}
+
+		post, err := store.Posts.GetPostByID(r.Context(), postID, userID)
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "message": tr(r, "api.post_not_found")})
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching post:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "message": tr(r, "api.server_error")})
+			return
+		}
+
+		hash := translate.SourceHash(post.Title, post.Content)
+		translation, err := database.GetPostTranslation(r.Context(), store.DB, postID, lang, hash)
+		cached := err == nil
+		if err == sql.ErrNoRows {
+			texts, err := translator.Translate(r.Context(), []string{post.Title, post.Content}, lang)
+			if err != nil {
+				log.Printf("Error translating post %d to %s: %v", postID, lang, err)
+				writeJSON(w, http.StatusBadGateway, map[string]interface{}{"success": false, "message": tr(r, "post.translate_failed")})
+				return
+			}
+			translation = models.PostTranslation{
+				PostID: postID, Lang: lang, SourceHash: hash, Title: texts[0], Content: texts[1], Provider: translator.Name(),
+			}
+			if err := database.SavePostTranslation(r.Context(), store.DB, translation); err != nil {
+				log.Println("Error saving post translation:", err)
+			}
+		} else if err != nil {
+			log.Println("Error fetching post translation:", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "message": tr(r, "api.server_error")})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success":  true,
+			"lang":     lang,
+			"title":    translation.Title,
+			"content":  translation.Content,
+			"provider": translation.Provider,
+			"cached":   cached,
+		})
+	}
+}
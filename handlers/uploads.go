@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"forum/storage"
+)
+
+// uploadImageTypes — типы загружаемых изображений (по содержимому файла) и расширения их ключей.
+var uploadImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// parsePostForm разбирает форму поста: обычную или multipart с файлом изображения image_file.
+// Тело запроса ограничивается размером загрузки с запасом на остальные поля. Возвращает код ошибки
+// формы поста: upload_size, если тело слишком большое, bad_request — если форму не удалось разобрать.
+func parsePostForm(w http.ResponseWriter, r *http.Request) string {
+	if uploadStorage != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize+1<<20)
+	}
+	err := r.ParseMultipartForm(1 << 20)
+	if errors.Is(err, http.ErrNotMultipart) {
+		err = r.ParseForm()
+	}
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return "upload_size"
+	case err != nil:
+		log.Println("Error parsing form:", err)
+		return "bad_request"
+	}
+	return ""
+}
+
+// uploadedImage возвращает файл изображения из формы поста или nil, если файл не выбран либо загрузка выключена.
+func uploadedImage(r *http.Request) *multipart.FileHeader {
+	if uploadStorage == nil || r.MultipartForm == nil {
+		return nil
+	}
+	files := r.MultipartForm.File["image_file"]
+	if len(files) == 0 || files[0].Size == 0 {
+		return nil
+	}
+	return files[0]
+}
+
+// saveUploadedImage сохраняет загруженное изображение в хранилище под каталогом dir (posts — изображения постов,
+// site — логотип) и возвращает его адрес. Тип файла определяется по содержимому, а не по имени или заголовку браузера.
+// Если файл не подходит, возвращает код ошибки формы: upload_size или upload_type.
+func saveUploadedImage(r *http.Request, file *multipart.FileHeader, dir string) (string, string, error) {
+	if file.Size > maxUploadSize {
+		return "", "upload_size", nil
+	}
+	f, err := file.Open()
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", "", err
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	ext, ok := uploadImageTypes[contentType]
+	if !ok {
+		return "", "upload_type", nil
+	}
+
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", "", err
+	}
+	key := dir + "/" + time.Now().UTC().Format("2006/01") + "/" + hex.EncodeToString(name) + ext
+	if err := uploadStorage.Put(r.Context(), key, contentType, io.MultiReader(bytes.NewReader(head), f)); err != nil {
+		return "", "", err
+	}
+	return uploadStorage.URL(key), "", nil
+}
+
+// UploadHandler отдаёт загруженные изображения по адресам /uploads/{key}, читая их из хранилища.
+// Ключи случайные, а файл под ключом не меняется, поэтому браузер может кэшировать его бессрочно.
+// Если загрузка выключена или файла нет, ничего не пишет, и CustomHandler отвечает 404.
+func UploadHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if uploadStorage == nil {
+			return
+		}
+		body, contentType, err := uploadStorage.Get(r.Context(), r.PathValue("key"))
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			return
+		}
+		if err != nil {
+			log.Println("Error reading upload:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		defer body.Close()
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		io.Copy(w, body)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"forum/database"
+)
+
+// verifyEmailPath — адрес ссылки подтверждения почты из письма.
+const verifyEmailPath = "/verify-email"
+
+// sendVerificationEmail отправляет на адрес email пользователя userID письмо со ссылкой подтверждения
+// на языке запроса; в письме форум назван так же, как на страницах (см. pageSite).
+func sendVerificationEmail(r *http.Request, userID int, email string) error {
+	if mailSender == nil {
+		return errors.New("sending mail is not configured")
+	}
+	link := absoluteURL(r, verifyEmailPath+"?token="+url.QueryEscape(verifyTokens.New(userID, email, time.Now())))
+	site := pageSite(r).Title
+	body := tr(r, "verify.email.body", site, link, int(verifyTokens.MaxAge.Hours()))
+	return mailSender.Send(r.Context(), email, tr(r, "verify.email.subject", site), body)
+}
+
+// emailUnverified сообщает, что пользователь userID не подтвердил почту и поэтому не может публиковать посты
+// и комментарии. Если подтверждение выключено, ограничение снимается и с тех, кто не успел подтвердить.
+func emailUnverified(ctx context.Context, store *database.Store, userID int) (bool, error) {
+	if verifyTokens == nil {
+		return false, nil
+	}
+	_, verified, err := database.GetEmailVerification(ctx, store.DB, userID)
+	return err == nil && !verified, err
+}
+
+// VerifyEmailHandler подтверждает почту. GET открывает ссылку из письма (параметр token) и отмечает почту
+// пользователя подтверждённой, даже если он не вошёл; POST отправляет вошедшему пользователю новое письмо.
+// Если подтверждение почты выключено, ничего не пишет, и CustomHandler отвечает 404.
+func VerifyEmailHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if verifyTokens == nil {
+			return
+		}
+		if r.Method == http.MethodPost {
+			resendVerificationEmail(w, r, store)
+			return
+		}
+
+		token := r.URL.Query().Get("token")
+		userID, ok := verifyTokens.UserID(token)
+		if !ok {
+			http.Redirect(w, r, "/?message=verify_invalid", http.StatusSeeOther)
+			return
+		}
+		email, verified, err := database.GetEmailVerification(r.Context(), store.DB, userID)
+		if err == sql.ErrNoRows {
+			http.Redirect(w, r, "/?message=verify_invalid", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			log.Println("Error fetching email verification:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		// Повторное открытие ссылки после подтверждения не считается ошибкой.
+		if verified {
+			http.Redirect(w, r, "/?message=email_verified", http.StatusSeeOther)
+			return
+		}
+		if !verifyTokens.Valid(token, email, time.Now()) {
+			log.Printf("Email verification of user %d rejected: invalid or expired token.", userID)
+			http.Redirect(w, r, "/?message=verify_invalid", http.StatusSeeOther)
+			return
+		}
+		if err := database.SetEmailVerified(r.Context(), store.DB, userID, true); err != nil {
+			log.Println("Error verifying email:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		log.Printf("User %d verified their email.", userID)
+		http.Redirect(w, r, "/?message=email_verified", http.StatusSeeOther)
+	}
+}
+
+// resendVerificationEmail отправляет вошедшему пользователю новое письмо со ссылкой подтверждения.
+func resendVerificationEmail(w http.ResponseWriter, r *http.Request, store *database.Store) {
+	isAuth, userID, _ := IsAuthenticated(store, r)
+	if !isAuth {
+		http.Redirect(w, r, "/?message=login_required", http.StatusSeeOther)
+		return
+	}
+	email, verified, err := database.GetEmailVerification(r.Context(), store.DB, userID)
+	if err != nil {
+		log.Println("Error fetching email verification:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError)
+		return
+	}
+	if verified {
+		http.Redirect(w, r, "/?message=email_verified", http.StatusSeeOther)
+		return
+	}
+	if err := sendVerificationEmail(r, userID, email); err != nil {
+		log.Println("Error sending verification email:", err)
+		http.Redirect(w, r, "/?message=verify_send_failed", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/?message=verify_sent", http.StatusSeeOther)
+}
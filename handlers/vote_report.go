@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"forum/database"
+	"forum/models"
+)
+
+const (
+	voteReportDays     = 7  // период отчёта по умолчанию, в днях
+	voteReportMaxDays  = 90 // наибольший период отчёта: голоса за него разбираются в памяти
+	voteReportMinVotes = 5  // порог голосов по умолчанию
+	voteReportSize     = 50 // строк в каждом разделе отчёта
+)
+
+// voteReportNewAccountAge — возраст, до которого аккаунт считается новым в отчёте, если privileges.new_account_age
+// выключен.
+const voteReportNewAccountAge = 72 * time.Hour
+
+// voteReportData — данные страницы отчёта о подозрительных голосах.
+type voteReportData struct {
+	models.PageData
+	Days     int
+	MinVotes int
+	AgeHours int
+	Report   models.VoteReport
+}
+
+// VoteReportHandler показывает администратору отчёт о подозрительных голосах (/admin/votes): авторов,
+// за которых массово голосуют новые аккаунты, и пары пользователей, ставящих лайки друг другу.
+// Параметры days (1–90, по умолчанию 7) и min (по умолчанию 5) задают период и порог голосов.
+// Аккаунт считается новым так же, как в ограничениях privileges.new_account_age.
+func VoteReportHandler(store *database.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := requireAdmin(w, r, store)
+		if !ok {
+			return
+		}
+
+		data := voteReportData{PageData: models.PageData{Username: username}, Days: voteReportDays, MinVotes: voteReportMinVotes}
+		if n, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && n >= 1 && n <= voteReportMaxDays {
+			data.Days = n
+		}
+		if n, err := strconv.Atoi(r.URL.Query().Get("min")); err == nil && n >= 1 {
+			data.MinVotes = n
+		}
+		age := privileges.NewAccountAge
+		if age <= 0 {
+			age = voteReportNewAccountAge
+		}
+		data.AgeHours = int(age.Hours())
+
+		since := time.Now().AddDate(0, 0, -data.Days)
+		report, err := database.GetVoteReport(r.Context(), store.DB, since, age, data.MinVotes, voteReportSize)
+		if err != nil {
+			log.Println("Error building vote report:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError)
+			return
+		}
+		data.Report = report
+
+		w.Header().Set("Cache-Control", "no-store")
+		renderPage(w, r, "admin_votes.html", data)
+	}
+}
@@ -0,0 +1,122 @@
+// Package htmlpdf превращает HTML-страницу в PDF внешним конвертером: программой вроде wkhtmltopdf
+// или сервисом Gotenberg. Сам форум PDF не рисует, поэтому выгрузка в PDF работает, только если
+// конвертер настроен (см. config.Export).
+package htmlpdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// maxSize ограничивает размер готового PDF, чтобы неисправный конвертер не исчерпал память сервера.
+const maxSize = 50 << 20
+
+// Converter превращает самодостаточный HTML-документ (стили внутри, изображения по полным адресам) в PDF.
+type Converter interface {
+	Convert(ctx context.Context, html []byte) ([]byte, error)
+}
+
+// New возвращает конвертер по настройкам: command — командная строка программы, url — адрес Gotenberg.
+// Если не задано ни то, ни другое, возвращает nil: выгрузка в PDF выключена.
+func New(command, url string, timeout time.Duration) Converter {
+	switch {
+	case command != "":
+		return &Command{Args: strings.Fields(command), Timeout: timeout}
+	case url != "":
+		return &Gotenberg{URL: strings.TrimRight(url, "/"), Client: &http.Client{Timeout: timeout}}
+	}
+	return nil
+}
+
+// Command запускает программу, которая читает HTML со стандартного ввода и пишет PDF в стандартный вывод,
+// например wkhtmltopdf --quiet - -.
+type Command struct {
+	Args    []string // программа и её аргументы
+	Timeout time.Duration
+}
+
+// Convert запускает программу для html. Программа, не успевшая за Timeout, завершается.
+func (c *Command) Convert(ctx context.Context, html []byte) ([]byte, error) {
+	if len(c.Args) == 0 {
+		return nil, errors.New("htmlpdf: empty command")
+	}
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, c.Args[0], c.Args[1:]...)
+	cmd.Stdin = bytes.NewReader(html)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &limitedWriter{w: &out, n: maxSize}
+	cmd.Stderr = &limitedWriter{w: &stderr, n: 4 << 10}
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("htmlpdf: %s: %w: %s", c.Args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out.Bytes(), nil
+}
+
+// Gotenberg отправляет HTML в сервис Gotenberg (маршрут Chromium /forms/chromium/convert/html).
+type Gotenberg struct {
+	URL    string // адрес сервиса без «/» в конце, например http://gotenberg:3000
+	Client *http.Client
+}
+
+// Convert отправляет html как index.html и возвращает полученный PDF.
+func (g *Gotenberg) Convert(ctx context.Context, html []byte) ([]byte, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("files", "index.html")
+	if err != nil {
+		return nil, err
+	}
+	part.Write(html)
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.URL+"/forms/chromium/convert/html", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("htmlpdf: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("htmlpdf: gotenberg responded %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	pdf, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(pdf) > maxSize {
+		return nil, errors.New("htmlpdf: document is too large")
+	}
+	return pdf, nil
+}
+
+// limitedWriter пишет не больше n байт и возвращает ошибку при превышении, останавливая программу.
+type limitedWriter struct {
+	w io.Writer
+	n int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > l.n {
+		return 0, errors.New("htmlpdf: output is too large")
+	}
+	l.n -= len(p)
+	return l.w.Write(p)
+}
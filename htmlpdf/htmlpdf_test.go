@@ -0,0 +1,53 @@
+package htmlpdf
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// TestCommand проверяет, что программа получает HTML на стандартный ввод, а её вывод становится PDF.
+func TestCommand(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat is not available")
+	}
+	c := New("cat", "", time.Minute)
+	pdf, err := c.Convert(context.Background(), []byte("<p>hi</p>"))
+	if err != nil || string(pdf) != "<p>hi</p>" {
+		t.Fatalf("Convert = %q, %v", pdf, err)
+	}
+
+	if _, err := New("false", "", time.Minute).Convert(context.Background(), nil); err == nil {
+		t.Error("Convert ignored a failing command")
+	}
+	if New("", "", time.Minute) != nil {
+		t.Error("New without a backend returned a converter")
+	}
+}
+
+// TestGotenberg проверяет запрос к Gotenberg: файл index.html в форме и PDF в ответе.
+func TestGotenberg(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/forms/chromium/convert/html" {
+			http.NotFound(w, r)
+			return
+		}
+		file, header, err := r.FormFile("files")
+		if err != nil || header.Filename != "index.html" {
+			http.Error(w, "no index.html", http.StatusBadRequest)
+			return
+		}
+		html, _ := io.ReadAll(file)
+		w.Write(append([]byte("%PDF "), html...))
+	}))
+	defer srv.Close()
+
+	pdf, err := New("", srv.URL+"/", time.Minute).Convert(context.Background(), []byte("<p>hi</p>"))
+	if err != nil || string(pdf) != "%PDF <p>hi</p>" {
+		t.Fatalf("Convert = %q, %v", pdf, err)
+	}
+}
@@ -0,0 +1,189 @@
+// Package i18n переводит строки интерфейса: каталоги сообщений на английском и русском
+// и выбор языка запроса по сохранённой настройке (cookie lang) или заголовку Accept-Language.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default — язык, если ни настройка, ни Accept-Language не указывают поддерживаемый.
+// Английский каталог считается основным: ключ, которого нет в другом языке, берётся из него.
+const Default = "en"
+
+// CookieName — cookie с выбранным языком; для вошедших пользователей выбор также хранится в профиле.
+const CookieName = "lang"
+
+// Languages перечисляет поддерживаемые языки в порядке показа в переключателе.
+var Languages = []string{"en", "ru"}
+
+//go:embed messages/*.json
+var messageFiles embed.FS
+
+// catalogs — сообщения по языкам: ключ → текст (может содержать глаголы fmt, например %s).
+var catalogs = mustLoad()
+
+// mustLoad разбирает встроенные каталоги messages/<язык>.json. Каталоги проверяются тестами,
+// поэтому ошибка здесь означает повреждённую сборку.
+func mustLoad() map[string]map[string]string {
+	loaded := make(map[string]map[string]string, len(Languages))
+	for _, lang := range Languages {
+		data, err := messageFiles.ReadFile(path.Join("messages", lang+".json"))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: %v", err))
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: messages/%s.json: %v", lang, err))
+		}
+		loaded[lang] = messages
+	}
+	return loaded
+}
+
+// Supported сообщает, есть ли каталог для языка lang.
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Lookup возвращает сообщение key на языке lang без подстановки аргументов, а если его там нет —
+// на языке по умолчанию. ok ложно, если ключ не найден ни в одном из каталогов.
+func Lookup(lang, key string) (string, bool) {
+	if msg, ok := catalogs[lang][key]; ok {
+		return msg, true
+	}
+	msg, ok := catalogs[Default][key]
+	return msg, ok
+}
+
+// T возвращает сообщение key на языке lang, подставляя args через fmt.Sprintf.
+// Отсутствующий ключ возвращается как есть, чтобы пропуск в каталоге был заметен на странице.
+func T(lang, key string, args ...interface{}) string {
+	msg, ok := Lookup(lang, key)
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Plural возвращает сообщение с числом n в нужной форме: key.one, key.few или key.many (см. pluralForm),
+// подставляя n. Формы задаются в каждом каталоге; в английском few и many совпадают.
+func Plural(lang, key string, n int) string {
+	return T(lang, key+"."+pluralForm(lang, n), n)
+}
+
+// pluralForm выбирает форму слова для числа n по правилам языка lang: в русском «1 комментарий»,
+// «2 комментария», «5 комментариев» (с исключениями для 11–14), в английском — one только для 1.
+func pluralForm(lang string, n int) string {
+	if n < 0 {
+		n = -n
+	}
+	if lang != "ru" {
+		if n == 1 {
+			return "one"
+		}
+		return "many"
+	}
+	switch last, lastTwo := n%10, n%100; {
+	case last == 1 && lastTwo != 11:
+		return "one"
+	case last >= 2 && last <= 4 && (lastTwo < 12 || lastTwo > 14):
+		return "few"
+	default:
+		return "many"
+	}
+}
+
+// Messages возвращает сообщения языка lang, ключи которых начинаются с одного из prefixes.
+// Используется, чтобы передать скриптам страницы только нужную им часть каталога.
+func Messages(lang string, prefixes ...string) map[string]string {
+	result := make(map[string]string)
+	for _, l := range []string{Default, lang} {
+		for key, msg := range catalogs[l] {
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(key, prefix) {
+					result[key] = msg
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+// Negotiate выбирает поддерживаемый язык по заголовку Accept-Language с учётом весов q.
+// Региональные варианты сводятся к основному языку (ru-RU → ru). Без подходящего языка возвращает Default.
+func Negotiate(header string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if !Supported(lang) {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return Default
+	}
+	// Стабильная сортировка: при равных весах побеждает язык, указанный раньше.
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+type contextKey struct{}
+
+// WithLanguage возвращает контекст с языком запроса lang.
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, contextKey{}, lang)
+}
+
+// FromContext возвращает язык запроса, выбранный Middleware, или Default.
+func FromContext(ctx context.Context) string {
+	if lang, ok := ctx.Value(contextKey{}).(string); ok {
+		return lang
+	}
+	return Default
+}
+
+// Middleware выбирает язык запроса и сохраняет его в контексте (см. FromContext):
+// язык из cookie lang, если он поддерживается, иначе по заголовку Accept-Language.
+// Язык ответа указывается в Content-Language, а Vary сообщает кэшам, что ответ зависит от Accept-Language.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var lang string
+		if cookie, err := r.Cookie(CookieName); err == nil && Supported(cookie.Value) {
+			lang = cookie.Value
+		} else {
+			lang = Negotiate(r.Header.Get("Accept-Language"))
+		}
+		w.Header().Set("Content-Language", lang)
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(WithLanguage(r.Context(), lang)))
+	})
+}
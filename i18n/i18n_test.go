@@ -0,0 +1,108 @@
+package i18n
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+// verbs находит глаголы fmt в сообщении.
+var verbs = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+// TestCatalogs проверяет, что каталоги содержат одинаковые ключи с одинаковыми глаголами fmt.
+func TestCatalogs(t *testing.T) {
+	for _, lang := range Languages {
+		for key, msg := range catalogs[Default] {
+			translated, ok := catalogs[lang][key]
+			if !ok {
+				t.Errorf("%s: missing key %q", lang, key)
+				continue
+			}
+			if want, got := verbs.FindAllString(msg, -1), verbs.FindAllString(translated, -1); !slices.Equal(want, got) {
+				t.Errorf("%s: %q has verbs %v, want %v", lang, key, got, want)
+			}
+		}
+		for key := range catalogs[lang] {
+			if _, ok := catalogs[Default][key]; !ok {
+				t.Errorf("%s: key %q is not in the %s catalog", lang, key, Default)
+			}
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header, want string
+	}{
+		{"", Default},
+		{"ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7", "ru"},
+		{"de-DE,de;q=0.9,ru;q=0.5,en;q=0.6", "en"},
+		{"fr, ru;q=0.1", "ru"},
+		{"ru;q=0, fr", Default},
+		{"EN-gb", "en"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+// TestMiddleware проверяет приоритет cookie над Accept-Language.
+func TestMiddleware(t *testing.T) {
+	var got string
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "ru")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got != "ru" || w.Header().Get("Content-Language") != "ru" {
+		t.Errorf("Accept-Language: got %q, Content-Language %q", got, w.Header().Get("Content-Language"))
+	}
+
+	r.AddCookie(&http.Cookie{Name: CookieName, Value: "en"})
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if got != "en" {
+		t.Errorf("cookie: got %q, want en", got)
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T("ru", "user.greeting", "Аня"); got != "С наступающим, Аня!" {
+		t.Errorf("T = %q", got)
+	}
+	if got := T("ru", "no.such.key"); got != "no.such.key" {
+		t.Errorf("missing key: T = %q", got)
+	}
+}
+
+// TestPlural проверяет выбор формы слова по числу.
+func TestPlural(t *testing.T) {
+	tests := []struct {
+		lang string
+		n    int
+		want string
+	}{
+		{"ru", 1, "1 комментарий"},
+		{"ru", 3, "3 комментария"},
+		{"ru", 5, "5 комментариев"},
+		{"ru", 11, "11 комментариев"},
+		{"ru", 12, "12 комментариев"},
+		{"ru", 21, "21 комментарий"},
+		{"ru", 104, "104 комментария"},
+		{"ru", 0, "0 комментариев"},
+		{"en", 1, "1 comment"},
+		{"en", 2, "2 comments"},
+		{"en", 0, "0 comments"},
+	}
+	for _, tt := range tests {
+		if got := Plural(tt.lang, "post.comment_count", tt.n); got != tt.want {
+			t.Errorf("Plural(%s, %d) = %q, want %q", tt.lang, tt.n, got, tt.want)
+		}
+	}
+}
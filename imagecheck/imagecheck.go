@@ -0,0 +1,169 @@
+// Package imagecheck проверяет адреса изображений, которые авторы прикладывают к постам:
+// адрес должен быть абсолютным http(s)-адресом, домен — проходить списки разрешённых и запрещённых
+// доменов (их ведёт администратор), а при включённой проверке Verifier запросом HEAD убеждается,
+// что по адресу действительно лежит изображение.
+package imagecheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// MaxURLLen — наибольшая длина адреса изображения в байтах.
+const MaxURLLen = 2048
+
+// Ошибки проверки адреса изображения.
+var (
+	ErrInvalidURL = errors.New("imagecheck: invalid image URL")
+	ErrDomain     = errors.New("imagecheck: image domain not allowed")
+	ErrNotImage   = errors.New("imagecheck: URL does not return an image")
+)
+
+// Policy — списки доменов изображений. Домен в списке покрывает и свои поддомены.
+// Пустой Allow разрешает все домены, кроме перечисленных в Block; Block важнее Allow.
+type Policy struct {
+	Allow []string
+	Block []string
+}
+
+// ParseDomains разбирает список доменов, разделённых пробелами, запятыми или переводами строк,
+// приводит их к нижнему регистру и убирает повторы. Возвращает ошибку для строки, не похожей на домен.
+func ParseDomains(value string) ([]string, error) {
+	fields := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	})
+	domains := make([]string, 0, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for _, d := range fields {
+		d = strings.TrimPrefix(strings.TrimSuffix(d, "."), "*.")
+		if !validDomain(d) {
+			return nil, fmt.Errorf("invalid domain %q", d)
+		}
+		if !seen[d] {
+			seen[d] = true
+			domains = append(domains, d)
+		}
+	}
+	return domains, nil
+}
+
+// validDomain сообщает, состоит ли d из меток латинских букв, цифр и дефисов, разделённых точками.
+func validDomain(d string) bool {
+	if d == "" || len(d) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(d, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Validate проверяет адрес изображения raw и возвращает его в каноническом виде.
+// Ошибка ErrInvalidURL — адрес не абсолютный http(s), содержит логин или слишком длинный;
+// ErrDomain — домен запрещён политикой.
+func (p Policy) Validate(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || len(raw) > MaxURLLen {
+		return "", ErrInvalidURL
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return "", ErrInvalidURL
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if matches(host, p.Block) || (len(p.Allow) > 0 && !matches(host, p.Allow)) {
+		return "", ErrDomain
+	}
+	return u.String(), nil
+}
+
+// matches сообщает, совпадает ли host с одним из доменов или является его поддоменом.
+func matches(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewClient создаёт HTTP-клиент для запросов к внешним хостам изображений с ограничением времени timeout
+// и не больше чем тремя перенаправлениями. Соединения с адресами локальной сети и самого сервера запрещены,
+// чтобы через адрес изображения нельзя было опрашивать внутренние службы.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: denyPrivate}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 3 {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+}
+
+// Verifier проверяет запросом HEAD, что адрес отвечает изображением (см. NewClient).
+type Verifier struct {
+	client *http.Client
+}
+
+// NewVerifier создаёт Verifier, ожидающий ответа не дольше timeout.
+func NewVerifier(timeout time.Duration) *Verifier {
+	return &Verifier{client: NewClient(timeout)}
+}
+
+// Verify отправляет HEAD на rawURL и возвращает ErrNotImage, если сервер недоступен,
+// отвечает ошибкой или присылает не изображение (Content-Type не image/*).
+func (v *Verifier) Verify(ctx context.Context, rawURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return ErrInvalidURL
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotImage, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%w: status %d", ErrNotImage, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(strings.ToLower(ct), "image/") {
+		return fmt.Errorf("%w: content type %q", ErrNotImage, ct)
+	}
+	return nil
+}
+
+// denyPrivate запрещает соединения с внутренними адресами: loopback, частными сетями, link-local и unspecified.
+// Проверяется уже разрешённый адрес, поэтому обойти её DNS-записью, указывающей внутрь сети, нельзя.
+func denyPrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("address %s is not allowed", address)
+	}
+	return nil
+}
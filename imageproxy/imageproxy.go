@@ -0,0 +1,187 @@
+// Package imageproxy отдаёт внешние изображения постов через сам форум: сервер загружает их по адресу
+// image_url и пересылает браузеру, поэтому браузеры посетителей не обращаются к сторонним хостам
+// (нет смешанного содержимого на HTTPS и слежки через запросы картинок).
+//
+// Адреса прокси подписываются (HMAC), чтобы /img нельзя было использовать как открытый прокси
+// для произвольных адресов: Proxy.URL строит адрес /img/{подпись}?url=..., а обработчик отвечает
+// только на адреса со своей подписью.
+package imageproxy
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/cache"
+	"forum/imagecheck"
+)
+
+// Ошибки загрузки изображения.
+var (
+	errTooLarge = errors.New("image is too large")
+	errNotImage = errors.New("response is not a supported image")
+)
+
+// allowedTypes — форматы, которые прокси пересылает. SVG не входит: он может содержать скрипты.
+var allowedTypes = map[string]bool{
+	"image/png":    true,
+	"image/jpeg":   true,
+	"image/gif":    true,
+	"image/webp":   true,
+	"image/bmp":    true,
+	"image/x-icon": true,
+}
+
+// Options — настройки прокси.
+type Options struct {
+	Secret   []byte        // ключ подписи адресов
+	MaxSize  int64         // наибольший размер изображения в байтах
+	Timeout  time.Duration // наибольшее время загрузки изображения
+	CacheTTL time.Duration // сколько изображение хранится в кэше и в браузере
+	Cache    cache.Cache   // кэш загруженных изображений; nil — изображения загружаются при каждом запросе
+	Client   *http.Client  // nil — imagecheck.NewClient(Timeout)
+}
+
+// Proxy — обработчик /img/{sig}.
+type Proxy struct {
+	opts   Options
+	client *http.Client
+}
+
+// New создаёт прокси изображений.
+func New(opts Options) *Proxy {
+	client := opts.Client
+	if client == nil {
+		client = imagecheck.NewClient(opts.Timeout)
+	}
+	return &Proxy{opts: opts, client: client}
+}
+
+// URL возвращает адрес изображения raw через прокси. Адреса не http(s) возвращаются как есть:
+// их не к чему проксировать, а опасные схемы экранирует html/template.
+func (p *Proxy) URL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return raw
+	}
+	return "/img/" + p.sign(raw) + "?" + url.Values{"url": {raw}}.Encode()
+}
+
+// sign возвращает подпись адреса raw.
+func (p *Proxy) sign(raw string) string {
+	mac := hmac.New(sha256.New, p.opts.Secret)
+	mac.Write([]byte(raw))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// ServeHTTP отдаёт изображение по подписанному адресу /img/{sig}?url=...
+// На неверную подпись отвечает 403, на недоступное, слишком большое или не поддерживаемое изображение — 502.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	raw := r.URL.Query().Get("url")
+	sig := r.PathValue("sig")
+	if raw == "" || !hmac.Equal([]byte(sig), []byte(p.sign(raw))) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	// Подпись зависит только от адреса, а изображение по нему в кэше не меняется, поэтому подпись служит ETag.
+	etag := `"` + sig + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	contentType, body, err := p.load(r.Context(), sig, raw)
+	if err != nil {
+		log.Printf("Image proxy failed to load %s: %v.", raw, err)
+		w.Header().Set("Cache-Control", "no-store")
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	h.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(p.opts.CacheTTL.Seconds())))
+	h.Set("ETag", etag)
+	// Изображение открывается и как отдельная страница, поэтому ему запрещено всё, кроме показа.
+	h.Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+// load возвращает тип и содержимое изображения из кэша или загружает его по адресу raw.
+// В кэше значение хранится как тип, нулевой байт и содержимое.
+func (p *Proxy) load(ctx context.Context, sig, raw string) (string, []byte, error) {
+	key := "img:" + sig
+	if p.opts.Cache != nil {
+		if v, ok := p.opts.Cache.Get(key); ok {
+			if i := strings.IndexByte(string(v), 0); i >= 0 {
+				return string(v[:i]), v[i+1:], nil
+			}
+		}
+	}
+
+	contentType, body, err := p.fetch(ctx, raw)
+	if err != nil {
+		return "", nil, err
+	}
+	if p.opts.Cache != nil {
+		v := make([]byte, 0, len(contentType)+1+len(body))
+		v = append(append(append(v, contentType...), 0), body...)
+		p.opts.Cache.Set(key, v, p.opts.CacheTTL)
+	}
+	return contentType, body, nil
+}
+
+// fetch загружает изображение и проверяет его размер и формат: заявленный сервером Content-Type
+// должен быть изображением, а формат, определённый по содержимому, — одним из allowedTypes.
+// Возвращает формат, определённый по содержимому.
+func (p *Proxy) fetch(ctx context.Context, raw string) (string, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, raw, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Accept", "image/*")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > p.opts.MaxSize {
+		return "", nil, errTooLarge
+	}
+	declared, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(declared, "image/") {
+		return "", nil, errNotImage
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, p.opts.MaxSize+1))
+	if err != nil {
+		return "", nil, err
+	}
+	if int64(len(body)) > p.opts.MaxSize {
+		return "", nil, errTooLarge
+	}
+	sniffed := http.DetectContentType(body)
+	if !allowedTypes[sniffed] {
+		return "", nil, errNotImage
+	}
+	return sniffed, body, nil
+}
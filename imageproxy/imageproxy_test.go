@@ -0,0 +1,86 @@
+package imageproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"forum/cache"
+)
+
+// png — минимальная сигнатура PNG, по которой http.DetectContentType распознаёт формат.
+var png = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// TestProxy проверяет подпись адресов, кэширование и отказ для неподходящих ответов.
+func TestProxy(t *testing.T) {
+	hits := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		switch r.URL.Path {
+		case "/a.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(png)
+		case "/big.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(append(png, make([]byte, 64)...))
+		case "/x.svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Write([]byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`))
+		case "/page":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write(png)
+		}
+	}))
+	defer upstream.Close()
+
+	p := New(Options{
+		Secret:   []byte("secret"),
+		MaxSize:  32,
+		CacheTTL: time.Hour,
+		Cache:    cache.NewMemory(10),
+		Client:   upstream.Client(),
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/img/{sig}", p)
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	src := p.URL(upstream.URL + "/a.png")
+	if !strings.HasPrefix(src, "/img/") {
+		t.Fatalf("URL = %q, want /img/...", src)
+	}
+	for i := 0; i < 2; i++ {
+		w := get(src, nil)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" || w.Body.String() != string(png) {
+			t.Fatalf("GET %s = %d %q", src, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+	if hits != 1 {
+		t.Errorf("upstream hits = %d, want 1 (second request from cache)", hits)
+	}
+	if w := get(src, http.Header{"If-None-Match": {get(src, nil).Header().Get("ETag")}}); w.Code != http.StatusNotModified {
+		t.Errorf("conditional GET = %d, want 304", w.Code)
+	}
+
+	forged := strings.Replace(src, "a.png", "b.png", 1)
+	if w := get(forged, nil); w.Code != http.StatusForbidden {
+		t.Errorf("forged URL = %d, want 403", w.Code)
+	}
+	for _, path := range []string{"/big.png", "/x.svg", "/page"} {
+		if w := get(p.URL(upstream.URL+path), nil); w.Code != http.StatusBadGateway {
+			t.Errorf("%s = %d, want 502", path, w.Code)
+		}
+	}
+	if got := p.URL("javascript:alert(1)"); got != "javascript:alert(1)" {
+		t.Errorf("URL(javascript) = %q, want unchanged", got)
+	}
+}
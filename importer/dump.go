@@ -0,0 +1,311 @@
+// Package importer загружает посты, пользователей, комментарии и голоса из выгрузки другого форума.
+// Поддерживает один JSON-файл или каталог с CSV-файлами (users.csv, posts.csv, comments.csv, votes.csv).
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SourceID — идентификатор записи в исходной системе.
+// В JSON может быть как строкой, так и числом.
+type SourceID string
+
+// UnmarshalJSON принимает идентификатор в виде строки или числа.
+func (id *SourceID) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*id = SourceID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*id = SourceID(n.String())
+	return nil
+}
+
+// Timestamp — время из выгрузки, допускающее несколько распространённых форматов.
+type Timestamp struct {
+	time.Time
+}
+
+// timestampLayouts перечисляет поддерживаемые форматы времени в порядке проверки.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	time.DateOnly,
+}
+
+// parseTimestamp разбирает время в одном из поддерживаемых форматов или Unix-секундах.
+// Пустая строка даёт нулевое время.
+func parseTimestamp(value string) (Timestamp, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Timestamp{}, nil
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return Timestamp{t}, nil
+		}
+	}
+	if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return Timestamp{time.Unix(sec, 0).UTC()}, nil
+	}
+	return Timestamp{}, fmt.Errorf("unsupported timestamp %q", value)
+}
+
+// UnmarshalJSON принимает время строкой или числом Unix-секунд.
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	var value string
+	switch v := raw.(type) {
+	case nil:
+		return nil
+	case string:
+		value = v
+	case float64:
+		value = strconv.FormatInt(int64(v), 10)
+	default:
+		return fmt.Errorf("unsupported timestamp %s", data)
+	}
+	parsed, err := parseTimestamp(value)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// User описывает пользователя из выгрузки. Пользователи сопоставляются с существующими по email.
+type User struct {
+	Email       string    `json:"email"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	CreatedAt   Timestamp `json:"created_at"`
+}
+
+// Post описывает пост из выгрузки. Автор задаётся email, категории — именами.
+type Post struct {
+	ID          SourceID  `json:"id"`
+	AuthorEmail string    `json:"author_email"`
+	Title       string    `json:"title"`
+	Content     string    `json:"content"`
+	ImageURL    string    `json:"image_url"`
+	Categories  []string  `json:"categories"`
+	Board       string    `json:"board"`
+	CreatedAt   Timestamp `json:"created_at"`
+}
+
+// Comment описывает комментарий из выгрузки, привязанный к посту по исходному идентификатору.
+type Comment struct {
+	ID          SourceID  `json:"id"`
+	PostID      SourceID  `json:"post_id"`
+	AuthorEmail string    `json:"author_email"`
+	Content     string    `json:"content"`
+	CreatedAt   Timestamp `json:"created_at"`
+}
+
+// Vote описывает голос за пост или комментарий (value равно 1 или -1).
+type Vote struct {
+	UserEmail string   `json:"user_email"`
+	PostID    SourceID `json:"post_id"`
+	CommentID SourceID `json:"comment_id"`
+	Value     int      `json:"value"`
+}
+
+// Dump — полная выгрузка форума, готовая к импорту.
+type Dump struct {
+	Users    []User    `json:"users"`
+	Posts    []Post    `json:"posts"`
+	Comments []Comment `json:"comments"`
+	Votes    []Vote    `json:"votes"`
+}
+
+// Load читает выгрузку по указанному пути.
+// Каталог читается как набор CSV-файлов, обычный файл — как JSON.
+func Load(path string) (*Dump, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return LoadCSV(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadJSON(f)
+}
+
+// LoadJSON читает выгрузку в формате JSON.
+func LoadJSON(r io.Reader) (*Dump, error) {
+	var dump Dump
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&dump); err != nil {
+		return nil, fmt.Errorf("decode JSON dump: %w", err)
+	}
+	return &dump, nil
+}
+
+// LoadCSV читает выгрузку из каталога с CSV-файлами.
+// Каждый файл должен содержать строку заголовков; отсутствующие файлы пропускаются.
+// Категории поста в posts.csv перечисляются через точку с запятой.
+func LoadCSV(dir string) (*Dump, error) {
+	var dump Dump
+
+	err := readCSV(filepath.Join(dir, "users.csv"), func(row csvRow) error {
+		createdAt, err := parseTimestamp(row.get("created_at"))
+		if err != nil {
+			return err
+		}
+		dump.Users = append(dump.Users, User{
+			Email:       row.get("email"),
+			Username:    row.get("username"),
+			DisplayName: row.get("display_name"),
+			CreatedAt:   createdAt,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readCSV(filepath.Join(dir, "posts.csv"), func(row csvRow) error {
+		createdAt, err := parseTimestamp(row.get("created_at"))
+		if err != nil {
+			return err
+		}
+		var categories []string
+		for _, name := range strings.Split(row.get("categories"), ";") {
+			if name = strings.TrimSpace(name); name != "" {
+				categories = append(categories, name)
+			}
+		}
+		dump.Posts = append(dump.Posts, Post{
+			ID:          SourceID(row.get("id")),
+			AuthorEmail: row.get("author_email"),
+			Title:       row.get("title"),
+			Content:     row.get("content"),
+			ImageURL:    row.get("image_url"),
+			Categories:  categories,
+			Board:       row.get("board"),
+			CreatedAt:   createdAt,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readCSV(filepath.Join(dir, "comments.csv"), func(row csvRow) error {
+		createdAt, err := parseTimestamp(row.get("created_at"))
+		if err != nil {
+			return err
+		}
+		dump.Comments = append(dump.Comments, Comment{
+			ID:          SourceID(row.get("id")),
+			PostID:      SourceID(row.get("post_id")),
+			AuthorEmail: row.get("author_email"),
+			Content:     row.get("content"),
+			CreatedAt:   createdAt,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readCSV(filepath.Join(dir, "votes.csv"), func(row csvRow) error {
+		value, err := strconv.Atoi(row.get("value"))
+		if err != nil {
+			return fmt.Errorf("invalid vote value %q", row.get("value"))
+		}
+		dump.Votes = append(dump.Votes, Vote{
+			UserEmail: row.get("user_email"),
+			PostID:    SourceID(row.get("post_id")),
+			CommentID: SourceID(row.get("comment_id")),
+			Value:     value,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dump, nil
+}
+
+// csvRow — строка CSV с доступом к значениям по имени колонки.
+type csvRow struct {
+	header map[string]int
+	record []string
+}
+
+// get возвращает значение колонки или пустую строку, если колонки нет.
+func (r csvRow) get(name string) string {
+	i, ok := r.header[name]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+// readCSV читает CSV-файл с заголовком и вызывает fn для каждой строки.
+// Отсутствующий файл не считается ошибкой.
+func readCSV(path string, fn func(csvRow) error) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	names, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	header := make(map[string]int, len(names))
+	for i, name := range names {
+		header[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		if err := fn(csvRow{header: header, record: record}); err != nil {
+			return fmt.Errorf("%s line %d: %w", filepath.Base(path), line, err)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"forum/database"
+)
+
+// commentTimeLayout совпадает с форматом, в котором CommentHandler сохраняет время комментария.
+const commentTimeLayout = "2006-01-02 15:04:05"
+
+// defaultCategory используется для постов без категорий или с неизвестными категориями.
+const defaultCategory = "other"
+
+// Report содержит итоги импорта.
+type Report struct {
+	UsersCreated int
+	UsersMatched int
+	Posts        int
+	Comments     int
+	Votes        int
+	Skipped      []string
+}
+
+// String возвращает краткую сводку импорта.
+func (r Report) String() string {
+	return fmt.Sprintf("users: %d created, %d matched; posts: %d; comments: %d; votes: %d; skipped: %d",
+		r.UsersCreated, r.UsersMatched, r.Posts, r.Comments, r.Votes, len(r.Skipped))
+}
+
+// importer хранит состояние одного запуска импорта внутри транзакции.
+type importer struct {
+	ctx      context.Context
+	tx       *sql.Tx
+	report   Report
+	users    map[string]int // email в нижнем регистре -> ID пользователя
+	profiles map[string]User
+	posts    map[SourceID]int64
+	comments map[SourceID]int64
+}
+
+// Import записывает выгрузку в базу данных в одной транзакции.
+// Пользователи сопоставляются с существующими по email; новые получают случайный пароль,
+// который нужно сбросить. Время создания записей и голоса переносятся без изменений.
+func Import(ctx context.Context, db *sql.DB, dump *Dump) (Report, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Report{}, err
+	}
+	defer tx.Rollback()
+
+	im := &importer{
+		ctx:      ctx,
+		tx:       tx,
+		users:    make(map[string]int),
+		profiles: make(map[string]User),
+		posts:    make(map[SourceID]int64),
+		comments: make(map[SourceID]int64),
+	}
+	for _, u := range dump.Users {
+		im.profiles[normalizeEmail(u.Email)] = u
+	}
+
+	for _, u := range dump.Users {
+		if _, err := im.userID(u.Email); err != nil {
+			return Report{}, err
+		}
+	}
+	for _, p := range dump.Posts {
+		if err := im.importPost(p); err != nil {
+			return Report{}, fmt.Errorf("post %s: %w", p.ID, err)
+		}
+	}
+	for _, c := range dump.Comments {
+		if err := im.importComment(c); err != nil {
+			return Report{}, fmt.Errorf("comment %s: %w", c.ID, err)
+		}
+	}
+	for _, v := range dump.Votes {
+		if err := im.importVote(v); err != nil {
+			return Report{}, fmt.Errorf("vote by %s: %w", v.UserEmail, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Report{}, err
+	}
+	return im.report, nil
+}
+
+// normalizeEmail приводит email к виду, используемому для сопоставления пользователей.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// userID возвращает ID пользователя по email, создавая его при необходимости.
+// Данные профиля берутся из раздела users выгрузки, если пользователь там описан.
+func (im *importer) userID(email string) (int, error) {
+	key := normalizeEmail(email)
+	if key == "" {
+		return 0, fmt.Errorf("empty email")
+	}
+	if id, ok := im.users[key]; ok {
+		return id, nil
+	}
+
+	var id int
+	err := im.tx.QueryRowContext(im.ctx, "SELECT id FROM users WHERE LOWER(email) = ?", key).Scan(&id)
+	if err == nil {
+		im.users[key] = id
+		im.report.UsersMatched++
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	profile := im.profiles[key]
+	base := strings.TrimSpace(profile.Username)
+	if base == "" {
+		base = key[:strings.Index(key+"@", "@")]
+	}
+	username, err := im.uniqueUsername(base)
+	if err != nil {
+		return 0, err
+	}
+
+	// Пароль неизвестен: сохраняем хэш случайной строки, пользователь должен его сбросить.
+	hash, err := bcrypt.GenerateFromPassword([]byte(uuid.New().String()), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, err
+	}
+	createdAt := profile.CreatedAt.Time
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	var displayName interface{}
+	if profile.DisplayName != "" {
+		displayName = profile.DisplayName
+	}
+
+	result, err := im.tx.ExecContext(im.ctx,
+		"INSERT INTO users (email, username, password, role, created_at, display_name) VALUES (?, ?, ?, 'user', ?, ?)",
+		strings.TrimSpace(email), username, string(hash), createdAt, displayName,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("create user %s: %w", email, err)
+	}
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	im.users[key] = int(newID)
+	im.report.UsersCreated++
+	return int(newID), nil
+}
+
+// uniqueUsername подбирает свободное имя пользователя, добавляя числовой суффикс при совпадении.
+func (im *importer) uniqueUsername(base string) (string, error) {
+	candidate := base
+	for n := 2; ; n++ {
+		var exists bool
+		err := im.tx.QueryRowContext(im.ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(username) = LOWER(?))", candidate).Scan(&exists)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s_%d", base, n)
+	}
+}
+
+// importPost создаёт пост в его разделе вместе с категориями.
+func (im *importer) importPost(p Post) error {
+	if strings.TrimSpace(p.Title) == "" || strings.TrimSpace(p.Content) == "" {
+		im.report.Skipped = append(im.report.Skipped, fmt.Sprintf("post %s: empty title or content", p.ID))
+		return nil
+	}
+	userID, err := im.userID(p.AuthorEmail)
+	if err != nil {
+		return err
+	}
+	createdAt := p.CreatedAt.Time
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	boardID, err := im.boardID(p.Board)
+	if err != nil {
+		return err
+	}
+
+	result, err := im.tx.ExecContext(im.ctx,
+		"INSERT INTO posts (user_id, board_id, title, content, image_url, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		userID, boardID, p.Title, p.Content, p.ImageURL, createdAt,
+	)
+	if err != nil {
+		return err
+	}
+	postID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if p.ID != "" {
+		im.posts[p.ID] = postID
+	}
+	im.report.Posts++
+
+	categoryIDs := make(map[int]bool)
+	for _, name := range p.Categories {
+		id, err := im.categoryID(name)
+		if err != nil {
+			return err
+		}
+		categoryIDs[id] = true
+	}
+	if len(categoryIDs) == 0 {
+		id, err := im.categoryID(defaultCategory)
+		if err != nil {
+			return err
+		}
+		categoryIDs[id] = true
+	}
+	for id := range categoryIDs {
+		if _, err := im.tx.ExecContext(im.ctx, "INSERT INTO post_categories (post_id, category_id) VALUES (?, ?)", postID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// boardID возвращает ID раздела по адресу, подставляя раздел по умолчанию для пустых и неизвестных адресов.
+// Разделы не создаются при импорте: их заводит администратор (см. подкоманду create-board).
+func (im *importer) boardID(slug string) (int, error) {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if slug == "" {
+		slug = database.DefaultBoard
+	}
+	var id int
+	err := im.tx.QueryRowContext(im.ctx, "SELECT id FROM boards WHERE slug = ?", slug).Scan(&id)
+	if err == sql.ErrNoRows && slug != database.DefaultBoard {
+		return im.boardID(database.DefaultBoard)
+	}
+	return id, err
+}
+
+// categoryID возвращает ID категории по имени, подставляя категорию по умолчанию для неизвестных имён.
+func (im *importer) categoryID(name string) (int, error) {
+	var id int
+	err := im.tx.QueryRowContext(im.ctx, "SELECT id FROM categories WHERE name = ?", strings.ToLower(strings.TrimSpace(name))).Scan(&id)
+	if err == sql.ErrNoRows && name != defaultCategory {
+		return im.categoryID(defaultCategory)
+	}
+	return id, err
+}
+
+// importComment создаёт комментарий к ранее импортированному посту.
+func (im *importer) importComment(c Comment) error {
+	postID, ok := im.posts[c.PostID]
+	if !ok {
+		im.report.Skipped = append(im.report.Skipped, fmt.Sprintf("comment %s: unknown post %s", c.ID, c.PostID))
+		return nil
+	}
+	if strings.TrimSpace(c.Content) == "" {
+		im.report.Skipped = append(im.report.Skipped, fmt.Sprintf("comment %s: empty content", c.ID))
+		return nil
+	}
+	userID, err := im.userID(c.AuthorEmail)
+	if err != nil {
+		return err
+	}
+	createdAt := c.CreatedAt.Time
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	result, err := im.tx.ExecContext(im.ctx,
+		"INSERT INTO comments (post_id, user_id, content, created_at) VALUES (?, ?, ?, ?)",
+		postID, userID, c.Content, createdAt.Format(commentTimeLayout),
+	)
+	if err != nil {
+		return err
+	}
+	commentID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if c.ID != "" {
+		im.comments[c.ID] = commentID
+	}
+	im.report.Comments++
+	return nil
+}
+
+// importVote переносит голос за пост или комментарий. Повторный голос того же пользователя перезаписывает прежний.
+func (im *importer) importVote(v Vote) error {
+	if v.Value != 1 && v.Value != -1 {
+		im.report.Skipped = append(im.report.Skipped, fmt.Sprintf("vote by %s: invalid value %d", v.UserEmail, v.Value))
+		return nil
+	}
+
+	var table, column string
+	var targetID int64
+	var ok bool
+	switch {
+	case v.CommentID != "":
+		table, column = "comment_votes", "comment_id"
+		targetID, ok = im.comments[v.CommentID]
+	case v.PostID != "":
+		table, column = "post_votes", "post_id"
+		targetID, ok = im.posts[v.PostID]
+	}
+	if !ok {
+		im.report.Skipped = append(im.report.Skipped, fmt.Sprintf("vote by %s: unknown target", v.UserEmail))
+		return nil
+	}
+
+	userID, err := im.userID(v.UserEmail)
+	if err != nil {
+		return err
+	}
+	// Прежний голос удаляется и записывается заново: ON CONFLICT (SQLite) и ON DUPLICATE KEY (MySQL) пишутся
+	// по-разному, а импорт и так идёт в одной транзакции.
+	del := fmt.Sprintf("DELETE FROM %s WHERE user_id = ? AND %s = ?", table, column)
+	if _, err := im.tx.ExecContext(im.ctx, del, userID, targetID); err != nil {
+		return err
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (user_id, %s, vote) VALUES (?, ?, ?)", table, column)
+	if _, err := im.tx.ExecContext(im.ctx, insert, userID, targetID, v.Value); err != nil {
+		return err
+	}
+	im.report.Votes++
+	return nil
+}
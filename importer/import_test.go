@@ -0,0 +1,150 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"forum/database"
+)
+
+// dumpJSON — выгрузка для TestImportJSON; dumpCSV описывает те же данные в CSV.
+// Alice уже зарегистрирована на форуме, Bob описан в разделе users, Carol встречается только как автор поста.
+// Alice голосует за пост «Imported» дважды: второй голос заменяет первый.
+const dumpJSON = `{
+    "users": [
+        {"email": "Bob@Example.com", "username": "bob", "display_name": "Bobby", "created_at": "2020-01-02 03:04:05"}
+    ],
+    "posts": [
+        {"id": 1, "author_email": "bob@example.com", "title": "Imported", "content": "Body", "categories": ["science", "unknown"], "created_at": "2021-03-04T05:06:07Z"},
+        {"id": "p2", "author_email": "carol@example.com", "title": "Second", "content": "Body"},
+        {"id": 3, "author_email": "bob@example.com", "title": "", "content": "No title"}
+    ],
+    "comments": [
+        {"id": 10, "post_id": 1, "author_email": "alice@example.com", "content": "Nice", "created_at": 1614834367},
+        {"id": 11, "post_id": 99, "author_email": "alice@example.com", "content": "Lost"}
+    ],
+    "votes": [
+        {"user_email": "alice@example.com", "post_id": 1, "value": 1},
+        {"user_email": "alice@example.com", "post_id": 1, "value": -1},
+        {"user_email": "bob@example.com", "comment_id": 10, "value": 1},
+        {"user_email": "bob@example.com", "post_id": "p2", "value": 5}
+    ]
+}`
+
+var dumpCSV = map[string]string{
+	"users.csv": "email,username,display_name,created_at\n" +
+		"Bob@Example.com,bob,Bobby,2020-01-02 03:04:05\n",
+	"posts.csv": "id,author_email,title,content,categories,created_at\n" +
+		"1,bob@example.com,Imported,Body,science; unknown,2021-03-04T05:06:07Z\n" +
+		"p2,carol@example.com,Second,Body,,\n" +
+		"3,bob@example.com,,No title,,\n",
+	"comments.csv": "id,post_id,author_email,content,created_at\n" +
+		"10,1,alice@example.com,Nice,1614834367\n" +
+		"11,99,alice@example.com,Lost,\n",
+	"votes.csv": "user_email,post_id,comment_id,value\n" +
+		"alice@example.com,1,,1\n" +
+		"alice@example.com,1,,-1\n" +
+		"bob@example.com,,10,1\n" +
+		"bob@example.com,p2,,5\n",
+}
+
+// TestImportJSON проверяет импорт выгрузки в формате JSON.
+func TestImportJSON(t *testing.T) {
+	dump, err := LoadJSON(strings.NewReader(dumpJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testImport(t, dump)
+}
+
+// TestImportCSV проверяет импорт каталога с CSV-файлами.
+func TestImportCSV(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range dumpCSV {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dump, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testImport(t, dump)
+}
+
+// testImport импортирует dump в новую базу с уже зарегистрированной Alice и сверяет пользователей,
+// посты, комментарии и голоса.
+func testImport(t *testing.T, dump *Dump) {
+	ctx := context.Background()
+	db, err := database.OpenSQLite(filepath.Join(t.TempDir(), "forum.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer database.CloseStatements(db)
+	if err := database.RegisterUser(ctx, db, "alice@example.com", "alice", "hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Import(ctx, db, dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.UsersCreated != 2 || report.UsersMatched != 1 || report.Posts != 2 || report.Comments != 1 || report.Votes != 3 || len(report.Skipped) != 3 {
+		t.Errorf("report = %s %q", report, report.Skipped)
+	}
+
+	var username, displayName, createdAt string
+	err = db.QueryRow("SELECT username, display_name, CAST(created_at AS TEXT) FROM users WHERE email = 'Bob@Example.com'").Scan(&username, &displayName, &createdAt)
+	if err != nil || username != "bob" || displayName != "Bobby" || !strings.HasPrefix(createdAt, "2020-01-02") {
+		t.Errorf("imported Bob = %q, %q, %q, %v", username, displayName, createdAt, err)
+	}
+	if err := db.QueryRow("SELECT username FROM users WHERE email = 'carol@example.com'").Scan(&username); err != nil || username != "carol" {
+		t.Errorf("author without a profile = %q, %v", username, err)
+	}
+
+	var postID, likes, dislikes int
+	err = db.QueryRow("SELECT id, likes, dislikes, CAST(created_at AS TEXT) FROM posts WHERE title = 'Imported'").Scan(&postID, &likes, &dislikes, &createdAt)
+	if err != nil || likes != 0 || dislikes != 1 || !strings.HasPrefix(createdAt, "2021-03-04") {
+		t.Errorf("imported post = %d likes, %d dislikes, created %q, %v; want the repeated vote to replace the first", likes, dislikes, createdAt, err)
+	}
+	if categories := postCategories(t, db, "Imported"); categories != "other,science" {
+		t.Errorf("categories of the imported post = %q", categories)
+	}
+	if categories := postCategories(t, db, "Second"); categories != "other" {
+		t.Errorf("categories of a post without categories = %q", categories)
+	}
+	var votes int
+	if err := db.QueryRow("SELECT COUNT(*) FROM post_votes WHERE post_id = ?", postID).Scan(&votes); err != nil || votes != 1 {
+		t.Errorf("post votes = %d, %v, want 1", votes, err)
+	}
+
+	var content, author string
+	err = db.QueryRow(`
+        SELECT c.content, u.username, c.likes FROM comments c JOIN users u ON u.id = c.user_id WHERE c.post_id = ?
+    `, postID).Scan(&content, &author, &likes)
+	if err != nil || content != "Nice" || author != "alice" || likes != 1 {
+		t.Errorf("imported comment = %q by %q with %d likes, %v", content, author, likes, err)
+	}
+}
+
+// postCategories возвращает названия категорий поста title через запятую по алфавиту.
+func postCategories(t *testing.T, db *sql.DB, title string) string {
+	t.Helper()
+	var names sql.NullString
+	err := db.QueryRow(`
+        SELECT GROUP_CONCAT(name, ',') FROM (
+            SELECT c.name FROM categories c
+            JOIN post_categories pc ON pc.category_id = c.id
+            JOIN posts p ON p.id = pc.post_id
+            WHERE p.title = ? ORDER BY c.name)
+    `, title).Scan(&names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names.String
+}
@@ -0,0 +1,1162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"forum/cache"
+	"forum/config"
+	"forum/database"
+	"forum/emailverify"
+	"forum/handlers"
+	"forum/mailreply"
+	"forum/oauth"
+	"forum/storage"
+)
+
+// TestLoginFlow проверяет вход с верным и неверным паролем и выход через маршруты приложения.
+func TestLoginFlow(t *testing.T) {
+	f := NewTestForum(t)
+
+	w := f.Do(http.MethodPost, "/login", url.Values{"email": {f.Alice.Email}, "password": {"wrong"}}, nil)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/?login_error=invalid" {
+		t.Fatalf("wrong password: %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	w = f.Do(http.MethodPost, "/login", url.Values{"email": {f.Alice.Email}, "password": {TestPassword}}, nil)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/" {
+		t.Fatalf("login: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	var session *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "session_id" {
+			session = c
+		}
+	}
+	if session == nil || session.Value == "" {
+		t.Fatal("login did not set a session cookie")
+	}
+	user := f.Alice
+	user.Session = session
+	if isAuth, userID := authenticated(f, &user); !isAuth || userID != f.Alice.ID {
+		t.Fatalf("after login: authenticated %v as %d, want user %d", isAuth, userID, f.Alice.ID)
+	}
+	// Вход удаляет прежние сессии пользователя.
+	if isAuth, _ := authenticated(f, &f.Alice); isAuth {
+		t.Error("old session survived login")
+	}
+
+	if w := f.Do(http.MethodPost, "/logout", nil, &user); w.Code != http.StatusSeeOther {
+		t.Fatalf("logout: %d", w.Code)
+	}
+	if isAuth, _ := authenticated(f, &user); isAuth {
+		t.Error("session survived logout")
+	}
+}
+
+// authenticated сообщает, узнаёт ли приложение сессию пользователя as.
+func authenticated(f *TestForum, as *TestUser) (bool, int) {
+	userID, _, _, err := f.Store.Users.GetSessionData(context.Background(), as.Session.Value)
+	return err == nil, userID
+}
+
+// TestVoting проверяет, что лайк требует входа, ставится и снимается повторным запросом,
+// а дизлайк заменяет лайк.
+func TestVoting(t *testing.T) {
+	f := NewTestForum(t)
+	like := fmt.Sprintf("/post/%d/like", f.PostID)
+	dislike := fmt.Sprintf("/post/%d/dislike", f.PostID)
+
+	tests := []struct {
+		name     string
+		target   string
+		as       *TestUser
+		success  bool
+		likes    int
+		dislikes int
+		vote     int
+	}{
+		{"anonymous", like, nil, false, 0, 0, 0},
+		{"like", like, &f.Bob, true, 1, 0, 1},
+		{"second user", like, &f.Admin, true, 2, 0, 1},
+		{"unlike", like, &f.Bob, true, 1, 0, 0},
+		{"dislike", dislike, &f.Admin, true, 0, 1, -1},
+	}
+	for _, tt := range tests {
+		w := f.Do(http.MethodPost, tt.target, nil, tt.as)
+		var resp struct {
+			Success  bool `json:"success"`
+			Likes    int  `json:"likes"`
+			Dislikes int  `json:"dislikes"`
+			UserVote int  `json:"user_vote"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: decoding response: %v", tt.name, err)
+		}
+		if resp.Success != tt.success || resp.Likes != tt.likes || resp.Dislikes != tt.dislikes || resp.UserVote != tt.vote {
+			t.Errorf("%s: got %+v, want success %v, %d/%d, vote %d", tt.name, resp, tt.success, tt.likes, tt.dislikes, tt.vote)
+		}
+	}
+}
+
+// TestDeletePermissions проверяет, что удалить пост или комментарий может только автор или администратор.
+func TestDeletePermissions(t *testing.T) {
+	f := NewTestForum(t)
+	post := fmt.Sprintf("/post/%d", f.PostID)
+	comment := fmt.Sprintf("/comment/%d", f.CommentID)
+
+	tests := []struct {
+		name   string
+		target string
+		as     *TestUser
+		code   int
+	}{
+		{"anonymous comment", comment, nil, http.StatusUnauthorized},
+		{"other user's comment", comment, &f.Alice, http.StatusForbidden},
+		{"own comment", comment, &f.Bob, http.StatusOK},
+		{"deleted comment", comment, &f.Bob, http.StatusNotFound},
+		{"other user's post", post, &f.Bob, http.StatusForbidden},
+		{"admin", post, &f.Admin, http.StatusOK},
+		{"deleted post", post, &f.Alice, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := f.Do(http.MethodDelete, tt.target, nil, tt.as); w.Code != tt.code {
+			t.Errorf("%s: DELETE %s = %d, want %d", tt.name, tt.target, w.Code, tt.code)
+		}
+	}
+}
+
+// TestPostOpenGraph проверяет метки превью ссылки на странице поста.
+func TestPostOpenGraph(t *testing.T) {
+	f := NewTestForum(t)
+	w := f.Do(http.MethodGet, fmt.Sprintf("/post/%d", f.PostID), nil, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET post: %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`<meta property="og:title" content="Hello">`,
+		`<meta property="og:description" content="First post">`,
+		fmt.Sprintf(`<meta property="og:url" content="http://localhost:8080/post/%d">`, f.PostID),
+		`<meta property="article:author" content="alice">`,
+		`<meta name="twitter:card" content="summary">`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("post page does not contain %s", want)
+		}
+	}
+	if strings.Contains(body, "og:image") {
+		t.Error("post without an image has og:image")
+	}
+}
+
+// TestNotifications проверяет, что собранный за день лайк показывается автору поста непрочитанным,
+// а после просмотра страницы уведомление становится прочитанным.
+func TestNotifications(t *testing.T) {
+	f := NewTestForum(t)
+	if w := f.Do(http.MethodGet, "/notifications", nil, nil); w.Code != http.StatusSeeOther {
+		t.Fatalf("anonymous: %d", w.Code)
+	}
+	f.Do(http.MethodPost, fmt.Sprintf("/post/%d/like", f.PostID), nil, &f.Bob)
+	if n, err := database.BuildVoteNotifications(context.Background(), f.Store.DB, time.Now()); err != nil || n != 1 {
+		t.Fatalf("BuildVoteNotifications = %d, %v; want 1", n, err)
+	}
+
+	w := f.Do(http.MethodGet, "/notifications", nil, &f.Alice)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET notifications: %d", w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `class="unread"`) || !strings.Contains(body, "got 1 like") {
+		t.Errorf("notification missing or read:\n%s", body)
+	}
+	if body := f.Do(http.MethodGet, "/notifications", nil, &f.Alice).Body.String(); strings.Contains(body, `class="unread"`) {
+		t.Error("notification still unread after viewing")
+	}
+	if body := f.Do(http.MethodGet, "/notifications", nil, &f.Bob).Body.String(); strings.Contains(body, "got 1 like") {
+		t.Error("notification shown to the voter")
+	}
+}
+
+// TestRoleChange проверяет, что смена роли из панели администратора сразу действует в открытых сессиях
+// пользователя, блокировка завершает их, а свою роль администратор изменить не может.
+func TestRoleChange(t *testing.T) {
+	f := NewTestForum(t)
+	setRole := func(email, role string) string {
+		w := f.Do(http.MethodPost, "/admin/roles", url.Values{"email": {email}, "role": {role}}, &f.Admin)
+		return w.Header().Get("Location")
+	}
+
+	if w := f.Do(http.MethodGet, "/admin", nil, &f.Bob); w.Code != http.StatusForbidden {
+		t.Fatalf("user on /admin: %d", w.Code)
+	}
+	if w := f.Do(http.MethodPost, "/admin/roles", url.Values{"email": {f.Alice.Email}, "role": {"admin"}}, &f.Bob); w.Code != http.StatusForbidden {
+		t.Fatalf("user changing roles: %d", w.Code)
+	}
+	if loc := setRole(f.Bob.Email, "admin"); loc != "/admin?message=role_saved" {
+		t.Fatalf("promote: %q", loc)
+	}
+	if w := f.Do(http.MethodGet, "/admin", nil, &f.Bob); w.Code != http.StatusOK {
+		t.Errorf("promoted user's session on /admin: %d", w.Code)
+	}
+	if loc := setRole(f.Bob.Email, "banned"); loc != "/admin?error=ban_admin" {
+		t.Errorf("ban admin: %q", loc)
+	}
+	if loc := setRole(f.Admin.Email, "user"); loc != "/admin?error=own_role" {
+		t.Errorf("own role: %q", loc)
+	}
+
+	setRole(f.Alice.Email, "banned")
+	if isAuth, _ := authenticated(f, &f.Alice); isAuth {
+		t.Error("banned user's session survived")
+	}
+	w := f.Do(http.MethodPost, "/login", url.Values{"email": {f.Alice.Email}, "password": {TestPassword}}, nil)
+	if loc := w.Header().Get("Location"); loc != "/?login_error=banned" {
+		t.Errorf("banned login: %q", loc)
+	}
+}
+
+// TestInviteOnlyRegistration проверяет, что в режиме приглашений регистрация требует неиспользованного
+// приглашения, а зарегистрировавшийся по нему появляется в дереве приглашений.
+func TestInviteOnlyRegistration(t *testing.T) {
+	f := NewTestForum(t)
+	ctx := context.Background()
+	register := func(email, username, invite string) string {
+		form := url.Values{"email": {email}, "username": {username}, "password": {TestPassword}, "invite": {invite}}
+		return f.Do(http.MethodPost, "/register", form, nil).Body.String()
+	}
+
+	f.Do(http.MethodPost, "/admin/settings", url.Values{"invite_only": {"1"}}, &f.Admin)
+	if body := register("carol@example.com", "carol", ""); !strings.Contains(body, "an invite code is required") {
+		t.Fatalf("registration without invite was not refused:\n%s", body)
+	}
+
+	if w := f.Do(http.MethodPost, "/invites", nil, &f.Alice); w.Header().Get("Location") != "/invites?message=created" {
+		t.Fatalf("create invite: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	invites, err := database.ListInvites(ctx, f.Store.DB, f.Alice.ID)
+	if err != nil || len(invites) != 1 {
+		t.Fatalf("ListInvites = %v, %v", invites, err)
+	}
+	code := invites[0].Code
+	if body := register("carol@example.com", "carol", code); !strings.Contains(body, "Registration successful") {
+		t.Fatalf("registration with invite failed:\n%s", body)
+	}
+	if exists, _ := f.Store.Users.EmailExists(ctx, "carol@example.com"); !exists {
+		t.Fatal("invited user was not created")
+	}
+	if body := register("dave@example.com", "dave", code); !strings.Contains(body, "already been used") {
+		t.Errorf("used invite accepted:\n%s", body)
+	}
+	if exists, _ := f.Store.Users.EmailExists(ctx, "dave@example.com"); exists {
+		t.Error("user created with a used invite")
+	}
+
+	body := f.Do(http.MethodGet, "/admin/invites", nil, &f.Admin).Body.String()
+	if !strings.Contains(body, ">alice</a>") || !strings.Contains(body, ">carol</a>") {
+		t.Errorf("invite tree does not show alice and carol:\n%s", body)
+	}
+}
+
+// TestRegistrationEmailDomains проверяет списки разрешённых и запрещённых почтовых доменов регистрации.
+func TestRegistrationEmailDomains(t *testing.T) {
+	f := NewTestForum(t)
+	f.Do(http.MethodPost, "/admin/email-domains", url.Values{"allow": {"school.edu"}, "block": {"old.school.edu"}}, &f.Admin)
+
+	tests := []struct {
+		email string
+		ok    bool
+	}{
+		{"student@school.edu", true},
+		{"teacher@staff.school.edu", true},
+		{"graduate@old.school.edu", false},
+		{"someone@example.com", false},
+	}
+	for i, tt := range tests {
+		form := url.Values{"email": {tt.email}, "username": {fmt.Sprintf("user%d", i)}, "password": {TestPassword}}
+		body := f.Do(http.MethodPost, "/register", form, nil).Body.String()
+		if ok := strings.Contains(body, "Registration successful"); ok != tt.ok {
+			t.Errorf("register %s: success %v, want %v", tt.email, ok, tt.ok)
+		}
+	}
+}
+
+// TestUsernameRules проверяет правила имён при регистрации и смене имени в профиле.
+func TestUsernameRules(t *testing.T) {
+	f := NewTestForum(t)
+	tests := []struct {
+		username string
+		want     string
+	}{
+		{"al", "from 3 to 30 characters"},
+		{"bad name", "characters that are not allowed"},
+		{"Mod_erator", "reserved"},
+		{"carol", "Registration successful"},
+	}
+	for i, tt := range tests {
+		form := url.Values{"email": {fmt.Sprintf("user%d@example.com", i)}, "username": {tt.username}, "password": {TestPassword}}
+		if body := f.Do(http.MethodPost, "/register", form, nil).Body.String(); !strings.Contains(body, tt.want) {
+			t.Errorf("register %q: page does not mention %q", tt.username, tt.want)
+		}
+	}
+
+	if w := f.Do(http.MethodPost, "/profile", url.Values{"username": {"admin2"}}, &f.Bob); w.Code != http.StatusSeeOther {
+		t.Errorf("rename to a valid name: %d", w.Code)
+	}
+	if w := f.Do(http.MethodPost, "/profile", url.Values{"username": {"Support"}}, &f.Bob); w.Code != http.StatusBadRequest {
+		t.Errorf("rename to a reserved name: %d", w.Code)
+	}
+	if name, _ := f.Store.Users.GetUsernameByID(context.Background(), f.Bob.ID); name != "admin2" {
+		t.Errorf("username after a refused rename = %q", name)
+	}
+}
+
+// TestDisplayNames проверяет, что посты и комментарии подписаны отображаемым именем автора с @username,
+// а авторы без отображаемого имени — просто username.
+func TestDisplayNames(t *testing.T) {
+	f := NewTestForum(t)
+	if w := f.Do(http.MethodPost, "/profile", url.Values{"display_name": {"Alice Liddell"}}, &f.Alice); w.Code != http.StatusSeeOther {
+		t.Fatalf("set display name: %d", w.Code)
+	}
+	const alice = "Alice Liddell (@alice)"
+	for _, target := range []string{"/", fmt.Sprintf("/post/%d", f.PostID), fmt.Sprintf("/profile/%d", f.Alice.ID)} {
+		if body := f.Do(http.MethodGet, target, nil, &f.Bob).Body.String(); !strings.Contains(body, alice) {
+			t.Errorf("%s does not show %q", target, alice)
+		}
+	}
+	if body := f.Do(http.MethodGet, fmt.Sprintf("/post/%d", f.PostID), nil, nil).Body.String(); !strings.Contains(body, ">bob</a>") {
+		t.Error("comment of a user without a display name is not signed with the username")
+	}
+
+	w := f.Do(http.MethodPost, fmt.Sprintf("/post/%d/comments", f.PostID), url.Values{"content": {"Signed reply"}}, &f.Alice)
+	if !strings.Contains(w.Body.String(), `"author":"`+alice+`"`) {
+		t.Errorf("comment response: %s", w.Body)
+	}
+}
+
+// TestGitHubLogin проверяет вход через GitHub на поддельном сервере: ответ с чужим state отклоняется,
+// новый пользователь получает свободное имя на основе логина, а повторный вход открывает ту же учётную запись.
+func TestGitHubLogin(t *testing.T) {
+	f := NewTestForum(t)
+	accounts := map[string]string{
+		"alice-gh": `{"id":7,"login":"alice","name":"Alice on GitHub"}`,
+		"bob-gh":   `{"id":8,"login":"bobby","name":""}`,
+	}
+	emails := map[string]string{"alice-gh": "alice.gh@example.com", "bob-gh": f.Bob.Email}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		switch r.URL.Path {
+		case "/token":
+			r.ParseForm()
+			fmt.Fprintf(w, `{"access_token":%q}`, r.Form.Get("code"))
+		case "/user":
+			io.WriteString(w, accounts[token])
+		case "/user/emails":
+			fmt.Fprintf(w, `[{"email":%q,"primary":true,"verified":true}]`, emails[token])
+		}
+	}))
+	defer server.Close()
+	github := oauth.NewGitHub("id", "secret", server.Client())
+	github.AuthURL, github.TokenURL, github.APIURL = server.URL+"/authorize", server.URL+"/token", server.URL
+	handlers.UseGitHub(github)
+	t.Cleanup(func() { handlers.UseGitHub(nil) })
+
+	// login проходит вход через GitHub с кодом code и возвращает ответ на возврат с GitHub.
+	login := func(code string, forgeState bool) *httptest.ResponseRecorder {
+		w := f.Do(http.MethodGet, "/auth/github?redirect=/notifications", nil, nil)
+		location, err := url.Parse(w.Header().Get("Location"))
+		if w.Code != http.StatusSeeOther || err != nil || !strings.HasPrefix(location.String(), server.URL+"/authorize") {
+			t.Fatalf("start GitHub login: %d %q", w.Code, w.Header().Get("Location"))
+		}
+		state := location.Query().Get("state")
+		if forgeState {
+			state = "forged"
+		}
+		r := httptest.NewRequest(http.MethodGet, "/auth/github/callback?"+url.Values{"code": {code}, "state": {state}}.Encode(), nil)
+		for _, c := range w.Result().Cookies() {
+			r.AddCookie(c)
+		}
+		w = httptest.NewRecorder()
+		f.Handler.ServeHTTP(w, r)
+		return w
+	}
+	session := func(w *httptest.ResponseRecorder) string {
+		for _, c := range w.Result().Cookies() {
+			if c.Name == "session_id" {
+				return c.Value
+			}
+		}
+		return ""
+	}
+
+	if w := login("alice-gh", true); w.Header().Get("Location") != "/?login_error=oauth" || session(w) != "" {
+		t.Errorf("forged state: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	w := login("alice-gh", false)
+	if w.Header().Get("Location") != "/notifications" || session(w) == "" {
+		t.Fatalf("first GitHub login: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	userID, _, err := database.GetOAuthUser(context.Background(), f.Store.DB, oauth.ProviderGitHub, "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var username string
+	if err := f.Store.DB.QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&username); err != nil || username != "alice2" {
+		t.Errorf("username of the GitHub user = %q, %v; want alice2", username, err)
+	}
+	if w := login("alice-gh", false); session(w) == "" {
+		t.Errorf("second GitHub login: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	var users int
+	f.Store.DB.QueryRow("SELECT COUNT(*) FROM users WHERE username LIKE 'alice%'").Scan(&users)
+	if users != 2 {
+		t.Errorf("%d alice accounts after logging in twice, want 2", users)
+	}
+
+	if w := login("bob-gh", false); w.Header().Get("Location") != "/?login_error=oauth_email_taken" {
+		t.Errorf("GitHub email of an existing user: %d %q", w.Code, w.Header().Get("Location"))
+	}
+}
+
+// sentMail запоминает письма вместо отправки.
+type sentMail struct{ to, subject, body []string }
+
+func (m *sentMail) Send(_ context.Context, to, subject, body string) error {
+	m.to, m.subject, m.body = append(m.to, to), append(m.subject, subject), append(m.body, body)
+	return nil
+}
+
+// TestEmailVerification проверяет подтверждение почты: новый пользователь получает письмо со ссылкой
+// и не может публиковать посты и комментарии, пока не откроет её; неверная ссылка отклоняется.
+func TestEmailVerification(t *testing.T) {
+	f := NewTestForum(t)
+	mail := &sentMail{}
+	handlers.UseMail(mail)
+	handlers.UseEmailVerification(&emailverify.Tokens{Secret: []byte("secret"), MaxAge: time.Hour})
+	t.Cleanup(func() {
+		handlers.UseMail(nil)
+		handlers.UseEmailVerification(nil)
+	})
+
+	form := url.Values{"email": {"carol@example.com"}, "username": {"carol"}, "password": {TestPassword}}
+	if body := f.Do(http.MethodPost, "/register", form, nil).Body.String(); !strings.Contains(body, "confirmation link to carol@example.com") {
+		t.Fatalf("registration page does not mention the confirmation email:\n%s", body)
+	}
+	if len(mail.to) != 1 || mail.to[0] != "carol@example.com" {
+		t.Fatalf("confirmation emails sent to %v", mail.to)
+	}
+	link := regexp.MustCompile(`http://\S+/verify-email\?token=\S+`).FindString(mail.body[0])
+	if link == "" {
+		t.Fatalf("no confirmation link in the email:\n%s", mail.body[0])
+	}
+
+	w := f.Do(http.MethodPost, "/login", url.Values{"email": {"carol@example.com"}, "password": {TestPassword}}, nil)
+	carol := TestUser{Email: "carol@example.com", Username: "carol"}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "session_id" {
+			carol.Session = c
+		}
+	}
+	if carol.Session == nil {
+		t.Fatalf("login of an unverified user: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	post := url.Values{"title": {"Hello"}, "content": {"First post"}, "categories": {"other"}}
+	if w := f.Do(http.MethodPost, "/post/new", post, &carol); w.Header().Get("Location") != "/post/new?error=unverified" {
+		t.Errorf("post by an unverified user: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := f.Do(http.MethodPost, fmt.Sprintf("/post/%d/comments", f.PostID), url.Values{"content": {"Hi all"}}, &carol); w.Code != http.StatusForbidden {
+		t.Errorf("comment by an unverified user: %d %s", w.Code, w.Body)
+	}
+	if w := f.Do(http.MethodPost, "/verify-email", nil, &carol); w.Header().Get("Location") != "/?message=verify_sent" || len(mail.to) != 2 {
+		t.Errorf("resend confirmation: %d %q, %d emails", w.Code, w.Header().Get("Location"), len(mail.to))
+	}
+
+	target := strings.TrimPrefix(link, "http://example.com")
+	if w := f.Do(http.MethodGet, target+"0", nil, nil); w.Header().Get("Location") != "/?message=verify_invalid" {
+		t.Errorf("forged link: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := f.Do(http.MethodGet, target, nil, nil); w.Header().Get("Location") != "/?message=email_verified" {
+		t.Fatalf("confirmation link: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := f.Do(http.MethodPost, "/post/new", post, &carol); !strings.HasPrefix(w.Header().Get("Location"), "/post/") ||
+		strings.Contains(w.Header().Get("Location"), "error") {
+		t.Errorf("post after verification: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	// Пользователи, зарегистрированные до включения подтверждения, публикуют как прежде.
+	if w := f.Do(http.MethodPost, fmt.Sprintf("/post/%d/comments", f.PostID), url.Values{"content": {"Still here"}}, &f.Bob); w.Code != http.StatusOK {
+		t.Errorf("comment by an existing user: %d %s", w.Code, w.Body)
+	}
+}
+
+// TestPostImageUpload проверяет загрузку изображения с постом: файл сохраняется в хранилище и отдаётся
+// по адресу из поста, а файл, который не является изображением, отклоняется.
+func TestPostImageUpload(t *testing.T) {
+	f := NewTestForum(t)
+	uploads, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlers.UseStorage(uploads, 1<<20)
+	t.Cleanup(func() { handlers.UseStorage(nil, 0) })
+
+	upload := func(content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("title", "Picture")
+		mw.WriteField("content", "With an image")
+		mw.WriteField("categories", "other")
+		fw, _ := mw.CreateFormFile("image_file", "picture.png")
+		io.WriteString(fw, content)
+		mw.Close()
+		r := httptest.NewRequest(http.MethodPost, "/post/new", &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		r.AddCookie(f.Admin.Session)
+		w := httptest.NewRecorder()
+		f.Handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := upload("not an image"); !strings.Contains(w.Header().Get("Location"), "error=upload_type") {
+		t.Errorf("text upload: %d %s", w.Code, w.Header().Get("Location"))
+	}
+
+	png := "\x89PNG\r\n\x1a\n picture"
+	w := upload(png)
+	if w.Code != http.StatusSeeOther || !strings.HasPrefix(w.Header().Get("Location"), "/post/") {
+		t.Fatalf("image upload: %d %s", w.Code, w.Header().Get("Location"))
+	}
+	var imageURL string
+	if err := f.Store.DB.QueryRow("SELECT image_url FROM posts WHERE title = 'Picture'").Scan(&imageURL); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(imageURL, "/uploads/posts/") || !strings.HasSuffix(imageURL, ".png") {
+		t.Fatalf("image_url = %q", imageURL)
+	}
+	w = f.Do(http.MethodGet, imageURL, nil, nil)
+	if w.Code != http.StatusOK || w.Body.String() != png || w.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("GET %s: %d %q %q", imageURL, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+	if w := f.Do(http.MethodGet, "/uploads/posts/missing.png", nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET missing upload: %d", w.Code)
+	}
+}
+
+// TestMembersOnlyPost проверяет, что пост только для авторизованных скрыт от анонимных посетителей: в лентах,
+// профиле, JSON API, комментариях, серии, открытой по ссылке подборке и недельной подборке.
+func TestMembersOnlyPost(t *testing.T) {
+	f := NewTestForum(t)
+	ctx := context.Background()
+	form := url.Values{"title": {"Secret plans"}, "content": {"For members"}, "categories": {"other"}, "members_only": {"1"},
+		"new_series": {"Saga"}}
+	w := f.Do(http.MethodPost, "/post/new", form, &f.Alice)
+	location := w.Header().Get("Location")
+	if w.Code != http.StatusSeeOther || !strings.HasPrefix(location, "/post/") {
+		t.Fatalf("create post: %d %s", w.Code, location)
+	}
+	postID, err := strconv.Atoi(strings.TrimPrefix(location, "/post/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w := f.Do(http.MethodGet, location, nil, nil); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login?redirect="+location {
+		t.Errorf("anonymous GET %s: %d %s", location, w.Code, w.Header().Get("Location"))
+	}
+	if w := f.Do(http.MethodGet, location, nil, &f.Bob); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Secret plans") {
+		t.Errorf("member GET %s: %d", location, w.Code)
+	}
+
+	// Серия, открытая по ссылке подборка и недельная подборка с этим постом.
+	nav, err := f.Store.Series.GetPostSeries(ctx, postID, f.Alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collectionID, err := f.Store.Collections.CreateCollection(ctx, f.Bob.ID, "Reading")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Store.Collections.AddCollectionItem(ctx, int(collectionID), postID); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Store.Collections.SetCollectionShared(ctx, int(collectionID), true); err != nil {
+		t.Fatal(err)
+	}
+	collection, err := f.Store.Collections.GetCollection(ctx, int(collectionID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastWeek := database.DigestWeek(time.Now()).AddDate(0, 0, -7)
+	if _, err := f.Store.DB.ExecContext(ctx, "UPDATE posts SET created_at = ? WHERE id = ?",
+		lastWeek.Add(36*time.Hour).Format("2006-01-02 15:04:05"), postID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := database.EnsureDigest(ctx, f.Store.DB, lastWeek, database.DigestSize); err != nil {
+		t.Fatal(err)
+	}
+
+	series := fmt.Sprintf("/series/%d", nav.SeriesID)
+	for _, target := range []string{"/", fmt.Sprintf("/profile/%d", f.Alice.ID), "/api/posts", "/api/feed", series,
+		"/shared/" + collection.ShareToken, "/digest"} {
+		if body := f.Do(http.MethodGet, target, nil, nil).Body.String(); strings.Contains(body, "Secret plans") {
+			t.Errorf("anonymous %s shows the members-only post", target)
+		}
+	}
+	comments := fmt.Sprintf("/api/comments?post_id=%d", postID)
+	if w := f.Do(http.MethodGet, comments, nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("anonymous %s: %d, want 404", comments, w.Code)
+	}
+	if w := f.Do(http.MethodGet, comments, nil, &f.Bob); w.Code != http.StatusOK {
+		t.Errorf("member %s: %d", comments, w.Code)
+	}
+	for _, target := range []string{"/", series} {
+		if body := f.Do(http.MethodGet, target, nil, &f.Bob).Body.String(); !strings.Contains(body, "Secret plans") {
+			t.Errorf("%s of a logged-in user does not show the members-only post", target)
+		}
+	}
+}
+
+// TestCommentPolicy проверяет, что комментарии к посту с правилом followers доступны только подписчикам автора,
+// а к посту с правилом nobody — только самому автору.
+func TestCommentPolicy(t *testing.T) {
+	f := NewTestForum(t)
+	form := url.Values{"title": {"Followers talk"}, "content": {"Only for friends"}, "categories": {"other"}, "comment_policy": {"followers"}}
+	w := f.Do(http.MethodPost, "/post/new", form, &f.Alice)
+	location := w.Header().Get("Location")
+	if w.Code != http.StatusSeeOther || !strings.HasPrefix(location, "/post/") {
+		t.Fatalf("create post: %d %s", w.Code, location)
+	}
+	comment := url.Values{"content": {"Hello there"}}
+
+	w = f.Do(http.MethodPost, location+"/comments", comment, &f.Bob)
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), `"policy":"followers"`) {
+		t.Fatalf("comment from a non-follower: %d %s", w.Code, w.Body)
+	}
+	if body := f.Do(http.MethodGet, location, nil, &f.Bob).Body.String(); strings.Contains(body, "comment-form-") {
+		t.Error("post page shows the comment form to a non-follower")
+	}
+	profile := fmt.Sprintf("/profile/%d", f.Alice.ID)
+	if w := f.Do(http.MethodPost, profile+"/follow", url.Values{"follow": {"1"}}, &f.Bob); w.Code != http.StatusSeeOther || w.Header().Get("Location") != profile {
+		t.Fatalf("follow: %d %s", w.Code, w.Header().Get("Location"))
+	}
+	if w := f.Do(http.MethodPost, location+"/comments", comment, &f.Bob); w.Code != http.StatusOK || strings.Contains(w.Body.String(), `"success":false`) {
+		t.Errorf("comment from a follower: %d %s", w.Code, w.Body)
+	}
+
+	postID := strings.TrimPrefix(location, "/post/")
+	edit := url.Values{"title": {"Followers talk"}, "content": {"Closed now"}, "categories": {"other"}, "comment_policy": {"nobody"}}
+	if w := f.Do(http.MethodPost, "/post/"+postID+"/edit", edit, &f.Alice); w.Code != http.StatusSeeOther {
+		t.Fatalf("edit post: %d", w.Code)
+	}
+	if w := f.Do(http.MethodPost, location+"/comments", comment, &f.Bob); w.Code != http.StatusForbidden {
+		t.Errorf("comment on a closed post: %d %s", w.Code, w.Body)
+	}
+	if w := f.Do(http.MethodPost, location+"/comments", comment, &f.Alice); w.Code != http.StatusOK {
+		t.Errorf("author comment on a closed post: %d %s", w.Code, w.Body)
+	}
+	if w := f.Do(http.MethodPost, "/post/new", url.Values{"title": {"T"}, "content": {"C"}, "categories": {"other"}, "comment_policy": {"friends"}}, &f.Alice); w.Header().Get("Location") != "/post/new?error=bad_request" {
+		t.Errorf("unknown comment policy: %d %s", w.Code, w.Header().Get("Location"))
+	}
+}
+
+// TestManagePosts проверяет пакетные действия на странице /my/posts: снятие с публикации и возврат,
+// отказ при чужом посте в выборке и удаление своих комментариев.
+func TestManagePosts(t *testing.T) {
+	f := NewTestForum(t)
+	postID := fmt.Sprint(f.PostID)
+
+	if w := f.Do(http.MethodGet, "/my/posts", nil, nil); w.Header().Get("Location") != "/login?redirect=/my/posts" {
+		t.Errorf("guest: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if body := f.Do(http.MethodGet, "/my/posts", nil, &f.Alice).Body.String(); !strings.Contains(body, `name="post_id" value="`+postID+`"`) {
+		t.Fatalf("own post is not listed:\n%s", body)
+	}
+
+	bulk := func(as *TestUser, form url.Values) string {
+		return f.Do(http.MethodPost, "/my/posts", form, as).Header().Get("Location")
+	}
+	if loc := bulk(&f.Bob, url.Values{"action": {"unpublish"}, "post_id": {postID}}); loc != "/my/posts?error=not_owner" {
+		t.Errorf("unpublish a foreign post: %q", loc)
+	}
+	if loc := bulk(&f.Alice, url.Values{"action": {"unpublish"}, "post_id": {postID}}); loc != "/my/posts?message=unpublish" {
+		t.Fatalf("unpublish: %q", loc)
+	}
+	if body := f.Do(http.MethodGet, "/post/"+postID, nil, &f.Bob).Body.String(); strings.Contains(body, "First post") {
+		t.Error("unpublished post is shown to others")
+	}
+	if body := f.Do(http.MethodGet, "/my/posts", nil, &f.Alice).Body.String(); !strings.Contains(body, "Unpublished") {
+		t.Errorf("unpublished post is not marked:\n%s", body)
+	}
+	if loc := bulk(&f.Alice, url.Values{"action": {"publish"}, "post_id": {postID}}); loc != "/my/posts?message=publish" {
+		t.Fatalf("publish: %q", loc)
+	}
+	if body := f.Do(http.MethodGet, "/post/"+postID, nil, &f.Bob).Body.String(); !strings.Contains(body, "First post") {
+		t.Error("post published again is not shown")
+	}
+	if loc := bulk(&f.Alice, url.Values{"action": {"categories"}, "post_id": {postID}, "categories": {"nonsense"}}); loc != "/my/posts?error=category" {
+		t.Errorf("unknown category: %q", loc)
+	}
+	if loc := bulk(&f.Alice, url.Values{"action": {"categories"}, "post_id": {postID}, "categories": {"science", "games"}}); loc != "/my/posts?message=categories" {
+		t.Errorf("change categories: %q", loc)
+	}
+	if loc := bulk(&f.Alice, url.Values{"action": {"delete"}}); loc != "/my/posts?error=empty" {
+		t.Errorf("nothing selected: %q", loc)
+	}
+
+	commentID := fmt.Sprint(f.CommentID)
+	if loc := bulk(&f.Alice, url.Values{"action": {"delete_comments"}, "comment_id": {commentID}}); loc != "/my/posts?error=not_owner" {
+		t.Errorf("delete a foreign comment: %q", loc)
+	}
+	if loc := bulk(&f.Bob, url.Values{"action": {"delete_comments"}, "comment_id": {commentID}}); loc != "/my/posts?message=delete_comments" {
+		t.Errorf("delete own comment: %q", loc)
+	}
+	if body := f.Do(http.MethodGet, "/post/"+postID, nil, &f.Bob).Body.String(); strings.Contains(body, "First comment") {
+		t.Error("deleted comment is still shown")
+	}
+}
+
+// TestCategoryStats проверяет, что лента категории и список категорий показывают число постов
+// и давность последней активности.
+func TestCategoryStats(t *testing.T) {
+	f := NewTestForum(t)
+	post := url.Values{"title": {"Aurora forecast"}, "content": {"Tonight"}, "categories": {"science"}}
+	if w := f.Do(http.MethodPost, "/post/new", post, &f.Alice); w.Code != http.StatusSeeOther {
+		t.Fatalf("create post: %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	body := f.Do(http.MethodGet, "/?category=science", nil, nil).Body.String()
+	for _, want := range []string{`<h2><span class="category-chip"`, "1 post, <time", "active just now"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("category page does not contain %q", want)
+		}
+	}
+	if !strings.Contains(body, `<a href="/?category=games" class="">Party Games</a>`) || !strings.Contains(body, "0 posts</span>") {
+		t.Error("the categories card does not list empty categories")
+	}
+}
+
+// TestPasswordReset проверяет сброс пароля по ссылке из письма: ответ не выдаёт, зарегистрирован ли адрес,
+// новый пароль действует, прежние сессии закрываются, а ссылка срабатывает один раз.
+func TestPasswordReset(t *testing.T) {
+	f := NewTestForum(t)
+	if w := f.Do(http.MethodGet, "/forgot-password", nil, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("without mail: %d, want 404", w.Code)
+	}
+	mail := &sentMail{}
+	handlers.UseMail(mail)
+	t.Cleanup(func() { handlers.UseMail(nil) })
+
+	if body := f.Do(http.MethodGet, "/", nil, nil).Body.String(); !strings.Contains(body, `href="/forgot-password"`) {
+		t.Error("the sign-in box does not link to /forgot-password")
+	}
+	forgot := func(email string) string {
+		return f.Do(http.MethodPost, "/forgot-password", url.Values{"email": {email}}, nil).Header().Get("Location")
+	}
+	if loc := forgot("nobody@example.com"); loc != "/forgot-password?message=sent" || len(mail.to) != 0 {
+		t.Errorf("unknown address: %q, %d emails", loc, len(mail.to))
+	}
+	if loc := forgot(f.Alice.Email); loc != "/forgot-password?message=sent" || len(mail.to) != 1 || mail.to[0] != f.Alice.Email {
+		t.Fatalf("reset request: %q, emails to %v", loc, mail.to)
+	}
+	link := regexp.MustCompile(`http://\S+/reset-password\?token=\S+`).FindString(mail.body[0])
+	if link == "" {
+		t.Fatalf("no reset link in the email:\n%s", mail.body[0])
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, token := u.RequestURI(), u.Query().Get("token")
+
+	if body := f.Do(http.MethodGet, target, nil, nil).Body.String(); !strings.Contains(body, `name="confirm"`) {
+		t.Fatalf("reset form is not shown:\n%s", body)
+	}
+	if body := f.Do(http.MethodGet, target+"x", nil, nil).Body.String(); strings.Contains(body, `name="confirm"`) || !strings.Contains(body, "invalid or has expired") {
+		t.Error("a wrong token shows the form")
+	}
+	mismatch := url.Values{"token": {token}, "password": {"new-password"}, "confirm": {"other"}}
+	if loc := f.Do(http.MethodPost, "/reset-password", mismatch, nil).Header().Get("Location"); !strings.HasSuffix(loc, "&error=mismatch") {
+		t.Errorf("mismatched passwords: %q", loc)
+	}
+
+	reset := url.Values{"token": {token}, "password": {"new-password"}, "confirm": {"new-password"}}
+	if loc := f.Do(http.MethodPost, "/reset-password", reset, nil).Header().Get("Location"); loc != "/?message=password_reset" {
+		t.Fatalf("reset: %q", loc)
+	}
+	if w := f.Do(http.MethodGet, "/my/posts", nil, &f.Alice); w.Header().Get("Location") != "/login?redirect=/my/posts" {
+		t.Errorf("old session still works after reset: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	login := func(password string) string {
+		return f.Do(http.MethodPost, "/login", url.Values{"email": {f.Alice.Email}, "password": {password}}, nil).Header().Get("Location")
+	}
+	if loc := login(TestPassword); !strings.Contains(loc, "login_error") {
+		t.Errorf("old password still accepted: %q", loc)
+	}
+	if loc := login("new-password"); strings.Contains(loc, "login_error") {
+		t.Errorf("new password rejected: %q", loc)
+	}
+	if loc := f.Do(http.MethodPost, "/reset-password", reset, nil).Header().Get("Location"); loc != "/reset-password" {
+		t.Errorf("reused link: %q", loc)
+	}
+}
+
+// TestSiteBranding проверяет оформление сайта из панели администратора: название, описание, ссылки подвала
+// и логотип появляются на страницах вместо оформления по умолчанию, а неверные ссылки отклоняются.
+func TestSiteBranding(t *testing.T) {
+	f := NewTestForum(t)
+	uploads, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlers.UseStorage(uploads, 1<<20)
+	t.Cleanup(func() { handlers.UseStorage(nil, 0) })
+
+	if w := f.Do(http.MethodGet, "/", nil, nil); !strings.Contains(w.Body.String(), `href="/pages/rules"`) {
+		t.Errorf("default footer links missing: %s", w.Body)
+	}
+
+	save := func(fields map[string]string, logo string, as *TestUser) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		for name, value := range fields {
+			mw.WriteField(name, value)
+		}
+		if logo != "" {
+			fw, _ := mw.CreateFormFile("image_file", "logo.png")
+			io.WriteString(fw, logo)
+		}
+		mw.Close()
+		r := httptest.NewRequest(http.MethodPost, "/admin/site", &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		r.AddCookie(as.Session)
+		w := httptest.NewRecorder()
+		f.Handler.ServeHTTP(w, r)
+		return w
+	}
+
+	branding := map[string]string{
+		"title":        "School Forum",
+		"description":  "Class of 2027",
+		"footer_links": "Contacts | /pages/contacts\nBlog | https://blog.example.com/",
+	}
+	if w := save(branding, "", &f.Alice); w.Code != http.StatusForbidden {
+		t.Errorf("branding by a regular user: %d", w.Code)
+	}
+	if w := save(map[string]string{"footer_links": "Evil | javascript:alert(1)"}, "", &f.Admin); w.Header().Get("Location") != "/admin?error=footer_links" {
+		t.Errorf("invalid footer link: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := save(branding, "not an image", &f.Admin); w.Header().Get("Location") != "/admin?error=upload_type" {
+		t.Errorf("text logo: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := save(branding, "\x89PNG\r\n\x1a\n logo", &f.Admin); w.Header().Get("Location") != "/admin?message=site_saved" {
+		t.Fatalf("save branding: %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	for _, target := range []string{"/", "/leaderboard", fmt.Sprintf("/post/%d", f.PostID)} {
+		body := f.Do(http.MethodGet, target, nil, nil).Body.String()
+		for _, want := range []string{"School Forum", "Class of 2027", `href="/pages/contacts"`, `href="https://blog.example.com/"`, `src="/uploads/site/`} {
+			if !strings.Contains(body, want) {
+				t.Errorf("GET %s: missing %q", target, want)
+			}
+		}
+		if strings.Contains(body, "Polar Lights") || strings.Contains(body, `href="/pages/rules"`) {
+			t.Errorf("GET %s: default branding still shown", target)
+		}
+	}
+	if body := f.Do(http.MethodGet, "/no-such-page", nil, nil).Body.String(); !strings.Contains(body, "School Forum") || !strings.Contains(body, `src="/uploads/site/`) {
+		t.Errorf("404 page lost the branding: %s", body)
+	}
+	if body := f.Do(http.MethodGet, "/admin", nil, &f.Admin).Body.String(); !strings.Contains(body, "Blog | https://blog.example.com/") {
+		t.Errorf("admin form lost footer links: %s", body)
+	}
+
+	if w := save(map[string]string{"reset_logo": "1"}, "", &f.Admin); w.Header().Get("Location") != "/admin?message=site_saved" {
+		t.Fatalf("reset branding: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if body := f.Do(http.MethodGet, "/", nil, nil).Body.String(); strings.Contains(body, "School Forum") || !strings.Contains(body, `href="/pages/rules"`) {
+		t.Errorf("default branding not restored")
+	}
+}
+
+// TestPostShares проверяет кнопки «Поделиться»: переход засчитывается и ведёт на страницу соцсети с адресом поста,
+// переходы по короткой ссылке тоже засчитываются, итог виден на странице поста и в отчёте администратора.
+func TestPostShares(t *testing.T) {
+	f := NewTestForum(t)
+	share := fmt.Sprintf("/post/%d/share/", f.PostID)
+
+	w := f.Do(http.MethodGet, share+"telegram", nil, nil)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if w.Code != http.StatusFound || err != nil || location.Host != "t.me" {
+		t.Fatalf("share to Telegram: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if got := location.Query().Get("url"); !strings.HasSuffix(got, fmt.Sprintf("/post/%d", f.PostID)) {
+		t.Errorf("shared address = %q", got)
+	}
+	if w := f.Do(http.MethodGet, share+"myspace", nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown channel: %d", w.Code)
+	}
+	if w := f.Do(http.MethodGet, "/post/999999/share/telegram", nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("missing post: %d", w.Code)
+	}
+
+	link, err := database.EnsureShortLink(context.Background(), f.Store.DB, f.PostID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := f.Do(http.MethodGet, "/s/"+link.Code, nil, nil); w.Code != http.StatusFound {
+		t.Fatalf("short link: %d", w.Code)
+	}
+
+	if body := f.Do(http.MethodGet, fmt.Sprintf("/post/%d", f.PostID), nil, &f.Bob).Body.String(); !strings.Contains(body, "shared 2 times") ||
+		!strings.Contains(body, share+"whatsapp") {
+		t.Errorf("post page lacks share buttons or total: %s", body)
+	}
+	if w := f.Do(http.MethodGet, "/admin/shares", nil, &f.Alice); w.Code != http.StatusForbidden {
+		t.Errorf("share report for a regular user: %d", w.Code)
+	}
+	body := f.Do(http.MethodGet, "/admin/shares", nil, &f.Admin).Body.String()
+	for _, want := range []string{"Total: 2", "Telegram", "Short link", fmt.Sprintf(`href="/post/%d"`, f.PostID)} {
+		if !strings.Contains(body, want) {
+			t.Errorf("share report lacks %q", want)
+		}
+	}
+}
+
+func TestPostReports(t *testing.T) {
+	f := NewTestForum(t)
+	postURL := fmt.Sprintf("/post/%d", f.PostID)
+	report := func(reason string, as *TestUser) string {
+		t.Helper()
+		w := f.Do(http.MethodPost, postURL+"/report", url.Values{"reason": {reason}}, as)
+		if w.Code != http.StatusSeeOther {
+			t.Fatalf("report %q: %d", reason, w.Code)
+		}
+		return w.Header().Get("Location")
+	}
+
+	if body := f.Do(http.MethodGet, postURL, nil, &f.Bob).Body.String(); !strings.Contains(body, postURL+"/report") ||
+		!strings.Contains(body, "Illegal content") {
+		t.Errorf("post page lacks the report form")
+	}
+	if body := f.Do(http.MethodGet, postURL, nil, &f.Alice).Body.String(); strings.Contains(body, postURL+"/report") {
+		t.Errorf("author can report their own post")
+	}
+	for _, c := range []struct {
+		reason string
+		as     *TestUser
+		want   string
+	}{
+		{"boring", &f.Bob, postURL + "?error=report_reason"},
+		{"illegal", &f.Alice, postURL + "?error=report_own"},
+		{"illegal", &f.Bob, postURL + "?message=reported"},
+		{"spam", &f.Bob, postURL + "?message=already_reported"},
+		{"illegal", &f.Admin, "/?message=post_reported"}, // reports.illegal по умолчанию 2, жалоба администратора весит 3
+	} {
+		if got := report(c.reason, c.as); got != c.want {
+			t.Errorf("report %q by %s → %q, want %q", c.reason, c.as.Username, got, c.want)
+		}
+	}
+
+	if body := f.Do(http.MethodGet, postURL, nil, &f.Bob).Body.String(); strings.Contains(body, postURL+"/report") {
+		t.Errorf("hidden post is still shown")
+	}
+	if body := f.Do(http.MethodGet, "/moderation", nil, &f.Admin).Body.String(); !strings.Contains(body, "Report weight: 4 · Illegal content") {
+		t.Errorf("moderation queue lacks the reported post: %s", body)
+	}
+}
+
+func TestTrustLevels(t *testing.T) {
+	f := NewTestForum(t)
+	ctx := context.Background()
+	edit := fmt.Sprintf("/post/%d/edit", f.PostID)
+
+	if body := f.Do(http.MethodGet, fmt.Sprintf("/profile/%d", f.Alice.ID), nil, nil).Body.String(); !strings.Contains(body, "Trust level: new") {
+		t.Errorf("new user's profile lacks the trust level")
+	}
+	if body := f.Do(http.MethodGet, fmt.Sprintf("/profile/%d", f.Admin.ID), nil, nil).Body.String(); !strings.Contains(body, "Trust level: regular") {
+		t.Errorf("administrator's profile lacks the regular trust level")
+	}
+
+	if w := f.Do(http.MethodGet, edit, nil, &f.Alice); w.Code != http.StatusOK {
+		t.Fatalf("edit a fresh post: %d", w.Code)
+	}
+	// Новичок может править пост час после публикации (trust.new.edit_window).
+	if _, err := f.Store.DB.ExecContext(ctx, "UPDATE posts SET created_at = ? WHERE id = ?", time.Now().Add(-2*time.Hour), f.PostID); err != nil {
+		t.Fatal(err)
+	}
+	postURL := fmt.Sprintf("/post/%d", f.PostID)
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		form := url.Values{"title": {"Changed"}, "content": {"Changed"}, "categories": {"news"}}
+		w := f.Do(method, edit, form, &f.Alice)
+		if w.Code != http.StatusSeeOther || w.Header().Get("Location") != postURL+"?error=edit_window" {
+			t.Errorf("%s an old post: %d %q", method, w.Code, w.Header().Get("Location"))
+		}
+	}
+	if body := f.Do(http.MethodGet, postURL+"?error=edit_window", nil, &f.Alice).Body.String(); !strings.Contains(body, "can no longer be edited") {
+		t.Errorf("post page lacks the edit window message")
+	}
+	if post, err := f.Store.Posts.GetPostByID(ctx, f.PostID, 0); err != nil || post.Title != "Hello" {
+		t.Errorf("post changed after the edit window: %+v, %v", post, err)
+	}
+}
+
+// TestLikers проверяет списки лайкнувших пост и комментарий и скрытие себя в них на странице профиля.
+func TestLikers(t *testing.T) {
+	f := NewTestForum(t)
+	likes := fmt.Sprintf("/post/%d/likes", f.PostID)
+	for _, u := range []*TestUser{&f.Bob, &f.Admin} {
+		if w := f.Do(http.MethodPost, fmt.Sprintf("/post/%d/like", f.PostID), nil, u); w.Code != http.StatusOK {
+			t.Fatalf("like as %s: %d", u.Username, w.Code)
+		}
+	}
+	if w := f.Do(http.MethodPost, fmt.Sprintf("/comment/%d/like", f.CommentID), nil, &f.Alice); w.Code != http.StatusOK {
+		t.Fatalf("like the comment: %d", w.Code)
+	}
+
+	w := f.Do(http.MethodGet, likes, nil, nil)
+	if body := w.Body.String(); w.Code != http.StatusOK || !strings.Contains(body, f.Bob.Username) || !strings.Contains(body, f.Admin.Username) {
+		t.Errorf("post likers: %d, want Bob and Admin", w.Code)
+	}
+	w = f.Do(http.MethodGet, fmt.Sprintf("/comment/%d/likes", f.CommentID), nil, nil)
+	if body := w.Body.String(); w.Code != http.StatusOK || !strings.Contains(body, fmt.Sprintf("/profile/%d", f.Alice.ID)) {
+		t.Errorf("comment likers: %d, want Alice", w.Code)
+	}
+
+	if w := f.Do(http.MethodPost, "/profile/likes", url.Values{"hidden": {"1"}}, &f.Admin); w.Code != http.StatusSeeOther {
+		t.Fatalf("hide likes: %d", w.Code)
+	}
+	if body := f.Do(http.MethodGet, fmt.Sprintf("/profile/%d", f.Admin.ID), nil, &f.Admin).Body.String(); !strings.Contains(body, "Show me in who-liked lists") {
+		t.Errorf("profile lacks the likes privacy switch")
+	}
+	body := f.Do(http.MethodGet, likes, nil, nil).Body.String()
+	if strings.Contains(body, fmt.Sprintf("/profile/%d\"", f.Admin.ID)) || !strings.Contains(body, "And 1 more person") {
+		t.Errorf("hidden liker is listed by name")
+	}
+
+	if w := f.Do(http.MethodGet, likes+"?cursor=bogus", nil, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("bad cursor: %d, want 400", w.Code)
+	}
+	if w := f.Do(http.MethodGet, "/post/999999/likes", nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("likers of a missing post: %d, want 404", w.Code)
+	}
+}
+
+// TestPostFragmentCache проверяет, что анонимному посетителю страница поста отдаётся из кэша
+// готовых страниц, а после правки поста или голоса за него — заново; закрытый пост из кэша не отдаётся.
+func TestPostFragmentCache(t *testing.T) {
+	f := NewTestForum(t)
+	c := cache.NewMemory(0)
+	f.WithCache(c)
+	ctx := context.Background()
+	postURL := fmt.Sprintf("/post/%d", f.PostID)
+
+	w := f.Do(http.MethodGet, postURL, nil, nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "❤️ 0") {
+		t.Fatalf("post page: %d", w.Code)
+	}
+	if _, ok := c.Get("fragment:post:" + w.Header().Get("ETag")); !ok {
+		t.Fatal("anonymous post page is not cached")
+	}
+	if w := f.Do(http.MethodGet, postURL, nil, &f.Alice); w.Code != http.StatusOK {
+		t.Fatalf("post page for the author: %d", w.Code)
+	} else if _, ok := c.Get("fragment:post:" + w.Header().Get("ETag")); ok {
+		t.Error("authenticated post page is cached")
+	}
+	// Запись в обход хранилища не меняет версию данных, поэтому страница остаётся прежней.
+	if _, err := f.Store.DB.ExecContext(ctx, "UPDATE posts SET title = 'Stale' WHERE id = ?", f.PostID); err != nil {
+		t.Fatal(err)
+	}
+	if body := f.Do(http.MethodGet, postURL, nil, nil).Body.String(); strings.Contains(body, "Stale") {
+		t.Error("post page was rendered again instead of served from the cache")
+	}
+
+	edit := url.Values{"title": {"Edited"}, "content": {"Edited content"}, "categories": {"news"}}
+	if w := f.Do(http.MethodPost, postURL+"/edit", edit, &f.Alice); w.Code != http.StatusSeeOther {
+		t.Fatalf("edit post: %d", w.Code)
+	}
+	if body := f.Do(http.MethodGet, postURL, nil, nil).Body.String(); !strings.Contains(body, "Edited content") {
+		t.Error("cached post page not refreshed after the edit")
+	}
+
+	if w := f.Do(http.MethodPost, postURL+"/like", nil, &f.Bob); w.Code != http.StatusOK {
+		t.Fatalf("like post: %d", w.Code)
+	}
+	if body := f.Do(http.MethodGet, postURL, nil, nil).Body.String(); !strings.Contains(body, "❤️ 1") {
+		t.Error("cached post page not refreshed after the vote")
+	}
+
+	// Закрытие поста без смены версии данных не отдаёт анонимному посетителю страницу из кэша.
+	if _, err := f.Store.DB.ExecContext(ctx, "UPDATE posts SET members_only = 1 WHERE id = ?", f.PostID); err != nil {
+		t.Fatal(err)
+	}
+	f.Store.InvalidateCache()
+	if w := f.Do(http.MethodGet, postURL, nil, nil); w.Code != http.StatusSeeOther {
+		t.Errorf("members-only post for an anonymous visitor: %d, want a redirect to login", w.Code)
+	}
+}
+
+// TestInboundEmailReply проверяет, что ответ на письмо публикуется комментарием по тем же правилам, что и комментарий
+// с сайта, а отклонённое письмо получает ответ 406.
+func TestInboundEmailReply(t *testing.T) {
+	f := NewTestForum(t)
+	cfg := config.Default()
+	cfg.Mail.ReplyDomain = "reply.example.com"
+	cfg.Mail.InboundToken = "inbound-secret-token"
+	files, err := loadAssets(cfg.Server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handlers.Configure(cfg, files.templates, files.manifest); err != nil {
+		t.Fatal(err)
+	}
+	addresses := &mailreply.Addresses{Secret: []byte("secret"), Domain: cfg.Mail.ReplyDomain}
+	handlers.UseReplyAddresses(addresses)
+	t.Cleanup(func() {
+		handlers.UseReplyAddresses(nil)
+		handlers.Configure(config.Default(), files.templates, files.manifest)
+	})
+
+	reply := func(sender, text string) *httptest.ResponseRecorder {
+		form := url.Values{"recipient": {addresses.For(f.PostID, f.Bob.ID)}, "sender": {sender}, "stripped-text": {text}}
+		return f.Do(http.MethodPost, "/inbound/email/"+cfg.Mail.InboundToken, form, nil)
+	}
+	if w := reply(f.Bob.Email, "Thanks, answered by mail"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"success":true`) {
+		t.Fatalf("reply by mail: %d %s", w.Code, w.Body.String())
+	}
+	if body := f.Do(http.MethodGet, fmt.Sprintf("/post/%d", f.PostID), nil, nil).Body.String(); !strings.Contains(body, "Thanks, answered by mail") {
+		t.Error("comment from the email reply is missing on the post page")
+	}
+	tests := []struct {
+		name   string
+		sender string
+		text   string
+	}{
+		{"too short", f.Bob.Email, "ok"},
+		{"empty", f.Bob.Email, "   "},
+		{"foreign sender", f.Alice.Email, "Not my address"},
+	}
+	for _, tt := range tests {
+		if w := reply(tt.sender, tt.text); w.Code != http.StatusNotAcceptable {
+			t.Errorf("%s: %d, want 406", tt.name, w.Code)
+		}
+	}
+	if _, err := f.Store.DB.Exec("UPDATE posts SET comment_policy = ? WHERE id = ?", database.CommentsNobody, f.PostID); err != nil {
+		t.Fatal(err)
+	}
+	if w := reply(f.Bob.Email, "Comments are closed"); w.Code != http.StatusNotAcceptable || !strings.Contains(w.Body.String(), "closed") {
+		t.Errorf("reply to a closed post: %d %s", w.Code, w.Body.String())
+	}
+	if w := f.Do(http.MethodPost, "/inbound/email/wrong-token", url.Values{"recipient": {addresses.For(f.PostID, f.Bob.ID)}}, nil); w.Code != http.StatusNotFound {
+		t.Errorf("wrong token: %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,150 @@
+// Package integrations отправляет уведомления о новых постах во внешние сервисы (Discord, Telegram).
+// Каждая интеграция может быть ограничена списком категорий.
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"forum/config"
+)
+
+// sendTimeout ограничивает время одной отправки во внешний сервис.
+const sendTimeout = 10 * time.Second
+
+// PostEvent описывает опубликованный пост для внешних уведомлений.
+type PostEvent struct {
+	ID         int
+	Title      string
+	Author     string
+	URL        string
+	Categories []string
+}
+
+// Notifier отправляет уведомление о посте в конкретный сервис.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, event PostEvent) error
+}
+
+// target связывает получателя уведомлений с категориями, на которые он подписан.
+type target struct {
+	notifier   Notifier
+	categories map[string]bool // пустой набор означает все категории
+}
+
+// Dispatcher рассылает уведомления о новых постах всем настроенным интеграциям.
+// Нулевое значение и nil-указатель безопасны и ничего не отправляют.
+type Dispatcher struct {
+	BaseURL string
+	targets []target
+	wg      sync.WaitGroup
+}
+
+// Add подключает интеграцию для указанных категорий (пустой список — все категории).
+func (d *Dispatcher) Add(n Notifier, categories []string) {
+	t := target{notifier: n, categories: make(map[string]bool)}
+	for _, c := range categories {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			t.categories[c] = true
+		}
+	}
+	d.targets = append(d.targets, t)
+}
+
+// Enabled сообщает, настроена ли хотя бы одна интеграция.
+func (d *Dispatcher) Enabled() bool {
+	return d != nil && len(d.targets) > 0
+}
+
+// PostPublished асинхронно уведомляет интеграции, подписанные на категории поста.
+// Ошибки отправки только логируются и не влияют на публикацию.
+func (d *Dispatcher) PostPublished(event PostEvent) {
+	if !d.Enabled() {
+		return
+	}
+	if event.URL == "" {
+		event.URL = fmt.Sprintf("%s/post/%d", strings.TrimRight(d.BaseURL, "/"), event.ID)
+	}
+	for _, t := range d.targets {
+		if !t.matches(event.Categories) {
+			continue
+		}
+		d.wg.Add(1)
+		go func(n Notifier) {
+			defer d.wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := n.Notify(ctx, event); err != nil {
+				log.Printf("Error sending %s notification for post %d: %v", n.Name(), event.ID, err)
+			}
+		}(t.notifier)
+	}
+}
+
+// Wait дожидается завершения всех начатых отправок.
+func (d *Dispatcher) Wait() {
+	if d != nil {
+		d.wg.Wait()
+	}
+}
+
+// matches проверяет, подписана ли интеграция хотя бы на одну из категорий.
+func (t target) matches(categories []string) bool {
+	if len(t.categories) == 0 {
+		return true
+	}
+	for _, c := range categories {
+		if t.categories[c] {
+			return true
+		}
+	}
+	return false
+}
+
+// New создаёт Dispatcher с каналами из настроек cfg; baseURL — адрес форума для ссылок.
+// Discord подключается, если задан вебхук, Telegram — если заданы токен бота и ID канала.
+func New(baseURL string, cfg config.Integrations) *Dispatcher {
+	d := &Dispatcher{BaseURL: baseURL}
+	if cfg.DiscordWebhookURL != "" {
+		d.Add(&Discord{WebhookURL: cfg.DiscordWebhookURL}, cfg.DiscordCategories)
+	}
+	if cfg.TelegramBotToken != "" && cfg.TelegramChatID != "" {
+		d.Add(&Telegram{BotToken: cfg.TelegramBotToken, ChatID: cfg.TelegramChatID}, cfg.TelegramCategories)
+	}
+	return d
+}
+
+// postJSON отправляет JSON-запрос и возвращает ошибку для ответов вне диапазона 2xx.
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+)
+
+// Discord публикует сообщение через вебхук канала Discord.
+type Discord struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+// Name возвращает имя интеграции для логов.
+func (d *Discord) Name() string { return "discord" }
+
+// Notify отправляет в канал embed с заголовком, автором, категориями и ссылкой на пост.
+func (d *Discord) Notify(ctx context.Context, event PostEvent) error {
+	payload := map[string]interface{}{
+		"embeds": []map[string]interface{}{{
+			"title":       truncate(event.Title, 256),
+			"url":         event.URL,
+			"description": "New post by " + event.Author,
+			"fields": []map[string]interface{}{{
+				"name":   "Categories",
+				"value":  categoryList(event.Categories),
+				"inline": true,
+			}},
+		}},
+	}
+	return postJSON(ctx, d.Client, d.WebhookURL, payload)
+}
+
+// Telegram публикует сообщение в канал или чат через Bot API.
+type Telegram struct {
+	BotToken string
+	ChatID   string
+	Client   *http.Client
+}
+
+// Name возвращает имя интеграции для логов.
+func (t *Telegram) Name() string { return "telegram" }
+
+// Notify отправляет HTML-сообщение с заголовком-ссылкой, автором и категориями.
+func (t *Telegram) Notify(ctx context.Context, event PostEvent) error {
+	text := fmt.Sprintf("<b><a href=\"%s\">%s</a></b>\nby %s\n%s",
+		html.EscapeString(event.URL),
+		html.EscapeString(event.Title),
+		html.EscapeString(event.Author),
+		html.EscapeString(categoryList(event.Categories)),
+	)
+	payload := map[string]interface{}{
+		"chat_id":    t.ChatID,
+		"text":       text,
+		"parse_mode": "HTML",
+	}
+	url := "https://api.telegram.org/bot" + t.BotToken + "/sendMessage"
+	if err := postJSON(ctx, t.Client, url, payload); err != nil {
+		// Ошибки net/http содержат URL запроса, а в нём токен бота.
+		return fmt.Errorf("%s", strings.ReplaceAll(err.Error(), t.BotToken, "***"))
+	}
+	return nil
+}
+
+// categoryList форматирует список категорий в виде хэштегов.
+func categoryList(categories []string) string {
+	if len(categories) == 0 {
+		return "—"
+	}
+	tags := make([]string, len(categories))
+	for i, c := range categories {
+		tags[i] = "#" + c
+	}
+	return strings.Join(tags, " ")
+}
+
+// truncate обрезает строку до max символов.
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
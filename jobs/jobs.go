@@ -0,0 +1,144 @@
+// Package jobs запускает периодические фоновые задачи сервера (резервные копии, очистку устаревших данных)
+// и хранит состояние их последних запусков для панели администратора.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Func — тело фоновой задачи. Ошибка логируется, задача продолжает выполняться по расписанию.
+type Func func(ctx context.Context) error
+
+// Status — состояние фоновой задачи.
+type Status struct {
+	Name         string
+	Schedule     string
+	Runs         int
+	LastRun      time.Time // начало последнего запуска; нулевое, если задача ещё не запускалась
+	LastDuration time.Duration
+	LastError    string
+}
+
+var (
+	statusMu sync.Mutex
+	statuses []*Status
+
+	// running учитывает горутины задач, чтобы Wait мог дождаться их завершения.
+	running sync.WaitGroup
+)
+
+// register добавляет задачу в список состояний.
+func register(name, schedule string) *Status {
+	st := &Status{Name: name, Schedule: schedule}
+	statusMu.Lock()
+	statuses = append(statuses, st)
+	statusMu.Unlock()
+	return st
+}
+
+// Statuses возвращает копию состояний задач в порядке их запуска.
+func Statuses() []Status {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	list := make([]Status, len(statuses))
+	for i, st := range statuses {
+		list[i] = *st
+	}
+	return list
+}
+
+// Every запускает fn в отдельной горутине каждые interval, пока не отменён ctx.
+// Первый запуск происходит через interval после вызова, а не сразу, чтобы не замедлять старт сервера.
+func Every(ctx context.Context, name string, interval time.Duration, fn Func) {
+	st := register(name, "every "+interval.String())
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				run(ctx, st, fn)
+			}
+		}
+	}()
+	log.Printf("Job %s scheduled every %s.", name, interval)
+}
+
+// windowCheckInterval — как часто Daily проверяет, не началось ли окно запуска.
+const windowCheckInterval = 5 * time.Minute
+
+// Daily запускает fn один раз в сутки внутри окна window (по местному времени сервера), пока не отменён ctx.
+func Daily(ctx context.Context, name string, window Window, fn Func) {
+	st := register(name, "daily "+window.String())
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		ticker := time.NewTicker(windowCheckInterval)
+		defer ticker.Stop()
+		var lastRun time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if !window.Contains(now) || !lastRun.Before(window.opened(now)) {
+					continue
+				}
+				lastRun = now
+				run(ctx, st, fn)
+			}
+		}
+	}()
+	log.Printf("Job %s scheduled daily %s.", name, window)
+}
+
+// Wait дожидается остановки задач после отмены их контекста: начатый запуск доводится до конца,
+// новые не начинаются. Возвращает ошибку ctx, если задачи не успели остановиться.
+func Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run выполняет задачу один раз, перехватывая панику, чтобы она не остановила сервер.
+func run(ctx context.Context, st *Status, fn Func) {
+	start := time.Now()
+	var err error
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("Job %s panicked: %v.", st.Name, rec)
+			err = fmt.Errorf("panic: %v", rec)
+		}
+		statusMu.Lock()
+		st.Runs++
+		st.LastRun = start
+		st.LastDuration = time.Since(start)
+		st.LastError = ""
+		if err != nil {
+			st.LastError = err.Error()
+		}
+		statusMu.Unlock()
+	}()
+	// Остановка сервера не прерывает начатый запуск (например, запись резервной копии).
+	if err = fn(context.WithoutCancel(ctx)); err != nil {
+		log.Printf("Job %s failed: %v.", st.Name, err)
+		return
+	}
+	log.Printf("Job %s finished in %s.", st.Name, time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestWait проверяет, что отмена контекста не прерывает начатый запуск и Wait дожидается его завершения.
+func TestWait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	finished := make(chan error, 1)
+	Every(ctx, "wait-test", time.Millisecond, func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+		finished <- ctx.Err()
+		return nil
+	})
+
+	<-started
+	cancel()
+	waitCtx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	if err := Wait(waitCtx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-finished:
+		if err != nil {
+			t.Errorf("job context was cancelled during the run: %v", err)
+		}
+	default:
+		t.Error("Wait returned before the running job finished")
+	}
+}
@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window — ежедневный интервал времени суток, например 03:00–05:00. Окно может переходить через полночь (23:00–02:00).
+type Window struct {
+	Start, End time.Duration // смещение от полуночи
+}
+
+// ParseWindow разбирает окно в формате "HH:MM-HH:MM".
+func ParseWindow(s string) (Window, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return Window{}, fmt.Errorf("invalid window %q (expected HH:MM-HH:MM)", s)
+	}
+	start, err := parseClock(strings.TrimSpace(from))
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %w", s, err)
+	}
+	end, err := parseClock(strings.TrimSpace(to))
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %w", s, err)
+	}
+	if start == end {
+		return Window{}, fmt.Errorf("invalid window %q: start and end are equal", s)
+	}
+	return Window{Start: start, End: end}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains сообщает, попадает ли t в окно.
+func (w Window) Contains(t time.Time) bool {
+	offset := t.Sub(midnight(t))
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// opened возвращает момент открытия окна, внутри которого находится t.
+func (w Window) opened(t time.Time) time.Time {
+	day := midnight(t)
+	if w.Start > w.End && t.Sub(day) < w.End {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day.Add(w.Start)
+}
+
+func (w Window) String() string {
+	return clock(w.Start) + "-" + clock(w.End)
+}
+
+func clock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+func midnight(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+// TestWindow проверяет окна внутри суток и через полночь.
+func TestWindow(t *testing.T) {
+	at := func(clock string) time.Time {
+		c, err := time.Parse("15:04", clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return time.Date(2026, 1, 10, c.Hour(), c.Minute(), 0, 0, time.UTC)
+	}
+
+	night, err := ParseWindow("03:00-05:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrap, err := ParseWindow("23:30 - 01:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		w     Window
+		clock string
+		want  bool
+	}{
+		{night, "02:59", false},
+		{night, "03:00", true},
+		{night, "04:59", true},
+		{night, "05:00", false},
+		{wrap, "23:29", false},
+		{wrap, "23:30", true},
+		{wrap, "00:30", true},
+		{wrap, "01:00", false},
+	}
+	for _, tt := range tests {
+		if got := tt.w.Contains(at(tt.clock)); got != tt.want {
+			t.Errorf("%s.Contains(%s) = %t, want %t", tt.w, tt.clock, got, tt.want)
+		}
+	}
+
+	if got, want := wrap.opened(at("00:30")), time.Date(2026, 1, 9, 23, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("opened(00:30) = %s, want %s", got, want)
+	}
+	for _, s := range []string{"", "03:00", "25:00-01:00", "03:00-03:00"} {
+		if _, err := ParseWindow(s); err == nil {
+			t.Errorf("ParseWindow(%q) succeeded", s)
+		}
+	}
+}
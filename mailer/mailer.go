@@ -0,0 +1,117 @@
+// Package mailer отправляет письма форума (например, ссылку подтверждения почты) через SMTP-сервер.
+// Если сервер предлагает STARTTLS, соединение шифруется; логин и пароль передаются только по
+// зашифрованному соединению или на localhost (см. smtp.PlainAuth).
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// Sender отправляет письмо с темой subject и текстом body на адрес to.
+type Sender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// New возвращает отправку через SMTP-сервер addr (host:port) от имени from или nil, если addr пуст:
+// отправка писем выключена. username и password нужны, если сервер требует входа.
+func New(addr, username, password, from string, timeout time.Duration) Sender {
+	if addr == "" {
+		return nil
+	}
+	return &SMTP{Addr: addr, Username: username, Password: password, From: from, Timeout: timeout}
+}
+
+// SMTP отправляет письма через SMTP-сервер.
+type SMTP struct {
+	Addr     string
+	Username string
+	Password string
+	From     string        // адрес отправителя, можно с именем: "Polar Lights <noreply@forum.example>"
+	Timeout  time.Duration // наибольшее время отправки одного письма; 0 — без ограничения
+}
+
+// Send отправляет письмо; ответ сервера с ошибкой возвращается как ошибка.
+func (s *SMTP) Send(ctx context.Context, to, subject, body string) error {
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("mailer: sender address: %w", err)
+	}
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("mailer: starttls: %w", err)
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return fmt.Errorf("mailer: auth: %w", err)
+		}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+	if err := c.Rcpt(to); err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+	if _, err := w.Write(message(s.From, to, subject, body, time.Now())); err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+	return c.Quit()
+}
+
+// message собирает текст письма с заголовками. Тема может быть не в ASCII, поэтому кодируется
+// по RFC 2047, а текст передаётся в quoted-printable.
+func message(from, to, subject, body string, date time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&b)
+	qp.Write([]byte(body))
+	qp.Close()
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
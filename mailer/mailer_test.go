@@ -0,0 +1,83 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSend отправляет письмо на поддельный SMTP-сервер и разбирает то, что он получил.
+func TestSend(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 test ESMTP")
+		var rcpt, data string
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+			case "EHLO", "HELO", "MAIL":
+				tp.PrintfLine("250 OK")
+			case "RCPT":
+				rcpt = line
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				b, _ := tp.ReadDotBytes()
+				data = string(b)
+				tp.PrintfLine("250 OK")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				received <- rcpt + "\n" + data
+				return
+			default:
+				tp.PrintfLine("502 unknown")
+			}
+		}
+	}()
+
+	s := New(l.Addr().String(), "", "", "Polar Lights <noreply@forum.example>", 5*time.Second)
+	if err := s.Send(context.Background(), "anna@example.com", "Подтвердите почту", "Откройте ссылку:\nhttps://forum.example/verify-email?token=1.2.abc"); err != nil {
+		t.Fatal(err)
+	}
+	got := <-received
+	rcpt, data, _ := strings.Cut(got, "\n")
+	if rcpt != "RCPT TO:<anna@example.com>" {
+		t.Errorf("RCPT = %q", rcpt)
+	}
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); subject != "Подтвердите почту" {
+		t.Errorf("Subject = %q", subject)
+	}
+	body, _ := io.ReadAll(msg.Body)
+	if !strings.Contains(string(body), "verify-email?token=3D1.2.abc") {
+		t.Errorf("body is not quoted-printable with the link:\n%s", body)
+	}
+
+	if New("", "", "", "noreply@forum.example", 0) != nil {
+		t.Error("New without a server address must return nil")
+	}
+}
@@ -0,0 +1,119 @@
+// Package mailreply превращает ответы на письма-уведомления в комментарии. Письмо об обсуждении
+// отправляется с адресом ответа вида reply+{пост}-{пользователь}-{подпись}@домен; почтовый сервис
+// пересылает пришедший на него ответ вебхуком, а форум публикует текст ответа комментарием к посту.
+//
+// Подпись (HMAC) связывает адрес с постом и пользователем, поэтому адрес нельзя подобрать или
+// переделать под чужой пост или чужое имя, а отправитель письма дополнительно сверяется с почтой пользователя.
+package mailreply
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+)
+
+// prefix начинает локальную часть адреса ответа.
+const prefix = "reply+"
+
+// Addresses строит и проверяет подписанные адреса ответа.
+type Addresses struct {
+	Secret []byte // ключ подписи
+	Domain string // домен адресов ответа, например reply.forum.example.com
+}
+
+// For возвращает адрес, ответ на который станет комментарием пользователя userID к посту postID.
+func (a *Addresses) For(postID, userID int) string {
+	return fmt.Sprintf("%s%d-%d-%s@%s", prefix, postID, userID, a.sign(postID, userID), a.Domain)
+}
+
+// Parse разбирает адрес ответа (можно с именем, как в заголовке To) и проверяет домен и подпись.
+// ok ложно для любого чужого, изменённого или неподписанного адреса.
+func (a *Addresses) Parse(address string) (postID, userID int, ok bool) {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		address = parsed.Address
+	}
+	local, domain, found := strings.Cut(strings.TrimSpace(address), "@")
+	if !found || !strings.EqualFold(domain, a.Domain) || !strings.HasPrefix(strings.ToLower(local), prefix) {
+		return 0, 0, false
+	}
+	parts := strings.Split(local[len(prefix):], "-")
+	if len(parts) != 3 {
+		return 0, 0, false
+	}
+	postID, err1 := strconv.Atoi(parts[0])
+	userID, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || postID <= 0 || userID <= 0 {
+		return 0, 0, false
+	}
+	// Почтовые серверы могут менять регистр локальной части, поэтому подпись сравнивается в нижнем регистре.
+	if !hmac.Equal([]byte(strings.ToLower(parts[2])), []byte(a.sign(postID, userID))) {
+		return 0, 0, false
+	}
+	return postID, userID, true
+}
+
+// Find ищет адрес ответа среди получателей list (значение заголовка To или Cc с несколькими адресами)
+// и возвращает пост и пользователя первого подходящего адреса.
+func (a *Addresses) Find(list string) (postID, userID int, ok bool) {
+	addresses, err := mail.ParseAddressList(list)
+	if err != nil {
+		return a.Parse(list)
+	}
+	for _, addr := range addresses {
+		if postID, userID, ok = a.Parse(addr.Address); ok {
+			return postID, userID, true
+		}
+	}
+	return 0, 0, false
+}
+
+// sign возвращает подпись пары пост — пользователь.
+func (a *Addresses) sign(postID, userID int) string {
+	mac := hmac.New(sha256.New, a.Secret)
+	fmt.Fprintf(mac, "%d:%d", postID, userID)
+	return hex.EncodeToString(mac.Sum(nil)[:10])
+}
+
+// SenderAddress возвращает адрес из заголовка From («Имя <адрес>» или просто адрес).
+func SenderAddress(from string) string {
+	if parsed, err := mail.ParseAddress(from); err == nil {
+		from = parsed.Address
+	}
+	return strings.TrimSpace(from)
+}
+
+// ReplyText оставляет из текста письма только новый ответ: отбрасывает цитату исходного письма
+// (строки «> …» и строку «On … wrote:» перед ними), пересланное исходное сообщение и подпись после «-- ».
+func ReplyText(body string) string {
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+	var kept []string
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if line == "-- " || strings.HasPrefix(trimmed, ">") || isOriginalMessage(trimmed) {
+			break
+		}
+		// «On Mon, 5 Oct 2026, Alice wrote:» бывает перенесена на две строки.
+		if isAttribution(trimmed) || i+1 < len(lines) && isAttribution(trimmed+" "+strings.TrimSpace(lines[i+1])) {
+			break
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// isAttribution распознаёт строку, которой почтовые программы предваряют цитату.
+func isAttribution(line string) bool {
+	lower := strings.ToLower(line)
+	return (strings.HasPrefix(lower, "on ") && strings.HasSuffix(lower, "wrote:")) ||
+		strings.HasSuffix(lower, "написал:") || strings.HasSuffix(lower, "написала:") || strings.HasSuffix(lower, "пишет:")
+}
+
+// isOriginalMessage распознаёт разделитель, после которого Outlook и подобные программы вставляют исходное письмо.
+func isOriginalMessage(line string) bool {
+	lower := strings.ToLower(line)
+	return strings.HasPrefix(lower, "-----original message-----") || strings.HasPrefix(lower, "________________________________")
+}
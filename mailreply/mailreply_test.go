@@ -0,0 +1,51 @@
+package mailreply
+
+import "testing"
+
+// TestAddresses проверяет, что адрес ответа разбирается обратно, а изменённый или чужой адрес отклоняется.
+func TestAddresses(t *testing.T) {
+	a := &Addresses{Secret: []byte("secret"), Domain: "reply.example.com"}
+	addr := a.For(12, 34)
+
+	for _, s := range []string{addr, "Forum <" + addr + ">", "REPLY+" + addr[len(prefix):]} {
+		if post, user, ok := a.Parse(s); !ok || post != 12 || user != 34 {
+			t.Errorf("Parse(%q) = %d, %d, %v", s, post, user, ok)
+		}
+	}
+
+	if post, user, ok := a.Find("Alice <alice@example.com>, Forum <" + addr + ">"); !ok || post != 12 || user != 34 {
+		t.Errorf("Find = %d, %d, %v", post, user, ok)
+	}
+
+	other := &Addresses{Secret: []byte("other"), Domain: "reply.example.com"}
+	for _, s := range []string{
+		"reply+12-35" + addr[len("reply+12-34"):], // подпись адреса другого пользователя
+		other.For(12, 34),
+		"reply+12-34@reply.example.com",
+		addr[:len(addr)-len("example.com")] + "example.org",
+		"alice@example.com",
+	} {
+		if _, _, ok := a.Parse(s); ok {
+			t.Errorf("Parse(%q) accepted a forged address", s)
+		}
+	}
+}
+
+// TestReplyText проверяет, что от письма остаётся только новый текст ответа.
+func TestReplyText(t *testing.T) {
+	tests := []struct {
+		body, want string
+	}{
+		{"Thanks!\r\n\r\nOn Mon, 5 Oct 2026, Forum wrote:\r\n> New comment", "Thanks!"},
+		{"Agreed.\nOn Mon, 5 Oct 2026 at 10:00, Forum <reply@example.com>\nwrote:\n> quoted", "Agreed."},
+		{"Да, согласен.\n\nForum написал:\n> текст", "Да, согласен."},
+		{"Sure\n-- \nAlice", "Sure"},
+		{"Ok\n-----Original Message-----\nFrom: Forum", "Ok"},
+		{"Line one\nline two", "Line one\nline two"},
+	}
+	for _, tt := range tests {
+		if got := ReplyText(tt.body); got != tt.want {
+			t.Errorf("ReplyText(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
@@ -1,10 +1,33 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"forum/accesslog"
+	"forum/botcheck"
+	"forum/cache"
+	"forum/config"
 	"forum/database"
+	"forum/emailverify"
+	"forum/handlers"
+	"forum/imageproxy"
+	"forum/integrations"
+	"forum/jobs"
+	"forum/mailer"
+	"forum/mailreply"
+	"forum/oauth"
+	"forum/proxy"
+	"forum/storage"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 
@@ -12,18 +35,494 @@ import (
 var db *sql.DB
 
 // main инициализирует приложение и запускает сервер.
-// Устанавливает соединение с базой данных, настраивает маршруты и слушает порт 8080.
+// Читает настройки (см. пакет config), устанавливает соединение с базой данных, настраивает маршруты
+// и слушает адрес из настроек (по умолчанию :8080).
+// Если передана подкоманда (например, import), выполняет её вместо запуска сервера.
 func main() {
-	var err error
-	db, err = database.InitDB()
+	cfg, err := config.Load(config.Path())
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	store, err := openStore(cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	db = store.DB
 	defer db.Close()
+	defer database.CloseStatements(db)
+
+	if len(os.Args) > 1 {
+		if store.Dialect != database.DialectSQLite {
+			log.Fatal("Subcommands are only supported with the SQLite backend.")
+		}
+		if err := runCommand(cfg, db, os.Args[1:]); err != nil {
+			log.Println(err)
+			db.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := serve(cfg, store); err != nil {
+		log.Println(err)
+		database.CloseStatements(db)
+		db.Close()
+		os.Exit(1)
+	}
+	log.Println("Server stopped.")
+}
+
+// serve запускает HTTP-сервер (или HTTPS с сервером перенаправления, см. setupTLS) и фоновые задачи и работает до сигнала SIGINT или SIGTERM.
+// При остановке сервер перестаёт принимать соединения и дожидается текущих запросов, затем останавливает
+// фоновые задачи (начатый запуск доводится до конца) и отправку уведомлений. Всё это ограничено
+// server.shutdown_timeout; после возврата соединение с базой можно закрывать.
+// Обработчики с долгими соединениями (SSE, WebSocket) Shutdown не прерывает — их нужно закрывать
+// через RegisterOnShutdown.
+func serve(cfg config.Config, store *database.Store) error {
+	files, err := loadAssets(cfg.Server)
+	if err != nil {
+		return err
+	}
+	if err := handlers.Configure(cfg, files.templates, files.manifest); err != nil {
+		return fmt.Errorf("error loading templates: %w", err)
+	}
+	imgProxy, err := newImageProxy(cfg.Images, store)
+	if err != nil {
+		return fmt.Errorf("error configuring image proxy: %w", err)
+	}
+	handlers.UseImageProxy(imgProxy)
+	replies, err := newReplyAddresses(cfg.Mail, store)
+	if err != nil {
+		return fmt.Errorf("error configuring mail replies: %w", err)
+	}
+	handlers.UseReplyAddresses(replies)
+	registrationForm, err := newRegistrationForm(cfg.Registration, store)
+	if err != nil {
+		return fmt.Errorf("error configuring registration form: %w", err)
+	}
+	handlers.UseRegistrationForm(registrationForm)
+	uploads, err := newStorage(cfg.Uploads)
+	if err != nil {
+		return fmt.Errorf("error configuring uploads: %w", err)
+	}
+	handlers.UseStorage(uploads, int64(cfg.Uploads.MaxSize)<<20)
+	verifyTokens, err := newVerifyTokens(cfg.Registration, store)
+	if err != nil {
+		return fmt.Errorf("error configuring email verification: %w", err)
+	}
+	handlers.UseMail(mailer.New(cfg.Mail.SMTPAddr, cfg.Mail.SMTPUsername, cfg.Mail.SMTPPassword, cfg.Mail.From, cfg.Mail.SendTimeout))
+	handlers.UseEmailVerification(verifyTokens)
+	handlers.UseGitHub(oauth.NewGitHub(cfg.OAuth.GitHubClientID, cfg.OAuth.GitHubClientSecret, &http.Client{Timeout: 10 * time.Second}))
+	tlsCfg, redirect, err := setupTLS(cfg.Server)
+	if err != nil {
+		return err
+	}
+	proxies, err := proxy.New(cfg.Server.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	// Кэширует ленты и посты для анонимных посетителей, если кэш не отключён.
+	if cfg.Cache.TTL > 0 {
+		c, err := openCache(cfg.Cache)
+		if err != nil {
+			return fmt.Errorf("error connecting to cache: %w", err)
+		}
+		if closer, ok := c.(io.Closer); ok {
+			defer closer.Close()
+		}
+		store = store.WithCache(c, cfg.Cache.TTL)
+		log.Printf("Cache enabled (ttl %s).", cfg.Cache.TTL)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Запускает фоновые задачи (очистка сессий, резервное копирование).
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	startJobs(jobsCtx, cfg, store, uploads)
+
+	// Подключает внешние интеграции (Discord, Telegram).
+	notifier := integrations.New(cfg.Server.BaseURL, cfg.Integrations)
+
+	// Настраивает маршруты и обработчик HTTP-запросов; за доверенными прокси адрес клиента берётся из заголовков.
+	handler := securityHeaders(cfg.Security, imgProxy != nil, setupRoutes(files, cfg.Server.Timeouts, store, notifier))
+	if path := cfg.AccessLog.Path; path != "" {
+		accessLog, err := accesslog.Open(path, int64(cfg.AccessLog.MaxSize)<<20, cfg.AccessLog.Rotate, cfg.AccessLog.Keep)
+		if err != nil {
+			return fmt.Errorf("error opening access log: %w", err)
+		}
+		defer accessLog.Close()
+		handler = accesslog.Middleware(accessLog, cfg.AccessLog.Format, handler)
+		log.Printf("Access log (%s) written to %s.", cfg.AccessLog.Format, path)
+	}
+	handler = proxies.Middleware(handler)
+	srv := newServer(cfg.Server.Addr, handler, cfg.Server.Timeouts)
+	srv.TLSConfig = tlsCfg
+	servers := []*http.Server{srv}
+	listenErr := make(chan error, 2)
+	if tlsCfg == nil {
+		go func() {
+			listenErr <- srv.ListenAndServe()
+		}()
+		log.Println("Server started on", cfg.Server.Addr)
+	} else {
+		go func() {
+			listenErr <- srv.ListenAndServeTLS("", "")
+		}()
+		log.Println("Server started with HTTPS on", cfg.Server.Addr)
+		if addr := cfg.Server.TLS.RedirectAddr; addr != "" {
+			redirectSrv := newServer(addr, redirect, cfg.Server.Timeouts)
+			servers = append(servers, redirectSrv)
+			go func() {
+				listenErr <- redirectSrv.ListenAndServe()
+			}()
+			log.Println("Redirecting HTTP to HTTPS on", addr)
+		}
+	}
+
+	select {
+	case err := <-listenErr:
+		for _, s := range servers {
+			s.Close()
+		}
+		return err
+	case <-ctx.Done():
+	}
+	// Повторный сигнал завершает процесс сразу, не дожидаясь остановки.
+	stop()
+	log.Printf("Shutting down (timeout %s)...", cfg.Server.ShutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	var shutdownErr error
+	for _, s := range servers {
+		if err := s.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error draining requests on %s: %v.", s.Addr, err)
+			s.Close()
+			if shutdownErr == nil {
+				shutdownErr = err
+			}
+		}
+	}
+
+	stopJobs()
+	if err := jobs.Wait(shutdownCtx); err != nil {
+		log.Printf("Background jobs did not stop in time: %v.", err)
+		if shutdownErr == nil {
+			shutdownErr = err
+		}
+	}
+	notifier.Wait()
+	if shutdownErr != nil {
+		return fmt.Errorf("shutdown: %w", shutdownErr)
+	}
+	return nil
+}
+
+// openStore подключается к базе данных, выбранной в настройках: SQLite (по умолчанию) или MySQL/MariaDB.
+// Незаданные параметры пула и SQLite берутся из значений по умолчанию пакета database.
+func openStore(cfg config.Database) (*database.Store, error) {
+	switch cfg.Driver {
+	case "sqlite":
+		opts := database.DefaultSQLiteOptions()
+		applyPool(&opts.Pool, cfg)
+		if cfg.SlowQueryThreshold != nil {
+			opts.SlowQueryThreshold = *cfg.SlowQueryThreshold
+		}
+		if cfg.SQLite.Synchronous != "" {
+			opts.Synchronous = cfg.SQLite.Synchronous
+		}
+		opts.CacheSize = cfg.SQLite.CacheSize
+		if cfg.SQLite.BusyTimeout != nil {
+			opts.BusyTimeout = *cfg.SQLite.BusyTimeout
+		}
+		db, err := database.InitDB(cfg.Path, opts)
+		if err != nil {
+			return nil, err
+		}
+		return database.NewSQLiteStore(db), nil
+	case "mysql":
+		opts := database.DefaultMySQLOptions()
+		applyPool(&opts.Pool, cfg)
+		if cfg.SlowQueryThreshold != nil {
+			opts.SlowQueryThreshold = *cfg.SlowQueryThreshold
+		}
+		db, err := database.OpenMySQL(cfg.DSN, opts)
+		if err != nil {
+			return nil, err
+		}
+		return database.NewMySQLStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+	}
+}
+
+// applyPool переносит в pool заданные в настройках параметры пула соединений.
+func applyPool(pool *database.PoolOptions, cfg config.Database) {
+	if cfg.MaxOpenConns != nil {
+		pool.MaxOpenConns = *cfg.MaxOpenConns
+	}
+	if cfg.MaxIdleConns != nil {
+		pool.MaxIdleConns = *cfg.MaxIdleConns
+	}
+	if cfg.ConnMaxLifetime != nil {
+		pool.ConnMaxLifetime = *cfg.ConnMaxLifetime
+	}
+}
+
+// openCache создаёт кэш, выбранный в настройках: память процесса (по умолчанию)
+// или Redis, общий для нескольких экземпляров форума.
+func openCache(cfg config.Cache) (cache.Cache, error) {
+	if cfg.Backend == "redis" {
+		return cache.NewRedis(cfg.RedisURL, "forum:")
+	}
+	return cache.NewMemory(0), nil
+}
+
+// newImageProxy создаёт прокси изображений постов или возвращает nil, если он выключен (images.proxy).
+// Если ключ подписи не задан, берёт его из настроек сайта (см. signingSecret).
+func newImageProxy(cfg config.Images, store *database.Store) (*imageproxy.Proxy, error) {
+	if !cfg.Proxy {
+		return nil, nil
+	}
+	secret, err := signingSecret(store, cfg.ProxySecret, database.SettingImageProxySecret, "Image proxy")
+	if err != nil {
+		return nil, err
+	}
+	var c cache.Cache
+	if cfg.ProxyCacheEntries > 0 {
+		c = cache.NewMemory(cfg.ProxyCacheEntries)
+	}
+	return imageproxy.New(imageproxy.Options{
+		Secret:   []byte(secret),
+		MaxSize:  int64(cfg.ProxyMaxSize) << 20,
+		Timeout:  cfg.ProxyTimeout,
+		CacheTTL: cfg.ProxyCacheTTL,
+		Cache:    c,
+	}), nil
+}
+
+// newReplyAddresses возвращает адреса ответа на письма-уведомления или nil, если приём ответов выключен
+// (mail.reply_domain). Если ключ подписи не задан, берёт его из настроек сайта (см. signingSecret).
+func newReplyAddresses(cfg config.Mail, store *database.Store) (*mailreply.Addresses, error) {
+	if cfg.ReplyDomain == "" {
+		return nil, nil
+	}
+	secret, err := signingSecret(store, cfg.ReplySecret, database.SettingMailReplySecret, "Mail reply")
+	if err != nil {
+		return nil, err
+	}
+	return &mailreply.Addresses{Secret: []byte(secret), Domain: cfg.ReplyDomain}, nil
+}
+
+// newRegistrationForm возвращает проверку меток времени формы регистрации.
+// Если ключ подписи не задан, берёт его из настроек сайта (см. signingSecret).
+func newRegistrationForm(cfg config.Registration, store *database.Store) (*botcheck.Form, error) {
+	secret, err := signingSecret(store, cfg.FormSecret, database.SettingRegistrationFormSecret, "Registration form")
+	if err != nil {
+		return nil, err
+	}
+	return &botcheck.Form{Secret: []byte(secret), MinDelay: cfg.MinFillTime, MaxAge: cfg.FormMaxAge}, nil
+}
+
+// newVerifyTokens возвращает подпись ссылок подтверждения почты или nil, если подтверждение выключено.
+// Если ключ подписи не задан, берёт его из настроек сайта (см. signingSecret).
+func newVerifyTokens(cfg config.Registration, store *database.Store) (*emailverify.Tokens, error) {
+	if !cfg.VerifyEmail {
+		return nil, nil
+	}
+	secret, err := signingSecret(store, cfg.VerifySecret, database.SettingEmailVerifySecret, "Email verification")
+	if err != nil {
+		return nil, err
+	}
+	return &emailverify.Tokens{Secret: []byte(secret), MaxAge: cfg.VerifyLinkAge}, nil
+}
+
+// newStorage создаёт хранилище загружаемых изображений или возвращает nil, если загрузка выключена (uploads.max_size: 0).
+func newStorage(cfg config.Uploads) (storage.Storage, error) {
+	if cfg.MaxSize == 0 {
+		return nil, nil
+	}
+	if cfg.Backend == "s3" {
+		return storage.NewS3(storage.S3Options{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			PublicURL: cfg.S3.PublicURL,
+		})
+	}
+	return storage.NewLocal(cfg.Dir)
+}
 
-	// Настраивает маршруты и возвращает обработчик HTTP-запросов.
-	handler := setupRoutes(db)
+// signingSecret возвращает ключ подписи configured, а если он не задан — ключ из настройки сайта setting,
+// при первом запуске создавая и сохраняя его там: с ключом, меняющимся при каждом запуске,
+// ранее выданные подписанные адреса (страницы в кэше браузера, отправленные письма) стали бы недействительными.
+// name называет ключ в журнале.
+func signingSecret(store *database.Store, configured, setting, name string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+	ctx := context.Background()
+	secret, err := store.Settings.GetSetting(ctx, setting)
+	if err != nil || secret != "" {
+		return secret, err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	secret = hex.EncodeToString(b)
+	if err := store.Settings.SetSetting(ctx, setting, secret); err != nil {
+		return "", err
+	}
+	log.Println(name + " signing key generated.")
+	return secret, nil
+}
+
+// notificationCatchUpDays — за сколько последних прошедших дней пересобираются уведомления о лайках.
+const notificationCatchUpDays = 3
+
+// startJobs запускает фоновые задачи сервера с расписанием из настроек:
+// удаление истёкших сессий, окончательное удаление давно удалённых постов и комментариев,
+// сверку счётчиков лайков и комментариев, подборку лучших постов прошлой недели (см. database.EnsureDigest),
+// уведомления авторам о лайках за день (см. database.BuildVoteNotifications) и резервные копии SQLite
+// (если задан backup.dir), а также удаление загруженных изображений, которые больше ни к чему не относятся
+// (если загрузка включена).
+// Обслуживание базы описано в startMaintenance.
+func startJobs(ctx context.Context, cfg config.Config, store *database.Store, uploads storage.Storage) {
+	jobs.Every(ctx, "session-cleanup", cfg.Session.CleanupInterval, func(ctx context.Context) error {
+		deleted, err := store.Users.PurgeExpiredSessions(ctx)
+		if err != nil {
+			return err
+		}
+		pruned := database.PruneSessions(time.Now())
+		log.Printf("Expired sessions removed: %d from database, %d from memory.", deleted, pruned)
+		return nil
+	})
 
-	log.Println("Server started on :8080")
-	log.Fatal(http.ListenAndServe(":8080", handler))
+	retention := cfg.Jobs.DeletedRetention
+	jobs.Every(ctx, "purge-deleted", cfg.Jobs.PurgeInterval, func(ctx context.Context) error {
+		posts, comments, err := store.Posts.PurgeDeletedContent(ctx, retention)
+		if err != nil {
+			return err
+		}
+		log.Printf("Deleted content purged: %d posts, %d comments.", posts, comments)
+		return nil
+	})
+
+	historyRetention := cfg.Jobs.HistoryRetention
+	jobs.Every(ctx, "purge-history", cfg.Jobs.PurgeInterval, func(ctx context.Context) error {
+		views, err := store.Users.PurgeViewHistory(ctx, historyRetention)
+		if err != nil {
+			return err
+		}
+		log.Printf("View history purged: %d views.", views)
+		return nil
+	})
+
+	// Файл остаётся без ссылок, если изображение поста заменили при редактировании, пост окончательно удалили
+	// или пост так и не создали после загрузки. Срок uploadGrace защищает файлы постов, которые ещё сохраняются.
+	if uploads != nil {
+		uploadGrace := cfg.Jobs.UploadGrace
+		jobs.Every(ctx, "upload-gc", cfg.Jobs.UploadGCInterval, func(ctx context.Context) error {
+			removed, err := storage.RemoveOrphans(ctx, uploads, "", time.Now().Add(-uploadGrace), func(ctx context.Context, url string) (bool, error) {
+				return database.UploadReferenced(ctx, store.DB, url)
+			})
+			if removed > 0 {
+				log.Printf("Orphaned uploads removed: %d.", removed)
+			}
+			return err
+		})
+	}
+
+	jobs.Every(ctx, "reconcile-counters", cfg.Jobs.ReconcileInterval, func(ctx context.Context) error {
+		posts, comments, err := database.ReconcileCounters(ctx, store.DB)
+		if err != nil {
+			return err
+		}
+		if posts > 0 || comments > 0 {
+			log.Printf("Counters reconciled: %d posts, %d comments had drifted.", posts, comments)
+		}
+		return nil
+	})
+
+	jobs.Every(ctx, "weekly-digest", cfg.Jobs.DigestInterval, func(ctx context.Context) error {
+		week := database.DigestWeek(time.Now()).AddDate(0, 0, -7)
+		_, created, err := database.EnsureDigest(ctx, store.DB, week, database.DigestSize)
+		if created {
+			log.Printf("Weekly digest for %s saved.", week.Format(database.DigestWeekFormat))
+		}
+		return err
+	})
+
+	// Лайки собираются в уведомления за прошедшие дни целиком. Повторная сборка дня ничего не дублирует,
+	// поэтому пересобираются несколько последних дней — на случай, если сервер был остановлен.
+	jobs.Every(ctx, "vote-notifications", cfg.Jobs.NotificationInterval, func(ctx context.Context) error {
+		now := time.Now()
+		for days := notificationCatchUpDays; days >= 1; days-- {
+			day := now.AddDate(0, 0, -days)
+			created, err := database.BuildVoteNotifications(ctx, store.DB, day)
+			if err != nil {
+				return err
+			}
+			if created > 0 {
+				log.Printf("Vote notifications for %s: %d created.", day.Format(database.NotificationDayFormat), created)
+			}
+		}
+		return nil
+	})
+
+	if dir := cfg.Backup.Dir; dir != "" {
+		if store.Dialect != database.DialectSQLite {
+			log.Println("backup.dir is ignored: scheduled backups are only supported with the SQLite backend.")
+		} else {
+			keep := cfg.Backup.Keep
+			jobs.Every(ctx, "backup", cfg.Backup.Interval, func(ctx context.Context) error {
+				path, err := database.WriteRotatingBackup(ctx, store.DB, dir, keep)
+				if err == nil {
+					log.Println("Backup written:", path)
+				}
+				return err
+			})
+		}
+	}
+
+	startMaintenance(ctx, cfg.Jobs, store)
+}
+
+// startMaintenance запускает обновление статистики планировщика (PRAGMA optimize или ANALYZE TABLE).
+// Если задано окно обслуживания (например, 03:00-05:00 по местному времени), обслуживание выполняется
+// раз в сутки внутри окна, а при maintenance_vacuum дополнительно выполняется VACUUM.
+// Без окна статистика обновляется каждые maintenance_interval, а VACUUM не выполняется, так как блокирует запись.
+func startMaintenance(ctx context.Context, cfg config.Jobs, store *database.Store) {
+	maintain := func(vacuum bool) jobs.Func {
+		return func(ctx context.Context) error {
+			if err := database.Optimize(ctx, store.DB, store.Dialect); err != nil {
+				return fmt.Errorf("optimize: %w", err)
+			}
+			if !vacuum {
+				return nil
+			}
+			if err := database.Vacuum(ctx, store.DB, store.Dialect); err != nil {
+				return fmt.Errorf("vacuum: %w", err)
+			}
+			return nil
+		}
+	}
+
+	if cfg.MaintenanceWindow != "" {
+		// Окно уже проверено в config.Validate.
+		window, _ := jobs.ParseWindow(cfg.MaintenanceWindow)
+		jobs.Daily(ctx, "maintenance", window, maintain(cfg.MaintenanceVacuum))
+		return
+	}
+	if cfg.MaintenanceVacuum {
+		log.Println("maintenance_vacuum is ignored: VACUUM only runs inside the maintenance window.")
+	}
+	jobs.Every(ctx, "maintenance", cfg.MaintenanceInterval, maintain(false))
 }
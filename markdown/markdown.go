@@ -0,0 +1,182 @@
+// Package markdown переводит в HTML подмножество Markdown, которого достаточно для служебных страниц
+// (правила, о проекте, FAQ): заголовки, абзацы, списки, цитаты, блоки кода, разделители,
+// а внутри строк — жирный и курсивный текст, код и ссылки.
+//
+// Исходный HTML не пропускается: весь текст экранируется, а ссылки допускаются только
+// на http(s), mailto и адреса внутри сайта, поэтому результат можно вставлять в шаблон без очистки.
+package markdown
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+var (
+	headingPattern   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	bulletPattern    = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedPattern   = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	rulePattern      = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	safeLinkPrefixes = []string{"http://", "https://", "mailto:", "/", "#"}
+)
+
+// Render переводит текст src в HTML.
+func Render(src string) template.HTML {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var b strings.Builder
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			i++
+		case strings.HasPrefix(trimmed, "```"):
+			i++
+			var code []string
+			for i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```") {
+				code = append(code, lines[i])
+				i++
+			}
+			i++ // закрывающая ```
+			b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+		case headingPattern.MatchString(trimmed):
+			m := headingPattern.FindStringSubmatch(trimmed)
+			level := string(rune('0' + len(m[1])))
+			b.WriteString("<h" + level + ">" + inline(m[2]) + "</h" + level + ">\n")
+			i++
+		case rulePattern.MatchString(trimmed):
+			b.WriteString("<hr>\n")
+			i++
+		case strings.HasPrefix(trimmed, ">"):
+			var quote []string
+			for i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">") {
+				quote = append(quote, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+				i++
+			}
+			b.WriteString("<blockquote>\n" + string(Render(strings.Join(quote, "\n"))) + "</blockquote>\n")
+		case bulletPattern.MatchString(line):
+			i = list(&b, lines, i, "ul", bulletPattern)
+		case orderedPattern.MatchString(line):
+			i = list(&b, lines, i, "ol", orderedPattern)
+		default:
+			var para []string
+			for i < len(lines) && startsParagraphLine(lines[i]) {
+				para = append(para, strings.TrimSpace(lines[i]))
+				i++
+			}
+			b.WriteString("<p>" + inline(strings.Join(para, " ")) + "</p>\n")
+		}
+	}
+	return template.HTML(b.String())
+}
+
+// startsParagraphLine сообщает, продолжает ли строка абзац: пустые строки и начала других блоков его завершают.
+func startsParagraphLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" &&
+		!strings.HasPrefix(trimmed, "```") &&
+		!strings.HasPrefix(trimmed, ">") &&
+		!headingPattern.MatchString(trimmed) &&
+		!rulePattern.MatchString(trimmed) &&
+		!bulletPattern.MatchString(line) &&
+		!orderedPattern.MatchString(line)
+}
+
+// list выводит подряд идущие пункты списка tag, начиная со строки i, и возвращает номер первой строки после списка.
+func list(b *strings.Builder, lines []string, i int, tag string, item *regexp.Regexp) int {
+	b.WriteString("<" + tag + ">\n")
+	for i < len(lines) {
+		m := item.FindStringSubmatch(lines[i])
+		if m == nil {
+			break
+		}
+		b.WriteString("<li>" + inline(strings.TrimSpace(m[1])) + "</li>\n")
+		i++
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// inline переводит разметку внутри строки: `код`, **жирный**, *курсив* и [текст](адрес).
+// Всё остальное экранируется; незакрытая разметка выводится как обычный текст.
+func inline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				b.WriteString("<code>" + html.EscapeString(s[i+1:i+1+end]) + "</code>")
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(s[i:], "**"):
+			if inner, ok := delimited(s[i:], "**"); ok {
+				b.WriteString("<strong>" + inline(inner) + "</strong>")
+				i += len(inner) + 4
+				continue
+			}
+		case s[i] == '*' || s[i] == '_' && (i == 0 || !isWordByte(s[i-1])):
+			if inner, ok := delimited(s[i:], s[i:i+1]); ok {
+				b.WriteString("<em>" + inline(inner) + "</em>")
+				i += len(inner) + 2
+				continue
+			}
+		case s[i] == '[':
+			if text, href, n, ok := link(s[i:]); ok {
+				b.WriteString(`<a href="` + html.EscapeString(href) + `">` + inline(text) + "</a>")
+				i += n
+				continue
+			}
+		}
+		b.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+	return b.String()
+}
+
+// delimited возвращает текст между разделителем delim в начале s и его закрывающей парой.
+// Как в CommonMark, текст не может начинаться или заканчиваться пробелом, поэтому «2 * 3» остаётся текстом.
+func delimited(s, delim string) (string, bool) {
+	rest := s[len(delim):]
+	if rest == "" || rest[0] == ' ' {
+		return "", false
+	}
+	for from := 0; ; {
+		end := strings.Index(rest[from:], delim)
+		if end < 0 {
+			return "", false
+		}
+		end += from
+		if end > 0 && rest[end-1] != ' ' {
+			return rest[:end], true
+		}
+		from = end + 1
+	}
+}
+
+// isWordByte сообщает, является ли байт буквой или цифрой ASCII: подчёркивание внутри слова (snake_case) не выделяет текст.
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// link разбирает ссылку [текст](адрес) в начале s и возвращает её части и длину.
+// Ссылки с другими схемами (например, javascript:) не распознаются и выводятся как текст.
+func link(s string) (text, href string, n int, ok bool) {
+	closeText := strings.Index(s, "](")
+	if closeText < 0 {
+		return "", "", 0, false
+	}
+	closeHref := strings.IndexByte(s[closeText+2:], ')')
+	if closeHref < 0 {
+		return "", "", 0, false
+	}
+	text = s[1:closeText]
+	href = strings.TrimSpace(s[closeText+2 : closeText+2+closeHref])
+	for _, prefix := range safeLinkPrefixes {
+		if strings.HasPrefix(strings.ToLower(href), prefix) && !strings.HasPrefix(href, "//") {
+			return text, href, closeText + 3 + closeHref, true
+		}
+	}
+	return "", "", 0, false
+}
@@ -0,0 +1,29 @@
+package markdown
+
+import "testing"
+
+// TestRender проверяет блоки, разметку внутри строк и экранирование небезопасного ввода.
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"heading", "## Rules ##", "<h2>Rules</h2>\n"},
+		{"paragraph", "first line\nsecond line\n\nnext", "<p>first line second line</p>\n<p>next</p>\n"},
+		{"inline", "**bold** and *em* with `a<b>`", "<p><strong>bold</strong> and <em>em</em> with <code>a&lt;b&gt;</code></p>\n"},
+		{"lists", "- one\n- two\n\n1. first\n2) second", "<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n"},
+		{"quote", "> be kind", "<blockquote>\n<p>be kind</p>\n</blockquote>\n"},
+		{"code block", "```\n<script>\n```", "<pre><code>&lt;script&gt;</code></pre>\n"},
+		{"rule", "---", "<hr>\n"},
+		{"link", "[FAQ](/pages/faq) and [site](https://example.com)", `<p><a href="/pages/faq">FAQ</a> and <a href="https://example.com">site</a></p>` + "\n"},
+		{"unsafe link", "[x](javascript:alert(1))", "<p>[x](javascript:alert(1))</p>\n"},
+		{"protocol-relative link", "[x](//evil.example)", "<p>[x](//evil.example)</p>\n"},
+		{"raw html", `<img src=x onerror="alert(1)">`, "<p>&lt;img src=x onerror=&#34;alert(1)&#34;&gt;</p>\n"},
+		{"underscores", "_em_ but snake_case_name", "<p><em>em</em> but snake_case_name</p>\n"},
+		{"unclosed", "2 * 3 and **open", "<p>2 * 3 and **open</p>\n"},
+	}
+	for _, tt := range tests {
+		if got := string(Render(tt.src)); got != tt.want {
+			t.Errorf("%s: Render(%q) = %q, want %q", tt.name, tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"forum/handlers"
+)
+
+// methods сопоставляет методы HTTP обработчикам одного маршрута.
+// На HEAD отвечает обработчик GET, если отдельного нет; на остальные методы отвечает 405
+// с заголовком Allow и телом в формате, который принимает клиент (см. handlers.MethodNotAllowed).
+type methods map[string]http.Handler
+
+// ServeHTTP передаёт запрос обработчику его метода.
+func (m methods) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h, ok := m[r.Method]
+	if !ok && r.Method == http.MethodHead {
+		h, ok = m[http.MethodGet]
+	}
+	if !ok {
+		w.Header().Set("Allow", m.allow())
+		handlers.MethodNotAllowed(w, r)
+		return
+	}
+	h.ServeHTTP(w, r)
+}
+
+// allow возвращает список разрешённых методов для заголовка Allow.
+func (m methods) allow() string {
+	list := make([]string, 0, len(m)+1)
+	for method := range m {
+		list = append(list, method)
+	}
+	if _, ok := m[http.MethodGet]; ok {
+		if _, ok := m[http.MethodHead]; !ok {
+			list = append(list, http.MethodHead)
+		}
+	}
+	slices.Sort(list)
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"forum/config"
+	"forum/handlers"
+)
+
+// TestMethods проверяет выбор обработчика по методу и единообразный ответ 405.
+func TestMethods(t *testing.T) {
+	cfg := config.Default()
+	files, err := loadAssets(cfg.Server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handlers.Configure(cfg, files.templates, files.manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method))
+	})
+	m := methods{"GET": ok, "DELETE": ok}
+
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodDelete} {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(method, "/post/1", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: got %d, want 200", method, w.Code)
+		}
+	}
+
+	tests := []struct {
+		accept, contentType string
+	}{
+		{"application/json", "application/json"},
+		{"application/json, text/html;q=0.9", "application/json"},
+		{"text/html,application/xhtml+xml,application/json;q=0.8", "text/html; charset=utf-8"},
+		{"*/*", "text/html; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/post/1", nil)
+		r.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Accept %q: got %d, want 405", tt.accept, w.Code)
+		}
+		if got := w.Header().Get("Allow"); got != "DELETE, GET, HEAD" {
+			t.Errorf("Accept %q: Allow = %q", tt.accept, got)
+		}
+		if got := w.Header().Get("Content-Type"); got != tt.contentType {
+			t.Errorf("Accept %q: Content-Type = %q, want %q", tt.accept, got, tt.contentType)
+		}
+		if strings.HasPrefix(tt.contentType, "application/json") {
+			var body struct {
+				Success bool
+				Message string
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil || body.Success || body.Message == "" {
+				t.Errorf("Accept %q: unexpected body %+v (%v)", tt.accept, body, err)
+			}
+		}
+	}
+}
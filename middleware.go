@@ -4,9 +4,13 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"forum/handlers"
+	"forum/proxy"
 	"log"
 	"net/http"
-	"text/template"
+	"runtime/debug"
 )
 
 // CustomHandler обрабатывает HTTP-запросы с перехватом паник и обработкой ошибок 404.
@@ -18,51 +22,82 @@ type CustomHandler struct {
 // ServeHTTP обрабатывает входящий HTTP-запрос.
 // Перехватывает паники, логирует запросы и ответы, возвращает страницу 404, если маршрут не найден.
 func (h *CustomHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Println("Incoming request:", r.Method, r.URL.Path, "from", proxy.ClientIP(r))
+
+	// Если ни один шаблон не подошёл, ответ 404 от ServeMux заменяется стилизованной страницей.
+	_, pattern := h.mux.Handler(r)
+	rr := &responseRecorder{ResponseWriter: w, statusCode: 0, written: false, catchNotFound: pattern == ""}
+
 	defer func() {
-		if rec := recover(); rec != nil {
-			log.Printf("Panic recovered: %v.", rec)
-			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		rec := recover()
+		if rec == nil {
+			return
+		}
+		// http.ErrAbortHandler — намеренный обрыв ответа, сервер обработает его сам.
+		if rec == http.ErrAbortHandler {
+			panic(rec)
+		}
+		errorID := newErrorID()
+		log.Printf("Panic recovered (error %s) on %s %s: %v.\n%s", errorID, r.Method, r.URL.Path, rec, debug.Stack())
+		if rr.written {
+			// Заголовки уже отправлены: дописать страницу ошибки в начатый ответ нельзя.
+			return
+		}
+		// Заголовки обработчика относятся к несостоявшемуся ответу; заголовки безопасности сохраняются.
+		for _, name := range []string{"Content-Type", "Content-Length", "Content-Disposition", "Content-Encoding", "ETag", "Last-Modified"} {
+			w.Header().Del(name)
 		}
+		w.Header().Set("Cache-Control", "no-store")
+		handlers.InternalError(w, r, errorID)
 	}()
 
-	log.Println("Incoming request:", r.Method, r.URL.Path)
-
-	rr := &responseRecorder{ResponseWriter: w, statusCode: 0, written: false}
 	h.mux.ServeHTTP(rr, r)
 
 	log.Println("After mux: statusCode =", rr.statusCode, "written =", rr.written)
 
 	if !rr.written {
 		log.Println("Route not found:", r.URL.Path)
+		w.Header().Del("Content-Type")
 		if r.Method == http.MethodHead {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
 		w.WriteHeader(http.StatusNotFound)
-		tmpl, err := template.ParseFiles("templates/404.html")
-		if err != nil {
-			log.Println("Error parsing 404 template:", err)
-			http.Error(w, "Page not found.", http.StatusNotFound)
-			return
-		}
-		err = tmpl.Execute(w, nil)
-		if err != nil {
+		if err := handlers.Render(w, r, "404.html", nil); err != nil {
 			log.Println("Error executing 404 template:", err)
+			http.Error(w, "Page not found.", http.StatusNotFound)
 		}
 	}
 }
 
+// newErrorID возвращает короткий случайный идентификатор ошибки, который пользователь видит на странице,
+// а администратор находит в журнале.
+func newErrorID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
 // responseRecorder отслеживает статус ответа и факт записи.
 // Используется для определения, был ли отправлен ответ маршрутизатором.
 type responseRecorder struct {
 	http.ResponseWriter
 	statusCode int  // Код статуса ответа.
 	written    bool // Флаг, указывающий, был ли записан ответ.
+
+	catchNotFound bool // Перехватывать ответ 404, чтобы вместо него отрисовать шаблон.
+	discarding    bool // Ответ 404 перехвачен, его тело отбрасывается.
 }
 
 // WriteHeader записывает код статуса ответа.
 // Устанавливает код и флаг written, если ответ ещё не был записан.
 func (rec *responseRecorder) WriteHeader(code int) {
+	if rec.catchNotFound && code == http.StatusNotFound {
+		rec.discarding = true
+		return
+	}
 	if !rec.written {
 		rec.statusCode = code
 		rec.written = true
@@ -73,6 +108,9 @@ func (rec *responseRecorder) WriteHeader(code int) {
 // Write записывает данные в ответ.
 // Устанавливает код 200 и флаг written, если ответ ещё не был записан.
 func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.discarding {
+		return len(b), nil
+	}
 	if !rec.written {
 		rec.statusCode = http.StatusOK
 		rec.ResponseWriter.WriteHeader(http.StatusOK)
@@ -84,3 +122,9 @@ func (rec *responseRecorder) Write(b []byte) (int, error) {
 	}
 	return n, err
 }
+
+// Unwrap возвращает исходный ResponseWriter, чтобы http.ResponseController мог управлять
+// сроками соединения и сбрасывать буфер ответа.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
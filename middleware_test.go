@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"forum/config"
+	"forum/handlers"
+)
+
+// TestPanicRecovery проверяет страницу 500 с идентификатором ошибки и то,
+// что после отправки заголовков ответ не дописывается.
+func TestPanicRecovery(t *testing.T) {
+	cfg := config.Default()
+	files, err := loadAssets(cfg.Server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handlers.Configure(cfg, files.templates, files.manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/early", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		panic("boom")
+	})
+	mux.HandleFunc("/late", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panic("boom")
+	})
+	h := &CustomHandler{mux: mux}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/early", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("early panic: got %d, want 500", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("early panic: Content-Type %q, want text/html", ct)
+	}
+	if !strings.Contains(w.Body.String(), "Error ID:") {
+		t.Errorf("early panic: page has no error ID: %s", w.Body.String())
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/early", nil)
+	r.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	var body struct {
+		ErrorID string `json:"error_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || len(body.ErrorID) != 12 {
+		t.Errorf("json panic: body %q, err %v", w.Body.String(), err)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/late", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("late panic: got %d %q, want untouched response", w.Code, w.Body.String())
+	}
+}
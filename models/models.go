@@ -46,21 +46,35 @@ type Comment struct {
 
 // PostData используется для отображения поста с дополнительной информацией.
 // Содержит данные поста, автора, лайки, дизлайки, комментарии и голос пользователя.
+// CommentCount — число комментариев из счётчика posts.comment_count; в списках постов сами комментарии не загружаются,
+// а LatestComment — последний комментарий с отрывком текста для карточки в ленте (nil, если его нет или он не загружен).
 type PostData struct {
-	ID           int
-	Title        string
-	Content      string
-	CreatedAt    time.Time
-	CreatedAtStr string
-	UserID       int
-	Username     string
-	Likes        int
-	Dislikes     int
-	Comments     []CommentData
-	ImageURL     string
-	Category     string
-	Categories   []string
-	UserVote     int
+	ID            int
+	Title         string
+	Content       string
+	CreatedAt     time.Time
+	CreatedAtStr  string
+	UserID        int
+	Username      string
+	DisplayName   string // отображаемое имя автора; пусто — не задано
+	Likes         int
+	Dislikes      int
+	Comments      []CommentData
+	CommentCount  int
+	LatestComment *CommentData
+	Link          string // адрес карточки в ленте; пусто — страница поста
+	ImageURL      string
+	Category      string
+	Categories    []Category
+	UserVote      int
+	BoardID       int
+	BoardSlug     string
+	BoardName     string
+	Hidden        bool
+	Unread        bool
+	MembersOnly   bool   // пост видят только авторизованные пользователи
+	CommentPolicy string // кто может комментировать: everyone, followers или nobody
+	Unpublished   bool   // автор снял пост с публикации; виден только ему в /my/posts
 }
 
 // CommentData используется для отображения комментария с дополнительной информацией.
@@ -70,6 +84,8 @@ type CommentData struct {
 	PostID       int
 	UserID       int
 	Username     string
+	DisplayName  string // отображаемое имя автора; пусто — не задано
+	PostTitle    string // заголовок поста в списке комментариев пользователя
 	Content      string
 	CreatedAt    time.Time
 	CreatedAtStr string
@@ -89,7 +105,427 @@ type PageData struct {
 	Filter           string
 	Role             string
 	ProfileUsername  string
+	ProfileName      string // отображаемое имя владельца профиля; пусто — не задано
 	ProfileCreatedAt string
+	ProfileTrust     string // уровень доверия владельца профиля: new, basic, member или regular
+	ProfileUserID    int
+	PinnedPostID     int
 	Post             PostData
 	Message          string
+	Boards           []Board
+	Board            Board
+	Categories       []Category // категории с числом постов и последней активностью для навигации
+	Category         Category   // категория, по которой отфильтрована лента; пустая — без фильтра
+	IsModerator      bool
+	ShowHidden       bool
+	Days             int    // лента за последние Days дней; 0 — без ограничения
+	From             string // начало периода ленты ГГГГ-ММ-ДД, как в запросе
+	To               string // конец периода ленты ГГГГ-ММ-ДД включительно, как в запросе
+	Author           string // имя автора, посты которого показывает лента
+	NextPage         string
+	NextFeed         string
+	NextComments     string
+	Series           []Series
+	SeriesNav        SeriesNav
+	Collections      []Collection
+	Search           SearchFilters
+	Leaderboards     Leaderboards
+	ShortLink        ShortLink
+	Shares           int           // сколько раз постом поделились кнопками и короткой ссылкой
+	ThreadSummary    ThreadSummary // сводка обсуждения на странице поста
+	OpenGraph        OpenGraph     // превью ссылки на страницу поста
+	CanSummarize     bool          // показать кнопку «Кратко об обсуждении»
+	FormToken        string        // подписанная метка времени формы регистрации
+	Invite           string        // код приглашения в форме регистрации
+	InviteOnly       bool          // регистрация только по приглашениям
+	CommentBlock     string        // почему пользователь не может комментировать пост; пусто — может
+	EmailUnverified  bool          // пользователь не подтвердил почту и не может публиковать
+	Following        bool          // пользователь подписан на владельца профиля
+	Followers        int           // число подписчиков владельца профиля
+	LikesHidden      bool          // владелец своего профиля скрывает себя в списках лайкнувших
+	Site             Site          // название, логотип и ссылки подвала; заполняется при выводе страницы
+}
+
+// SetSite задаёт оформление сайта для страницы; через него handlers.Render заполняет поле Site
+// любых данных страницы, в которые встроен PageData.
+func (p *PageData) SetSite(site Site) {
+	p.Site = site
+}
+
+// Site — оформление сайта, которое администратор задаёт в панели: название, описание под ним,
+// логотип и ссылки подвала. Пустые поля на страницах заменяются оформлением по умолчанию.
+type Site struct {
+	Title       string
+	Description string
+	Logo        string // адрес изображения логотипа
+	Footer      string // строка подвала
+	FooterLinks []FooterLink
+}
+
+// FooterLink — ссылка в подвале сайта.
+type FooterLink struct {
+	Label string
+	URL   string
+}
+
+// OpenGraph — описание страницы для превью ссылок в соцсетях и мессенджерах (метки Open Graph и Twitter Card).
+type OpenGraph struct {
+	Title       string
+	Description string
+	URL         string // полный адрес страницы
+	Image       string // полный адрес изображения; пусто — превью без картинки
+	Author      string
+	Published   string // время публикации в RFC 3339
+}
+
+// SearchFilters — разобранный запрос поиска /search (см. database.ParseSearchQuery).
+// Значения фильтров хранятся так, как их ввёл пользователь, чтобы форма поиска показывала текущий запрос.
+type SearchFilters struct {
+	Text       string   // слова и фразы без фильтров, как в запросе
+	Terms      []string // слова и фразы из Text без операторов и скобок
+	Author     string
+	Categories []string
+	After      string   // ГГГГ-ММ-ДД: посты, созданные в этот день и позже
+	Before     string   // ГГГГ-ММ-ДД: посты, созданные раньше этого дня
+	MinScore   string   // наименьший рейтинг (лайки минус дизлайки)
+	Invalid    []string // фильтры с неверным значением; при поиске не учитываются
+}
+
+// Empty сообщает, что в запросе нет ни слов, ни фильтров.
+func (f SearchFilters) Empty() bool {
+	return len(f.Terms) == 0 && f.Author == "" && len(f.Categories) == 0 && f.After == "" && f.Before == "" && f.MinScore == ""
+}
+
+// HasCategory сообщает, выбрана ли категория slug.
+func (f SearchFilters) HasCategory(slug string) bool {
+	for _, c := range f.Categories {
+		if c == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// Category — категория постов со значком и цветом, которыми её метка выделяется в списках.
+// Значок и цвет задаёт администратор; пустые значения означают оформление по умолчанию.
+type Category struct {
+	ID           int
+	Name         string
+	Icon         string
+	Color        string
+	PostCount    int       // число опубликованных постов в категории
+	LastActivity time.Time // время последнего поста или комментария в категории; нулевое — активности не было
+}
+
+// Series — упорядоченная серия постов одного автора, например урок из нескольких частей.
+type Series struct {
+	ID        int
+	UserID    int
+	Username  string
+	Title     string
+	CreatedAt time.Time
+	Parts     []SeriesPart
+}
+
+// SeriesPart — пост в серии.
+type SeriesPart struct {
+	PostID    int
+	Title     string
+	CreatedAt time.Time
+}
+
+// SeriesNav описывает место поста в серии для навигации на странице поста.
+// Нулевой SeriesID означает, что пост не входит в серию.
+type SeriesNav struct {
+	SeriesID int
+	Title    string
+	Part     int
+	Total    int
+	Prev     *SeriesPart
+	Next     *SeriesPart
+}
+
+// Board представляет раздел форума со своими постами, описанием и модераторами.
+// Категории остаются метками внутри разделов.
+type Board struct {
+	ID          int
+	Slug        string
+	Name        string
+	Description string
+	PostCount   int
+	Moderators  []string
+}
+
+// Page — служебная страница сайта (правила, о проекте, FAQ) с текстом в Markdown, доступная по адресу /pages/{slug}.
+type Page struct {
+	Slug      string
+	Title     string
+	Content   string
+	UpdatedAt time.Time
+}
+
+// PostSummary используется в облегчённых JSON-списках для мобильного клиента.
+// Содержит только идентификатор, заголовок, отрывок текста, рейтинг и число комментариев.
+type PostSummary struct {
+	ID           int    `json:"id"`
+	Title        string `json:"title"`
+	Excerpt      string `json:"excerpt"`
+	Score        int    `json:"score"`
+	CommentCount int    `json:"comment_count"`
+}
+
+// LeaderboardEntry — строка таблицы лидеров: пользователь и его показатель (число постов, лайков или рейтинг).
+type LeaderboardEntry struct {
+	UserID   int
+	Username string
+	Value    int
+}
+
+// Leaderboards — таблицы лидеров за один период (см. database.GetLeaderboards).
+type Leaderboards struct {
+	Posters    []LeaderboardEntry
+	Liked      []LeaderboardEntry
+	Commenters []LeaderboardEntry
+}
+
+// VoteSuspect — автор, за посты и комментарии которого подозрительно часто голосуют новые аккаунты.
+type VoteSuspect struct {
+	UserID   int
+	Username string
+	Likes    int // лайков от новых аккаунтов
+	Dislikes int // дизлайков от новых аккаунтов
+	Voters   int // разных новых аккаунтов среди голосовавших
+	Percent  int // доля голосов новых аккаунтов среди всех голосов за автора, в процентах
+}
+
+// VoteRing — два пользователя, которые часто ставят лайки друг другу.
+type VoteRing struct {
+	UserID     int
+	Username   string
+	OtherID    int
+	OtherName  string
+	Likes      int // лайков от UserID пользователю OtherID
+	OtherLikes int // лайков от OtherID пользователю UserID
+}
+
+// VoteReport — подозрительные голоса за период (см. database.GetVoteReport).
+type VoteReport struct {
+	NewAccountVotes []VoteSuspect
+	Rings           []VoteRing
+}
+
+// ActivityDay — активность пользователя за один день для тепловой карты профиля.
+type ActivityDay struct {
+	Date     string `json:"date"` // ГГГГ-ММ-ДД
+	Posts    int    `json:"posts"`
+	Comments int    `json:"comments"`
+	Count    int    `json:"count"` // посты и комментарии вместе
+}
+
+// CommentSummary используется в JSON-списках комментариев для мобильного клиента.
+type CommentSummary struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	Likes     int       `json:"likes"`
+	Dislikes  int       `json:"dislikes"`
+	UserVote  int       `json:"user_vote"`
+}
+
+// Collection — именованная подборка закладок пользователя. Непустой ShareToken означает,
+// что подборка открыта для чтения всем по ссылке /shared/{ShareToken}.
+// Saved отмечает, что в подборке уже есть пост, для которого составлен список.
+type Collection struct {
+	ID         int
+	UserID     int
+	Username   string
+	Name       string
+	ShareToken string
+	CreatedAt  time.Time
+	ItemCount  int
+	Saved      bool
+	Items      []CollectionItem
+}
+
+// CollectionItem — пост в подборке закладок.
+type CollectionItem struct {
+	PostID   int
+	Title    string
+	Username string
+	SavedAt  time.Time
+}
+
+// Digest — снимок лучших постов недели по категориям (см. database.BuildDigest).
+// Снимок сохраняется один раз после окончания недели и дальше не меняется, поэтому прошлые недели
+// показываются такими, какими были на момент подведения итогов.
+type Digest struct {
+	Week       string // ГГГГ-ММ-ДД: понедельник недели
+	Start      time.Time
+	End        time.Time
+	CreatedAt  time.Time
+	Categories []DigestCategory
+}
+
+// DigestCategory — лучшие посты недели в одной категории.
+type DigestCategory struct {
+	Name  string
+	Posts []DigestPost
+}
+
+// DigestPost — пост в недельной подборке с рейтингом и числом комментариев на момент снимка.
+type DigestPost struct {
+	ID       int
+	Title    string
+	UserID   int
+	Username string
+	Score    int
+	Comments int
+}
+
+// HistoryEntry — пост в истории просмотров пользователя (/history).
+type HistoryEntry struct {
+	PostID   int
+	Title    string
+	UserID   int
+	Username string
+	ViewedAt time.Time
+}
+
+// Liker — пользователь в списке лайкнувших пост или комментарий.
+type Liker struct {
+	UserID      int
+	Username    string
+	DisplayName string // отображаемое имя; пусто — не задано
+}
+
+// Likers — страница списка лайкнувших пост или комментарий (/post/{id}/likes, /comment/{id}/likes).
+type Likers struct {
+	PostID int     // пост, к которому относится элемент (для комментария — пост комментария)
+	Users  []Liker // от последних лайков к первым
+	Hidden int     // сколько лайкнувших скрывают себя в таких списках; в Users не попадают
+}
+
+// Invite — приглашение на регистрацию. Invitee пусто, пока по приглашению никто не зарегистрировался.
+type Invite struct {
+	Code      string
+	CreatedAt time.Time
+	InviteeID int
+	Invitee   string
+	Used      bool
+}
+
+// InviteNode — пользователь в дереве приглашений с теми, кто зарегистрировался по его приглашениям.
+type InviteNode struct {
+	UserID   int
+	Username string
+	Children []InviteNode
+}
+
+// ShortLink — короткая ссылка /s/{code} на пост для чатов и печатных материалов со счётчиком переходов.
+type ShortLink struct {
+	Code   string
+	PostID int
+	Clicks int
+}
+
+// UserStanding — показатели пользователя, от которых зависят его права и уровень доверия
+// (см. database.GetUserStanding и permissions.Standing).
+type UserStanding struct {
+	Karma     int
+	CreatedAt time.Time
+	Read      int // прочитанных постов
+	Posts     int // неудалённых постов
+	Comments  int // неудалённых комментариев
+	Role      string
+}
+
+// ShareReport — сколько раз делились постами за период: по способам, по дням и самые распространяемые посты
+// (см. database.GetShareReport).
+type ShareReport struct {
+	Total    int
+	Channels []ShareCount // способы по убыванию числа
+	Days     []ShareDay   // дни с переходами по порядку
+	Posts    []SharedPost
+}
+
+// ShareCount — сколько раз делились способом Channel: кнопкой соцсети или короткой ссылкой (short_link).
+type ShareCount struct {
+	Channel string
+	Count   int
+}
+
+// ShareDay — сколько раз делились постами за день Day (ГГГГ-ММ-ДД, UTC).
+type ShareDay struct {
+	Day   string
+	Count int
+}
+
+// SharedPost — пост и сколько раз им поделились за период отчёта.
+type SharedPost struct {
+	PostID int
+	Title  string
+	Count  int
+}
+
+// PostTranslation — машинный перевод поста на язык Lang, сохранённый в базе.
+// SourceHash — отпечаток заголовка и текста, с которых сделан перевод (см. translate.SourceHash).
+type PostTranslation struct {
+	PostID     int
+	Lang       string
+	SourceHash string
+	Title      string
+	Content    string
+	Provider   string
+	CreatedAt  time.Time
+}
+
+// ModerationDecision — решение автоматической проверки поста или комментария (см. пакет screening)
+// вместе с решением модератора, если содержимое было скрыто до проверки.
+type ModerationDecision struct {
+	ID         int
+	Kind       string // post или comment
+	ItemID     int
+	UserID     int
+	Username   string
+	Provider   string
+	Score      float64
+	Flagged    bool
+	Categories string // названия нарушений через запятую
+	Status     string // allowed, pending, approved или rejected
+	CreatedAt  time.Time
+	ReviewedBy string // имя модератора; пусто, если решения ещё нет
+	ReviewedAt time.Time
+	PostID     int    // пост или пост комментария
+	Title      string // заголовок поста
+	Content    string // текст поста или комментария
+	BoardName  string
+}
+
+// Notification — уведомление автору: сколько лайков получил его пост или комментарий за день
+// (см. database.BuildVoteNotifications).
+type Notification struct {
+	ID        int
+	Kind      string // post_likes или comment_likes
+	ItemID    int    // пост или комментарий
+	PostID    int    // пост или пост комментария
+	Title     string // заголовок поста
+	Day       string // ГГГГ-ММ-ДД: день, за который посчитаны лайки
+	Likes     int
+	CreatedAt time.Time
+	Read      bool
+	Link      string // адрес поста или комментария; заполняет обработчик
+}
+
+// ThreadSummary — сводка обсуждения поста языковой моделью (см. пакет summarize).
+// Сводка действительна, пока в обсуждении те же комментарии, что и при её создании:
+// CommentCount и LastCommentID запоминают их число и последний из них.
+type ThreadSummary struct {
+	PostID        int
+	Summary       string // пусто — сводки нет или она устарела
+	Provider      string
+	CommentCount  int
+	LastCommentID int
+	CreatedAt     time.Time
 }
@@ -0,0 +1,161 @@
+// Package oauth реализует вход через внешних провайдеров по OAuth 2.0 (authorization code flow).
+// Пока поддерживается GitHub: пользователь подтверждает доступ на github.com, форум обменивает полученный код
+// на токен и читает по нему профиль. Защиту от подделки запроса (параметр state) обеспечивает вызывающий код.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ProviderGitHub — имя провайдера в таблице oauth_accounts.
+const ProviderGitHub = "github"
+
+// User — профиль пользователя у провайдера.
+type User struct {
+	ID    string // постоянный идентификатор у провайдера; логин может смениться
+	Login string
+	Name  string
+	Email string // подтверждённый основной адрес; пусто — провайдер его не сообщил
+}
+
+// GitHub — клиент OAuth-приложения GitHub. Адреса можно заменить для GitHub Enterprise и тестов.
+type GitHub struct {
+	ClientID     string
+	ClientSecret string
+	AuthURL      string // страница подтверждения доступа; пусто — https://github.com/login/oauth/authorize
+	TokenURL     string // обмен кода на токен; пусто — https://github.com/login/oauth/access_token
+	APIURL       string // REST API; пусто — https://api.github.com
+	Client       *http.Client
+}
+
+// NewGitHub возвращает клиент приложения clientID или nil, если clientID пуст: вход через GitHub выключен.
+func NewGitHub(clientID, clientSecret string, client *http.Client) *GitHub {
+	if clientID == "" {
+		return nil
+	}
+	return &GitHub{ClientID: clientID, ClientSecret: clientSecret, Client: client}
+}
+
+// AuthCodeURL возвращает адрес страницы GitHub, куда отправляется пользователь. После подтверждения GitHub
+// вернёт его на redirectURI с параметрами code и state. Запрашивается только чтение адресов почты.
+func (g *GitHub) AuthCodeURL(state, redirectURI string) string {
+	q := url.Values{
+		"client_id":    {g.ClientID},
+		"redirect_uri": {redirectURI},
+		"scope":        {"user:email"},
+		"state":        {state},
+		"allow_signup": {"true"},
+	}
+	return or(g.AuthURL, "https://github.com/login/oauth/authorize") + "?" + q.Encode()
+}
+
+// Exchange обменивает код подтверждения на токен доступа.
+func (g *GitHub) Exchange(ctx context.Context, code, redirectURI string) (string, error) {
+	form := url.Values{
+		"client_id":     {g.ClientID},
+		"client_secret": {g.ClientSecret},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, or(g.TokenURL, "https://github.com/login/oauth/access_token"),
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	var resp struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := g.do(req, &resp); err != nil {
+		return "", err
+	}
+	// GitHub сообщает об ошибке обмена (например, просроченном коде) в теле ответа с кодом 200.
+	if resp.Error != "" {
+		return "", fmt.Errorf("oauth: github token: %s: %s", resp.Error, resp.ErrorDescription)
+	}
+	if resp.AccessToken == "" {
+		return "", errors.New("oauth: github token: empty access token")
+	}
+	return resp.AccessToken, nil
+}
+
+// User возвращает профиль владельца токена. Адрес из профиля бывает скрыт, поэтому почта берётся
+// из списка адресов: основной подтверждённый.
+func (g *GitHub) User(ctx context.Context, token string) (User, error) {
+	var profile struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := g.api(ctx, token, "/user", &profile); err != nil {
+		return User{}, err
+	}
+	if profile.ID == 0 || profile.Login == "" {
+		return User{}, errors.New("oauth: github user: empty profile")
+	}
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := g.api(ctx, token, "/user/emails", &emails); err != nil {
+		return User{}, err
+	}
+	u := User{ID: strconv.FormatInt(profile.ID, 10), Login: profile.Login, Name: profile.Name}
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			u.Email = e.Email
+		}
+	}
+	return u, nil
+}
+
+// api выполняет GET-запрос path к REST API с токеном token и разбирает JSON-ответ в v.
+func (g *GitHub) api(ctx context.Context, token, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(or(g.APIURL, "https://api.github.com"), "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return g.do(req, v)
+}
+
+// do выполняет запрос и разбирает JSON-ответ с кодом 200 в v.
+func (g *GitHub) do(req *http.Request, v interface{}) error {
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("oauth: github %s: %s %s", req.URL.Path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("oauth: github %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
+
+func or(value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,65 @@
+package oauth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestGitHub проверяет адрес подтверждения, обмен кода на токен и чтение профиля на поддельном сервере GitHub.
+func TestGitHub(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login/oauth/access_token":
+			r.ParseForm()
+			if r.Form.Get("client_secret") != "secret" || r.Form.Get("code") != "good" {
+				io.WriteString(w, `{"error":"bad_verification_code","error_description":"The code is incorrect or expired."}`)
+				return
+			}
+			io.WriteString(w, `{"access_token":"token","token_type":"bearer"}`)
+		case "/user":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+				return
+			}
+			io.WriteString(w, `{"id":42,"login":"octocat","name":"The Octocat","email":null}`)
+		case "/user/emails":
+			io.WriteString(w, `[{"email":"old@example.com","primary":false,"verified":true},
+				{"email":"octo@example.com","primary":true,"verified":true}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	g := NewGitHub("id", "secret", nil)
+	g.AuthURL, g.TokenURL, g.APIURL = server.URL+"/login/oauth/authorize", server.URL+"/login/oauth/access_token", server.URL
+	u, err := url.Parse(g.AuthCodeURL("xyz", "https://forum.example/auth/github/callback"))
+	if err != nil || u.Query().Get("state") != "xyz" || u.Query().Get("client_id") != "id" ||
+		u.Query().Get("redirect_uri") != "https://forum.example/auth/github/callback" {
+		t.Errorf("AuthCodeURL = %v, %v", u, err)
+	}
+
+	ctx := context.Background()
+	if _, err := g.Exchange(ctx, "bad", ""); err == nil || !strings.Contains(err.Error(), "bad_verification_code") {
+		t.Errorf("Exchange with a bad code: err = %v", err)
+	}
+	token, err := g.Exchange(ctx, "good", "")
+	if err != nil || token != "token" {
+		t.Fatalf("Exchange = %q, %v", token, err)
+	}
+	user, err := g.User(ctx, token)
+	if err != nil || user != (User{ID: "42", Login: "octocat", Name: "The Octocat", Email: "octo@example.com"}) {
+		t.Errorf("User = %+v, %v", user, err)
+	}
+	if _, err := g.User(ctx, "other"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("User with a bad token: err = %v", err)
+	}
+	if NewGitHub("", "secret", nil) != nil {
+		t.Error("NewGitHub without a client ID must return nil")
+	}
+}
@@ -0,0 +1,160 @@
+// Package permissions решает, какие действия доступны пользователю в зависимости от его кармы —
+// суммарного рейтинга (лайки минус дизлайки) его постов и комментариев — и уровня доверия (см. TrustLevel).
+// Новички (аккаунты моложе Rules.NewAccountAge или ещё не получившие уровень basic) не могут добавлять
+// изображения, ограничены во внешних ссылках и в числе постов и комментариев в час. Число голосов в час ограничено
+// для всех, а для новичков — строже. От уровня доверия зависят также срок правки своих постов и вес жалоб.
+// Обработчики проверяют такие права только через Rules, чтобы пороги задавались в одном месте.
+package permissions
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Privilege — действие, доступное с определённой кармы.
+type Privilege string
+
+const (
+	PostImage Privilege = "post_image" // изображение в посте
+	Downvote  Privilege = "downvote"   // дизлайк поста или комментария
+)
+
+// Activity — вид записей, число которых в час ограничено.
+type Activity string
+
+const (
+	ActivityPost    Activity = "post"
+	ActivityComment Activity = "comment"
+	ActivityVote    Activity = "vote" // лайк или дизлайк поста или комментария
+)
+
+// Rules — пороги кармы и ограничения новых аккаунтов. Нулевые пороги кармы, NewAccountAge и лимиты в час
+// отключают соответствующую проверку; NewAccountLinks 0 запрещает новым аккаунтам внешние ссылки.
+type Rules struct {
+	ImageKarma                int
+	DownvoteKarma             int
+	NewAccountAge             time.Duration // аккаунт моложе этого срока считается новым
+	NewAccountLinks           int           // наибольшее число внешних ссылок в посте или комментарии нового аккаунта
+	NewAccountPostsPerHour    int
+	NewAccountCommentsPerHour int
+	VotesPerHour              int                     // голосов в час для всех пользователей
+	NewAccountVotesPerHour    int                     // голосов в час для новых аккаунтов; 0 — как у всех
+	SiteHost                  string                  // ссылки на этот хост (адрес самого форума) не считаются внешними
+	Trust                     [TrustRegular + 1]Level // условия и права уровней доверия по порядку
+}
+
+// Standing — сведения о пользователе, от которых зависят его права.
+type Standing struct {
+	Role      string
+	Karma     int
+	CreatedAt time.Time
+	Read      int // прочитанных постов
+	Posts     int // своих неудалённых постов
+	Comments  int // своих неудалённых комментариев
+}
+
+// Required возвращает карму, с которой доступно действие p; 0 — действие доступно всем.
+func (r Rules) Required(p Privilege) int {
+	switch p {
+	case PostImage:
+		return r.ImageKarma
+	case Downvote:
+		return r.DownvoteKarma
+	}
+	return 0
+}
+
+// Allows сообщает, доступно ли пользователю действие p. Администраторам доступно всё.
+func (r Rules) Allows(s Standing, p Privilege) bool {
+	required := r.Required(p)
+	return required == 0 || s.Role == "admin" || s.Karma >= required
+}
+
+// IsNew сообщает, считается ли аккаунт новым в момент now — на уровне доверия TrustNew (см. Level).
+// Администраторы новыми не считаются.
+func (r Rules) IsNew(s Standing, now time.Time) bool {
+	return r.Level(s, now) == TrustNew
+}
+
+// AllowsImage сообщает, может ли пользователь добавить изображение в пост: аккаунт не новый и кармы достаточно.
+func (r Rules) AllowsImage(s Standing, now time.Time) bool {
+	return !r.IsNew(s, now) && r.Allows(s, PostImage)
+}
+
+// LinkLimit возвращает, сколько внешних ссылок пользователь может вставить в один пост или комментарий
+// в момент now; -1 — без ограничения. Ограничение действует только для новых аккаунтов.
+func (r Rules) LinkLimit(s Standing, now time.Time) int {
+	if !r.IsNew(s, now) {
+		return -1
+	}
+	return r.NewAccountLinks
+}
+
+// AllowsLinks сообщает, укладываются ли тексты одного поста или комментария в ограничение ссылок (см. LinkLimit).
+func (r Rules) AllowsLinks(s Standing, now time.Time, texts ...string) bool {
+	limit := r.LinkLimit(s, now)
+	if limit < 0 {
+		return true
+	}
+	n := 0
+	for _, text := range texts {
+		n += CountExternalLinks(text, r.SiteHost)
+	}
+	return n <= limit
+}
+
+// HourlyLimit возвращает, сколько записей вида a пользователь может создать за час в момент now;
+// 0 — без ограничения. Посты и комментарии ограничены только для новых аккаунтов, голоса — для всех,
+// кроме администраторов.
+func (r Rules) HourlyLimit(s Standing, now time.Time, a Activity) int {
+	if a == ActivityVote {
+		if s.Role == "admin" {
+			return 0
+		}
+		if r.NewAccountVotesPerHour > 0 && r.IsNew(s, now) {
+			return r.NewAccountVotesPerHour
+		}
+		return r.VotesPerHour
+	}
+	if !r.IsNew(s, now) {
+		return 0
+	}
+	switch a {
+	case ActivityPost:
+		return r.NewAccountPostsPerHour
+	case ActivityComment:
+		return r.NewAccountCommentsPerHour
+	}
+	return 0
+}
+
+// linkPattern находит адреса http(s):// и www.; вторая группа — хост с необязательным портом.
+var linkPattern = regexp.MustCompile(`(?i)\b(https?://|www\.)([^\s/?#]+)`)
+
+// CountLinks возвращает число ссылок в тексте.
+func CountLinks(text string) int {
+	return CountExternalLinks(text, "")
+}
+
+// CountExternalLinks возвращает число ссылок в тексте, ведущих не на хост siteHost; пустой siteHost — все ссылки.
+// Хосты сравниваются без учёта регистра, порта и приставки www.
+func CountExternalLinks(text, siteHost string) int {
+	siteHost = normalizeHost(siteHost)
+	n := 0
+	for _, m := range linkPattern.FindAllStringSubmatch(text, -1) {
+		if siteHost == "" || normalizeHost(m[2]) != siteHost {
+			n++
+		}
+	}
+	return n
+}
+
+// normalizeHost приводит хост к нижнему регистру и убирает порт и приставку www.
+func normalizeHost(host string) string {
+	host = strings.ToLower(host)
+	if i := strings.LastIndexByte(host, ':'); i >= 0 {
+		host = host[:i]
+	}
+	return strings.TrimPrefix(host, "www.")
+}
@@ -0,0 +1,89 @@
+package permissions
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRules проверяет пороги кармы, ограничения новых аккаунтов и исключение для администраторов.
+func TestRules(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	rules := Rules{
+		ImageKarma: 50, DownvoteKarma: 20, NewAccountAge: 72 * time.Hour, NewAccountLinks: 1,
+		NewAccountPostsPerHour: 3, NewAccountCommentsPerHour: 10, VotesPerHour: 100, NewAccountVotesPerHour: 20,
+		SiteHost: "forum.example",
+	}
+	newcomer := Standing{Role: "user", Karma: 20, CreatedAt: now.Add(-time.Hour)}
+	veteran := Standing{Role: "user", Karma: 50, CreatedAt: now.Add(-30 * 24 * time.Hour)}
+	admin := Standing{Role: "admin", CreatedAt: now}
+
+	tests := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"newcomer downvote", rules.Allows(newcomer, Downvote), true},
+		{"newcomer image", rules.Allows(newcomer, PostImage), false},
+		{"new account with karma image", rules.AllowsImage(Standing{Karma: 100, CreatedAt: now}, now), false},
+		{"veteran allows image", rules.AllowsImage(veteran, now), true},
+		{"veteran image", rules.Allows(veteran, PostImage), true},
+		{"admin image", rules.Allows(admin, PostImage), true},
+		{"disabled threshold", Rules{}.Allows(Standing{Karma: -5}, PostImage), true},
+		{"newcomer one link", rules.AllowsLinks(newcomer, now, "see https://example.com", "no links"), true},
+		{"newcomer two links", rules.AllowsLinks(newcomer, now, "https://a.example", "www.b.example"), false},
+		{"veteran many links", rules.AllowsLinks(veteran, now, "http://a.example http://b.example"), true},
+		{"admin many links", rules.AllowsLinks(admin, now, "http://a.example http://b.example"), true},
+		{"newcomer site links", rules.AllowsLinks(newcomer, now, "https://forum.example/post/1 www.FORUM.example:8080"), true},
+		{"newcomer no links allowed", Rules{NewAccountAge: 2 * time.Hour}.AllowsLinks(newcomer, now, "http://a.example"), false},
+		{"newcomer is new", rules.IsNew(newcomer, now), true},
+		{"veteran is new", rules.IsNew(veteran, now), false},
+		{"admin is new", rules.IsNew(admin, now), false},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	limits := []struct {
+		name string
+		got  int
+		want int
+	}{
+		{"newcomer posts", rules.HourlyLimit(newcomer, now, ActivityPost), 3},
+		{"newcomer comments", rules.HourlyLimit(newcomer, now, ActivityComment), 10},
+		{"veteran posts", rules.HourlyLimit(veteran, now, ActivityPost), 0},
+		{"admin comments", rules.HourlyLimit(admin, now, ActivityComment), 0},
+		{"newcomer votes", rules.HourlyLimit(newcomer, now, ActivityVote), 20},
+		{"veteran votes", rules.HourlyLimit(veteran, now, ActivityVote), 100},
+		{"admin votes", rules.HourlyLimit(admin, now, ActivityVote), 0},
+		{"newcomer votes without own limit", Rules{NewAccountAge: time.Hour, VotesPerHour: 100}.HourlyLimit(newcomer, now, ActivityVote), 100},
+	}
+	for _, tt := range limits {
+		if tt.got != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+// TestCountLinks проверяет распознавание ссылок в тексте.
+func TestCountLinks(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"plain text about http and www", 0},
+		{"https://example.com and HTTP://EXAMPLE.ORG/path", 2},
+		{"visit www.example.com.", 1},
+		{"https:// alone", 0},
+	}
+	for _, tt := range tests {
+		if got := CountLinks(tt.text); got != tt.want {
+			t.Errorf("CountLinks(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+	if got := CountExternalLinks("https://forum.example/a http://other.example www.forum.example", "Forum.Example:443"); got != 1 {
+		t.Errorf("CountExternalLinks = %d, want 1", got)
+	}
+}
@@ -0,0 +1,87 @@
+package permissions
+
+import "time"
+
+// TrustLevel — уровень доверия пользователя. Уровень растёт сам по мере активности (см. Rules.Level):
+// с возрастом аккаунта, прочитанными постами, своими постами и комментариями и кармой.
+type TrustLevel int
+
+const (
+	TrustNew     TrustLevel = iota // новичок: действуют ограничения новых аккаунтов
+	TrustBasic                     // освоившийся
+	TrustMember                    // участник
+	TrustRegular                   // завсегдатай
+)
+
+// trustNames — названия уровней доверия; подписи берутся из каталога (trust.<название>).
+var trustNames = [...]string{"new", "basic", "member", "regular"}
+
+// String возвращает название уровня: new, basic, member или regular.
+func (l TrustLevel) String() string {
+	if l < TrustNew || l > TrustRegular {
+		return "unknown"
+	}
+	return trustNames[l]
+}
+
+// Requirement — показатели активности, с которых пользователь получает уровень доверия.
+// Нулевое поле не проверяется.
+type Requirement struct {
+	Age      time.Duration // возраст аккаунта
+	Read     int           // прочитанных постов
+	Posts    int           // своих неудалённых постов
+	Comments int           // своих неудалённых комментариев
+	Karma    int
+}
+
+// Level — условия уровня доверия и права на нём. Условия уровня TrustNew не проверяются.
+type Level struct {
+	Requirement
+	EditWindow time.Duration // сколько после публикации можно править свой пост; 0 — без ограничения
+	FlagWeight int           // вес жалобы на пост; меньше 1 считается за 1
+}
+
+// met сообщает, выполнены ли условия уровня для пользователя s в момент now.
+func (l Level) met(s Standing, now time.Time) bool {
+	return now.Sub(s.CreatedAt) >= l.Age && s.Read >= l.Read && s.Posts >= l.Posts &&
+		s.Comments >= l.Comments && (l.Karma == 0 || s.Karma >= l.Karma)
+}
+
+// Level возвращает уровень доверия пользователя в момент now: наибольший уровень, условия которого и всех
+// уровней ниже выполнены. Аккаунт моложе NewAccountAge остаётся новичком; администраторы — завсегдатаи.
+func (r Rules) Level(s Standing, now time.Time) TrustLevel {
+	if s.Role == "admin" {
+		return TrustRegular
+	}
+	if r.NewAccountAge > 0 && now.Sub(s.CreatedAt) < r.NewAccountAge {
+		return TrustNew
+	}
+	level := TrustNew
+	for l := TrustBasic; l <= TrustRegular; l++ {
+		if !r.Trust[l].met(s, now) {
+			break
+		}
+		level = l
+	}
+	return level
+}
+
+// EditWindow возвращает, сколько после публикации пользователь может править свой пост в момент now;
+// 0 — без ограничения. Администраторов срок не ограничивает.
+func (r Rules) EditWindow(s Standing, now time.Time) time.Duration {
+	if s.Role == "admin" {
+		return 0
+	}
+	return r.Trust[r.Level(s, now)].EditWindow
+}
+
+// AllowsEdit сообщает, может ли пользователь в момент now править свой пост, опубликованный в published.
+func (r Rules) AllowsEdit(s Standing, now, published time.Time) bool {
+	window := r.EditWindow(s, now)
+	return window == 0 || now.Sub(published) <= window
+}
+
+// FlagWeight возвращает вес жалобы пользователя на пост в момент now (см. Level.FlagWeight).
+func (r Rules) FlagWeight(s Standing, now time.Time) int {
+	return max(r.Trust[r.Level(s, now)].FlagWeight, 1)
+}
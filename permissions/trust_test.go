@@ -0,0 +1,83 @@
+package permissions
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTrustLevels проверяет рост уровня доверия по показателям активности и зависящие от него права:
+// ограничения новичков, срок правки постов и вес жалоб.
+func TestTrustLevels(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	rules := Rules{NewAccountAge: 72 * time.Hour, NewAccountLinks: 0}
+	rules.Trust = [TrustRegular + 1]Level{
+		TrustNew:     {EditWindow: time.Hour, FlagWeight: 1},
+		TrustBasic:   {Requirement: Requirement{Read: 5}, EditWindow: 24 * time.Hour, FlagWeight: 1},
+		TrustMember:  {Requirement: Requirement{Age: 30 * 24 * time.Hour, Read: 30, Comments: 10, Karma: 10}, FlagWeight: 2},
+		TrustRegular: {Requirement: Requirement{Age: 90 * 24 * time.Hour, Read: 100, Posts: 5, Comments: 50, Karma: 50}, FlagWeight: 3},
+	}
+	month := now.Add(-31 * 24 * time.Hour)
+	lurker := Standing{Role: "user", CreatedAt: month, Read: 2}
+	reader := Standing{Role: "user", CreatedAt: month, Read: 40, Comments: 3}
+	member := Standing{Role: "user", CreatedAt: month, Read: 40, Comments: 12, Karma: 15}
+	busyNewcomer := Standing{Role: "user", CreatedAt: now.Add(-time.Hour), Read: 500, Posts: 20, Comments: 90, Karma: 99}
+	regular := Standing{Role: "user", CreatedAt: now.Add(-100 * 24 * time.Hour), Read: 150, Posts: 6, Comments: 60, Karma: 70}
+	admin := Standing{Role: "admin", CreatedAt: now}
+
+	levels := []struct {
+		name string
+		s    Standing
+		want TrustLevel
+	}{
+		{"lurker", lurker, TrustNew},
+		{"reader", reader, TrustBasic},
+		{"member", member, TrustMember},
+		{"busy newcomer", busyNewcomer, TrustNew},
+		{"regular", regular, TrustRegular},
+		{"admin", admin, TrustRegular},
+	}
+	for _, tt := range levels {
+		if got := rules.Level(tt.s, now); got != tt.want {
+			t.Errorf("Level(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if got := (Rules{}).Level(Standing{Karma: -5, CreatedAt: now}, now); got != TrustRegular {
+		t.Errorf("Level without requirements = %v, want regular", got)
+	}
+
+	published := now.Add(-2 * time.Hour)
+	checks := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"lurker is new", rules.IsNew(lurker, now), true},
+		{"lurker links", rules.AllowsLinks(lurker, now, "https://a.example"), false},
+		{"reader links", rules.AllowsLinks(reader, now, "https://a.example"), true},
+		{"lurker edits after an hour", rules.AllowsEdit(lurker, now, published), false},
+		{"reader edits within a day", rules.AllowsEdit(reader, now, published), true},
+		{"reader edits after a day", rules.AllowsEdit(reader, now, now.Add(-25*time.Hour)), false},
+		{"member edits any time", rules.AllowsEdit(member, now, now.AddDate(-1, 0, 0)), true},
+		{"admin edits any time", (Rules{Trust: [TrustRegular + 1]Level{TrustRegular: {EditWindow: time.Minute}}}).AllowsEdit(admin, now, published), true},
+	}
+	for _, tt := range checks {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		s    Standing
+		want int
+	}{{lurker, 1}, {member, 2}, {regular, 3}} {
+		if got := rules.FlagWeight(tt.s, now); got != tt.want {
+			t.Errorf("FlagWeight(%+v) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+	if got := (Rules{}).FlagWeight(lurker, now); got != 1 {
+		t.Errorf("FlagWeight without weights = %d, want 1", got)
+	}
+	if TrustMember.String() != "member" {
+		t.Errorf("TrustMember.String() = %q", TrustMember.String())
+	}
+}
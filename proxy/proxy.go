@@ -0,0 +1,143 @@
+// Package proxy восстанавливает адрес клиента и схему запроса за доверенными обратными прокси
+// (nginx, балансировщик) по заголовкам X-Forwarded-For, X-Real-IP и X-Forwarded-Proto.
+// Заголовки от остальных адресов игнорируются, так как клиент может подставить их сам.
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Trusted — список доверенных прокси.
+type Trusted struct {
+	nets []*net.IPNet
+}
+
+// New разбирает доверенные прокси: IP-адреса или подсети в нотации CIDR (например, 10.0.0.0/8).
+func New(entries []string) (*Trusted, error) {
+	t := &Trusted{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		ipNet, err := parse(entry)
+		if err != nil {
+			return nil, err
+		}
+		t.nets = append(t.nets, ipNet)
+	}
+	return t, nil
+}
+
+// parse возвращает подсеть для адреса или подсети entry; отдельный адрес — подсеть из одного адреса.
+func parse(entry string) (*net.IPNet, error) {
+	if strings.Contains(entry, "/") {
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+		}
+		return ipNet, nil
+	}
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+	}
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// Validate проверяет список доверенных прокси.
+func Validate(entries []string) error {
+	_, err := New(entries)
+	return err
+}
+
+// contains сообщает, входит ли адрес в доверенные прокси.
+func (t *Trusted) contains(ip net.IP) bool {
+	for _, n := range t.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// secureKey — ключ контекста с признаком HTTPS-запроса, принятого прокси.
+type secureKey struct{}
+
+// Middleware подменяет r.RemoteAddr адресом клиента и запоминает схему запроса, если запрос пришёл
+// от доверенного прокси. Без доверенных прокси возвращает next без изменений.
+func (t *Trusted) Middleware(next http.Handler) http.Handler {
+	if t == nil || len(t.nets) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if peer := net.ParseIP(ClientIP(r)); peer != nil && t.contains(peer) {
+			r = t.resolve(r)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// resolve применяет заголовки доверенного прокси к копии запроса.
+func (t *Trusted) resolve(r *http.Request) *http.Request {
+	r = r.Clone(r.Context())
+	if ip := t.forwardedFor(r.Header.Get("X-Forwarded-For")); ip != nil {
+		r.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+	} else if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		r.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+	}
+	// Первое значение выставляет прокси, принявший соединение клиента.
+	proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+	if strings.EqualFold(strings.TrimSpace(proto), "https") {
+		r = r.WithContext(context.WithValue(r.Context(), secureKey{}, true))
+	}
+	return r
+}
+
+// forwardedFor возвращает адрес клиента из X-Forwarded-For: цепочка просматривается справа налево,
+// доверенные прокси пропускаются. Если доверенные все адреса, клиентом считается самый левый.
+func (t *Trusted) forwardedFor(header string) net.IP {
+	if header == "" {
+		return nil
+	}
+	hops := strings.Split(header, ",")
+	var leftmost net.IP
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			// Некорректное значение в цепочке: дальше него заголовку доверять нельзя.
+			return leftmost
+		}
+		if !t.contains(ip) {
+			return ip
+		}
+		leftmost = ip
+	}
+	return leftmost
+}
+
+// ClientIP возвращает IP-адрес клиента из r.RemoteAddr (после Middleware — с учётом прокси).
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// IsSecure сообщает, пришёл ли запрос по HTTPS: напрямую или через доверенный прокси.
+func IsSecure(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	secure, _ := r.Context().Value(secureKey{}).(bool)
+	return secure
+}
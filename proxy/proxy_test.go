@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestMiddleware проверяет, что заголовки учитываются только от доверенных прокси.
+func TestMiddleware(t *testing.T) {
+	trusted, err := New([]string{"10.0.0.0/8", "192.168.1.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		remote     string
+		headers    map[string]string
+		wantIP     string
+		wantSecure bool
+	}{
+		{"untrusted peer", "203.0.113.7:5000", map[string]string{"X-Forwarded-For": "1.2.3.4", "X-Forwarded-Proto": "https"}, "203.0.113.7", false},
+		{"single hop", "10.1.2.3:5000", map[string]string{"X-Forwarded-For": "198.51.100.4", "X-Forwarded-Proto": "https"}, "198.51.100.4", true},
+		{"spoofed chain", "10.1.2.3:5000", map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.4, 192.168.1.5"}, "198.51.100.4", false},
+		{"all trusted", "10.1.2.3:5000", map[string]string{"X-Forwarded-For": "10.9.9.9, 192.168.1.5"}, "10.9.9.9", false},
+		{"real ip", "192.168.1.5:5000", map[string]string{"X-Real-IP": "2001:db8::1", "X-Forwarded-Proto": "http"}, "2001:db8::1", false},
+		{"no headers", "10.1.2.3:5000", nil, "10.1.2.3", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			var gotIP string
+			var gotSecure bool
+			trusted.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotIP, gotSecure = ClientIP(r), IsSecure(r)
+			})).ServeHTTP(httptest.NewRecorder(), r)
+			if gotIP != tt.wantIP || gotSecure != tt.wantSecure {
+				t.Errorf("got %s secure=%v, want %s secure=%v", gotIP, gotSecure, tt.wantIP, tt.wantSecure)
+			}
+		})
+	}
+}
+
+// TestNewInvalid проверяет, что некорректные адреса прокси не принимаются.
+func TestNewInvalid(t *testing.T) {
+	for _, entry := range []string{"10.0.0.0/33", "proxy.local", "1.2.3"} {
+		if _, err := New([]string{entry}); err == nil {
+			t.Errorf("New(%q) succeeded, want an error", entry)
+		}
+	}
+}
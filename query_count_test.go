@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"forum/config"
+	"forum/database"
+	"forum/handlers"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// indexQueryBudget — максимум запросов к базе на один показ главной страницы авторизованному пользователю:
+// сессия, имя пользователя, версия ленты, состояние прочтения для ETag, разделы, категории, посты, комментарии
+// и оформление сайта. Число не должно зависеть от количества постов.
+const indexQueryBudget = 9
+
+// executedQueries считает запросы, выполненные через драйвер sqlite3_counting.
+var executedQueries atomic.Int64
+
+func init() {
+	sql.Register("sqlite3_counting", countingDriver{})
+}
+
+// countingDriver оборачивает драйвер SQLite и увеличивает executedQueries на каждый выполненный запрос,
+// включая запросы через подготовленные выражения.
+type countingDriver struct{}
+
+func (countingDriver) Open(name string) (driver.Conn, error) {
+	conn, err := (&sqlite3.SQLiteDriver{}).Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+type countingConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (c *countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	executedQueries.Add(1)
+	return c.SQLiteConn.QueryContext(ctx, query, args)
+}
+
+func (c *countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	executedQueries.Add(1)
+	return c.SQLiteConn.ExecContext(ctx, query, args)
+}
+
+func (c *countingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.SQLiteConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &countingStmt{stmt.(*sqlite3.SQLiteStmt)}, nil
+}
+
+type countingStmt struct {
+	*sqlite3.SQLiteStmt
+}
+
+func (s *countingStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	executedQueries.Add(1)
+	return s.SQLiteStmt.QueryContext(ctx, args)
+}
+
+func (s *countingStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	executedQueries.Add(1)
+	return s.SQLiteStmt.ExecContext(ctx, args)
+}
+
+// newCountingForum создаёт временную базу с posts постами (по две категории, голос и два комментария у каждого)
+// и возвращает обработчик приложения поверх считающего драйвера вместе с cookie авторизованного пользователя.
+func newCountingForum(tb testing.TB, posts int) (http.Handler, *http.Cookie) {
+	tb.Helper()
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	tb.Cleanup(func() { log.SetOutput(out) })
+
+	path := filepath.Join(tb.TempDir(), "forum.db")
+	seedDB, err := database.OpenSQLite(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	seedPosts(tb, database.NewSQLiteStore(seedDB), posts)
+	database.CloseStatements(seedDB)
+	seedDB.Close()
+
+	db, err := sql.Open("sqlite3_counting", path+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		database.CloseStatements(db)
+		db.Close()
+	})
+	cfg := config.Default()
+	files, err := loadAssets(cfg.Server)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := handlers.Configure(cfg, files.templates, files.manifest); err != nil {
+		tb.Fatal(err)
+	}
+	return setupRoutes(files, cfg.Server.Timeouts, database.NewSQLiteStore(db), nil), &http.Cookie{Name: "session_id", Value: "query-count-session"}
+}
+
+// seedPosts наполняет базу тестовыми данными через репозитории.
+func seedPosts(tb testing.TB, store *database.Store, posts int) {
+	ctx := context.Background()
+	if err := store.Users.RegisterUser(ctx, "reader@example.com", "reader", "hash"); err != nil {
+		tb.Fatal(err)
+	}
+	userID, _, _, _, err := store.Users.GetUserByEmail(ctx, "reader@example.com")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := store.Users.CreateSession(ctx, "query-count-session", userID, "user", time.Now().Add(time.Hour)); err != nil {
+		tb.Fatal(err)
+	}
+
+	board, err := store.Boards.GetBoardBySlug(ctx, database.DefaultBoard)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	news, _ := store.Posts.GetCategoryIDByName(ctx, "news")
+	games, _ := store.Posts.GetCategoryIDByName(ctx, "games")
+	for i := 0; i < posts; i++ {
+		postID, err := store.Posts.CreatePost(ctx, userID, board.ID, fmt.Sprintf("Post %d", i), "Body", "", time.Now())
+		if err != nil {
+			tb.Fatal(err)
+		}
+		for _, catID := range []int{news, games} {
+			if err := store.Posts.AddPostCategory(ctx, postID, catID); err != nil {
+				tb.Fatal(err)
+			}
+		}
+		if err := store.Votes.SetPostLike(ctx, userID, int(postID)); err != nil {
+			tb.Fatal(err)
+		}
+		for j := 0; j < 2; j++ {
+			if _, err := store.Comments.CreateComment(ctx, int(postID), userID, "Comment", time.Now().Format("2006-01-02 15:04:05")); err != nil {
+				tb.Fatal(err)
+			}
+		}
+	}
+}
+
+// countIndexQueries возвращает число запросов к базе при одном показе главной страницы.
+func countIndexQueries(tb testing.TB, handler http.Handler, cookie *http.Cookie) int64 {
+	tb.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	executedQueries.Store(0)
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		tb.Fatalf("GET / = %d", rec.Code)
+	}
+	return executedQueries.Load()
+}
+
+// TestIndexQueryCount проверяет, что главная страница выполняет фиксированное число запросов
+// независимо от количества постов (нет запросов в цикле по постам).
+func TestIndexQueryCount(t *testing.T) {
+	smallHandler, cookie := newCountingForum(t, 3)
+	largeHandler, _ := newCountingForum(t, 30)
+
+	small := countIndexQueries(t, smallHandler, cookie)
+	large := countIndexQueries(t, largeHandler, cookie)
+	if small != large {
+		t.Fatalf("index queries grow with posts: %d for 3 posts, %d for 30 posts", small, large)
+	}
+	if large > indexQueryBudget {
+		t.Fatalf("index executed %d queries, budget is %d", large, indexQueryBudget)
+	}
+}
+
+// BenchmarkIndexHandler измеряет показ главной страницы с 30 постами и сообщает число запросов на показ.
+func BenchmarkIndexHandler(b *testing.B) {
+	handler, cookie := newCountingForum(b, 30)
+	var total int64
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		total += countIndexQueries(b, handler, cookie)
+	}
+	b.ReportMetric(float64(total)/float64(b.N), "queries/op")
+}
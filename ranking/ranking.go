@@ -0,0 +1,74 @@
+// Package ranking считает, в каком порядке показывать посты в ленте «Лучшие» и лучшие комментарии.
+//
+// По умолчанию лента упорядочена по рейтингу (лайки минус дизлайки), и старые популярные посты остаются наверху
+// навсегда. Decay добавляет затухание: рейтинг поста весит вдвое меньше через каждый период полураспада,
+// поэтому свежие посты с меньшим рейтингом со временем обгоняют старые.
+//
+// Лучшие комментарии упорядочиваются по Wilson: по нижней границе доли лайков, в которой можно быть уверенным
+// при таком числе голосов, а не по разнице лайков и дизлайков.
+package ranking
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Decay — затухание рейтинга со временем. Нулевой HalfLife выключает затухание: порядок задаёт сам рейтинг.
+type Decay struct {
+	HalfLife time.Duration // через этот срок рейтинг поста весит вдвое меньше
+}
+
+// Enabled сообщает, включено ли затухание.
+func (d Decay) Enabled() bool {
+	return d.HalfLife > 0
+}
+
+// Score возвращает вес поста с рейтингом score, созданного в created, в момент now: score·2^(−возраст/HalfLife).
+// Отрицательный рейтинг затухает так же, поэтому давно опущенный пост перестаёт тянуть ленту вниз.
+// Пост «из будущего» (часы сервера сдвинулись) считается только что созданным.
+func (d Decay) Score(score int, created, now time.Time) float64 {
+	if !d.Enabled() {
+		return float64(score)
+	}
+	age := max(now.Sub(created), 0)
+	return float64(score) * math.Exp2(-float64(age)/float64(d.HalfLife))
+}
+
+// Item — пост для упорядочивания: рейтинг и время создания.
+type Item struct {
+	Score   int
+	Created time.Time
+}
+
+// Order возвращает индексы items в порядке убывания веса в момент now. При равном весе сохраняется
+// исходный порядок, поэтому items удобно передавать уже упорядоченными от новых к старым.
+func (d Decay) Order(items []Item, now time.Time) []int {
+	weights := make([]float64, len(items))
+	order := make([]int, len(items))
+	for i, it := range items {
+		weights[i] = d.Score(it.Score, it.Created, now)
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return weights[order[a]] > weights[order[b]]
+	})
+	return order
+}
+
+// wilsonZ — квантиль нормального распределения для доверительного уровня 95%.
+const wilsonZ = 1.96
+
+// Wilson возвращает нижнюю границу 95%-го доверительного интервала Уилсона для доли лайков среди голосов,
+// от 0 до 1; без голосов — 0. Комментарий с 5 лайками и без дизлайков (≈0,57) так выше комментария
+// со 100 лайками и 80 дизлайками (≈0,48), хотя разница голосов у второго больше: его одобряет лишь
+// чуть больше половины голосовавших, а у первого все, хоть голосов пока и мало.
+func Wilson(likes, dislikes int) float64 {
+	n := float64(likes + dislikes)
+	if n <= 0 {
+		return 0
+	}
+	p := float64(likes) / n
+	z2 := wilsonZ * wilsonZ
+	return (p + z2/(2*n) - wilsonZ*math.Sqrt((p*(1-p)+z2/(4*n))/n)) / (1 + z2/n)
+}
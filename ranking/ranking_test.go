@@ -0,0 +1,63 @@
+package ranking
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestDecayScore проверяет полураспад рейтинга и выключенное затухание.
+func TestDecayScore(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	d := Decay{HalfLife: 24 * time.Hour}
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"fresh", d.Score(10, now, now), 10},
+		{"one half-life", d.Score(10, now.Add(-24*time.Hour), now), 5},
+		{"two half-lives", d.Score(-8, now.Add(-48*time.Hour), now), -2},
+		{"future", d.Score(10, now.Add(time.Hour), now), 10},
+		{"disabled", Decay{}.Score(10, now.Add(-1000*time.Hour), now), 10},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: Score = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+// TestDecayOrder проверяет, что свежий пост обгоняет старый с большим рейтингом, а равные сохраняют порядок.
+func TestDecayOrder(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	items := []Item{
+		{Score: 3, Created: now.Add(-time.Hour)},
+		{Score: 0, Created: now.Add(-2 * time.Hour)},
+		{Score: 0, Created: now.Add(-3 * time.Hour)},
+		{Score: 50, Created: now.Add(-30 * 24 * time.Hour)},
+	}
+	if got := (Decay{HalfLife: 24 * time.Hour}).Order(items, now); !slices.Equal(got, []int{0, 3, 1, 2}) {
+		t.Errorf("Order with decay = %v", got)
+	}
+	if got := (Decay{}).Order(items, now); !slices.Equal(got, []int{3, 0, 1, 2}) {
+		t.Errorf("Order without decay = %v", got)
+	}
+}
+
+// TestWilson проверяет, что уверенно одобренный комментарий выше спорного с большей разницей голосов.
+func TestWilson(t *testing.T) {
+	if got := Wilson(0, 0); got != 0 {
+		t.Errorf("Wilson(0, 0) = %v, want 0", got)
+	}
+	if a, b := Wilson(5, 0), Wilson(100, 80); a <= b {
+		t.Errorf("Wilson(5, 0) = %v, not above Wilson(100, 80) = %v", a, b)
+	}
+	if a, b := Wilson(100, 0), Wilson(5, 0); a <= b {
+		t.Errorf("Wilson(100, 0) = %v, not above Wilson(5, 0) = %v", a, b)
+	}
+	if got := Wilson(5, 0); math.Abs(got-0.5655) > 0.001 {
+		t.Errorf("Wilson(5, 0) = %v, want about 0.5655", got)
+	}
+}
@@ -4,40 +4,183 @@
 package main
 
 import (
-	"database/sql"
+	"fmt"
 	"net/http"
+	"strconv"
 
+	"forum/config"
+	"forum/database"
+	"forum/fingerprint"
 	"forum/handlers"
+	"forum/i18n"
+	"forum/integrations"
 )
 
 // setupRoutes настраивает маршруты приложения и возвращает HTTP-обработчик.
 // Регистрирует обработчики для статических файлов и основных маршрутов, оборачивает их в CustomHandler.
-func setupRoutes(db *sql.DB) http.Handler {
+// Статические файлы берутся из a; notifier получает события о новых постах для внешних интеграций.
+// Время обработки ограничивается по классу маршрута (см. routeClass) значениями из t.
+func setupRoutes(a assets, t config.Timeouts, store *database.Store, notifier *integrations.Dispatcher) http.Handler {
 	mux := http.NewServeMux()
+	handle := func(pattern string, class routeClass, h http.Handler) {
+		mux.Handle(pattern, withTimeout(class.timeout(t), h))
+	}
 
 	// Обслуживает статические файлы из директорий static и images.
-	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+	// Файлы с хешем в имени кэшируются браузером бессрочно (см. пакет fingerprint).
+	mux.Handle("/static/", methods{"GET": http.StripPrefix("/static/", fingerprint.Handler(a.manifest, a.static))})
 	// Исправлено: изображения теперь обслуживаются из static/images
-	mux.Handle("/images/", http.StripPrefix("/images/", http.FileServer(http.Dir("static/images"))))
-
-	// Регистрирует обработчики для основных маршрутов
-	mux.HandleFunc("/", handlers.IndexHandler(db))
-	mux.HandleFunc("/register", handlers.RegisterHandler(db))
-	mux.HandleFunc("/login", handlers.LoginHandler(db))
-	mux.HandleFunc("/logout", handlers.LogoutHandler(db))
-	mux.HandleFunc("/profile", handlers.ProfileHandler(db))
-	mux.HandleFunc("/post", handlers.PostHandler(db))
-	mux.HandleFunc("/create-post", handlers.CreatePostHandler(db))
-	mux.HandleFunc("/edit-post", handlers.EditPostHandler(db))
-	mux.HandleFunc("/delete-post", handlers.DeletePostHandler(db))
-	mux.HandleFunc("/delete-comment", handlers.DeleteCommentHandler(db))
-	mux.HandleFunc("/like", handlers.LikeHandler(db))
-	mux.HandleFunc("/dislike", handlers.DislikeHandler(db))
-	mux.HandleFunc("/comment", handlers.CommentHandler(db))
-	mux.HandleFunc("/comment-like", handlers.CommentLikeHandler(db))
-	mux.HandleFunc("/comment-dislike", handlers.CommentDislikeHandler(db))
-	mux.HandleFunc("/update-profile", handlers.UpdateProfileHandler(db))
-
-	// Оборачивает маршрутизатор в CustomHandler для обработки паник и ошибок 404.
-	return &CustomHandler{mux: mux}
+	mux.Handle("/images/", methods{"GET": http.FileServerFS(a.static)})
+	// Внешние изображения постов, загруженные сервером (см. handlers.UseImageProxy).
+	handle("/img/{sig}", pageRoute, methods{"GET": handlers.ImageProxyHandler()})
+	// Изображения, загруженные с постами (см. handlers.UseStorage).
+	handle("/uploads/{key...}", pageRoute, methods{"GET": handlers.UploadHandler()})
+
+	// Регистрирует обработчики для основных маршрутов; ID берутся из пути (см. r.PathValue).
+	// Для каждого пути перечислены допустимые методы, на остальные отвечает 405 (см. methods).
+	handle("/{$}", pageRoute, methods{"GET": handlers.IndexHandler(store)})
+	handle("/b/{board}", pageRoute, methods{"GET": handlers.BoardHandler(store)})
+	handle("/pages/{slug}", pageRoute, methods{"GET": handlers.PageHandler(store)})
+	handle("/search", pageRoute, methods{"GET": handlers.SearchHandler(store)})
+	handle("/leaderboard", pageRoute, methods{"GET": handlers.LeaderboardHandler(store)})
+	handle("/digest", pageRoute, methods{"GET": handlers.DigestHandler(store)})
+	handle("/digest/{week}", pageRoute, methods{"GET": handlers.DigestWeekHandler(store)})
+	register := handlers.RegisterHandler(store)
+	handle("/register", pageRoute, methods{"GET": register, "POST": register})
+	login := handlers.LoginHandler(store)
+	handle("/login", pageRoute, methods{"GET": login, "POST": login})
+	logout := handlers.LogoutHandler(store)
+	handle("/logout", pageRoute, methods{"GET": logout, "POST": logout})
+	verifyEmail := handlers.VerifyEmailHandler(store)
+	handle("/verify-email", pageRoute, methods{"GET": verifyEmail, "POST": verifyEmail})
+	forgotPassword := handlers.ForgotPasswordHandler(store)
+	handle("/forgot-password", pageRoute, methods{"GET": forgotPassword, "POST": forgotPassword})
+	resetPassword := handlers.ResetPasswordHandler(store)
+	handle("/reset-password", pageRoute, methods{"GET": resetPassword, "POST": resetPassword})
+	handle("/auth/github", pageRoute, methods{"GET": handlers.GitHubLoginHandler()})
+	handle("/auth/github/callback", pageRoute, methods{"GET": handlers.GitHubCallbackHandler(store)})
+	handle("/language", pageRoute, methods{"POST": handlers.LanguageHandler(store)})
+	handle("/read-all", pageRoute, methods{"POST": handlers.MarkAllReadHandler(store)})
+	history := handlers.HistoryHandler(store)
+	handle("/history", pageRoute, methods{"GET": history, "POST": history})
+	handle("/notifications", pageRoute, methods{"GET": handlers.NotificationsHandler(store)})
+	managePosts := handlers.ManagePostsHandler(store)
+	handle("/my/posts", pageRoute, methods{"GET": managePosts, "POST": managePosts})
+	invites := handlers.InvitesHandler(store)
+	handle("/invites", pageRoute, methods{"GET": invites, "POST": invites})
+	handle("/profile/{id}", pageRoute, methods{"GET": handlers.ProfileHandler(store)})
+	handle("/profile/{id}/follow", pageRoute, methods{"POST": handlers.FollowHandler(store)})
+	handle("/profile/likes", pageRoute, methods{"POST": handlers.LikesPrivacyHandler(store)})
+	handle("/profile", pageRoute, methods{
+		"GET":  legacyRedirect("user_id", "/profile/%d"),
+		"POST": handlers.UpdateProfileHandler(store),
+	})
+
+	createPost := handlers.CreatePostHandler(store, notifier)
+	handle("/post/new", uploadRoute, methods{"GET": createPost, "POST": createPost})
+	handle("/post/{id}", pageRoute, methods{
+		"GET":    handlers.PostHandler(store),
+		"DELETE": handlers.DeletePostHandler(store),
+	})
+	editPost := handlers.EditPostHandler(store)
+	handle("/post/{id}/edit", uploadRoute, methods{"GET": editPost, "POST": editPost})
+	handle("/post/{id}/like", pageRoute, methods{"POST": handlers.LikeHandler(store)})
+	handle("/post/{id}/dislike", pageRoute, methods{"POST": handlers.DislikeHandler(store)})
+	handle("/post/{id}/likes", pageRoute, methods{"GET": handlers.PostLikersHandler(store)})
+	handle("/post/{id}/comments", pageRoute, methods{"POST": handlers.CommentHandler(store)})
+	handle("/post/{id}/pin", pageRoute, methods{"POST": handlers.PinPostHandler(store)})
+	handle("/post/{id}/save", pageRoute, methods{"POST": handlers.SavePostHandler(store)})
+	handle("/post/{id}/report", pageRoute, methods{"POST": handlers.ReportPostHandler(store)})
+	handle("/post/{id}/export", pageRoute, methods{"GET": handlers.ExportPostHandler(store)})
+	handle("/post/{id}/translate", pageRoute, methods{"POST": handlers.TranslatePostHandler(store)})
+	handle("/post/{id}/summarize", longRoute, methods{"POST": handlers.SummarizeThreadHandler(store)})
+	handle("/s/{code}", pageRoute, methods{"GET": handlers.ShortLinkHandler(store)})
+	handle("/post/{id}/share/{channel}", pageRoute, methods{"GET": handlers.SharePostHandler(store)})
+
+	handle("/comment/{id}", pageRoute, methods{"DELETE": handlers.DeleteCommentHandler(store)})
+	handle("/comment/{id}/like", pageRoute, methods{"POST": handlers.CommentLikeHandler(store)})
+	handle("/comment/{id}/dislike", pageRoute, methods{"POST": handlers.CommentDislikeHandler(store)})
+	handle("/comment/{id}/likes", pageRoute, methods{"GET": handlers.CommentLikersHandler(store)})
+	handle("/inbound/email/{token}", uploadRoute, methods{"POST": handlers.InboundEmailHandler(store)})
+	handle("/series/{id}", pageRoute, methods{"GET": handlers.SeriesHandler(store)})
+	handle("/series/{id}/move", pageRoute, methods{"POST": handlers.MoveSeriesPartHandler(store)})
+	collections := handlers.CollectionsHandler(store)
+	handle("/collections", pageRoute, methods{"GET": collections, "POST": collections})
+	handle("/collections/{id}", pageRoute, methods{"GET": handlers.CollectionHandler(store)})
+	handle("/collections/{id}/share", pageRoute, methods{"POST": handlers.ShareCollectionHandler(store)})
+	handle("/collections/{id}/remove", pageRoute, methods{"POST": handlers.RemoveCollectionItemHandler(store)})
+	handle("/collections/{id}/delete", pageRoute, methods{"POST": handlers.DeleteCollectionHandler(store)})
+	handle("/shared/{token}", pageRoute, methods{"GET": handlers.SharedCollectionHandler(store)})
+
+	// Старые адреса с ID в строке запроса перенаправляются на новые пути с любым методом.
+	handle("/post", pageRoute, legacyRedirect("post_id", "/post/%d"))
+	handle("/create-post", pageRoute, legacyRedirect("", "/post/new"))
+	handle("/edit-post", pageRoute, legacyRedirect("post_id", "/post/%d/edit"))
+	handle("/delete-post", pageRoute, legacyRedirect("post_id", "/post/%d"))
+	handle("/like", pageRoute, legacyRedirect("post_id", "/post/%d/like"))
+	handle("/dislike", pageRoute, legacyRedirect("post_id", "/post/%d/dislike"))
+	handle("/comment", pageRoute, legacyRedirect("post_id", "/post/%d/comments"))
+	handle("/delete-comment", pageRoute, legacyRedirect("comment_id", "/comment/%d"))
+	handle("/comment-like", pageRoute, legacyRedirect("comment_id", "/comment/%d/like"))
+	handle("/comment-dislike", pageRoute, legacyRedirect("comment_id", "/comment/%d/dislike"))
+	handle("/update-profile", pageRoute, legacyRedirect("", "/profile"))
+
+	// Служебные страницы администратора
+	handle("/admin", pageRoute, methods{"GET": handlers.AdminHandler(store)})
+	handle("/admin/backup", longRoute, methods{"GET": handlers.BackupHandler(store)})
+	integrity := handlers.IntegrityHandler(store)
+	handle("/admin/integrity", longRoute, methods{"GET": integrity, "POST": integrity})
+	handle("/admin/settings", pageRoute, methods{"POST": handlers.AdminSettingsHandler(store)})
+	handle("/admin/categories", pageRoute, methods{"POST": handlers.AdminCategoriesHandler(store)})
+	handle("/admin/site", pageRoute, methods{"POST": handlers.AdminSiteHandler(store)})
+	handle("/admin/images", pageRoute, methods{"POST": handlers.AdminImageDomainsHandler(store)})
+	handle("/admin/email-domains", pageRoute, methods{"POST": handlers.AdminEmailDomainsHandler(store)})
+	handle("/admin/roles", pageRoute, methods{"POST": handlers.AdminRolesHandler(store)})
+	handle("/admin/invites", pageRoute, methods{"GET": handlers.AdminInvitesHandler(store)})
+	adminPages := handlers.AdminPagesHandler(store)
+	handle("/admin/pages", pageRoute, methods{"GET": adminPages, "POST": adminPages})
+	handle("/admin/pages/{slug}", pageRoute, methods{"GET": handlers.AdminPageHandler(store)})
+	handle("/admin/pages/{slug}/delete", pageRoute, methods{"POST": handlers.DeletePageHandler(store)})
+	handle("/admin/votes", pageRoute, methods{"GET": handlers.VoteReportHandler(store)})
+	handle("/admin/shares", pageRoute, methods{"GET": handlers.ShareReportHandler(store)})
+	handle("/moderation", pageRoute, methods{"GET": handlers.ModerationHandler(store)})
+	handle("/moderation/{id}", pageRoute, methods{"POST": handlers.ResolveModerationHandler(store, notifier)})
+
+	// Облегчённые JSON-эндпоинты для мобильного клиента
+	handle("/api/posts", pageRoute, methods{"GET": handlers.APIPostsHandler(store)})
+	handle("/api/comments", pageRoute, methods{"GET": handlers.APICommentsHandler(store)})
+	handle("/api/feed", pageRoute, methods{"GET": handlers.APIFeedHandler(store)})
+	handle("/api/activity", pageRoute, methods{"GET": handlers.APIActivityHandler(store)})
+
+	// Оборачивает маршрутизатор в CustomHandler для обработки паник и ошибок 404;
+	// язык и оформление страниц (в том числе страниц ошибок) выбираются до него.
+	return i18n.Middleware(handlers.SiteMiddleware(store, &CustomHandler{mux: mux}))
+}
+
+// legacyRedirect перенаправляет старый адрес на путь target, подставляя в него ID из параметра param
+// (строки запроса или формы); остальные параметры запроса сохраняются. Если param пуст, target используется как есть.
+// GET и HEAD перенаправляются с кодом 301, остальные методы — с кодом 308, чтобы клиент повторил метод и тело запроса.
+// Без корректного ID ничего не пишет, и CustomHandler отвечает страницей 404.
+func legacyRedirect(param, target string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		location := target
+		if param != "" {
+			id, err := strconv.Atoi(r.FormValue(param))
+			if err != nil || id <= 0 {
+				return
+			}
+			query.Del(param)
+			location = fmt.Sprintf(target, id)
+		}
+		if len(query) > 0 {
+			location += "?" + query.Encode()
+		}
+
+		code := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, location, code)
+	}
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestLegacyRedirect проверяет перенаправление старых адресов с ID в строке запроса на новые пути.
+func TestLegacyRedirect(t *testing.T) {
+	tests := []struct {
+		method, target, body string
+		param, pattern       string
+		code                 int
+		want                 string
+	}{
+		{http.MethodGet, "/post?post_id=3", "", "post_id", "/post/%d", http.StatusMovedPermanently, "/post/3"},
+		{http.MethodGet, "/post?post_id=3&error=oops", "", "post_id", "/post/%d", http.StatusMovedPermanently, "/post/3?error=oops"},
+		{http.MethodPost, "/comment", "post_id=7&content=hello", "post_id", "/post/%d/comments", http.StatusPermanentRedirect, "/post/7/comments"},
+		{http.MethodDelete, "/delete-comment?comment_id=5", "", "comment_id", "/comment/%d", http.StatusPermanentRedirect, "/comment/5"},
+		{http.MethodGet, "/create-post", "", "", "/post/new", http.StatusMovedPermanently, "/post/new"},
+		{http.MethodGet, "/post?post_id=abc", "", "post_id", "/post/%d", 0, ""},
+		{http.MethodGet, "/profile", "", "user_id", "/profile/%d", 0, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		if tt.body != "" {
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		w := httptest.NewRecorder()
+		legacyRedirect(tt.param, tt.pattern).ServeHTTP(w, r)
+		if tt.code == 0 {
+			// Без корректного ID ответ не пишется, страницу 404 отдаёт CustomHandler.
+			if w.Header().Get("Location") != "" {
+				t.Errorf("%s %s: unexpected redirect to %q", tt.method, tt.target, w.Header().Get("Location"))
+			}
+			continue
+		}
+		if w.Code != tt.code || w.Header().Get("Location") != tt.want {
+			t.Errorf("%s %s: got %d %q, want %d %q", tt.method, tt.target, w.Code, w.Header().Get("Location"), tt.code, tt.want)
+		}
+	}
+}
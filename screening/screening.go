@@ -0,0 +1,163 @@
+// Package screening проверяет новые посты и комментарии автоматическим модератором до публикации.
+// Проверку выполняет внешний сервис: API модерации OpenAI или свой сервис с локальной моделью,
+// отвечающий по простому протоколу HTTP (см. HTTP). Форум сохраняет каждое решение с оценкой,
+// а содержимое с высокой оценкой скрывает до проверки модератором (см. database.RecordScreening).
+package screening
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Content — проверяемое содержимое: пост (с заголовком) или комментарий.
+type Content struct {
+	Kind     string `json:"kind"` // post или comment
+	Title    string `json:"title,omitempty"`
+	Text     string `json:"text"`
+	AuthorID int    `json:"author_id"`
+}
+
+// Verdict — решение проверки. Score — уверенность в нарушении от 0 до 1; Flagged — сервис сам счёл
+// содержимое нарушающим правила; Categories — названия нарушений (spam, harassment и т. п.).
+type Verdict struct {
+	Score      float64  `json:"score"`
+	Flagged    bool     `json:"flagged"`
+	Categories []string `json:"categories"`
+}
+
+// Hold сообщает, нужно ли скрыть содержимое до проверки модератором при пороге оценки threshold.
+func (v Verdict) Hold(threshold float64) bool {
+	return v.Flagged || v.Score >= threshold
+}
+
+// Screener проверяет содержимое. Ошибка означает, что решения нет: форум публикует содержимое без проверки.
+type Screener interface {
+	Name() string
+	Screen(ctx context.Context, c Content) (Verdict, error)
+}
+
+// Providers — имена встроенных проверок для настройки screening.provider.
+var Providers = []string{"openai", "http"}
+
+// New возвращает встроенную проверку name. Пустое или неизвестное имя даёт nil: проверка выключена
+// (имя заранее проверяет config.Validate).
+func New(name, url, apiKey, model string, timeout time.Duration) Screener {
+	client := &http.Client{Timeout: timeout}
+	switch name {
+	case "openai":
+		if url == "" {
+			url = "https://api.openai.com"
+		}
+		return &OpenAI{URL: strings.TrimRight(url, "/"), APIKey: apiKey, Model: model, Client: client}
+	case "http":
+		return &HTTP{URL: url, APIKey: apiKey, Client: client}
+	}
+	return nil
+}
+
+// OpenAI проверяет содержимое через API модерации OpenAI (POST /v1/moderations).
+type OpenAI struct {
+	URL    string
+	APIKey string
+	Model  string // пусто — модель по умолчанию сервиса
+	Client *http.Client
+}
+
+// Name возвращает имя проверки для журнала и базы.
+func (o *OpenAI) Name() string { return "openai" }
+
+// Screen отправляет заголовок и текст одним вводом; оценка — наибольшая из оценок категорий.
+func (o *OpenAI) Screen(ctx context.Context, c Content) (Verdict, error) {
+	payload := map[string]interface{}{"input": joinContent(c)}
+	if o.Model != "" {
+		payload["model"] = o.Model
+	}
+	var resp struct {
+		Results []struct {
+			Flagged        bool               `json:"flagged"`
+			Categories     map[string]bool    `json:"categories"`
+			CategoryScores map[string]float64 `json:"category_scores"`
+		} `json:"results"`
+	}
+	if err := postJSON(ctx, o.Client, o.URL+"/v1/moderations", o.APIKey, payload, &resp); err != nil {
+		return Verdict{}, fmt.Errorf("openai: %w", err)
+	}
+	if len(resp.Results) == 0 {
+		return Verdict{}, fmt.Errorf("openai: empty moderation result")
+	}
+	result := resp.Results[0]
+	v := Verdict{Flagged: result.Flagged}
+	for name, score := range result.CategoryScores {
+		v.Score = max(v.Score, score)
+		if result.Categories[name] {
+			v.Categories = append(v.Categories, name)
+		}
+	}
+	sort.Strings(v.Categories)
+	return v, nil
+}
+
+// HTTP проверяет содержимое своим сервисом, например с локальной моделью классификации.
+// Сервис получает POST с JSON Content ({"kind", "title", "text", "author_id"}) и отвечает JSON Verdict
+// ({"score": 0.93, "flagged": true, "categories": ["spam"]}).
+type HTTP struct {
+	URL    string
+	APIKey string // передаётся как Bearer-токен, если задан
+	Client *http.Client
+}
+
+// Name возвращает имя проверки для журнала и базы.
+func (h *HTTP) Name() string { return "http" }
+
+// Screen отправляет содержимое сервису и возвращает его решение.
+func (h *HTTP) Screen(ctx context.Context, c Content) (Verdict, error) {
+	var v Verdict
+	if err := postJSON(ctx, h.Client, h.URL, h.APIKey, c, &v); err != nil {
+		return Verdict{}, fmt.Errorf("screening service: %w", err)
+	}
+	if v.Score < 0 || v.Score > 1 {
+		return Verdict{}, fmt.Errorf("screening service: score %v is out of range 0..1", v.Score)
+	}
+	return v, nil
+}
+
+// joinContent объединяет заголовок и текст поста в один ввод.
+func joinContent(c Content) string {
+	if c.Title == "" {
+		return c.Text
+	}
+	return c.Title + "\n\n" + c.Text
+}
+
+// postJSON отправляет payload и разбирает JSON-ответ в dst; ответ не 2xx считается ошибкой.
+func postJSON(ctx context.Context, client *http.Client, url, apiKey string, payload, dst interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(dst)
+}
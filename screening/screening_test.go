@@ -0,0 +1,58 @@
+package screening
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestOpenAI проверяет разбор ответа API модерации: оценка — наибольшая по категориям.
+func TestOpenAI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/v1/moderations" || r.Header.Get("Authorization") != "Bearer key" || req["input"] != "Title\n\nText" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"results":[{"flagged":true,"categories":{"harassment":true,"spam":false},
+			"category_scores":{"harassment":0.91,"spam":0.2}}]}`))
+	}))
+	defer srv.Close()
+
+	v, err := New("openai", srv.URL, "key", "", time.Minute).Screen(context.Background(), Content{Kind: "post", Title: "Title", Text: "Text"})
+	if err != nil || !v.Flagged || v.Score != 0.91 || !slices.Equal(v.Categories, []string{"harassment"}) {
+		t.Fatalf("Screen = %+v, %v", v, err)
+	}
+}
+
+// TestHTTP проверяет протокол своего сервиса и отказ от оценки вне диапазона.
+func TestHTTP(t *testing.T) {
+	score := 0.4
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var c Content
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil || c.Kind != "comment" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(Verdict{Score: score, Categories: []string{"spam"}})
+	}))
+	defer srv.Close()
+
+	s := New("http", srv.URL, "", "", time.Minute)
+	v, err := s.Screen(context.Background(), Content{Kind: "comment", Text: "buy now"})
+	if err != nil || v.Score != 0.4 || v.Hold(0.8) || !v.Hold(0.3) {
+		t.Fatalf("Screen = %+v, %v", v, err)
+	}
+	score = 7
+	if _, err := s.Screen(context.Background(), Content{Kind: "comment", Text: "x"}); err == nil {
+		t.Error("Screen accepted a score out of range")
+	}
+	if New("", "", "", "", time.Minute) != nil {
+		t.Error("New without a provider returned one")
+	}
+}
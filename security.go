@@ -0,0 +1,66 @@
+// Package main содержит middleware, добавляющее заголовки безопасности ко всем ответам.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"forum/config"
+	"forum/proxy"
+)
+
+// defaultCSP — политика по умолчанию. Шаблоны используют встроенные обработчики (onclick) и стили,
+// а стили подключают шрифты Google, поэтому 'unsafe-inline' и fonts.googleapis.com разрешены.
+// %s заменяется источниками изображений (с пробелом впереди).
+const defaultCSP = "default-src 'self'; " +
+	"script-src 'self' 'unsafe-inline'; " +
+	"style-src 'self' 'unsafe-inline' https://fonts.googleapis.com; " +
+	"font-src 'self' https://fonts.gstatic.com; " +
+	"img-src 'self' data:%s; " +
+	"connect-src 'self'; object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+
+// contentSecurityPolicy возвращает значение Content-Security-Policy или пустую строку, если заголовок отключён.
+// proxyImages — изображения постов идут через прокси /img, и внешние источники изображений не нужны.
+func contentSecurityPolicy(cfg config.Security, proxyImages bool) string {
+	switch cfg.ContentSecurityPolicy {
+	case "off":
+		return ""
+	case "":
+		images := " https:"
+		switch {
+		case len(cfg.ImageHosts) > 0:
+			images = " " + strings.Join(cfg.ImageHosts, " ")
+		case proxyImages:
+			images = ""
+		}
+		return fmt.Sprintf(defaultCSP, images)
+	default:
+		return cfg.ContentSecurityPolicy
+	}
+}
+
+// securityHeaders добавляет к ответам Content-Security-Policy, X-Content-Type-Options, Referrer-Policy,
+// X-Frame-Options и, для запросов по HTTPS (напрямую или через доверенный прокси), Strict-Transport-Security.
+// proxyImages сужает источники изображений в Content-Security-Policy (см. contentSecurityPolicy).
+func securityHeaders(cfg config.Security, proxyImages bool, next http.Handler) http.Handler {
+	csp := contentSecurityPolicy(cfg, proxyImages)
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", int64(cfg.HSTSMaxAge.Seconds()))
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		if csp != "" {
+			h.Set("Content-Security-Policy", csp)
+		}
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		h.Set("X-Frame-Options", "DENY")
+		if hsts != "" && proxy.IsSecure(r) {
+			h.Set("Strict-Transport-Security", hsts)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"forum/config"
+)
+
+// TestSecurityHeaders проверяет заголовки безопасности и то, что HSTS отправляется только по HTTPS.
+func TestSecurityHeaders(t *testing.T) {
+	cfg := config.Security{ImageHosts: []string{"https://i.imgur.com"}, HSTSMaxAge: time.Hour}
+	handler := securityHeaders(cfg, true, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	csp := w.Header().Get("Content-Security-Policy")
+	if !strings.Contains(csp, "img-src 'self' data: https://i.imgur.com;") || !strings.Contains(csp, "frame-ancestors 'none'") {
+		t.Errorf("Content-Security-Policy = %q", csp)
+	}
+	for name, want := range map[string]string{
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           "strict-origin-when-cross-origin",
+		"Strict-Transport-Security": "",
+	} {
+		if got := w.Header().Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.TLS = &tls.ConnectionState{}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if got := w.Header().Get("Strict-Transport-Security"); got != "max-age=3600" {
+		t.Errorf("Strict-Transport-Security over HTTPS = %q", got)
+	}
+
+	if got := contentSecurityPolicy(config.Security{ContentSecurityPolicy: "off"}, false); got != "" {
+		t.Errorf("disabled policy = %q", got)
+	}
+	if got := contentSecurityPolicy(config.Security{}, false); !strings.Contains(got, "img-src 'self' data: https:;") {
+		t.Errorf("default policy = %q", got)
+	}
+	if got := contentSecurityPolicy(config.Security{}, true); !strings.Contains(got, "img-src 'self' data:;") {
+		t.Errorf("policy with image proxy = %q", got)
+	}
+}
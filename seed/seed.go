@@ -0,0 +1,224 @@
+// Package seed генерирует демонстрационные данные (пользователей, посты, комментарии и голоса)
+// для проверки постраничного вывода, ранжирования и производительности без ручного наполнения базы.
+// Данные записываются через пакет importer, поэтому попадают в базу одной транзакцией.
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"forum/importer"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// EmailDomain — домен адресов демонстрационных пользователей: user001@seed.example и т. д.
+const EmailDomain = "seed.example"
+
+// Options задаёт объём и параметры генерируемых данных.
+type Options struct {
+	Users    int           // число пользователей
+	Posts    int           // число постов
+	Comments int           // среднее число комментариев на пост
+	Votes    int           // среднее число голосов на пост (не больше числа пользователей)
+	Period   time.Duration // посты распределяются по этому периоду до текущего момента
+	Password string        // пароль всех демонстрационных пользователей
+	Rand     int64         // начальное значение генератора; одинаковое значение даёт одинаковые данные
+}
+
+// DefaultOptions возвращает небольшой набор данных, достаточный для нескольких страниц ленты.
+func DefaultOptions() Options {
+	return Options{
+		Users:    20,
+		Posts:    200,
+		Comments: 4,
+		Votes:    6,
+		Period:   90 * 24 * time.Hour,
+		Password: "demo-password",
+		Rand:     1,
+	}
+}
+
+// Validate проверяет объём данных.
+func (o Options) Validate() error {
+	if o.Users < 1 {
+		return fmt.Errorf("at least one user is required")
+	}
+	if o.Posts < 0 || o.Comments < 0 || o.Votes < 0 {
+		return fmt.Errorf("posts, comments and votes must not be negative")
+	}
+	if o.Period <= 0 {
+		return fmt.Errorf("period must be positive")
+	}
+	if len(o.Password) < 8 {
+		return fmt.Errorf("password must be at least 8 characters")
+	}
+	return nil
+}
+
+// Run генерирует данные и записывает их в базу. Пользователи с теми же адресами, созданные
+// предыдущим запуском, переиспользуются; всем демонстрационным пользователям назначается пароль opts.Password.
+func Run(ctx context.Context, db *sql.DB, opts Options) (importer.Report, error) {
+	if err := opts.Validate(); err != nil {
+		return importer.Report{}, err
+	}
+	report, err := importer.Import(ctx, db, Generate(opts, time.Now()))
+	if err != nil {
+		return report, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return report, err
+	}
+	_, err = db.ExecContext(ctx, "UPDATE users SET password = ? WHERE email LIKE ?", string(hash), "%@"+EmailDomain)
+	return report, err
+}
+
+// Generate строит выгрузку с демонстрационными данными; время постов отсчитывается назад от now.
+func Generate(opts Options, now time.Time) *importer.Dump {
+	rnd := rand.New(rand.NewSource(opts.Rand))
+	dump := &importer.Dump{}
+
+	emails := make([]string, opts.Users)
+	for i := range emails {
+		emails[i] = fmt.Sprintf("user%03d@%s", i+1, EmailDomain)
+		first, last := pick(rnd, firstNames), pick(rnd, lastNames)
+		dump.Users = append(dump.Users, importer.User{
+			Email:       emails[i],
+			Username:    fmt.Sprintf("%s_%s%d", strings.ToLower(first), strings.ToLower(last), i+1),
+			DisplayName: first + " " + last,
+			CreatedAt:   importer.Timestamp{Time: now.Add(-opts.Period - time.Duration(rnd.Int63n(int64(opts.Period))))},
+		})
+	}
+
+	votes := min(opts.Votes, opts.Users)
+	for i := 0; i < opts.Posts; i++ {
+		postID := importer.SourceID(fmt.Sprintf("p%d", i+1))
+		createdAt := now.Add(-time.Duration(rnd.Int63n(int64(opts.Period))))
+		postCategories := []string{pick(rnd, categoryNames)}
+		if rnd.Intn(3) == 0 {
+			postCategories = append(postCategories, pick(rnd, categoryNames))
+		}
+		dump.Posts = append(dump.Posts, importer.Post{
+			ID:          postID,
+			AuthorEmail: pick(rnd, emails),
+			Title:       title(rnd),
+			Content:     paragraph(rnd, 2+rnd.Intn(5)),
+			Categories:  postCategories,
+			CreatedAt:   importer.Timestamp{Time: createdAt},
+		})
+
+		// Число комментариев и голосов разбросано вокруг среднего, чтобы у ранжирования были различия.
+		for j, n := 0, spread(rnd, opts.Comments); j < n; j++ {
+			commentID := importer.SourceID(fmt.Sprintf("%s-c%d", postID, j+1))
+			dump.Comments = append(dump.Comments, importer.Comment{
+				ID:          commentID,
+				PostID:      postID,
+				AuthorEmail: pick(rnd, emails),
+				Content:     sentence(rnd),
+				CreatedAt:   importer.Timestamp{Time: between(rnd, createdAt, now)},
+			})
+			if rnd.Intn(2) == 0 {
+				dump.Votes = append(dump.Votes, importer.Vote{UserEmail: pick(rnd, emails), CommentID: commentID, Value: vote(rnd, 0.8)})
+			}
+		}
+		// Каждому посту — своя «популярность»: доля лайков от 0.3 до 0.95.
+		likeShare := 0.3 + rnd.Float64()*0.65
+		for _, voter := range rnd.Perm(opts.Users)[:min(spread(rnd, votes), opts.Users)] {
+			dump.Votes = append(dump.Votes, importer.Vote{UserEmail: emails[voter], PostID: postID, Value: vote(rnd, likeShare)})
+		}
+	}
+	return dump
+}
+
+// spread возвращает случайное число от 0 до 2*avg со средним avg.
+func spread(rnd *rand.Rand, avg int) int {
+	if avg <= 0 {
+		return 0
+	}
+	return rnd.Intn(2*avg + 1)
+}
+
+// vote возвращает лайк с вероятностью likeShare, иначе дизлайк.
+func vote(rnd *rand.Rand, likeShare float64) int {
+	if rnd.Float64() < likeShare {
+		return 1
+	}
+	return -1
+}
+
+// between возвращает случайный момент между from и to.
+func between(rnd *rand.Rand, from, to time.Time) time.Time {
+	if !to.After(from) {
+		return from
+	}
+	return from.Add(time.Duration(rnd.Int63n(int64(to.Sub(from)))))
+}
+
+func pick(rnd *rand.Rand, list []string) string {
+	return list[rnd.Intn(len(list))]
+}
+
+// title собирает заголовок поста из шаблона и слов словаря.
+func title(rnd *rand.Rand) string {
+	return capitalize(fmt.Sprintf(pick(rnd, titleTemplates), pick(rnd, topics)))
+}
+
+// sentence собирает предложение из случайных слов.
+func sentence(rnd *rand.Rand) string {
+	n := 6 + rnd.Intn(12)
+	words := make([]string, n)
+	for i := range words {
+		words[i] = pick(rnd, wordList)
+	}
+	return capitalize(strings.Join(words, " ")) + "."
+}
+
+// capitalize делает первую букву строки заглавной (строки словаря — ASCII).
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// paragraph собирает абзац из n предложений.
+func paragraph(rnd *rand.Rand, n int) string {
+	sentences := make([]string, n)
+	for i := range sentences {
+		sentences[i] = sentence(rnd)
+	}
+	return strings.Join(sentences, " ")
+}
+
+var (
+	categoryNames = []string{"news", "life", "auto", "creative", "gadgets", "science", "games", "other"}
+
+	firstNames = []string{"Alex", "Maria", "Ivan", "Olga", "Timur", "Aigerim", "Daniyar", "Elena", "Sergey", "Dana", "Nurlan", "Kate"}
+	lastNames  = []string{"Petrov", "Ivanova", "Sidorov", "Kim", "Abenov", "Smirnova", "Lee", "Orlov", "Zhakupova", "Volkov"}
+
+	titleTemplates = []string{
+		"What do you think about %s?",
+		"My experience with %s",
+		"%s: a beginner's question",
+		"Is %s worth it in 2025?",
+		"Best resources to learn %s",
+		"Unpopular opinion about %s",
+		"Help needed with %s",
+		"%s — weekly discussion",
+	}
+	topics = []string{
+		"electric cars", "Go generics", "indie games", "home gardening", "mechanical keyboards", "space telescopes",
+		"street photography", "remote work", "SQLite in production", "board games", "smart watches", "learning Kazakh",
+		"open source", "retro consoles", "running a marathon", "3D printing",
+	}
+	wordList = strings.Fields(`the a this that we you they it really quite very just also maybe probably
+		forum post idea question answer problem solution project team code game car phone city weekend book
+		think know like want need try use build share read write play drive learn find help start finish
+		good bad new old fast slow simple hard interesting useful strange great small big first last
+		today yesterday tomorrow always never sometimes often again still already here there because but and or`)
+)
@@ -0,0 +1,39 @@
+package seed
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestGenerate проверяет воспроизводимость данных и то, что один пользователь голосует за пост не больше одного раза.
+func TestGenerate(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Users, opts.Posts, opts.Votes = 5, 50, 10
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	dump := Generate(opts, now)
+	if !reflect.DeepEqual(dump, Generate(opts, now)) {
+		t.Fatal("Generate with the same seed produced different data")
+	}
+	if len(dump.Users) != 5 || len(dump.Posts) != 50 {
+		t.Fatalf("Generate = %d users, %d posts", len(dump.Users), len(dump.Posts))
+	}
+
+	voted := make(map[string]bool)
+	for _, v := range dump.Votes {
+		if v.PostID == "" {
+			continue
+		}
+		key := string(v.PostID) + "/" + v.UserEmail
+		if voted[key] {
+			t.Fatalf("duplicate vote %s", key)
+		}
+		voted[key] = true
+	}
+	for _, p := range dump.Posts {
+		if p.CreatedAt.After(now) || p.CreatedAt.Before(now.Add(-opts.Period)) {
+			t.Fatalf("post %s created at %v, outside the seed period", p.ID, p.CreatedAt)
+		}
+	}
+}
@@ -1,12 +1,79 @@
 // /static/script.js
 let userRole; // Объявляем без значения, зададим его из HTML
 
+// t возвращает сообщение каталога на языке страницы (window.messages задаёт шаблон),
+// подставляя аргументы вместо %d и %s по порядку.
+function t(key, ...args) {
+    let message = (window.messages || {})[key] || key;
+    args.forEach(arg => { message = message.replace(/%[ds]/, arg); });
+    return message;
+}
+
 document.addEventListener("DOMContentLoaded", () => {
     userRole = window.userRole || "";
     initCountdownTimer();
     createSnowfall();
+    initInfiniteScroll();
 });
 
+// initInfiniteScroll включает подгрузку при прокрутке для ленты (/api/feed) и комментариев поста (/api/comments).
+function initInfiniteScroll() {
+    loadMoreOnScroll(document.getElementById("feed-more"), document.querySelector(".posts"), "js.feed_failed");
+    const comments = document.getElementById("comments-more");
+    if (comments) {
+        loadMoreOnScroll(comments, comments.previousElementSibling, "js.comments_failed");
+    }
+}
+
+// loadMoreOnScroll подгружает в container следующую порцию HTML из data-api ссылки «Показать ещё»,
+// когда ссылка попадает в область видимости или по ней щёлкают. Без JavaScript ссылка открывает следующую страницу.
+function loadMoreOnScroll(more, container, failedKey) {
+    if (!more || !container) return;
+
+    let loading = false;
+    const load = () => {
+        if (loading) return;
+        loading = true;
+        fetch(more.dataset.api, {
+            credentials: 'same-origin',
+            headers: { 'Accept': 'application/json' }
+        })
+            .then(response => response.json())
+            .then(data => {
+                if (!data.success) throw new Error(data.message);
+                container.insertAdjacentHTML("beforeend", data.html);
+                if (!data.next_cursor) {
+                    if (observer) observer.disconnect();
+                    more.remove();
+                    return;
+                }
+                const api = new URL(more.dataset.api, window.location.href);
+                const page = new URL(more.href, window.location.href);
+                api.searchParams.set("cursor", data.next_cursor);
+                page.searchParams.set("cursor", data.next_cursor);
+                more.dataset.api = api.pathname + api.search;
+                more.href = page.pathname + page.search;
+                loading = false;
+            })
+            .catch(error => {
+                console.error('Error loading more:', error);
+                if (observer) observer.disconnect();
+                more.textContent = t(failedKey);
+                more.classList.add("failed");
+            });
+    };
+
+    more.addEventListener("click", event => {
+        if (more.classList.contains("failed")) return;
+        event.preventDefault();
+        load();
+    });
+    const observer = "IntersectionObserver" in window
+        ? new IntersectionObserver(entries => { if (entries.some(e => e.isIntersecting)) load(); }, { rootMargin: "400px" })
+        : null;
+    if (observer) observer.observe(more);
+}
+
 function initCountdownTimer() {
     const countdownEl = document.getElementById("countdown-timer");
     const miniCountdownEl = document.getElementById("mini-countdown");
@@ -44,12 +111,16 @@ function createSnowfall() {
 }
 
 function vote(postId, action) {
-    fetch(`/${action}?post_id=${postId}`, { method: 'GET', credentials: 'same-origin' })
+    fetch(`/post/${postId}/${action}`, {
+        method: 'POST',
+        credentials: 'same-origin',
+        headers: { 'Accept': 'application/json' }
+    })
         .then(response => response.json())
         .then(data => {
             if (data.success) {
-                document.getElementById(`likes-${postId}`).textContent = `Likes: ${data.likes}`;
-                document.getElementById(`dislikes-${postId}`).textContent = `Dislikes: ${data.dislikes}`;
+                document.getElementById(`likes-${postId}`).textContent = t("votes.likes", data.likes);
+                document.getElementById(`dislikes-${postId}`).textContent = t("votes.dislikes", data.dislikes);
                 const likeBtn = document.querySelector(`#votes-${postId} .vote-btn[data-action="like"]`);
                 const dislikeBtn = document.querySelector(`#votes-${postId} .vote-btn[data-action="dislike"]`);
                 likeBtn.classList.toggle('liked', data.user_vote === 1);
@@ -62,15 +133,17 @@ function vote(postId, action) {
 }
 
 function voteComment(commentId, action) {
-    fetch(`/${action}?comment_id=${commentId}`, {
-        method: 'POST', // Используем POST вместо GET
-        credentials: 'same-origin'
+    const kind = action === 'comment-like' ? 'like' : 'dislike';
+    fetch(`/comment/${commentId}/${kind}`, {
+        method: 'POST',
+        credentials: 'same-origin',
+        headers: { 'Accept': 'application/json' }
     })
     .then(response => response.json())
     .then(data => {
         if (data.success) {
-            document.getElementById(`comment-likes-${commentId}`).textContent = `Likes: ${data.likes}`;
-            document.getElementById(`comment-dislikes-${commentId}`).textContent = `Dislikes: ${data.dislikes}`;
+            document.getElementById(`comment-likes-${commentId}`).textContent = t("votes.likes", data.likes);
+            document.getElementById(`comment-dislikes-${commentId}`).textContent = t("votes.dislikes", data.dislikes);
             const likeBtn = document.querySelector(`#comment-${commentId} .vote-btn[data-action="comment-like"]`);
             const dislikeBtn = document.querySelector(`#comment-${commentId} .vote-btn[data-action="comment-dislike"]`);
             likeBtn.classList.toggle('liked', data.user_vote === 1);
@@ -89,17 +162,17 @@ function addComment(event, postId) {
     const errorDiv = document.getElementById(`error-${postId}`);
 
     if (content.trim() === "") {
-        errorDiv.textContent = "Comment content cannot be empty or contain only whitespace";
+        errorDiv.textContent = t("comment.error.empty");
         errorDiv.style.display = "block";
         return;
     }
     if (content.trim().length < 3) {
-        errorDiv.textContent = "Comment must be at least 3 characters long";
+        errorDiv.textContent = t("comment.error.too_short", 3);
         errorDiv.style.display = "block";
         return;
     }
     if (content.trim().length > 500) {
-        errorDiv.textContent = "Comment cannot be longer than 500 characters";
+        errorDiv.textContent = t("comment.error.too_long", 500);
         errorDiv.style.display = "block";
         return;
     }
@@ -107,33 +180,38 @@ function addComment(event, postId) {
     errorDiv.style.display = "none";
 
     const formData = new URLSearchParams();
-    formData.append("post_id", postId); // Используем postId напрямую
     formData.append("content", content);
 
-    fetch("/comment", {
+    fetch(`/post/${postId}/comments`, {
         method: "POST",
         body: formData,
         credentials: "same-origin",
         headers: {
+            "Accept": "application/json",
             "Content-Type": "application/x-www-form-urlencoded"
         }
     })
     .then(response => response.json())
     .then(data => {
-        if (data.success) {
+        if (data.success && data.held) {
+            // Комментарий скрыт автоматической проверкой до решения модератора.
+            errorDiv.textContent = data.message;
+            errorDiv.style.display = "block";
+            form.reset();
+        } else if (data.success) {
             const commentsDiv = document.getElementById(`comments-${postId}`);
             const comment = document.createElement("div");
             comment.className = "comment";
             comment.id = `comment-${data.comment_id}`;
             comment.innerHTML = `
-                <p>${data.content} by <a href="/profile?user_id=${data.user_id}">${data.username}</a> (${data.created_at})</p>
-                <p id="comment-likes-${data.comment_id}">Likes: 0</p>
-                <p id="comment-dislikes-${data.comment_id}">Dislikes: 0</p>
-                <button onclick="voteComment(${data.comment_id}, 'comment-like')" class="vote-btn" data-action="comment-like">Like</button>
-                <button onclick="voteComment(${data.comment_id}, 'comment-dislike')" class="vote-btn" data-action="comment-dislike">Dislike</button>
+                <p>${data.content} — <a href="/profile/${data.user_id}">${data.author || data.username}</a> (${data.created_at})</p>
+                <p id="comment-likes-${data.comment_id}">${t("votes.likes", 0)}</p>
+                <p id="comment-dislikes-${data.comment_id}">${t("votes.dislikes", 0)}</p>
+                <button onclick="voteComment(${data.comment_id}, 'comment-like')" class="vote-btn" data-action="comment-like">${t("votes.like")}</button>
+                <button onclick="voteComment(${data.comment_id}, 'comment-dislike')" class="vote-btn" data-action="comment-dislike">${t("votes.dislike")}</button>
             `;
             comment.classList.add("fade-in");
-            commentsDiv.appendChild(comment);
+            commentsDiv.prepend(comment);
             form.reset();
         } else {
             errorDiv.textContent = data.message;
@@ -142,18 +220,19 @@ function addComment(event, postId) {
     })
     .catch(error => {
         console.error("Error adding comment:", error);
-        errorDiv.textContent = "Failed to add comment";
+        errorDiv.textContent = t("js.comment_failed");
         errorDiv.style.display = "block";
     });
 }
 
 function deleteComment(commentId) {
-    if (confirm("Are you sure you want to delete this comment?")) {
+    if (confirm(t("js.confirm_delete_comment"))) {
         console.log("Deleting comment with ID:", commentId);
 
-        fetch(`/delete-comment?comment_id=${commentId}`, {
+        fetch(`/comment/${commentId}`, {
             method: 'DELETE', // Используем DELETE
             credentials: 'same-origin',
+            headers: { 'Accept': 'application/json' }
         })
         .then(response => {
             console.log("Fetch response status:", response.status);
@@ -164,7 +243,7 @@ function deleteComment(commentId) {
             if (data.success) {
                 const notification = document.createElement('div');
                 notification.className = 'notification success';
-                notification.textContent = 'Comment deleted successfully';
+                notification.textContent = t("js.comment_deleted");
                 document.body.appendChild(notification);
 
                 // Удаляем комментарий из DOM
@@ -183,7 +262,7 @@ function deleteComment(commentId) {
         })
         .catch(error => {
             console.error('Error in fetch:', error);
-            alert('Failed to delete comment');
+            alert(t("js.delete_comment_failed"));
         });
     }
 }
@@ -193,12 +272,12 @@ function validateCreatePostForm() {
     const selectedOptions = select.selectedOptions;
     
     if (selectedOptions.length === 0) {
-        alert("Please choose category");
+        alert(t("js.choose_category"));
         return false;
     }
     
     if (selectedOptions.length > 3) {
-        alert("You can select up to 3 categories");
+        alert(t("js.too_many_categories"));
         return false;
     }
     
@@ -206,12 +285,13 @@ function validateCreatePostForm() {
 }
 
 function deletePost(postId) {
-    if (confirm("Are you sure you want to delete this post?")) {
+    if (confirm(t("js.confirm_delete_post"))) {
         console.log("Deleting post with ID:", postId);
 
-        fetch(`/delete-post?post_id=${postId}`, {
+        fetch(`/post/${postId}`, {
             method: 'DELETE',
-            credentials: 'same-origin'
+            credentials: 'same-origin',
+            headers: { 'Accept': 'application/json' }
         })
         .then(response => {
             console.log("Fetch response status:", response.status);
@@ -223,7 +303,7 @@ function deletePost(postId) {
 	                // Показываем уведомление об успешном удалении
                 const notification = document.createElement('div');
                 notification.className = 'notification success';
-                notification.textContent = 'Post deleted successfully';
+                notification.textContent = t("js.post_deleted");
                 document.body.appendChild(notification);
 
                 // Удаляем пост из DOM
@@ -241,7 +321,7 @@ function deletePost(postId) {
                     const postsSection = document.querySelector('.posts');
                     if (postsSection) {
                         console.log("No posts remaining, updating DOM");
-                        postsSection.innerHTML = '<p class="no-posts">No posts available.</p>';
+                        postsSection.innerHTML = `<p class="no-posts">${t("js.no_posts")}</p>`;
                     }
                 }
 
@@ -253,7 +333,7 @@ function deletePost(postId) {
         })
         .catch(error => {
             console.error('Error in fetch:', error);
-            alert('Failed to delete post');
+            alert(t("js.delete_post_failed"));
         });
     }
 }
@@ -265,11 +345,12 @@ function editPost(event, postId) {
     const formData = new FormData(form);
     const csrfToken = document.getElementById('csrf_token').value;
 
-    fetch(`/edit-post?post_id=${postId}`, {
-        method: 'PUT',
+    fetch(`/post/${postId}/edit`, {
+        method: 'POST',
         body: formData,
         credentials: 'same-origin',
         headers: {
+            'Accept': 'application/json',
             'X-CSRF-Token': csrfToken
         }
     })
@@ -282,7 +363,7 @@ function editPost(event, postId) {
         if (data.success) {
             const notification = document.createElement('div');
             notification.className = 'notification success';
-            notification.textContent = 'Post updated successfully';
+            notification.textContent = t("js.post_updated");
             document.body.appendChild(notification);
             setTimeout(() => {
                 notification.remove();
@@ -296,6 +377,99 @@ function editPost(event, postId) {
     })
     .catch(error => {
         console.error('Error in fetch:', error);
-        alert('Failed to update post');
+        alert(t("js.update_post_failed"));
+    });
+}
+// translatePost показывает машинный перевод поста на язык lang вместо оригинала (POST /post/{id}/translate);
+// повторное нажатие кнопки возвращает оригинал. Перевод запрашивается у сервера один раз за показ страницы.
+function translatePost(postId, lang, button) {
+    const title = document.getElementById(`post-title-${postId}`);
+    const text = document.getElementById(`post-text-${postId}`);
+    if (!title || !text) return;
+
+    if (!button.dataset.label) {
+        button.dataset.label = button.textContent;
+    }
+    const show = (translated) => {
+        const source = translated ? button.dataset : { title: button.dataset.originalTitle, content: button.dataset.originalContent };
+        title.textContent = source.title;
+        text.textContent = source.content;
+        text.classList.toggle('machine-translated', translated);
+        text.title = translated ? t("js.machine_translated") : "";
+        button.textContent = translated ? t("js.show_original") : button.dataset.label;
+        button.dataset.shown = translated ? "1" : "";
+    };
+
+    if (button.dataset.shown) {
+        show(false);
+        return;
+    }
+    if (button.dataset.title !== undefined) {
+        show(true);
+        return;
+    }
+
+    const formData = new URLSearchParams();
+    formData.append("lang", lang);
+    button.disabled = true;
+    fetch(`/post/${postId}/translate`, {
+        method: 'POST',
+        body: formData,
+        credentials: 'same-origin',
+        headers: {
+            'Accept': 'application/json',
+            'Content-Type': 'application/x-www-form-urlencoded'
+        }
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.success) {
+            button.dataset.originalTitle = title.textContent;
+            button.dataset.originalContent = text.textContent;
+            button.dataset.title = data.title;
+            button.dataset.content = data.content;
+            show(true);
+        } else {
+            alert(data.message);
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert(t("js.translate_failed"));
+    })
+    .finally(() => { button.disabled = false; });
+}
+
+function summarizeThread(postId, button) {
+    const box = document.getElementById(`thread-summary-${postId}`);
+    if (!box) return;
+
+    button.disabled = true;
+    button.textContent = t("js.summarizing");
+    fetch(`/post/${postId}/summarize`, {
+        method: 'POST',
+        credentials: 'same-origin',
+        headers: { 'Accept': 'application/json' }
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.success) {
+            const heading = document.createElement("h5");
+            heading.textContent = `📝 ${t("js.summary")}`;
+            const text = document.createElement("p");
+            text.textContent = data.summary;
+            const note = document.createElement("small");
+            note.textContent = t("js.summary_note", data.comments);
+            box.replaceChildren(heading, text, note);
+        } else {
+            alert(data.message);
+            button.remove();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert(t("js.summarize_failed"));
+        button.disabled = false;
+        button.textContent = `📝 ${t("js.summarize")}`;
     });
-}
\ No newline at end of file
+}
@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3Options — параметры S3-совместимого хранилища.
+type S3Options struct {
+	Endpoint  string // адрес API, например https://s3.eu-central-1.amazonaws.com или http://minio:9000
+	Region    string // регион подписи запросов; пусто — us-east-1
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PublicURL — адрес, по которому браузеры получают файлы напрямую (публичный бакет или CDN), без «/» в конце.
+	// Пусто — файлы отдаёт форум по адресам LocalPrefix+ключ, читая их из бакета.
+	PublicURL string
+	Client    *http.Client // nil — http.DefaultClient
+}
+
+// S3 хранит файлы в бакете S3-совместимого хранилища. Запросы подписываются AWS Signature Version 4,
+// адреса объектов строятся в стиле пути (endpoint/bucket/key), который поддерживают все совместимые хранилища.
+type S3 struct {
+	opts S3Options
+}
+
+// NewS3 проверяет параметры и возвращает хранилище в бакете opts.Bucket.
+func NewS3(opts S3Options) (*S3, error) {
+	u, err := url.Parse(opts.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("storage: invalid S3 endpoint %q", opts.Endpoint)
+	}
+	if opts.Bucket == "" {
+		return nil, errors.New("storage: S3 bucket is required")
+	}
+	opts.Endpoint = strings.TrimRight(opts.Endpoint, "/")
+	opts.PublicURL = strings.TrimRight(opts.PublicURL, "/")
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	return &S3{opts: opts}, nil
+}
+
+// Put загружает файл целиком в память, чтобы подписать его хеш; загрузки форума ограничены по размеру.
+func (s *S3) Put(ctx context.Context, key, contentType string, r io.Reader) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s.error(resp)
+	}
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, "", nil)
+	if err != nil {
+		return nil, "", err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, resp.Header.Get("Content-Type"), nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, "", ErrNotFound
+	}
+	defer resp.Body.Close()
+	return nil, "", s.error(resp)
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s.error(resp)
+	}
+	return nil
+}
+
+func (s *S3) URL(key string) string {
+	if s.opts.PublicURL != "" {
+		return s.opts.PublicURL + "/" + key
+	}
+	return LocalPrefix + key
+}
+
+// List постранично запрашивает список объектов бакета (ListObjectsV2).
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	for {
+		resp, err := s.request(ctx, http.MethodGet, "", query, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := s.error(resp)
+			resp.Body.Close()
+			return nil, err
+		}
+		var page struct {
+			Contents []struct {
+				Key          string
+				LastModified time.Time
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("storage: S3 list: %w", err)
+		}
+		for _, c := range page.Contents {
+			objects = append(objects, Object{Key: c.Key, Modified: c.LastModified})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		query.Set("continuation-token", page.NextContinuationToken)
+	}
+}
+
+// do выполняет подписанный запрос method к объекту key с телом body.
+func (s *S3) do(ctx context.Context, method, key, contentType string, body []byte) (*http.Response, error) {
+	if !ValidKey(key) {
+		return nil, ErrInvalidKey
+	}
+	return s.request(ctx, method, key, nil, contentType, body)
+}
+
+// request выполняет подписанный запрос method к объекту key (пустой key — к самому бакету) с параметрами query.
+func (s *S3) request(ctx context.Context, method, key string, query url.Values, contentType string, body []byte) (*http.Response, error) {
+	target := s.opts.Endpoint + "/" + s.opts.Bucket
+	if key != "" {
+		target += "/" + key
+	}
+	if len(query) > 0 {
+		// Подпись требует параметров, отсортированных по имени и закодированных с %20 вместо «+».
+		target += "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, body, time.Now().UTC())
+	return s.opts.Client.Do(req)
+}
+
+// error возвращает ошибку с кодом ответа и началом его тела (S3 описывает ошибку в XML).
+func (s *S3) error(resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("storage: S3 %s %s: %s %s", resp.Request.Method, resp.Request.URL.Path, resp.Status, bytes.TrimSpace(msg))
+}
+
+// sign добавляет к запросу заголовки подписи AWS Signature Version 4 на момент now.
+// Подписываются host, x-amz-content-sha256 и x-amz-date; параметры запроса уже закодированы так, как требует подпись.
+func (s *S3) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + s.opts.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+
+	key := hmacSHA256([]byte("AWS4"+s.opts.SecretKey), day)
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.opts.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,90 @@
+// Package storage хранит файлы, которые пользователи загружают на форум (изображения постов): на диске сервера
+// или в S3-совместимом хранилище (Amazon S3, MinIO, Cloudflare R2 и т. п.), чтобы в продакшене файлы жили
+// вне машины с форумом. Обработчики работают с интерфейсом Storage и не зависят от выбранного хранилища.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrNotFound возвращается, если файла с таким ключом нет.
+var ErrNotFound = errors.New("storage: file not found")
+
+// ErrInvalidKey возвращается для ключа, который нельзя использовать как путь к файлу (см. ValidKey).
+var ErrInvalidKey = errors.New("storage: invalid key")
+
+// Storage — хранилище загруженных файлов. Ключ — относительный путь вида posts/2026/01/abc.png.
+type Storage interface {
+	// Put сохраняет содержимое r под ключом key, заменяя прежний файл.
+	Put(ctx context.Context, key, contentType string, r io.Reader) error
+	// Get открывает файл key и возвращает его содержимое и тип; закрыть его должен вызывающий.
+	// Если файла нет, возвращает ErrNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, string, error)
+	// Delete удаляет файл key; отсутствие файла ошибкой не считается.
+	Delete(ctx context.Context, key string) error
+	// URL возвращает адрес файла для страниц форума.
+	URL(key string) string
+	// List возвращает все файлы, ключи которых начинаются с prefix.
+	List(ctx context.Context, prefix string) ([]Object, error)
+}
+
+// Object — файл в хранилище.
+type Object struct {
+	Key      string
+	Modified time.Time // время последней записи
+}
+
+// LocalPrefix — путь, по которому форум сам отдаёт загруженные файлы (см. handlers.UploadHandler).
+const LocalPrefix = "/uploads/"
+
+// ValidKey сообщает, подходит ли key для ключа файла: непустые части из латинских букв, цифр, «-», «_» и «.»,
+// разделённые «/», без частей «.» и «..». Такой ключ безопасно использовать и как путь на диске, и в адресе.
+func ValidKey(key string) bool {
+	if key == "" || len(key) > 512 {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+		for _, c := range part {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// RemoveOrphans удаляет файлы с ключами на prefix, записанные раньше before, адреса которых (см. Storage.URL)
+// не использует форум: referenced сообщает, ссылается ли на адрес какой-нибудь пост или комментарий.
+// Свежие файлы не трогаются, потому что файл сохраняется раньше поста, который на него ссылается.
+// Возвращает число удалённых файлов.
+func RemoveOrphans(ctx context.Context, s Storage, prefix string, before time.Time, referenced func(ctx context.Context, url string) (bool, error)) (int, error) {
+	objects, err := s.List(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, obj := range objects {
+		if !obj.Modified.Before(before) {
+			continue
+		}
+		used, err := referenced(ctx, s.URL(obj.Key))
+		if err != nil {
+			return removed, err
+		}
+		if used {
+			continue
+		}
+		if err := s.Delete(ctx, obj.Key); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
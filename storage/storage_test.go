@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestValidKey проверяет, что ключ не может выйти за пределы хранилища.
+func TestValidKey(t *testing.T) {
+	for key, want := range map[string]bool{
+		"posts/2026/01/a1b2.png": true,
+		"a.jpg":                  true,
+		"":                       false,
+		"/etc/passwd":            false,
+		"posts/../../secret":     false,
+		"posts//a.png":           false,
+		"posts/a b.png":          false,
+		`posts\a.png`:            false,
+	} {
+		if got := ValidKey(key); got != want {
+			t.Errorf("ValidKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+// TestLocal проверяет запись, чтение, удаление и адрес файла на диске.
+func TestLocal(t *testing.T) {
+	s, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorage(t, s)
+	if got := s.URL("posts/a.png"); got != "/uploads/posts/a.png" {
+		t.Errorf("URL = %q", got)
+	}
+	if err := s.Put(context.Background(), "../a.png", "image/png", strings.NewReader("x")); err != ErrInvalidKey {
+		t.Errorf("Put outside the directory: err = %v, want ErrInvalidKey", err)
+	}
+}
+
+// TestS3 проверяет запросы к S3-совместимому хранилищу на поддельном сервере, который хранит объекты в памяти
+// и требует подписанных запросов.
+func TestS3(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string]string{}
+	types := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") ||
+			r.Header.Get("X-Amz-Date") == "" || r.Header.Get("X-Amz-Content-Sha256") == "" {
+			http.Error(w, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = string(body)
+			types[r.URL.Path] = r.Header.Get("Content-Type")
+		case http.MethodGet:
+			if r.URL.Path == "/media" {
+				listObjects(w, r, objects)
+				return
+			}
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", types[r.URL.Path])
+			io.WriteString(w, body)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	s, err := NewS3(S3Options{Endpoint: server.URL, Region: "eu-west-1", Bucket: "media", AccessKey: "AKID", SecretKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorage(t, s)
+	if _, ok := objects["/media/posts/a.png"]; ok {
+		t.Error("object survived Delete")
+	}
+	if got := s.URL("posts/a.png"); got != "/uploads/posts/a.png" {
+		t.Errorf("URL without public URL = %q", got)
+	}
+
+	public, _ := NewS3(S3Options{Endpoint: server.URL, Bucket: "media", PublicURL: "https://cdn.example.com/"})
+	if got := public.URL("posts/a.png"); got != "https://cdn.example.com/posts/a.png" {
+		t.Errorf("URL with public URL = %q", got)
+	}
+	denied, _ := NewS3(S3Options{Endpoint: server.URL, Bucket: "media", AccessKey: "other"})
+	if err := denied.Put(context.Background(), "posts/a.png", "image/png", strings.NewReader("x")); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put with wrong credentials: err = %v", err)
+	}
+	if _, err := NewS3(S3Options{Endpoint: "minio:9000", Bucket: "media"}); err == nil {
+		t.Error("NewS3 accepted an endpoint without a scheme")
+	}
+}
+
+// listObjects отвечает на запрос ListObjectsV2 к бакету media по одному объекту на страницу,
+// чтобы проверить продолжение списка.
+func listObjects(w http.ResponseWriter, r *http.Request, objects map[string]string) {
+	var keys []string
+	for path := range objects {
+		key := strings.TrimPrefix(path, "/media/")
+		if strings.HasPrefix(key, r.URL.Query().Get("prefix")) && key > r.URL.Query().Get("continuation-token") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	fmt.Fprint(w, "<ListBucketResult>")
+	if len(keys) > 0 {
+		fmt.Fprintf(w, "<Contents><Key>%s</Key><LastModified>%s</LastModified></Contents>", keys[0], time.Now().UTC().Format(time.RFC3339))
+	}
+	if len(keys) > 1 {
+		fmt.Fprintf(w, "<IsTruncated>true</IsTruncated><NextContinuationToken>%s</NextContinuationToken>", keys[0])
+	}
+	fmt.Fprint(w, "</ListBucketResult>")
+}
+
+// TestRemoveOrphans проверяет, что удаляются только старые файлы, на которые никто не ссылается.
+func TestRemoveOrphans(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"posts/used.png", "posts/orphan.png"} {
+		if err := s.Put(ctx, key, "image/png", strings.NewReader("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	referenced := func(ctx context.Context, url string) (bool, error) {
+		return url == "/uploads/posts/used.png", nil
+	}
+
+	if n, err := RemoveOrphans(ctx, s, "posts/", time.Now().Add(-time.Hour), referenced); err != nil || n != 0 {
+		t.Fatalf("RemoveOrphans within the grace period = %d, %v, want 0", n, err)
+	}
+	if n, err := RemoveOrphans(ctx, s, "posts/", time.Now().Add(time.Hour), referenced); err != nil || n != 1 {
+		t.Fatalf("RemoveOrphans = %d, %v, want 1", n, err)
+	}
+	objects, err := s.List(ctx, "")
+	if err != nil || len(objects) != 1 || objects[0].Key != "posts/used.png" {
+		t.Fatalf("List after RemoveOrphans = %v, %v", objects, err)
+	}
+}
+
+// testStorage проверяет общий для всех хранилищ порядок работы с файлом posts/a.png.
+func testStorage(t *testing.T, s Storage) {
+	t.Helper()
+	ctx := context.Background()
+	if err := s.Put(ctx, "posts/a.png", "image/png", strings.NewReader("picture")); err != nil {
+		t.Fatal(err)
+	}
+	r, contentType, err := s.Get(ctx, "posts/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(r)
+	r.Close()
+	if string(body) != "picture" || contentType != "image/png" {
+		t.Errorf("Get = %q, %q", body, contentType)
+	}
+	if err := s.Put(ctx, "posts/b.png", "image/png", strings.NewReader("second")); err != nil {
+		t.Fatal(err)
+	}
+	objects, err := s.List(ctx, "posts/")
+	if err != nil || len(objects) != 2 || objects[0].Key != "posts/a.png" || objects[1].Key != "posts/b.png" {
+		t.Fatalf("List = %v, %v", objects, err)
+	}
+	if time.Since(objects[0].Modified) > time.Minute {
+		t.Errorf("Modified = %v", objects[0].Modified)
+	}
+	if objects, err := s.List(ctx, "avatars/"); err != nil || len(objects) != 0 {
+		t.Errorf("List with another prefix = %v, %v", objects, err)
+	}
+	if err := s.Delete(ctx, "posts/b.png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "posts/a.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Get(ctx, "posts/a.png"); err != ErrNotFound {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, "posts/a.png"); err != nil {
+		t.Errorf("Delete of a missing file: %v", err)
+	}
+}
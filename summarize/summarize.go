@@ -0,0 +1,196 @@
+// Package summarize пересказывает длинные обсуждения языковой моделью: пост и его комментарии
+// отправляются настроенному сервису, а в ответ приходит короткая сводка для начала ветки.
+// Поддерживаются API, совместимые с OpenAI Chat Completions (OpenAI и локальные серверы вроде vLLM или llama.cpp),
+// и Ollama. Сводки хранит форум (см. database.SaveThreadSummary), так что модель получает обсуждение
+// заново только после новых комментариев.
+package summarize
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxInputChars ограничивает размер обсуждения в запросе: комментарии сверх него не отправляются.
+const maxInputChars = 24000
+
+// maxSummaryChars ограничивает длину сохраняемой сводки, если модель не уложилась в просьбу.
+const maxSummaryChars = 2000
+
+// prompt — системная инструкция модели.
+const prompt = "You summarize forum discussions. Write 3-5 sentences in the language the discussion is written in: " +
+	"what the post is about, the main points and disagreements in the comments, and any conclusion the participants reached. " +
+	"Do not add opinions of your own. Reply with the summary only."
+
+// Comment — комментарий обсуждения.
+type Comment struct {
+	Author string
+	Text   string
+}
+
+// Thread — пост с комментариями в порядке публикации.
+type Thread struct {
+	Title    string
+	Text     string
+	Author   string
+	Comments []Comment
+}
+
+// Summarizer пересказывает обсуждение.
+type Summarizer interface {
+	Name() string
+	Summarize(ctx context.Context, t Thread) (string, error)
+}
+
+// Providers — имена встроенных сервисов для настройки summary.provider.
+var Providers = []string{"openai", "ollama"}
+
+// New возвращает встроенный сервис name. Пустое или неизвестное имя даёт nil: сводки выключены
+// (имя заранее проверяет config.Validate).
+func New(name, url, apiKey, model string, timeout time.Duration) Summarizer {
+	client := &http.Client{Timeout: timeout}
+	switch name {
+	case "openai":
+		if url == "" {
+			url = "https://api.openai.com"
+		}
+		if model == "" {
+			model = "gpt-4o-mini"
+		}
+		return &OpenAI{URL: strings.TrimRight(url, "/"), APIKey: apiKey, Model: model, Client: client}
+	case "ollama":
+		if url == "" {
+			url = "http://localhost:11434"
+		}
+		return &Ollama{URL: strings.TrimRight(url, "/"), Model: model, Client: client}
+	}
+	return nil
+}
+
+// OpenAI пересказывает через API Chat Completions (POST /v1/chat/completions) OpenAI или совместимого сервера.
+type OpenAI struct {
+	URL    string
+	APIKey string // пусто — без авторизации, для локальных серверов
+	Model  string
+	Client *http.Client
+}
+
+// Name возвращает имя сервиса для журнала и базы.
+func (o *OpenAI) Name() string { return "openai" }
+
+// Summarize отправляет обсуждение одним сообщением пользователя и возвращает ответ модели.
+func (o *OpenAI) Summarize(ctx context.Context, t Thread) (string, error) {
+	payload := map[string]interface{}{
+		"model":    o.Model,
+		"messages": messages(t),
+	}
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := postJSON(ctx, o.Client, o.URL+"/v1/chat/completions", o.APIKey, payload, &resp); err != nil {
+		return "", fmt.Errorf("openai: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("openai: empty completion")
+	}
+	return clean(resp.Choices[0].Message.Content)
+}
+
+// Ollama пересказывает локальной моделью через API Ollama (POST /api/chat).
+type Ollama struct {
+	URL    string
+	Model  string
+	Client *http.Client
+}
+
+// Name возвращает имя сервиса для журнала и базы.
+func (o *Ollama) Name() string { return "ollama" }
+
+// Summarize отправляет обсуждение без потоковой выдачи и возвращает ответ модели.
+func (o *Ollama) Summarize(ctx context.Context, t Thread) (string, error) {
+	payload := map[string]interface{}{
+		"model":    o.Model,
+		"messages": messages(t),
+		"stream":   false,
+	}
+	var resp struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	}
+	if err := postJSON(ctx, o.Client, o.URL+"/api/chat", "", payload, &resp); err != nil {
+		return "", fmt.Errorf("ollama: %w", err)
+	}
+	return clean(resp.Message.Content)
+}
+
+// messages собирает системную инструкцию и текст обсуждения.
+func messages(t Thread) []map[string]string {
+	return []map[string]string{
+		{"role": "system", "content": prompt},
+		{"role": "user", "content": Format(t)},
+	}
+}
+
+// Format записывает обсуждение текстом для модели. Комментарии, не уместившиеся в maxInputChars,
+// отбрасываются с конца, и модель узнаёт, сколько их пропущено.
+func Format(t Thread) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Post by %s: %s\n\n%s\n\nComments:\n", t.Author, t.Title, t.Text)
+	for i, c := range t.Comments {
+		line := fmt.Sprintf("- %s: %s\n", c.Author, strings.Join(strings.Fields(c.Text), " "))
+		if b.Len()+len(line) > maxInputChars {
+			fmt.Fprintf(&b, "(%d more comments omitted)\n", len(t.Comments)-i)
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// clean обрезает ответ модели и отвергает пустой.
+func clean(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	if r := []rune(s); len(r) > maxSummaryChars {
+		s = strings.TrimSpace(string(r[:maxSummaryChars])) + "…"
+	}
+	return s, nil
+}
+
+// postJSON отправляет payload и разбирает JSON-ответ в dst; ответ не 2xx считается ошибкой.
+func postJSON(ctx context.Context, client *http.Client, url, apiKey string, payload, dst interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(dst)
+}
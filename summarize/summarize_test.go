@@ -0,0 +1,71 @@
+package summarize
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestOpenAI проверяет запрос Chat Completions и разбор ответа.
+func TestOpenAI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model    string              `json:"model"`
+			Messages []map[string]string `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer key" || req.Model != "gpt-4o-mini" ||
+			len(req.Messages) != 2 || !strings.Contains(req.Messages[1]["content"], "- bob: Agreed") {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"  Everyone agreed.\n"}}]}`))
+	}))
+	defer srv.Close()
+
+	thread := Thread{Title: "Title", Text: "Text", Author: "alice", Comments: []Comment{{Author: "bob", Text: "Agreed"}}}
+	got, err := New("openai", srv.URL, "key", "", time.Minute).Summarize(context.Background(), thread)
+	if err != nil || got != "Everyone agreed." {
+		t.Fatalf("Summarize = %q, %v", got, err)
+	}
+}
+
+// TestOllama проверяет запрос без потоковой выдачи и отказ от пустой сводки.
+func TestOllama(t *testing.T) {
+	reply := "A short summary."
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/api/chat" || req["stream"] != false || req["model"] != "llama3" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": map[string]string{"content": reply}})
+	}))
+	defer srv.Close()
+
+	s := New("ollama", srv.URL, "", "llama3", time.Minute)
+	if got, err := s.Summarize(context.Background(), Thread{Title: "T"}); err != nil || got != reply {
+		t.Fatalf("Summarize = %q, %v", got, err)
+	}
+	reply = " "
+	if _, err := s.Summarize(context.Background(), Thread{Title: "T"}); err == nil {
+		t.Error("Summarize accepted an empty summary")
+	}
+}
+
+// TestFormat проверяет, что длинное обсуждение обрезается по комментариям с пометкой о пропущенных.
+func TestFormat(t *testing.T) {
+	thread := Thread{Title: "T", Text: "Text", Author: "alice"}
+	for i := 0; i < 100; i++ {
+		thread.Comments = append(thread.Comments, Comment{Author: "bob", Text: strings.Repeat("word ", 100)})
+	}
+	got := Format(thread)
+	if len(got) > maxInputChars+100 || !strings.Contains(got, "more comments omitted") {
+		t.Errorf("Format = %d chars, omitted note present: %v", len(got), strings.Contains(got, "omitted"))
+	}
+}
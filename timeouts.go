@@ -0,0 +1,73 @@
+// Package main содержит ограничения времени обработки запросов по классам маршрутов.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"forum/config"
+)
+
+// routeClass — класс маршрута, определяющий время обработки запроса.
+type routeClass int
+
+const (
+	pageRoute   routeClass = iota // страницы и JSON API
+	uploadRoute                   // отправка форм с содержимым: чтение тела может занять время
+	longRoute                     // долгие служебные операции, например выгрузка резервной копии
+	streamRoute                   // долгие соединения (SSE): без ограничения времени
+)
+
+// writeGrace — запас времени на запись ответа об ошибке после истечения контекста запроса.
+const writeGrace = 5 * time.Second
+
+// timeout возвращает время обработки для класса маршрута; 0 — без ограничения.
+func (c routeClass) timeout(t config.Timeouts) time.Duration {
+	switch c {
+	case uploadRoute:
+		return t.Upload
+	case longRoute:
+		return t.Long
+	case streamRoute:
+		return 0
+	default:
+		return t.Page
+	}
+}
+
+// newServer создаёт HTTP-сервер с ограничениями времени соединения. ReadTimeout и WriteTimeout
+// рассчитаны на обычные страницы; маршруты других классов продлевают их в withTimeout.
+func newServer(addr string, handler http.Handler, t config.Timeouts) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: t.ReadHeader,
+		ReadTimeout:       t.Page,
+		WriteTimeout:      t.Page + writeGrace,
+		IdleTimeout:       t.Idle,
+	}
+}
+
+// withTimeout ограничивает обработку запроса временем d: контекст запроса (а с ним и запросы к базе)
+// отменяется по истечении d, а сроки чтения и записи соединения переносятся, чтобы медленный клиент
+// не удерживал соединение дольше. При d = 0 сроки соединения снимаются.
+func withTimeout(d time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		// Ошибки означают, что обёртка ответа не поддерживает сроки (например, в тестах).
+		if d <= 0 {
+			rc.SetReadDeadline(time.Time{})
+			rc.SetWriteDeadline(time.Time{})
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		rc.SetReadDeadline(start.Add(d))
+		rc.SetWriteDeadline(start.Add(d + writeGrace))
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"forum/config"
+)
+
+// TestWithTimeout проверяет, что маршрут долгого класса продлевает сроки соединения сервера,
+// а контекст запроса получает ограничение своего класса.
+func TestWithTimeout(t *testing.T) {
+	timeouts := config.Timeouts{ReadHeader: time.Second, Idle: time.Second, Page: 50 * time.Millisecond, Long: 5 * time.Second}
+	mux := http.NewServeMux()
+	mux.Handle("/long", withTimeout(longRoute.timeout(timeouts), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := r.Context().Deadline()
+		if !ok || time.Until(deadline) < time.Second {
+			t.Errorf("context deadline = %v, %v; want the long route timeout", deadline, ok)
+		}
+		// Дольше, чем WriteTimeout сервера для обычных страниц (page + writeGrace).
+		time.Sleep(writeGrace + 100*time.Millisecond)
+		io.WriteString(w, "done")
+	})))
+
+	srv := httptest.NewUnstartedServer(&CustomHandler{mux: mux})
+	srv.Config = newServer("", srv.Config.Handler, timeouts)
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/long")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || string(body) != "done" {
+		t.Fatalf("body = %q, err = %v", body, err)
+	}
+}
@@ -0,0 +1,67 @@
+// Package main содержит настройку HTTPS: сертификаты из файлов или Let's Encrypt (autocert)
+// и HTTP-сервер, перенаправляющий посетителей на HTTPS.
+
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"forum/config"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// setupTLS возвращает настройки TLS для основного сервера и обработчик HTTP-сервера перенаправления.
+// Если HTTPS выключен, возвращает nil. С autocert обработчик перенаправления также отвечает
+// на проверки Let's Encrypt (HTTP-01), поэтому для выпуска сертификата redirect_addr должен быть доступен на порту 80.
+func setupTLS(cfg config.Server) (*tls.Config, http.Handler, error) {
+	redirect := redirectHTTPS(cfg.Addr)
+	switch {
+	case cfg.TLS.CertFile != "":
+		cert, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error loading TLS certificate: %w", err)
+		}
+		return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, redirect, nil
+	case len(cfg.TLS.AutocertDomains) > 0:
+		domains := make([]string, 0, len(cfg.TLS.AutocertDomains))
+		for _, d := range cfg.TLS.AutocertDomains {
+			if d = strings.TrimSpace(d); d != "" {
+				domains = append(domains, d)
+			}
+		}
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(domains...),
+			Cache:      autocert.DirCache(cfg.TLS.AutocertCacheDir),
+			Email:      cfg.TLS.AutocertEmail,
+		}
+		log.Printf("Let's Encrypt certificates enabled for %s.", strings.Join(domains, ", "))
+		tlsCfg := m.TLSConfig()
+		tlsCfg.MinVersion = tls.VersionTLS12
+		return tlsCfg, m.HTTPHandler(redirect), nil
+	default:
+		return nil, nil, nil
+	}
+}
+
+// redirectHTTPS перенаправляет запросы на тот же адрес по HTTPS. Порт HTTPS берётся из addr
+// и опускается, если это стандартный 443.
+func redirectHTTPS(addr string) http.Handler {
+	_, port, _ := net.SplitHostPort(addr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
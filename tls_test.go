@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRedirectHTTPS проверяет адрес перенаправления на HTTPS для стандартного и нестандартного порта.
+func TestRedirectHTTPS(t *testing.T) {
+	tests := []struct {
+		addr, host, target, want string
+	}{
+		{":443", "forum.example.com", "/post?post_id=3", "https://forum.example.com/post?post_id=3"},
+		{":443", "forum.example.com:80", "/", "https://forum.example.com/"},
+		{":8443", "localhost:8080", "/login", "https://localhost:8443/login"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		r.Host = tt.host
+		w := httptest.NewRecorder()
+		redirectHTTPS(tt.addr).ServeHTTP(w, r)
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != tt.want {
+			t.Errorf("%s %s%s: got %d %q, want %q", tt.addr, tt.host, tt.target, w.Code, w.Header().Get("Location"), tt.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Package translate подключает машинный перевод постов: сообщество форума пишет и по-русски, и по-английски.
+// Провайдер (DeepL, LibreTranslate или своя реализация Provider) выбирается настройкой translation.provider;
+// готовые переводы хранятся в базе (см. database.GetPostTranslation), поэтому каждый пост переводится
+// на каждый язык один раз, пока автор не изменит текст.
+package translate
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Provider переводит тексты на язык target (код ISO 639-1, например "ru"); язык исходного текста
+// определяет сам провайдер. Возвращает переводы в том же порядке, что и texts.
+type Provider interface {
+	Name() string
+	Translate(ctx context.Context, texts []string, target string) ([]string, error)
+}
+
+// Providers — имена встроенных провайдеров для настройки translation.provider.
+var Providers = []string{"deepl", "libretranslate"}
+
+// New возвращает встроенный провайдер name. Пустое или неизвестное имя даёт nil: перевод выключен
+// (имя заранее проверяет config.Validate). url заменяет адрес сервиса по умолчанию.
+func New(name, url, apiKey string, timeout time.Duration) Provider {
+	client := &http.Client{Timeout: timeout}
+	switch name {
+	case "deepl":
+		if url == "" {
+			url = "https://api-free.deepl.com"
+		}
+		return &DeepL{URL: strings.TrimRight(url, "/"), APIKey: apiKey, Client: client}
+	case "libretranslate":
+		return &LibreTranslate{URL: strings.TrimRight(url, "/"), APIKey: apiKey, Client: client}
+	}
+	return nil
+}
+
+// SourceHash возвращает отпечаток исходных текстов. Перевод в базе хранится вместе с отпечатком
+// и считается устаревшим, если пост изменился.
+func SourceHash(texts ...string) string {
+	h := sha256.New()
+	for _, t := range texts {
+		h.Write([]byte(t))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DeepL переводит через DeepL API (https://www.deepl.com/docs-api); для бесплатного ключа адрес api-free.deepl.com.
+type DeepL struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+// Name возвращает имя провайдера для журнала и базы.
+func (d *DeepL) Name() string { return "deepl" }
+
+// Translate отправляет все тексты одним запросом /v2/translate.
+func (d *DeepL) Translate(ctx context.Context, texts []string, target string) ([]string, error) {
+	form := url.Values{"target_lang": {deeplLanguage(target)}, "text": texts}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL+"/v2/translate", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "DeepL-Auth-Key "+d.APIKey)
+
+	var resp struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+	if err := do(d.Client, req, &resp); err != nil {
+		return nil, fmt.Errorf("deepl: %w", err)
+	}
+	if len(resp.Translations) != len(texts) {
+		return nil, fmt.Errorf("deepl: got %d translations for %d texts", len(resp.Translations), len(texts))
+	}
+	out := make([]string, len(texts))
+	for i, t := range resp.Translations {
+		out[i] = t.Text
+	}
+	return out, nil
+}
+
+// deeplLanguage переводит код языка в код DeepL: английский требует варианта (EN-US или EN-GB).
+func deeplLanguage(lang string) string {
+	if lang == "en" {
+		return "EN-US"
+	}
+	return strings.ToUpper(lang)
+}
+
+// LibreTranslate переводит через свой или публичный сервер LibreTranslate (https://libretranslate.com).
+type LibreTranslate struct {
+	URL    string
+	APIKey string // нужен не всем серверам
+	Client *http.Client
+}
+
+// Name возвращает имя провайдера для журнала и базы.
+func (l *LibreTranslate) Name() string { return "libretranslate" }
+
+// Translate отправляет все тексты одним запросом /translate с определением исходного языка.
+func (l *LibreTranslate) Translate(ctx context.Context, texts []string, target string) ([]string, error) {
+	payload := map[string]interface{}{"q": texts, "source": "auto", "target": target, "format": "text"}
+	if l.APIKey != "" {
+		payload["api_key"] = l.APIKey
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.URL+"/translate", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var resp struct {
+		TranslatedText []string `json:"translatedText"`
+	}
+	if err := do(l.Client, req, &resp); err != nil {
+		return nil, fmt.Errorf("libretranslate: %w", err)
+	}
+	if len(resp.TranslatedText) != len(texts) {
+		return nil, fmt.Errorf("libretranslate: got %d translations for %d texts", len(resp.TranslatedText), len(texts))
+	}
+	return resp.TranslatedText, nil
+}
+
+// do выполняет запрос и разбирает JSON-ответ в dst; ответ не 2xx считается ошибкой.
+func do(client *http.Client, req *http.Request, dst interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(dst)
+}
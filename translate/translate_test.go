@@ -0,0 +1,66 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestDeepL проверяет запрос к DeepL: ключ в заголовке, тексты одним запросом и код языка с вариантом.
+func TestDeepL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/translate" || r.Header.Get("Authorization") != "DeepL-Auth-Key key" || r.FormValue("target_lang") != "EN-US" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var resp struct {
+			Translations []map[string]string `json:"translations"`
+		}
+		for _, text := range r.Form["text"] {
+			resp.Translations = append(resp.Translations, map[string]string{"text": "en:" + text})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	p := New("deepl", srv.URL, "key", time.Minute)
+	got, err := p.Translate(context.Background(), []string{"Привет", "Мир"}, "en")
+	if err != nil || !slices.Equal(got, []string{"en:Привет", "en:Мир"}) {
+		t.Fatalf("Translate = %q, %v", got, err)
+	}
+	if _, err := New("deepl", srv.URL, "wrong", time.Minute).Translate(context.Background(), []string{"x"}, "en"); err == nil {
+		t.Error("Translate ignored an error response")
+	}
+}
+
+// TestLibreTranslate проверяет запрос к LibreTranslate со списком текстов.
+func TestLibreTranslate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Q      []string `json:"q"`
+			Target string   `json:"target"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Path != "/translate" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		out := make([]string, len(req.Q))
+		for i, q := range req.Q {
+			out[i] = req.Target + ":" + q
+		}
+		json.NewEncoder(w).Encode(map[string][]string{"translatedText": out})
+	}))
+	defer srv.Close()
+
+	got, err := New("libretranslate", srv.URL+"/", "", time.Minute).Translate(context.Background(), []string{"Hello"}, "ru")
+	if err != nil || !slices.Equal(got, []string{"ru:Hello"}) {
+		t.Fatalf("Translate = %q, %v", got, err)
+	}
+	if New("", "", "", time.Minute) != nil {
+		t.Error("New without a provider returned one")
+	}
+}
@@ -0,0 +1,107 @@
+// Package usernames проверяет имена пользователей при регистрации и смене имени.
+//
+// Имя должно укладываться в заданную длину (в символах, а не байтах), состоять из разрешённых символов,
+// не совпадать с зарезервированным именем вроде admin или moderator и не содержать бранных слов.
+// Зарезервированные имена и бранные слова сравниваются без учёта регистра и разделителей «_», «.» и «-»,
+// поэтому Ad_Min тоже считается занятым.
+package usernames
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Причины отказа Rules.Check.
+var (
+	ErrLength   = errors.New("usernames: invalid length")
+	ErrCharset  = errors.New("usernames: invalid characters")
+	ErrReserved = errors.New("usernames: reserved name")
+	ErrProfane  = errors.New("usernames: profanity")
+)
+
+// Rules — правила имён пользователей. nil — имя проверяется только на пустоту.
+type Rules struct {
+	MinLength int
+	MaxLength int
+	charset   *regexp.Regexp
+	reserved  map[string]bool
+	profanity []string
+}
+
+// New возвращает правила: длина от minLength до maxLength символов, каждый символ подходит под класс
+// регулярного выражения charset (например, [\p{L}\p{N}_.-]), reserved и profanity — зарезервированные
+// имена и бранные слова в дополнение к встроенным спискам Reserved и Profanity.
+func New(minLength, maxLength int, charset string, reserved, profanity []string) (*Rules, error) {
+	re, err := regexp.Compile(`^(?:` + charset + `)+$`)
+	if err != nil {
+		return nil, fmt.Errorf("usernames: invalid charset %q: %w", charset, err)
+	}
+	r := &Rules{MinLength: minLength, MaxLength: maxLength, charset: re, reserved: map[string]bool{}}
+	for _, name := range append(append([]string{}, Reserved...), reserved...) {
+		if name = normalize(name); name != "" {
+			r.reserved[name] = true
+		}
+	}
+	for _, word := range append(append([]string{}, Profanity...), profanity...) {
+		if word = normalize(word); word != "" {
+			r.profanity = append(r.profanity, word)
+		}
+	}
+	return r, nil
+}
+
+// Check проверяет имя name. Возвращает ErrLength, ErrCharset, ErrReserved или ErrProfane; nil — имя подходит.
+func (r *Rules) Check(name string) error {
+	if r == nil {
+		if name == "" {
+			return ErrLength
+		}
+		return nil
+	}
+	if n := utf8.RuneCountInString(name); n < r.MinLength || n > r.MaxLength {
+		return ErrLength
+	}
+	if !r.charset.MatchString(name) {
+		return ErrCharset
+	}
+	normalized := normalize(name)
+	if r.reserved[normalized] {
+		return ErrReserved
+	}
+	for _, word := range r.profanity {
+		if strings.Contains(normalized, word) {
+			return ErrProfane
+		}
+	}
+	return nil
+}
+
+// normalize приводит имя к нижнему регистру и убирает разделители, которыми обходят списки: Ad_Min → admin.
+func normalize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '.' || r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// Reserved — имена, которые нельзя занять: их легко принять за служебные учётные записи или администрацию.
+// Список дополняется настройкой usernames.reserved.
+var Reserved = []string{
+	"admin", "administrator", "administration", "moderator", "mod", "mods", "root", "system", "sysadmin",
+	"support", "staff", "official", "owner", "webmaster", "postmaster", "security", "help", "info",
+	"anonymous", "deleted", "null", "undefined", "guest", "bot", "forum", "api",
+	"админ", "администратор", "модератор", "поддержка", "система",
+}
+
+// Profanity — бранные слова и корни, которые нельзя использовать ни в какой части имени.
+// Проверяется вхождение, поэтому в список попадают только слова, не встречающиеся внутри обычных имён.
+// Список дополняется настройкой usernames.profanity.
+var Profanity = []string{
+	"fuck", "shit", "cunt", "bitch", "whore", "nigger", "faggot", "asshole", "dickhead", "motherfucker",
+	"хуй", "хуе", "пизд", "ебат", "ебан", "ёбан", "бляд", "блят", "шлюх", "пидор", "пидар", "залуп", "мудак",
+}
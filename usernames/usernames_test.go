@@ -0,0 +1,39 @@
+package usernames
+
+import "testing"
+
+// TestCheck проверяет длину, допустимые символы, зарезервированные имена и бранные слова.
+func TestCheck(t *testing.T) {
+	r, err := New(3, 12, `[\p{L}\p{N}_.-]`, []string{"Polar Lights"}, []string{"darn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]error{
+		"alice":         nil,
+		"Анна_1990":     nil,
+		"john.doe":      nil,
+		"ab":            ErrLength,
+		"abcdefghijklm": ErrLength,
+		"Юлия":          nil,
+		"bob smith":     ErrCharset,
+		"<script>":      ErrCharset,
+		"Admin":         ErrReserved,
+		"Mod-Erator":    ErrReserved,
+		"polar_lights":  ErrReserved,
+		"admin2":        nil,
+		"xXfuckXx":      ErrProfane,
+		"darn_it":       ErrProfane,
+	} {
+		if got := r.Check(name); got != want {
+			t.Errorf("Check(%q) = %v, want %v", name, got, want)
+		}
+	}
+
+	var none *Rules
+	if none.Check("") != ErrLength || none.Check("any name!") != nil {
+		t.Error("nil Rules must only reject empty names")
+	}
+	if _, err := New(3, 12, `[a-z`, nil, nil); err == nil {
+		t.Error("New accepted an invalid charset")
+	}
+}